@@ -0,0 +1,198 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 锁文件刚创建、尚未写入内容时的宽限期，期间内容为空不视为残留
+const lockWriteGrace = 5 * time.Second
+
+// DSID 中允许出现在文件名里的字符
+var accountLockUnsafeChars = regexp.MustCompile(`[^0-9A-Za-z_-]`)
+
+// readOnlyCommands 只读取数据、不修改账号的子命令，无需获取账号锁，可与其他进程并行
+var readOnlyCommands = map[string]bool{
+	"history":       true,
+	"doctor":        true,
+	"config-keys":   true,
+	"stats":         true,
+	"list":          true,
+	"otp":           true,
+	"verify-watch":  true,
+	"forward-check": true,
+	"test-send":     true,
+	"notify-test":   true,
+	"mock-server":   true,
+	"score":         true,
+	"soak":          true,
+	"export":        true,
+	"checklist":     true,
+	"jobs":          true, // 只与守护进程通信，由守护进程持有账号锁
+}
+
+// LockInfo 锁文件内容，记录持有者以便判断锁是否残留
+type LockInfo struct {
+	PID       int    `json:"pid"`
+	Hostname  string `json:"hostname"`
+	StartedAt int64  `json:"started_at"` // Unix 秒
+	Command   string `json:"command,omitempty"`
+}
+
+// LockHeldError 锁被另一个仍在运行的实例持有
+type LockHeldError struct {
+	Path string
+	Info LockInfo
+}
+
+func (e *LockHeldError) Error() string {
+	return fmt.Sprintf("该账号已有实例在运行 (PID: %d，主机: %s)", e.Info.PID, e.Info.Hostname)
+}
+
+// stateDir 返回状态目录：配置 state_dir 优先，其次 $XDG_STATE_HOME，
+// Linux 默认 ~/.local/state/icloud-hme，其他系统使用用户配置目录
+func stateDir(config *Config) string {
+	if config != nil && config.StateDir != "" {
+		return config.StateDir
+	}
+	if dir := os.Getenv("XDG_STATE_HOME"); dir != "" {
+		return filepath.Join(dir, "icloud-hme")
+	}
+	if runtime.GOOS == "linux" {
+		if home, err := os.UserHomeDir(); err == nil {
+			return filepath.Join(home, ".local", "state", "icloud-hme")
+		}
+	}
+	if dir, err := os.UserConfigDir(); err == nil {
+		return filepath.Join(dir, "icloud-hme")
+	}
+	return "."
+}
+
+// accountLockFile 返回账号对应的锁文件路径，未配置 DSID 时使用 LOCK_FILE
+func accountLockFile(config *Config) string {
+	dir := filepath.Join(stateDir(config), "locks")
+	dsid := accountLockUnsafeChars.ReplaceAllString(config.DSID, "_")
+	if dsid == "" {
+		return filepath.Join(dir, LOCK_FILE)
+	}
+	return filepath.Join(dir, "account-"+dsid+".lock")
+}
+
+// commandNeedsLock 命令是否会修改账号数据（无子命令即交互菜单）
+func commandNeedsLock(args []string) bool {
+	return len(args) == 0 || !readOnlyCommands[args[0]]
+}
+
+// UseAccount 按账号切换锁文件，只能在获取锁之前调用
+func (psm *ProcessSafetyManager) UseAccount(config *Config) {
+	psm.mutex.Lock()
+	defer psm.mutex.Unlock()
+
+	if !psm.isLocked {
+		psm.lockFile = accountLockFile(config)
+	}
+}
+
+// currentLockInfo 本进程写入锁文件的内容
+func currentLockInfo() LockInfo {
+	hostname, _ := os.Hostname()
+	return LockInfo{
+		PID:       os.Getpid(),
+		Hostname:  hostname,
+		StartedAt: time.Now().Unix(),
+		Command:   strings.Join(os.Args[1:], " "),
+	}
+}
+
+// readLockInfo 读取锁文件，兼容旧版只写 PID 的格式
+func readLockInfo(path string) (LockInfo, error) {
+	var info LockInfo
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return info, err
+	}
+	data = []byte(strings.TrimSpace(string(data)))
+	if len(data) == 0 {
+		return info, fmt.Errorf("锁文件为空")
+	}
+	if err := json.Unmarshal(data, &info); err == nil {
+		return info, nil
+	}
+	pid, err := strconv.Atoi(string(data))
+	if err != nil {
+		return info, fmt.Errorf("无法识别锁文件内容")
+	}
+	info.PID = pid
+	return info, nil
+}
+
+// lockIsStale 判断锁是否为残留：持有者在本机且进程已不存在，或内容损坏且超过宽限期。
+// 其他主机持有的锁（如共享目录）无法确认，一律视为有效
+func lockIsStale(path string, info LockInfo, readErr error) bool {
+	if readErr != nil {
+		stat, err := os.Stat(path)
+		return err == nil && time.Since(stat.ModTime()) > lockWriteGrace
+	}
+	hostname, _ := os.Hostname()
+	if info.Hostname != "" && info.Hostname != hostname {
+		return false
+	}
+	return !processAlive(info.PID)
+}
+
+// printLockGuidance 锁被占用时提示如何处理
+func printLockGuidance(err error) {
+	var held *LockHeldError
+	if !errors.As(err, &held) {
+		return
+	}
+	if held.Info.StartedAt > 0 {
+		printInfo(fmt.Sprintf("该实例启动于 %s", time.Unix(held.Info.StartedAt, 0).Format("2006-01-02 15:04:05")))
+	}
+	if held.Info.Command != "" {
+		printInfo(fmt.Sprintf("运行的命令: %s", held.Info.Command))
+	}
+	printInfo("同一账号同时只允许一个进程修改，请等待其结束或先退出它（菜单按 0，serve/watch 按 Ctrl+C）")
+	printInfo("只读命令（history、list、otp、verify-watch、forward-check、test-send）可以并行运行")
+	if strings.HasPrefix(held.Info.Command, "daemon") {
+		printInfo("持有锁的是守护进程：用 jobs submit 提交创建任务，jobs 查看进度")
+	}
+	printInfo(fmt.Sprintf("如确认该实例已不存在（如在另一台主机上异常退出），可手动删除锁文件: %s", held.Path))
+}
+
+// SwitchAccount 运行中切换账号：先获取新账号的锁，成功后再释放旧锁，失败时保持原账号
+func (psm *ProcessSafetyManager) SwitchAccount(config *Config) error {
+	psm.mutex.Lock()
+	defer psm.mutex.Unlock()
+
+	newFile := accountLockFile(config)
+	if newFile == psm.lockFile {
+		return nil
+	}
+	if !psm.isLocked {
+		psm.lockFile = newFile
+		return nil
+	}
+
+	oldFile := psm.lockFile
+	psm.lockFile = newFile
+	psm.isLocked = false
+	if err := psm.acquireLocked(); err != nil {
+		psm.lockFile = oldFile
+		psm.isLocked = true
+		return err
+	}
+	if err := os.Remove(oldFile); err != nil && !os.IsNotExist(err) {
+		printWarning(fmt.Sprintf("释放原账号锁失败: %v", err))
+	}
+	return nil
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"icloud-hme-generator/pkg/hme"
+)
+
+// reportAPIError 菜单中接口请求失败时的提示：按错误类别说明原因与处理方法，而不是直接显示原始响应。
+// 会话过期时可立即粘贴新的 curl 命令更新账号，被限流时可等待到限流结束；返回 true 表示可以重试该操作
+func reportAPIError(config *Config, action string, err error) bool {
+	logFailure(slog.LevelError, action+"失败", err)
+	if errors.Is(err, hme.ErrSessionExpired) {
+		status := SessionStatus{State: SessionExpired, CheckedAt: time.Now()}
+		var statusErr *APIStatusError
+		if errors.As(err, &statusErr) {
+			status.HTTPStatus = statusErr.StatusCode
+		}
+		printError(action + "失败")
+		printSessionProblem(status)
+		if !confirmAction("现在粘贴新的 curl 命令更新账号") {
+			return false
+		}
+		before := config.clone()
+		handleImportCurl(config)
+		return len(describeAccountChanges(before, config)) > 0 && confirmAction("重试"+action)
+	}
+
+	printError(fmt.Sprintf("%s失败: %v", action, err))
+	if wait, limited := retryAfterFor(err); limited {
+		if wait <= 0 {
+			return true
+		}
+		printInfo(fmt.Sprintf("iCloud 要求等待 %s（约 %s）后再试", wait.Round(time.Second), time.Now().Add(wait).Format("15:04")))
+		if !confirmAction("等待结束后自动重试") {
+			return false
+		}
+		return waitWithCountdown(wait, "限流结束")
+	}
+	if class := classifyFailure(err); class != failureOther {
+		printInfo("建议: " + class.Advice)
+	}
+	return false
+}
+
+// listHMEInteractive 菜单中获取邮箱列表，失败时说明原因并按需重试；放弃时返回 false
+func listHMEInteractive(config *Config, message string) ([]HMEEmail, bool) {
+	for {
+		var emails []HMEEmail
+		err := withSpinner(message, func() error {
+			var err error
+			emails, err = listHME(config)
+			return err
+		})
+		if err == nil {
+			return emails, true
+		}
+		if !reportAPIError(config, "获取邮箱列表", err) {
+			return nil, false
+		}
+	}
+}
@@ -0,0 +1,249 @@
+package main
+
+import (
+	"bufio"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 口令哈希参数
+const (
+	passphraseHashScheme     = "pbkdf2-sha256"
+	passphraseHashIterations = 600000
+	passphraseMinLength      = 8
+	passphraseMaxAttempts    = 3
+	appPassphraseEnv         = "ICLOUD_HME_APP_PASSPHRASE" // 服务、守护进程与定时任务等无法输入口令时由环境变量提供
+)
+
+// AppLockConfig 启动口令配置
+type AppLockConfig struct {
+	PassphraseHash     string `json:"passphrase_hash"`      // 口令哈希，为空表示不启用，使用 app-lock set 设置
+	IdleTimeoutMinutes int    `json:"idle_timeout_minutes"` // 菜单/服务模式空闲多久后重新锁定，0 表示不自动锁定
+}
+
+// Enabled 是否启用了启动口令
+func (c AppLockConfig) Enabled() bool {
+	return c.PassphraseHash != ""
+}
+
+// hashPassphrase 生成口令哈希，格式为 pbkdf2-sha256$迭代次数$盐$密钥
+func hashPassphrase(passphrase string) (string, error) {
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return "", fmt.Errorf("生成随机盐失败: %v", err)
+	}
+	key, err := pbkdf2.Key(sha256.New, passphrase, salt, passphraseHashIterations, 32)
+	if err != nil {
+		return "", fmt.Errorf("计算口令哈希失败: %v", err)
+	}
+	return fmt.Sprintf("%s$%d$%s$%s", passphraseHashScheme, passphraseHashIterations,
+		base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(key)), nil
+}
+
+// verifyPassphrase 校验口令是否与哈希匹配
+func verifyPassphrase(encoded, passphrase string) bool {
+	parts := strings.Split(encoded, "$")
+	if len(parts) != 4 || parts[0] != passphraseHashScheme {
+		return false
+	}
+	iterations, err := strconv.Atoi(parts[1])
+	if err != nil || iterations <= 0 {
+		return false
+	}
+	salt, err := base64.RawStdEncoding.DecodeString(parts[2])
+	if err != nil {
+		return false
+	}
+	expected, err := base64.RawStdEncoding.DecodeString(parts[3])
+	if err != nil {
+		return false
+	}
+	key, err := pbkdf2.Key(sha256.New, passphrase, salt, iterations, len(expected))
+	if err != nil {
+		return false
+	}
+	return subtle.ConstantTimeCompare(key, expected) == 1
+}
+
+// setTerminalEcho 开关终端回显
+func setTerminalEcho(on bool) error {
+	arg := "-echo"
+	if on {
+		arg = "echo"
+	}
+	cmd := exec.Command("stty", arg)
+	cmd.Stdin = os.Stdin
+	return cmd.Run()
+}
+
+// readPassphrase 读取口令，输入时不回显
+func readPassphrase(prompt string) string {
+	fmt.Print(ColorCyan + "  › " + ColorReset + prompt)
+	if err := setTerminalEcho(false); err == nil {
+		defer func() {
+			setTerminalEcho(true)
+			fmt.Println()
+		}()
+	}
+	reader := bufio.NewReader(os.Stdin)
+	input, _ := reader.ReadString('\n')
+	return strings.TrimRight(input, "\r\n")
+}
+
+// unlockApp 校验启动口令：先看环境变量 ICLOUD_HME_APP_PASSPHRASE，否则在终端询问口令，连续错误超过上限时返回错误
+func unlockApp(config *Config) error {
+	if passphrase, ok := os.LookupEnv(appPassphraseEnv); ok {
+		if !verifyPassphrase(config.AppLock.PassphraseHash, passphrase) {
+			return fmt.Errorf("%s 中的启动口令错误", appPassphraseEnv)
+		}
+		return nil
+	}
+	if !stdinIsTerminal() {
+		return fmt.Errorf("已启用启动口令，非交互运行时请通过环境变量 %s 提供口令", appPassphraseEnv)
+	}
+	for attempt := 1; attempt <= passphraseMaxAttempts; attempt++ {
+		if verifyPassphrase(config.AppLock.PassphraseHash, readPassphrase("请输入启动口令: ")) {
+			return nil
+		}
+		printError(fmt.Sprintf("口令错误 (%d/%d)", attempt, passphraseMaxAttempts))
+		time.Sleep(time.Second)
+	}
+	return fmt.Errorf("口令错误次数过多")
+}
+
+// idleLock 空闲超时锁，超过指定时间无操作后进入锁定状态
+type idleLock struct {
+	mutex        sync.Mutex
+	timeout      time.Duration
+	lastActivity time.Time
+	locked       bool
+}
+
+// newIdleLock 创建空闲锁，未启用口令或未设置超时时返回 nil
+func newIdleLock(settings AppLockConfig) *idleLock {
+	if !settings.Enabled() || settings.IdleTimeoutMinutes <= 0 {
+		return nil
+	}
+	return &idleLock{
+		timeout:      time.Duration(settings.IdleTimeoutMinutes) * time.Minute,
+		lastActivity: time.Now(),
+	}
+}
+
+// Touch 记录一次操作，已锁定时返回 false
+func (l *idleLock) Touch() bool {
+	if l == nil {
+		return true
+	}
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if l.locked {
+		return false
+	}
+	l.lastActivity = time.Now()
+	return true
+}
+
+// Expired 检查是否已超时，超时后保持锁定直到 Unlock
+func (l *idleLock) Expired() bool {
+	if l == nil {
+		return false
+	}
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if !l.locked && time.Since(l.lastActivity) > l.timeout {
+		l.locked = true
+	}
+	return l.locked
+}
+
+// Unlock 解除锁定并重新开始计时
+func (l *idleLock) Unlock() {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	l.locked = false
+	l.lastActivity = time.Now()
+}
+
+// runAppLock 管理启动口令
+func runAppLock(config *Config, args []string) error {
+	action := "status"
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		action, args = args[0], args[1:]
+	}
+
+	fs := flag.NewFlagSet("app-lock", flag.ContinueOnError)
+	idle := fs.Int("idle", -1, "空闲多少分钟后重新锁定（0 表示不自动锁定）")
+	if err := fs.Parse(args); err != nil {
+		return usageError(err)
+	}
+
+	switch action {
+	case "status":
+		printHeader("启动口令")
+		if !config.AppLock.Enabled() {
+			printInfo("未启用，使用 app-lock set 设置口令")
+			return nil
+		}
+		printSuccess("已启用")
+		if config.AppLock.IdleTimeoutMinutes > 0 {
+			printInfo(fmt.Sprintf("空闲 %d 分钟后重新锁定", config.AppLock.IdleTimeoutMinutes))
+		} else {
+			printInfo("未设置空闲自动锁定")
+		}
+		return nil
+
+	case "set":
+		passphrase := readPassphrase("新口令: ")
+		if len([]rune(passphrase)) < passphraseMinLength {
+			return fmt.Errorf("口令长度不能少于 %d 个字符", passphraseMinLength)
+		}
+		if readPassphrase("再次输入新口令: ") != passphrase {
+			return fmt.Errorf("两次输入的口令不一致")
+		}
+		hash, err := hashPassphrase(passphrase)
+		if err != nil {
+			return err
+		}
+		config.AppLock.PassphraseHash = hash
+		if *idle >= 0 {
+			config.AppLock.IdleTimeoutMinutes = *idle
+		}
+		saveConfigWithMessage(config, "启动口令已设置")
+		return nil
+
+	case "clear":
+		if !config.AppLock.Enabled() {
+			printInfo("未启用启动口令")
+			return nil
+		}
+		if !confirmAction("确认关闭启动口令") {
+			printInfo("已取消")
+			return nil
+		}
+		// 关闭前须再次校验当前口令，--yes 只跳过确认
+		if err := unlockApp(config); err != nil {
+			return fmt.Errorf("启动口令未关闭: %w", err)
+		}
+		config.AppLock = AppLockConfig{}
+		saveConfigWithMessage(config, "启动口令已关闭")
+		return nil
+
+	default:
+		return fmt.Errorf("未知操作: %s (可用: status, set, clear)", action)
+	}
+}
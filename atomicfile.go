@@ -0,0 +1,167 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// 本地状态文件的上一版本保存在同目录的 <文件名>.bak
+const backupSuffix = ".bak"
+
+// 修改时间早于该时长的临时文件视为崩溃残留，而不是其他进程正在写入
+const staleTempFileAge = time.Minute
+
+// writeFileAtomic 原子替换本地状态文件：先写入同目录的临时文件（tmpPattern，如 .inventory-*.tmp）并落盘，
+// 再把当前文件硬链接为 .bak 保留上一版本，最后用 rename 替换并同步目录。
+// 任一步骤中断时，原文件、.bak 或已落盘的临时文件中至少有一份完整，启动时由 readFileRecovering 恢复
+func writeFileAtomic(path, tmpPattern string, data []byte) error {
+	dir := filepath.Dir(path)
+	tmp, err := os.CreateTemp(dir, tmpPattern)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0600); err != nil {
+		return err
+	}
+
+	if err := backupFile(path); err != nil {
+		return fmt.Errorf("备份 %s 失败: %w", filepath.Base(path), err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return err
+	}
+	syncDir(dir)
+	return nil
+}
+
+// backupFile 把当前文件保留为 .bak；优先使用硬链接，不支持时复制内容
+func backupFile(path string) error {
+	backup := path + backupSuffix
+	if err := os.Remove(backup); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if err := os.Link(path, backup); err == nil || os.IsNotExist(err) {
+		return nil
+	}
+
+	src, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	dst, err := os.OpenFile(backup, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(dst, src); err != nil {
+		dst.Close()
+		return err
+	}
+	if err := dst.Sync(); err != nil {
+		dst.Close()
+		return err
+	}
+	return dst.Close()
+}
+
+// syncDir 同步目录项，确保 rename 在断电后仍然生效（部分系统不支持，忽略错误）
+func syncDir(dir string) {
+	if d, err := os.Open(dir); err == nil {
+		d.Sync()
+		d.Close()
+	}
+}
+
+// readFileRecovering 读取本地状态文件并用 validate 校验。按以下顺序选择第一份完整的内容：
+// 比当前文件更新的崩溃残留临时文件、当前文件、.bak。选中的不是当前文件时写回 path，
+// 并返回恢复来源（文件名）；文件与备份都不存在时返回 os.ErrNotExist
+func readFileRecovering(path, tmpPattern string, validate func([]byte) error) ([]byte, string, error) {
+	data, readErr := os.ReadFile(path)
+	var current time.Time
+	if info, err := os.Stat(path); err == nil {
+		current = info.ModTime()
+	}
+	currentErr := readErr
+	if currentErr == nil {
+		currentErr = validate(data)
+	}
+
+	var candidates []string
+	for _, tmp := range staleTempFiles(path, tmpPattern) {
+		if info, err := os.Stat(tmp); err == nil && (currentErr != nil || info.ModTime().After(current)) {
+			candidates = append(candidates, tmp)
+		}
+	}
+	if currentErr == nil && len(candidates) == 0 {
+		return data, "", nil
+	}
+	if currentErr == nil {
+		candidates = append(candidates, path)
+	}
+	candidates = append(candidates, path+backupSuffix)
+
+	for _, candidate := range candidates {
+		if candidate == path {
+			return data, "", nil
+		}
+		recovered, err := os.ReadFile(candidate)
+		if err != nil || validate(recovered) != nil {
+			continue
+		}
+		if err := writeFileAtomic(path, tmpPattern, recovered); err != nil {
+			return nil, "", fmt.Errorf("从 %s 恢复失败: %w", filepath.Base(candidate), err)
+		}
+		removeStaleTempFiles(path, tmpPattern)
+		return recovered, filepath.Base(candidate), nil
+	}
+
+	if os.IsNotExist(readErr) {
+		return nil, "", os.ErrNotExist
+	}
+	return nil, "", currentErr
+}
+
+// staleTempFiles 崩溃残留的临时文件，按修改时间从新到旧排列
+func staleTempFiles(path, tmpPattern string) []string {
+	matches, _ := filepath.Glob(filepath.Join(filepath.Dir(path), tmpPattern))
+	type tempFile struct {
+		path    string
+		modTime time.Time
+	}
+	var files []tempFile
+	for _, match := range matches {
+		if info, err := os.Stat(match); err == nil && time.Since(info.ModTime()) > staleTempFileAge {
+			files = append(files, tempFile{match, info.ModTime()})
+		}
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].modTime.After(files[j].modTime) })
+	paths := make([]string, len(files))
+	for i, file := range files {
+		paths[i] = file.path
+	}
+	return paths
+}
+
+// removeStaleTempFiles 恢复完成后删除崩溃残留的临时文件
+func removeStaleTempFiles(path, tmpPattern string) {
+	for _, tmp := range staleTempFiles(path, tmpPattern) {
+		os.Remove(tmp)
+	}
+}
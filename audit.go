@@ -0,0 +1,176 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// AuditWebhookConfig 审计 Webhook 配置
+type AuditWebhookConfig struct {
+	URL            string `json:"url"`             // 接收审计事件的地址，为空表示禁用
+	Secret         string `json:"secret"`          // HMAC-SHA256 签名密钥
+	TimeoutSeconds int    `json:"timeout_seconds"` // 单次投递超时
+	MaxRetries     int    `json:"max_retries"`     // 投递失败后的最大重试次数
+}
+
+// AuditEvent 审计事件
+type AuditEvent struct {
+	ID          string `json:"id"`
+	Timestamp   int64  `json:"timestamp"` // Unix 毫秒
+	Action      string `json:"action"`    // create / deactivate / reactivate / delete
+	Actor       string `json:"actor"`     // 发起操作的 API Key 名称
+	RemoteAddr  string `json:"remoteAddr"`
+	AnonymousID string `json:"anonymousId,omitempty"`
+	HME         string `json:"hme,omitempty"`
+	Label       string `json:"label,omitempty"`
+	Success     bool   `json:"success"`
+	Error       string `json:"error,omitempty"`
+}
+
+// AuditWebhook 审计事件投递器，异步发送并签名
+type AuditWebhook struct {
+	settings AuditWebhookConfig
+	client   *http.Client
+	queue    chan AuditEvent
+}
+
+// NewAuditWebhook 创建审计 Webhook，未配置 URL 时返回 nil
+func NewAuditWebhook(settings AuditWebhookConfig) *AuditWebhook {
+	if settings.URL == "" {
+		return nil
+	}
+
+	timeout := settings.TimeoutSeconds
+	if timeout <= 0 {
+		timeout = 10
+	}
+
+	aw := &AuditWebhook{
+		settings: settings,
+		client:   &http.Client{Timeout: time.Duration(timeout) * time.Second},
+		queue:    make(chan AuditEvent, 256),
+	}
+	// 由安全管理器持有，退出时先投递完队列中剩余的事件
+	safetyManager.Go("audit-webhook", aw.run)
+	return aw
+}
+
+// Emit 提交审计事件，队列已满时丢弃并告警，避免阻塞 API 请求
+func (aw *AuditWebhook) Emit(event AuditEvent) {
+	if aw == nil {
+		return
+	}
+
+	if event.ID == "" {
+		event.ID = newEventID()
+	}
+	if event.Timestamp == 0 {
+		event.Timestamp = time.Now().UnixMilli()
+	}
+
+	select {
+	case aw.queue <- event:
+	default:
+		printWarning(fmt.Sprintf("审计队列已满，丢弃事件 %s (%s)", event.ID, event.Action))
+	}
+}
+
+// run 按顺序投递队列中的事件；ctx 取消（程序退出）时把队列中剩余的事件各投递一次后返回
+func (aw *AuditWebhook) run(ctx context.Context) {
+	for {
+		select {
+		case event := <-aw.queue:
+			aw.send(ctx, event)
+		case <-ctx.Done():
+			for {
+				select {
+				case event := <-aw.queue:
+					aw.send(ctx, event)
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+// send 投递事件，失败时告警
+func (aw *AuditWebhook) send(ctx context.Context, event AuditEvent) {
+	if err := aw.deliver(ctx, event); err != nil {
+		printWarning(fmt.Sprintf("审计事件 %s 投递失败: %v", event.ID, err))
+	}
+}
+
+// deliver 投递单个事件（使用指数退避重试）；ctx 取消后不再等待重试，只做当前这一次尝试
+func (aw *AuditWebhook) deliver(ctx context.Context, event AuditEvent) error {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("序列化审计事件失败: %v", err)
+	}
+
+	var lastErr error
+	for i := 0; i <= aw.settings.MaxRetries; i++ {
+		if i > 0 {
+			select {
+			case <-time.After(time.Duration(1<<uint(i-1)) * time.Second):
+			case <-ctx.Done():
+				return lastErr
+			}
+		}
+
+		req, err := http.NewRequest("POST", aw.settings.URL, bytes.NewReader(payload))
+		if err != nil {
+			return fmt.Errorf("创建请求失败: %v", err)
+		}
+
+		timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("User-Agent", "icloud-hme/"+VERSION)
+		req.Header.Set("X-HME-Event-ID", event.ID)
+		req.Header.Set("X-HME-Timestamp", timestamp)
+		if aw.settings.Secret != "" {
+			req.Header.Set("X-HME-Signature", "sha256="+signAuditPayload(aw.settings.Secret, timestamp, payload))
+		}
+
+		resp, err := aw.client.Do(req)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		resp.Body.Close()
+
+		if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+			return nil
+		}
+		lastErr = fmt.Errorf("状态码 %d", resp.StatusCode)
+	}
+
+	return lastErr
+}
+
+// signAuditPayload 计算签名：HMAC-SHA256(secret, timestamp + "." + body)
+func signAuditPayload(secret, timestamp string, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(payload)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// newEventID 生成随机事件ID
+func newEventID() string {
+	buf := make([]byte, 12)
+	if _, err := rand.Read(buf); err != nil {
+		return strconv.FormatInt(time.Now().UnixNano(), 36)
+	}
+	return hex.EncodeToString(buf)
+}
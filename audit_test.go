@@ -0,0 +1,33 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+)
+
+// TestAuditWebhookFlushesOnShutdown 退出时队列中尚未投递的事件仍会发送
+func TestAuditWebhookFlushesOnShutdown(t *testing.T) {
+	var received atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received.Add(1)
+	}))
+	defer server.Close()
+
+	aw := &AuditWebhook{
+		settings: AuditWebhookConfig{URL: server.URL, MaxRetries: 3},
+		client:   server.Client(),
+		queue:    make(chan AuditEvent, 8),
+	}
+	for i := 0; i < 3; i++ {
+		aw.Emit(AuditEvent{Action: "create"})
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	aw.run(ctx)
+	if got := received.Load(); got != 3 {
+		t.Fatalf("投递了 %d 个事件，期望 3 个", got)
+	}
+}
@@ -0,0 +1,218 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"net/mail"
+	"strings"
+	"time"
+)
+
+// 两段式公共后缀，域名以这些结尾时保留三段（如 amazon.co.uk）
+var secondLevelSuffixes = map[string]bool{
+	"co.uk": true, "org.uk": true, "ac.uk": true, "co.jp": true, "ne.jp": true,
+	"com.cn": true, "net.cn": true, "org.cn": true, "com.au": true, "net.au": true,
+	"com.br": true, "com.hk": true, "com.tw": true, "co.kr": true, "co.nz": true, "co.in": true,
+}
+
+// AutoLabelSuggestion 根据首封来信生成的标签建议
+type AutoLabelSuggestion struct {
+	Email      HMEEmail
+	Sender     string
+	Domain     string
+	ReceivedAt time.Time
+}
+
+// isPlaceholderLabel 标签为空或为批量创建产生的序号占位标签
+func isPlaceholderLabel(label string) bool {
+	style := classifyLabelStyle(label)
+	return style == LabelStyleEmpty || style == LabelStyleSequential
+}
+
+// senderDomain 从发件人地址中提取主域名，如 news@mail.acme.co.uk -> acme.co.uk
+func senderDomain(from string) string {
+	address, err := mail.ParseAddress(from)
+	if err != nil {
+		return ""
+	}
+	at := strings.LastIndex(address.Address, "@")
+	if at < 0 {
+		return ""
+	}
+	parts := strings.Split(strings.ToLower(strings.TrimSuffix(address.Address[at+1:], ".")), ".")
+	keep := 2
+	if len(parts) >= 3 && secondLevelSuffixes[strings.Join(parts[len(parts)-2:], ".")] {
+		keep = 3
+	}
+	if len(parts) > keep {
+		parts = parts[len(parts)-keep:]
+	}
+	return strings.Join(parts, ".")
+}
+
+// suggestAutoLabels 为占位标签的邮箱查找首封来信并生成标签建议
+func suggestAutoLabels(settings IMAPConfig, emails []HMEEmail) ([]AutoLabelSuggestion, error) {
+	var candidates []HMEEmail
+	for _, email := range emails {
+		if email.IsActive && isPlaceholderLabel(email.Label) {
+			candidates = append(candidates, email)
+		}
+	}
+	if len(candidates) == 0 {
+		return nil, nil
+	}
+
+	timeout := 30 * time.Second
+	client, err := dialIMAP(settings, timeout)
+	if err != nil {
+		return nil, err
+	}
+	defer client.Close()
+
+	var suggestions []AutoLabelSuggestion
+	for _, email := range candidates {
+		uids, err := client.SearchTo(email.HME, time.Time{}, timeout)
+		if err != nil {
+			return suggestions, fmt.Errorf("搜索 %s 的邮件失败: %v", email.HME, err)
+		}
+		if len(uids) == 0 {
+			continue
+		}
+
+		// UID 递增，最小的即首封来信
+		first := uids[0]
+		for _, uid := range uids {
+			if uid < first {
+				first = uid
+			}
+		}
+		header, err := client.FetchHeader(first, timeout)
+		if err != nil {
+			return suggestions, fmt.Errorf("读取 %s 的邮件失败: %v", email.HME, err)
+		}
+		msg, err := mail.ReadMessage(bytes.NewReader(append(header, '\r', '\n')))
+		if err != nil {
+			continue
+		}
+		domain := senderDomain(msg.Header.Get("From"))
+		if domain == "" {
+			continue
+		}
+
+		suggestion := AutoLabelSuggestion{Email: email, Sender: msg.Header.Get("From"), Domain: domain}
+		if date, err := msg.Header.Date(); err == nil {
+			suggestion.ReceivedAt = date
+		}
+		suggestions = append(suggestions, suggestion)
+	}
+	return suggestions, nil
+}
+
+// applyAutoLabel 将建议写回 iCloud 标签，withNote 时同时在备注中记录首封来信
+func applyAutoLabel(config *Config, suggestion AutoLabelSuggestion, withNote bool) error {
+	note := suggestion.Email.Note
+	if withNote {
+		line := fmt.Sprintf("首封来信: %s", suggestion.Sender)
+		if !suggestion.ReceivedAt.IsZero() {
+			line += " (" + suggestion.ReceivedAt.Local().Format("2006-01-02") + ")"
+		}
+		if note != "" {
+			note += "\n"
+		}
+		note += line
+	}
+	return updateMetaDataHME(config, suggestion.Email.AnonymousID, suggestion.Domain, note)
+}
+
+// autoLabelPass 执行一次自动标注并返回成功数量，供 watch 定时调用
+func autoLabelPass(config *Config, emails []HMEEmail) (int, error) {
+	suggestions, err := suggestAutoLabels(config.IMAP, emails)
+	if err != nil {
+		return 0, err
+	}
+	updated := 0
+	for _, suggestion := range suggestions {
+		if err := applyAutoLabel(config, suggestion, true); err != nil {
+			return updated, fmt.Errorf("更新 %s 失败: %v", suggestion.Email.HME, err)
+		}
+		updated++
+	}
+	return updated, nil
+}
+
+// runAutoLabel 根据首封来信的发件域名为占位标签的邮箱命名
+func runAutoLabel(config *Config, args []string) error {
+	fs := flag.NewFlagSet("auto-label", flag.ContinueOnError)
+	apply := fs.Bool("apply", false, "确认后写回 iCloud 标签")
+	withNote := fs.Bool("note", true, "在备注中记录首封来信的发件人")
+	if err := fs.Parse(args); err != nil {
+		return usageError(err)
+	}
+	if !config.IMAP.Enabled() {
+		return configError("未配置 IMAP，请在 config.json 的 imap 中填写服务器与账号")
+	}
+
+	var emails []HMEEmail
+	if err := withSpinner("获取邮箱列表", func() error {
+		var err error
+		emails, err = listHME(config)
+		return err
+	}); err != nil {
+		return fmt.Errorf("获取列表失败: %w", err)
+	}
+
+	var suggestions []AutoLabelSuggestion
+	if err := withSpinner("查找首封来信", func() error {
+		var err error
+		suggestions, err = suggestAutoLabels(config.IMAP, emails)
+		return err
+	}); err != nil {
+		return err
+	}
+
+	printHeader("根据来信自动标注")
+	if len(suggestions) == 0 {
+		printInfo("没有可自动标注的邮箱（仅处理无标签或 auto-N 等占位标签、且已收到邮件的邮箱）")
+		return nil
+	}
+	for _, suggestion := range suggestions {
+		from := suggestion.Email.Label
+		if from == "" {
+			from = "(无标签)"
+		}
+		fmt.Printf("  %s  %s "+ColorDim+"→"+ColorReset+" "+ColorBrightGreen+"%s"+ColorReset+"\n",
+			suggestion.Email.HME, from, suggestion.Domain)
+		fmt.Printf("    "+ColorDim+"首封来信: %s"+ColorReset+"\n", suggestion.Sender)
+	}
+
+	if !*apply {
+		fmt.Println()
+		printInfo("使用 auto-label -apply 写回以上标签")
+		return nil
+	}
+	if !confirmAction(fmt.Sprintf("确认更新 %d 个邮箱的标签", len(suggestions))) {
+		printInfo("已取消")
+		return nil
+	}
+
+	failCount := 0
+	for i, suggestion := range suggestions {
+		printProgressBar(i, len(suggestions), "更新进度")
+		if err := applyAutoLabel(config, suggestion, *withNote); err != nil {
+			fmt.Printf("\n    "+ColorRed+"[!]"+ColorReset+" %s: %v\n", suggestion.Email.HME, err)
+			failCount++
+		}
+		if i < len(suggestions)-1 {
+			time.Sleep(500 * time.Millisecond)
+		}
+	}
+	printProgressBar(len(suggestions), len(suggestions), "更新进度")
+
+	printSeparator()
+	printSuccess(fmt.Sprintf("已更新 %d 个标签", len(suggestions)-failCount))
+	if failCount > 0 {
+		printError(fmt.Sprintf("失败 %d 个", failCount))
+	}
+	return nil
+}
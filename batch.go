@@ -0,0 +1,496 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// runBatchCommand 命令行批量创建，支持从文件或标准输入读取标签
+func runBatchCommand(config *Config, args []string) error {
+	fs := flag.NewFlagSet("batch", flag.ContinueOnError)
+	count := fs.Int("count", 0, "创建数量（使用 -labels-file 时忽略）")
+	prefix := fs.String("prefix", "", "标签前缀，默认 auto-（随机可读标签时默认为空）")
+	readable := fs.Bool("readable", false, "使用 brave-otter-042 形式的随机可读标签")
+	labelsFile := fs.String("labels-file", "", "标签文件，每行一个标签创建一个邮箱（- 表示标准输入）")
+	duration := fs.Duration("duration", 0, "限定时长（如 2h），在时间内尽可能多地创建；同时指定 -count 时作为数量上限")
+	target := fs.Int("target", 0, "补足到指定数量的激活邮箱（统计标签以 -prefix 开头的邮箱），已达到时不创建")
+	prefixes := fs.String("prefixes", "", "按权重轮换的前缀，如 shop-:3,news-:1,forum-:1")
+	rotate := fs.Bool("rotate", false, "按配置中的 label_prefix_weights 轮换前缀")
+	template := fs.String("template", "", "标签模板，如 shop-{{date:2006-01}}-{{n}}、{{word}}-{{rand:4}}")
+	resume := fs.Bool("resume", false, "继续上次中断的批量任务")
+	manifestPath := fs.String("manifest", "", "按迁移清单（migrate export 导出）重建同名邮箱，并补上备注、网站与标记")
+	if err := fs.Parse(args); err != nil {
+		return usageError(err)
+	}
+	if err := checkCooldown(config); err != nil {
+		return err
+	}
+	if *resume {
+		printHeader("继续批量任务")
+		if err := probeQuota(config); err != nil {
+			return err
+		}
+		emails, errors, err := resumeBatchJob(config)
+		if err != nil {
+			return err
+		}
+		return finishBatchCommand(config, emails, errors)
+	}
+	var weights []PrefixWeight
+	switch {
+	case *prefixes != "":
+		parsed, err := parsePrefixWeights(*prefixes)
+		if err != nil {
+			return usageError(err)
+		}
+		weights = parsed
+	case *rotate:
+		if len(config.LabelPrefixWeights) == 0 {
+			return configError("未配置 label_prefix_weights，可改用 -prefixes 指定")
+		}
+		weights = config.LabelPrefixWeights
+	}
+	if weights != nil && (*target > 0 || *labelsFile != "" || *readable) {
+		return usageError(fmt.Errorf("前缀轮换不能与 -target、-labels-file 或 -readable 同时使用"))
+	}
+	if *template != "" && (weights != nil || *target > 0 || *labelsFile != "" || *readable || *manifestPath != "") {
+		return usageError(fmt.Errorf("-template 不能与其他标签选项、-target 或 -manifest 同时使用"))
+	}
+	if *manifestPath != "" && (weights != nil || *target > 0 || *labelsFile != "" || *readable || *duration > 0) {
+		return usageError(fmt.Errorf("-manifest 不能与其他标签选项、-target 或 -duration 同时使用"))
+	}
+	if *labelsFile == "" && !*readable && strings.TrimSpace(*prefix) == "" {
+		*prefix = "auto-"
+	}
+
+	// 补足模式：只创建目标数量与现有激活邮箱之间的差额，重复执行不会多建
+	var current []HMEEmail
+	highest := 0
+	if *target > 0 {
+		if *labelsFile != "" || *duration > 0 {
+			return usageError(fmt.Errorf("-target 不能与 -labels-file 或 -duration 同时使用"))
+		}
+		if strings.TrimSpace(*prefix) == "" {
+			return usageError(fmt.Errorf("-target 需要用 -prefix 指定统计的标签前缀"))
+		}
+		if err := withSpinner("获取邮箱列表", func() error {
+			var err error
+			current, err = listHME(config)
+			return err
+		}); err != nil {
+			return fmt.Errorf("获取列表失败: %w", err)
+		}
+
+		var active int
+		active, highest = countPoolAliases(current, *prefix)
+		printInfo(fmt.Sprintf("标签以 %s 开头的激活邮箱 %d 个，目标 %d 个", *prefix, active, *target))
+		if active >= *target {
+			printSuccess("已达到目标数量，无需创建")
+			if outputJSON {
+				writeJSON(CLISummary{Op: "batch", Summary: true})
+			}
+			return nil
+		}
+		*count = *target - active
+	}
+
+	var labelFor LabelFunc
+	var labelDesc string
+	var manifest *MigrationManifest
+	switch {
+	case *manifestPath != "":
+		loaded, err := loadManifest(*manifestPath)
+		if err != nil {
+			return err
+		}
+		manifest = loaded
+		if err := withSpinner("获取邮箱列表", func() error {
+			var err error
+			current, err = listHME(config)
+			return err
+		}); err != nil {
+			return fmt.Errorf("获取列表失败: %w", err)
+		}
+		labels := pendingManifestLabels(manifest, current)
+		printInfo(fmt.Sprintf("迁移清单 %d 个邮箱（来自账号 %s），当前账号已有 %d 个同名邮箱", len(manifest.Aliases), manifest.Account, len(manifest.Aliases)-len(labels)))
+		if len(labels) == 0 {
+			printSuccess("清单中的邮箱都已存在，无需创建")
+			applyManifestMetadata(config, manifest)
+			if outputJSON {
+				writeJSON(CLISummary{Op: "batch", Summary: true})
+			}
+			return nil
+		}
+		*count = len(labels)
+		labelFor = listLabels(labels)
+		labelDesc = fmt.Sprintf("迁移清单 %s", *manifestPath)
+	case *labelsFile != "":
+		labels, err := readLabelsFile(*labelsFile)
+		if err != nil {
+			return err
+		}
+		*count = len(labels)
+		labelFor = listLabels(labels)
+		labelDesc = fmt.Sprintf("来自 %s", *labelsFile)
+		if *labelsFile == "-" {
+			labelDesc = "来自标准输入"
+		}
+	case *template != "":
+		generated, err := templateLabels(*template)
+		if err != nil {
+			return usageError(err)
+		}
+		labelFor = generated
+		labelDesc = *template
+	case weights != nil:
+		// 各前缀的序号接在已有最大序号之后，列表获取失败时无法避免重名
+		if err := withSpinner("获取邮箱列表", func() error {
+			var err error
+			current, err = listHME(config)
+			return err
+		}); err != nil {
+			return fmt.Errorf("获取列表失败: %w", err)
+		}
+		labelFor = weightedPrefixLabels(weights, current)
+		labelDesc = describePrefixWeights(weights)
+	case *readable:
+		if current == nil {
+			if err := withSpinner("获取邮箱列表", func() error {
+				var err error
+				current, err = listHME(config)
+				return err
+			}); err != nil {
+				return fmt.Errorf("获取列表失败: %w", err)
+			}
+		}
+		var existing []string
+		for _, email := range current {
+			existing = append(existing, email.Label)
+		}
+		labelFor = readableLabels(*prefix, existing)
+		labelDesc = *prefix + "<形容词>-<名词>-<数字>"
+	default:
+		// 补足模式下序号接在已有同前缀标签之后，避免与现有邮箱重名
+		sequence := sequentialLabels(*prefix)
+		labelFor = func(index int) string { return sequence(highest + index) }
+		labelDesc = *prefix + "*"
+	}
+	if *count <= 0 && *duration <= 0 {
+		return usageError(fmt.Errorf("用法: batch -count 数量 [-prefix 前缀] [-readable]、batch -target 数量 -prefix 前缀、batch -duration 时长 或 batch -labels-file 文件"))
+	}
+
+	printHeader("批量创建邮箱")
+	if *count > 0 {
+		fmt.Printf("  "+ColorCyan+"数量:"+ColorReset+" "+ColorBold+"%d"+ColorReset+" 个\n", *count)
+	}
+	if *duration > 0 {
+		fmt.Printf("  "+ColorCyan+"时长:"+ColorReset+" "+ColorBold+"%s"+ColorReset+"（到 %s 为止）\n", *duration, time.Now().Add(*duration).Format("15:04:05"))
+	}
+	fmt.Printf("  "+ColorCyan+"标签:"+ColorReset+" %s\n", labelDesc)
+	if *labelsFile != "" || manifest != nil || *template != "" {
+		for i := 1; i <= *count && i <= 5; i++ {
+			fmt.Printf("    "+ColorDim+"%d. %s"+ColorReset+"\n", i, labelFor(i))
+		}
+		if *count > 5 {
+			fmt.Printf("    "+ColorDim+"... 共 %d 个"+ColorReset+"\n", *count)
+		}
+	}
+	if *count > 50 {
+		printWarning("建议单次创建不超过 50 个")
+	}
+	// 标签来自标准输入时无法再交互确认
+	if *labelsFile != "-" && !confirmAction("开始批量创建") {
+		printInfo("已取消")
+		return nil
+	}
+	if err := probeQuota(config); err != nil {
+		return err
+	}
+
+	var emails []string
+	var errors []error
+	startBatchReport(labelDesc, *count)
+	if *duration > 0 {
+		emails, errors = batchGenerateUntil(config, time.Now().Add(*duration), *count, labelFor)
+	} else {
+		emails, errors = runTrackedBatch(config, *count, labelDesc, labelFor)
+	}
+	if manifest != nil && len(emails) > 0 && !operationCanceled() {
+		applyManifestMetadata(config, manifest)
+	}
+	return finishBatchCommand(config, emails, errors)
+}
+
+// finishBatchCommand 输出批量结果并按成功/失败情况返回退出码
+func finishBatchCommand(config *Config, emails []string, errors []error) error {
+	printSeparator()
+	if len(emails) > 0 {
+		printSuccess(fmt.Sprintf("批量创建完成 (成功 %d 个)", len(emails)))
+		if config.OutputFile != "" {
+			saveEmailsToFile(emails, config.OutputFile)
+		}
+	}
+	printFailureSummary(errors)
+	finishBatchReport(config, len(emails), errors)
+	announceBatch(config, len(emails), len(errors))
+	if outputJSON {
+		writeJSON(CLISummary{Op: "batch", Summary: true, Succeeded: len(emails), Failed: len(errors), Emails: emails})
+	}
+	switch {
+	case operationCanceled():
+		return withExitCode(ExitPartial, fmt.Errorf("批量创建已停止，成功 %d 个: %w", len(emails), context.Canceled))
+	case len(errors) == 0:
+	case len(emails) > 0:
+		return withExitCode(ExitPartial, fmt.Errorf("批量创建部分失败"))
+	default:
+		// 全部失败时按第一个错误归类（如 Cookie 失效或被限流）
+		return fmt.Errorf("全部 %d 个均失败: %w", len(errors), errors[0])
+	}
+	return nil
+}
+
+// countPoolAliases 统计标签以 prefix 开头（忽略大小写）的激活邮箱数量，并返回该前缀下已用的最大序号
+func countPoolAliases(emails []HMEEmail, prefix string) (active, highest int) {
+	prefix = strings.ToLower(prefix)
+	for _, email := range emails {
+		label := strings.ToLower(strings.TrimSpace(email.Label))
+		if !strings.HasPrefix(label, prefix) {
+			continue
+		}
+		if email.IsActive {
+			active++
+		}
+		if n, err := strconv.Atoi(strings.TrimPrefix(label, prefix)); err == nil && n > highest {
+			highest = n
+		}
+	}
+	return active, highest
+}
+
+// batchGenerateUntil 在截止时间前按 delay_seconds 的节奏串行创建，max 大于 0 时同时作为数量上限。
+// 认证失败时立即停止，其他错误记录后继续
+func batchGenerateUntil(config *Config, deadline time.Time, max int, labelFor LabelFunc) ([]string, []error) {
+	printSubHeader("限时批量创建执行中")
+	start := time.Now()
+	delay := time.Duration(config.DelaySeconds) * time.Second
+	gate := &rateLimitGate{}
+
+	var emails []string
+	var errs []error
+	for i := 1; max <= 0 || i <= max; i++ {
+		if !time.Now().Before(deadline) || operationCanceled() {
+			break
+		}
+
+		label := labelFor(i)
+		remaining := time.Until(deadline).Round(time.Second)
+		fmt.Printf("  "+ColorGray+"..."+ColorReset+" #%d 创建邮箱 "+ColorDim+"(%s，剩余 %s)"+ColorReset+" ... ", i, label, remaining)
+
+		email, err := createHMEWithBackoff(config, label, gate, func(wait time.Duration) bool {
+			// 等待会超过截止时间时不再重试
+			if !time.Now().Add(wait).Before(deadline) {
+				return false
+			}
+			fmt.Printf(ColorYellow+"[~]"+ColorReset+" 被限流，%s 后重试 ... ", wait.Round(time.Second))
+			emitProgressPause("create", max, wait)
+			return true
+		})
+		if errors.Is(err, context.Canceled) {
+			fmt.Print(ColorYellow + "[-]" + ColorReset + " 已取消\n")
+			break
+		}
+		emitProgressItem("create", i, max, label, email, err)
+		currentBatchJob.Record(label, email, err)
+		updateRetryQueue(config, label, email, err)
+		if err != nil {
+			fmt.Print(ColorRed + "[!]" + ColorReset + "\n")
+			fmt.Printf("    错误: %v\n", err)
+			errs = append(errs, err)
+			if exitCodeFor(err) == ExitAuth {
+				printError("认证失败，停止批量创建")
+				break
+			}
+		} else {
+			fmt.Print(ColorGreen + "[+]" + ColorReset + "\n")
+			fmt.Printf("    "+ColorCyan+"邮箱:"+ColorReset+" %s\n", email)
+			emails = append(emails, email)
+
+			if err := saveEmailToFile(config, email, label, CreationOrigin{Source: SourceBatch}); err != nil {
+				fmt.Printf("    "+ColorYellow+"警告:"+ColorReset+" 保存到文件失败: %v\n", err)
+			}
+		}
+
+		// 等待结束时已超过截止时间则直接结束
+		if delay > 0 && (max <= 0 || i < max) {
+			if !time.Now().Add(delay).Before(deadline) {
+				break
+			}
+			emitProgressPause("create", max, delay)
+			if !sleepUnlessCanceled(delay) {
+				break
+			}
+		}
+	}
+
+	reportBatchCanceled(len(emails)+len(errs), max)
+	elapsed := time.Since(start).Round(time.Second)
+	fmt.Println()
+	printInfo(fmt.Sprintf("用时 %s，成功 %d 个，失败 %d 个", elapsed, len(emails), len(errs)))
+	emitProgressDone("create", len(emails)+len(errs), len(emails), len(errs))
+	return emails, errs
+}
+
+// 分段创建时连续失败（非限流）达到该次数即停止
+const maxChunkFailures = 5
+
+// batchGenerateChunked 分段串行创建：每成功 batch_chunk_size 个暂停一段时间，直到成功数量达到 count。
+// 失败的标签会在下一次尝试中重试；被限流时提前进入暂停，认证失败或连续失败过多时停止
+func batchGenerateChunked(config *Config, count int, labelFor LabelFunc) ([]string, []error) {
+	chunkSize := config.BatchChunkSize
+	pause := time.Duration(config.BatchChunkPauseMinutes) * time.Minute
+	delay := time.Duration(config.DelaySeconds) * time.Second
+	chunks := (count + chunkSize - 1) / chunkSize
+	fmt.Printf("  "+ColorCyan+"分段:"+ColorReset+" 每 %d 个暂停 %s，共 %d 段\n\n", chunkSize, pause, chunks)
+
+	gate := &rateLimitGate{}
+	var emails []string
+	// 失败的序号会用同一标签重试，只有最后一次尝试仍失败的标签才计入结果
+	var lastErr error
+	inChunk, failures, attempt, failedAttempts := 0, 0, 0, 0
+	for len(emails) < count && !operationCanceled() {
+		index := len(emails) + 1
+		label := labelFor(index)
+		attempt++
+		fmt.Printf("  "+ColorGray+"..."+ColorReset+" [%d/%d] 创建邮箱 "+ColorDim+"(%s)"+ColorReset+" ... ", index, count, label)
+
+		email, err := createHMEWithBackoff(config, label, gate, func(wait time.Duration) bool {
+			fmt.Printf(ColorYellow+"[~]"+ColorReset+" 被限流，%s 后重试 ... ", wait.Round(time.Second))
+			emitProgressPause("create", count, wait)
+			return true
+		})
+		if errors.Is(err, context.Canceled) {
+			fmt.Print(ColorYellow + "[-]" + ColorReset + " 已取消\n")
+			break
+		}
+		emitProgressItem("create", index, count, label, email, err)
+		currentBatchJob.Record(label, email, err)
+		updateRetryQueue(config, label, email, err)
+
+		limited := false
+		if err != nil {
+			fmt.Print(ColorRed + "[!]" + ColorReset + "\n")
+			fmt.Printf("    错误: %v\n", err)
+			lastErr = err
+			failedAttempts++
+			if exitCodeFor(err) == ExitAuth {
+				printError("认证失败，停止批量创建")
+				break
+			}
+			_, limited = retryAfterFor(err)
+			if !limited {
+				failures++
+				if failures >= maxChunkFailures {
+					printError(fmt.Sprintf("连续失败 %d 次，停止批量创建", failures))
+					break
+				}
+			}
+		} else {
+			fmt.Print(ColorGreen + "[+]" + ColorReset + "\n")
+			fmt.Printf("    "+ColorCyan+"邮箱:"+ColorReset+" %s\n", email)
+			emails = append(emails, email)
+			inChunk++
+			failures = 0
+			lastErr = nil
+
+			if err := saveEmailToFile(config, email, label, CreationOrigin{Source: SourceBatch}); err != nil {
+				fmt.Printf("    "+ColorYellow+"警告:"+ColorReset+" 保存到文件失败: %v\n", err)
+			}
+		}
+		if len(emails) >= count {
+			break
+		}
+
+		// 本段已满或仍被限流时进入长暂停，否则按 delay_seconds 间隔
+		if inChunk >= chunkSize || limited {
+			fmt.Println()
+			printInfo(fmt.Sprintf("已完成 %d/%d，暂停 %s 后继续", len(emails), count, pause))
+			emitProgressPause("create", count, pause)
+			if !waitWithCountdown(pause, "下一段开始") {
+				break
+			}
+			inChunk = 0
+		} else if delay > 0 {
+			emitProgressPause("create", count, delay)
+			if !sleepUnlessCanceled(delay) {
+				break
+			}
+		}
+	}
+
+	var errs []error
+	if lastErr != nil {
+		errs = append(errs, lastErr)
+	}
+	reportBatchCanceled(len(emails), count)
+	fmt.Println()
+	printInfo(fmt.Sprintf("共尝试 %d 次，成功 %d 个，失败 %d 次", attempt, len(emails), failedAttempts))
+	emitProgressDone("create", count, len(emails), len(errs))
+	return emails, errs
+}
+
+// waitWithCountdown 等待指定时间，同时在同一行显示剩余时间；收到退出信号时提前结束并返回 false
+func waitWithCountdown(wait time.Duration, message string) bool {
+	deadline := time.Now().Add(wait)
+	if plainUI {
+		fmt.Printf("  [~] %s倒计时 %s (预计 %s 继续)\n", message, wait.Round(time.Second), deadline.Format("15:04:05"))
+		return sleepUnlessCanceled(wait)
+	}
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	defer fmt.Print("\r\033[K")
+	for {
+		remaining := time.Until(deadline)
+		if remaining <= 0 {
+			return true
+		}
+		minutes := int(remaining.Round(time.Second).Seconds()) / 60
+		seconds := int(remaining.Round(time.Second).Seconds()) % 60
+		fmt.Printf("\r  "+ColorYellow+"[~]"+ColorReset+" %s倒计时 "+ColorBold+"%02d:%02d"+ColorReset+" "+ColorDim+"(预计 %s 继续)"+ColorReset+"   ",
+			message, minutes, seconds, deadline.Format("15:04:05"))
+		select {
+		case <-ticker.C:
+		case <-apiContext().Done():
+			return false
+		}
+	}
+}
+
+// sleepUnlessCanceled 等待 wait，收到退出信号时提前返回 false
+func sleepUnlessCanceled(wait time.Duration) bool {
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-apiContext().Done():
+		return false
+	}
+}
+
+// reportBatchCanceled 批量循环因退出信号停止时提示已完成的数量，随后照常输出已完成部分的汇总
+func reportBatchCanceled(done, total int) {
+	if !operationCanceled() {
+		return
+	}
+	fmt.Println()
+	if total > 0 {
+		printWarning(fmt.Sprintf("已停止：收到退出信号，完成 %d/%d", done, total))
+	} else {
+		printWarning(fmt.Sprintf("已停止：收到退出信号，完成 %d 个", done))
+	}
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"sync/atomic"
+	"testing"
+)
+
+// TestBatchChunkedRetrySucceeds 分段批量中被限流后重试成功的标签不计为失败，全部创建成功时正常退出
+func TestBatchChunkedRetrySucceeds(t *testing.T) {
+	mock := newMockServer(MockFaultConfig{Seed: 1})
+	mock.quiet = true
+	var limited atomic.Bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// 第一次 reserve 返回 429，之后照常处理
+		if path.Base(r.URL.Path) == "reserve" && limited.CompareAndSwap(false, true) {
+			w.Header().Set("Content-Type", "application/json")
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusTooManyRequests)
+			w.Write([]byte(`{"success":false,"error":{"errorCode":"-41015","errorMessage":"rate limited"}}`))
+			return
+		}
+		mock.handler().ServeHTTP(w, r)
+	}))
+	defer server.Close()
+
+	config := newTestConfig(t, server.URL+"/v1/hme/reserve")
+	config.BatchChunkSize = 2
+	config.BatchChunkPauseMinutes = 0
+	config.RetryPolicy.RateLimitRetries = 0
+	saved := inventory
+	inventory = nil
+	defer func() { inventory = saved }()
+	configMutex.Lock()
+	savedConfig := globalConfig
+	globalConfig = config
+	configMutex.Unlock()
+	defer func() {
+		configMutex.Lock()
+		globalConfig = savedConfig
+		configMutex.Unlock()
+	}()
+
+	const count = 3
+	emails, errs := batchGenerateChunked(config, count, func(index int) string {
+		return fmt.Sprintf("chunk-%d", index)
+	})
+	if !limited.Load() {
+		t.Fatal("没有触发限流")
+	}
+	if len(emails) != count || len(errs) != 0 {
+		t.Fatalf("成功 %d 个、失败 %v，期望成功 %d 个且没有失败", len(emails), errs, count)
+	}
+	if err := finishBatchCommand(config, emails, errs); exitCodeFor(err) != ExitOK {
+		t.Fatalf("退出码 %d（%v），期望 %d", exitCodeFor(err), err, ExitOK)
+	}
+}
@@ -0,0 +1,209 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// BatchJob 批量创建任务的断点记录，中断或崩溃后可从未完成的标签继续
+type BatchJob struct {
+	ID        string         `json:"id"`
+	Total     int            `json:"total"`
+	Labels    []string       `json:"labels"` // 计划创建的全部标签，开始时一次性生成
+	Completed []BatchJobItem `json:"completed"`
+	LastError string         `json:"last_error,omitempty"`
+	StartedAt int64          `json:"started_at"` // Unix 毫秒
+	UpdatedAt int64          `json:"updated_at"`
+	Finished  bool           `json:"finished"`
+
+	path  string
+	mutex sync.Mutex
+}
+
+// BatchJobItem 已成功创建的一项
+type BatchJobItem struct {
+	Label string `json:"label"`
+	Email string `json:"email"`
+	At    int64  `json:"at"`
+}
+
+// currentBatchJob 正在执行的批量任务，batchGenerate 每完成一项都会写入断点；nil 表示不记录
+var currentBatchJob *BatchJob
+
+// startBatchJob 生成全部标签并写入新的任务文件，覆盖之前的任务
+func startBatchJob(path string, count int, labelFor LabelFunc) (*BatchJob, error) {
+	now := time.Now()
+	job := &BatchJob{
+		ID:        now.Format("20060102-150405"),
+		Total:     count,
+		Labels:    make([]string, count),
+		Completed: []BatchJobItem{},
+		StartedAt: now.UnixMilli(),
+		UpdatedAt: now.UnixMilli(),
+		path:      path,
+	}
+	for i := range job.Labels {
+		job.Labels[i] = labelFor(i + 1)
+	}
+	if err := job.save(); err != nil {
+		return nil, err
+	}
+	return job, nil
+}
+
+// 任务文件写入时使用的临时文件名
+const batchJobTempPattern = ".batch-job-*.tmp"
+
+// loadBatchJob 读取任务文件，文件不存在时返回 nil；文件损坏时自动恢复
+func loadBatchJob(path string) (*BatchJob, error) {
+	if path == "" {
+		return nil, nil
+	}
+	data, recoveredFrom, err := readFileRecovering(path, batchJobTempPattern, batchJobFormat.validator(func() interface{} {
+		return &BatchJob{}
+	}))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("读取批量任务失败: %v", err)
+	}
+	if recoveredFrom != "" {
+		printWarning(fmt.Sprintf("批量任务 %s 已损坏或未写完，已从 %s 恢复", path, recoveredFrom))
+	}
+	var job BatchJob
+	if err := batchJobFormat.decode(data, &job); err != nil {
+		return nil, fmt.Errorf("解析批量任务失败: %v", err)
+	}
+	job.path = path
+	return &job, nil
+}
+
+// unfinishedBatchJob 返回可继续的任务，没有时返回 nil
+func unfinishedBatchJob(config *Config) *BatchJob {
+	job, err := loadBatchJob(config.BatchJobFile)
+	if err != nil || job == nil || job.Finished || len(job.Remaining()) == 0 {
+		return nil
+	}
+	return job
+}
+
+// Remaining 尚未成功创建的标签（保持原顺序）
+func (j *BatchJob) Remaining() []string {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+
+	done := make(map[string]bool, len(j.Completed))
+	for _, item := range j.Completed {
+		done[item.Label] = true
+	}
+	var remaining []string
+	for _, label := range j.Labels {
+		if !done[label] {
+			remaining = append(remaining, label)
+		}
+	}
+	return remaining
+}
+
+// Done 已完成数量
+func (j *BatchJob) Done() int {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+	return len(j.Completed)
+}
+
+// Record 记录一项结果并立即写入断点
+func (j *BatchJob) Record(label, email string, err error) {
+	if j == nil {
+		return
+	}
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+
+	if err != nil {
+		j.LastError = fmt.Sprintf("%s: %v", label, err)
+	} else {
+		j.Completed = append(j.Completed, BatchJobItem{Label: label, Email: email, At: time.Now().UnixMilli()})
+	}
+	if saveErr := j.saveLocked(); saveErr != nil {
+		printWarning(fmt.Sprintf("保存批量任务断点失败: %v", saveErr))
+	}
+}
+
+// Finish 本轮执行结束，全部标签都已创建时标记为完成
+func (j *BatchJob) Finish() {
+	if j == nil {
+		return
+	}
+	remaining := len(j.Remaining())
+
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+	j.Finished = remaining == 0
+	if err := j.saveLocked(); err != nil {
+		printWarning(fmt.Sprintf("保存批量任务断点失败: %v", err))
+	}
+	if remaining > 0 {
+		printInfo(fmt.Sprintf("还有 %d 个未创建，可用菜单 [r] 或 batch -resume 继续", remaining))
+	}
+}
+
+func (j *BatchJob) save() error {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+	return j.saveLocked()
+}
+
+// saveLocked 原子写入任务文件（调用方需持有锁）
+func (j *BatchJob) saveLocked() error {
+	j.UpdatedAt = time.Now().UnixMilli()
+	if err := batchJobFormat.write(j.path, batchJobTempPattern, j); err != nil {
+		return fmt.Errorf("写入批量任务失败: %v", err)
+	}
+	return nil
+}
+
+// runTrackedBatch 带断点记录地执行批量创建；任务文件不可用时退化为普通批量创建
+func runTrackedBatch(config *Config, count int, labelDesc string, labelFor LabelFunc) ([]string, []error) {
+	if config.BatchJobFile == "" {
+		return batchGenerate(config, count, labelDesc, labelFor)
+	}
+	job, err := startBatchJob(config.BatchJobFile, count, labelFor)
+	if err != nil {
+		printWarning(fmt.Sprintf("无法记录批量任务断点: %v", err))
+		return batchGenerate(config, count, labelDesc, labelFor)
+	}
+	return runBatchJob(config, job, labelDesc)
+}
+
+// resumeBatchJob 继续上次未完成的批量任务
+func resumeBatchJob(config *Config) ([]string, []error, error) {
+	job := unfinishedBatchJob(config)
+	if job == nil {
+		return nil, nil, fmt.Errorf("没有未完成的批量任务")
+	}
+	remaining := len(job.Remaining())
+	printInfo(fmt.Sprintf("继续任务 %s：已完成 %d/%d，剩余 %d 个", job.ID, job.Done(), job.Total, remaining))
+	if job.LastError != "" {
+		fmt.Printf("    "+ColorDim+"上次错误: %s"+ColorReset+"\n", job.LastError)
+	}
+	labelDesc := fmt.Sprintf("继续任务 %s", job.ID)
+	startBatchReport(labelDesc, remaining)
+	emails, errs := runBatchJob(config, job, labelDesc)
+	return emails, errs, nil
+}
+
+// runBatchJob 按任务中剩余的标签执行批量创建
+func runBatchJob(config *Config, job *BatchJob, labelDesc string) ([]string, []error) {
+	remaining := job.Remaining()
+	currentBatchJob = job
+	defer func() { currentBatchJob = nil }()
+
+	emails, errs := batchGenerate(config, len(remaining), labelDesc, listLabels(remaining))
+	job.Finish()
+	return emails, errs
+}
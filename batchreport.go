@@ -0,0 +1,155 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// BatchReport 一次批量创建的运行记录，结束后生成 Markdown 摘要，便于贴到 issue 或个人日志
+type BatchReport struct {
+	LabelDesc  string
+	Planned    int // 计划数量，限时模式不限数量时为 0
+	StartedAt  time.Time
+	FinishedAt time.Time
+	Items      []BatchReportItem
+	Pauses     int           // 限速/限流等待次数
+	Paused     time.Duration // 等待总时长
+
+	mutex sync.Mutex
+}
+
+// BatchReportItem 时间线中的一项
+type BatchReportItem struct {
+	At    time.Time
+	Label string
+	Email string
+	Error string
+}
+
+// activeBatchReport 正在记录的批量运行，由进度事件填充；nil 表示不记录
+var (
+	activeBatchReport *BatchReport
+	batchReportMutex  sync.Mutex
+)
+
+// startBatchReport 开始记录一次批量创建
+func startBatchReport(labelDesc string, planned int) {
+	batchReportMutex.Lock()
+	defer batchReportMutex.Unlock()
+	activeBatchReport = &BatchReport{LabelDesc: labelDesc, Planned: planned, StartedAt: time.Now()}
+}
+
+// recordBatchReport 记录批量创建的进度事件
+func recordBatchReport(event ProgressEvent) {
+	batchReportMutex.Lock()
+	report := activeBatchReport
+	batchReportMutex.Unlock()
+	if report == nil || event.Op != "create" {
+		return
+	}
+
+	report.mutex.Lock()
+	defer report.mutex.Unlock()
+	switch event.Event {
+	case "item":
+		report.Items = append(report.Items, BatchReportItem{At: time.Now(), Label: event.Label, Email: event.Email, Error: event.Error})
+	case "pause":
+		report.Pauses++
+		report.Paused += time.Duration(event.Seconds * float64(time.Second))
+	}
+}
+
+// finishBatchReport 结束记录并在输出文件旁生成摘要，未开始记录时不做任何事
+func finishBatchReport(config *Config, succeeded int, errs []error) {
+	batchReportMutex.Lock()
+	report := activeBatchReport
+	activeBatchReport = nil
+	batchReportMutex.Unlock()
+	if report == nil {
+		return
+	}
+	report.FinishedAt = time.Now()
+
+	dir := "."
+	if config.OutputFile != "" {
+		dir = filepath.Dir(config.OutputFile)
+	}
+	path := filepath.Join(dir, "batch-report-"+report.StartedAt.Format("20060102-150405")+".md")
+	if err := os.WriteFile(path, []byte(report.Markdown(config, succeeded, errs)), 0600); err != nil {
+		printWarning(fmt.Sprintf("保存运行摘要失败: %v", err))
+		return
+	}
+	printInfo(fmt.Sprintf("运行摘要已保存到 %s", path))
+}
+
+// Markdown 生成摘要。邮箱地址已打码，不包含 Cookie、DSID 等账号信息
+func (r *BatchReport) Markdown(config *Config, succeeded int, errs []error) string {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "## iCloud HME 批量创建摘要 (%s)\n\n", r.StartedAt.Format("2006-01-02 15:04"))
+
+	planned := "不限"
+	if r.Planned > 0 {
+		planned = fmt.Sprintf("%d", r.Planned)
+	}
+	fmt.Fprintf(&b, "- **结果**: 成功 %d，失败 %d，计划 %s\n", succeeded, len(errs), planned)
+	fmt.Fprintf(&b, "- **时间**: %s → %s（用时 %s）\n", r.StartedAt.Format("15:04:05"), r.FinishedAt.Format("15:04:05"), r.FinishedAt.Sub(r.StartedAt).Round(time.Second))
+	if r.Pauses > 0 {
+		fmt.Fprintf(&b, "- **等待**: %d 次，共 %s\n", r.Pauses, r.Paused.Round(time.Second))
+	}
+
+	b.WriteString("\n### 设置\n\n| 项目 | 值 |\n| --- | --- |\n")
+	command := "交互菜单"
+	if len(os.Args) > 1 {
+		command = strings.Join(os.Args[1:], " ")
+	}
+	fmt.Fprintf(&b, "| 命令 | `%s` |\n", markdownCell(command))
+	fmt.Fprintf(&b, "| 标签 | %s |\n", markdownCell(r.LabelDesc))
+	fmt.Fprintf(&b, "| 并发 | %d |\n", max(config.MaxConcurrency, 1))
+	fmt.Fprintf(&b, "| 请求间隔 | %d 秒 |\n", config.DelaySeconds)
+	if config.RequestsPerMinute > 0 {
+		fmt.Fprintf(&b, "| 全局限速 | 每分钟 %d 次请求（抖动 %d%%） |\n", config.RequestsPerMinute, config.RequestJitterPercent)
+	}
+	if config.LatencySLO.ReserveWarnMS > 0 {
+		fmt.Fprintf(&b, "| 耗时监控 | reserve 中位耗时超过 %d ms 时警告，放慢 %d 秒 |\n", config.LatencySLO.ReserveWarnMS, config.LatencySLO.SlowdownSeconds)
+	}
+	if config.BatchChunkSize > 0 {
+		fmt.Fprintf(&b, "| 分段 | 每 %d 个暂停 %d 分钟 |\n", config.BatchChunkSize, config.BatchChunkPauseMinutes)
+	} else {
+		b.WriteString("| 分段 | 未启用 |\n")
+	}
+	fmt.Fprintf(&b, "| 版本 | %s |\n", VERSION)
+
+	if len(r.Items) > 0 {
+		b.WriteString("\n### 时间线\n\n| # | 时间 | 标签 | 结果 |\n| --- | --- | --- | --- |\n")
+		for i, item := range r.Items {
+			result := "✓ " + maskEmailAddress(item.Email)
+			if item.Error != "" {
+				result = "✗ " + item.Error
+			}
+			fmt.Fprintf(&b, "| %d | %s | %s | %s |\n", i+1, item.At.Format("15:04:05"), markdownCell(item.Label), markdownCell(result))
+		}
+	}
+
+	if len(errs) > 0 {
+		groups := groupFailures(errs)
+		b.WriteString("\n### 失败原因\n\n")
+		for _, g := range groups {
+			fmt.Fprintf(&b, "- %s × %d，例如 `%s`\n", g.Class.Name, g.Count, markdownCell(g.Sample))
+		}
+		fmt.Fprintf(&b, "\n建议: %s\n", failureAdvice(groups).Advice)
+	}
+	return b.String()
+}
+
+// markdownCell 转义表格单元格中的竖线并去掉换行
+func markdownCell(s string) string {
+	s = strings.ReplaceAll(s, "|", "\\|")
+	return strings.Join(strings.Fields(s), " ")
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+)
+
+// blockRule 一条屏蔽规则：子串（不区分大小写）或用 /.../ 包起来的正则表达式
+type blockRule struct {
+	text string // 配置中的原文，用于显示
+	sub  string
+	re   *regexp.Regexp
+}
+
+// parseBlocklist 解析 email_quality.blocklist，如 ["spam", "zhang", "/[0-9]{4,}/"]
+func parseBlocklist(entries []string) ([]blockRule, error) {
+	var rules []blockRule
+	for _, entry := range entries {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		rule := blockRule{text: entry}
+		if len(entry) > 2 && strings.HasPrefix(entry, "/") && strings.HasSuffix(entry, "/") {
+			re, err := regexp.Compile("(?i)" + entry[1:len(entry)-1])
+			if err != nil {
+				return nil, configError("email_quality.blocklist 中的 %s 不是有效的正则表达式: %v", entry, err)
+			}
+			rule.re = re
+		} else {
+			rule.sub = strings.ToLower(entry)
+		}
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+// blockedBy 前缀命中的第一条屏蔽规则，没有命中时返回空字符串
+func blockedBy(rules []blockRule, prefix string) string {
+	lower := strings.ToLower(prefix)
+	for _, rule := range rules {
+		if (rule.re != nil && rule.re.MatchString(prefix)) || (rule.re == nil && strings.Contains(lower, rule.sub)) {
+			return rule.text
+		}
+	}
+	return ""
+}
@@ -18,7 +18,7 @@ NC='\033[0m' # No Color
 
 # 变量定义
 BINARY_NAME="icloud-hme"
-MAIN_FILE="main.go"
+MAIN_FILE="."
 BUILD_DIR="build"
 VERSION=$(git describe --tags --always --dirty 2>/dev/null || echo "v2.1.0")
 
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"sync"
+	"time"
+)
+
+// 邮箱列表缓存命中状态
+const (
+	CacheHit   = "HIT"   // 缓存新鲜，直接返回
+	CacheStale = "STALE" // 缓存过期，先返回旧数据并在后台刷新
+	CacheMiss  = "MISS"  // 无可用缓存，同步拉取
+)
+
+// EmailListSnapshot 某一时刻的邮箱列表快照
+type EmailListSnapshot struct {
+	Emails     []HMEEmail
+	FetchedAt  time.Time // 最近一次从 Apple 拉取的时间
+	ModifiedAt time.Time // 列表内容最近一次发生变化的时间
+	Status     string
+}
+
+// EmailListCache 邮箱列表的读穿缓存
+type EmailListCache struct {
+	fetch func() ([]HMEEmail, error)
+	ttl   time.Duration
+
+	mutex      sync.Mutex
+	emails     []HMEEmail
+	digest     [sha256.Size]byte
+	fetchedAt  time.Time
+	modifiedAt time.Time
+	dirty      bool
+	generation uint64     // Invalidate 时递增，早于当前代开始的拉取结果一律丢弃
+	inflight   *listFetch // 正在进行的拉取，并发的调用方共用同一次请求
+}
+
+// listFetch 一次进行中的列表拉取
+type listFetch struct {
+	generation uint64
+	done       chan struct{}
+	applied    bool // 结果是否已写入缓存（拉取期间缓存被标记失效时为 false）
+	err        error
+}
+
+// NewEmailListCache 创建邮箱列表缓存
+func NewEmailListCache(ttl time.Duration, fetch func() ([]HMEEmail, error)) *EmailListCache {
+	return &EmailListCache{fetch: fetch, ttl: ttl}
+}
+
+// Get 获取邮箱列表；maxAge >= 0 时表示调用方能接受的最大缓存年龄，超出则同步刷新
+func (c *EmailListCache) Get(maxAge time.Duration) (*EmailListSnapshot, error) {
+	c.mutex.Lock()
+	age := time.Since(c.fetchedAt)
+	hasData := !c.fetchedAt.IsZero() && !c.dirty
+
+	if hasData && (maxAge < 0 || age <= maxAge) {
+		status := CacheHit
+		if age > c.ttl {
+			status = CacheStale
+			c.refreshAsync()
+		}
+		snapshot := c.snapshot(status)
+		c.mutex.Unlock()
+		return snapshot, nil
+	}
+	c.mutex.Unlock()
+
+	if err := c.Refresh(); err != nil {
+		return nil, err
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.snapshot(CacheMiss), nil
+}
+
+// Refresh 同步从 Apple 拉取最新列表。已有同一代的拉取在进行时等待它的结果而不重复请求；
+// 拉取期间缓存被标记失效（如刚创建了邮箱）时结果作废，重新拉取
+func (c *EmailListCache) Refresh() error {
+	for {
+		c.mutex.Lock()
+		flight := c.inflight
+		if flight == nil || flight.generation != c.generation {
+			flight = c.startFetch()
+		}
+		c.mutex.Unlock()
+
+		<-flight.done
+		if flight.err != nil {
+			return flight.err
+		}
+		if flight.applied {
+			return nil
+		}
+	}
+}
+
+// startFetch 按当前代开始一次拉取（调用方需持有锁）
+func (c *EmailListCache) startFetch() *listFetch {
+	flight := &listFetch{generation: c.generation, done: make(chan struct{})}
+	c.inflight = flight
+
+	go func() {
+		emails, err := c.fetch()
+		var digest [sha256.Size]byte
+		if err == nil {
+			data, _ := json.Marshal(emails)
+			digest = sha256.Sum256(data)
+		}
+
+		c.mutex.Lock()
+		defer c.mutex.Unlock()
+		defer close(flight.done)
+		if c.inflight == flight {
+			c.inflight = nil
+		}
+		flight.err = err
+		if err != nil || flight.generation != c.generation {
+			return
+		}
+
+		now := time.Now()
+		if digest != c.digest || c.modifiedAt.IsZero() {
+			c.modifiedAt = now
+			c.digest = digest
+		}
+		c.emails = emails
+		c.fetchedAt = now
+		c.dirty = false
+		flight.applied = true
+	}()
+	return flight
+}
+
+// Invalidate 标记缓存失效，下次读取时同步刷新；进行中的拉取可能早于这次变更，其结果将被丢弃
+func (c *EmailListCache) Invalidate() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.dirty = true
+	c.generation++
+}
+
+// refreshAsync 在后台刷新缓存，已有拉取进行时不再发起（调用方需持有锁）
+func (c *EmailListCache) refreshAsync() {
+	if c.inflight != nil {
+		return
+	}
+	c.startFetch()
+
+	safetyManager.Go("list-refresh", func(ctx context.Context) {
+		if err := c.Refresh(); err != nil && ctx.Err() == nil {
+			printWarning("后台刷新邮箱列表失败: " + err.Error())
+		}
+	})
+}
+
+// snapshot 复制当前缓存内容（调用方需持有锁）
+func (c *EmailListCache) snapshot(status string) *EmailListSnapshot {
+	emails := make([]HMEEmail, len(c.emails))
+	copy(emails, c.emails)
+	return &EmailListSnapshot{
+		Emails:     emails,
+		FetchedAt:  c.fetchedAt,
+		ModifiedAt: c.modifiedAt,
+		Status:     status,
+	}
+}
@@ -0,0 +1,207 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// 设置了 pattern / reject_pattern / blocklist 或检查相似邮箱时的默认生成预算
+const (
+	defaultPatternMaxTries = 20
+	defaultPatternTimeout  = 60 * time.Second
+)
+
+// candidateFilter 智能创建对地址前缀（@ 之前的部分）的格式要求
+type candidateFilter struct {
+	match    *regexp.Regexp // 前缀必须匹配
+	reject   *regexp.Regexp // 前缀不能匹配
+	blocked  []blockRule    // 屏蔽词，命中时直接丢弃
+	aliases  []string       // 已有邮箱的前缀，用于检查相似候选
+	distance int            // 与已有前缀的编辑距离小于此值视为相似
+	penalize bool           // 相似候选扣分而不是丢弃
+	maxTries int
+	timeout  time.Duration
+
+	rejected map[string]int // 按原因统计被拒绝的候选
+}
+
+// newCandidateFilter 按 email_quality 编译格式要求并读取已有邮箱的前缀，
+// 未设置 pattern、reject_pattern 与 blocklist 且不需要检查相似邮箱时返回 nil
+func newCandidateFilter(quality EmailQualityConfig) (*candidateFilter, error) {
+	blocked, err := parseBlocklist(quality.Blocklist)
+	if err != nil {
+		return nil, err
+	}
+	var aliases []string
+	distance := quality.SimilarDistance
+	if distance == 0 {
+		distance = defaultSimilarDistance
+	}
+	switch quality.SimilarAction {
+	case "", SimilarActionReject, SimilarActionPenalize:
+	default:
+		return nil, configError("email_quality.similar_action 只能是 %s 或 %s", SimilarActionReject, SimilarActionPenalize)
+	}
+	if distance > 0 {
+		aliases = aliasPrefixes()
+	}
+	if quality.Pattern == "" && quality.RejectPattern == "" && len(blocked) == 0 && len(aliases) == 0 {
+		return nil, nil
+	}
+	filter := &candidateFilter{blocked: blocked, aliases: aliases, distance: distance, penalize: quality.SimilarAction == SimilarActionPenalize, maxTries: quality.PatternMaxTries, timeout: time.Duration(quality.PatternTimeoutSeconds) * time.Second, rejected: make(map[string]int)}
+	if quality.Pattern != "" {
+		if filter.match, err = regexp.Compile(quality.Pattern); err != nil {
+			return nil, configError("email_quality.pattern 不是有效的正则表达式: %v", err)
+		}
+	}
+	if quality.RejectPattern != "" {
+		if filter.reject, err = regexp.Compile(quality.RejectPattern); err != nil {
+			return nil, configError("email_quality.reject_pattern 不是有效的正则表达式: %v", err)
+		}
+	}
+	if filter.maxTries <= 0 {
+		filter.maxTries = defaultPatternMaxTries
+	}
+	if filter.timeout <= 0 {
+		filter.timeout = defaultPatternTimeout
+	}
+	return filter, nil
+}
+
+// Check 检查候选地址，符合要求时返回空字符串，否则返回原因并计数
+func (f *candidateFilter) Check(email string) string {
+	if f == nil {
+		return ""
+	}
+	prefix := emailPrefix(email)
+	reason, key := "", ""
+	switch rule := blockedBy(f.blocked, prefix); {
+	case rule != "":
+		reason = "包含屏蔽词 " + rule
+	case !f.penalize && f.similarTo(prefix) != "":
+		// 汇总时按一类统计，不逐个列出相似的已有邮箱
+		reason, key = f.similarTo(prefix), "与已有邮箱重名或相似"
+	case f.match != nil && !f.match.MatchString(prefix):
+		reason = "不匹配 " + f.match.String()
+	case f.reject != nil && f.reject.MatchString(prefix):
+		reason = "匹配了排除规则 " + f.reject.String()
+	}
+	if key == "" {
+		key = reason
+	}
+	if key != "" {
+		f.rejected[key]++
+	}
+	return reason
+}
+
+// similarTo 前缀与已有邮箱相似时返回说明，如 “与已有邮箱 blue.river_81 相似”
+func (f *candidateFilter) similarTo(prefix string) string {
+	existing, distance := closestAlias(f.aliases, prefix, f.distance)
+	switch {
+	case existing == "":
+		return ""
+	case distance == 0:
+		return "与已有邮箱 " + existing + " 重名"
+	}
+	return "与已有邮箱 " + existing + " 相似"
+}
+
+// Score 候选的质量评分；penalize 模式下与已有邮箱相似的候选扣 similarAliasPenalty 分
+func (f *candidateFilter) Score(email string, quality EmailQualityConfig) int {
+	score := evaluateEmailQuality(email, quality)
+	if f != nil && f.penalize && f.similarTo(emailPrefix(email)) != "" {
+		score = max(score-similarAliasPenalty, 0)
+	}
+	return score
+}
+
+// Rejected 被拒绝的候选总数
+func (f *candidateFilter) Rejected() int {
+	if f == nil {
+		return 0
+	}
+	total := 0
+	for _, n := range f.rejected {
+		total += n
+	}
+	return total
+}
+
+// Summary 按原因汇总被拒绝的候选，如 “拒绝 7 个：不匹配 ^[a-z]+\.[a-z]+$ 5 个、匹配了排除规则 [0-9] 2 个”
+func (f *candidateFilter) Summary() string {
+	if f.Rejected() == 0 {
+		return ""
+	}
+	reasons := make([]string, 0, len(f.rejected))
+	for reason := range f.rejected {
+		reasons = append(reasons, reason)
+	}
+	sort.Slice(reasons, func(i, j int) bool {
+		if f.rejected[reasons[i]] != f.rejected[reasons[j]] {
+			return f.rejected[reasons[i]] > f.rejected[reasons[j]]
+		}
+		return reasons[i] < reasons[j]
+	})
+	parts := make([]string, len(reasons))
+	for i, reason := range reasons {
+		parts[i] = fmt.Sprintf("%s %d 个", reason, f.rejected[reason])
+	}
+	return fmt.Sprintf("拒绝 %d 个：%s", f.Rejected(), strings.Join(parts, "、"))
+}
+
+// Rules 格式要求的各条规则，如 “匹配 ^[a-z]+\.[a-z]+$”
+func (f *candidateFilter) Rules() []string {
+	var rules []string
+	if f.match != nil {
+		rules = append(rules, "匹配 "+f.match.String())
+	}
+	if f.reject != nil {
+		rules = append(rules, "排除 "+f.reject.String())
+	}
+	if len(f.blocked) > 0 {
+		rules = append(rules, fmt.Sprintf("屏蔽词 %d 条", len(f.blocked)))
+	}
+	if len(f.aliases) > 0 {
+		rule := fmt.Sprintf("与 %d 个已有邮箱的编辑距离不小于 %d", len(f.aliases), f.distance)
+		if f.penalize {
+			rule = fmt.Sprintf("与 %d 个已有邮箱的编辑距离小于 %d 时扣 %d 分", len(f.aliases), f.distance, similarAliasPenalty)
+		}
+		rules = append(rules, rule)
+	}
+	return rules
+}
+
+// Describe 格式要求及生成预算的说明，用于智能创建开始前显示
+func (f *candidateFilter) Describe() string {
+	return fmt.Sprintf("%s（最多 %d 个候选或 %s）", strings.Join(f.Rules(), "，"), f.maxTries, f.timeout)
+}
+
+// extendFilteredCandidates 第一轮候选都不符合格式要求时继续逐个生成，直到找到一个符合的、
+// 用完候选数或时间预算、被限流或收到退出信号。generated 为已生成的数量，序号接在其后
+func extendFilteredCandidates(config *Config, filter *candidateFilter, generated int, langs []string, started time.Time) []EmailCandidate {
+	deadline := started.Add(filter.timeout)
+	for id := generated + 1; id <= filter.maxTries && time.Now().Before(deadline) && !operationCanceled(); id++ {
+		lang := ""
+		if len(langs) > 0 {
+			lang = langs[(id-1)%len(langs)]
+		}
+		email, err := generateHMEWithLang(config, lang)
+		if err != nil {
+			fmt.Printf("  "+ColorRed+"[!]"+ColorReset+" 生成失败: %v\n", err)
+			if _, limited := retryAfterFor(err); limited {
+				break
+			}
+			continue
+		}
+		if reason := filter.Check(email); reason != "" {
+			fmt.Printf("  "+ColorDim+"[-] 邮箱 #%d: %s（%s）"+ColorReset+"\n", id, email, reason)
+			continue
+		}
+		return []EmailCandidate{{Email: email, Score: filter.Score(email, config.EmailQuality), ID: id, Lang: lang}}
+	}
+	return nil
+}
@@ -0,0 +1,299 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 候选池大小与显示数量的上限；generate 不占用创建配额，但同样受限流约束
+const (
+	maxCandidatePoolSize = 100
+	defaultPoolSize      = 20
+	defaultPoolTop       = 5
+)
+
+// candidatePoolSize 候选池大小与显示的前 N 个
+func candidatePoolSize(config *Config) (size, top int) {
+	size, top = config.EmailQuality.PoolSize, config.EmailQuality.PoolTop
+	if size <= 0 {
+		size = defaultPoolSize
+	}
+	if size > maxCandidatePoolSize {
+		size = maxCandidatePoolSize
+	}
+	if top <= 0 {
+		top = defaultPoolTop
+	}
+	if top > size {
+		top = size
+	}
+	return size, top
+}
+
+// generateCandidatePool 生成 size 个候选地址并在本地评分（不确认创建）。
+// 串行时每次间隔 delay_seconds，按 max_concurrency 并发时只经过 requests_per_minute 全局限速；
+// 被限流或收到退出信号时停止，保留已生成的候选
+func generateCandidatePool(config *Config, size int) ([]EmailCandidate, error) {
+	filter, err := newCandidateFilter(config.EmailQuality)
+	if err != nil {
+		return nil, err
+	}
+	langs := candidateLangCodes(config)
+	workers := config.MaxConcurrency
+	if workers < 1 {
+		workers = 1
+	}
+	if workers > size {
+		workers = size
+	}
+
+	var (
+		mutex      sync.Mutex
+		candidates []EmailCandidate
+		seen       = make(map[string]bool)
+		next       = 1
+		done       int
+		failed     int
+		stopped    error
+		lastErr    error
+		wg         sync.WaitGroup
+	)
+	// take 领取下一个序号，已停止或已领完时返回 0
+	take := func() int {
+		mutex.Lock()
+		defer mutex.Unlock()
+		if stopped != nil || next > size || operationCanceled() {
+			return 0
+		}
+		next++
+		return next - 1
+	}
+
+	printProgressBar(0, size, "生成候选")
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for id := take(); id > 0; id = take() {
+				lang := ""
+				if len(langs) > 0 {
+					lang = langs[(id-1)%len(langs)]
+				}
+				email, err := generateHMEWithLang(config, lang)
+
+				mutex.Lock()
+				done++
+				switch {
+				case err != nil:
+					failed++
+					lastErr = err
+					if _, limited := retryAfterFor(err); limited && stopped == nil {
+						stopped = err
+					}
+				case filter.Check(email) != "":
+				case !seen[strings.ToLower(email)]:
+					seen[strings.ToLower(email)] = true
+					candidates = append(candidates, EmailCandidate{Email: email, Score: filter.Score(email, config.EmailQuality), ID: id, Lang: lang})
+				}
+				printProgressBar(done, size, "生成候选")
+				mutex.Unlock()
+
+				if err == nil && config.DelaySeconds > 0 && workers == 1 && id < size && !sleepUnlessCanceled(time.Duration(config.DelaySeconds)*time.Second) {
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	if done < size && !plainUI {
+		fmt.Println()
+	}
+
+	if stopped != nil {
+		printWarning(fmt.Sprintf("生成 %d 个后被限流，停止生成，从已有的 %d 个候选中选择", done, len(candidates)))
+	} else if lastErr != nil {
+		printWarning(fmt.Sprintf("%d 个生成失败，最后一个错误: %v", failed, lastErr))
+	}
+	if summary := filter.Summary(); summary != "" {
+		printInfo("格式要求" + summary)
+	}
+	if len(candidates) == 0 {
+		if filter.Rejected() > 0 {
+			return nil, fmt.Errorf("没有符合格式要求的候选（%s）", filter.Summary())
+		}
+		if lastErr != nil {
+			return nil, fmt.Errorf("没有生成任何候选: %w", lastErr)
+		}
+		return nil, fmt.Errorf("没有生成任何候选")
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		if candidates[i].Score != candidates[j].Score {
+			return candidates[i].Score > candidates[j].Score
+		}
+		return candidates[i].ID < candidates[j].ID
+	})
+	return candidates, nil
+}
+
+// parseCandidateSelection 解析选择：序号可用逗号分隔或写成范围（如 1,3 或 1-3），all 表示全部，回车选第 1 个
+func parseCandidateSelection(input string, count int) ([]int, error) {
+	input = strings.ToLower(strings.TrimSpace(input))
+	if input == "" {
+		return []int{0}, nil
+	}
+	if input == "all" {
+		picks := make([]int, count)
+		for i := range picks {
+			picks[i] = i
+		}
+		return picks, nil
+	}
+
+	var picks []int
+	chosen := make(map[int]bool)
+	for _, part := range strings.Split(input, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		from, to := part, part
+		if i := strings.Index(part, "-"); i > 0 {
+			from, to = part[:i], part[i+1:]
+		}
+		start, err1 := strconv.Atoi(strings.TrimSpace(from))
+		end, err2 := strconv.Atoi(strings.TrimSpace(to))
+		if err1 != nil || err2 != nil || start < 1 || end < start || end > count {
+			return nil, fmt.Errorf("无效的选择: %s（请输入 1-%d 之间的序号）", part, count)
+		}
+		for n := start; n <= end; n++ {
+			if !chosen[n] {
+				chosen[n] = true
+				picks = append(picks, n-1)
+			}
+		}
+	}
+	if len(picks) == 0 {
+		return nil, fmt.Errorf("没有选择任何邮箱")
+	}
+	return picks, nil
+}
+
+// printCandidatePool 按分数从高到低显示前 top 个候选
+func printCandidatePool(config *Config, candidates []EmailCandidate, top int) {
+	printSubHeader(fmt.Sprintf("候选池前 %d 名", top))
+	for i, candidate := range candidates[:top] {
+		scoreColor := ColorRed
+		if candidate.Score >= config.EmailQuality.MinScore {
+			scoreColor = ColorGreen
+		} else if candidate.Score >= config.EmailQuality.MinScore-20 {
+			scoreColor = ColorYellow
+		}
+		fmt.Printf("  "+ColorBrightCyan+"%2d."+ColorReset+" "+ColorBrightWhite+"%s"+ColorReset+"%s  "+scoreColor+"%d"+ColorReset+"/100\n",
+			i+1, candidate.Email, formatCandidateLang(candidate.Lang), candidate.Score)
+		if config.EmailQuality.Selection.ShowScores {
+			showDetailedScore(candidate.Email, config.EmailQuality)
+		}
+	}
+	printLangComparison(candidates)
+	fmt.Println()
+}
+
+// poolLabel 确认多个邮箱时在标签后加序号，只确认一个时沿用原标签
+func poolLabel(label string, index, total int) string {
+	if total == 1 {
+		return label
+	}
+	return fmt.Sprintf("%s-%d", label, index+1)
+}
+
+// reservePoolCandidates 依次确认选中的候选，被限流或收到退出信号时停止，返回已创建的邮箱
+func reservePoolCandidates(config *Config, picks []EmailCandidate, label string) ([]string, error) {
+	var created []string
+	for i, candidate := range picks {
+		if i > 0 && config.DelaySeconds > 0 && !sleepUnlessCanceled(time.Duration(config.DelaySeconds)*time.Second) {
+			return created, apiContext().Err()
+		}
+		itemLabel := poolLabel(label, i, len(picks))
+		fmt.Printf("  "+ColorDim+"..."+ColorReset+" 确认 %s "+ColorDim+"(标签: %s)"+ColorReset+" ... ", candidate.Email, itemLabel)
+		email, err := reserveHME(config, candidate.Email, itemLabel)
+		if err != nil {
+			fmt.Print(ColorRed + "[!]" + ColorReset + "\n")
+			return created, fmt.Errorf("确认 %s 失败: %w", candidate.Email, err)
+		}
+		fmt.Print(ColorGreen + "[+]" + ColorReset + "\n")
+		if err := saveEmailToFile(config, email, itemLabel, CreationOrigin{Source: SourceSmart}); err != nil {
+			printWarning(fmt.Sprintf("保存邮箱到文件失败: %v", err))
+		}
+		created = append(created, email)
+	}
+	return created, nil
+}
+
+// handleCandidatePool 智能创建的候选池模式：一次生成较多候选，按分数列出前 N 个，确认其中一个或多个。
+// 未确认的候选直接丢弃，generate 不占用创建配额
+func handleCandidatePool(config *Config, label string) {
+	size, top := candidatePoolSize(config)
+	printSubHeader("候选池生成")
+	fmt.Printf("  "+ColorCyan+"候选池:"+ColorReset+" %d 个 "+ColorDim+"|"+ColorReset+" "+ColorCyan+"显示前:"+ColorReset+" %d 个\n", size, top)
+	if langs := candidateLangCodes(config); len(langs) > 0 {
+		fmt.Printf("  "+ColorCyan+"候选语言:"+ColorReset+" %s\n", strings.Join(langs, "、"))
+	}
+	if filter, err := newCandidateFilter(config.EmailQuality); err == nil && filter != nil {
+		fmt.Printf("  "+ColorCyan+"格式要求:"+ColorReset+" %s\n", strings.Join(filter.Rules(), "，"))
+	}
+	fmt.Println()
+
+	createdAt := time.Now()
+	candidates, err := generateCandidatePool(config, size)
+	for err != nil {
+		if !reportAPIError(config, "生成候选", err) {
+			return
+		}
+		candidates, err = generateCandidatePool(config, size)
+	}
+	if top > len(candidates) {
+		top = len(candidates)
+	}
+	printCandidatePool(config, candidates, top)
+
+	printInfo(fmt.Sprintf("输入序号确认创建，可多选（如 1,3 或 1-3，all 为前 %d 个全部），回车选第 1 个，输入 0 放弃", top))
+	input := readInput("选择: ")
+	if strings.TrimSpace(input) == "0" {
+		printInfo("已放弃，未创建任何邮箱")
+		return
+	}
+	indexes, err := parseCandidateSelection(input, top)
+	if err != nil {
+		printError(err.Error())
+		return
+	}
+	picks := make([]EmailCandidate, len(indexes))
+	for i, index := range indexes {
+		picks[i] = candidates[index]
+	}
+	if len(picks) > 1 {
+		printInfo(fmt.Sprintf("将确认 %d 个邮箱，标签依次为 %s … %s", len(picks), poolLabel(label, 0, len(picks)), poolLabel(label, len(picks)-1, len(picks))))
+	}
+	fmt.Println()
+
+	created, err := reservePoolCandidates(config, picks, label)
+	fmt.Println()
+	if err != nil {
+		printError(err.Error())
+	}
+	if len(created) == 0 {
+		return
+	}
+	printSuccess(fmt.Sprintf("已创建 %d 个邮箱，其余 %d 个候选已丢弃", len(created), len(candidates)-len(created)))
+	for _, email := range created {
+		fmt.Printf("  "+ColorBrightMagenta+"邮箱: "+ColorReset+ColorBold+"%s"+ColorReset+"\n", email)
+	}
+	if len(created) == 1 {
+		offerVerificationWait(config, created[0], createdAt)
+	}
+}
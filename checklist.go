@@ -0,0 +1,243 @@
+package main
+
+import (
+	"encoding/csv"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// LoginChange 一个需要更新登录邮箱的服务：原地址已停用或迁移，换成了新地址
+type LoginChange struct {
+	Service string `json:"service"`
+	Site    string `json:"site,omitempty"`
+	Label   string `json:"label"`
+	OldHME  string `json:"old_hme"`
+	NewHME  string `json:"new_hme"`
+}
+
+// serviceName 服务名称：优先使用台账中记录的网站（去掉协议、www. 与路径），其次是标签、备注
+func serviceName(site, label, note string) string {
+	if site = strings.TrimSpace(site); site != "" {
+		if i := strings.Index(site, "://"); i >= 0 {
+			site = site[i+3:]
+		}
+		if i := strings.IndexAny(site, "/?#"); i >= 0 {
+			site = site[:i]
+		}
+		return strings.TrimPrefix(strings.ToLower(site), "www.")
+	}
+	if label = strings.TrimSpace(label); label != "" {
+		return label
+	}
+	if note = strings.TrimSpace(note); note != "" {
+		return note
+	}
+	return "(未命名)"
+}
+
+// changesFromReport 读取 migrate import 生成的迁移报告，只保留原地址与新地址都存在且不同的行
+func changesFromReport(path string) ([]LoginChange, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("打开迁移报告失败: %v", err)
+	}
+	defer file.Close()
+	records, err := csv.NewReader(file).ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("解析迁移报告失败: %v", err)
+	}
+	if len(records) == 0 {
+		return nil, fmt.Errorf("迁移报告为空")
+	}
+	columns := make(map[string]int)
+	for i, name := range records[0] {
+		columns[strings.TrimSpace(name)] = i
+	}
+	for _, name := range []string{"old_hme", "new_hme", "label"} {
+		if _, ok := columns[name]; !ok {
+			return nil, fmt.Errorf("迁移报告缺少 %s 列，请使用 migrate import 生成的报告", name)
+		}
+	}
+	cell := func(record []string, name string) string {
+		if i, ok := columns[name]; ok && i < len(record) {
+			return strings.TrimSpace(record[i])
+		}
+		return ""
+	}
+
+	var changes []LoginChange
+	for _, record := range records[1:] {
+		change := LoginChange{Site: cell(record, "site"), Label: cell(record, "label"), OldHME: cell(record, "old_hme"), NewHME: cell(record, "new_hme")}
+		if change.OldHME == "" || change.NewHME == "" || strings.EqualFold(change.OldHME, change.NewHME) {
+			continue
+		}
+		if change.Site == "" {
+			if old, ok := inventory.Get(change.OldHME); ok {
+				change.Site = old.Site
+			}
+		}
+		change.Service = serviceName(change.Site, change.Label, cell(record, "note"))
+		changes = append(changes, change)
+	}
+	return changes, nil
+}
+
+// retiredAt 邮箱在本地台账中被停用或删除的时间，仍在使用时返回 0
+func retiredAt(record InventoryRecord) int64 {
+	if record.IsTombstone() {
+		return record.DeletedAt
+	}
+	if !record.knownActive() {
+		if event, ok := record.LastEvent(InventoryEventDeactivated); ok {
+			return event.At
+		}
+	}
+	return 0
+}
+
+// absMillis 毫秒差的绝对值
+func absMillis(ms int64) int64 {
+	if ms < 0 {
+		return -ms
+	}
+	return ms
+}
+
+// changesFromLedger 从本地台账推断最近的轮换：since 之后停用或删除的邮箱，
+// 与之后新建的同名（忽略大小写）激活邮箱配对
+func changesFromLedger(since time.Time) []LoginChange {
+	records := inventory.Search("")
+	sort.Slice(records, func(i, j int) bool { return records[i].CreatedAt < records[j].CreatedAt })
+
+	replacements := make(map[string][]InventoryRecord)
+	for _, record := range records {
+		if retiredAt(record) == 0 && !record.IsTombstone() && record.Label != "" {
+			key := strings.ToLower(record.Label)
+			replacements[key] = append(replacements[key], record)
+		}
+	}
+
+	var changes []LoginChange
+	for _, old := range records {
+		retired := retiredAt(old)
+		if retired == 0 || retired < since.UnixMilli() || old.Label == "" {
+			continue
+		}
+		// 轮换时通常先建新邮箱再停用旧的（或反过来），取创建时间最接近停用时间的一个
+		key := strings.ToLower(old.Label)
+		best := -1
+		for i, candidate := range replacements[key] {
+			if candidate.CreatedAt > old.CreatedAt && (best < 0 || absMillis(candidate.CreatedAt-retired) < absMillis(replacements[key][best].CreatedAt-retired)) {
+				best = i
+			}
+		}
+		if best < 0 {
+			continue
+		}
+		candidate := replacements[key][best]
+		replacements[key] = append(replacements[key][:best:best], replacements[key][best+1:]...)
+		site := old.Site
+		if site == "" {
+			site = candidate.Site
+		}
+		changes = append(changes, LoginChange{Service: serviceName(site, old.Label, old.Note), Site: site, Label: old.Label, OldHME: old.HME, NewHME: candidate.HME})
+	}
+	return changes
+}
+
+// writeChecklist 按格式写出清单，md 为可勾选的 Markdown 任务列表
+func writeChecklist(w io.Writer, changes []LoginChange, format string) error {
+	if format == "csv" {
+		writer := csv.NewWriter(w)
+		writer.Write([]string{"done", "service", "site", "label", "old_hme", "new_hme"})
+		for _, change := range changes {
+			writer.Write([]string{"", change.Service, change.Site, change.Label, change.OldHME, change.NewHME})
+		}
+		writer.Flush()
+		return writer.Error()
+	}
+
+	var b strings.Builder
+	b.WriteString("# 登录邮箱更新清单\n\n")
+	fmt.Fprintf(&b, "生成时间：%s · 共 %d 项\n\n", time.Now().Format("2006-01-02 15:04"), len(changes))
+	b.WriteString("逐个登录以下服务，把账号邮箱从原地址改为新地址，完成后勾选。\n\n")
+	missingSite := 0
+	for _, change := range changes {
+		fmt.Fprintf(&b, "- [ ] **%s** — `%s` → `%s`", change.Service, change.OldHME, change.NewHME)
+		if change.Site == "" {
+			b.WriteString("（未记录使用网站）")
+			missingSite++
+		}
+		b.WriteString("\n")
+	}
+	if missingSite > 0 {
+		fmt.Fprintf(&b, "\n> %d 项未记录使用网站，服务名称取自标签；可用 `ledger site 邮箱 网站` 补上后重新生成。\n", missingSite)
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// runChecklist 生成登录邮箱更新清单：checklist [-report 迁移报告.csv] [-days 30] [-format md|csv] [-o 文件]
+func runChecklist(args []string) error {
+	fs := flag.NewFlagSet("checklist", flag.ContinueOnError)
+	report := fs.String("report", "", "migrate import 生成的迁移报告；不指定时从本地台账中找最近轮换的邮箱")
+	days := fs.Int("days", 30, "不指定 -report 时，查找最近多少天内停用或删除后重建的邮箱")
+	format := fs.String("format", "md", "清单格式：md 或 csv")
+	output := fs.String("o", "", "清单文件（- 表示输出到标准输出，默认按日期生成文件名）")
+	if err := fs.Parse(args); err != nil {
+		return usageError(err)
+	}
+	if *format != "md" && *format != "csv" {
+		return usageError(fmt.Errorf("-format 只支持 md 或 csv"))
+	}
+	if *days <= 0 {
+		return usageError(fmt.Errorf("-days 必须大于 0"))
+	}
+	if inventory == nil {
+		return fmt.Errorf("本地清单不可用")
+	}
+
+	var changes []LoginChange
+	if *report != "" {
+		var err error
+		if changes, err = changesFromReport(*report); err != nil {
+			return err
+		}
+	} else {
+		changes = changesFromLedger(time.Now().AddDate(0, 0, -*days))
+	}
+	sort.SliceStable(changes, func(i, j int) bool { return strings.ToLower(changes[i].Service) < strings.ToLower(changes[j].Service) })
+
+	if outputJSON {
+		return writeJSON(changes)
+	}
+	if len(changes) == 0 {
+		if *report != "" {
+			printInfo("迁移报告中没有需要更新登录邮箱的服务")
+		} else {
+			printInfo(fmt.Sprintf("最近 %d 天内没有停用后以同名标签重建的邮箱", *days))
+		}
+		return nil
+	}
+	if *output == "-" {
+		return writeChecklist(dataOutput, changes, *format)
+	}
+	if *output == "" {
+		*output = "hme-login-checklist-" + time.Now().Format("20060102") + "." + *format
+	}
+	file, err := os.OpenFile(*output, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return fmt.Errorf("创建清单文件失败: %v", err)
+	}
+	defer file.Close()
+	if err := writeChecklist(file, changes, *format); err != nil {
+		return fmt.Errorf("写入清单文件失败: %v", err)
+	}
+	printSuccess(fmt.Sprintf("已生成 %d 项登录邮箱更新清单: %s", len(changes), *output))
+	return nil
+}
@@ -0,0 +1,473 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 机器可读输出格式，由全局选项 --json 或配置 output_format 设置
+const OutputFormatJSON = "json"
+
+// dataOutput 子命令输出数据的目标（始终是原始标准输出）。JSON 模式下标准输出只保留数据，
+// 启动信息、进度等提示改写到标准错误，便于在脚本中直接用管道交给 jq 等工具处理
+var dataOutput io.Writer = os.Stdout
+
+var dataOutputMutex sync.Mutex
+
+// outputFormatConfigured 在完整加载配置前读取 output_format，以便启动信息也不写入标准输出
+func outputFormatConfigured(path string) string {
+	return strings.ToLower(strings.TrimSpace(peekConfig(path).OutputFormat))
+}
+
+// setupDataOutput 子命令以 JSON 输出时，把提示信息改写到标准错误；交互菜单不受影响
+func setupDataOutput(args []string) {
+	if len(args) == 0 {
+		outputJSON = false
+		return
+	}
+	if !outputJSON && outputFormatConfigured(CONFIG_FILE) != OutputFormatJSON {
+		return
+	}
+	outputJSON = true
+	os.Stdout = os.Stderr
+}
+
+// writeJSON 向 dataOutput 输出一行 JSON（JSON 模式下每个结果一行，即 NDJSON）
+func writeJSON(value interface{}) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	dataOutputMutex.Lock()
+	defer dataOutputMutex.Unlock()
+	_, err = dataOutput.Write(append(data, '\n'))
+	return err
+}
+
+// CLIError 结构化的错误，保留 Apple 返回的 errorCode 与 HTTP 状态码
+type CLIError struct {
+	Message    string  `json:"message"`
+	Class      string  `json:"class"`                // auth、rate_limited、network 等，与失败汇总一致
+	ErrorCode  string  `json:"error_code,omitempty"` // Apple 的 errorCode，如 -41015
+	HTTPStatus int     `json:"http_status,omitempty"`
+	RetryAfter float64 `json:"retry_after_seconds,omitempty"`
+	Advice     string  `json:"advice,omitempty"` // 可操作的处理建议，无法归类时为空
+	ExitCode   int     `json:"exit_code"`
+}
+
+// newCLIError 从错误链中提取结构化信息，err 为 nil 时返回 nil
+func newCLIError(err error) *CLIError {
+	if err == nil {
+		return nil
+	}
+	class := classifyFailure(err)
+	result := &CLIError{Message: err.Error(), Class: class.Key, ExitCode: exitCodeFor(err)}
+	if class != failureOther {
+		result.Advice = class.Advice
+	}
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		result.ErrorCode = apiErr.ErrorCode
+	}
+	var status *APIStatusError
+	if errors.As(err, &status) {
+		result.HTTPStatus = status.StatusCode
+		if apiErr := status.APIError(); apiErr != nil && result.ErrorCode == "" {
+			result.ErrorCode = apiErr.ErrorCode
+		}
+	}
+	if wait, limited := retryAfterFor(err); limited {
+		result.RetryAfter = wait.Seconds()
+	}
+	return result
+}
+
+// CLIResult 单项操作的 JSON 结果（batch、delete 等逐行输出）
+type CLIResult struct {
+	Op          string    `json:"op"` // create、rename、edit、deactivate、reactivate、delete
+	Index       int       `json:"index,omitempty"`
+	Label       string    `json:"label,omitempty"`
+	Email       string    `json:"email,omitempty"`
+	AnonymousID string    `json:"anonymous_id,omitempty"`
+	OK          bool      `json:"ok"`
+	Error       *CLIError `json:"error,omitempty"`
+}
+
+// writeResult JSON 模式下输出单项结果
+func writeResult(result CLIResult, err error) {
+	if !outputJSON {
+		return
+	}
+	result.OK = err == nil
+	result.Error = newCLIError(err)
+	writeJSON(result)
+}
+
+// CLISummary 批量操作结束时的汇总行
+type CLISummary struct {
+	Op        string   `json:"op"`
+	Summary   bool     `json:"summary"`
+	Succeeded int      `json:"succeeded"`
+	Failed    int      `json:"failed"`
+	Emails    []string `json:"emails,omitempty"`
+}
+
+// writeFailure JSON 模式下把命令失败输出为 {"ok":false,"error":{...}}
+func writeFailure(err error) {
+	if !outputJSON {
+		return
+	}
+	writeJSON(struct {
+		OK    bool      `json:"ok"`
+		Error *CLIError `json:"error"`
+	}{false, newCLIError(err)})
+}
+
+// CLIEmail 子命令 JSON 输出中的邮箱
+type CLIEmail struct {
+	Email       string `json:"email"`
+	Label       string `json:"label"`
+	Note        string `json:"note,omitempty"`
+	AnonymousID string `json:"anonymous_id,omitempty"`
+	Active      bool   `json:"active"`
+	CreatedAt   string `json:"created_at,omitempty"` // RFC 3339
+	ForwardTo   string `json:"forward_to,omitempty"`
+}
+
+func newCLIEmail(email HMEEmail) CLIEmail {
+	item := CLIEmail{
+		Email:       email.HME,
+		Label:       email.Label,
+		Note:        email.Note,
+		AnonymousID: email.AnonymousID,
+		Active:      email.IsActive,
+		ForwardTo:   email.ForwardToEmail,
+	}
+	if email.CreateTimestamp > 0 {
+		item.CreatedAt = time.UnixMilli(email.CreateTimestamp).Format(time.RFC3339)
+	}
+	return item
+}
+
+// runCreateCommand 创建单个邮箱：create -label 标签 [-lang 语言]
+func runCreateCommand(config *Config, args []string) error {
+	fs := flag.NewFlagSet("create", flag.ContinueOnError)
+	label := fs.String("label", "", "邮箱标签（必填）")
+	langFlag := fs.String("lang", "", "本次生成使用的语言，如 ja-jp（默认使用配置 lang_code）")
+	if err := fs.Parse(args); err != nil {
+		return usageError(err)
+	}
+	if strings.TrimSpace(*label) == "" {
+		return usageError(fmt.Errorf("用法: create -label 标签 [-lang 语言]"))
+	}
+	lang, err := parseLangCode(*langFlag)
+	if err != nil {
+		return usageError(err)
+	}
+	if err := checkCooldown(config); err != nil {
+		return err
+	}
+
+	var email string
+	if err := withSpinner("创建邮箱", func() error {
+		var err error
+		email, err = createHMEWithLang(config, *label, lang)
+		return err
+	}); err != nil {
+		return fmt.Errorf("创建失败: %w", err)
+	}
+	if err := saveEmailToFile(config, email, *label, CreationOrigin{Source: SourceCLI}); err != nil {
+		printWarning(fmt.Sprintf("保存邮箱到文件失败: %v", err))
+	}
+
+	if outputJSON {
+		return writeJSON(CLIEmail{Email: email, Label: *label, Active: true, CreatedAt: time.Now().Format(time.RFC3339)})
+	}
+	printSuccess(fmt.Sprintf("已创建 %s (%s)", email, *label))
+	return nil
+}
+
+// CLIScore score 命令的 JSON 输出：总分、评级与各项得分的原因
+type CLIScore struct {
+	Email     string    `json:"email"`
+	Score     int       `json:"score"`
+	Grade     string    `json:"grade"`
+	Breakdown Breakdown `json:"breakdown"`
+}
+
+// runScoreCommand 用当前 email_quality 配置为地址评分并说明原因：score 邮箱地址...，JSON 模式下每个地址输出一行
+func runScoreCommand(config *Config, args []string) error {
+	fs := flag.NewFlagSet("score", flag.ContinueOnError)
+	if err := fs.Parse(args); err != nil {
+		return usageError(err)
+	}
+	if fs.NArg() == 0 {
+		return usageError(fmt.Errorf("用法: score 邮箱地址..."))
+	}
+	engine, err := newScoringEngine(config.EmailQuality)
+	if err != nil {
+		return err
+	}
+
+	if !outputJSON {
+		printHeader("邮箱评分说明")
+	}
+	for i, email := range fs.Args() {
+		email = strings.TrimSpace(email)
+		if !strings.Contains(email, "@") {
+			return usageError(fmt.Errorf("%s 不是邮箱地址", email))
+		}
+		score, breakdown := engine.Score(email)
+		if outputJSON {
+			grade, _ := scoreGrade(score)
+			if err := writeJSON(CLIScore{Email: email, Score: score, Grade: grade, Breakdown: breakdown}); err != nil {
+				return err
+			}
+			continue
+		}
+		fmt.Printf("  "+ColorBrightCyan+"%2d."+ColorReset+" %s\n", i+1, email)
+		printScoreReport(score, breakdown)
+		fmt.Println()
+	}
+	return nil
+}
+
+// runListCommand 列出邮箱：list [-active|-inactive] [-search 关键字]，JSON 模式下输出一个数组
+func runListCommand(config *Config, args []string) error {
+	fs := flag.NewFlagSet("list", flag.ContinueOnError)
+	activeOnly := fs.Bool("active", false, "只列出激活的邮箱")
+	inactiveOnly := fs.Bool("inactive", false, "只列出已停用的邮箱")
+	search := fs.String("search", "", "按邮箱地址、标签或备注筛选（忽略大小写）")
+	if err := fs.Parse(args); err != nil {
+		return usageError(err)
+	}
+	if *activeOnly && *inactiveOnly {
+		return usageError(fmt.Errorf("-active 与 -inactive 不能同时使用"))
+	}
+
+	var emails []HMEEmail
+	if err := withSpinner("获取邮箱列表", func() error {
+		var err error
+		emails, err = listHME(config)
+		return err
+	}); err != nil {
+		return fmt.Errorf("获取列表失败: %w", err)
+	}
+
+	keyword := strings.ToLower(strings.TrimSpace(*search))
+	items := make([]CLIEmail, 0, len(emails))
+	for _, email := range emails {
+		if (*activeOnly && !email.IsActive) || (*inactiveOnly && email.IsActive) {
+			continue
+		}
+		if keyword != "" && !strings.Contains(strings.ToLower(email.HME+" "+email.Label+" "+email.Note), keyword) {
+			continue
+		}
+		items = append(items, newCLIEmail(email))
+	}
+
+	if outputJSON {
+		return writeJSON(items)
+	}
+	if len(items) == 0 {
+		printInfo("没有符合条件的邮箱")
+		return nil
+	}
+	for _, item := range items {
+		status := ColorGreen + "●" + ColorReset
+		if !item.Active {
+			status = ColorGray + "○" + ColorReset
+		}
+		fmt.Printf("  %s %s "+ColorCyan+"%s"+ColorReset+"\n", status, formatEmailAddress(item.Email, 36), item.Label)
+	}
+	printInfo(fmt.Sprintf("共 %d 个", len(items)))
+	return nil
+}
+
+// resolveEmailArgs 按邮箱地址、anonymous_id 或 -label 查找要操作的邮箱
+func resolveEmailArgs(config *Config, targets []string, label string) ([]HMEEmail, error) {
+	if len(targets) == 0 && label == "" {
+		return nil, usageError(fmt.Errorf("请指定邮箱地址、anonymous_id 或 -label"))
+	}
+
+	var emails []HMEEmail
+	if err := withSpinner("获取邮箱列表", func() error {
+		var err error
+		emails, err = listHME(config)
+		return err
+	}); err != nil {
+		return nil, fmt.Errorf("获取列表失败: %w", err)
+	}
+
+	var selected []HMEEmail
+	seen := make(map[string]bool)
+	add := func(email HMEEmail) {
+		if !seen[email.AnonymousID] {
+			seen[email.AnonymousID] = true
+			selected = append(selected, email)
+		}
+	}
+	for _, target := range targets {
+		found := false
+		for _, email := range emails {
+			if strings.EqualFold(email.HME, target) || email.AnonymousID == target {
+				add(email)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("未找到邮箱: %s", target)
+		}
+	}
+	if label != "" {
+		found := false
+		for _, email := range emails {
+			if strings.EqualFold(strings.TrimSpace(email.Label), strings.TrimSpace(label)) {
+				add(email)
+				found = true
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("未找到标签为 %s 的邮箱", label)
+		}
+	}
+	return selected, nil
+}
+
+// emailAction 对单个邮箱执行的状态变更
+type emailAction struct {
+	Op      string // JSON 输出中的 op
+	Name    string // 用于提示，如 "停用"
+	Apply   func(config *Config, anonymousID string) error
+	Skip    func(email HMEEmail) string // 返回非空原因时跳过该邮箱
+	Confirm func(config *Config, count int) bool
+	Record  func(email HMEEmail) error
+}
+
+// runEmailAction 解析参数并逐个执行，部分失败返回 ExitPartial
+func runEmailAction(config *Config, name string, args []string, action emailAction) error {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	label := fs.String("label", "", "按标签选择邮箱（忽略大小写）")
+	if err := fs.Parse(args); err != nil {
+		return usageError(err)
+	}
+	selected, err := resolveEmailArgs(config, fs.Args(), *label)
+	if err != nil {
+		return err
+	}
+
+	var targets []HMEEmail
+	for _, email := range selected {
+		if reason := action.Skip(email); reason != "" {
+			printInfo(fmt.Sprintf("跳过 %s：%s", email.HME, reason))
+			continue
+		}
+		targets = append(targets, email)
+	}
+	if len(targets) == 0 {
+		printInfo(fmt.Sprintf("没有需要%s的邮箱", action.Name))
+		return nil
+	}
+
+	for _, email := range targets {
+		fmt.Printf("  "+ColorYellow+"›"+ColorReset+" %s "+ColorDim+"(%s)"+ColorReset+"\n", email.HME, email.Label)
+	}
+	if !action.Confirm(config, len(targets)) {
+		return fmt.Errorf("已取消")
+	}
+
+	failed := 0
+	for i, email := range targets {
+		err := action.Apply(config, email.AnonymousID)
+		writeResult(CLIResult{Op: action.Op, Email: email.HME, Label: email.Label, AnonymousID: email.AnonymousID}, err)
+		if err != nil {
+			printError(fmt.Sprintf("%s %s 失败: %v", action.Name, email.HME, err))
+			failed++
+			if failed == len(targets) {
+				return fmt.Errorf("全部 %d 个均%s失败: %w", failed, action.Name, err)
+			}
+		} else {
+			printSuccess(fmt.Sprintf("已%s %s", action.Name, email.HME))
+			if err := action.Record(email); err != nil {
+				printWarning(fmt.Sprintf("记录本地清单失败: %v", err))
+			}
+		}
+		if i < len(targets)-1 {
+			time.Sleep(500 * time.Millisecond)
+		}
+	}
+	if failed > 0 {
+		return withExitCode(ExitPartial, fmt.Errorf("%d 个%s失败", failed, action.Name))
+	}
+	return nil
+}
+
+// runDeactivateCommand 停用邮箱：deactivate 邮箱或anonymous_id... [-label 标签]
+func runDeactivateCommand(config *Config, args []string) error {
+	return runEmailAction(config, "deactivate", args, emailAction{
+		Op:    "deactivate",
+		Name:  "停用",
+		Apply: deactivateHME,
+		Skip: func(email HMEEmail) string {
+			if !email.IsActive {
+				return "已停用"
+			}
+			return ""
+		},
+		Confirm: func(config *Config, count int) bool {
+			return confirmOperation(config.Safety.Deactivate, "确认停用这些邮箱", count, false)
+		},
+		Record: func(email HMEEmail) error {
+			return inventory.RecordEvent(email, InventoryEventDeactivated, CreationOrigin{Source: SourceCLI})
+		},
+	})
+}
+
+// runReactivateCommand 重新激活邮箱
+func runReactivateCommand(config *Config, args []string) error {
+	return runEmailAction(config, "reactivate", args, emailAction{
+		Op:    "reactivate",
+		Name:  "重新激活",
+		Apply: reactivateHME,
+		Skip: func(email HMEEmail) string {
+			if email.IsActive {
+				return "已是激活状态"
+			}
+			return ""
+		},
+		Confirm: func(config *Config, count int) bool {
+			return confirmAction("确认重新激活这些邮箱")
+		},
+		Record: func(email HMEEmail) error {
+			return inventory.RecordEvent(email, InventoryEventReactivated, CreationOrigin{Source: SourceCLI})
+		},
+	})
+}
+
+// runDeleteCommand 彻底删除已停用的邮箱；无人值守时需要 --yes --force-delete
+func runDeleteCommand(config *Config, args []string) error {
+	return runEmailAction(config, "delete", args, emailAction{
+		Op:    "delete",
+		Name:  "彻底删除",
+		Apply: permanentDeleteHME,
+		Skip: func(email HMEEmail) string {
+			if email.IsActive {
+				return "仍处于激活状态，请先 deactivate"
+			}
+			return ""
+		},
+		Confirm: func(config *Config, count int) bool {
+			printWarning("此操作不可恢复")
+			return confirmOperation(config.Safety.PermanentDelete, "确认彻底删除这些邮箱", count, true)
+		},
+		Record: inventory.RecordDeletion,
+	})
+}
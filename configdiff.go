@@ -0,0 +1,182 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// 影响 HTTP 客户端（连接池、超时、代理）的配置键，变更时重新创建客户端，否则沿用原有连接
+var networkConfigKeys = []string{"timeout_seconds", "proxy_url"}
+
+// 只在启动时读取、热重载后需要重启才生效的配置键
+var restartConfigKeys = []string{"plain_ui", "app_lock"}
+
+// reloadSubsystems 热重载时按变更的配置键重建的子系统
+var reloadSubsystems = []struct {
+	name    string
+	keys    []string
+	rebuild func(config *Config)
+}{
+	{"评分器", []string{"email_quality.weights", "email_quality.scorers", "email_quality.wordlist_file", "email_quality.blocklist"}, func(config *Config) {
+		resetScoringEngines()
+		scoringEngineFor(config.EmailQuality) // 立即校验，配置有误时现在就提示
+	}},
+	{"全局限速", []string{"requests_per_minute", "request_jitter_percent"}, func(config *Config) {
+		config.sharedLimiter()
+	}},
+	{"日志", []string{"logging"}, func(config *Config) {
+		if err := setupLogging(config); err != nil {
+			printWarning(err.Error())
+		}
+	}},
+}
+
+// atMainMenu 主菜单是否正在等待输入；其他时候热重载不清屏、不重绘菜单，以免打断进行中的操作
+var atMainMenu atomic.Bool
+
+// 批量创建等操作进行中检测到的配置变更，操作结束、回到主菜单时再应用，以免打乱进度显示
+var (
+	pendingReloadMutex sync.Mutex
+	pendingReload      *Config
+)
+
+// deferConfigReload 有操作进行中时暂存新配置（多次修改只保留最新的一份），返回 false 表示当前空闲、应立即应用
+func deferConfigReload(config *Config) bool {
+	pendingReloadMutex.Lock()
+	defer pendingReloadMutex.Unlock()
+	if !safetyManager.Busy() {
+		return false
+	}
+	pendingReload = config
+	return true
+}
+
+// applyPendingReload 应用操作进行中暂存的配置并显示变更摘要，没有暂存时不做任何事
+func applyPendingReload() {
+	pendingReloadMutex.Lock()
+	config := pendingReload
+	pendingReload = nil
+	pendingReloadMutex.Unlock()
+	if config == nil {
+		return
+	}
+	changed := configChanges(getCurrentConfig(), config)
+	if len(changed) == 0 {
+		return
+	}
+	rebuilt, restart := applyConfigReload(getCurrentConfig(), config, changed)
+	printConfigReload(changed, rebuilt, restart)
+	fmt.Println("    " + ColorDim + "修改时有操作进行中，已在操作结束后应用" + ColorReset)
+}
+
+// configChanges 比较两份配置，返回值不同的配置键（嵌套键用点连接，如 email_quality.min_score），按字母排序
+func configChanges(oldConfig, newConfig *Config) []string {
+	oldKeys, newKeys := flattenConfig(oldConfig), flattenConfig(newConfig)
+	var changed []string
+	for key, value := range newKeys {
+		if oldValue, ok := oldKeys[key]; !ok || oldValue != value {
+			changed = append(changed, key)
+		}
+	}
+	for key := range oldKeys {
+		if _, ok := newKeys[key]; !ok {
+			changed = append(changed, key)
+		}
+	}
+	sort.Strings(changed)
+	return changed
+}
+
+// flattenConfig 把配置展开为 键 → JSON 值；对象逐层展开，数组整体比较
+func flattenConfig(config *Config) map[string]string {
+	flat := make(map[string]string)
+	data, err := json.Marshal(config)
+	if err != nil {
+		return flat
+	}
+	var tree map[string]interface{}
+	if json.Unmarshal(data, &tree) != nil {
+		return flat
+	}
+	var walk func(prefix string, value interface{})
+	walk = func(prefix string, value interface{}) {
+		if object, ok := value.(map[string]interface{}); ok && len(object) > 0 {
+			for key, child := range object {
+				if prefix != "" {
+					key = prefix + "." + key
+				}
+				walk(key, child)
+			}
+			return
+		}
+		encoded, _ := json.Marshal(value)
+		flat[prefix] = string(encoded)
+	}
+	walk("", tree)
+	return flat
+}
+
+// changedUnder 变更的键中是否有位于 prefixes 之下的（键本身或其子键）
+func changedUnder(changed, prefixes []string) bool {
+	for _, key := range changed {
+		for _, prefix := range prefixes {
+			if key == prefix || strings.HasPrefix(key, prefix+".") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// applyConfigReload 发布热重载后的配置，只重建受变更影响的子系统；返回重建的子系统与需要重启才生效的键
+func applyConfigReload(oldConfig, newConfig *Config, changed []string) (rebuilt, restart []string) {
+	if changedUnder(changed, networkConfigKeys) {
+		rebuilt = append(rebuilt, "HTTP 客户端")
+	} else {
+		newConfig.shareClient(oldConfig)
+	}
+
+	configMutex.Lock()
+	globalConfig = newConfig
+	configMutex.Unlock()
+	// lang_code 为 auto 时使用的系统语言在重载时重新检测
+	resetDetectedLangCode()
+
+	for _, subsystem := range reloadSubsystems {
+		if changedUnder(changed, subsystem.keys) {
+			subsystem.rebuild(newConfig)
+			rebuilt = append(rebuilt, subsystem.name)
+		}
+	}
+	for _, key := range restartConfigKeys {
+		if changedUnder(changed, []string{key}) {
+			restart = append(restart, key)
+		}
+	}
+	logger().Info("配置已重新加载", "changed", strings.Join(changed, ","))
+	return rebuilt, restart
+}
+
+// printConfigReload 显示热重载的变更摘要
+func printConfigReload(changed, rebuilt, restart []string) {
+	const maxShown = 8
+	shown := changed
+	if len(shown) > maxShown {
+		shown = shown[:maxShown]
+	}
+	summary := strings.Join(shown, "、")
+	if len(changed) > maxShown {
+		summary += fmt.Sprintf(" 等 %d 项", len(changed))
+	}
+	fmt.Printf(ColorGreen+"[+] 配置已重新加载，变更 %d 项: "+ColorReset+"%s\n", len(changed), summary)
+	if len(rebuilt) > 0 {
+		fmt.Println("    " + ColorDim + "已重建: " + strings.Join(rebuilt, "、") + ColorReset)
+	}
+	if len(restart) > 0 {
+		fmt.Println("    " + ColorYellow + "重启后生效: " + strings.Join(restart, "、") + ColorReset)
+	}
+}
@@ -0,0 +1,296 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// 配置文件格式
+const (
+	ConfigFormatJSON = "json"
+	ConfigFormatYAML = "yaml"
+	ConfigFormatTOML = "toml"
+)
+
+// configFileCandidates 依次查找的配置文件，使用第一个存在的
+var configFileCandidates = []string{"config.json", "config.yaml", "config.yml", "config.toml"}
+
+// findConfigFile 当前目录中的配置文件，都不存在时为 config.json（配置向导创建的文件）
+func findConfigFile() string {
+	for _, name := range configFileCandidates {
+		if _, err := os.Stat(name); err == nil {
+			return name
+		}
+	}
+	return configFileCandidates[0]
+}
+
+// configFormatOf 按扩展名判断配置文件格式
+func configFormatOf(path string) string {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		return ConfigFormatYAML
+	case ".toml":
+		return ConfigFormatTOML
+	default:
+		return ConfigFormatJSON
+	}
+}
+
+// 配置树中的节点类型
+const (
+	nodeNull = iota
+	nodeString
+	nodeNumber
+	nodeBool
+	nodeObject
+	nodeArray
+)
+
+// configNode 解析后的配置树：三种格式都先解析成它，校验后再转换为 JSON，节点记录所在行号用于报错
+type configNode struct {
+	Kind   int
+	Line   int
+	Str    string // 字符串的值，数字为其 JSON 写法
+	Bool   bool
+	Keys   []string // 对象的键，保持文件中的顺序
+	Fields map[string]*configNode
+	Items  []*configNode
+	Plain  bool   // YAML 中未加引号的标量，字段为字符串时按原文使用
+	Raw    string // 未加引号的标量的原文
+}
+
+// newObjectNode 空对象
+func newObjectNode(line int) *configNode {
+	return &configNode{Kind: nodeObject, Line: line, Fields: make(map[string]*configNode)}
+}
+
+// set 设置对象中的键，保持首次出现的顺序
+func (n *configNode) set(key string, value *configNode) {
+	if _, ok := n.Fields[key]; !ok {
+		n.Keys = append(n.Keys, key)
+	}
+	n.Fields[key] = value
+}
+
+// kindName 节点类型的名称，用于报错
+func (n *configNode) kindName() string {
+	switch n.Kind {
+	case nodeString:
+		return "字符串"
+	case nodeNumber:
+		return "数字"
+	case nodeBool:
+		return "布尔值"
+	case nodeObject:
+		return "对象"
+	case nodeArray:
+		return "列表"
+	default:
+		return "null"
+	}
+}
+
+// writeJSON 以 JSON 写出节点，对象保持键的顺序
+func (n *configNode) writeJSON(buf *bytes.Buffer) {
+	switch n.Kind {
+	case nodeString:
+		buf.WriteString(jsonString(n.Str))
+	case nodeNumber:
+		buf.WriteString(n.Str)
+	case nodeBool:
+		if n.Bool {
+			buf.WriteString("true")
+		} else {
+			buf.WriteString("false")
+		}
+	case nodeObject:
+		buf.WriteByte('{')
+		for i, key := range n.Keys {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			buf.WriteString(jsonString(key))
+			buf.WriteByte(':')
+			n.Fields[key].writeJSON(buf)
+		}
+		buf.WriteByte('}')
+	case nodeArray:
+		buf.WriteByte('[')
+		for i, item := range n.Items {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			item.writeJSON(buf)
+		}
+		buf.WriteByte(']')
+	default:
+		buf.WriteString("null")
+	}
+}
+
+// jsonString 字符串的 JSON 写法（不转义 HTML 字符）
+func jsonString(s string) string {
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	encoder.Encode(s)
+	return strings.TrimSuffix(buf.String(), "\n")
+}
+
+// configSyntaxError 带行号的配置文件错误
+type configSyntaxError struct {
+	Line int
+	Msg  string
+}
+
+func (e *configSyntaxError) Error() string {
+	if e.Line <= 0 {
+		return e.Msg
+	}
+	return fmt.Sprintf("第 %d 行: %s", e.Line, e.Msg)
+}
+
+// syntaxErrorf 构造带行号的错误
+func syntaxErrorf(line int, format string, args ...interface{}) error {
+	return &configSyntaxError{Line: line, Msg: fmt.Sprintf(format, args...)}
+}
+
+// lineAt data 中 offset 处的行号
+func lineAt(data []byte, offset int64) int {
+	if offset > int64(len(data)) {
+		offset = int64(len(data))
+	}
+	return bytes.Count(data[:offset], []byte("\n")) + 1
+}
+
+// parseJSONNode 解析 JSON 配置并记录行号
+func parseJSONNode(data []byte) (*configNode, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var parse func() (*configNode, error)
+	parse = func() (*configNode, error) {
+		token, err := decoder.Token()
+		if err != nil {
+			return nil, err
+		}
+		line := lineAt(data, decoder.InputOffset())
+		switch value := token.(type) {
+		case json.Delim:
+			if value == '{' {
+				node := newObjectNode(line)
+				for decoder.More() {
+					keyToken, err := decoder.Token()
+					if err != nil {
+						return nil, err
+					}
+					key, _ := keyToken.(string)
+					keyLine := lineAt(data, decoder.InputOffset())
+					child, err := parse()
+					if err != nil {
+						return nil, err
+					}
+					if child.Kind == nodeObject || child.Kind == nodeArray {
+						child.Line = keyLine
+					}
+					if _, dup := node.Fields[key]; dup {
+						return nil, syntaxErrorf(keyLine, "重复的键 %s", key)
+					}
+					node.set(key, child)
+				}
+				_, err := decoder.Token()
+				return node, err
+			}
+			node := &configNode{Kind: nodeArray, Line: line}
+			for decoder.More() {
+				child, err := parse()
+				if err != nil {
+					return nil, err
+				}
+				node.Items = append(node.Items, child)
+			}
+			_, err := decoder.Token()
+			return node, err
+		case string:
+			return &configNode{Kind: nodeString, Line: line, Str: value}, nil
+		case json.Number:
+			return &configNode{Kind: nodeNumber, Line: line, Str: value.String()}, nil
+		case bool:
+			return &configNode{Kind: nodeBool, Line: line, Bool: value}, nil
+		default:
+			return &configNode{Kind: nodeNull, Line: line}, nil
+		}
+	}
+
+	root, err := parse()
+	if err == nil {
+		if _, extra := decoder.Token(); extra != io.EOF {
+			err = syntaxErrorf(lineAt(data, decoder.InputOffset()), "JSON 结束后还有多余的内容")
+		}
+	}
+	if err != nil {
+		var syntax *json.SyntaxError
+		switch {
+		case errors.As(err, &syntax):
+			return nil, syntaxErrorf(lineAt(data, syntax.Offset), "JSON 语法错误: %v", err)
+		case errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF):
+			return nil, syntaxErrorf(lineAt(data, int64(len(data))), "JSON 不完整")
+		}
+		return nil, err
+	}
+	return root, nil
+}
+
+// parseConfigNode 按格式解析配置文件
+func parseConfigNode(format string, data []byte) (*configNode, error) {
+	switch format {
+	case ConfigFormatYAML:
+		return parseYAMLNode(data)
+	case ConfigFormatTOML:
+		return parseTOMLNode(data)
+	default:
+		return parseJSONNode(data)
+	}
+}
+
+// readConfigJSON 读取配置文件（JSON、YAML 或 TOML），按 Config 的结构校验后转换为 JSON；
+// 返回未知配置项等警告，错误信息带文件名与行号
+func readConfigJSON(path string) ([]byte, []string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	root, err := parseConfigNode(configFormatOf(path), data)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%s %w", path, err)
+	}
+	// 旧版本的配置先在内存中升级，JSON 文件本身由 upgradeConfigFile 改写
+	version, _, err := migrateConfigNode(root)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%s %w", path, err)
+	}
+	warnings, err := validateConfigNode(root)
+	if version > currentConfigVersion {
+		warnings = append([]string{configVersionWarning(version)}, warnings...)
+	}
+	for i := range warnings {
+		warnings[i] = path + " " + warnings[i]
+	}
+	if err != nil {
+		return nil, warnings, fmt.Errorf("%s %w", path, err)
+	}
+	var buf bytes.Buffer
+	root.writeJSON(&buf)
+	return buf.Bytes(), warnings, nil
+}
+
+// configWritable 程序只改写 JSON 配置；YAML 与 TOML 由用户手动维护，改写会丢失其中的注释
+func configWritable(path string) bool {
+	return configFormatOf(path) == ConfigFormatJSON
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+// TestParseConfigNode 三种格式解析后转换为相同的 JSON，语法错误带行号
+func TestParseConfigNode(t *testing.T) {
+	tests := []struct {
+		name   string
+		format string
+		input  string
+		want   string
+		err    string
+	}{
+		{"JSON", ConfigFormatJSON, `{"a": 1, "b": [true, null], "c": {"d": "x"}}`, `{"a":1,"b":[true,null],"c":{"d":"x"}}`, ""},
+		{"JSON 重复的键", ConfigFormatJSON, "{\n\"a\": 1,\n\"a\": 2}", "", "第 3 行: 重复的键 a"},
+
+		{"YAML 块写法", ConfigFormatYAML, "a: 1\nb:\n  - true\n  - ~\nc:\n  d: x # 注释\n", `{"a":1,"b":[true,null],"c":{"d":"x"}}`, ""},
+		{"YAML 流式写法", ConfigFormatYAML, "a: [1, 2.5, \"s\"]\nb: {c: 'x'}\n", `{"a":[1,2.5,"s"],"b":{"c":"x"}}`, ""},
+		{"YAML 块文本", ConfigFormatYAML, "a: |\n  line1\n  line2\nb: >\n  x\n  y\n", `{"a":"line1\nline2\n","b":"x y\n"}`, ""},
+		{"YAML 锚点与别名", ConfigFormatYAML, "base: &b {x: 1}\ncopy: *b\n", `{"base":{"x":1},"copy":{"x":1}}`, ""},
+		{"YAML 空文件", ConfigFormatYAML, "# 只有注释\n", `{}`, ""},
+		{"YAML 进制与下划线", ConfigFormatYAML, "a: 0x1F\nb: -3\nc: 1e3\n", `{"a":31,"b":-3,"c":1000}`, ""},
+		{"YAML 非 true/false 按字符串", ConfigFormatYAML, "a: yes\nb: off\n", `{"a":"yes","b":"off"}`, ""},
+		{"YAML 重复的键", ConfigFormatYAML, "a: 1\nb: 2\na: 3\n", "", "第 3 行: 重复的键 a"},
+		{"YAML 合并键", ConfigFormatYAML, "base: &b {x: 1}\ncopy:\n  <<: *b\n", "", "第 3 行: 不支持合并键"},
+		{"YAML 多文档", ConfigFormatYAML, "a: 1\n---\nb: 2\n", "", "第 2 行: 不支持多文档 YAML"},
+		{"YAML 语法错误", ConfigFormatYAML, "a: 1\n b: 2\n", "", "第 2 行: YAML 语法错误"},
+		{"YAML 无穷大", ConfigFormatYAML, "a: .inf\n", "", "第 1 行: 不支持的数字"},
+
+		{"TOML 表与表数组", ConfigFormatTOML, "a = 1\n[c]\nd = \"x\"\n[[e]]\nf = true\n[[e]]\nf = false\n", `{"a":1,"c":{"d":"x"},"e":[{"f":true},{"f":false}]}`, ""},
+		{"TOML 点分键与内联表", ConfigFormatTOML, "a.b = 1\nc = { d = [1, 2], e = 'x' }\n", `{"a":{"b":1},"c":{"d":[1,2],"e":"x"}}`, ""},
+		{"TOML 跨行数组与注释", ConfigFormatTOML, "a = [\n  1, # 一\n  2,\n]\n", `{"a":[1,2]}`, ""},
+		{"TOML 进制与下划线", ConfigFormatTOML, "a = 0x1F\nb = 0o17\nc = 0b101\nd = 1_000\ne = 1.5e3\n", `{"a":31,"b":15,"c":5,"d":1000,"e":1500}`, ""},
+		{"TOML 多行字符串", ConfigFormatTOML, "a = \"\"\"\nx \\\n  y\"\"\"\nb = '''\nraw\\n'''\n", `{"a":"x y","b":"raw\\n"}`, ""},
+		{"TOML 转义", ConfigFormatTOML, `a = "\té"` + "\n", `{"a":"\té"}`, ""},
+		{"TOML 日期按字符串", ConfigFormatTOML, "a = 2024-01-02T15:04:05Z\n", `{"a":"2024-01-02T15:04:05Z"}`, ""},
+		{"TOML 重复定义的表", ConfigFormatTOML, "[a]\nx = 1\n[a]\ny = 2\n", "", "第 3 行: TOML 语法错误: table a already exists"},
+		{"TOML 行尾多余内容", ConfigFormatTOML, "a = 1 b = 2\n", "", "第 1 行: TOML 语法错误"},
+		{"TOML 重复的键", ConfigFormatTOML, "a = 1\nb = 2\na = 3\n", "", "第 3 行: TOML 语法错误"},
+		{"TOML 无穷大", ConfigFormatTOML, "a = inf\n", "", "第 1 行: 不支持的数字"},
+		{"TOML 表名缺少括号", ConfigFormatTOML, "a = 1\n[b\n", "", "第 2 行: TOML 语法错误"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root, err := parseConfigNode(tt.format, []byte(tt.input))
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("错误 = %v，期望包含 %q", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("解析失败: %v", err)
+			}
+			var buf bytes.Buffer
+			root.writeJSON(&buf)
+			if buf.String() != tt.want {
+				t.Fatalf("结果 = %s，期望 %s", buf.String(), tt.want)
+			}
+		})
+	}
+}
+
+// TestYAMLPlainScalarKeepsText 未加引号的标量用于字符串字段时保留原文，不会被当作数字改写
+func TestYAMLPlainScalarKeepsText(t *testing.T) {
+	root, err := parseYAMLNode([]byte("dsid: 0123\nlabel_prefix: 1.50\n"))
+	if err != nil {
+		t.Fatalf("解析失败: %v", err)
+	}
+	if _, err := validateConfigNode(root); err != nil {
+		t.Fatalf("校验失败: %v", err)
+	}
+	for key, want := range map[string]string{"dsid": "0123", "label_prefix": "1.50"} {
+		if node := root.Fields[key]; node.Kind != nodeString || node.Str != want {
+			t.Errorf("%s = %q（类型 %s），期望字符串 %q", key, node.Str, node.kindName(), want)
+		}
+	}
+}
+
+// TestTOMLNodeLines TOML 配置树中的行号与键的顺序对应文件中的位置，用于校验报错
+func TestTOMLNodeLines(t *testing.T) {
+	input := "z = 1\n[server]\nport = 8080\ntags = [\n  \"x\",\n  \"y\",\n]\n[[rules]]\nname = \"r1\"\n[[rules]]\nname = \"r2\"\nopts = { level = 3 }\n"
+	root, err := parseTOMLNode([]byte(input))
+	if err != nil {
+		t.Fatalf("解析失败: %v", err)
+	}
+	if got := strings.Join(root.Keys, ","); got != "z,server,rules" {
+		t.Errorf("键的顺序 = %s，期望 z,server,rules", got)
+	}
+	server := root.Fields["server"]
+	second := root.Fields["rules"].Items[1]
+	for name, tt := range map[string]struct {
+		node *configNode
+		want int
+	}{
+		"server":              {server, 2},
+		"server.port":         {server.Fields["port"], 3},
+		"tags[1]":             {server.Fields["tags"].Items[1], 6},
+		"rules[1]":            {second, 10},
+		"rules[1].name":       {second.Fields["name"], 11},
+		"rules[1].opts.level": {second.Fields["opts"].Fields["level"], 12},
+	} {
+		if tt.node.Line != tt.want {
+			t.Errorf("%s 的行号 = %d，期望 %d", name, tt.node.Line, tt.want)
+		}
+	}
+}
@@ -0,0 +1,206 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// currentConfigVersion 当前程序使用的配置版本（config_version），没有该字段的配置视为版本 0
+const currentConfigVersion = 1
+
+// configMigrations 按版本逐级升级配置：configMigrations[n] 把版本 n 的配置改写为版本 n+1，返回改动说明
+var configMigrations = map[int]func(root *configNode) []string{
+	0: migrateConfigV0,
+}
+
+// migrateConfigV0 版本 0 → 1：developer_mode 移到 developer.enabled；
+// email_quality 中手动选择相关的 show_scores、allow_manual、show_all_emails 移到 email_quality.selection
+func migrateConfigV0(root *configNode) []string {
+	moves := [][2]string{
+		{"developer_mode", "developer.enabled"},
+		{"email_quality.show_scores", "email_quality.selection.show_scores"},
+		{"email_quality.allow_manual", "email_quality.selection.allow_manual"},
+		{"email_quality.show_all_emails", "email_quality.selection.show_all_emails"},
+	}
+	var notes []string
+	for _, move := range moves {
+		if moveConfigKey(root, move[0], move[1]) {
+			notes = append(notes, move[0]+" → "+move[1])
+		}
+	}
+	return notes
+}
+
+// remove 删除对象中的键，返回被删除的值及其位置
+func (n *configNode) remove(key string) (*configNode, int) {
+	value, ok := n.Fields[key]
+	if !ok {
+		return nil, -1
+	}
+	delete(n.Fields, key)
+	for i, existing := range n.Keys {
+		if existing == key {
+			n.Keys = append(n.Keys[:i], n.Keys[i+1:]...)
+			return value, i
+		}
+	}
+	return value, -1
+}
+
+// insert 在对象的 index 处插入新键（index 无效时追加到末尾）
+func (n *configNode) insert(key string, value *configNode, index int) {
+	if _, ok := n.Fields[key]; ok || index < 0 || index > len(n.Keys) {
+		n.set(key, value)
+		return
+	}
+	n.Keys = append(n.Keys[:index], append([]string{key}, n.Keys[index:]...)...)
+	n.Fields[key] = value
+}
+
+// configObjectAt 点分路径上的对象，create 为 true 时创建缺少的层级（新建的对象插在 root 的 index 处）
+func configObjectAt(root *configNode, keys []string, create bool, index int) *configNode {
+	node := root
+	for i, key := range keys {
+		next := node.Fields[key]
+		if next == nil && create {
+			next = newObjectNode(node.Line)
+			if i == 0 {
+				node.insert(key, next, index)
+			} else {
+				node.set(key, next)
+			}
+		}
+		if next == nil || next.Kind != nodeObject {
+			return nil
+		}
+		node = next
+	}
+	return node
+}
+
+// moveConfigKey 把 from 处的值移到 to（均为点分路径）；to 已有值时保留 to，只删除 from。返回是否有改动
+func moveConfigKey(root *configNode, from, to string) bool {
+	fromKeys, toKeys := strings.Split(from, "."), strings.Split(to, ".")
+	parent := configObjectAt(root, fromKeys[:len(fromKeys)-1], false, -1)
+	if parent == nil {
+		return false
+	}
+	value, index := parent.remove(fromKeys[len(fromKeys)-1])
+	if value == nil {
+		return false
+	}
+	// 新建的层级放在原键所在的位置，如 email_quality.selection 位于原 show_scores 处
+	common := 0
+	for common < len(fromKeys)-1 && common < len(toKeys)-1 && fromKeys[common] == toKeys[common] {
+		common++
+	}
+	base := configObjectAt(root, toKeys[:common], false, -1)
+	if common != len(fromKeys)-1 {
+		index = -1
+	}
+	target := configObjectAt(base, toKeys[common:len(toKeys)-1], true, index)
+	if target == nil {
+		return true
+	}
+	last := toKeys[len(toKeys)-1]
+	if _, exists := target.Fields[last]; !exists {
+		if len(toKeys)-1 == common {
+			target.insert(last, value, index)
+		} else {
+			target.set(last, value)
+		}
+	}
+	return true
+}
+
+// configVersionOf 配置树中的 config_version
+func configVersionOf(root *configNode) (int, error) {
+	node := root.Fields["config_version"]
+	if node == nil || node.Kind == nodeNull {
+		return 0, nil
+	}
+	version, err := strconv.Atoi(node.Str)
+	if node.Kind != nodeNumber || err != nil || version < 0 {
+		return 0, syntaxErrorf(node.Line, "config_version 必须是 ≥ 0 的整数")
+	}
+	return version, nil
+}
+
+// migrateConfigNode 把配置树升级到当前版本，返回原来的版本与改动说明；更新的程序写入的配置保持不变
+func migrateConfigNode(root *configNode) (int, []string, error) {
+	if root.Kind != nodeObject {
+		return 0, nil, nil
+	}
+	from, err := configVersionOf(root)
+	if err != nil || from >= currentConfigVersion {
+		return from, nil, err
+	}
+	var notes []string
+	for version := from; version < currentConfigVersion; version++ {
+		notes = append(notes, configMigrations[version](root)...)
+	}
+	root.remove("config_version")
+	root.insert("config_version", &configNode{Kind: nodeNumber, Str: strconv.Itoa(currentConfigVersion)}, 0)
+	return from, notes, nil
+}
+
+// configVersionWarning 配置由更新版本的程序写入时的提示
+func configVersionWarning(version int) string {
+	return fmt.Sprintf("配置由更新版本的程序写入（config_version %d，当前程序支持到 %d），不认识的配置项将被忽略，保存时写成版本 %d",
+		version, currentConfigVersion, currentConfigVersion)
+}
+
+// upgradeConfigFile 配置文件的版本低于当前版本时就地升级，原文件备份为 <文件名>.v<版本>.bak，返回升级说明（YAML 与 TOML 不改写，只返回需要手动修改的说明）；
+// 不需要升级或文件无法解析时返回空字符串（解析错误由随后的加载报告）
+func upgradeConfigFile(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", nil
+	}
+	root, err := parseConfigNode(configFormatOf(path), data)
+	if err != nil {
+		return "", nil
+	}
+	from, notes, err := migrateConfigNode(root)
+	if err != nil || from >= currentConfigVersion {
+		return "", nil
+	}
+
+	if !configWritable(path) {
+		// YAML 与 TOML 改写后会丢失注释，只在内存中升级，由用户按说明手动修改
+		message := fmt.Sprintf("%s 的配置版本 %d 低于当前版本 %d，已在内存中升级；程序不会改写 YAML/TOML 配置，请手动修改", path, from, currentConfigVersion)
+		if len(notes) > 0 {
+			message += "：" + strings.Join(notes, "，")
+		}
+		return message, nil
+	}
+
+	var compact, upgraded bytes.Buffer
+	root.writeJSON(&compact)
+	if err := json.Indent(&upgraded, compact.Bytes(), "", "  "); err != nil {
+		return "", fmt.Errorf("升级配置失败: %v", err)
+	}
+	upgraded.WriteByte('\n')
+
+	mode := os.FileMode(0644)
+	if info, err := os.Stat(path); err == nil {
+		mode = info.Mode().Perm()
+	}
+	backup := fmt.Sprintf("%s.v%d.bak", path, from)
+	if err := os.WriteFile(backup, data, mode); err != nil {
+		return "", fmt.Errorf("备份配置文件失败: %v", err)
+	}
+	if err := os.WriteFile(path, upgraded.Bytes(), mode); err != nil {
+		return "", fmt.Errorf("写入升级后的配置失败: %v", err)
+	}
+
+	message := fmt.Sprintf("%s 已从配置版本 %d 升级到 %d，原文件备份为 %s（其中含有 Cookie 与 dsid，确认无误后请删除，或用 purge-local-data -include-config -force 一并清除）", path, from, currentConfigVersion, backup)
+	if len(notes) > 0 {
+		message += "：" + strings.Join(notes, "，")
+	}
+	return message, nil
+}
@@ -0,0 +1,245 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// configRule 配置项的取值范围
+type configRule struct {
+	Min, Max int // Max 小于 Min 时不限上限
+	Enum     []string
+}
+
+// configRules 需要额外校验取值范围的配置项（按点分路径）
+var configRules = map[string]configRule{
+	"config_version":                          {Min: 0, Max: -1},
+	"count":                                   {Min: 0, Max: -1},
+	"delay_seconds":                           {Min: 0, Max: -1},
+	"max_concurrency":                         {Min: 0, Max: -1},
+	"timeout_seconds":                         {Min: 0, Max: -1},
+	"requests_per_minute":                     {Min: 0, Max: -1},
+	"request_jitter_percent":                  {Min: 0, Max: 100},
+	"rate_limit_cooldown_minutes":             {Min: 0, Max: -1},
+	"batch_chunk_size":                        {Min: 0, Max: -1},
+	"batch_chunk_pause_minutes":               {Min: 0, Max: -1},
+	"retry_policy.max_retries":                {Min: -1, Max: -2},
+	"retry_policy.base_delay_ms":              {Min: 0, Max: -1},
+	"retry_policy.max_delay_seconds":          {Min: 0, Max: -1},
+	"retry_policy.jitter_percent":             {Min: 0, Max: 100},
+	"retry_policy.rate_limit_retries":         {Min: -1, Max: -2},
+	"retry_policy.rate_limit_backoff_seconds": {Min: 0, Max: -1},
+	"session_refresh.interval_minutes":        {Min: 0, Max: -1},
+	"app_lock.idle_timeout_minutes":           {Min: 0, Max: -1},
+	"email_quality.min_score":                 {Min: 0, Max: 100},
+	"output_format":                           {Enum: []string{"", "text", OutputFormatJSON}},
+	"logging.level":                           {Enum: []string{"", "debug", "info", "warn", "error"}},
+	"logging.format":                          {Enum: []string{"", "text", OutputFormatJSON}},
+	"logging.max_size_mb":                     {Min: 0, Max: -1},
+	"logging.max_backups":                     {Min: 0, Max: -1},
+	"daemon.poll_seconds":                     {Min: 0, Max: -1},
+	"serve.max_concurrent":                    {Min: 0, Max: -1},
+	"serve.global_max_concurrent":             {Min: 0, Max: -1},
+}
+
+// configValidator 按 Config 的结构校验配置树
+type configValidator struct {
+	warnings []string
+}
+
+// validateConfigNode 校验配置树：类型不符或超出范围时返回带行号的错误，未知的配置项只给出警告；
+// YAML 中未加引号的标量在字段为字符串时按原文转换为字符串
+func validateConfigNode(root *configNode) ([]string, error) {
+	v := &configValidator{}
+	err := v.check(root, reflect.TypeOf(Config{}), "")
+	return v.warnings, err
+}
+
+// jsonFields 结构体中按 JSON 键名索引的字段
+func jsonFields(t reflect.Type) (map[string]reflect.Type, []string) {
+	fields := make(map[string]reflect.Type)
+	var names []string
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		fields[name] = field.Type
+		names = append(names, name)
+	}
+	return fields, names
+}
+
+// joinPath 拼接配置项路径
+func joinPath(prefix, key string) string {
+	if prefix == "" {
+		return key
+	}
+	return prefix + "." + key
+}
+
+// check 校验 node 是否能解码为 t 类型
+func (v *configValidator) check(node *configNode, t reflect.Type, path string) error {
+	if t.Kind() == reflect.Interface {
+		return nil
+	}
+	if node.Kind == nodeNull {
+		// null 解码时保持字段原值
+		return nil
+	}
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if node.Plain && t.Kind() == reflect.String {
+		node.Kind, node.Str = nodeString, node.Raw
+	}
+
+	switch {
+	case t == reflect.TypeOf(time.Time{}):
+		if node.Kind != nodeString {
+			return v.typeError(node, path, "时间字符串")
+		}
+		if _, err := time.Parse(time.RFC3339Nano, node.Str); err != nil {
+			return syntaxErrorf(node.Line, "%s 必须是 RFC 3339 格式的时间，如 2024-01-02T15:04:05Z", path)
+		}
+	case t.Kind() == reflect.String:
+		if node.Kind != nodeString {
+			return v.typeError(node, path, "字符串")
+		}
+		if rule, ok := configRules[path]; ok && rule.Enum != nil && !containsString(rule.Enum, node.Str) {
+			return syntaxErrorf(node.Line, "%s 必须是 %s 之一，实际为 %q", path, strings.Join(nonEmpty(rule.Enum), "、"), node.Str)
+		}
+	case t.Kind() == reflect.Bool:
+		if node.Kind != nodeBool {
+			return v.typeError(node, path, "布尔值（true 或 false）")
+		}
+	case t.Kind() >= reflect.Int && t.Kind() <= reflect.Uint64:
+		return v.checkInteger(node, t, path)
+	case t.Kind() == reflect.Float32 || t.Kind() == reflect.Float64:
+		if node.Kind != nodeNumber {
+			return v.typeError(node, path, "数字")
+		}
+	case t.Kind() == reflect.Slice || t.Kind() == reflect.Array:
+		if node.Kind != nodeArray {
+			return v.typeError(node, path, "列表")
+		}
+		for i, item := range node.Items {
+			if err := v.check(item, t.Elem(), fmt.Sprintf("%s[%d]", path, i)); err != nil {
+				return err
+			}
+		}
+	case t.Kind() == reflect.Map:
+		if node.Kind != nodeObject {
+			return v.typeError(node, path, "对象")
+		}
+		for _, key := range node.Keys {
+			if err := v.check(node.Fields[key], t.Elem(), joinPath(path, key)); err != nil {
+				return err
+			}
+		}
+	case t.Kind() == reflect.Struct:
+		if node.Kind != nodeObject {
+			return v.typeError(node, path, "对象")
+		}
+		fields, names := jsonFields(t)
+		for _, key := range node.Keys {
+			child := node.Fields[key]
+			fieldType, ok := fields[key]
+			if !ok {
+				v.unknownKey(child, joinPath(path, key), key, names)
+				continue
+			}
+			if err := v.check(child, fieldType, joinPath(path, key)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// checkInteger 校验整数字段及其取值范围
+func (v *configValidator) checkInteger(node *configNode, t reflect.Type, path string) error {
+	rule, hasRule := configRules[path]
+	expect := "整数"
+	if hasRule {
+		switch {
+		case rule.Max >= rule.Min:
+			expect = fmt.Sprintf(" %d~%d 之间的整数", rule.Min, rule.Max)
+		default:
+			expect = fmt.Sprintf(" ≥ %d 的整数", rule.Min)
+		}
+	}
+	if node.Kind != nodeNumber {
+		return v.typeError(node, path, expect)
+	}
+	n, err := strconv.ParseInt(node.Str, 10, 64)
+	if err != nil {
+		// 1e3、5.0 这类写法也是整数
+		f, ferr := strconv.ParseFloat(node.Str, 64)
+		if ferr != nil || f != math.Trunc(f) || math.Abs(f) > 1<<53 {
+			return syntaxErrorf(node.Line, "%s 必须是%s，实际为 %s", path, expect, node.Str)
+		}
+		n = int64(f)
+		node.Str = strconv.FormatInt(n, 10)
+	}
+	overflow := false
+	if t.Kind() >= reflect.Uint && t.Kind() <= reflect.Uint64 {
+		overflow = n < 0 || reflect.New(t).Elem().OverflowUint(uint64(n))
+	} else {
+		overflow = reflect.New(t).Elem().OverflowInt(n)
+	}
+	if overflow || hasRule && (n < int64(rule.Min) || rule.Max >= rule.Min && n > int64(rule.Max)) {
+		return syntaxErrorf(node.Line, "%s 必须是%s，实际为 %d", path, expect, n)
+	}
+	return nil
+}
+
+// typeError 类型不符的错误
+func (v *configValidator) typeError(node *configNode, path, expect string) error {
+	actual := node.kindName()
+	if node.Kind == nodeString {
+		actual = fmt.Sprintf("字符串 %q", node.Str)
+	}
+	return syntaxErrorf(node.Line, "%s 必须是%s，实际为%s", path, expect, actual)
+}
+
+// unknownKey 记录未知配置项的警告，有拼写相近的配置项时给出建议
+func (v *configValidator) unknownKey(node *configNode, path, key string, names []string) {
+	message := fmt.Sprintf("未知的配置项 %s，将被忽略", path)
+	if suggestion, _ := closestAlias(names, key, 4); suggestion != "" {
+		message = fmt.Sprintf("未知的配置项 %s（是否想写 %s？），将被忽略", path, path[:len(path)-len(key)]+suggestion)
+	}
+	v.warnings = append(v.warnings, (&configSyntaxError{Line: node.Line, Msg: message}).Error())
+}
+
+// containsString list 中是否包含 s
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+// nonEmpty 去掉空字符串
+func nonEmpty(list []string) []string {
+	var result []string
+	for _, item := range list {
+		if item != "" {
+			result = append(result, item)
+		}
+	}
+	return result
+}
@@ -0,0 +1,221 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pelletier/go-toml/v2"
+	"github.com/pelletier/go-toml/v2/unstable"
+)
+
+// TOML 配置由 go-toml 解析与校验，再按语法树中各键所在的行号与出现顺序转换为配置树；日期时间按字符串读取
+
+// parseTOMLNode 解析 TOML 配置
+func parseTOMLNode(data []byte) (*configNode, error) {
+	var doc map[string]interface{}
+	if err := toml.Unmarshal(data, &doc); err != nil {
+		return nil, tomlSyntaxError(data, err)
+	}
+	positions, err := tomlKeyPositions(data)
+	if err != nil {
+		return nil, tomlSyntaxError(data, err)
+	}
+	return convertTOMLValue(doc, "", 1, positions)
+}
+
+// tomlSyntaxError 把 go-toml 的错误转换为带行号的错误。重复定义的表、键等错误不带位置，
+// 按行逐步截取文件重新解析，第一次出现同样错误的行即为出错的行
+func tomlSyntaxError(data []byte, err error) error {
+	message := strings.TrimPrefix(err.Error(), "toml: ")
+	var decodeErr *toml.DecodeError
+	if errors.As(err, &decodeErr) {
+		line, _ := decodeErr.Position()
+		return syntaxErrorf(line, "TOML 语法错误: %s", message)
+	}
+	lines := strings.SplitAfter(string(data), "\n")
+	for i := range lines {
+		var doc map[string]interface{}
+		prefix := strings.Join(lines[:i+1], "")
+		if prefixErr := toml.Unmarshal([]byte(prefix), &doc); prefixErr != nil && prefixErr.Error() == err.Error() {
+			return syntaxErrorf(i+1, "TOML 语法错误: %s", message)
+		}
+	}
+	return fmt.Errorf("TOML 语法错误: %s", message)
+}
+
+// tomlPosition 键在文件中的行号与首次出现的顺序
+type tomlPosition struct {
+	line  int
+	order int
+}
+
+// tomlWalker 遍历语法树记录键的位置。路径由各级键以 \x00 连接，表数组与数组的元素记为 [序号]
+type tomlWalker struct {
+	parser    *unstable.Parser
+	positions map[string]tomlPosition
+	arrays    map[string]int // 表数组已有的元素数
+}
+
+// tomlKeyPositions 记录每个键（含表数组与数组中的元素）所在的行号与出现顺序
+func tomlKeyPositions(data []byte) (map[string]tomlPosition, error) {
+	w := &tomlWalker{parser: &unstable.Parser{}, positions: map[string]tomlPosition{}, arrays: map[string]int{}}
+	w.parser.Reset(data)
+	current := ""
+	for w.parser.NextExpression() {
+		expr := w.parser.Expression()
+		switch expr.Kind {
+		case unstable.Table, unstable.ArrayTable:
+			path, line := w.tablePath(expr.Key())
+			if expr.Kind == unstable.ArrayTable {
+				index := w.arrays[path]
+				w.arrays[path] = index + 1
+				path = tomlIndexPath(path, index)
+				w.record(path, line)
+			}
+			current = path
+		case unstable.KeyValue:
+			w.keyValue(current, expr)
+		}
+	}
+	return w.positions, w.parser.Error()
+}
+
+// tomlKeyPath 路径中的下一级键
+func tomlKeyPath(path, key string) string {
+	return path + "\x00" + key
+}
+
+// tomlIndexPath 路径中数组的第 index 个元素
+func tomlIndexPath(path string, index int) string {
+	return path + "\x00[" + strconv.Itoa(index) + "]"
+}
+
+// record 记录路径首次出现的位置
+func (w *tomlWalker) record(path string, line int) {
+	if _, ok := w.positions[path]; !ok {
+		w.positions[path] = tomlPosition{line: line, order: len(w.positions)}
+	}
+}
+
+// line 节点所在的行号，语法树中没有记录位置的节点（如布尔值）使用 fallback
+func (w *tomlWalker) line(node *unstable.Node, fallback int) int {
+	if node.Raw.Length == 0 {
+		return fallback
+	}
+	return w.parser.Shape(node.Raw).Start.Line
+}
+
+// tablePath 表头的路径；经过表数组时进入其最后一个元素
+func (w *tomlWalker) tablePath(keys unstable.Iterator) (string, int) {
+	path, line := "", 0
+	for keys.Next() {
+		key := keys.Node()
+		line = w.line(key, line)
+		path = tomlKeyPath(path, string(key.Data))
+		w.record(path, line)
+		if count, ok := w.arrays[path]; ok && !keys.IsLast() {
+			path = tomlIndexPath(path, count-1)
+		}
+	}
+	return path, line
+}
+
+// keyValue 记录键值对（含点分键的各级）及其值中的键
+func (w *tomlWalker) keyValue(table string, kv *unstable.Node) {
+	path, line := table, 0
+	for keys := kv.Key(); keys.Next(); {
+		key := keys.Node()
+		line = w.line(key, line)
+		path = tomlKeyPath(path, string(key.Data))
+		w.record(path, line)
+	}
+	w.value(path, kv.Value(), line)
+}
+
+// value 记录内联表中的键与数组中的元素
+func (w *tomlWalker) value(path string, node *unstable.Node, line int) {
+	switch node.Kind {
+	case unstable.InlineTable:
+		for children := node.Children(); children.Next(); {
+			if child := children.Node(); child.Kind == unstable.KeyValue {
+				w.keyValue(path, child)
+			}
+		}
+	case unstable.Array:
+		index := 0
+		for children := node.Children(); children.Next(); {
+			child := children.Node()
+			if child.Kind == unstable.Comment {
+				continue
+			}
+			itemPath, itemLine := tomlIndexPath(path, index), w.line(child, line)
+			w.record(itemPath, itemLine)
+			w.value(itemPath, child, itemLine)
+			index++
+		}
+	}
+}
+
+// convertTOMLValue 把 go-toml 解析出的值转换为配置树，行号与键的顺序取自 positions，找不到时沿用上一级的行号
+func convertTOMLValue(value interface{}, path string, line int, positions map[string]tomlPosition) (*configNode, error) {
+	if position, ok := positions[path]; ok {
+		line = position.line
+	}
+	switch v := value.(type) {
+	case map[string]interface{}:
+		node := newObjectNode(line)
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		order := func(key string) int {
+			if position, ok := positions[tomlKeyPath(path, key)]; ok {
+				return position.order
+			}
+			return len(positions)
+		}
+		sort.Strings(keys)
+		sort.SliceStable(keys, func(i, j int) bool { return order(keys[i]) < order(keys[j]) })
+		for _, key := range keys {
+			child, err := convertTOMLValue(v[key], tomlKeyPath(path, key), line, positions)
+			if err != nil {
+				return nil, err
+			}
+			node.set(key, child)
+		}
+		return node, nil
+	case []interface{}:
+		node := &configNode{Kind: nodeArray, Line: line}
+		for i, item := range v {
+			child, err := convertTOMLValue(item, tomlIndexPath(path, i), line, positions)
+			if err != nil {
+				return nil, err
+			}
+			node.Items = append(node.Items, child)
+		}
+		return node, nil
+	case string:
+		return &configNode{Kind: nodeString, Line: line, Str: v}, nil
+	case bool:
+		return &configNode{Kind: nodeBool, Line: line, Bool: v}, nil
+	case int64:
+		return &configNode{Kind: nodeNumber, Line: line, Str: strconv.FormatInt(v, 10)}, nil
+	case float64:
+		if math.IsInf(v, 0) || math.IsNaN(v) {
+			return nil, syntaxErrorf(line, "不支持的数字 %v", v)
+		}
+		return &configNode{Kind: nodeNumber, Line: line, Str: strconv.FormatFloat(v, 'g', -1, 64)}, nil
+	case time.Time:
+		return &configNode{Kind: nodeString, Line: line, Str: v.Format(time.RFC3339Nano)}, nil
+	case fmt.Stringer:
+		// 不带时区的日期与时间（toml.LocalDate 等）
+		return &configNode{Kind: nodeString, Line: line, Str: v.String()}, nil
+	default:
+		return nil, syntaxErrorf(line, "无法识别的值 %v", v)
+	}
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"regexp"
+	"strconv"
+
+	"gopkg.in/yaml.v3"
+)
+
+// YAML 配置由 yaml.v3 解析，再按节点的行号转换为配置树；锚点与别名按引用的内容展开，合并键（<<）与多文档不支持
+
+// yamlErrorPattern yaml.v3 语法错误中的行号
+var yamlErrorPattern = regexp.MustCompile(`^yaml: line (\d+): (.*)$`)
+
+// parseYAMLNode 解析 YAML 配置
+func parseYAMLNode(data []byte) (*configNode, error) {
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	var doc yaml.Node
+	if err := decoder.Decode(&doc); err != nil {
+		if errors.Is(err, io.EOF) {
+			return newObjectNode(1), nil
+		}
+		return nil, yamlSyntaxError(err)
+	}
+	var next yaml.Node
+	if err := decoder.Decode(&next); !errors.Is(err, io.EOF) {
+		if err != nil {
+			return nil, yamlSyntaxError(err)
+		}
+		return nil, syntaxErrorf(next.Line, "不支持多文档 YAML")
+	}
+	if len(doc.Content) == 0 {
+		return newObjectNode(1), nil
+	}
+	return convertYAMLNode(doc.Content[0], 0)
+}
+
+// yamlSyntaxError 把 yaml.v3 的错误转换为带行号的错误
+func yamlSyntaxError(err error) error {
+	if m := yamlErrorPattern.FindStringSubmatch(err.Error()); m != nil {
+		line, _ := strconv.Atoi(m[1])
+		return syntaxErrorf(line, "YAML 语法错误: %s", m[2])
+	}
+	return fmt.Errorf("YAML 语法错误: %v", err)
+}
+
+// yamlAliasDepth 别名展开的最大层数，防止互相引用
+const yamlAliasDepth = 32
+
+// convertYAMLNode 把 yaml.v3 的节点转换为配置树，depth 为已展开的别名层数
+func convertYAMLNode(n *yaml.Node, depth int) (*configNode, error) {
+	switch n.Kind {
+	case yaml.AliasNode:
+		if depth >= yamlAliasDepth {
+			return nil, syntaxErrorf(n.Line, "别名嵌套过深")
+		}
+		node, err := convertYAMLNode(n.Alias, depth+1)
+		if err != nil {
+			return nil, err
+		}
+		node.Line = n.Line
+		return node, nil
+	case yaml.MappingNode:
+		node := newObjectNode(n.Line)
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			keyNode, valueNode := n.Content[i], n.Content[i+1]
+			if keyNode.Kind != yaml.ScalarNode {
+				return nil, syntaxErrorf(keyNode.Line, "键必须是字符串")
+			}
+			if keyNode.ShortTag() == "!!merge" {
+				return nil, syntaxErrorf(keyNode.Line, "不支持合并键 <<")
+			}
+			if _, dup := node.Fields[keyNode.Value]; dup {
+				return nil, syntaxErrorf(keyNode.Line, "重复的键 %s", keyNode.Value)
+			}
+			child, err := convertYAMLNode(valueNode, depth)
+			if err != nil {
+				return nil, err
+			}
+			if child.Kind == nodeObject || child.Kind == nodeArray {
+				child.Line = keyNode.Line
+			}
+			node.set(keyNode.Value, child)
+		}
+		return node, nil
+	case yaml.SequenceNode:
+		node := &configNode{Kind: nodeArray, Line: n.Line}
+		for _, item := range n.Content {
+			child, err := convertYAMLNode(item, depth)
+			if err != nil {
+				return nil, err
+			}
+			node.Items = append(node.Items, child)
+		}
+		return node, nil
+	case yaml.ScalarNode:
+		return convertYAMLScalar(n)
+	default:
+		return nil, syntaxErrorf(n.Line, "无法识别的 YAML 内容")
+	}
+}
+
+// convertYAMLScalar 标量按 yaml.v3 解析出的类型转换；未加引号的标量保留原文，字段为字符串时按原文使用
+func convertYAMLScalar(n *yaml.Node) (*configNode, error) {
+	node := &configNode{Kind: nodeString, Line: n.Line, Str: n.Value}
+	if n.Style == 0 {
+		node.Plain, node.Raw = true, n.Value
+	}
+	switch n.ShortTag() {
+	case "!!null":
+		node.Kind = nodeNull
+	case "!!bool":
+		if err := n.Decode(&node.Bool); err != nil {
+			return nil, syntaxErrorf(n.Line, "无效的布尔值 %s", n.Value)
+		}
+		node.Kind = nodeBool
+	case "!!int", "!!float":
+		var value interface{}
+		if err := n.Decode(&value); err != nil {
+			return nil, syntaxErrorf(n.Line, "无效的数字 %s", n.Value)
+		}
+		switch number := value.(type) {
+		case int:
+			node.Str = strconv.Itoa(number)
+		case int64:
+			node.Str = strconv.FormatInt(number, 10)
+		case uint64:
+			node.Str = strconv.FormatUint(number, 10)
+		case float64:
+			if math.IsInf(number, 0) || math.IsNaN(number) {
+				return nil, syntaxErrorf(n.Line, "不支持的数字 %s", n.Value)
+			}
+			node.Str = strconv.FormatFloat(number, 'g', -1, 64)
+		default:
+			return nil, syntaxErrorf(n.Line, "无效的数字 %s", n.Value)
+		}
+		node.Kind = nodeNumber
+	}
+	return node, nil
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// 关闭短语确认的特殊值
+const confirmPhraseDisabled = "-"
+
+// 全局命令行选项
+var (
+	assumeYes   bool // --yes / -y：自动接受确认（彻底删除除外）
+	forceDelete bool // --force-delete：配合 --yes 自动确认彻底删除
+	outputJSON  bool // --json：子命令在标准输出以 JSON 输出结果
+)
+
+// parseGlobalFlags 取出任意位置的全局选项，返回剩余参数
+func parseGlobalFlags(args []string) []string {
+	rest := make([]string, 0, len(args))
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		switch {
+		case arg == "--yes" || arg == "-yes" || arg == "-y":
+			assumeYes = true
+		case arg == "--force-delete" || arg == "-force-delete":
+			forceDelete = true
+		case arg == "--json" || arg == "-json":
+			outputJSON = true
+		case arg == "--plain" || arg == "-plain":
+			plainUI = true
+		case arg == "--progress" || arg == "-progress":
+			if i+1 < len(args) {
+				i++
+				setProgressFormat(args[i])
+			}
+		case strings.HasPrefix(arg, "--progress=") || strings.HasPrefix(arg, "-progress="):
+			setProgressFormat(arg[strings.Index(arg, "=")+1:])
+		case arg == "--profile" || arg == "-profile":
+			if i+1 < len(args) {
+				i++
+				profileOverride = args[i]
+			}
+		case strings.HasPrefix(arg, "--profile=") || strings.HasPrefix(arg, "-profile="):
+			profileOverride = arg[strings.Index(arg, "=")+1:]
+		case arg == "--set" || arg == "-set":
+			if i+1 < len(args) {
+				i++
+				configFlagOverrides = append(configFlagOverrides, args[i])
+			}
+		case strings.HasPrefix(arg, "--set=") || strings.HasPrefix(arg, "-set="):
+			configFlagOverrides = append(configFlagOverrides, arg[strings.Index(arg, "=")+1:])
+		default:
+			rest = append(rest, arg)
+		}
+	}
+	if forceDelete && !assumeYes {
+		printWarning("--force-delete 需要与 --yes 同时使用，已忽略")
+		forceDelete = false
+	}
+	return rest
+}
+
+// ConfirmConfig 单类操作的确认策略
+type ConfirmConfig struct {
+	Phrase         string `json:"phrase"`           // 需要完整输入的确认短语，"-" 表示只需 y/n
+	PhraseMinCount int    `json:"phrase_min_count"` // 操作数量达到该值时才要求输入短语，0 表示总是要求
+	MaxCount       int    `json:"max_count"`        // 单次操作的数量上限，0 表示不限
+}
+
+// SafetyConfig 危险操作的确认配置
+type SafetyConfig struct {
+	PermanentDelete ConfirmConfig `json:"permanent_delete"` // 彻底删除，默认需输入 DELETE
+	Deactivate      ConfirmConfig `json:"deactivate"`       // 批量停用，默认只需 y/n
+
+	// 跳过所有确认，仅用于无人值守的脚本运行
+	DisableConfirmations bool `json:"disable_confirmations"`
+}
+
+// phraseRequired 当前数量是否需要输入确认短语
+func (c ConfirmConfig) phraseRequired(count int) bool {
+	return c.Phrase != "" && c.Phrase != confirmPhraseDisabled && count >= c.PhraseMinCount
+}
+
+// confirmOperation 按配置确认批量操作：先检查数量上限，再根据数量决定 y/n 或输入确认短语。
+// destructive 为 true 的操作（彻底删除）不会被 --yes 单独自动确认
+func confirmOperation(settings ConfirmConfig, message string, count int, destructive bool) bool {
+	if settings.MaxCount > 0 && count > settings.MaxCount {
+		printError(fmt.Sprintf("单次最多操作 %d 个邮箱（当前 %d 个），可调整 safety 中的 max_count", settings.MaxCount, count))
+		return false
+	}
+	if destructive && assumeYes && !forceDelete {
+		printError("--yes 不会自动确认彻底删除，如确需无人值守删除请同时指定 --force-delete")
+		return false
+	}
+	if getCurrentConfig().Safety.DisableConfirmations {
+		printWarning("已按配置跳过确认 (safety.disable_confirmations)")
+		return true
+	}
+	if assumeYes {
+		fmt.Printf("\n  "+ColorYellow+"?"+ColorReset+" %s "+ColorDim+"(--yes 自动确认)"+ColorReset+"\n", message)
+		return true
+	}
+	if !settings.phraseRequired(count) {
+		return confirmAction(message)
+	}
+
+	fmt.Println()
+	input := readInput(fmt.Sprintf("%s，请输入 "+ColorBold+ColorRed+"%s"+ColorReset+" 确认: ", message, settings.Phrase))
+	if input != settings.Phrase {
+		printInfo("输入不匹配")
+		return false
+	}
+	return true
+}
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// 冷却记录写入时使用的临时文件名
+const cooldownTempPattern = ".cooldown-*.tmp"
+
+// Apple 限流但未给出 retryAfter 时默认的冷却时间
+const defaultRateLimitCooldownMinutes = 60
+
+// RateLimitCooldown 持久化的限流冷却，重启程序后仍然有效
+type RateLimitCooldown struct {
+	Until      time.Time `json:"until"`
+	RecordedAt time.Time `json:"recorded_at"`
+	Reason     string    `json:"reason,omitempty"`
+}
+
+// 同一进程内并发的创建任务共用一个冷却文件
+var cooldownMutex sync.Mutex
+
+// cooldownFile 冷却记录文件，与账号锁一样位于状态目录并按 dsid 区分
+func cooldownFile(config *Config) string {
+	name := "cooldown.json"
+	if dsid := accountLockUnsafeChars.ReplaceAllString(config.DSID, "_"); dsid != "" {
+		name = "cooldown-" + dsid + ".json"
+	}
+	return filepath.Join(stateDir(config), name)
+}
+
+// activeCooldown 返回尚未结束的冷却截止时间，没有冷却时返回零值
+func activeCooldown(config *Config) time.Time {
+	cooldownMutex.Lock()
+	defer cooldownMutex.Unlock()
+	data, err := os.ReadFile(cooldownFile(config))
+	if err != nil {
+		return time.Time{}
+	}
+	var cooldown RateLimitCooldown
+	if cooldownFormat.decode(data, &cooldown) != nil || !time.Now().Before(cooldown.Until) {
+		return time.Time{}
+	}
+	return cooldown.Until
+}
+
+// cooldownDuration 限流错误对应的冷却时间：Apple 给出 retryAfter 时以它为准，否则使用 rate_limit_cooldown_minutes
+func cooldownDuration(config *Config, err error) time.Duration {
+	if wait, ok := explicitRetryAfter(err); ok {
+		return wait
+	}
+	minutes := config.RateLimitCooldownMinutes
+	if minutes <= 0 {
+		minutes = defaultRateLimitCooldownMinutes
+	}
+	return time.Duration(minutes) * time.Minute
+}
+
+// trackCooldown 根据创建结果更新冷却记录：被限流时记录（保留更晚的截止时间），创建成功时清除
+func trackCooldown(config *Config, err error) {
+	if err == nil {
+		cooldownMutex.Lock()
+		os.Remove(cooldownFile(config))
+		cooldownMutex.Unlock()
+		return
+	}
+	if _, limited := retryAfterFor(err); !limited {
+		return
+	}
+
+	until := time.Now().Add(cooldownDuration(config, err))
+	if current := activeCooldown(config); current.After(until) {
+		return
+	}
+	logger().Warn("被限流，记录冷却", "until", until.Format(time.RFC3339), "error", err)
+	cooldownMutex.Lock()
+	defer cooldownMutex.Unlock()
+	path := cooldownFile(config)
+	if mkErr := os.MkdirAll(filepath.Dir(path), 0700); mkErr == nil {
+		cooldownFormat.write(path, cooldownTempPattern, RateLimitCooldown{Until: until, RecordedAt: time.Now(), Reason: err.Error()})
+	}
+}
+
+// formatCooldown 冷却提示，如 “被限流，15:04 后可再次创建（还需 12m0s）”
+func formatCooldown(until time.Time) string {
+	return fmt.Sprintf("被限流，%s 后可再次创建（还需 %s）", until.Format("15:04"), time.Until(until).Round(time.Second))
+}
+
+// rateLimitStatus 主菜单常驻的限流状态条：冷却中时显示剩余冷却时间，否则显示正常，
+// 设置了 requests_per_minute 时附带共享限速器的余量
+func rateLimitStatus(config *Config) string {
+	status := ColorGreen + "● 正常" + ColorReset
+	if until := activeCooldown(config); !until.IsZero() {
+		status = fmt.Sprintf(ColorYellow+"● 冷却中"+ColorReset+" 还需 %s "+ColorDim+"(%s 后可再次创建)"+ColorReset,
+			time.Until(until).Round(time.Second), until.Format("15:04"))
+	}
+	if limiter, ok := config.sharedLimiter().(*requestLimiter); ok {
+		next := "可立即请求"
+		if wait := limiter.NextIn(); wait > 0 {
+			next = fmt.Sprintf("%s 后可请求", wait.Round(time.Second))
+		}
+		status += fmt.Sprintf(ColorDim+" | 全局限速 每分钟 %d 次，%s"+ColorReset, limiter.perMinute, next)
+	}
+	return ColorCyan + "限流状态:" + ColorReset + " " + status
+}
+
+// CooldownError 冷却未结束时拒绝创建，按限流归类（退出码 5）
+type CooldownError struct {
+	Until time.Time
+}
+
+func (e *CooldownError) Error() string {
+	return formatCooldown(e.Until)
+}
+
+// checkCooldown 子命令创建前检查冷却
+func checkCooldown(config *Config) error {
+	if until := activeCooldown(config); !until.IsZero() {
+		return &CooldownError{Until: until}
+	}
+	return nil
+}
+
+// waitForCooldown 菜单创建前检查冷却：未结束时询问是否等到冷却结束再继续，返回 false 表示放弃本次创建
+func waitForCooldown(config *Config) bool {
+	until := activeCooldown(config)
+	if until.IsZero() {
+		return true
+	}
+	printWarning(formatCooldown(until))
+	if !confirmAction(fmt.Sprintf("等待到 %s 后自动继续", until.Format("15:04"))) {
+		printInfo("已取消")
+		return false
+	}
+	if !waitWithCountdown(time.Until(until), "冷却结束") {
+		return false
+	}
+	announce(config, SoundRateLimitEnd, "冷却结束，继续创建")
+	return true
+}
+
+// probeQuota 批量创建前先调用一次 generate 探测配额（只生成不确认，不占用创建配额）：
+// 立即被限流时记录冷却并返回 CooldownError，而不是让整批任务逐个失败。其他错误交给批量创建自行处理
+func probeQuota(config *Config) error {
+	var probeErr error
+	withSpinner("检查创建配额", func() error {
+		_, probeErr = generateHME(config)
+		return probeErr
+	})
+	if _, limited := retryAfterFor(probeErr); !limited {
+		return nil
+	}
+	until := activeCooldown(config)
+	if until.IsZero() {
+		until = time.Now().Add(cooldownDuration(config, probeErr))
+	}
+	return fmt.Errorf("创建前预检: %w", &CooldownError{Until: until})
+}
+
+// confirmQuota 菜单批量创建前预检配额，处于限流期时询问是否等到冷却结束再开始，返回 false 表示放弃
+func confirmQuota(config *Config) bool {
+	if probeQuota(config) == nil {
+		return true
+	}
+	printWarning("预检发现当前处于限流期，现在开始批量创建会逐个失败")
+	return waitForCooldown(config)
+}
@@ -0,0 +1,439 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+)
+
+// curl 命令中需要跳过取值的选项
+var curlValueFlags = map[string]bool{
+	"-X": true, "--request": true, "-d": true, "--data": true, "--data-raw": true, "--data-binary": true,
+	"--data-urlencode": true, "-o": true, "--output": true, "-u": true, "--user": true, "-x": true, "--proxy": true,
+	"-m": true, "--max-time": true, "--connect-timeout": true,
+}
+
+// curl 中不应写入配置的请求头：由 HTTP 客户端自行设置
+var curlSkippedHeaders = map[string]bool{
+	"Content-Length": true,
+	"Host":           true,
+}
+
+// curlRequest 从“复制为 cURL”中解析出的账号参数与请求头
+type curlRequest struct {
+	BaseURL               string
+	ClientBuildNumber     string
+	ClientMasteringNumber string
+	ClientID              string
+	DSID                  string
+	Headers               map[string]string
+}
+
+// readCurlCommand 读取粘贴的 curl 命令，以 \（bash）或 ^（Windows cmd）结尾的行与下一行相连
+func readCurlCommand() string {
+	fmt.Print(ColorCyan + "  › " + ColorReset + "粘贴 curl 命令后回车:\n")
+	reader := bufio.NewReader(os.Stdin)
+	var text strings.Builder
+	for {
+		line, err := reader.ReadString('\n')
+		text.WriteString(line)
+		trimmed := strings.TrimRight(line, " \t\r\n")
+		continued := strings.HasSuffix(trimmed, "\\") || strings.HasSuffix(trimmed, "^")
+		if err != nil || (!continued && strings.TrimSpace(text.String()) != "") {
+			break
+		}
+	}
+	return joinCurlLines(text.String())
+}
+
+// joinCurlLines 把多行的 curl 命令合并为一行：去掉行尾的续行符 \ 或 ^，忽略空行
+func joinCurlLines(text string) string {
+	var lines []string
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimRight(line, " \t\r")
+		if strings.HasSuffix(line, "\\") || strings.HasSuffix(line, "^") {
+			line = line[:len(line)-1]
+		}
+		if strings.TrimSpace(line) != "" {
+			lines = append(lines, line)
+		}
+	}
+	return strings.Join(lines, " ")
+}
+
+// splitCurlCommand 按 shell 规则拆分 curl 命令，支持单引号、双引号与 $'...'；
+// Windows cmd 形式（^" 转义）先去掉 ^ 再按双引号处理
+func splitCurlCommand(command string) ([]string, error) {
+	if strings.Contains(command, `^"`) {
+		var b strings.Builder
+		for i := 0; i < len(command); i++ {
+			if command[i] == '^' && i+1 < len(command) {
+				i++
+			}
+			b.WriteByte(command[i])
+		}
+		command = b.String()
+	}
+
+	var args []string
+	var current strings.Builder
+	inArg := false
+	for i := 0; i < len(command); i++ {
+		c := command[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			if inArg {
+				args = append(args, current.String())
+				current.Reset()
+				inArg = false
+			}
+		case c == '\'':
+			end := strings.IndexByte(command[i+1:], '\'')
+			if end < 0 {
+				return nil, fmt.Errorf("单引号未闭合")
+			}
+			current.WriteString(command[i+1 : i+1+end])
+			i += end + 1
+			inArg = true
+		case c == '$' && i+1 < len(command) && command[i+1] == '\'':
+			i += 2
+			for ; i < len(command) && command[i] != '\''; i++ {
+				if command[i] == '\\' && i+1 < len(command) {
+					i++
+					switch command[i] {
+					case 'n':
+						current.WriteByte('\n')
+					case 't':
+						current.WriteByte('\t')
+					case 'r':
+						current.WriteByte('\r')
+					default:
+						current.WriteByte(command[i])
+					}
+					continue
+				}
+				current.WriteByte(command[i])
+			}
+			if i >= len(command) {
+				return nil, fmt.Errorf("$'...' 引号未闭合")
+			}
+			inArg = true
+		case c == '"':
+			i++
+			for ; i < len(command) && command[i] != '"'; i++ {
+				if command[i] == '\\' && i+1 < len(command) && strings.IndexByte("\"\\$`", command[i+1]) >= 0 {
+					i++
+				}
+				current.WriteByte(command[i])
+			}
+			if i >= len(command) {
+				return nil, fmt.Errorf("双引号未闭合")
+			}
+			inArg = true
+		case c == '\\' && i+1 < len(command):
+			i++
+			current.WriteByte(command[i])
+			inArg = true
+		default:
+			current.WriteByte(c)
+			inArg = true
+		}
+	}
+	if inArg {
+		args = append(args, current.String())
+	}
+	return args, nil
+}
+
+// parseCurlCommand 从浏览器开发者工具“复制为 cURL”得到的 iCloud 隐藏邮箱请求中取出配置所需的字段
+func parseCurlCommand(command string) (*curlRequest, error) {
+	args, err := splitCurlCommand(strings.TrimSpace(command))
+	if err != nil {
+		return nil, fmt.Errorf("无法解析 curl 命令: %v", err)
+	}
+	if len(args) == 0 || args[0] != "curl" {
+		return nil, fmt.Errorf("不是 curl 命令，请在浏览器开发者工具的网络面板中右键请求，选择“复制为 cURL (bash)”")
+	}
+
+	request := &curlRequest{Headers: make(map[string]string)}
+	var rawURL string
+	for i := 1; i < len(args); i++ {
+		arg := args[i]
+		value := ""
+		if i+1 < len(args) {
+			value = args[i+1]
+		}
+		switch {
+		case arg == "-H" || arg == "--header":
+			i++
+			name, content, ok := strings.Cut(value, ":")
+			name = http.CanonicalHeaderKey(strings.TrimSpace(name))
+			if !ok || name == "" || curlSkippedHeaders[name] {
+				continue
+			}
+			request.Headers[name] = strings.TrimSpace(content)
+		case arg == "-b" || arg == "--cookie":
+			i++
+			request.Headers["Cookie"] = value
+		case arg == "-A" || arg == "--user-agent":
+			i++
+			request.Headers["User-Agent"] = value
+		case arg == "-e" || arg == "--referer":
+			i++
+			request.Headers["Referer"] = value
+		case arg == "--url":
+			i++
+			rawURL = value
+		case curlValueFlags[arg]:
+			i++
+		case strings.HasPrefix(arg, "-"):
+		case rawURL == "":
+			rawURL = arg
+		}
+	}
+	if rawURL == "" {
+		return nil, fmt.Errorf("curl 命令中没有请求地址")
+	}
+	if err := request.setURL(rawURL); err != nil {
+		return nil, err
+	}
+	if !strings.Contains(request.BaseURL, "maildomainws") {
+		printWarning(fmt.Sprintf("%s 看起来不是隐藏邮箱接口，请复制发往 pXX-maildomainws.icloud.com 的请求", request.BaseURL))
+	}
+	if request.Headers["Cookie"] == "" {
+		return nil, fmt.Errorf("curl 命令中没有 Cookie，请确认复制的是已登录的 iCloud 请求")
+	}
+	return request, nil
+}
+
+// setURL 从隐藏邮箱接口的请求地址中取出 base_url 与账号参数
+func (r *curlRequest) setURL(rawURL string) error {
+	parsed, err := url.Parse(rawURL)
+	if err != nil || parsed.Host == "" {
+		return fmt.Errorf("无效的请求地址: %s", rawURL)
+	}
+	query := parsed.Query()
+	r.BaseURL = parsed.Scheme + "://" + parsed.Host + "/v1/hme/reserve"
+	r.ClientBuildNumber = query.Get("clientBuildNumber")
+	r.ClientMasteringNumber = query.Get("clientMasteringNumber")
+	r.ClientID = query.Get("clientId")
+	r.DSID = query.Get("dsid")
+	return nil
+}
+
+// applyTo 用解析结果更新配置的账号字段：请求头整体替换（curl 中带 User-Agent 时不再使用预设），
+// curl 中缺少的账号参数保持原值
+func (r *curlRequest) applyTo(config *Config) {
+	config.BaseURL = r.BaseURL
+	overlay := func(target *string, value string) {
+		if value != "" {
+			*target = value
+		}
+	}
+	overlay(&config.ClientBuildNumber, r.ClientBuildNumber)
+	overlay(&config.ClientMasteringNumber, r.ClientMasteringNumber)
+	overlay(&config.ClientID, r.ClientID)
+	overlay(&config.DSID, r.DSID)
+	config.Headers = make(map[string]string, len(r.Headers))
+	for key, value := range r.Headers {
+		config.Headers[key] = value
+	}
+	if r.Headers["User-Agent"] != "" {
+		config.UserAgentPreset = ""
+	}
+}
+
+// describeAccountChanges 列出账号字段的变化，请求头只显示名称，不输出 Cookie 等内容
+func describeAccountChanges(before, after *Config) []string {
+	var changes []string
+	for _, field := range []struct {
+		name        string
+		old, latest string
+	}{
+		{"base_url", before.BaseURL, after.BaseURL},
+		{"dsid", before.DSID, after.DSID},
+		{"client_id", before.ClientID, after.ClientID},
+		{"client_build_number", before.ClientBuildNumber, after.ClientBuildNumber},
+		{"client_mastering_number", before.ClientMasteringNumber, after.ClientMasteringNumber},
+		{"user_agent_preset", before.UserAgentPreset, after.UserAgentPreset},
+	} {
+		if field.old != field.latest {
+			changes = append(changes, fmt.Sprintf("%s: %s → %s", field.name, orDash(field.old), orDash(field.latest)))
+		}
+	}
+	// 请求头名称不区分大小写（curl 中多为小写，配置示例中为首字母大写）
+	canonical := func(headers map[string]string) map[string]string {
+		out := make(map[string]string, len(headers))
+		for key, value := range headers {
+			out[http.CanonicalHeaderKey(key)] = value
+		}
+		return out
+	}
+	beforeHeaders, afterHeaders := canonical(before.Headers), canonical(after.Headers)
+	var added, removed, updated []string
+	for key, value := range afterHeaders {
+		old, ok := beforeHeaders[key]
+		switch {
+		case !ok:
+			added = append(added, key)
+		case old != value:
+			updated = append(updated, key)
+		}
+	}
+	for key := range beforeHeaders {
+		if _, ok := afterHeaders[key]; !ok {
+			removed = append(removed, key)
+		}
+	}
+	for _, group := range []struct {
+		title string
+		keys  []string
+	}{{"更新请求头", updated}, {"新增请求头", added}, {"移除请求头", removed}} {
+		if len(group.keys) > 0 {
+			sort.Strings(group.keys)
+			changes = append(changes, group.title+": "+strings.Join(group.keys, ", "))
+		}
+	}
+	return changes
+}
+
+// verifyAccount 用一次列表请求验证账号配置，返回邮箱数量
+func verifyAccount(config *Config) (int, error) {
+	var emails []HMEEmail
+	err := withSpinner("验证账号（获取邮箱列表）", func() error {
+		var err error
+		emails, err = listHME(config)
+		return err
+	})
+	if err != nil {
+		class := classifyFailure(err)
+		return 0, withExitCode(exitCodeFor(err), fmt.Errorf("验证失败（%s）: %v。%s", class.Name, err, class.Advice))
+	}
+	return len(emails), nil
+}
+
+// printAccountChanges 显示导入将带来的变化
+func printAccountChanges(changes []string) {
+	if len(changes) == 0 {
+		printInfo("账号配置没有变化")
+		return
+	}
+	for _, change := range changes {
+		fmt.Printf("  "+ColorYellow+"~"+ColorReset+" %s\n", change)
+	}
+}
+
+// CLIImportCurl import-curl 与 import-har 命令的 JSON 输出
+type CLIImportCurl struct {
+	Changes  []string `json:"changes"`
+	Verified bool     `json:"verified"`
+	Emails   int      `json:"emails,omitempty"`
+	Saved    bool     `json:"saved"`
+}
+
+// runImportCurl 从 curl 命令更新账号配置：import-curl [-dry-run] [-no-verify] [文件|-]。
+// 未指定文件时从标准输入读取；使用 --profile 时写入该账号
+func runImportCurl(config *Config, args []string) error {
+	fs := flag.NewFlagSet("import-curl", flag.ContinueOnError)
+	dryRun := fs.Bool("dry-run", false, "只显示将要修改的字段，不保存")
+	noVerify := fs.Bool("no-verify", false, "保存前不验证账号")
+	if err := fs.Parse(args); err != nil {
+		return usageError(err)
+	}
+	if fs.NArg() > 1 {
+		return usageError(fmt.Errorf("用法: import-curl [-dry-run] [-no-verify] [文件|-]"))
+	}
+
+	var command string
+	switch path := fs.Arg(0); {
+	case path != "" && path != "-":
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("读取 %s 失败: %v", path, err)
+		}
+		command = joinCurlLines(string(data))
+	case path == "" && stdinIsTerminal():
+		command = readCurlCommand()
+	default:
+		data, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			return fmt.Errorf("读取标准输入失败: %v", err)
+		}
+		command = joinCurlLines(string(data))
+	}
+	request, err := parseCurlCommand(command)
+	if err != nil {
+		return usageError(err)
+	}
+	return importAccount(config, request, "从 curl 导入账号", *dryRun, *noVerify)
+}
+
+// importAccount 命令行导入账号的公共流程：显示变化，按需验证后保存
+func importAccount(config *Config, request *curlRequest, title string, dryRun, noVerify bool) error {
+	updated := config.clone()
+	request.applyTo(updated)
+	result := CLIImportCurl{Changes: describeAccountChanges(config, updated)}
+	printHeader(title)
+	if config.profile != "" {
+		printInfo(fmt.Sprintf("写入账号: %s", config.profile))
+	}
+	printAccountChanges(result.Changes)
+
+	if !noVerify {
+		n, err := verifyAccount(updated)
+		if err != nil {
+			return err
+		}
+		result.Verified, result.Emails = true, n
+		printSuccess(fmt.Sprintf("验证通过，账号中共有 %d 个隐藏邮箱", n))
+	}
+	if !dryRun && len(result.Changes) > 0 {
+		saveConfigWithMessage(updated, "账号配置已更新")
+		result.Saved = true
+	}
+	if outputJSON {
+		return writeJSON(result)
+	}
+	return nil
+}
+
+// handleImportCurl 程序设置中的“从 curl 更新账号”，Cookie 过期后重新抓包即可恢复
+func handleImportCurl(config *Config) {
+	printHeader("从 curl 更新账号")
+	fmt.Println("  在浏览器开发者工具的网络面板中，右键任意发往 pXX-maildomainws.icloud.com 的请求，")
+	fmt.Println("  选择“复制为 cURL (bash)”后粘贴到这里。")
+	fmt.Println()
+	request, err := parseCurlCommand(readCurlCommand())
+	if err != nil {
+		printError(err.Error())
+		return
+	}
+	applyImportedAccount(config, request)
+}
+
+// applyImportedAccount 菜单中导入账号的公共流程：显示变化并验证，验证失败时询问是否仍然保存
+func applyImportedAccount(config *Config, request *curlRequest) {
+	updated := config.clone()
+	request.applyTo(updated)
+	changes := describeAccountChanges(config, updated)
+	printAccountChanges(changes)
+	if len(changes) == 0 {
+		return
+	}
+	if n, err := verifyAccount(updated); err != nil {
+		printError(err.Error())
+		if !confirmAction("仍然保存") {
+			return
+		}
+	} else {
+		printSuccess(fmt.Sprintf("验证通过，账号中共有 %d 个隐藏邮箱", n))
+	}
+	request.applyTo(config)
+	saveConfigWithMessage(config, "账号配置已更新")
+}
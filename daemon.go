@@ -0,0 +1,737 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"icloud-hme-generator/pkg/hme"
+)
+
+// DaemonConfig 守护进程模式（daemon）：常驻运行，按持久化的任务队列创建邮箱，遵守限流冷却与安静时段；
+// 守护进程运行期间菜单与 jobs 命令作为客户端提交和查看任务
+type DaemonConfig struct {
+	ListenAddr  string `json:"listen_addr"`  // 客户端连接地址，默认为状态目录中的 Unix Socket；使用 TCP 地址时必须配置 token
+	Token       string `json:"token"`        // 客户端认证令牌，通过 Authorization: Bearer 传递
+	JobsFile    string `json:"jobs_file"`    // 任务队列文件，重启后继续未完成的任务；默认为状态目录中按 dsid 区分的文件
+	QuietHours  string `json:"quiet_hours"`  // 安静时段，如 23:00-07:00，期间不发出创建请求
+	PollSeconds int    `json:"poll_seconds"` // 空闲时检查到期任务与重试队列的间隔
+}
+
+// 任务状态
+const (
+	JobQueued   = "queued"   // 等待开始时间
+	JobRunning  = "running"  // 正在创建
+	JobWaiting  = "waiting"  // 已到开始时间，因安静时段、限流冷却或会话过期暂停
+	JobDone     = "done"     // 全部完成
+	JobCanceled = "canceled" // 已取消
+)
+
+// 单个任务的最大数量
+const maxDaemonJobCount = 1000
+
+// 任务中保留的最近邮箱与错误数量，重复执行的任务不会无限增长
+const maxDaemonJobHistory = 100
+
+// 任务队列写入时使用的临时文件名
+const daemonJobsTempPattern = ".daemon-jobs-*.tmp"
+
+// DaemonJob 守护进程队列中的一个创建任务
+type DaemonJob struct {
+	ID              string   `json:"id"`
+	Count           int      `json:"count"` // 每次执行创建的数量
+	LabelPrefix     string   `json:"label_prefix"`
+	LabelMode       string   `json:"label_mode"` // sequence、readable 或 template
+	LabelTemplate   string   `json:"label_template,omitempty"`
+	RunAt           int64    `json:"run_at"`                     // Unix 毫秒，不早于该时间开始
+	IntervalMinutes int      `json:"interval_minutes,omitempty"` // 大于 0 时每次执行结束后按间隔再次排队
+	Status          string   `json:"status"`
+	Reason          string   `json:"reason,omitempty"` // 暂停原因
+	Progress        int      `json:"progress"`         // 本次执行已处理的数量
+	NextIndex       int      `json:"next_index"`       // 下一个标签的序号，重复执行时延续
+	Runs            int      `json:"runs"`             // 已完成的执行次数
+	Created         int      `json:"created"`
+	Failed          int      `json:"failed"`
+	Emails          []string `json:"emails"`
+	Errors          []string `json:"errors"`
+	CreatedAt       int64    `json:"created_at"`
+	UpdatedAt       int64    `json:"updated_at"`
+	FinishedAt      int64    `json:"finished_at,omitempty"`
+}
+
+// DaemonJobRequest 提交任务的请求体
+type DaemonJobRequest struct {
+	Count           int    `json:"count"`
+	LabelPrefix     string `json:"label_prefix"`
+	LabelMode       string `json:"label_mode"`
+	LabelTemplate   string `json:"label_template"`
+	RunAt           int64  `json:"run_at"` // Unix 毫秒，0 表示立即
+	IntervalMinutes int    `json:"interval_minutes"`
+}
+
+// DaemonStatus 守护进程的运行状态
+type DaemonStatus struct {
+	Version    string         `json:"version"`
+	PID        int            `json:"pid"`
+	StartedAt  int64          `json:"started_at"`
+	State      string         `json:"state"` // 当前在做什么，如 空闲、安静时段、冷却中
+	RetryQueue int            `json:"retry_queue"`
+	Jobs       map[string]int `json:"jobs"` // 按状态统计的任务数
+}
+
+// Finished 任务是否已结束（完成或取消）
+func (j *DaemonJob) Finished() bool {
+	return j.Status == JobDone || j.Status == JobCanceled
+}
+
+// labels 任务的标签生成方式，提交时已校验
+func (j *DaemonJob) labels() (LabelFunc, error) {
+	switch j.LabelMode {
+	case "readable":
+		return readableLabels(j.LabelPrefix, nil), nil
+	case "template":
+		return templateLabels(j.LabelTemplate)
+	default:
+		return sequentialLabels(j.LabelPrefix), nil
+	}
+}
+
+// newDaemonJob 校验请求并创建任务；未指定前缀时使用配置中的 label_prefix
+func newDaemonJob(config *Config, req DaemonJobRequest) (*DaemonJob, error) {
+	if req.Count <= 0 || req.Count > maxDaemonJobCount {
+		return nil, fmt.Errorf("count 必须在 1-%d 之间", maxDaemonJobCount)
+	}
+	if req.IntervalMinutes < 0 {
+		return nil, fmt.Errorf("interval_minutes 不能为负数")
+	}
+	now := time.Now().UnixMilli()
+	job := &DaemonJob{
+		ID:              newEventID()[:8],
+		Count:           req.Count,
+		LabelPrefix:     req.LabelPrefix,
+		LabelMode:       req.LabelMode,
+		LabelTemplate:   req.LabelTemplate,
+		RunAt:           max(req.RunAt, now),
+		IntervalMinutes: req.IntervalMinutes,
+		Status:          JobQueued,
+		NextIndex:       1,
+		CreatedAt:       now,
+		UpdatedAt:       now,
+	}
+	switch job.LabelMode {
+	case "", "sequence":
+		job.LabelMode = "sequence"
+		if strings.TrimSpace(job.LabelPrefix) == "" {
+			job.LabelPrefix = config.LabelPrefix
+		}
+		if strings.TrimSpace(job.LabelPrefix) == "" {
+			job.LabelPrefix = "auto-"
+		}
+	case "readable":
+	case "template":
+		if job.LabelTemplate == "" {
+			job.LabelTemplate = config.LabelTemplate
+		}
+		if _, err := templateLabels(job.LabelTemplate); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("label_mode 只能是 sequence、readable 或 template")
+	}
+	return job, nil
+}
+
+// daemonListenAddr 守护进程的连接地址：未配置时为状态目录中按 dsid 区分的 Unix Socket
+func daemonListenAddr(config *Config) string {
+	if config.Daemon.ListenAddr != "" {
+		return config.Daemon.ListenAddr
+	}
+	name := "daemon.sock"
+	if dsid := accountLockUnsafeChars.ReplaceAllString(config.DSID, "_"); dsid != "" {
+		name = "daemon-" + dsid + ".sock"
+	}
+	return "unix:" + filepath.Join(stateDir(config), name)
+}
+
+// daemonJobsFile 任务队列文件：未配置时与冷却记录一样位于状态目录并按 dsid 区分
+func daemonJobsFile(config *Config) string {
+	if config.Daemon.JobsFile != "" {
+		return config.Daemon.JobsFile
+	}
+	name := "daemon-jobs.json"
+	if dsid := accountLockUnsafeChars.ReplaceAllString(config.DSID, "_"); dsid != "" {
+		name = "daemon-jobs-" + dsid + ".json"
+	}
+	return filepath.Join(stateDir(config), name)
+}
+
+// parseQuietHours 解析 HH:MM-HH:MM 形式的安静时段，返回起止时刻（当天的分钟数），结束早于开始时跨越午夜
+func parseQuietHours(value string) (start, end int, err error) {
+	from, to, ok := strings.Cut(value, "-")
+	if !ok {
+		return 0, 0, fmt.Errorf("daemon.quiet_hours 格式应为 HH:MM-HH:MM，如 23:00-07:00")
+	}
+	for i, part := range []string{from, to} {
+		clock, err := time.Parse("15:04", strings.TrimSpace(part))
+		if err != nil {
+			return 0, 0, fmt.Errorf("daemon.quiet_hours 中的时间 %q 无效，应为 HH:MM", strings.TrimSpace(part))
+		}
+		minutes := clock.Hour()*60 + clock.Minute()
+		if i == 0 {
+			start = minutes
+		} else {
+			end = minutes
+		}
+	}
+	if start == end {
+		return 0, 0, fmt.Errorf("daemon.quiet_hours 的开始与结束时间相同")
+	}
+	return start, end, nil
+}
+
+// quietHoursUntil now 处于安静时段时返回安静时段的结束时间，否则返回零值；未配置或格式无效时视为没有安静时段
+func quietHoursUntil(value string, now time.Time) time.Time {
+	if strings.TrimSpace(value) == "" {
+		return time.Time{}
+	}
+	start, end, err := parseQuietHours(value)
+	if err != nil {
+		return time.Time{}
+	}
+	midnight := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	minute := now.Hour()*60 + now.Minute()
+	switch {
+	case start < end && minute >= start && minute < end:
+		return midnight.Add(time.Duration(end) * time.Minute)
+	case start > end && minute >= start:
+		return midnight.AddDate(0, 0, 1).Add(time.Duration(end) * time.Minute)
+	case start > end && minute < end:
+		return midnight.Add(time.Duration(end) * time.Minute)
+	}
+	return time.Time{}
+}
+
+// jobDaemon 守护进程：任务队列、执行循环与客户端接口
+type jobDaemon struct {
+	mutex     sync.Mutex
+	settings  DaemonConfig
+	path      string
+	jobs      []*DaemonJob
+	state     string
+	halted    string // 会话过期等需要人工处理的原因，非空时不再发出创建请求
+	gate      *rateLimitGate
+	wake      chan struct{}
+	server    *http.Server
+	startedAt time.Time
+}
+
+// loadDaemonJobs 读取任务队列，文件不存在时返回空队列
+func loadDaemonJobs(path string) ([]*DaemonJob, error) {
+	data, recoveredFrom, err := readFileRecovering(path, daemonJobsTempPattern, daemonJobsFormat.validator(func() interface{} {
+		return &[]*DaemonJob{}
+	}))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("读取任务队列失败: %v", err)
+	}
+	if recoveredFrom != "" {
+		printWarning(fmt.Sprintf("任务队列 %s 已损坏或未写完，已从 %s 恢复", path, recoveredFrom))
+	}
+	var jobs []*DaemonJob
+	if err := daemonJobsFormat.decode(data, &jobs); err != nil {
+		return nil, fmt.Errorf("解析任务队列失败: %v", err)
+	}
+	return jobs, nil
+}
+
+// saveLocked 原子写入任务队列（调用方需持有 d.mutex）
+func (d *jobDaemon) saveLocked() {
+	jobs := d.jobs
+	if jobs == nil {
+		jobs = []*DaemonJob{}
+	}
+	if err := daemonJobsFormat.write(d.path, daemonJobsTempPattern, jobs); err != nil {
+		printWarning(fmt.Sprintf("写入任务队列失败: %v", err))
+		logger().Error("写入任务队列失败", "path", d.path, "error", err)
+	}
+}
+
+// notify 唤醒执行循环（提交或取消任务后立即处理）
+func (d *jobDaemon) notify() {
+	select {
+	case d.wake <- struct{}{}:
+	default:
+	}
+}
+
+// findLocked 按 ID 查找任务，允许唯一的 ID 前缀（调用方需持有 d.mutex）
+func (d *jobDaemon) findLocked(id string) *DaemonJob {
+	var found *DaemonJob
+	for _, job := range d.jobs {
+		if job.ID == id {
+			return job
+		}
+		if id != "" && strings.HasPrefix(job.ID, id) {
+			if found != nil {
+				return nil
+			}
+			found = job
+		}
+	}
+	return found
+}
+
+// snapshot 复制任务，避免并发读写
+func (d *jobDaemon) snapshot(job *DaemonJob) DaemonJob {
+	copied := *job
+	copied.Emails = append([]string{}, job.Emails...)
+	copied.Errors = append([]string{}, job.Errors...)
+	return copied
+}
+
+// setState 更新当前状态说明，并把已到开始时间的任务标记为暂停（reason 为空时恢复暂停的任务）
+func (d *jobDaemon) setState(state, reason string) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	if d.state != state {
+		logger().Info("守护进程状态", "state", state)
+	}
+	d.state = state
+	now := time.Now().UnixMilli()
+	changed := false
+	for _, job := range d.jobs {
+		if job.Finished() || job.RunAt > now {
+			continue
+		}
+		status := JobWaiting
+		if reason == "" {
+			if job.Status != JobWaiting {
+				continue
+			}
+			status = JobQueued
+			if job.Progress > 0 {
+				status = JobRunning
+			}
+		}
+		if job.Status != status || job.Reason != reason {
+			job.Status, job.Reason, job.UpdatedAt = status, reason, now
+			changed = true
+		}
+	}
+	if changed {
+		d.saveLocked()
+	}
+}
+
+// nextDue 最早到开始时间的未完成任务；没有时返回下一个任务的等待时间
+func (d *jobDaemon) nextDue() (*DaemonJob, time.Duration) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	now := time.Now().UnixMilli()
+	var next *DaemonJob
+	for _, job := range d.jobs {
+		if job.Finished() {
+			continue
+		}
+		if next == nil || job.RunAt < next.RunAt {
+			next = job
+		}
+	}
+	if next == nil {
+		return nil, -1
+	}
+	if next.RunAt > now {
+		return nil, time.Duration(next.RunAt-now) * time.Millisecond
+	}
+	return next, 0
+}
+
+// run 执行循环：每次只创建一个邮箱，之间重新检查安静时段、冷却与取消，直到收到退出信号
+func (d *jobDaemon) run(ctx context.Context) {
+	for {
+		wait := d.step(getCurrentConfig())
+		timer := time.NewTimer(wait)
+		select {
+		case <-timer.C:
+		case <-d.wake:
+			timer.Stop()
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		}
+	}
+}
+
+// step 处理一次：安静时段与冷却中只更新状态；否则先重试已到时间的重试队列标签，再执行到期任务。返回下次处理前的等待时间
+func (d *jobDaemon) step(config *Config) time.Duration {
+	poll := time.Duration(d.settings.PollSeconds) * time.Second
+	delay := time.Duration(config.DelaySeconds) * time.Second
+
+	d.mutex.Lock()
+	halted := d.halted
+	d.mutex.Unlock()
+	if halted != "" {
+		d.setState(halted, halted)
+		return poll
+	}
+	if until := quietHoursUntil(d.settings.QuietHours, time.Now()); !until.IsZero() {
+		state := fmt.Sprintf("安静时段，%s 后继续", until.Format("15:04"))
+		d.setState(state, state)
+		return min(time.Until(until), poll)
+	}
+	if until := activeCooldown(config); !until.IsZero() {
+		state := fmt.Sprintf("被限流，%s 后继续", until.Format("15:04"))
+		d.setState(state, state)
+		return min(time.Until(until), poll)
+	}
+
+	// 重试队列中的标签此前已被限流，优先于新任务
+	if labels := dueRetryLabels(config); len(labels) > 0 {
+		d.setState(fmt.Sprintf("重试队列（%d 个）", len(labels)), "")
+		d.retryLabel(config, labels[0])
+		return delay
+	}
+
+	job, wait := d.nextDue()
+	if job == nil {
+		d.setState("空闲", "")
+		if wait < 0 {
+			return poll
+		}
+		return min(wait, poll)
+	}
+	d.setState("执行任务 "+job.ID, "")
+	d.runItem(config, job)
+	return delay
+}
+
+// createOne 创建一个邮箱；被限流时不在此等待，冷却已记录，由执行循环等到冷却结束再用同一标签重试
+func (d *jobDaemon) createOne(config *Config, label string) (string, error) {
+	email, err := createHMEWithBackoff(config, label, d.gate, func(time.Duration) bool { return false })
+	if errors.Is(err, hme.ErrSessionExpired) {
+		d.mutex.Lock()
+		d.halted = "会话已过期，更新 Cookie 后重启守护进程"
+		d.mutex.Unlock()
+		printError(fmt.Sprintf("创建 %s 失败: %v", label, err))
+		announce(config, SoundError, "守护进程已暂停: 会话已过期")
+	}
+	return email, err
+}
+
+// retryLabel 重试重试队列中的一个标签；非限流的失败从队列移除，避免反复请求
+func (d *jobDaemon) retryLabel(config *Config, label string) {
+	email, err := d.createOne(config, label)
+	if errors.Is(err, context.Canceled) || errors.Is(err, hme.ErrSessionExpired) {
+		return
+	}
+	updateRetryQueue(config, label, email, err)
+	if _, limited := retryAfterFor(err); limited {
+		printWarning(fmt.Sprintf("重试 %s 时被限流，等待冷却结束", label))
+		return
+	}
+	if err != nil {
+		printError(fmt.Sprintf("重试 %s 失败，已移出重试队列: %v", label, err))
+		if _, removeErr := removeRetryQueue(config, []string{label}); removeErr != nil {
+			printWarning(removeErr.Error())
+		}
+		return
+	}
+	printSuccess(fmt.Sprintf("重试队列 %s → %s", label, email))
+	if err := saveEmailToFile(config, email, label, CreationOrigin{Source: SourceDaemon, Actor: "retry-queue"}); err != nil {
+		printWarning(fmt.Sprintf("保存邮箱到文件失败: %v", err))
+	}
+}
+
+// runItem 为任务创建下一个邮箱并记录进度；本次执行完成后结束任务，或按间隔重新排队
+func (d *jobDaemon) runItem(config *Config, job *DaemonJob) {
+	d.mutex.Lock()
+	if job.Finished() {
+		d.mutex.Unlock()
+		return
+	}
+	if job.Status != JobRunning {
+		job.Status, job.UpdatedAt = JobRunning, time.Now().UnixMilli()
+		d.saveLocked()
+	}
+	labelFor, err := job.labels()
+	index := job.NextIndex
+	d.mutex.Unlock()
+	if err != nil {
+		d.finish(job, err.Error())
+		return
+	}
+	label := labelFor(index)
+
+	email, err := d.createOne(config, label)
+	if errors.Is(err, context.Canceled) || errors.Is(err, hme.ErrSessionExpired) {
+		return
+	}
+	if _, limited := retryAfterFor(err); limited {
+		printWarning(fmt.Sprintf("任务 %s 创建 %s 时被限流，等待冷却结束后继续", job.ID, label))
+		return
+	}
+
+	d.mutex.Lock()
+	job.NextIndex++
+	job.Progress++
+	if err != nil {
+		job.Failed++
+		job.Errors = appendRecent(job.Errors, fmt.Sprintf("%s: %v", label, err))
+	} else {
+		job.Created++
+		job.Emails = appendRecent(job.Emails, email)
+	}
+	now := time.Now()
+	job.UpdatedAt = now.UnixMilli()
+	if job.Progress >= job.Count && !job.Finished() {
+		job.Runs++
+		job.Progress = 0
+		if job.IntervalMinutes > 0 {
+			job.Status = JobQueued
+			job.RunAt = now.Add(time.Duration(job.IntervalMinutes) * time.Minute).UnixMilli()
+		} else {
+			job.Status = JobDone
+			job.FinishedAt = now.UnixMilli()
+		}
+	}
+	status := job.Status
+	d.saveLocked()
+	d.mutex.Unlock()
+
+	if err != nil {
+		printError(fmt.Sprintf("任务 %s 创建 %s 失败: %v", job.ID, label, err))
+	} else {
+		printSuccess(fmt.Sprintf("任务 %s %s → %s", job.ID, label, email))
+		if err := saveEmailToFile(config, email, label, CreationOrigin{Source: SourceDaemon, Actor: job.ID}); err != nil {
+			printWarning(fmt.Sprintf("保存邮箱到文件失败: %v", err))
+		}
+	}
+	if status == JobDone {
+		printInfo(fmt.Sprintf("任务 %s 已完成", job.ID))
+		logger().Info("守护进程任务完成", "job", job.ID, "created", job.Created, "failed", job.Failed)
+	}
+}
+
+// finish 结束任务并记录原因
+func (d *jobDaemon) finish(job *DaemonJob, reason string) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	now := time.Now().UnixMilli()
+	job.Status, job.Reason, job.UpdatedAt, job.FinishedAt = JobDone, reason, now, now
+	d.saveLocked()
+}
+
+// appendRecent 追加一项，只保留最近 maxDaemonJobHistory 项
+func appendRecent(list []string, item string) []string {
+	list = append(list, item)
+	if len(list) > maxDaemonJobHistory {
+		list = list[len(list)-maxDaemonJobHistory:]
+	}
+	return list
+}
+
+// authorize 配置了 token 时要求客户端携带令牌；Unix Socket 本身只允许当前用户连接
+func (d *jobDaemon) authorize(next http.HandlerFunc) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if token, ok := bearerToken(r); d.settings.Token != "" && (!ok || !tokenEqual(token, d.settings.Token)) {
+			writeServeError(w, http.StatusUnauthorized, "unauthorized", "令牌无效或缺失")
+			return
+		}
+		next(w, r)
+	})
+}
+
+func (d *jobDaemon) handleStatus(w http.ResponseWriter, r *http.Request) {
+	d.mutex.Lock()
+	status := DaemonStatus{
+		Version:   VERSION,
+		PID:       os.Getpid(),
+		StartedAt: d.startedAt.UnixMilli(),
+		State:     d.state,
+		Jobs:      make(map[string]int),
+	}
+	for _, job := range d.jobs {
+		status.Jobs[job.Status]++
+	}
+	d.mutex.Unlock()
+	status.RetryQueue = len(retryQueueItems(getCurrentConfig()))
+	writeServeResult(w, status)
+}
+
+// handleListJobs 列出任务，未结束的在前，同类按创建时间倒序
+func (d *jobDaemon) handleListJobs(w http.ResponseWriter, r *http.Request) {
+	d.mutex.Lock()
+	jobs := make([]DaemonJob, 0, len(d.jobs))
+	for _, job := range d.jobs {
+		jobs = append(jobs, d.snapshot(job))
+	}
+	d.mutex.Unlock()
+	sort.SliceStable(jobs, func(i, j int) bool {
+		if jobs[i].Finished() != jobs[j].Finished() {
+			return !jobs[i].Finished()
+		}
+		return jobs[i].CreatedAt > jobs[j].CreatedAt
+	})
+	writeServeResult(w, jobs)
+}
+
+func (d *jobDaemon) handleSubmitJob(w http.ResponseWriter, r *http.Request) {
+	var req DaemonJobRequest
+	if !decodeServeBody(w, r, &req) {
+		return
+	}
+	job, err := newDaemonJob(getCurrentConfig(), req)
+	if err != nil {
+		writeServeError(w, http.StatusBadRequest, "invalid_job", err.Error())
+		return
+	}
+
+	d.mutex.Lock()
+	d.jobs = append(d.jobs, job)
+	d.saveLocked()
+	snapshot := d.snapshot(job)
+	d.mutex.Unlock()
+	d.notify()
+
+	printInfo(fmt.Sprintf("收到任务 %s: 创建 %d 个，%s 开始", job.ID, job.Count, time.UnixMilli(job.RunAt).Format("01-02 15:04")))
+	logger().Info("守护进程收到任务", "job", job.ID, "count", job.Count, "label_mode", job.LabelMode, "run_at", time.UnixMilli(job.RunAt).Format(time.RFC3339), "interval_minutes", job.IntervalMinutes)
+	writeServeJSON(w, http.StatusAccepted, ServeResponse{Success: true, Result: snapshot})
+}
+
+func (d *jobDaemon) handleGetJob(w http.ResponseWriter, r *http.Request) {
+	d.mutex.Lock()
+	job := d.findLocked(r.PathValue("id"))
+	var snapshot DaemonJob
+	if job != nil {
+		snapshot = d.snapshot(job)
+	}
+	d.mutex.Unlock()
+	if job == nil {
+		writeServeError(w, http.StatusNotFound, "not_found", "任务不存在")
+		return
+	}
+	writeServeResult(w, snapshot)
+}
+
+// handleCancelJob 取消任务；正在创建的那一个仍会完成并计入结果
+func (d *jobDaemon) handleCancelJob(w http.ResponseWriter, r *http.Request) {
+	d.mutex.Lock()
+	job := d.findLocked(r.PathValue("id"))
+	if job == nil {
+		d.mutex.Unlock()
+		writeServeError(w, http.StatusNotFound, "not_found", "任务不存在")
+		return
+	}
+	if job.Finished() {
+		d.mutex.Unlock()
+		writeServeError(w, http.StatusConflict, "job_finished", "任务已结束")
+		return
+	}
+	now := time.Now().UnixMilli()
+	job.Status, job.Reason, job.UpdatedAt, job.FinishedAt = JobCanceled, "", now, now
+	d.saveLocked()
+	snapshot := d.snapshot(job)
+	d.mutex.Unlock()
+	d.notify()
+
+	printInfo(fmt.Sprintf("任务 %s 已取消", job.ID))
+	writeServeResult(w, snapshot)
+}
+
+// runDaemon 启动守护进程：加载任务队列，监听客户端连接，并在后台执行任务直到收到退出信号
+func runDaemon(config *Config, args []string) error {
+	if len(args) > 0 {
+		return usageError(fmt.Errorf("daemon 不接受参数，用 jobs 命令提交与查看任务"))
+	}
+	settings := config.Daemon
+	settings.ListenAddr = daemonListenAddr(config)
+	settings.JobsFile = daemonJobsFile(config)
+	if !strings.HasPrefix(settings.ListenAddr, "unix:") && settings.Token == "" {
+		return configError("daemon.listen_addr 为 TCP 地址时必须配置 daemon.token")
+	}
+	if settings.QuietHours != "" {
+		if _, _, err := parseQuietHours(settings.QuietHours); err != nil {
+			return configError("%v", err)
+		}
+	}
+	dirs := []string{filepath.Dir(settings.JobsFile)}
+	if socket, ok := strings.CutPrefix(settings.ListenAddr, "unix:"); ok {
+		dirs = append(dirs, filepath.Dir(socket))
+	}
+	for _, dir := range dirs {
+		if err := os.MkdirAll(dir, 0700); err != nil {
+			return fmt.Errorf("创建状态目录失败: %v", err)
+		}
+	}
+
+	jobs, err := loadDaemonJobs(settings.JobsFile)
+	if err != nil {
+		return err
+	}
+	d := &jobDaemon{
+		settings:  settings,
+		path:      settings.JobsFile,
+		jobs:      jobs,
+		state:     "启动中",
+		gate:      &rateLimitGate{},
+		wake:      make(chan struct{}, 1),
+		startedAt: time.Now(),
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("GET /status", d.authorize(d.handleStatus))
+	mux.Handle("GET /jobs", d.authorize(d.handleListJobs))
+	mux.Handle("POST /jobs", d.authorize(d.handleSubmitJob))
+	mux.Handle("GET /jobs/{id}", d.authorize(d.handleGetJob))
+	mux.Handle("POST /jobs/{id}/cancel", d.authorize(d.handleCancelJob))
+	d.server = &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+
+	listener, err := listenAddr(settings.ListenAddr)
+	if err != nil {
+		return err
+	}
+	if strings.HasPrefix(settings.ListenAddr, "unix:") {
+		defer os.Remove(strings.TrimPrefix(settings.ListenAddr, "unix:"))
+	}
+
+	printHeader("iCloud 隐藏邮箱守护进程")
+	printInfo(fmt.Sprintf("监听地址: %s", settings.ListenAddr))
+	printInfo(fmt.Sprintf("任务队列: %s", settings.JobsFile))
+	unfinished := 0
+	for _, job := range jobs {
+		if !job.Finished() {
+			unfinished++
+		}
+	}
+	if unfinished > 0 {
+		printInfo(fmt.Sprintf("继续 %d 个未完成的任务", unfinished))
+	}
+	if settings.QuietHours != "" {
+		printInfo(fmt.Sprintf("安静时段: %s", settings.QuietHours))
+	}
+	if !isLoopbackListenAddr(settings.ListenAddr) {
+		printWarning("监听地址不是本机回环地址，令牌将以明文传输")
+	}
+	printInfo("用 jobs 命令或菜单提交与查看任务，Ctrl+C 退出")
+	logger().Info("守护进程启动", "listen", settings.ListenAddr, "jobs", len(jobs), "unfinished", unfinished)
+
+	safetyManager.Go("daemon-jobs", d.run)
+	safetyManager.Go("daemon-shutdown", func(ctx context.Context) {
+		<-ctx.Done()
+		d.server.Close()
+	})
+
+	if err := d.server.Serve(listener); err != http.ErrServerClosed {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,402 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// daemonClient 连接守护进程的客户端，jobs 命令与守护进程运行期间的菜单使用
+type daemonClient struct {
+	addr    string
+	token   string
+	baseURL string
+	client  *http.Client
+}
+
+// newDaemonClient 按 daemon 配置创建客户端，Unix Socket 地址通过自定义拨号连接
+func newDaemonClient(config *Config) *daemonClient {
+	c := &daemonClient{addr: daemonListenAddr(config), token: config.Daemon.Token}
+	transport := &http.Transport{}
+	if socket, ok := strings.CutPrefix(c.addr, "unix:"); ok {
+		transport.DialContext = func(ctx context.Context, _, _ string) (net.Conn, error) {
+			var dialer net.Dialer
+			return dialer.DialContext(ctx, "unix", socket)
+		}
+		c.baseURL = "http://daemon"
+	} else {
+		c.baseURL = "http://" + c.addr
+	}
+	c.client = &http.Client{Transport: transport, Timeout: 10 * time.Second}
+	return c
+}
+
+// call 发出请求并把 result 字段解码到 out；守护进程未运行时给出启动方法
+func (c *daemonClient) call(method, path string, body, out interface{}) error {
+	var reader *bytes.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	} else {
+		reader = bytes.NewReader(nil)
+	}
+	req, err := http.NewRequest(method, c.baseURL+path, reader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("无法连接守护进程 %s，请先运行 daemon 命令启动: %v", c.addr, err)
+	}
+	defer resp.Body.Close()
+
+	var response struct {
+		Success bool            `json:"success"`
+		Result  json.RawMessage `json:"result"`
+		Error   *APIError       `json:"error"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		return fmt.Errorf("守护进程响应无法解析: %v", err)
+	}
+	if !response.Success {
+		if response.Error != nil {
+			return fmt.Errorf("守护进程: %s", response.Error.ErrorMessage)
+		}
+		return fmt.Errorf("守护进程返回 HTTP %d", resp.StatusCode)
+	}
+	if out != nil {
+		return json.Unmarshal(response.Result, out)
+	}
+	return nil
+}
+
+// daemonRunning 当前账号的守护进程是否在运行并可以连接
+func daemonRunning(config *Config) bool {
+	var status DaemonStatus
+	return newDaemonClient(config).call(http.MethodGet, "/status", nil, &status) == nil
+}
+
+// parseJobTime 解析任务开始时间：15:04（今天，已过则明天）、2006-01-02 15:04、RFC 3339，或 +30m 这样的相对时间
+func parseJobTime(value string, now time.Time) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if strings.HasPrefix(value, "+") {
+		wait, err := time.ParseDuration(value[1:])
+		if err != nil || wait < 0 {
+			return time.Time{}, fmt.Errorf("无效的相对时间 %q，如 +30m、+2h", value)
+		}
+		return now.Add(wait), nil
+	}
+	if clock, err := time.ParseInLocation("15:04", value, now.Location()); err == nil {
+		at := time.Date(now.Year(), now.Month(), now.Day(), clock.Hour(), clock.Minute(), 0, 0, now.Location())
+		if !at.After(now) {
+			at = at.AddDate(0, 0, 1)
+		}
+		return at, nil
+	}
+	if at, err := time.ParseInLocation("2006-01-02 15:04", value, now.Location()); err == nil {
+		return at, nil
+	}
+	if at, err := time.Parse(time.RFC3339, value); err == nil {
+		return at, nil
+	}
+	return time.Time{}, fmt.Errorf("无效的开始时间 %q，可用 15:04、2006-01-02 15:04 或 +30m", value)
+}
+
+// parseJobInterval 解析重复间隔（如 24h、90m），留空表示只执行一次
+func parseJobInterval(value string) (int, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, nil
+	}
+	every, err := time.ParseDuration(value)
+	if err != nil || every < time.Minute {
+		return 0, fmt.Errorf("无效的重复间隔 %q，至少 1m，如 90m、24h", value)
+	}
+	return int(every / time.Minute), nil
+}
+
+// formatJobInterval 重复间隔的显示文字，如 “24 小时”“90 分钟”
+func formatJobInterval(minutes int) string {
+	if minutes%60 == 0 {
+		return fmt.Sprintf("%d 小时", minutes/60)
+	}
+	return fmt.Sprintf("%d 分钟", minutes)
+}
+
+// jobStatusText 任务状态的显示文字
+func jobStatusText(job DaemonJob) string {
+	switch job.Status {
+	case JobQueued:
+		return ColorCyan + time.UnixMilli(job.RunAt).Format("01-02 15:04") + " 开始" + ColorReset
+	case JobRunning:
+		return ColorBrightBlue + fmt.Sprintf("执行中 %d/%d", job.Progress, job.Count) + ColorReset
+	case JobWaiting:
+		return ColorYellow + "暂停: " + job.Reason + ColorReset
+	case JobDone:
+		return ColorGreen + "已完成" + ColorReset
+	case JobCanceled:
+		return ColorDim + "已取消" + ColorReset
+	}
+	return job.Status
+}
+
+// printDaemonJobs 列出任务
+func printDaemonJobs(jobs []DaemonJob) {
+	for _, job := range jobs {
+		schedule := ""
+		if job.IntervalMinutes > 0 {
+			schedule = "，每 " + formatJobInterval(job.IntervalMinutes)
+		}
+		fmt.Printf("  %s  %-12s ×%-4d %s "+ColorDim+"(成功 %d，失败 %d%s)"+ColorReset+"\n",
+			job.ID, jobLabelDesc(job), job.Count, jobStatusText(job), job.Created, job.Failed, schedule)
+	}
+	fmt.Println()
+}
+
+// jobLabelDesc 任务的标签方式，如 shop-、模板 shop-{{n}}、可读标签
+func jobLabelDesc(job DaemonJob) string {
+	switch job.LabelMode {
+	case "template":
+		return "模板 " + job.LabelTemplate
+	case "readable":
+		return "可读标签 " + job.LabelPrefix
+	}
+	return job.LabelPrefix
+}
+
+// printDaemonJob 显示单个任务的详情
+func printDaemonJob(job DaemonJob) {
+	fmt.Printf("  "+ColorCyan+"任务:"+ColorReset+" %s\n", job.ID)
+	fmt.Printf("  "+ColorCyan+"状态:"+ColorReset+" %s\n", jobStatusText(job))
+	fmt.Printf("  "+ColorCyan+"标签:"+ColorReset+" %s\n", jobLabelDesc(job))
+	fmt.Printf("  "+ColorCyan+"数量:"+ColorReset+" %d 个/次，已执行 %d 次，成功 %d，失败 %d\n", job.Count, job.Runs, job.Created, job.Failed)
+	if job.IntervalMinutes > 0 {
+		fmt.Printf("  "+ColorCyan+"重复:"+ColorReset+" 每 %s，下次 %s\n", formatJobInterval(job.IntervalMinutes), time.UnixMilli(job.RunAt).Format("01-02 15:04"))
+	}
+	for _, email := range job.Emails {
+		fmt.Println("    " + ColorGreen + "[+]" + ColorReset + " " + email)
+	}
+	for _, message := range job.Errors {
+		fmt.Println("    " + ColorRed + "[!]" + ColorReset + " " + message)
+	}
+	fmt.Println()
+}
+
+// printDaemonStatus 显示守护进程状态
+func printDaemonStatus(status DaemonStatus) {
+	fmt.Printf("  "+ColorCyan+"守护进程:"+ColorReset+" PID %d，启动于 %s，%s\n", status.PID, time.UnixMilli(status.StartedAt).Format("01-02 15:04"), status.State)
+	if status.RetryQueue > 0 {
+		fmt.Printf("  "+ColorCyan+"重试队列:"+ColorReset+" %d 个\n", status.RetryQueue)
+	}
+	fmt.Println()
+}
+
+// runJobs 守护进程客户端：jobs [list] | submit [-count n] [-prefix p] [-mode m] [-template t] [-at 时间] [-every 间隔] | status <id> | cancel <id>
+func runJobs(config *Config, args []string) error {
+	action := "list"
+	if len(args) > 0 {
+		action, args = args[0], args[1:]
+	}
+	client := newDaemonClient(config)
+
+	switch action {
+	case "list":
+		var status DaemonStatus
+		if err := client.call(http.MethodGet, "/status", nil, &status); err != nil {
+			return err
+		}
+		var jobs []DaemonJob
+		if err := client.call(http.MethodGet, "/jobs", nil, &jobs); err != nil {
+			return err
+		}
+		if outputJSON {
+			return writeJSON(map[string]interface{}{"status": status, "jobs": jobs})
+		}
+		printHeader("守护进程任务")
+		printDaemonStatus(status)
+		if len(jobs) == 0 {
+			printInfo("没有任务，用 jobs submit -count N 提交")
+			return nil
+		}
+		printDaemonJobs(jobs)
+		return nil
+
+	case "submit":
+		fs := flag.NewFlagSet("jobs submit", flag.ContinueOnError)
+		count := fs.Int("count", config.Count, "每次执行创建的数量")
+		prefix := fs.String("prefix", "", "标签前缀，默认使用配置中的 label_prefix")
+		mode := fs.String("mode", "sequence", "标签方式：sequence、readable 或 template")
+		template := fs.String("template", "", "template 方式的标签模板，默认使用配置中的 label_template")
+		at := fs.String("at", "", "开始时间：15:04、2006-01-02 15:04 或 +30m，默认立即")
+		every := fs.String("every", "", "重复间隔，如 24h，默认只执行一次")
+		if err := fs.Parse(args); err != nil {
+			return usageError(err)
+		}
+		runAt, err := parseJobTime(*at, time.Now())
+		if err != nil {
+			return usageError(err)
+		}
+		interval, err := parseJobInterval(*every)
+		if err != nil {
+			return usageError(err)
+		}
+		req := DaemonJobRequest{Count: *count, LabelPrefix: *prefix, LabelMode: *mode, LabelTemplate: *template, IntervalMinutes: interval}
+		if !runAt.IsZero() {
+			req.RunAt = runAt.UnixMilli()
+		}
+		var job DaemonJob
+		if err := client.call(http.MethodPost, "/jobs", req, &job); err != nil {
+			return err
+		}
+		if outputJSON {
+			return writeJSON(job)
+		}
+		printSuccess(fmt.Sprintf("已提交任务 %s: 创建 %d 个，%s 开始", job.ID, job.Count, time.UnixMilli(job.RunAt).Format("01-02 15:04")))
+		printInfo("用 jobs status " + job.ID + " 查看进度")
+		return nil
+
+	case "status", "cancel":
+		if len(args) != 1 {
+			return usageError(fmt.Errorf("用法: jobs %s <任务 ID>", action))
+		}
+		var job DaemonJob
+		var err error
+		if action == "cancel" {
+			err = client.call(http.MethodPost, "/jobs/"+url.PathEscape(args[0])+"/cancel", nil, &job)
+		} else {
+			err = client.call(http.MethodGet, "/jobs/"+url.PathEscape(args[0]), nil, &job)
+		}
+		if err != nil {
+			return err
+		}
+		if outputJSON {
+			return writeJSON(job)
+		}
+		if action == "cancel" {
+			printSuccess("已取消任务 " + job.ID)
+			return nil
+		}
+		printHeader("守护进程任务")
+		printDaemonJob(job)
+		return nil
+
+	default:
+		return usageError(fmt.Errorf("未知的 jobs 操作: %s（可用 list、submit、status、cancel）", action))
+	}
+}
+
+// runDaemonClientMenu 守护进程持有账号锁时的菜单：提交、查看与取消任务，创建由守护进程执行
+func runDaemonClientMenu(config *Config) {
+	client := newDaemonClient(config)
+	for {
+		printHeader("守护进程任务")
+		var status DaemonStatus
+		if err := client.call(http.MethodGet, "/status", nil, &status); err != nil {
+			printError(err.Error())
+			return
+		}
+		printDaemonStatus(status)
+		var jobs []DaemonJob
+		if err := client.call(http.MethodGet, "/jobs", nil, &jobs); err != nil {
+			printError(err.Error())
+			return
+		}
+		if len(jobs) > 0 {
+			printDaemonJobs(jobs)
+		}
+
+		fmt.Println("  " + ColorMagenta + "[1]" + ColorReset + " 提交创建任务")
+		fmt.Println("  " + ColorCyan + "[2]" + ColorReset + " 查看任务详情")
+		fmt.Println("  " + ColorYellow + "[3]" + ColorReset + " 取消任务")
+		fmt.Println("  " + ColorGray + "[r]" + ColorReset + " 刷新")
+		fmt.Println("  " + ColorGray + "[0]" + ColorReset + " 退出")
+		fmt.Println()
+
+		switch strings.ToLower(readInput("请选择: ")) {
+		case "1":
+			submitDaemonJobInteractive(config, client)
+		case "2":
+			var job DaemonJob
+			if err := client.call(http.MethodGet, "/jobs/"+url.PathEscape(readInput("任务 ID: ")), nil, &job); err != nil {
+				printError(err.Error())
+			} else {
+				printDaemonJob(job)
+			}
+			readInput("按回车返回")
+		case "3":
+			id := readInput("任务 ID: ")
+			if id == "" || !confirmAction("取消任务 "+id) {
+				continue
+			}
+			var job DaemonJob
+			if err := client.call(http.MethodPost, "/jobs/"+url.PathEscape(id)+"/cancel", nil, &job); err != nil {
+				printError(err.Error())
+				readInput("按回车返回")
+			} else {
+				printSuccess("已取消任务 " + job.ID)
+			}
+		case "0", "q", "exit":
+			return
+		}
+		clearScreen()
+	}
+}
+
+// submitDaemonJobInteractive 菜单中逐项填写并提交任务
+func submitDaemonJobInteractive(config *Config, client *daemonClient) {
+	count, err := readInt(fmt.Sprintf("创建数量 (1-%d): ", maxDaemonJobCount))
+	if err != nil || count <= 0 {
+		printError("请输入有效的数量")
+		readInput("按回车返回")
+		return
+	}
+	req := DaemonJobRequest{Count: count}
+	prefix := config.LabelPrefix
+	if strings.TrimSpace(prefix) == "" {
+		prefix = "auto-"
+	}
+	req.LabelPrefix = readInput(fmt.Sprintf("标签前缀 (默认 %s): ", prefix))
+	for {
+		runAt, err := parseJobTime(readInput("开始时间 (15:04、2006-01-02 15:04 或 +30m，回车立即): "), time.Now())
+		if err == nil {
+			if !runAt.IsZero() {
+				req.RunAt = runAt.UnixMilli()
+			}
+			break
+		}
+		printError(err.Error())
+	}
+	for {
+		interval, err := parseJobInterval(readInput("重复间隔 (如 24h，回车只执行一次): "))
+		if err == nil {
+			req.IntervalMinutes = interval
+			break
+		}
+		printError(err.Error())
+	}
+
+	var job DaemonJob
+	if err := client.call(http.MethodPost, "/jobs", req, &job); err != nil {
+		printError(err.Error())
+	} else {
+		printSuccess(fmt.Sprintf("已提交任务 %s: 创建 %d 个，%s 开始", job.ID, job.Count, time.UnixMilli(job.RunAt).Format("01-02 15:04")))
+	}
+	readInput("按回车返回")
+}
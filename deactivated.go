@@ -0,0 +1,185 @@
+package main
+
+import (
+	"encoding/csv"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// DeactivatedAlias 停用但未删除的邮箱
+type DeactivatedAlias struct {
+	Email         HMEEmail
+	DeactivatedAt time.Time // 零值表示本地没有停用记录
+	Detected      bool      // 停用时间为列表同步发现的时间，而非实际操作时间
+}
+
+// Age 停用至今的天数，停用时间未知时返回 -1
+func (d DeactivatedAlias) Age(now time.Time) int {
+	if d.DeactivatedAt.IsZero() {
+		return -1
+	}
+	return int(now.Sub(d.DeactivatedAt).Hours() / 24)
+}
+
+// collectDeactivated 从列表中挑出停用的邮箱，并结合本地清单补充停用时间（按停用时长倒序）
+func collectDeactivated(emails []HMEEmail) []DeactivatedAlias {
+	var result []DeactivatedAlias
+	for _, email := range emails {
+		if email.IsActive {
+			continue
+		}
+		alias := DeactivatedAlias{Email: email}
+		if record, ok := inventory.Get(email.HME); ok {
+			if event, ok := record.LastEvent(InventoryEventDeactivated); ok {
+				alias.DeactivatedAt = time.UnixMilli(event.At)
+				alias.Detected = event.Detected
+			}
+		}
+		result = append(result, alias)
+	}
+
+	sort.SliceStable(result, func(i, j int) bool {
+		a, b := result[i].DeactivatedAt, result[j].DeactivatedAt
+		if a.IsZero() != b.IsZero() {
+			return !a.IsZero()
+		}
+		return a.Before(b)
+	})
+	return result
+}
+
+// writeDeactivatedCSV 导出停用邮箱报告
+func writeDeactivatedCSV(w io.Writer, aliases []DeactivatedAlias, now time.Time) error {
+	writer := csv.NewWriter(w)
+	writer.Write([]string{"hme", "label", "note", "anonymous_id", "created_at", "deactivated_at", "days_deactivated", "deactivated_at_detected"})
+	for _, alias := range aliases {
+		created, deactivated, days := "", "", ""
+		if alias.Email.CreateTimestamp > 0 {
+			created = time.UnixMilli(alias.Email.CreateTimestamp).Format(time.RFC3339)
+		}
+		if !alias.DeactivatedAt.IsZero() {
+			deactivated = alias.DeactivatedAt.Format(time.RFC3339)
+			days = strconv.Itoa(alias.Age(now))
+		}
+		writer.Write([]string{
+			alias.Email.HME, alias.Email.Label, alias.Email.Note, alias.Email.AnonymousID,
+			created, deactivated, days, strconv.FormatBool(alias.Detected),
+		})
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// runDeactivatedReport 列出停用但未删除的邮箱及停用时长，便于定期复查后彻底删除
+func runDeactivatedReport(config *Config, args []string) error {
+	fs := flag.NewFlagSet("deactivated", flag.ContinueOnError)
+	olderThan := fs.Int("older-than", 0, "只显示停用超过多少天的邮箱")
+	csvPath := fs.String("csv", "", "导出为 CSV 文件（- 表示输出到标准输出）")
+	mask := fs.Bool("mask", false, "隐去邮箱前缀中间部分并省略 anonymous_id，便于截图或分享")
+	if err := fs.Parse(args); err != nil {
+		return usageError(err)
+	}
+
+	var emails []HMEEmail
+	if err := withSpinner("获取邮箱列表", func() error {
+		var err error
+		emails, err = listHME(config)
+		return err
+	}); err != nil {
+		return fmt.Errorf("获取列表失败: %w", err)
+	}
+	if err := inventory.Sync(emails); err != nil {
+		printWarning(fmt.Sprintf("同步本地清单失败: %v", err))
+	}
+
+	now := time.Now()
+	aliases := collectDeactivated(emails)
+	if *olderThan > 0 {
+		filtered := aliases[:0]
+		for _, alias := range aliases {
+			if alias.Age(now) >= *olderThan {
+				filtered = append(filtered, alias)
+			}
+		}
+		aliases = filtered
+	}
+	if *mask {
+		for i := range aliases {
+			aliases[i].Email.HME = maskEmailAddress(aliases[i].Email.HME)
+			aliases[i].Email.AnonymousID = ""
+		}
+	}
+
+	if *csvPath != "" {
+		if *csvPath == "-" {
+			return writeDeactivatedCSV(dataOutput, aliases, now)
+		}
+		file, err := os.OpenFile(*csvPath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+		if err != nil {
+			return fmt.Errorf("创建导出文件失败: %v", err)
+		}
+		defer file.Close()
+		if err := writeDeactivatedCSV(file, aliases, now); err != nil {
+			return fmt.Errorf("写入导出文件失败: %v", err)
+		}
+		printSuccess(fmt.Sprintf("已导出 %d 个停用邮箱到 %s", len(aliases), *csvPath))
+		return nil
+	}
+
+	printHeader("停用邮箱复查")
+	if len(aliases) == 0 {
+		printInfo("没有符合条件的停用邮箱")
+		return nil
+	}
+
+	var recent, middle, old, unknown int
+	for _, alias := range aliases {
+		switch age := alias.Age(now); {
+		case age < 0:
+			unknown++
+		case age < 30:
+			recent++
+		case age < 90:
+			middle++
+		default:
+			old++
+		}
+	}
+	fmt.Printf("  30 天内 %d "+ColorDim+"|"+ColorReset+" 30-90 天 %d "+ColorDim+"|"+ColorReset+" "+ColorYellow+"90 天以上 %d"+ColorReset+" "+ColorDim+"|"+ColorReset+" 时间未知 %d\n\n",
+		recent, middle, old, unknown)
+
+	emailWidth := 40
+	if width := getTerminalWidth() - 40; width < emailWidth && width > 20 {
+		emailWidth = width
+	}
+	for _, alias := range aliases {
+		label := alias.Email.Label
+		if label == "" {
+			label = "(无标签)"
+		}
+		age := ColorDim + "停用时间未知" + ColorReset
+		if days := alias.Age(now); days >= 0 {
+			color := ColorReset
+			if days >= 90 {
+				color = ColorYellow
+			}
+			age = fmt.Sprintf(color+"已停用 %d 天"+ColorReset, days)
+			if alias.Detected {
+				age += ColorDim + " (至少)" + ColorReset
+			}
+		}
+		fmt.Printf("  %s "+ColorCyan+"%-16s"+ColorReset+" %s\n", formatEmailAddress(alias.Email.HME, emailWidth), label, age)
+	}
+
+	fmt.Println()
+	printInfo(fmt.Sprintf("共 %d 个停用邮箱，确认不再需要后可在菜单 [6] 中彻底删除", len(aliases)))
+	if unknown > 0 {
+		printInfo("停用时间未知的邮箱在本工具开始记录之前已停用")
+	}
+	return nil
+}
@@ -0,0 +1,415 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"icloud-hme-generator/pkg/hme"
+)
+
+// 开发者工具默认请求的模拟服务器地址
+const defaultMockBaseURL = "http://127.0.0.1:8765/v1/hme/reserve"
+
+// 录制会话的默认目录
+const defaultSessionDir = "dev-sessions"
+
+// 发往非 iCloud 服务器时代替真实 dsid 与 clientId 的占位值
+const mockAccountPlaceholder = "dev-harness"
+
+// isICloudHost 地址是否属于 icloud.com，只有这些服务器才会收到账号的 Cookie、dsid 与 clientId
+func isICloudHost(host string) bool {
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	return host == "icloud.com" || strings.HasSuffix(host, ".icloud.com")
+}
+
+// withoutCredentials 去掉 Cookie 与 Authorization 后的请求头副本
+func withoutCredentials(headers map[string]string) map[string]string {
+	stripped := make(map[string]string, len(headers))
+	for key, value := range headers {
+		if !strings.EqualFold(key, "Cookie") && !strings.EqualFold(key, "Authorization") {
+			stripped[key] = value
+		}
+	}
+	return stripped
+}
+
+// confirmICloudTarget 目标是 iCloud 服务器时提示会使用真实账号并要求确认
+func confirmICloudTarget(target string) bool {
+	printWarning(fmt.Sprintf("%s 是 iCloud 服务器，请求会带上当前账号的 Cookie，并真实创建或修改邮箱", target))
+	if !confirmAction("确认继续") {
+		printInfo("已取消")
+		return false
+	}
+	return true
+}
+
+// 开发者功能开关，仅在开发者模式下生效
+const (
+	FeatureHTTPTrace     = "http_trace"
+	FeatureRecordSession = "record_session"
+	FeatureHTTPDump      = "http_dump"
+)
+
+// DeveloperConfig 开发者工具配置
+type DeveloperConfig struct {
+	Enabled     bool             `json:"enabled"`                 // 开发者模式，显示调试功能
+	MockBaseURL string           `json:"mock_base_url,omitempty"` // 模拟服务器的 reserve 接口地址
+	SessionDir  string           `json:"session_dir,omitempty"`   // 录制会话与 HTTP 调试日志的保存目录
+	Flags       map[string]bool  `json:"flags,omitempty"`         // 功能开关
+	MockServer  MockServerConfig `json:"mock_server"`             // 内置模拟服务器（mock-server 命令）
+}
+
+// FeatureFlag 可在开发者工具中切换的功能
+type FeatureFlag struct {
+	Name        string
+	Description string
+}
+
+var featureFlags = []FeatureFlag{
+	{FeatureHTTPTrace, "在标准错误输出每个接口请求的方法、路径、状态码与耗时"},
+	{FeatureRecordSession, "录制接口请求与响应（不含请求头和查询参数），供之后重放"},
+	{FeatureHTTPDump, "把每个接口请求的完整内容（URL、请求头、请求体、响应与各阶段耗时）写入调试日志，Cookie 与 dsid 已隐去"},
+}
+
+// featureEnabled 开发者模式下功能开关是否打开
+func (c *Config) featureEnabled(name string) bool {
+	return c != nil && c.Developer.Enabled && c.Developer.Flags[name]
+}
+
+// developerSessionDir 录制会话保存目录
+func (c *Config) developerSessionDir() string {
+	if c.Developer.SessionDir != "" {
+		return c.Developer.SessionDir
+	}
+	return defaultSessionDir
+}
+
+// handleDeveloperHarness 开发者工具菜单
+func handleDeveloperHarness(config *Config) {
+	for {
+		printHeader("开发者工具")
+		fmt.Println("  " + ColorGreen + "[1]" + ColorReset + " 测试评分算法")
+		fmt.Println("  " + ColorBlue + "[2]" + ColorReset + " 请求模拟服务器 " + ColorDim + "(生成 → 确认 → 列表)" + ColorReset)
+		fmt.Println("  " + ColorYellow + "[3]" + ColorReset + " 查看传输统计")
+		fmt.Println("  " + ColorMagenta + "[4]" + ColorReset + " 功能开关")
+		fmt.Println("  " + ColorCyan + "[5]" + ColorReset + " 重放录制的会话")
+		fmt.Println("  " + ColorDim + "[0]" + ColorReset + " 返回主菜单")
+		printSeparator()
+		fmt.Println()
+
+		switch strings.TrimSpace(readInput("选择 (0-5): ")) {
+		case "1":
+			testEmailScoring()
+		case "2":
+			runMockRoundTrip(config)
+		case "3":
+			printSubHeader("传输统计")
+			transportStats.Print()
+			latencyTracker.Print()
+			printRuntimeStats()
+			if path := sessionRecorder.Path(); path != "" {
+				fmt.Printf("  "+ColorCyan+"会话录制:"+ColorReset+" %s\n", path)
+			}
+			if path := httpDumper.Path(); path != "" {
+				fmt.Printf("  "+ColorCyan+"HTTP 调试日志:"+ColorReset+" %s\n", path)
+			}
+			if strings.ToLower(readInput("\n输入 r 清空统计 "+ColorGray+"(回车返回)"+ColorReset+": ")) == "r" {
+				transportStats.Reset()
+				latencyTracker.Reset()
+				printSuccess("已清空")
+			}
+		case "4":
+			handleFeatureFlags(config)
+		case "5":
+			handleReplaySession(config)
+		case "0", "":
+			return
+		default:
+			printError("无效选择，请输入 0-5")
+		}
+	}
+}
+
+// printRuntimeStats 显示运行时资源：goroutine 数、堆内存、打开的文件数与安全管理器跟踪的后台任务，
+// 长时间停留在菜单中时数值应保持稳定，持续增长说明有泄漏
+func printRuntimeStats() {
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+	printSubHeader("运行时")
+	fmt.Printf("  "+ColorCyan+"goroutine:"+ColorReset+" %d\n", runtime.NumGoroutine())
+	fmt.Printf("  "+ColorCyan+"堆内存:"+ColorReset+" %s "+ColorGray+"(向系统申请 %s)"+ColorReset+"\n", formatMiB(mem.HeapAlloc), formatMiB(mem.Sys))
+	if fds := openFileCount(); fds >= 0 {
+		fmt.Printf("  "+ColorCyan+"打开的文件:"+ColorReset+" %d\n", fds)
+	}
+	tasks := safetyManager.BackgroundTasks()
+	if len(tasks) == 0 {
+		fmt.Println("  " + ColorCyan + "后台任务:" + ColorReset + " 无")
+		return
+	}
+	names := make([]string, 0, len(tasks))
+	for name := range tasks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for i, name := range names {
+		if tasks[name] > 1 {
+			names[i] = fmt.Sprintf("%s ×%d", name, tasks[name])
+		}
+	}
+	fmt.Printf("  "+ColorCyan+"后台任务:"+ColorReset+" %s\n", strings.Join(names, ", "))
+}
+
+// mockBaseURL 询问要使用的模拟服务器地址，回车使用配置或默认值
+func mockBaseURL(config *Config) string {
+	fallback := config.Developer.MockBaseURL
+	if fallback == "" {
+		fallback = defaultMockBaseURL
+	}
+	if input := strings.TrimSpace(readInput("模拟服务器地址 " + ColorGray + "(回车使用 " + fallback + ")" + ColorReset + ": ")); input != "" {
+		return input
+	}
+	return fallback
+}
+
+// runMockRoundTrip 用当前配置的参数向模拟服务器走一遍完整流程，逐步显示耗时
+func runMockRoundTrip(config *Config) {
+	printSubHeader("请求模拟服务器")
+	client := config.hmeClient()
+	client.BaseURL = mockBaseURL(config)
+	target, err := url.Parse(client.BaseURL)
+	if err != nil || target.Host == "" {
+		printError("无效的服务器地址")
+		return
+	}
+	if isICloudHost(target.Host) {
+		if !confirmICloudTarget(target.Host) {
+			return
+		}
+	} else {
+		client.Headers = withoutCredentials(client.Headers)
+		client.DSID, client.ClientID = mockAccountPlaceholder, mockAccountPlaceholder
+		client.RefreshSession = nil
+	}
+	ctx := apiContext()
+
+	step := func(name string, action func() (string, error)) bool {
+		start := time.Now()
+		detail, err := action()
+		elapsed := time.Since(start).Round(time.Millisecond)
+		if err != nil {
+			printError(fmt.Sprintf("%s 失败 (%s): %v", name, elapsed, err))
+			return false
+		}
+		printSuccess(fmt.Sprintf("%s %s "+ColorDim+"(%s)"+ColorReset, name, detail, elapsed))
+		return true
+	}
+
+	var address string
+	if !step("generate", func() (string, error) {
+		var err error
+		address, err = client.Generate(ctx, "")
+		return address, err
+	}) {
+		return
+	}
+	if !step("reserve", func() (string, error) {
+		email, err := client.Reserve(ctx, address, "dev-harness", "")
+		if err != nil {
+			return "", err
+		}
+		return email.AnonymousID, nil
+	}) {
+		return
+	}
+	step("list", func() (string, error) {
+		list, err := client.List(ctx)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("共 %d 个", len(list.Emails)), nil
+	})
+}
+
+// handleFeatureFlags 切换开发者功能开关并保存到配置
+func handleFeatureFlags(config *Config) {
+	for {
+		printSubHeader("功能开关")
+		for i, flag := range featureFlags {
+			fmt.Printf("  "+ColorCyan+"[%d]"+ColorReset+" %-16s %s\n", i+1, flag.Name, formatBoolSetting(config.Developer.Flags[flag.Name]))
+			fmt.Printf("      "+ColorDim+"%s"+ColorReset+"\n", flag.Description)
+		}
+		input := readInput("\n切换序号 " + ColorGray + "(回车返回)" + ColorReset + ": ")
+		if input == "" {
+			return
+		}
+		index, err := strconv.Atoi(input)
+		if err != nil || index < 1 || index > len(featureFlags) {
+			printError("无效的序号")
+			continue
+		}
+		name := featureFlags[index-1].Name
+		if config.Developer.Flags == nil {
+			config.Developer.Flags = make(map[string]bool)
+		}
+		config.Developer.Flags[name] = !config.Developer.Flags[name]
+		saveConfigWithMessage(config, fmt.Sprintf("%s 已设置为: %v", name, config.Developer.Flags[name]))
+	}
+}
+
+// loadSession 读取录制的会话文件
+func loadSession(path string) ([]SessionEntry, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var entries []SessionEntry
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+		var entry SessionEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return nil, fmt.Errorf("第 %d 行格式错误: %w", line, err)
+		}
+		entries = append(entries, entry)
+	}
+	return entries, scanner.Err()
+}
+
+// sessionSuccess 从响应体中读取 success 字段，无法解析时返回 "-"
+func sessionSuccess(body []byte) string {
+	var envelope struct {
+		Success *bool `json:"success"`
+	}
+	if json.Unmarshal(body, &envelope) != nil || envelope.Success == nil {
+		return "-"
+	}
+	return strconv.FormatBool(*envelope.Success)
+}
+
+// handleReplaySession 选择一个录制的会话，按原顺序向目标服务器重放，比较状态码与 success
+func handleReplaySession(config *Config) {
+	printSubHeader("重放会话")
+	files, _ := filepath.Glob(filepath.Join(config.developerSessionDir(), "session-*.ndjson"))
+	if len(files) == 0 {
+		printInfo(fmt.Sprintf("%s 中没有录制的会话，请先打开功能开关 %s", config.developerSessionDir(), FeatureRecordSession))
+		return
+	}
+	sort.Sort(sort.Reverse(sort.StringSlice(files)))
+	for i, file := range files {
+		fmt.Printf("  "+ColorCyan+"[%d]"+ColorReset+" %s\n", i+1, filepath.Base(file))
+	}
+	input := readInput("\n选择会话 " + ColorGray + "(回车取消)" + ColorReset + ": ")
+	if input == "" {
+		return
+	}
+	index, err := strconv.Atoi(input)
+	if err != nil || index < 1 || index > len(files) {
+		printError("无效的序号")
+		return
+	}
+	entries, err := loadSession(files[index-1])
+	if err != nil {
+		printError(fmt.Sprintf("读取会话失败: %v", err))
+		return
+	}
+	if len(entries) == 0 {
+		printInfo("会话为空")
+		return
+	}
+
+	target, err := url.Parse(mockBaseURL(config))
+	if err != nil || target.Host == "" {
+		printError("无效的服务器地址")
+		return
+	}
+	if isICloudHost(target.Host) && !confirmICloudTarget(target.Host) {
+		return
+	}
+
+	fmt.Println()
+	matched := 0
+	for i, entry := range entries {
+		replayed, replayedSuccess, elapsed, err := replaySessionEntry(config, target, entry)
+		recorded := fmt.Sprintf("%d/%s", entry.Status, sessionSuccess(entry.ResponseBody))
+		result := fmt.Sprintf("%d/%s", replayed, replayedSuccess)
+		if err != nil {
+			result = err.Error()
+		}
+		mark := ColorGreen + "=" + ColorReset
+		if result == recorded {
+			matched++
+		} else {
+			mark = ColorRed + "≠" + ColorReset
+		}
+		fmt.Printf("  "+ColorDim+"%2d."+ColorReset+" %-6s %-28s %s %s %s "+ColorDim+"(原 %dms，现 %s)"+ColorReset+"\n",
+			i+1, entry.Method, entry.Path, recorded, mark, result, entry.ElapsedMS, elapsed.Round(time.Millisecond))
+	}
+	printSeparator()
+	if matched == len(entries) {
+		printSuccess(fmt.Sprintf("%d 个请求的状态码与 success 均一致", matched))
+	} else {
+		printWarning(fmt.Sprintf("%d/%d 个请求结果不同", len(entries)-matched, len(entries)))
+	}
+}
+
+// replaySessionEntry 重发一条记录，返回状态码与 success。目标是 iCloud 服务器时使用当前账号参数与请求头，
+// 其他服务器收不到 Cookie，dsid 与 clientId 也换成占位值
+func replaySessionEntry(config *Config, target *url.URL, entry SessionEntry) (int, string, time.Duration, error) {
+	headers, clientID, dsid := config.Headers, config.ClientID, config.DSID
+	if !isICloudHost(target.Host) {
+		headers = withoutCredentials(headers)
+		clientID, dsid = mockAccountPlaceholder, mockAccountPlaceholder
+	}
+	endpoint := *target
+	endpoint.Path = entry.Path
+	endpoint.RawQuery = url.Values{
+		"clientBuildNumber":     {config.ClientBuildNumber},
+		"clientMasteringNumber": {config.ClientMasteringNumber},
+		"clientId":              {clientID},
+		"dsid":                  {dsid},
+	}.Encode()
+
+	req, err := http.NewRequest(entry.Method, endpoint.String(), bytes.NewReader(entry.RequestBody))
+	if err != nil {
+		return 0, "", 0, err
+	}
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+	if len(entry.RequestBody) > 0 && req.Header.Get("Content-Type") == "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	config.applyUserAgent(req)
+
+	start := time.Now()
+	resp, err := config.httpClient().Do(req)
+	if err != nil {
+		return 0, "", time.Since(start), err
+	}
+	body, err := hme.ReadResponseBody(resp)
+	elapsed := time.Since(start)
+	if err != nil {
+		return resp.StatusCode, "", elapsed, err
+	}
+	return resp.StatusCode, sessionSuccess(body), elapsed, nil
+}
@@ -0,0 +1,214 @@
+package main
+
+import (
+	_ "embed"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+)
+
+// 内置英文常用词表，每行一个单词，大致按常用程度排列
+//
+//go:embed wordlist/en.txt
+var embeddedWordlist string
+
+// 参与词典匹配的最短单词长度，避免 at、in 之类的短词把随机字母也算作单词
+const minDictionaryWord = 3
+
+// dictionarySuffixes 词形变化的后缀及还原方式，如 kettles → kettle、doltish → dolt、bumpier → bumpy
+var dictionarySuffixes = []struct {
+	suffix, replace string
+}{
+	{"iest", "y"}, {"ier", "y"}, {"ies", "y"}, {"ied", "y"},
+	{"ing", ""}, {"ing", "e"}, {"ed", ""}, {"ed", "e"}, {"es", ""}, {"s", ""},
+	{"est", ""}, {"er", ""}, {"ish", ""}, {"ly", ""}, {"ness", ""}, {"ful", ""}, {"less", ""}, {"y", ""},
+}
+
+// wordDictionary 可读性评分使用的词典
+type wordDictionary struct {
+	ranks  map[string]int // 单词 → 在词表中的位置（从 1 开始，越小越常见）
+	maxLen int
+}
+
+// addWords 从词表文本中加入单词，已有的单词保持原来的位置
+func (d *wordDictionary) addWords(text string) {
+	for _, line := range strings.Split(text, "\n") {
+		word := strings.ToLower(strings.TrimSpace(line))
+		if word == "" || strings.HasPrefix(word, "#") {
+			continue
+		}
+		if fields := strings.Fields(word); len(fields) > 1 {
+			word = fields[0] // 兼容 “单词 词频” 格式的词表
+		}
+		if _, ok := d.ranks[word]; ok || len(word) < minDictionaryWord {
+			continue
+		}
+		d.ranks[word] = len(d.ranks) + 1
+		if len(word) > d.maxLen {
+			d.maxLen = len(word)
+		}
+	}
+}
+
+var (
+	dictionaryMutex sync.Mutex
+	dictionaryCache = make(map[string]*wordDictionary) // 按 wordlist_file 缓存
+)
+
+// dictionaryFor 内置词表加上 email_quality.wordlist_file 中的单词；自定义词表读取失败时提示一次并只使用内置词表
+func dictionaryFor(path string) *wordDictionary {
+	dictionaryMutex.Lock()
+	defer dictionaryMutex.Unlock()
+	if dict, ok := dictionaryCache[path]; ok {
+		return dict
+	}
+	dict := &wordDictionary{ranks: make(map[string]int)}
+	dict.addWords(embeddedWordlist)
+	if path != "" {
+		if data, err := os.ReadFile(path); err != nil {
+			printWarning(fmt.Sprintf("读取词表 %s 失败: %v，只使用内置词表", path, err))
+		} else {
+			dict.addWords(string(data))
+		}
+	}
+	dictionaryCache[path] = dict
+	return dict
+}
+
+// resetDictionaries 清空词典缓存，下次评分时重新读取自定义词表
+func resetDictionaries() {
+	dictionaryMutex.Lock()
+	defer dictionaryMutex.Unlock()
+	dictionaryCache = make(map[string]*wordDictionary)
+}
+
+// isWord 是否为词典中的单词或其常见词形变化（复数、过去式、-ish、-ier 等）
+func (d *wordDictionary) isWord(word string) bool {
+	if len(word) < minDictionaryWord {
+		return false
+	}
+	if _, ok := d.ranks[word]; ok {
+		return true
+	}
+	for _, s := range dictionarySuffixes {
+		if !strings.HasSuffix(word, s.suffix) {
+			continue
+		}
+		base := strings.TrimSuffix(word, s.suffix)
+		if len(base) < minDictionaryWord {
+			continue
+		}
+		if _, ok := d.ranks[base+s.replace]; ok {
+			return true
+		}
+		// 双写末尾辅音的变化，如 bigger → big、running → run
+		if n := len(base); s.replace == "" && n > minDictionaryWord && base[n-1] == base[n-2] {
+			if _, ok := d.ranks[base[:n-1]]; ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// coverage 字母串中能被拆分为词典单词的字母数（取覆盖最多的拆分方式），如 kettle|s 为 7
+func (d *wordDictionary) coverage(token string) int {
+	longest := d.maxLen + 4 // 留出词形变化后缀的长度
+	best := make([]int, len(token)+1)
+	for i := 1; i <= len(token); i++ {
+		best[i] = best[i-1]
+		for j := i - minDictionaryWord; j >= 0 && i-j <= longest; j-- {
+			if covered := best[j] + i - j; covered > best[i] && d.isWord(token[j:i]) {
+				best[i] = covered
+			}
+		}
+	}
+	return best[len(token)]
+}
+
+// pronounceability 按音节评估字母串是否好读（0-100）：每个音节以元音为核心，
+// 超过 3 个的辅音丛、超过 2 个的连续元音和没有元音的片段都会扣分
+func pronounceability(token string) int {
+	if len(token) == 1 {
+		return 50
+	}
+	isVowel := func(i int) bool {
+		switch token[i] {
+		case 'a', 'e', 'i', 'o', 'u':
+			return true
+		case 'y':
+			return i > 0 // 词首的 y 按辅音处理
+		}
+		return false
+	}
+
+	score, syllables := 100, 0
+	for i := 0; i < len(token); {
+		j := i
+		vowel := isVowel(i)
+		for j < len(token) && isVowel(j) == vowel {
+			j++
+		}
+		run := j - i
+		switch {
+		case vowel:
+			syllables++
+			if run > 2 {
+				score -= 15 * (run - 2)
+			}
+		case run > 3:
+			score -= 20 * (run - 3)
+		case run == 3 && i > 0 && j < len(token):
+			score -= 5 // 词中的三辅音丛（如 ngst）略难读
+		}
+		i = j
+	}
+	if syllables == 0 {
+		return 10
+	}
+	// 音节过少说明辅音太多，如 strngth
+	if perSyllable := len(token) / syllables; perSyllable > 5 {
+		score -= 10 * (perSyllable - 5)
+	}
+	return clampScore(score)
+}
+
+// evaluateReadability 评估可读性 (0-100分)：按字母片段计算词典单词覆盖率与音节可读性，
+// 数字等杂字符和连续重复字符扣分，如 kettles.doltish_8p 两个单词都在词典中，得分较高
+func evaluateReadability(prefix string, dict *wordDictionary, why *scoreReasons) int {
+	if prefix == "" {
+		return 0
+	}
+	prefix = strings.ToLower(prefix)
+
+	letters, covered, pronounce, noise := 0, 0, 0, 0
+	for _, token := range strings.FieldsFunc(prefix, func(r rune) bool { return r < 'a' || r > 'z' }) {
+		letters += len(token)
+		covered += dict.coverage(token)
+		pronounce += pronounceability(token) * len(token)
+	}
+	for _, r := range prefix {
+		if (r < 'a' || r > 'z') && !strings.ContainsRune("._-", r) {
+			noise++
+		}
+	}
+	if letters == 0 {
+		why.add("没有字母")
+		return 10 // 没有字母，如纯数字
+	}
+
+	score := covered*55/letters + pronounce/letters*45/100
+	why.add("%d/%d 个字母组成词典单词", covered, letters)
+	why.add("音节可读性 %d", pronounce/letters)
+	if noise > 0 {
+		penalty := min(noise*4, 20)
+		why.add("%d 个数字等杂字符 (-%d)", noise, penalty)
+		score -= penalty
+	}
+	if repeat := longestRepeat(prefix); repeat >= 3 {
+		why.add("%d 个连续重复字符 (-25)", repeat)
+		score -= 25
+	}
+	return clampScore(score)
+}
@@ -0,0 +1,293 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 批量修改标签时替换为序号的占位符，如 shop-{n} → shop-1、shop-2
+const labelSequencePlaceholder = "{n}"
+
+// 交互式编辑时输入该值表示清空备注
+const clearNoteInput = "-"
+
+// MetaDataEdit 一个邮箱的标签/备注修改
+type MetaDataEdit struct {
+	Email HMEEmail
+	Label string
+	Note  string
+}
+
+// Changed 是否与当前值不同
+func (e MetaDataEdit) Changed() bool {
+	return e.Label != e.Email.Label || e.Note != e.Email.Note
+}
+
+// parseIndexSelection 解析 "1,3,5-7" 或 all 形式的序号选择，返回从 0 开始的下标（去重并保持输入顺序）
+func parseIndexSelection(input string, count int) ([]int, error) {
+	input = strings.TrimSpace(input)
+	if strings.EqualFold(input, "all") || input == "*" {
+		indexes := make([]int, count)
+		for i := range indexes {
+			indexes[i] = i
+		}
+		return indexes, nil
+	}
+
+	var indexes []int
+	seen := make(map[int]bool)
+	for _, part := range strings.Split(input, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		start, end := part, part
+		if i := strings.Index(part, "-"); i > 0 {
+			start, end = part[:i], part[i+1:]
+		}
+		from, err1 := strconv.Atoi(strings.TrimSpace(start))
+		to, err2 := strconv.Atoi(strings.TrimSpace(end))
+		if err1 != nil || err2 != nil || from < 1 || to > count || from > to {
+			return nil, fmt.Errorf("无效的序号: %s", part)
+		}
+		for idx := from; idx <= to; idx++ {
+			if !seen[idx] {
+				seen[idx] = true
+				indexes = append(indexes, idx-1)
+			}
+		}
+	}
+	if len(indexes) == 0 {
+		return nil, fmt.Errorf("未选择任何邮箱")
+	}
+	return indexes, nil
+}
+
+// planMetaDataEdits 为选中的邮箱生成修改计划。label 为空时保留原标签，含 {n} 时按选择顺序编号；
+// note 为 nil 时保留原备注
+func planMetaDataEdits(emails []HMEEmail, label string, note *string) []MetaDataEdit {
+	edits := make([]MetaDataEdit, 0, len(emails))
+	for i, email := range emails {
+		edit := MetaDataEdit{Email: email, Label: email.Label, Note: email.Note}
+		if label != "" {
+			edit.Label = strings.ReplaceAll(label, labelSequencePlaceholder, strconv.Itoa(i+1))
+		}
+		if note != nil {
+			edit.Note = *note
+		}
+		edits = append(edits, edit)
+	}
+	return edits
+}
+
+// printMetaDataEdits 显示修改前后的标签与备注
+func printMetaDataEdits(edits []MetaDataEdit) {
+	for _, edit := range edits {
+		fmt.Printf("  "+ColorYellow+"›"+ColorReset+" %s\n", edit.Email.HME)
+		if edit.Label != edit.Email.Label {
+			fmt.Printf("      "+ColorCyan+"标签:"+ColorReset+" %s "+ColorDim+"→"+ColorReset+" "+ColorBrightGreen+"%s"+ColorReset+"\n", displayOrEmpty(edit.Email.Label), edit.Label)
+		}
+		if edit.Note != edit.Email.Note {
+			fmt.Printf("      "+ColorCyan+"备注:"+ColorReset+" %s "+ColorDim+"→"+ColorReset+" "+ColorBrightGreen+"%s"+ColorReset+"\n", displayOrEmpty(edit.Email.Note), displayOrEmpty(edit.Note))
+		}
+	}
+}
+
+// displayOrEmpty 空值显示为 (空)
+func displayOrEmpty(value string) string {
+	if value == "" {
+		return ColorDim + "(空)" + ColorReset
+	}
+	return value
+}
+
+// applyMetaDataEdits 逐个调用 updateMetaData 并同步本地清单，op 为进度事件中的操作名；
+// 收到退出信号时不再开始新的修改，返回成功与失败的数量以及最后一个错误
+func applyMetaDataEdits(config *Config, op string, edits []MetaDataEdit) (succeeded, failed int, lastErr error) {
+	for i, edit := range edits {
+		printProgressBar(i, len(edits), "更新进度")
+		err := updateMetaDataHME(config, edit.Email.AnonymousID, edit.Label, edit.Note)
+		emitProgressItem(op, i+1, len(edits), edit.Label, edit.Email.HME, err)
+		if err != nil {
+			fmt.Printf("\n    "+ColorRed+"[!]"+ColorReset+" %s: %v\n", edit.Email.HME, err)
+			failed++
+			lastErr = err
+		} else {
+			succeeded++
+			updated := edit.Email
+			updated.Label, updated.Note = edit.Label, edit.Note
+			if err := inventory.RecordMetaData(updated); err != nil {
+				printWarning(fmt.Sprintf("记录本地清单失败: %v", err))
+			}
+		}
+		if i < len(edits)-1 {
+			emitProgressPause(op, len(edits), 500*time.Millisecond)
+			if !sleepUnlessCanceled(500 * time.Millisecond) {
+				break
+			}
+		}
+	}
+	printProgressBar(succeeded+failed, len(edits), "更新进度")
+	emitProgressDone(op, len(edits), succeeded, failed)
+	reportBatchCanceled(succeeded+failed, len(edits))
+	return succeeded, failed, lastErr
+}
+
+// handleEditEmails 菜单中编辑邮箱的标签和备注，可一次选择多个邮箱批量改名
+func handleEditEmails(config *Config) {
+	printHeader("编辑标签/备注")
+	emails, ok := listHMEInteractive(config, "获取邮箱列表")
+	if !ok {
+		return
+	}
+	if len(emails) == 0 {
+		printInfo("暂无邮箱")
+		return
+	}
+
+	for i, email := range emails {
+		status := ColorGreen + "●" + ColorReset
+		if !email.IsActive {
+			status = ColorYellow + "○" + ColorReset
+		}
+		fmt.Printf("  "+ColorDim+"%2d."+ColorReset+" %s %s "+ColorCyan+"%s"+ColorReset+"\n", i+1, status, formatEmailAddress(email.HME, 36), email.Label)
+	}
+	fmt.Println()
+
+	printInfo("输入序号 (逗号分隔如 1,3,5，范围如 2-6，或输入 all 全选)")
+	input := readInput("序号: ")
+	if input == "" {
+		printInfo("已取消")
+		return
+	}
+	indexes, err := parseIndexSelection(input, len(emails))
+	if err != nil {
+		printError(err.Error())
+		return
+	}
+	selected := make([]HMEEmail, 0, len(indexes))
+	for _, idx := range indexes {
+		selected = append(selected, emails[idx])
+	}
+
+	if len(selected) > 1 {
+		printInfo(fmt.Sprintf("标签中的 %s 会按选择顺序替换为 1、2、3…", labelSequencePlaceholder))
+	} else {
+		fmt.Printf("  "+ColorCyan+"当前标签:"+ColorReset+" %s\n", displayOrEmpty(selected[0].Label))
+		fmt.Printf("  "+ColorCyan+"当前备注:"+ColorReset+" %s\n", displayOrEmpty(selected[0].Note))
+	}
+	label := strings.TrimSpace(readInput("新标签 " + ColorGray + "(回车保持不变)" + ColorReset + ": "))
+	noteInput := readInput("新备注 " + ColorGray + "(回车保持不变，输入 - 清空)" + ColorReset + ": ")
+	var note *string
+	switch strings.TrimSpace(noteInput) {
+	case "":
+	case clearNoteInput:
+		empty := ""
+		note = &empty
+	default:
+		value := strings.TrimSpace(noteInput)
+		note = &value
+	}
+
+	var edits []MetaDataEdit
+	for _, edit := range planMetaDataEdits(selected, label, note) {
+		if edit.Changed() {
+			edits = append(edits, edit)
+		}
+	}
+	if len(edits) == 0 {
+		printInfo("没有需要修改的内容")
+		return
+	}
+
+	fmt.Println()
+	printMetaDataEdits(edits)
+	if !confirmAction(fmt.Sprintf("确认修改 %d 个邮箱", len(edits))) {
+		printInfo("已取消")
+		return
+	}
+
+	printSubHeader("更新标签/备注")
+	succeeded, failed, _ := applyMetaDataEdits(config, "edit", edits)
+	fmt.Println()
+	printSeparator()
+	if succeeded > 0 {
+		printSuccess(fmt.Sprintf("已更新 %d 个", succeeded))
+	}
+	if failed > 0 {
+		printError(fmt.Sprintf("失败 %d 个", failed))
+	}
+}
+
+// runEditCommand 修改标签/备注：edit 邮箱或anonymous_id... [-label 标签] [-set-label 新标签] [-note 新备注]
+func runEditCommand(config *Config, args []string) error {
+	fs := flag.NewFlagSet("edit", flag.ContinueOnError)
+	label := fs.String("label", "", "按标签选择邮箱（忽略大小写）")
+	newLabel := fs.String("set-label", "", "新标签，选中多个邮箱时 {n} 替换为序号")
+	noteFlag := fs.String("note", "", "新备注，传入空字符串清空备注")
+	if err := fs.Parse(args); err != nil {
+		return usageError(err)
+	}
+	var note *string
+	fs.Visit(func(f *flag.Flag) {
+		if f.Name == "note" {
+			note = noteFlag
+		}
+	})
+	*newLabel = strings.TrimSpace(*newLabel)
+	if *newLabel == "" && note == nil {
+		return usageError(fmt.Errorf("用法: edit 邮箱或anonymous_id... [-label 标签] -set-label 新标签 [-note 新备注]"))
+	}
+
+	selected, err := resolveEmailArgs(config, fs.Args(), *label)
+	if err != nil {
+		return err
+	}
+	var edits []MetaDataEdit
+	for _, edit := range planMetaDataEdits(selected, *newLabel, note) {
+		if !edit.Changed() {
+			printInfo(fmt.Sprintf("跳过 %s：没有变化", edit.Email.HME))
+			continue
+		}
+		edits = append(edits, edit)
+	}
+	if len(edits) == 0 {
+		printInfo("没有需要修改的邮箱")
+		return nil
+	}
+
+	printMetaDataEdits(edits)
+	if len(edits) > 1 && !confirmAction(fmt.Sprintf("确认修改 %d 个邮箱", len(edits))) {
+		return fmt.Errorf("已取消")
+	}
+
+	failed := 0
+	for i, edit := range edits {
+		err := updateMetaDataHME(config, edit.Email.AnonymousID, edit.Label, edit.Note)
+		writeResult(CLIResult{Op: "edit", Email: edit.Email.HME, Label: edit.Label, AnonymousID: edit.Email.AnonymousID}, err)
+		if err != nil {
+			printError(fmt.Sprintf("修改 %s 失败: %v", edit.Email.HME, err))
+			failed++
+			if failed == len(edits) {
+				return fmt.Errorf("全部 %d 个均修改失败: %w", failed, err)
+			}
+		} else {
+			printSuccess(fmt.Sprintf("已修改 %s", edit.Email.HME))
+			updated := edit.Email
+			updated.Label, updated.Note = edit.Label, edit.Note
+			if err := inventory.RecordMetaData(updated); err != nil {
+				printWarning(fmt.Sprintf("记录本地清单失败: %v", err))
+			}
+		}
+		if i < len(edits)-1 && !sleepUnlessCanceled(500*time.Millisecond) {
+			break
+		}
+	}
+	if failed > 0 {
+		return withExitCode(ExitPartial, fmt.Errorf("%d 个修改失败", failed))
+	}
+	return nil
+}
@@ -0,0 +1,335 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 每日统计保留的天数
+const errorStatsRetentionDays = 180
+
+// 每日统计文件写入时使用的临时文件名
+const errorStatsTempPattern = ".error-stats-*.tmp"
+
+// 前后两段失败率相差超过该百分点时视为明显变化
+const errorRateShiftPoints = 20
+
+// DailyOutcomes 一个账号一天内的创建结果
+type DailyOutcomes struct {
+	Succeeded int            `json:"succeeded"`
+	Failed    map[string]int `json:"failed,omitempty"` // 按失败原因（failureClass.Key）计数
+}
+
+// Total 当天的创建次数
+func (d *DailyOutcomes) Total() int {
+	total := d.Succeeded
+	for _, count := range d.Failed {
+		total += count
+	}
+	return total
+}
+
+// ErrorStats 按账号与日期持久化的创建成功/失败次数，用于观察失败率的长期趋势
+type ErrorStats struct {
+	Accounts map[string]map[string]*DailyOutcomes `json:"accounts"` // 账号名 → 日期（2006-01-02）→ 结果
+	Classes  map[string]string                    `json:"classes"`  // 失败原因的显示名称
+}
+
+// 同一进程内的创建任务共用统计文件
+var errorStatsMutex sync.Mutex
+
+// errorStatsFile 统计文件位于状态目录，各账号共用以便比较
+func errorStatsFile(config *Config) string {
+	return filepath.Join(stateDir(config), "error-stats.json")
+}
+
+// loadErrorStats 读取统计文件，不存在时返回空统计
+func loadErrorStats(config *Config) (*ErrorStats, error) {
+	stats := &ErrorStats{}
+	data, _, err := readFileRecovering(errorStatsFile(config), errorStatsTempPattern, errorStatsFormat.validator(func() interface{} {
+		return &ErrorStats{}
+	}))
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("读取创建统计失败: %w", err)
+	}
+	if err == nil {
+		if err := errorStatsFormat.decode(data, stats); err != nil {
+			return nil, fmt.Errorf("解析创建统计失败: %w", err)
+		}
+	}
+	if stats.Accounts == nil {
+		stats.Accounts = make(map[string]map[string]*DailyOutcomes)
+	}
+	if stats.Classes == nil {
+		stats.Classes = make(map[string]string)
+	}
+	return stats, nil
+}
+
+// recordCreateOutcome 把一次创建结果计入当天的统计，取消的请求不计入。写入失败只影响统计，不影响创建
+func recordCreateOutcome(config *Config, err error) {
+	if errors.Is(err, context.Canceled) {
+		return
+	}
+	errorStatsMutex.Lock()
+	defer errorStatsMutex.Unlock()
+
+	stats, loadErr := loadErrorStats(config)
+	if loadErr != nil {
+		return
+	}
+	account := config.profileLabel()
+	days := stats.Accounts[account]
+	if days == nil {
+		days = make(map[string]*DailyOutcomes)
+		stats.Accounts[account] = days
+	}
+	today := time.Now().Format("2006-01-02")
+	day := days[today]
+	if day == nil {
+		day = &DailyOutcomes{}
+		days[today] = day
+	}
+	if err == nil {
+		day.Succeeded++
+	} else {
+		class := classifyFailure(err)
+		if day.Failed == nil {
+			day.Failed = make(map[string]int)
+		}
+		day.Failed[class.Key]++
+		stats.Classes[class.Key] = class.Name
+	}
+
+	oldest := time.Now().AddDate(0, 0, -errorStatsRetentionDays).Format("2006-01-02")
+	for _, days := range stats.Accounts {
+		for date := range days {
+			if date < oldest {
+				delete(days, date)
+			}
+		}
+	}
+
+	path := errorStatsFile(config)
+	if os.MkdirAll(filepath.Dir(path), 0700) == nil {
+		errorStatsFormat.write(path, errorStatsTempPattern, stats)
+	}
+}
+
+// windowOutcomes 汇总 [from, to) 天前（从今天往前数）的结果
+func windowOutcomes(days map[string]*DailyOutcomes, from, to int) DailyOutcomes {
+	var sum DailyOutcomes
+	sum.Failed = make(map[string]int)
+	for offset := from; offset < to; offset++ {
+		day := days[time.Now().AddDate(0, 0, -offset).Format("2006-01-02")]
+		if day == nil {
+			continue
+		}
+		sum.Succeeded += day.Succeeded
+		for key, count := range day.Failed {
+			sum.Failed[key] += count
+		}
+	}
+	return sum
+}
+
+// failureRate 失败率（百分比），没有创建记录时返回 -1
+func failureRate(d DailyOutcomes) int {
+	total := d.Total()
+	if total == 0 {
+		return -1
+	}
+	return (total - d.Succeeded) * 100 / total
+}
+
+// formatFailureRate 失败率文本，没有记录时显示 -
+func formatFailureRate(rate int) string {
+	if rate < 0 {
+		return "-"
+	}
+	return fmt.Sprintf("%d%%", rate)
+}
+
+// topFailures 按次数排列的失败原因，如 “被限流 8、网络错误 1”
+func (s *ErrorStats) topFailures(failed map[string]int) string {
+	keys := make([]string, 0, len(failed))
+	for key := range failed {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if failed[keys[i]] != failed[keys[j]] {
+			return failed[keys[i]] > failed[keys[j]]
+		}
+		return keys[i] < keys[j]
+	})
+	parts := make([]string, 0, len(keys))
+	for _, key := range keys {
+		name := s.Classes[key]
+		if name == "" {
+			name = key
+		}
+		parts = append(parts, fmt.Sprintf("%s %d", name, failed[key]))
+	}
+	return strings.Join(parts, "、")
+}
+
+// rateBar 失败率的简易条形图，每格 10%
+func rateBar(rate int) string {
+	if rate < 0 {
+		return ""
+	}
+	filled := (rate + 9) / 10
+	color := ColorGreen
+	switch {
+	case rate >= 50:
+		color = ColorRed
+	case rate >= 20:
+		color = ColorYellow
+	}
+	return color + strings.Repeat("█", filled) + ColorReset + ColorDim + strings.Repeat("░", 10-filled) + ColorReset
+}
+
+// printAccountTrend 输出一个账号最近 days 天的每日结果
+func (s *ErrorStats) printAccountTrend(account string, days int) {
+	printSubHeader("账号 " + account)
+	history := s.Accounts[account]
+	fmt.Println("  " + ColorDim + "日期           成功   失败  失败率              主要原因" + ColorReset)
+	for offset := days - 1; offset >= 0; offset-- {
+		date := time.Now().AddDate(0, 0, -offset).Format("2006-01-02")
+		day := history[date]
+		if day == nil || day.Total() == 0 {
+			fmt.Printf("  "+ColorDim+"%-10s  %5s  %5s  %6s"+ColorReset+"\n", date, "-", "-", "-")
+			continue
+		}
+		rate := failureRate(*day)
+		fmt.Printf("  %-10s  %5d  %5d  %6s  %s  %s\n", date, day.Succeeded, day.Total()-day.Succeeded, formatFailureRate(rate), rateBar(rate), s.topFailures(day.Failed))
+	}
+}
+
+// rateShift 最近半段与之前半段的失败率，任一段没有记录时 ok 为 false
+func rateShift(history map[string]*DailyOutcomes, days int) (before, after int, ok bool) {
+	half := max(days/2, 1)
+	after = failureRate(windowOutcomes(history, 0, half))
+	before = failureRate(windowOutcomes(history, half, days))
+	return before, after, before >= 0 && after >= 0
+}
+
+// printTrendSummary 比较各账号前后两段的失败率，判断变化是账号自身的问题还是 Apple 侧的变化
+func (s *ErrorStats) printTrendSummary(current string, days int) {
+	printSubHeader("趋势")
+	accounts := make([]string, 0, len(s.Accounts))
+	for account := range s.Accounts {
+		accounts = append(accounts, account)
+	}
+	sort.Strings(accounts)
+
+	rising := map[string]bool{}
+	compared := 0
+	for _, account := range accounts {
+		before, after, ok := rateShift(s.Accounts[account], days)
+		if !ok {
+			continue
+		}
+		compared++
+		marker := ColorDim + "→" + ColorReset
+		switch {
+		case after-before >= errorRateShiftPoints:
+			marker = ColorRed + "↑" + ColorReset
+			rising[account] = true
+		case before-after >= errorRateShiftPoints:
+			marker = ColorGreen + "↓" + ColorReset
+		}
+		fmt.Printf("  %-16s 失败率 %s %s %s "+ColorDim+"(前 %d 天 → 最近 %d 天)"+ColorReset+"\n",
+			account, formatFailureRate(before), marker, formatFailureRate(after), days-max(days/2, 1), max(days/2, 1))
+	}
+	if compared == 0 {
+		printInfo("记录不足，无法比较前后两段的失败率")
+		return
+	}
+
+	fmt.Println()
+	switch {
+	case len(rising) == 0:
+		printInfo("各账号失败率没有明显上升")
+	case len(rising) == compared && compared > 1:
+		printWarning("所有账号的失败率同时上升，可能是 Apple 侧的变化（接口、限流策略），可关注是否需要更新请求参数")
+	case rising[current] && len(rising) == 1 && compared > 1:
+		printWarning("只有当前账号的失败率上升，更可能是账号自身的问题（Cookie 过期、被单独限流）")
+	case rising[current] && compared == 1:
+		printWarning("当前账号的失败率上升；配置多个账号时可对比判断是否为 Apple 侧的变化")
+	case rising[current]:
+		printWarning("当前账号与部分其他账号的失败率上升，请对比各账号的主要失败原因")
+	default:
+		printInfo("当前账号的失败率没有明显上升")
+	}
+}
+
+// runStats 查看每日创建成功/失败次数与失败率趋势：stats [-days 14] [-all]
+func runStats(config *Config, args []string) error {
+	fs := flag.NewFlagSet("stats", flag.ContinueOnError)
+	days := fs.Int("days", 14, "显示最近的天数")
+	all := fs.Bool("all", false, "显示所有账号的每日明细")
+	if err := fs.Parse(args); err != nil {
+		return usageError(err)
+	}
+	if *days <= 0 || *days > errorStatsRetentionDays {
+		return usageError(fmt.Errorf("-days 需在 1-%d 之间", errorStatsRetentionDays))
+	}
+
+	errorStatsMutex.Lock()
+	stats, err := loadErrorStats(config)
+	errorStatsMutex.Unlock()
+	if err != nil {
+		return err
+	}
+	if outputJSON {
+		writeJSON(stats)
+		return nil
+	}
+	showErrorStats(config, stats, *days, *all)
+	return nil
+}
+
+// showErrorStats 输出当前账号（或所有账号）的每日明细与趋势比较
+func showErrorStats(config *Config, stats *ErrorStats, days int, all bool) {
+	printHeader("创建统计")
+	if len(stats.Accounts) == 0 {
+		printInfo("暂无记录，创建邮箱后会按天统计成功与失败次数")
+		return
+	}
+	current := config.profileLabel()
+	if all {
+		accounts := make([]string, 0, len(stats.Accounts))
+		for account := range stats.Accounts {
+			accounts = append(accounts, account)
+		}
+		sort.Strings(accounts)
+		for _, account := range accounts {
+			stats.printAccountTrend(account, days)
+		}
+	} else {
+		stats.printAccountTrend(current, days)
+	}
+	stats.printTrendSummary(current, days)
+}
+
+// handleErrorStats 菜单中查看创建统计
+func handleErrorStats(config *Config) {
+	errorStatsMutex.Lock()
+	stats, err := loadErrorStats(config)
+	errorStatsMutex.Unlock()
+	if err != nil {
+		printError(err.Error())
+		return
+	}
+	showErrorStats(config, stats, 14, len(stats.Accounts) > 1)
+	readInput("\n" + ColorGray + "(回车返回)" + ColorReset + " ")
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"sync"
+	"time"
+)
+
+// 事件类型
+const (
+	EventEmailCreated     = "email.created"
+	EventEmailDeactivated = "email.deactivated"
+	EventEmailReactivated = "email.reactivated"
+	EventEmailDeleted     = "email.deleted"
+	EventBatchProgress    = "batch.progress"
+	EventBatchCompleted   = "batch.completed"
+)
+
+// Event 推送给订阅者的事件
+type Event struct {
+	ID        uint64      `json:"id"`
+	Type      string      `json:"type"`
+	Timestamp int64       `json:"timestamp"` // Unix 毫秒
+	Data      interface{} `json:"data"`
+}
+
+// EventHub 进程内事件总线，订阅者处理过慢时丢弃事件而不阻塞发布方
+type EventHub struct {
+	mutex       sync.Mutex
+	nextID      uint64
+	subscribers map[chan Event]struct{}
+}
+
+// NewEventHub 创建事件总线
+func NewEventHub() *EventHub {
+	return &EventHub{subscribers: make(map[chan Event]struct{})}
+}
+
+// Subscribe 订阅事件，返回事件通道和取消订阅函数
+func (h *EventHub) Subscribe() (<-chan Event, func()) {
+	ch := make(chan Event, 64)
+
+	h.mutex.Lock()
+	h.subscribers[ch] = struct{}{}
+	h.mutex.Unlock()
+
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			h.mutex.Lock()
+			delete(h.subscribers, ch)
+			h.mutex.Unlock()
+			close(ch)
+		})
+	}
+}
+
+// Publish 发布事件
+func (h *EventHub) Publish(eventType string, data interface{}) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	h.nextID++
+	event := Event{
+		ID:        h.nextID,
+		Type:      eventType,
+		Timestamp: time.Now().UnixMilli(),
+		Data:      data,
+	}
+
+	for ch := range h.subscribers {
+		select {
+		case ch <- event:
+		default:
+		}
+	}
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+
+	"icloud-hme-generator/pkg/hme"
+)
+
+// 进程退出码，供 shell 脚本与 systemd 区分不同类型的失败
+const (
+	ExitOK          = 0 // 成功
+	ExitFailure     = 1 // 其他错误
+	ExitUsage       = 2 // 命令或参数错误
+	ExitConfig      = 3 // 配置文件缺失、格式错误或缺少必要配置
+	ExitAuth        = 4 // 认证失败（Cookie 失效、启动口令错误）
+	ExitRateLimited = 5 // 被 Apple 限流
+	ExitPartial     = 6 // 批量操作部分失败
+	ExitLocked      = 7 // 已有实例在运行
+)
+
+// exitError 携带退出码的错误
+type exitError struct {
+	code int
+	err  error
+}
+
+func (e *exitError) Error() string { return e.err.Error() }
+func (e *exitError) Unwrap() error { return e.err }
+
+// withExitCode 为错误指定退出码
+func withExitCode(code int, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &exitError{code: code, err: err}
+}
+
+// usageError 命令或参数错误
+func usageError(err error) error {
+	return withExitCode(ExitUsage, err)
+}
+
+// configError 配置错误
+func configError(format string, args ...interface{}) error {
+	return withExitCode(ExitConfig, fmt.Errorf(format, args...))
+}
+
+// exitCodeFor 根据错误类型确定退出码
+func exitCodeFor(err error) int {
+	if err == nil {
+		return ExitOK
+	}
+
+	// -h 只是查看帮助
+	if errors.Is(err, flag.ErrHelp) {
+		return ExitOK
+	}
+	var coded *exitError
+	if errors.As(err, &coded) {
+		return coded.code
+	}
+
+	if _, limited := retryAfterFor(err); limited {
+		return ExitRateLimited
+	}
+	// Apple 在会话过期时返回 421 或 450
+	if errors.Is(err, hme.ErrSessionExpired) {
+		return ExitAuth
+	}
+	var status *APIStatusError
+	if errors.As(err, &status) {
+		switch status.StatusCode {
+		case http.StatusForbidden:
+			return ExitAuth
+		case http.StatusServiceUnavailable:
+			return ExitRateLimited
+		}
+	}
+	return ExitFailure
+}
@@ -0,0 +1,226 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// 支持的导出格式：bitwarden 与 1password 为两者的 CSV 导入格式
+var exportFormats = []string{"csv", "json", "bitwarden", "1password"}
+
+// ExportedAlias 导出的一个邮箱
+type ExportedAlias struct {
+	Address   string    `json:"address"`
+	Label     string    `json:"label"`
+	Note      string    `json:"note,omitempty"`
+	Status    string    `json:"status"` // active 或 inactive
+	CreatedAt time.Time `json:"created_at"`
+	ForwardTo string    `json:"forward_to,omitempty"`
+	Site      string    `json:"site,omitempty"` // 本地台账中记录的使用网站
+}
+
+// exportedAliases 按创建时间排列邮箱，并附上本地台账中的使用网站
+func exportedAliases(emails []HMEEmail, includeInactive bool) []ExportedAlias {
+	sorted := append([]HMEEmail(nil), emails...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].CreateTimestamp < sorted[j].CreateTimestamp })
+	aliases := make([]ExportedAlias, 0, len(sorted))
+	for _, email := range sorted {
+		if !email.IsActive && !includeInactive {
+			continue
+		}
+		alias := ExportedAlias{Address: email.HME, Label: email.Label, Note: email.Note, Status: "active",
+			CreatedAt: time.UnixMilli(email.CreateTimestamp), ForwardTo: email.ForwardToEmail}
+		if !email.IsActive {
+			alias.Status = "inactive"
+		}
+		if inventory != nil {
+			if record, ok := inventory.Get(email.HME); ok {
+				alias.Site = record.Site
+			}
+		}
+		aliases = append(aliases, alias)
+	}
+	return aliases
+}
+
+// siteURL 密码管理器需要完整网址，台账中只记录了域名时补上 https://
+func siteURL(site string) string {
+	if site == "" || strings.Contains(site, "://") {
+		return site
+	}
+	return "https://" + site
+}
+
+// passwordManagerTitle 条目名称：优先使用标签，没有标签时使用地址
+func passwordManagerTitle(alias ExportedAlias) string {
+	if strings.TrimSpace(alias.Label) != "" {
+		return alias.Label
+	}
+	return alias.Address
+}
+
+// passwordManagerNotes 条目备注：邮箱备注之后注明来源与状态
+func passwordManagerNotes(alias ExportedAlias) string {
+	notes := "iCloud 隐藏邮箱"
+	if alias.Status != "active" {
+		notes += "（已停用）"
+	}
+	if alias.Note != "" {
+		notes = alias.Note + "\n" + notes
+	}
+	return notes
+}
+
+// writeAliasExport 按格式写出邮箱列表
+func writeAliasExport(w io.Writer, aliases []ExportedAlias, format string) error {
+	if format == "json" {
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(aliases)
+	}
+
+	writer := csv.NewWriter(w)
+	switch format {
+	case "bitwarden":
+		writer.Write([]string{"folder", "favorite", "type", "name", "notes", "fields", "reprompt", "login_uri", "login_username", "login_password", "login_totp"})
+		for _, alias := range aliases {
+			writer.Write([]string{"", "", "login", passwordManagerTitle(alias), passwordManagerNotes(alias), "", "0", siteURL(alias.Site), alias.Address, "", ""})
+		}
+	case "1password":
+		writer.Write([]string{"Title", "Website", "Username", "Password", "Notes"})
+		for _, alias := range aliases {
+			writer.Write([]string{passwordManagerTitle(alias), siteURL(alias.Site), alias.Address, "", passwordManagerNotes(alias)})
+		}
+	default:
+		writer.Write([]string{"address", "label", "note", "status", "created_at", "forward_to", "site"})
+		for _, alias := range aliases {
+			writer.Write([]string{alias.Address, alias.Label, alias.Note, alias.Status, alias.CreatedAt.Format(time.RFC3339), alias.ForwardTo, alias.Site})
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// exportAliasesFile 把邮箱列表导出到文件。文件中含全部邮箱地址，只允许当前用户读取
+func exportAliasesFile(path string, aliases []ExportedAlias, format string) error {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return fmt.Errorf("创建导出文件失败: %v", err)
+	}
+	defer file.Close()
+	if err := writeAliasExport(file, aliases, format); err != nil {
+		return fmt.Errorf("写入导出文件失败: %v", err)
+	}
+	return nil
+}
+
+// defaultExportPath 默认导出文件名
+func defaultExportPath(format string) string {
+	ext := ".csv"
+	if format == "json" {
+		ext = ".json"
+	}
+	name := "hme-aliases"
+	if format == "bitwarden" || format == "1password" {
+		name += "-" + format
+	}
+	return name + "-" + time.Now().Format("20060102") + ext
+}
+
+// fetchExportedAliases 获取 iCloud 列表并转换为导出格式
+func fetchExportedAliases(config *Config, includeInactive bool) ([]ExportedAlias, error) {
+	var emails []HMEEmail
+	if err := withSpinner("获取邮箱列表", func() error {
+		var err error
+		emails, err = listHME(config)
+		return err
+	}); err != nil {
+		return nil, fmt.Errorf("获取列表失败: %w", err)
+	}
+	return exportedAliases(emails, includeInactive), nil
+}
+
+// runExport 导出全部邮箱：export [-format csv|json|bitwarden|1password] [-o 文件] [-active]
+func runExport(config *Config, args []string) error {
+	fs := flag.NewFlagSet("export", flag.ContinueOnError)
+	format := fs.String("format", "csv", "导出格式："+strings.Join(exportFormats, "、"))
+	output := fs.String("o", "", "导出文件（- 表示输出到标准输出，默认按格式生成文件名）")
+	activeOnly := fs.Bool("active", false, "只导出激活的邮箱")
+	if err := fs.Parse(args); err != nil {
+		return usageError(err)
+	}
+	if !containsFold(exportFormats, *format) {
+		return usageError(fmt.Errorf("-format 只支持 %s", strings.Join(exportFormats, "、")))
+	}
+	*format = strings.ToLower(*format)
+
+	aliases, err := fetchExportedAliases(config, !*activeOnly)
+	if err != nil {
+		return err
+	}
+	if *output == "-" {
+		return writeAliasExport(dataOutput, aliases, *format)
+	}
+	if *output == "" {
+		*output = defaultExportPath(*format)
+	}
+	if err := exportAliasesFile(*output, aliases, *format); err != nil {
+		return err
+	}
+	printSuccess(fmt.Sprintf("已导出 %d 个邮箱到 %s", len(aliases), *output))
+	printExportHint(*format)
+	return nil
+}
+
+// printExportHint 提示在密码管理器中导入的位置
+func printExportHint(format string) {
+	switch format {
+	case "bitwarden":
+		printInfo("在 Bitwarden 中选择 工具 → 导入数据 → 文件格式 Bitwarden (csv) 导入")
+	case "1password":
+		printInfo("在 1Password 中选择 文件 → 导入 → 其他 → CSV 导入")
+	}
+	printWarning("导出文件包含全部邮箱地址，导入后请删除")
+}
+
+// handleExport 菜单中导出邮箱列表
+func handleExport(config *Config) {
+	printHeader("导出邮箱列表")
+	fmt.Println("  " + ColorCyan + "[1]" + ColorReset + " CSV")
+	fmt.Println("  " + ColorCyan + "[2]" + ColorReset + " JSON")
+	fmt.Println("  " + ColorCyan + "[3]" + ColorReset + " Bitwarden " + ColorDim + "(CSV 导入格式)" + ColorReset)
+	fmt.Println("  " + ColorCyan + "[4]" + ColorReset + " 1Password " + ColorDim + "(CSV 导入格式)" + ColorReset)
+	choice := strings.TrimSpace(readInput("选择格式 " + ColorGray + "(回车返回)" + ColorReset + ": "))
+	index := 0
+	if _, err := fmt.Sscanf(choice, "%d", &index); err != nil || index < 1 || index > len(exportFormats) {
+		return
+	}
+	format := exportFormats[index-1]
+
+	inactive := strings.ToLower(strings.TrimSpace(readInput("同时导出已停用的邮箱？" + ColorGray + "(y/N)" + ColorReset + ": ")))
+	aliases, err := fetchExportedAliases(config, inactive == "y" || inactive == "yes")
+	if err != nil {
+		printError(err.Error())
+		readInput("\n" + ColorGray + "(回车返回)" + ColorReset + " ")
+		return
+	}
+	fallback := defaultExportPath(format)
+	path := strings.TrimSpace(readInput("导出文件 " + ColorGray + "(回车使用 " + fallback + ")" + ColorReset + ": "))
+	if path == "" {
+		path = fallback
+	}
+	if err := exportAliasesFile(path, aliases, format); err != nil {
+		printError(err.Error())
+	} else {
+		printSuccess(fmt.Sprintf("已导出 %d 个邮箱到 %s", len(aliases), path))
+		printExportHint(format)
+	}
+	readInput("\n" + ColorGray + "(回车返回)" + ColorReset + " ")
+}
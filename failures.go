@@ -0,0 +1,123 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"sort"
+	"strings"
+
+	"icloud-hme-generator/pkg/hme"
+)
+
+// failureClass 批量失败的归类
+type failureClass struct {
+	Key      string
+	Name     string // 用于汇总，如 "被限流"
+	Advice   string
+	Priority int // 数量相同时的排列顺序，越小越靠前
+}
+
+var (
+	failureAuth        = failureClass{"auth", "认证失败", "Cookie 可能已过期，请重新抓取 headers.Cookie，并确认 dsid、client_id 与抓包一致", 0}
+	failureRateLimited = failureClass{"rate_limited", "被限流", "iCloud 限制了创建频率，请提高 delay_seconds、设置 requests_per_minute 全局限速，或设置 batch_chunk_size / batch_chunk_pause_minutes 分段创建", 1}
+	failureNetwork     = failureClass{"network", "网络错误", "请检查网络连接与代理设置，稍后重试", 2}
+	failureServer      = failureClass{"server", "服务器错误", "Apple 服务暂时异常，稍后重试", 3}
+	failureUnavailable = failureClass{"hme_unavailable", "无法使用隐藏邮件地址", "确认账号已订阅 iCloud+，并在 icloud.com 的“隐藏邮件地址”中手动创建过一次；家庭共享成员需由组织者开启共享", 0}
+	failureNotFound    = failureClass{"alias_not_found", "邮箱不存在", "该邮箱可能已在其他设备上删除，重新获取列表后再操作", 4}
+	failureOther       = failureClass{"other", "其他错误", "请查看上方的详细错误信息", 5}
+)
+
+// classifyFailure 根据错误类型归类
+func classifyFailure(err error) failureClass {
+	if exitCodeFor(err) == ExitAuth {
+		return failureAuth
+	}
+	if _, limited := retryAfterFor(err); limited {
+		return failureRateLimited
+	}
+	switch hme.ErrorKind(err) {
+	case hme.ErrHMEUnavailable:
+		return failureUnavailable
+	case hme.ErrAliasNotFound:
+		return failureNotFound
+	}
+
+	var status *APIStatusError
+	if errors.As(err, &status) {
+		if status.StatusCode >= 500 {
+			return failureServer
+		}
+		return failureClass{fmt.Sprintf("http_%d", status.StatusCode), fmt.Sprintf("HTTP %d", status.StatusCode), "检查 base_url 与请求参数是否仍然有效", 4}
+	}
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return failureClass{"api_" + apiErr.ErrorCode, fmt.Sprintf("API 错误 %s", apiErr.ErrorCode), fmt.Sprintf("Apple 返回: %s", apiErr.ErrorMessage), 4}
+	}
+
+	var urlErr *url.Error
+	var netErr net.Error
+	if errors.As(err, &urlErr) || errors.As(err, &netErr) {
+		return failureNetwork
+	}
+	return failureOther
+}
+
+// failureGroup 同一原因的失败
+type failureGroup struct {
+	Class  failureClass
+	Count  int
+	Sample string // 第一个错误的内容
+}
+
+// groupFailures 按原因归类失败，数量多的在前
+func groupFailures(errs []error) []*failureGroup {
+	groups := make(map[string]*failureGroup)
+	var sorted []*failureGroup
+	for _, err := range errs {
+		class := classifyFailure(err)
+		g, ok := groups[class.Key]
+		if !ok {
+			g = &failureGroup{Class: class, Sample: err.Error()}
+			groups[class.Key] = g
+			sorted = append(sorted, g)
+		}
+		g.Count++
+	}
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if sorted[i].Count != sorted[j].Count {
+			return sorted[i].Count > sorted[j].Count
+		}
+		return sorted[i].Class.Priority < sorted[j].Class.Priority
+	})
+	return sorted
+}
+
+// failureAdvice 认证失败会导致后续全部失败，优先提示；否则针对数量最多的原因
+func failureAdvice(groups []*failureGroup) failureClass {
+	for _, g := range groups {
+		if g.Class.Key == failureAuth.Key {
+			return failureAuth
+		}
+	}
+	return groups[0].Class
+}
+
+// printFailureSummary 按失败原因汇总批量错误，并给出最相关的处理建议
+func printFailureSummary(errs []error) {
+	if len(errs) == 0 {
+		return
+	}
+
+	groups := groupFailures(errs)
+	parts := make([]string, len(groups))
+	for i, g := range groups {
+		parts[i] = fmt.Sprintf("%d 个%s", g.Count, g.Class.Name)
+	}
+	printError(fmt.Sprintf("失败 %d 个: %s", len(errs), strings.Join(parts, "、")))
+	for _, g := range groups {
+		fmt.Printf("    "+ColorDim+"%s 示例: %s"+ColorReset+"\n", g.Class.Name, g.Sample)
+	}
+	printInfo("建议: " + failureAdvice(groups).Advice)
+}
@@ -0,0 +1,240 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+	"time"
+)
+
+// 转发健康状态
+const (
+	ForwardHealthy      = "healthy"      // 来信节奏正常
+	ForwardSuspect      = "suspect"      // 沉默时间远超平时的来信间隔，疑似转发被暂停
+	ForwardQuiet        = "quiet"        // 观察期内没有来信
+	ForwardInsufficient = "insufficient" // 来信太少，无法判断
+	ForwardElsewhere    = "elsewhere"    // 转发到其他邮箱，IMAP 无法检查
+)
+
+// 判定疑似中断所需的最少来信数与最短沉默时间
+const (
+	forwardMinSamples = 3
+	forwardMinSilence = 48 * time.Hour
+)
+
+// ForwardHealth 单个邮箱的转发检查结果
+type ForwardHealth struct {
+	Email        HMEEmail
+	Count        int
+	LastReceived time.Time
+	TypicalGap   time.Duration // 相邻来信间隔的中位数
+	Silence      time.Duration // 距最近一封来信的时间
+	Status       string
+}
+
+// analyzeForwardHealth 根据来信时间判断转发是否可能已中断
+func analyzeForwardHealth(email HMEEmail, dates []time.Time, now time.Time, factor float64) ForwardHealth {
+	health := ForwardHealth{Email: email, Count: len(dates)}
+	if len(dates) == 0 {
+		health.Status = ForwardQuiet
+		return health
+	}
+
+	sort.Slice(dates, func(i, j int) bool { return dates[i].Before(dates[j]) })
+	health.LastReceived = dates[len(dates)-1]
+	health.Silence = now.Sub(health.LastReceived)
+	if len(dates) < forwardMinSamples {
+		health.Status = ForwardInsufficient
+		return health
+	}
+
+	gaps := make([]time.Duration, 0, len(dates)-1)
+	for i := 1; i < len(dates); i++ {
+		gaps = append(gaps, dates[i].Sub(dates[i-1]))
+	}
+	sort.Slice(gaps, func(i, j int) bool { return gaps[i] < gaps[j] })
+	health.TypicalGap = gaps[len(gaps)/2]
+
+	threshold := time.Duration(float64(health.TypicalGap) * factor)
+	if threshold < forwardMinSilence {
+		threshold = forwardMinSilence
+	}
+	if health.Silence > threshold {
+		health.Status = ForwardSuspect
+	} else {
+		health.Status = ForwardHealthy
+	}
+	return health
+}
+
+// checkForwarding 通过 IMAP 统计每个激活邮箱在观察期内的来信
+func checkForwarding(settings IMAPConfig, emails []HMEEmail, window time.Duration, factor float64) ([]ForwardHealth, error) {
+	timeout := 30 * time.Second
+	client, err := dialIMAP(settings, timeout)
+	if err != nil {
+		return nil, err
+	}
+	defer client.Close()
+
+	now := time.Now()
+	since := now.Add(-window)
+	var results []ForwardHealth
+	for _, email := range emails {
+		if !email.IsActive {
+			continue
+		}
+		if strings.Contains(settings.Username, "@") && email.ForwardToEmail != "" &&
+			!strings.EqualFold(email.ForwardToEmail, settings.Username) {
+			results = append(results, ForwardHealth{Email: email, Status: ForwardElsewhere})
+			continue
+		}
+
+		uids, err := client.SearchTo(email.HME, since, timeout)
+		if err != nil {
+			return results, fmt.Errorf("搜索 %s 的邮件失败: %v", email.HME, err)
+		}
+		datesByUID, err := client.FetchDates(uids, timeout)
+		if err != nil {
+			return results, fmt.Errorf("读取 %s 的邮件时间失败: %v", email.HME, err)
+		}
+		dates := make([]time.Time, 0, len(datesByUID))
+		for _, date := range datesByUID {
+			if !date.Before(since) {
+				dates = append(dates, date)
+			}
+		}
+		results = append(results, analyzeForwardHealth(email, dates, now, factor))
+	}
+
+	// 疑似中断的排在最前
+	sort.SliceStable(results, func(i, j int) bool {
+		return (results[i].Status == ForwardSuspect) && (results[j].Status != ForwardSuspect)
+	})
+	return results, nil
+}
+
+// formatSilence 以天/小时显示时长
+func formatSilence(d time.Duration) string {
+	if d >= 24*time.Hour {
+		return fmt.Sprintf("%d 天", int(d.Hours()/24))
+	}
+	return fmt.Sprintf("%d 小时", int(d.Hours()))
+}
+
+// printForwardHealth 输出检查结果，showAll 为 false 时只显示疑似中断的邮箱
+func printForwardHealth(results []ForwardHealth, showAll bool) int {
+	counts := make(map[string]int)
+	for _, result := range results {
+		counts[result.Status]++
+	}
+	fmt.Printf("  "+ColorGreen+"正常"+ColorReset+" %d "+ColorDim+"|"+ColorReset+" "+ColorRed+"疑似中断"+ColorReset+" %d "+ColorDim+"|"+ColorReset+" 无来信 %d "+ColorDim+"|"+ColorReset+" 数据不足 %d "+ColorDim+"|"+ColorReset+" 转发到其他邮箱 %d\n\n",
+		counts[ForwardHealthy], counts[ForwardSuspect], counts[ForwardQuiet], counts[ForwardInsufficient], counts[ForwardElsewhere])
+
+	for _, result := range results {
+		label := result.Email.Label
+		if label == "" {
+			label = "(无标签)"
+		}
+		switch result.Status {
+		case ForwardSuspect:
+			fmt.Printf("  "+ColorRed+"!"+ColorReset+" %s "+ColorCyan+"%s"+ColorReset+"\n", result.Email.HME, label)
+			fmt.Printf("    "+ColorYellow+"已 %s 没有来信，平时约每 %s 一封（观察期内 %d 封）"+ColorReset+"\n",
+				formatSilence(result.Silence), formatSilence(result.TypicalGap), result.Count)
+		case ForwardHealthy, ForwardInsufficient:
+			if showAll {
+				fmt.Printf("  "+ColorGreen+"✓"+ColorReset+" %s "+ColorDim+"%s | %d 封 | 最近来信 %s前"+ColorReset+"\n",
+					result.Email.HME, label, result.Count, formatSilence(result.Silence))
+			}
+		case ForwardQuiet:
+			if showAll {
+				fmt.Printf("  "+ColorDim+"- %s %s | 观察期内无来信"+ColorReset+"\n", result.Email.HME, label)
+			}
+		case ForwardElsewhere:
+			if showAll {
+				fmt.Printf("  "+ColorDim+"- %s %s | 转发到 %s，无法检查"+ColorReset+"\n", result.Email.HME, label, result.Email.ForwardToEmail)
+			}
+		}
+	}
+	return counts[ForwardSuspect]
+}
+
+// runForwardCheck 检查转发是否被 Apple 静默暂停
+func runForwardCheck(config *Config, args []string) error {
+	fs := flag.NewFlagSet("forward-check", flag.ContinueOnError)
+	days := fs.Int("days", 90, "统计最近多少天的来信")
+	factor := fs.Float64("factor", 3, "沉默时间超过平时来信间隔的多少倍视为疑似中断")
+	every := fs.Int("every", 0, "每隔多少分钟重复检查（0 表示只检查一次）")
+	showAll := fs.Bool("all", false, "显示所有邮箱的检查结果")
+	if err := fs.Parse(args); err != nil {
+		return usageError(err)
+	}
+	if !config.IMAP.Enabled() {
+		return configError("未配置 IMAP，请在 config.json 的 imap 中填写服务器与账号")
+	}
+	if *days <= 0 || *factor <= 1 {
+		return fmt.Errorf("-days 必须大于 0，-factor 必须大于 1")
+	}
+
+	alerted := make(map[string]bool)
+	for {
+		if err := forwardCheckRound(getCurrentConfig(), time.Duration(*days)*24*time.Hour, *factor, *showAll, alerted); err != nil {
+			// 定时模式下网络波动等临时错误只记录，下一轮再检查；会话失效需要重新导入 Cookie，继续重试没有意义
+			if *every <= 0 || exitCodeFor(err) == ExitAuth || errors.Is(err, context.Canceled) {
+				return err
+			}
+			printWarning(fmt.Sprintf("%s 本轮检查失败，%d 分钟后重试: %v", time.Now().Format("15:04"), *every, err))
+			logFailure(slog.LevelWarn, "转发检查失败", err)
+		}
+
+		if *every <= 0 {
+			return nil
+		}
+		select {
+		case <-time.After(time.Duration(*every) * time.Minute):
+		case <-safetyManager.Context().Done():
+			return nil
+		}
+	}
+}
+
+// forwardCheckRound 获取邮箱列表并检查一轮，输出结果
+func forwardCheckRound(config *Config, window time.Duration, factor float64, showAll bool, alerted map[string]bool) error {
+	emails, err := listHME(config)
+	if err != nil {
+		return fmt.Errorf("获取列表失败: %w", err)
+	}
+	results, err := checkForwarding(config.IMAP, emails, window, factor)
+	if err != nil {
+		return err
+	}
+	reportForwardHealth(results, showAll, alerted)
+	return nil
+}
+
+// reportForwardHealth 输出一轮检查的结果；alerted 记录已提醒过的邮箱，只对新出现的疑似中断发出提醒
+func reportForwardHealth(results []ForwardHealth, showAll bool, alerted map[string]bool) {
+	printHeader(fmt.Sprintf("转发检查 (%s)", time.Now().Format("2006-01-02 15:04")))
+	suspect := printForwardHealth(results, showAll)
+	if suspect == 0 {
+		printSuccess("没有发现疑似中断的转发")
+	}
+
+	// 定时模式下只对新出现的疑似中断发出提醒
+	newAlerts := 0
+	for _, result := range results {
+		if result.Status == ForwardSuspect && !alerted[result.Email.AnonymousID] {
+			alerted[result.Email.AnonymousID] = true
+			newAlerts++
+		} else if result.Status != ForwardSuspect {
+			delete(alerted, result.Email.AnonymousID)
+		}
+	}
+	if newAlerts > 0 {
+		fmt.Print("\a")
+		printWarning(fmt.Sprintf("%d 个邮箱疑似被暂停转发，请在 iCloud 设置中检查“转发至”状态", newAlerts))
+	}
+}
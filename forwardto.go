@@ -0,0 +1,144 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// CLIForwardTo forward-to 子命令的 JSON 输出
+type CLIForwardTo struct {
+	Selected  string   `json:"selected"`
+	Available []string `json:"available"`
+}
+
+// resolveForwardTo 按序号（从 1 开始）或地址（忽略大小写）在可选转发地址中查找
+func resolveForwardTo(available []string, input string) (string, error) {
+	input = strings.TrimSpace(input)
+	if index, err := strconv.Atoi(input); err == nil {
+		if index < 1 || index > len(available) {
+			return "", fmt.Errorf("无效的序号: %d（共 %d 个地址）", index, len(available))
+		}
+		return available[index-1], nil
+	}
+	for _, address := range available {
+		if strings.EqualFold(address, input) {
+			return address, nil
+		}
+	}
+	return "", fmt.Errorf("%s 不是可选的转发地址（可选: %s），请先在 Apple ID 中添加并验证该邮箱", input, strings.Join(available, "、"))
+}
+
+// printForwardToOptions 列出可选的转发地址，标记当前选中的地址
+func printForwardToOptions(available []string, selected string) {
+	for i, address := range available {
+		marker := " "
+		if strings.EqualFold(address, selected) {
+			marker = ColorGreen + "●" + ColorReset
+		}
+		fmt.Printf("  %s "+ColorCyan+"[%d]"+ColorReset+" %s\n", marker, i+1, address)
+	}
+}
+
+// handleForwardTo 菜单中查看并切换隐藏邮箱的转发地址
+func handleForwardTo(config *Config) {
+	printHeader("转发地址")
+	var available []string
+	var selected string
+	for {
+		err := withSpinner("获取转发地址", func() error {
+			var err error
+			available, selected, err = forwardToHME(config)
+			return err
+		})
+		if err == nil {
+			break
+		}
+		if !reportAPIError(config, "获取转发地址", err) {
+			return
+		}
+	}
+	if len(available) == 0 {
+		printInfo("账号没有可选的转发地址")
+		return
+	}
+
+	printForwardToOptions(available, selected)
+	fmt.Println()
+	printInfo("转发地址对所有隐藏邮箱生效；新地址需先在 Apple ID 中添加并验证")
+	input := readInput("切换到序号 " + ColorGray + "(回车返回)" + ColorReset + ": ")
+	if input == "" {
+		return
+	}
+	address, err := resolveForwardTo(available, input)
+	if err != nil {
+		printError(err.Error())
+		return
+	}
+	if strings.EqualFold(address, selected) {
+		printInfo(fmt.Sprintf("%s 已是当前转发地址", address))
+		return
+	}
+	if !confirmAction(fmt.Sprintf("确认将转发地址从 %s 改为 %s", selected, address)) {
+		printInfo("已取消")
+		return
+	}
+	for {
+		err := withSpinner("修改转发地址", func() error {
+			return updateForwardToHME(config, address)
+		})
+		if err == nil {
+			break
+		}
+		if !reportAPIError(config, "修改转发地址", err) {
+			return
+		}
+	}
+	printSuccess(fmt.Sprintf("转发地址已改为 %s", address))
+}
+
+// runForwardToCommand 查看或修改转发地址：forward-to [地址或序号]
+func runForwardToCommand(config *Config, args []string) error {
+	fs := flag.NewFlagSet("forward-to", flag.ContinueOnError)
+	if err := fs.Parse(args); err != nil {
+		return usageError(err)
+	}
+	if fs.NArg() > 1 {
+		return usageError(fmt.Errorf("用法: forward-to [地址或序号]"))
+	}
+
+	var available []string
+	var selected string
+	if err := withSpinner("获取转发地址", func() error {
+		var err error
+		available, selected, err = forwardToHME(config)
+		return err
+	}); err != nil {
+		return fmt.Errorf("获取转发地址失败: %w", err)
+	}
+
+	if fs.NArg() == 1 {
+		address, err := resolveForwardTo(available, fs.Arg(0))
+		if err != nil {
+			return usageError(err)
+		}
+		if strings.EqualFold(address, selected) {
+			printInfo(fmt.Sprintf("%s 已是当前转发地址", address))
+		} else {
+			if err := updateForwardToHME(config, address); err != nil {
+				return fmt.Errorf("修改转发地址失败: %w", err)
+			}
+			printSuccess(fmt.Sprintf("转发地址已改为 %s", address))
+			selected = address
+		}
+	}
+
+	if outputJSON {
+		return writeJSON(CLIForwardTo{Selected: selected, Available: available})
+	}
+	if fs.NArg() == 0 {
+		printForwardToOptions(available, selected)
+	}
+	return nil
+}
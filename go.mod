@@ -2,6 +2,13 @@ module icloud-hme-generator
 
 go 1.24.3
 
-require github.com/fsnotify/fsnotify v1.9.0
+require (
+	github.com/fsnotify/fsnotify v1.9.0
+	go.etcd.io/bbolt v1.4.3
+	golang.org/x/crypto v0.40.0
+	gopkg.in/yaml.v3 v3.0.1
+)
 
-require golang.org/x/sys v0.13.0 // indirect
+require golang.org/x/sys v0.34.0
+
+require github.com/pelletier/go-toml/v2 v2.2.4
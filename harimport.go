@@ -0,0 +1,166 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// harFile 浏览器导出的 HAR（HTTP Archive）文件中用到的部分
+type harFile struct {
+	Log struct {
+		Entries []harEntry `json:"entries"`
+	} `json:"log"`
+}
+
+// harEntry HAR 中的一次请求
+type harEntry struct {
+	StartedDateTime string `json:"startedDateTime"`
+	Request         struct {
+		Method  string       `json:"method"`
+		URL     string       `json:"url"`
+		Headers []harNameVal `json:"headers"`
+		Cookies []harNameVal `json:"cookies"`
+	} `json:"request"`
+	Response struct {
+		Status int `json:"status"`
+	} `json:"response"`
+}
+
+// harNameVal HAR 中的请求头或 Cookie
+type harNameVal struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// isHMERequest 是否为发往隐藏邮箱接口（pXX-maildomainws.icloud.com/v1/hme/...、/v2/hme/list 等）的请求
+func isHMERequest(rawURL string) bool {
+	parsed, err := url.Parse(rawURL)
+	return err == nil && strings.Contains(parsed.Host, "maildomainws") && strings.Contains(parsed.Path, "/hme/")
+}
+
+// harEntryCookie 请求中的 Cookie：优先使用 Cookie 请求头，没有时由 cookies 列表拼接
+func harEntryCookie(entry harEntry) string {
+	for _, header := range entry.Request.Headers {
+		if strings.EqualFold(header.Name, "Cookie") && header.Value != "" {
+			return header.Value
+		}
+	}
+	var pairs []string
+	for _, cookie := range entry.Request.Cookies {
+		pairs = append(pairs, cookie.Name+"="+cookie.Value)
+	}
+	return strings.Join(pairs, "; ")
+}
+
+// parseHARFile 从 HAR 中找出隐藏邮箱接口的请求，取最后一个成功且带 Cookie 的请求作为账号配置（会话最新）
+func parseHARFile(data []byte) (*curlRequest, error) {
+	var har harFile
+	if err := json.Unmarshal(data, &har); err != nil {
+		return nil, fmt.Errorf("无法解析 HAR 文件: %v", err)
+	}
+	if len(har.Log.Entries) == 0 {
+		return nil, fmt.Errorf("HAR 文件中没有请求记录")
+	}
+
+	var found, withoutCookie int
+	var chosen *harEntry
+	for i := range har.Log.Entries {
+		entry := &har.Log.Entries[i]
+		if !isHMERequest(entry.Request.URL) {
+			continue
+		}
+		found++
+		if harEntryCookie(*entry) == "" {
+			withoutCookie++
+			continue
+		}
+		// 失败的请求（如 Cookie 已过期时的 421）只在没有成功请求时使用
+		if chosen == nil || entry.Response.Status < 400 || chosen.Response.Status >= 400 {
+			chosen = entry
+		}
+	}
+	switch {
+	case found == 0:
+		return nil, fmt.Errorf("HAR 文件中没有隐藏邮箱接口的请求，请在 icloud.com 打开“隐藏邮件地址”页面后再导出")
+	case chosen == nil:
+		return nil, fmt.Errorf("%d 个隐藏邮箱接口请求都不含 Cookie：浏览器默认导出的 HAR 会去掉 Cookie，请选择“导出 HAR（含敏感数据）”", withoutCookie)
+	}
+
+	request := &curlRequest{Headers: make(map[string]string)}
+	for _, header := range chosen.Request.Headers {
+		name := http.CanonicalHeaderKey(strings.TrimSpace(header.Name))
+		// HTTP/2 的伪请求头（:authority 等）不是普通请求头
+		if name == "" || strings.HasPrefix(name, ":") || curlSkippedHeaders[name] {
+			continue
+		}
+		request.Headers[name] = header.Value
+	}
+	request.Headers["Cookie"] = harEntryCookie(*chosen)
+	if err := request.setURL(chosen.Request.URL); err != nil {
+		return nil, err
+	}
+	return request, nil
+}
+
+// readHARFile 读取并解析 HAR 文件；路径两侧的引号与拖放到终端时产生的转义空格会被去掉
+func readHARFile(path string) (*curlRequest, error) {
+	path = strings.TrimSpace(path)
+	if len(path) >= 2 && (path[0] == '"' || path[0] == '\'') && path[len(path)-1] == path[0] {
+		path = path[1 : len(path)-1]
+	}
+	path = strings.ReplaceAll(path, `\ `, " ")
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("读取 %s 失败: %v", path, err)
+	}
+	return parseHARFile(data)
+}
+
+// runImportHAR 从 HAR 文件更新账号配置：import-har [-dry-run] [-no-verify] 文件；使用 --profile 时写入该账号
+func runImportHAR(config *Config, args []string) error {
+	fs := flag.NewFlagSet("import-har", flag.ContinueOnError)
+	dryRun := fs.Bool("dry-run", false, "只显示将要修改的字段，不保存")
+	noVerify := fs.Bool("no-verify", false, "保存前不验证账号")
+	if err := fs.Parse(args); err != nil {
+		return usageError(err)
+	}
+	if fs.NArg() != 1 {
+		return usageError(fmt.Errorf("用法: import-har [-dry-run] [-no-verify] 文件.har"))
+	}
+	request, err := readHARFile(fs.Arg(0))
+	if err != nil {
+		return usageError(err)
+	}
+	return importAccount(config, request, "从 HAR 文件导入账号", *dryRun, *noVerify)
+}
+
+// printHARGuide 说明如何在浏览器中导出 HAR 文件
+func printHARGuide() {
+	fmt.Println("  1. 在浏览器中登录 icloud.com，打开“隐藏邮件地址”页面")
+	fmt.Println("  2. 按 F12（Safari 为 ⌥⌘I）打开开发者工具，切换到“网络”面板后刷新页面")
+	fmt.Println("  3. 在请求列表上右键，选择“导出 HAR（含敏感数据）”或“全部另存为 HAR”")
+	fmt.Println("  4. 把保存的 .har 文件拖到这里（或输入路径）后回车")
+	fmt.Println()
+}
+
+// handleImportHAR 程序设置中的“从 HAR 文件更新账号”
+func handleImportHAR(config *Config) {
+	printHeader("从 HAR 文件更新账号")
+	printHARGuide()
+	path := readInput("HAR 文件路径: ")
+	if path == "" {
+		return
+	}
+	request, err := readHARFile(path)
+	if err != nil {
+		printError(err.Error())
+		return
+	}
+	printSuccess(fmt.Sprintf("已读取 %s 与 %d 个请求头", request.BaseURL, len(request.Headers)))
+	applyImportedAccount(config, request)
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// runHistory 搜索本地清单（包含已彻底删除的邮箱）
+func runHistory(args []string) error {
+	fs := flag.NewFlagSet("history", flag.ContinueOnError)
+	deletedOnly := fs.Bool("deleted", false, "只显示已彻底删除的邮箱")
+	if err := fs.Parse(args); err != nil {
+		return usageError(err)
+	}
+	if inventory == nil {
+		return fmt.Errorf("本地清单不可用")
+	}
+
+	keyword := strings.Join(fs.Args(), " ")
+	records := inventory.Search(keyword)
+
+	printHeader("邮箱历史记录")
+	if keyword != "" {
+		fmt.Printf("  "+ColorCyan+"关键字:"+ColorReset+" %s\n\n", keyword)
+	}
+
+	shown := 0
+	for _, record := range records {
+		if *deletedOnly && !record.IsTombstone() {
+			continue
+		}
+		shown++
+		printInventoryRecord("", record)
+	}
+
+	if shown == 0 {
+		printInfo("没有匹配的记录")
+		return nil
+	}
+	fmt.Println()
+	printInfo(fmt.Sprintf("共 %d 条记录", shown))
+	return nil
+}
+
+// printInventoryRecord 输出一条本地记录，prefix 为行首的序号等（可为空）
+func printInventoryRecord(prefix string, record InventoryRecord) {
+	status := ColorBrightGreen + "●" + ColorReset
+	if record.IsTombstone() {
+		status = ColorRed + "✕" + ColorReset
+	}
+	label := record.Label
+	if label == "" {
+		label = "(无标签)"
+	}
+
+	fmt.Printf("  %s%s %s "+ColorCyan+"%s"+ColorReset+"\n", prefix, status, record.HME, label)
+	fmt.Printf("    "+ColorDim+"创建 %s | 来源 %s"+ColorReset, formatMillis(record.CreatedAt), formatOrigin(record.Origin()))
+	if record.Score > 0 {
+		fmt.Printf(ColorDim+" | 评分 %d"+ColorReset, record.Score)
+	}
+	if record.IsTombstone() {
+		fmt.Printf(ColorDim+" | "+ColorReset+ColorRed+"删除 %s"+ColorReset, formatMillis(record.DeletedAt))
+	}
+	fmt.Println()
+	if record.Site != "" || len(record.Tags) > 0 {
+		fmt.Printf("    "+ColorDim+"网站: %s | 标记: %s"+ColorReset+"\n", orDash(record.Site), orDash(strings.Join(record.Tags, ", ")))
+	}
+	if record.Note != "" {
+		fmt.Printf("    "+ColorDim+"备注: %s"+ColorReset+"\n", record.Note)
+	}
+}
+
+// orDash 空值显示为 -
+func orDash(value string) string {
+	if value == "" {
+		return "-"
+	}
+	return value
+}
+
+// formatMillis 格式化 Unix 毫秒时间
+func formatMillis(ms int64) string {
+	if ms <= 0 {
+		return "-"
+	}
+	return time.UnixMilli(ms).Format("2006-01-02 15:04")
+}
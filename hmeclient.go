@@ -0,0 +1,151 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"sync"
+
+	"icloud-hme-generator/pkg/hme"
+)
+
+// 接口类型来自 pkg/hme，这里保留原名以便各命令沿用
+type (
+	HMEEmail       = hme.Email
+	APIError       = hme.APIError
+	APIStatusError = hme.StatusError
+)
+
+// hmeClient 按当前配置构建接口客户端；HTTP 连接池随配置复用
+func (c *Config) hmeClient() *hme.Client {
+	return &hme.Client{
+		BaseURL:               c.BaseURL,
+		ClientBuildNumber:     c.ClientBuildNumber,
+		ClientMasteringNumber: c.ClientMasteringNumber,
+		ClientID:              c.ClientID,
+		DSID:                  c.DSID,
+		Headers:               c.requestHeaders(),
+		LangCode:              c.resolveLangCode(""),
+		HTTPClient:            c.httpClient(),
+		PrepareRequest:        c.applyUserAgent,
+		Limiter:               c.sharedLimiter(),
+		Retry:                 c.RetryPolicy.hmePolicy(),
+		RefreshSession:        c.sessionRefresher(),
+	}
+}
+
+// apiContext 接口请求使用的上下文：收到退出信号后取消，进行中的请求与重试等待立即返回
+func apiContext() context.Context {
+	if safetyManager == nil {
+		return context.Background()
+	}
+	return safetyManager.Context()
+}
+
+// operationCanceled 是否已收到退出信号，批量循环据此停止开始新的创建
+func operationCanceled() bool {
+	return apiContext().Err() != nil
+}
+
+// applyUserAgent 在配置的请求头之上应用 User-Agent 预设或 user_agent
+func (c *Config) applyUserAgent(req *http.Request) {
+	if applyUserAgentPreset(req.Header, c.UserAgentPreset) {
+		return
+	}
+	if c.UserAgent != "" && req.Header.Get("User-Agent") == "" {
+		req.Header.Set("User-Agent", c.UserAgent)
+	}
+}
+
+// 第1步：生成邮箱地址
+func generateHME(config *Config) (string, error) {
+	return generateHMEWithLang(config, "")
+}
+
+// generateHMEWithLang 按指定语言生成邮箱地址，lang 为空时使用配置（影响 Apple 生成前缀所用的单词）
+func generateHMEWithLang(config *Config, lang string) (string, error) {
+	address, err := config.hmeClient().Generate(apiContext(), config.resolveLangCode(lang))
+	// 生成失败只影响冷却，创建结果只在 reserveHME 与 createHMEWithLang 中统计
+	if err != nil {
+		trackCooldown(config, err)
+	}
+	return address, err
+}
+
+// createdAnonymousIDs 刚创建的邮箱地址 → anonymousId，saveEmailToFile 记录本地清单时取出
+var createdAnonymousIDs sync.Map
+
+// 第2步：确认创建邮箱（设置 label）
+func reserveHME(config *Config, address string, label string) (string, error) {
+	email, err := config.hmeClient().Reserve(apiContext(), address, label, "")
+	trackCooldown(config, err)
+	recordCreateOutcome(config, err)
+	if err != nil {
+		return "", err
+	}
+	createdAnonymousIDs.Store(email.HME, email.AnonymousID)
+	// 返回实际的邮箱地址 - 注意是 result.hme.hme
+	return email.HME, nil
+}
+
+// 创建隐藏邮件地址（完整流程：生成 + 确认）
+func createHME(config *Config, label string) (string, error) {
+	return createHMEWithLang(config, label, "")
+}
+
+// createHMEWithLang 按指定语言创建邮箱，lang 为空时使用配置
+func createHMEWithLang(config *Config, label, lang string) (string, error) {
+	email, err := config.hmeClient().Create(apiContext(), label, config.resolveLangCode(lang))
+	trackCooldown(config, err)
+	recordCreateOutcome(config, err)
+	if err != nil {
+		logFailure(slog.LevelWarn, "创建邮箱失败", err, "label", label)
+		return "", err
+	}
+	createdAnonymousIDs.Store(email.HME, email.AnonymousID)
+	logger().Info("已创建邮箱", "email", email.HME, "label", label)
+	return email.HME, nil
+}
+
+// 获取邮箱列表
+func listHME(config *Config) ([]HMEEmail, error) {
+	list, err := config.hmeClient().List(apiContext())
+	if err != nil {
+		return nil, err
+	}
+	return list.Emails, nil
+}
+
+// 删除邮箱（停用）
+func deactivateHME(config *Config, anonymousID string) error {
+	return config.hmeClient().Deactivate(apiContext(), anonymousID)
+}
+
+// 彻底删除邮箱（不可恢复）
+func permanentDeleteHME(config *Config, anonymousID string) error {
+	return config.hmeClient().Delete(apiContext(), anonymousID)
+}
+
+// 重新激活邮箱
+func reactivateHME(config *Config, anonymousID string) error {
+	return config.hmeClient().Reactivate(apiContext(), anonymousID)
+}
+
+// 更新邮箱标签和备注
+func updateMetaDataHME(config *Config, anonymousID, label, note string) error {
+	return config.hmeClient().UpdateMetaData(apiContext(), anonymousID, label, note)
+}
+
+// 获取可选的转发地址及当前选中的地址
+func forwardToHME(config *Config) ([]string, string, error) {
+	list, err := config.hmeClient().List(apiContext())
+	if err != nil {
+		return nil, "", err
+	}
+	return list.ForwardToEmails, list.SelectedForwardTo, nil
+}
+
+// 修改转发地址
+func updateForwardToHME(config *Config, address string) error {
+	return config.hmeClient().UpdateForwardTo(apiContext(), address)
+}
@@ -0,0 +1,272 @@
+package main
+
+import (
+	"bytes"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptrace"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 调试日志中请求头的值整个隐去的请求头（Cookie、Set-Cookie 只隐去值，保留名称）
+var sensitiveDumpHeaders = []string{"Authorization", "Proxy-Authorization", "X-Apple-Session-Token", "X-Apple-Id-Session-Id", "Scnt"}
+
+// 调试日志中 JSON 请求体与响应体里隐去的字段（不区分大小写）：邮箱地址、转发地址、标签与备注，
+// 以及 validate 响应 dsInfo 中的 Apple ID、姓名等个人信息。邮箱地址保留首尾两个字符，其余整个隐去
+var sensitiveDumpFields = []string{
+	"hme", "forwardToEmail", "forwardToEmails", "label", "note",
+	"appleId", "appleIdAlias", "appleIdAliases", "primaryEmail", "fullName", "firstName", "lastName",
+	"iCloudAppleIdAlias", "appleIdEntries", "notificationId",
+}
+
+// httpTiming 一次请求各阶段的耗时，开启 http_dump 时通过 httptrace 采集
+type httpTiming struct {
+	mutex                                   sync.Mutex
+	dnsStart, connectStart, tlsStart, wrote time.Time
+	DNS, Connect, TLS, FirstByte            time.Duration
+	Reused                                  bool
+}
+
+// attach 把计时回调挂到 trace 上，保留已有的 GotConn 回调
+func (t *httpTiming) attach(trace *httptrace.ClientTrace) {
+	gotConn := trace.GotConn
+	trace.GotConn = func(info httptrace.GotConnInfo) {
+		t.mutex.Lock()
+		t.Reused = info.Reused
+		t.mutex.Unlock()
+		if gotConn != nil {
+			gotConn(info)
+		}
+	}
+	trace.DNSStart = func(httptrace.DNSStartInfo) { t.mark(&t.dnsStart) }
+	trace.DNSDone = func(httptrace.DNSDoneInfo) { t.since(t.dnsStart, &t.DNS) }
+	trace.ConnectStart = func(string, string) { t.mark(&t.connectStart) }
+	trace.ConnectDone = func(string, string, error) { t.since(t.connectStart, &t.Connect) }
+	trace.TLSHandshakeStart = func() { t.mark(&t.tlsStart) }
+	trace.TLSHandshakeDone = func(tls.ConnectionState, error) { t.since(t.tlsStart, &t.TLS) }
+	trace.WroteRequest = func(httptrace.WroteRequestInfo) { t.mark(&t.wrote) }
+	trace.GotFirstResponseByte = func() { t.since(t.wrote, &t.FirstByte) }
+}
+
+func (t *httpTiming) mark(at *time.Time) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	*at = time.Now()
+}
+
+func (t *httpTiming) since(start time.Time, into *time.Duration) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	if !start.IsZero() {
+		*into = time.Since(start)
+	}
+}
+
+// String 各阶段耗时，未经历的阶段（如复用连接时的 DNS、TLS）省略
+func (t *httpTiming) String() string {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	var parts []string
+	for _, phase := range []struct {
+		name  string
+		value time.Duration
+	}{{"dns", t.DNS}, {"connect", t.Connect}, {"tls", t.TLS}, {"首字节", t.FirstByte}} {
+		if phase.value > 0 {
+			parts = append(parts, fmt.Sprintf("%s %s", phase.name, phase.value.Round(time.Millisecond)))
+		}
+	}
+	if t.Reused {
+		parts = append(parts, "复用连接")
+	}
+	return strings.Join(parts, "，")
+}
+
+// HTTPExchange 一次完整的接口请求与响应
+type HTTPExchange struct {
+	At           time.Time
+	Request      *http.Request
+	RequestBody  []byte
+	Response     *http.Response // 网络错误时为 nil
+	ResponseBody []byte
+	Elapsed      time.Duration
+	Timing       *httpTiming
+	Err          error
+}
+
+// HTTPDumper 开启 http_dump 时把每个接口请求的完整内容写入调试日志，每次启动程序新建一个文件。
+// 写入前隐去 Cookie、dsid、邮箱地址、标签备注与 Apple ID 等账号信息，附到 issue 前仍建议自己检查一遍
+type HTTPDumper struct {
+	mutex sync.Mutex
+	file  *os.File
+	path  string
+}
+
+var httpDumper = &HTTPDumper{}
+
+// Dump 追加一次请求与响应，首次调用时创建调试日志
+func (d *HTTPDumper) Dump(config *Config, exchange HTTPExchange) error {
+	var buf bytes.Buffer
+	writeHTTPExchange(&buf, exchange)
+
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	if d.file == nil {
+		dir := config.developerSessionDir()
+		if err := os.MkdirAll(dir, 0700); err != nil {
+			return err
+		}
+		d.path = filepath.Join(dir, "http-dump-"+time.Now().Format("20060102-150405")+".log")
+		file, err := os.OpenFile(d.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+		if err != nil {
+			return err
+		}
+		d.file = file
+	}
+	_, err := d.file.Write(buf.Bytes())
+	return err
+}
+
+// Path 当前调试日志，尚未写入时为空
+func (d *HTTPDumper) Path() string {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	return d.path
+}
+
+// writeHTTPExchange 按 curl -v 的习惯格式化：> 为请求，< 为响应
+func writeHTTPExchange(buf *bytes.Buffer, exchange HTTPExchange) {
+	req := exchange.Request
+	fmt.Fprintf(buf, "==== %s %s %s (%s) ====\n", exchange.At.Format("2006-01-02 15:04:05.000"), req.Method, req.URL.Path, exchange.Elapsed.Round(time.Millisecond))
+	if timing := exchange.Timing.String(); timing != "" {
+		fmt.Fprintf(buf, "* %s\n", timing)
+	}
+	fmt.Fprintf(buf, "> %s %s\n", req.Method, redactLogText(req.URL.Redacted()))
+	writeDumpHeaders(buf, "> ", req.Header)
+	writeDumpBody(buf, "> ", exchange.RequestBody, "")
+
+	if exchange.Err != nil {
+		fmt.Fprintf(buf, "* 请求失败: %s\n\n", redactLogText(exchange.Err.Error()))
+		return
+	}
+	resp := exchange.Response
+	fmt.Fprintf(buf, "< %s %s\n", resp.Proto, resp.Status)
+	writeDumpHeaders(buf, "< ", resp.Header)
+	writeDumpBody(buf, "< ", exchange.ResponseBody, resp.Header.Get("Content-Encoding"))
+	buf.WriteString("\n")
+}
+
+// writeDumpHeaders 按名称排序输出请求头；Cookie 只保留名称，便于确认抓包时是否漏掉了某个 Cookie
+func writeDumpHeaders(buf *bytes.Buffer, prefix string, header http.Header) {
+	names := make([]string, 0, len(header))
+	for name := range header {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		for _, value := range header[name] {
+			switch {
+			case strings.EqualFold(name, "Cookie"):
+				value = redactCookies(value)
+			case strings.EqualFold(name, "Set-Cookie"):
+				cookie, attributes, _ := strings.Cut(value, ";")
+				value = redactCookies(cookie)
+				if attributes != "" {
+					value += ";" + attributes
+				}
+			case containsFold(sensitiveDumpHeaders, name):
+				value = redactedLogValue
+			default:
+				value = redactLogText(value)
+			}
+			fmt.Fprintf(buf, "%s%s: %s\n", prefix, name, value)
+		}
+	}
+	buf.WriteString(prefix + "\n")
+}
+
+// redactCookies 把 a=1; b=2 中的每个值替换为 ***，保留名称与顺序
+func redactCookies(value string) string {
+	parts := strings.Split(value, ";")
+	for i, part := range parts {
+		name, _, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if ok {
+			parts[i] = name + "=" + redactedLogValue
+		} else {
+			parts[i] = strings.TrimSpace(part)
+		}
+	}
+	return strings.Join(parts, "; ")
+}
+
+// writeDumpBody 输出请求体或响应体：gzip 先解开，JSON 缩进显示，其他压缩格式只记录长度
+func writeDumpBody(buf *bytes.Buffer, prefix string, body []byte, encoding string) {
+	if len(body) == 0 {
+		return
+	}
+	switch encoding = strings.ToLower(strings.TrimSpace(encoding)); encoding {
+	case "", "identity":
+	case "gzip":
+		body = decodeRecordedBody(body, encoding)
+	default:
+		fmt.Fprintf(buf, "%s<%d 字节 %s 压缩内容>\n", prefix, len(body), encoding)
+		return
+	}
+	if redacted, ok := redactDumpJSON(body); ok {
+		body = redacted
+	}
+	for _, line := range strings.Split(redactLogText(string(body)), "\n") {
+		buf.WriteString(prefix + line + "\n")
+	}
+}
+
+// redactDumpJSON 隐去 JSON 中 sensitiveDumpFields 列出的字段并缩进显示，不是 JSON 时返回 false
+func redactDumpJSON(body []byte) ([]byte, bool) {
+	decoder := json.NewDecoder(bytes.NewReader(bytes.TrimSpace(body)))
+	decoder.UseNumber()
+	var value any
+	if decoder.Decode(&value) != nil {
+		return nil, false
+	}
+	var redacted bytes.Buffer
+	encoder := json.NewEncoder(&redacted)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+	if encoder.Encode(redactDumpValue(value, false)) != nil {
+		return nil, false
+	}
+	return bytes.TrimSuffix(redacted.Bytes(), []byte("\n")), true
+}
+
+// redactDumpValue 递归处理 JSON 值；sensitive 为 true 时该字段的字符串（含数组中的）隐去，邮箱地址保留首尾便于对照
+func redactDumpValue(value any, sensitive bool) any {
+	switch v := value.(type) {
+	case map[string]any:
+		// 对象本身不整体隐去：响应中的 hme 既可能是地址，也可能是包含 anonymousId 等字段的邮箱对象
+		for key, child := range v {
+			v[key] = redactDumpValue(child, containsFold(sensitiveDumpFields, key))
+		}
+		return v
+	case []any:
+		for i, child := range v {
+			v[i] = redactDumpValue(child, sensitive)
+		}
+		return v
+	case string:
+		if !sensitive || v == "" {
+			return v
+		}
+		if strings.Contains(v, "@") {
+			return maskEmailAddress(v)
+		}
+		return redactedLogValue
+	default:
+		return v
+	}
+}
@@ -0,0 +1,31 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestRedactDumpJSON 调试日志中的邮箱地址、标签备注与 dsInfo 个人信息被隐去，其他字段原样保留
+func TestRedactDumpJSON(t *testing.T) {
+	body := `{"success":true,"result":{"hme":{"hme":"quiet.river_42@icloud.com","label":"github","note":"工作账号","anonymousId":"abc123","forwardToEmail":"someone@example.com","isActive":true}},` +
+		`"dsInfo":{"appleId":"someone@example.com","fullName":"Zhang San","appleIdAliases":["alias@icloud.com"],"dsid":"12345"}}`
+	redacted, ok := redactDumpJSON([]byte(body))
+	if !ok {
+		t.Fatal("合法的 JSON 未能处理")
+	}
+	text := string(redacted)
+	for _, leaked := range []string{"quiet.river_42", "github", "工作账号", "someone@", "Zhang San", "alias@"} {
+		if strings.Contains(text, leaked) {
+			t.Errorf("调试日志中仍有 %q:\n%s", leaked, text)
+		}
+	}
+	for _, kept := range []string{`"anonymousId": "abc123"`, `"isActive": true`, `"success": true`, "qu****42@icloud.com"} {
+		if !strings.Contains(text, kept) {
+			t.Errorf("调试日志中缺少 %q:\n%s", kept, text)
+		}
+	}
+
+	if _, ok := redactDumpJSON([]byte("<html>")); ok {
+		t.Error("不是 JSON 的响应体不应按 JSON 处理")
+	}
+}
@@ -0,0 +1,293 @@
+package main
+
+import (
+	"bufio"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// IMAPConfig 转发目标邮箱的 IMAP 配置，用于读取转发到真实邮箱的邮件
+type IMAPConfig struct {
+	Host      string `json:"host"`      // 如 imap.gmail.com，为空表示不启用
+	Port      int    `json:"port"`      // 默认 993
+	Username  string `json:"username"`  // 登录用户名
+	Password  string `json:"password"`  // 密码或应用专用密码
+	Mailbox   string `json:"mailbox"`   // 默认 INBOX
+	Plaintext bool   `json:"plaintext"` // 不使用 TLS（仅用于本机桥接程序）
+
+	// 垃圾邮件文件夹，如 Junk 或 [Gmail]/Spam，用于在时间线中标记被判为垃圾邮件的来信
+	JunkMailbox string `json:"junk_mailbox"`
+
+	// 自定义验证码正则，第一个捕获组为验证码，优先于内置规则
+	OTPPatterns []string `json:"otp_patterns"`
+}
+
+// Enabled 是否配置了 IMAP
+func (c IMAPConfig) Enabled() bool {
+	return c.Host != "" && c.Username != ""
+}
+
+// FETCH 响应中的 UID 与到达时间，两者顺序不固定
+var (
+	imapUIDPattern          = regexp.MustCompile(`\bUID (\d+)`)
+	imapInternalDatePattern = regexp.MustCompile(`INTERNALDATE "([^"]+)"`)
+)
+
+// imapClient 最小化的 IMAP4rev1 客户端，仅实现读取邮件所需的命令
+type imapClient struct {
+	conn   net.Conn
+	reader *bufio.Reader
+	tag    int
+}
+
+// imapResponse 一条服务器响应，Literals 为响应中 {n} 形式携带的原始数据
+type imapResponse struct {
+	Text     string
+	Literals [][]byte
+}
+
+// dialIMAP 连接并登录 IMAP 服务器
+func dialIMAP(settings IMAPConfig, timeout time.Duration) (*imapClient, error) {
+	if !settings.Enabled() {
+		return nil, configError("未配置 IMAP，请在 config.json 的 imap 中填写服务器与账号")
+	}
+	port := settings.Port
+	if port == 0 {
+		port = 993
+	}
+	address := net.JoinHostPort(settings.Host, strconv.Itoa(port))
+
+	dialer := &net.Dialer{Timeout: timeout}
+	var conn net.Conn
+	var err error
+	if settings.Plaintext {
+		conn, err = dialer.Dial("tcp", address)
+	} else {
+		conn, err = tls.DialWithDialer(dialer, "tcp", address, &tls.Config{ServerName: settings.Host})
+	}
+	if err != nil {
+		return nil, fmt.Errorf("连接 IMAP 服务器失败: %v", err)
+	}
+
+	c := &imapClient{conn: conn, reader: bufio.NewReader(conn)}
+	conn.SetDeadline(time.Now().Add(timeout))
+	greeting, err := c.readLine()
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("读取 IMAP 欢迎信息失败: %v", err)
+	}
+	if !strings.HasPrefix(greeting, "* OK") {
+		conn.Close()
+		return nil, fmt.Errorf("IMAP 服务器拒绝连接: %s", greeting)
+	}
+
+	if _, err := c.command(timeout, "LOGIN %s %s", imapQuote(settings.Username), imapQuote(settings.Password)); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("IMAP 登录失败: %v", err)
+	}
+
+	mailbox := settings.Mailbox
+	if mailbox == "" {
+		mailbox = "INBOX"
+	}
+	if err := c.Examine(mailbox, timeout); err != nil {
+		c.Close()
+		return nil, err
+	}
+	return c, nil
+}
+
+// Examine 以只读方式打开邮箱文件夹，后续搜索与读取都在该文件夹中进行
+func (c *imapClient) Examine(mailbox string, timeout time.Duration) error {
+	if _, err := c.command(timeout, "EXAMINE %s", imapQuote(mailbox)); err != nil {
+		return fmt.Errorf("打开邮箱 %s 失败: %v", mailbox, err)
+	}
+	return nil
+}
+
+// Close 登出并关闭连接
+func (c *imapClient) Close() error {
+	c.command(5*time.Second, "LOGOUT")
+	return c.conn.Close()
+}
+
+// SearchTo 查找收件人包含指定地址、且不早于 since 当天的邮件 UID，since 为零值时不限时间
+func (c *imapClient) SearchTo(address string, since time.Time, timeout time.Duration) ([]uint32, error) {
+	criteria := "TO " + imapQuote(address)
+	if !since.IsZero() {
+		criteria = "SINCE " + since.Format("2-Jan-2006") + " " + criteria
+	}
+	return c.search(criteria, timeout)
+}
+
+// search 执行 UID SEARCH 并解析结果
+func (c *imapClient) search(criteria string, timeout time.Duration) ([]uint32, error) {
+	responses, err := c.command(timeout, "UID SEARCH %s", criteria)
+	if err != nil {
+		return nil, err
+	}
+
+	var uids []uint32
+	for _, response := range responses {
+		if !strings.HasPrefix(response.Text, "* SEARCH") {
+			continue
+		}
+		for _, field := range strings.Fields(strings.TrimPrefix(response.Text, "* SEARCH")) {
+			if uid, err := strconv.ParseUint(field, 10, 32); err == nil {
+				uids = append(uids, uint32(uid))
+			}
+		}
+	}
+	return uids, nil
+}
+
+// SearchSubject 查找主题包含指定文本、且不早于 since 当天的邮件 UID
+func (c *imapClient) SearchSubject(text string, since time.Time, timeout time.Duration) ([]uint32, error) {
+	return c.search("SINCE "+since.Format("2-Jan-2006")+" SUBJECT "+imapQuote(text), timeout)
+}
+
+// FetchRaw 读取邮件原文（不修改已读状态）
+func (c *imapClient) FetchRaw(uid uint32, timeout time.Duration) ([]byte, error) {
+	return c.fetchSection(uid, "", timeout)
+}
+
+// FetchHeader 只读取邮件头
+func (c *imapClient) FetchHeader(uid uint32, timeout time.Duration) ([]byte, error) {
+	return c.fetchSection(uid, "HEADER", timeout)
+}
+
+// fetchSection 读取邮件的指定部分
+func (c *imapClient) fetchSection(uid uint32, section string, timeout time.Duration) ([]byte, error) {
+	responses, err := c.command(timeout, "UID FETCH %d BODY.PEEK[%s]", uid, section)
+	if err != nil {
+		return nil, err
+	}
+	for _, response := range responses {
+		if strings.HasPrefix(response.Text, "* ") && strings.Contains(response.Text, "FETCH") && len(response.Literals) > 0 {
+			return response.Literals[0], nil
+		}
+	}
+	return nil, fmt.Errorf("邮件 %d 不存在", uid)
+}
+
+// FetchDates 批量读取邮件的到达时间（INTERNALDATE）
+func (c *imapClient) FetchDates(uids []uint32, timeout time.Duration) (map[uint32]time.Time, error) {
+	dates := make(map[uint32]time.Time, len(uids))
+	if len(uids) == 0 {
+		return dates, nil
+	}
+	set := make([]string, len(uids))
+	for i, uid := range uids {
+		set[i] = strconv.FormatUint(uint64(uid), 10)
+	}
+
+	responses, err := c.command(timeout, "UID FETCH %s (INTERNALDATE)", strings.Join(set, ","))
+	if err != nil {
+		return nil, err
+	}
+	for _, response := range responses {
+		uidMatch := imapUIDPattern.FindStringSubmatch(response.Text)
+		dateMatch := imapInternalDatePattern.FindStringSubmatch(response.Text)
+		if uidMatch == nil || dateMatch == nil {
+			continue
+		}
+		uid, err := strconv.ParseUint(uidMatch[1], 10, 32)
+		if err != nil {
+			continue
+		}
+		if date, err := time.Parse("_2-Jan-2006 15:04:05 -0700", dateMatch[1]); err == nil {
+			dates[uint32(uid)] = date
+		}
+	}
+	return dates, nil
+}
+
+// command 发送命令并读取直到对应标签的完成响应
+func (c *imapClient) command(timeout time.Duration, format string, args ...interface{}) ([]imapResponse, error) {
+	c.tag++
+	tag := fmt.Sprintf("a%03d", c.tag)
+	c.conn.SetDeadline(time.Now().Add(timeout))
+
+	if _, err := fmt.Fprintf(c.conn, "%s %s\r\n", tag, fmt.Sprintf(format, args...)); err != nil {
+		return nil, fmt.Errorf("发送 IMAP 命令失败: %v", err)
+	}
+
+	var responses []imapResponse
+	for {
+		response, err := c.readResponse()
+		if err != nil {
+			return nil, fmt.Errorf("读取 IMAP 响应失败: %v", err)
+		}
+		if !strings.HasPrefix(response.Text, tag+" ") {
+			responses = append(responses, response)
+			continue
+		}
+
+		status := strings.TrimPrefix(response.Text, tag+" ")
+		if strings.HasPrefix(status, "OK") {
+			return responses, nil
+		}
+		return nil, fmt.Errorf("%s", status)
+	}
+}
+
+// readResponse 读取一条完整响应，包含其中的字面量数据
+func (c *imapClient) readResponse() (imapResponse, error) {
+	var response imapResponse
+	for {
+		line, err := c.readLine()
+		if err != nil {
+			return response, err
+		}
+		response.Text += line
+
+		// 行尾的 {n} 表示后面紧跟 n 字节字面量，读取后响应继续
+		size, ok := imapLiteralSize(line)
+		if !ok {
+			return response, nil
+		}
+		literal := make([]byte, size)
+		if _, err := io.ReadFull(c.reader, literal); err != nil {
+			return response, err
+		}
+		response.Literals = append(response.Literals, literal)
+	}
+}
+
+// readLine 读取一行并去掉 CRLF
+func (c *imapClient) readLine() (string, error) {
+	line, err := c.reader.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+// imapLiteralSize 解析行尾的 {n} 字面量长度
+func imapLiteralSize(line string) (int, bool) {
+	if !strings.HasSuffix(line, "}") {
+		return 0, false
+	}
+	start := strings.LastIndex(line, "{")
+	if start < 0 {
+		return 0, false
+	}
+	size, err := strconv.Atoi(strings.TrimSuffix(line[start+1:len(line)-1], "+"))
+	if err != nil || size < 0 {
+		return 0, false
+	}
+	return size, true
+}
+
+// imapQuote 将参数转换为 IMAP 带引号字符串
+func imapQuote(value string) string {
+	value = strings.ReplaceAll(value, `\`, `\\`)
+	value = strings.ReplaceAll(value, `"`, `\"`)
+	return `"` + value + `"`
+}
@@ -0,0 +1,220 @@
+package main
+
+import (
+	"encoding/csv"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// LabelImportRow CSV 中的一行：邮箱地址（或 anonymous_id）及要设置的标签、备注
+type LabelImportRow struct {
+	Line  int
+	Email string
+	Label string // 为空时保留原标签
+	Note  string // 为空时保留原备注，为 - 时清空
+}
+
+// labelImportColumns 表头中可识别的列名
+var labelImportColumns = map[string][]string{
+	"email": {"email", "hme", "address", "alias", "邮箱", "地址"},
+	"label": {"label", "标签"},
+	"note":  {"note", "notes", "备注"},
+}
+
+// labelImportHeader 第一行是表头时返回各列的位置，否则按 邮箱,标签,备注 的顺序读取
+func labelImportHeader(record []string) (map[string]int, bool) {
+	columns := make(map[string]int)
+	for i, cell := range record {
+		name := strings.ToLower(strings.TrimSpace(strings.TrimPrefix(cell, "\ufeff")))
+		for column, aliases := range labelImportColumns {
+			if _, ok := columns[column]; !ok && containsFold(aliases, name) {
+				columns[column] = i
+			}
+		}
+	}
+	if _, ok := columns["email"]; !ok {
+		return map[string]int{"email": 0, "label": 1, "note": 2}, false
+	}
+	return columns, true
+}
+
+// readLabelImport 读取 CSV（表头可选），跳过空行和以 # 开头的行
+func readLabelImport(path string) ([]LabelImportRow, error) {
+	var reader io.Reader = os.Stdin
+	if path != "-" {
+		file, err := os.Open(path)
+		if err != nil {
+			return nil, fmt.Errorf("打开 CSV 文件失败: %v", err)
+		}
+		defer file.Close()
+		reader = file
+	}
+
+	csvReader := csv.NewReader(reader)
+	csvReader.FieldsPerRecord = -1
+	csvReader.Comment = '#'
+	var rows []LabelImportRow
+	var columns map[string]int
+	for {
+		record, err := csvReader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("解析 CSV 失败: %v", err)
+		}
+		line, _ := csvReader.FieldPos(0)
+		if columns == nil {
+			var hasHeader bool
+			if columns, hasHeader = labelImportHeader(record); hasHeader {
+				continue
+			}
+		}
+		cell := func(column string) (string, bool) {
+			index, ok := columns[column]
+			if !ok || index >= len(record) {
+				return "", false
+			}
+			return strings.TrimSpace(record[index]), true
+		}
+		row := LabelImportRow{Line: line}
+		if row.Email, _ = cell("email"); row.Email == "" {
+			continue
+		}
+		row.Label, _ = cell("label")
+		row.Note, _ = cell("note")
+		rows = append(rows, row)
+	}
+	if len(rows) == 0 {
+		return nil, fmt.Errorf("CSV 文件中没有有效的行")
+	}
+	return rows, nil
+}
+
+// planLabelImport 按邮箱地址或 anonymous_id（忽略大小写）匹配当前列表，生成修改计划。
+// 返回找不到的行；同一邮箱出现多次时以最后一行为准
+func planLabelImport(rows []LabelImportRow, emails []HMEEmail) ([]MetaDataEdit, []LabelImportRow) {
+	index := make(map[string]int, len(emails)*2)
+	for i, email := range emails {
+		index[strings.ToLower(email.HME)] = i
+		index[strings.ToLower(email.AnonymousID)] = i
+	}
+
+	var unmatched []LabelImportRow
+	planned := make(map[int]int)
+	var edits []MetaDataEdit
+	for _, row := range rows {
+		i, ok := index[strings.ToLower(row.Email)]
+		if !ok {
+			unmatched = append(unmatched, row)
+			continue
+		}
+		edit := MetaDataEdit{Email: emails[i], Label: emails[i].Label, Note: emails[i].Note}
+		if row.Label != "" {
+			edit.Label = row.Label
+		}
+		if row.Note != "" {
+			edit.Note = row.Note
+			if row.Note == clearNoteInput {
+				edit.Note = ""
+			}
+		}
+		if existing, ok := planned[i]; ok {
+			printWarning(fmt.Sprintf("第 %d 行重复了 %s，以这一行为准", row.Line, emails[i].HME))
+			edits[existing] = edit
+			continue
+		}
+		planned[i] = len(edits)
+		edits = append(edits, edit)
+	}
+
+	changed := edits[:0]
+	for _, edit := range edits {
+		if edit.Changed() {
+			changed = append(changed, edit)
+		}
+	}
+	return changed, unmatched
+}
+
+// runImportLabels 从 CSV 批量更新标签/备注：import-labels [-dry-run] 文件.csv
+func runImportLabels(config *Config, args []string) error {
+	fs := flag.NewFlagSet("import-labels", flag.ContinueOnError)
+	dryRun := fs.Bool("dry-run", false, "只预览修改，不写入")
+	if err := fs.Parse(args); err != nil {
+		return usageError(err)
+	}
+	if fs.NArg() != 1 {
+		return usageError(fmt.Errorf("用法: import-labels [-dry-run] 文件.csv（列为 email,label,note，表头可选；- 表示从标准输入读取）"))
+	}
+	rows, err := readLabelImport(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	var emails []HMEEmail
+	if err := withSpinner("获取邮箱列表", func() error {
+		var err error
+		emails, err = listHME(config)
+		return err
+	}); err != nil {
+		return fmt.Errorf("获取列表失败: %w", err)
+	}
+	edits, unmatched := planLabelImport(rows, emails)
+
+	printHeader("从 CSV 导入标签/备注")
+	fmt.Printf("  "+ColorCyan+"CSV:"+ColorReset+" %d 行 "+ColorDim+"|"+ColorReset+" 需要修改 %d 个 "+ColorDim+"|"+ColorReset+" 未找到 %d 个\n",
+		len(rows), len(edits), len(unmatched))
+	for _, row := range unmatched {
+		fmt.Printf("  "+ColorRed+"✗"+ColorReset+" 第 %d 行 %s "+ColorDim+"不在当前账号的邮箱列表中"+ColorReset+"\n", row.Line, row.Email)
+	}
+	if len(edits) == 0 {
+		printInfo("没有需要修改的邮箱")
+		return nil
+	}
+	fmt.Println()
+	printMetaDataEdits(edits)
+
+	if *dryRun {
+		if outputJSON {
+			for _, edit := range edits {
+				writeJSON(struct {
+					Op     string `json:"op"`
+					DryRun bool   `json:"dry_run"`
+					Email  string `json:"email"`
+					Label  string `json:"label"`
+					Note   string `json:"note"`
+				}{"edit", true, edit.Email.HME, edit.Label, edit.Note})
+			}
+		}
+		printInfo("预览模式，未做任何修改；去掉 -dry-run 后执行")
+		return nil
+	}
+	// CSV 来自标准输入时无法再交互确认
+	if fs.Arg(0) != "-" && !confirmAction(fmt.Sprintf("确认修改 %d 个邮箱", len(edits))) {
+		return fmt.Errorf("已取消")
+	}
+
+	printSubHeader("更新标签/备注")
+	succeeded, failed, lastErr := applyMetaDataEdits(config, "edit", edits)
+	fmt.Println()
+	printSeparator()
+	if succeeded > 0 {
+		printSuccess(fmt.Sprintf("已更新 %d 个", succeeded))
+	}
+	if outputJSON {
+		writeJSON(CLISummary{Op: "edit", Summary: true, Succeeded: succeeded, Failed: failed})
+	}
+	switch {
+	case failed == len(edits):
+		return fmt.Errorf("全部 %d 个均修改失败: %w", failed, lastErr)
+	case failed > 0:
+		return withExitCode(ExitPartial, fmt.Errorf("%d 个修改失败", failed))
+	case succeeded < len(edits):
+		return withExitCode(ExitPartial, fmt.Errorf("已取消，完成 %d/%d", succeeded, len(edits)))
+	}
+	return nil
+}
@@ -0,0 +1,626 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// 邮箱创建来源
+const (
+	SourceCLI    = "cli"    // 菜单单个创建或 create 子命令
+	SourceSmart  = "smart"  // 交互式智能创建
+	SourceBatch  = "batch"  // 交互式批量创建
+	SourceAPI    = "api"    // 服务模式 REST API
+	SourceSync   = "sync"   // 从 iCloud 列表同步发现（非本工具创建）
+	SourceSignup = "signup" // 新服务注册流程
+	SourceDaemon = "daemon" // 守护进程任务队列
+)
+
+// 本地记录的邮箱状态事件
+const (
+	InventoryEventDeactivated = "deactivated"
+	InventoryEventReactivated = "reactivated"
+	InventoryEventSignup      = "signup"   // 在新服务注册流程中交给某个网站使用
+	InventoryEventVerified    = "verified" // 注册流程中收到了该网站的验证邮件
+)
+
+// InventoryEvent 邮箱状态变化记录
+type InventoryEvent struct {
+	Type     string `json:"type"`
+	At       int64  `json:"at"` // Unix 毫秒
+	Source   string `json:"source,omitempty"`
+	Actor    string `json:"actor,omitempty"`
+	Detected bool   `json:"detected,omitempty"` // 由列表同步发现（如在 Apple 设置中操作），时间为发现时间
+}
+
+// CreationOrigin 邮箱的创建来源及发起者
+type CreationOrigin struct {
+	Source string `json:"source"`
+	Actor  string `json:"actor,omitempty"` // 服务模式下为 API Key 名称
+}
+
+// InventoryRecord 本地邮箱清单中的一条记录
+type InventoryRecord struct {
+	HME         string `json:"hme"`
+	AnonymousID string `json:"anonymousId,omitempty"`
+	Label       string `json:"label"`
+	Source      string `json:"source"`
+	Actor       string `json:"actor,omitempty"`
+	Note        string `json:"note,omitempty"`
+	CreatedAt   int64  `json:"createdAt"`           // Unix 毫秒
+	DeletedAt   int64  `json:"deletedAt,omitempty"` // 彻底删除时间，非零表示墓碑记录
+
+	// 台账信息，只保存在本地
+	Score int      `json:"score,omitempty"` // 创建时的质量评分
+	Site  string   `json:"site,omitempty"`  // 使用该邮箱的网站
+	Tags  []string `json:"tags,omitempty"`
+
+	Events []InventoryEvent `json:"events,omitempty"`
+}
+
+// knownActive 根据最近的状态事件推断本地记录的激活状态（新邮箱默认为激活）
+func (r *InventoryRecord) knownActive() bool {
+	for i := len(r.Events) - 1; i >= 0; i-- {
+		switch r.Events[i].Type {
+		case InventoryEventDeactivated:
+			return false
+		case InventoryEventReactivated:
+			return true
+		}
+	}
+	return true
+}
+
+// IsTombstone 是否为已彻底删除的墓碑记录
+func (r InventoryRecord) IsTombstone() bool {
+	return r.DeletedAt > 0
+}
+
+// Inventory 本地邮箱清单，记录 Apple 端不保存的元数据。
+// 记录保存在 bbolt 数据库中（每个邮箱一条），修改只写入变化的记录；数据库只在读写时短暂打开，
+// 服务模式、守护进程与只读命令可以同时使用同一份清单
+type Inventory struct {
+	path    string
+	mutex   sync.RWMutex
+	records map[string]*InventoryRecord // 以邮箱地址为键
+}
+
+// 全局本地清单
+var inventory *Inventory
+
+// 旧版 JSON 清单写入时使用的临时文件名，导入时用于从未写完的文件恢复
+const inventoryTempPattern = ".inventory-*.tmp"
+
+// 清单数据库中的桶
+var (
+	inventoryRecordsBucket = []byte("records") // 邮箱地址 → 记录（JSON）
+	inventoryMetaBucket    = []byte("meta")    // 格式名称与版本
+)
+
+// 等待其他进程释放数据库文件锁的最长时间
+const inventoryLockTimeout = 5 * time.Second
+
+// inventoryDBPath 清单数据库路径：inventory_file 仍指向旧版 JSON 文件（.json）时改用同名的 .db
+func inventoryDBPath(path string) string {
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		return strings.TrimSuffix(path, filepath.Ext(path)) + ".db"
+	}
+	return path
+}
+
+// legacyInventoryPath 旧版 JSON 清单的路径，首次打开数据库时从这里导入
+func legacyInventoryPath(path string) string {
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		return path
+	}
+	return strings.TrimSuffix(path, filepath.Ext(path)) + ".json"
+}
+
+// OpenInventory 打开本地清单，数据库不存在时从旧版 JSON 清单导入（原文件保留为 .bak），都不存在时创建空清单
+func OpenInventory(path string) (*Inventory, error) {
+	inv := &Inventory{
+		path:    inventoryDBPath(path),
+		records: make(map[string]*InventoryRecord),
+	}
+
+	if _, err := os.Stat(inv.path); errors.Is(err, os.ErrNotExist) {
+		return inv, inv.importLegacy(legacyInventoryPath(path))
+	}
+	db, err := inv.open(true)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+	err = db.View(func(tx *bolt.Tx) error {
+		if err := checkInventoryFormat(tx.Bucket(inventoryMetaBucket)); err != nil {
+			return err
+		}
+		bucket := tx.Bucket(inventoryRecordsBucket)
+		if bucket == nil {
+			return nil
+		}
+		return bucket.ForEach(func(key, value []byte) error {
+			var record InventoryRecord
+			if err := json.Unmarshal(value, &record); err != nil {
+				return fmt.Errorf("解析记录 %s 失败: %v", key, err)
+			}
+			inv.records[record.HME] = &record
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("读取本地清单失败: %w", err)
+	}
+	return inv, nil
+}
+
+// importLegacy 把旧版 JSON 清单导入数据库；文件损坏时自动从未写完的临时文件或上一版本恢复
+func (inv *Inventory) importLegacy(legacy string) error {
+	data, recoveredFrom, err := readFileRecovering(legacy, inventoryTempPattern, inventoryFormat.validator(func() interface{} {
+		return &[]*InventoryRecord{}
+	}))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("读取旧版本地清单失败: %v", err)
+	}
+	if recoveredFrom != "" {
+		printWarning(fmt.Sprintf("本地清单 %s 已损坏或未写完，已从 %s 恢复", legacy, recoveredFrom))
+	}
+	var records []*InventoryRecord
+	if err := inventoryFormat.decode(data, &records); err != nil {
+		return fmt.Errorf("解析旧版本地清单失败: %v", err)
+	}
+	for _, record := range records {
+		inv.records[record.HME] = record
+	}
+	if err := inv.save(records...); err != nil {
+		return err
+	}
+	if err := os.Rename(legacy, legacy+backupSuffix); err != nil {
+		printWarning(fmt.Sprintf("旧版本地清单未能改名为 %s: %v", legacy+backupSuffix, err))
+	}
+	printInfo(fmt.Sprintf("已把本地清单 %s 的 %d 条记录导入 %s", legacy, len(records), inv.path))
+	return nil
+}
+
+// checkInventoryFormat 数据库由更新的程序写入且声明当前程序无法读取时返回 FormatTooNewError
+func checkInventoryFormat(meta *bolt.Bucket) error {
+	if meta == nil {
+		return nil
+	}
+	minReader, _ := strconv.Atoi(string(meta.Get([]byte("min_reader"))))
+	if minReader > inventoryFormat.Version {
+		version, _ := strconv.Atoi(string(meta.Get([]byte("version"))))
+		return &FormatTooNewError{Title: inventoryFormat.Title, Version: version, MinReader: minReader, Supported: inventoryFormat.Version}
+	}
+	return nil
+}
+
+// open 打开清单数据库，其他进程正在写入时最多等待 inventoryLockTimeout
+func (inv *Inventory) open(readOnly bool) (*bolt.DB, error) {
+	if !readOnly {
+		if dir := filepath.Dir(inv.path); dir != "." {
+			if err := os.MkdirAll(dir, 0700); err != nil {
+				return nil, fmt.Errorf("创建本地清单目录失败: %v", err)
+			}
+		}
+	}
+	db, err := bolt.Open(inv.path, 0600, &bolt.Options{Timeout: inventoryLockTimeout, ReadOnly: readOnly})
+	if errors.Is(err, bolt.ErrTimeout) {
+		return nil, fmt.Errorf("本地清单 %s 被其他进程占用", inv.path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("打开本地清单失败: %v", err)
+	}
+	return db, nil
+}
+
+// save 在一个事务中写入变化的记录（调用方需持有写锁）
+func (inv *Inventory) save(records ...*InventoryRecord) error {
+	db, err := inv.open(false)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	err = db.Update(func(tx *bolt.Tx) error {
+		meta, err := tx.CreateBucketIfNotExists(inventoryMetaBucket)
+		if err != nil {
+			return err
+		}
+		if err := checkInventoryFormat(meta); err != nil {
+			return err
+		}
+		for key, value := range map[string]string{
+			"format":     inventoryFormat.Name,
+			"version":    strconv.Itoa(inventoryFormat.Version),
+			"min_reader": strconv.Itoa(inventoryFormat.MinReader),
+			"written_by": VERSION,
+		} {
+			if err := meta.Put([]byte(key), []byte(value)); err != nil {
+				return err
+			}
+		}
+		bucket, err := tx.CreateBucketIfNotExists(inventoryRecordsBucket)
+		if err != nil {
+			return err
+		}
+		for _, record := range records {
+			data, err := json.Marshal(record)
+			if err != nil {
+				return err
+			}
+			if err := bucket.Put([]byte(record.HME), data); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("写入本地清单失败: %w", err)
+	}
+	return nil
+}
+
+// RecordCreation 记录新创建的邮箱及其质量评分，anonymousId 未知时留空（之后同步时补全）
+func (inv *Inventory) RecordCreation(hme, anonymousID, label string, origin CreationOrigin, score int) error {
+	inv.mutex.Lock()
+	defer inv.mutex.Unlock()
+
+	record := &InventoryRecord{
+		HME:         hme,
+		AnonymousID: anonymousID,
+		Label:       label,
+		Source:      origin.Source,
+		Actor:       origin.Actor,
+		CreatedAt:   time.Now().UnixMilli(),
+		Score:       score,
+	}
+	inv.records[hme] = record
+	return inv.save(record)
+}
+
+// PoolSize 预留池中可用的邮箱数量：带有 tag 标记、仍激活且尚未记录使用网站
+func (inv *Inventory) PoolSize(tag string) int {
+	if inv == nil {
+		return 0
+	}
+
+	inv.mutex.RLock()
+	defer inv.mutex.RUnlock()
+
+	n := 0
+	for _, record := range inv.records {
+		if record.pooled(tag) {
+			n++
+		}
+	}
+	return n
+}
+
+// ClaimPooled 取出预留池中最早创建的一个邮箱并用 update 修改，池为空时 ok 为 false
+func (inv *Inventory) ClaimPooled(tag string, update func(record *InventoryRecord)) (claimed InventoryRecord, ok bool, err error) {
+	if inv == nil {
+		return InventoryRecord{}, false, nil
+	}
+
+	inv.mutex.Lock()
+	defer inv.mutex.Unlock()
+
+	var oldest *InventoryRecord
+	for _, record := range inv.records {
+		if record.pooled(tag) && (oldest == nil || record.CreatedAt < oldest.CreatedAt) {
+			oldest = record
+		}
+	}
+	if oldest == nil {
+		return InventoryRecord{}, false, nil
+	}
+	update(oldest)
+	return *oldest, true, inv.save(oldest)
+}
+
+// pooled 记录是否为预留池中可用的邮箱
+func (r *InventoryRecord) pooled(tag string) bool {
+	return r.HasTag(tag) && r.Site == "" && !r.IsTombstone() && r.knownActive()
+}
+
+// Annotate 修改一条记录的台账信息（使用网站、标记），query 为邮箱地址或 anonymousId
+func (inv *Inventory) Annotate(query string, update func(record *InventoryRecord)) (InventoryRecord, error) {
+	if inv == nil {
+		return InventoryRecord{}, fmt.Errorf("本地清单不可用")
+	}
+
+	inv.mutex.Lock()
+	defer inv.mutex.Unlock()
+
+	record := inv.findLocked(HMEEmail{HME: query, AnonymousID: query})
+	if record == nil {
+		return InventoryRecord{}, fmt.Errorf("本地清单中没有 %s", query)
+	}
+	update(record)
+	return *record, inv.save(record)
+}
+
+// RecordDeletion 将已彻底删除的邮箱保留为墓碑记录，email 至少需包含 HME 或 AnonymousID
+func (inv *Inventory) RecordDeletion(email HMEEmail) error {
+	if inv == nil {
+		return nil
+	}
+
+	inv.mutex.Lock()
+	defer inv.mutex.Unlock()
+
+	record := inv.findLocked(email)
+	if record == nil {
+		if email.HME == "" {
+			return nil // 本地从未见过该邮箱，无法生成有意义的墓碑
+		}
+		record = inv.upsertLocked(email, SourceSync)
+	}
+	record.DeletedAt = time.Now().UnixMilli()
+	return inv.save(record)
+}
+
+// RecordEvent 记录停用/重新激活等状态变化
+func (inv *Inventory) RecordEvent(email HMEEmail, eventType string, origin CreationOrigin) error {
+	if inv == nil {
+		return nil
+	}
+
+	inv.mutex.Lock()
+	defer inv.mutex.Unlock()
+
+	record := inv.findLocked(email)
+	if record == nil {
+		if email.HME == "" {
+			return nil
+		}
+		record = inv.upsertLocked(email, SourceSync)
+	}
+	record.Events = append(record.Events, InventoryEvent{
+		Type:   eventType,
+		At:     time.Now().UnixMilli(),
+		Source: origin.Source,
+		Actor:  origin.Actor,
+	})
+	return inv.save(record)
+}
+
+// RecordMetaData 记录修改后的标签和备注
+func (inv *Inventory) RecordMetaData(email HMEEmail) error {
+	if inv == nil || email.HME == "" {
+		return nil
+	}
+
+	inv.mutex.Lock()
+	defer inv.mutex.Unlock()
+
+	return inv.save(inv.upsertLocked(email, SourceSync))
+}
+
+// Sync 用最新的 iCloud 列表更新本地清单：补全元数据、登记未知邮箱，并将已消失的邮箱标记为墓碑
+func (inv *Inventory) Sync(emails []HMEEmail) error {
+	if inv == nil {
+		return nil
+	}
+
+	inv.mutex.Lock()
+	defer inv.mutex.Unlock()
+
+	now := time.Now().UnixMilli()
+	present := make(map[string]bool, len(emails))
+	for _, email := range emails {
+		present[email.HME] = true
+		record := inv.upsertLocked(email, SourceSync)
+		record.DeletedAt = 0
+
+		// 记录在本工具之外发生的激活状态变化
+		if email.IsActive != record.knownActive() {
+			eventType := InventoryEventDeactivated
+			if email.IsActive {
+				eventType = InventoryEventReactivated
+			}
+			record.Events = append(record.Events, InventoryEvent{Type: eventType, At: now, Detected: true})
+		}
+	}
+
+	records := make([]*InventoryRecord, 0, len(inv.records))
+	for hme, record := range inv.records {
+		// 只有确认在 iCloud 中存在过（有 AnonymousID）的记录才会被标记为删除
+		if !present[hme] && !record.IsTombstone() && record.AnonymousID != "" {
+			record.DeletedAt = now
+		}
+		records = append(records, record)
+	}
+	return inv.save(records...)
+}
+
+// LabelMismatch 本地记录与 iCloud 的标签不一致
+type LabelMismatch struct {
+	HME    string `json:"hme"`
+	Local  string `json:"local"`
+	Remote string `json:"remote"`
+}
+
+// InventoryDiff 本地清单与 iCloud 列表的差异
+type InventoryDiff struct {
+	Unknown         []HMEEmail        `json:"unknown"`          // iCloud 中有、本地没有记录（在本工具之外创建）
+	Deleted         []InventoryRecord `json:"deleted"`          // 本地记录仍存在、iCloud 中已没有（在其他地方删除）
+	Restored        []InventoryRecord `json:"restored"`         // 本地为墓碑记录、iCloud 中又出现
+	LabelMismatches []LabelMismatch   `json:"label_mismatches"` // 标签不一致
+}
+
+// Empty 是否没有任何差异
+func (d InventoryDiff) Empty() bool {
+	return len(d.Unknown) == 0 && len(d.Deleted) == 0 && len(d.Restored) == 0 && len(d.LabelMismatches) == 0
+}
+
+// Diff 比较本地清单与最新的 iCloud 列表，不修改清单；判断删除的规则与 Sync 一致
+func (inv *Inventory) Diff(emails []HMEEmail) InventoryDiff {
+	diff := InventoryDiff{Unknown: []HMEEmail{}, Deleted: []InventoryRecord{}, Restored: []InventoryRecord{}, LabelMismatches: []LabelMismatch{}}
+	if inv == nil {
+		return diff
+	}
+
+	inv.mutex.RLock()
+	defer inv.mutex.RUnlock()
+
+	present := make(map[string]bool, len(emails))
+	for _, email := range emails {
+		present[email.HME] = true
+		record, ok := inv.records[email.HME]
+		switch {
+		case !ok:
+			diff.Unknown = append(diff.Unknown, email)
+		case record.IsTombstone():
+			diff.Restored = append(diff.Restored, *record)
+		case record.Label != email.Label:
+			diff.LabelMismatches = append(diff.LabelMismatches, LabelMismatch{HME: email.HME, Local: record.Label, Remote: email.Label})
+		}
+	}
+	for hme, record := range inv.records {
+		if !present[hme] && !record.IsTombstone() && record.AnonymousID != "" {
+			diff.Deleted = append(diff.Deleted, *record)
+		}
+	}
+
+	sort.Slice(diff.Unknown, func(i, j int) bool { return diff.Unknown[i].CreateTimestamp < diff.Unknown[j].CreateTimestamp })
+	sort.Slice(diff.Deleted, func(i, j int) bool { return diff.Deleted[i].CreatedAt < diff.Deleted[j].CreatedAt })
+	sort.Slice(diff.LabelMismatches, func(i, j int) bool { return diff.LabelMismatches[i].HME < diff.LabelMismatches[j].HME })
+	return diff
+}
+
+// Search 按关键字搜索邮箱地址、标签、备注、使用网站和标记（包含墓碑记录）
+func (inv *Inventory) Search(keyword string) []InventoryRecord {
+	if inv == nil {
+		return nil
+	}
+
+	inv.mutex.RLock()
+	defer inv.mutex.RUnlock()
+
+	keyword = strings.ToLower(strings.TrimSpace(keyword))
+	var results []InventoryRecord
+	for _, record := range inv.records {
+		if keyword == "" ||
+			strings.Contains(strings.ToLower(record.HME), keyword) ||
+			strings.Contains(strings.ToLower(record.Label), keyword) ||
+			strings.Contains(strings.ToLower(record.Note), keyword) ||
+			strings.Contains(strings.ToLower(record.Site), keyword) ||
+			record.HasTag(keyword) {
+			results = append(results, *record)
+		}
+	}
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].CreatedAt > results[j].CreatedAt
+	})
+	return results
+}
+
+// findLocked 按地址或 AnonymousID 查找记录（调用方需持有锁）
+func (inv *Inventory) findLocked(email HMEEmail) *InventoryRecord {
+	if record, ok := inv.records[email.HME]; ok && email.HME != "" {
+		return record
+	}
+	if email.AnonymousID == "" {
+		return nil
+	}
+	for _, record := range inv.records {
+		if record.AnonymousID == email.AnonymousID {
+			return record
+		}
+	}
+	return nil
+}
+
+// upsertLocked 新增或更新记录的 iCloud 元数据（调用方需持有写锁）
+func (inv *Inventory) upsertLocked(email HMEEmail, source string) *InventoryRecord {
+	record, ok := inv.records[email.HME]
+	if !ok {
+		record = &InventoryRecord{HME: email.HME, Source: source, CreatedAt: email.CreateTimestamp}
+		if record.CreatedAt == 0 {
+			record.CreatedAt = time.Now().UnixMilli()
+		}
+		inv.records[email.HME] = record
+	}
+	if email.AnonymousID != "" {
+		record.AnonymousID = email.AnonymousID
+	}
+	record.Label = email.Label
+	record.Note = email.Note
+	return record
+}
+
+// HasTag 是否带有指定标记（忽略大小写）
+func (r InventoryRecord) HasTag(tag string) bool {
+	for _, t := range r.Tags {
+		if strings.EqualFold(t, tag) {
+			return true
+		}
+	}
+	return false
+}
+
+// LastEvent 返回最近一次指定类型的状态事件
+func (r InventoryRecord) LastEvent(eventType string) (InventoryEvent, bool) {
+	for i := len(r.Events) - 1; i >= 0; i-- {
+		if r.Events[i].Type == eventType {
+			return r.Events[i], true
+		}
+	}
+	return InventoryEvent{}, false
+}
+
+// Get 按邮箱地址查询记录
+func (inv *Inventory) Get(hme string) (InventoryRecord, bool) {
+	if inv == nil {
+		return InventoryRecord{}, false
+	}
+
+	inv.mutex.RLock()
+	defer inv.mutex.RUnlock()
+
+	record, ok := inv.records[hme]
+	if !ok {
+		return InventoryRecord{}, false
+	}
+	return *record, true
+}
+
+// formatOrigin 格式化创建来源用于展示
+func formatOrigin(origin CreationOrigin) string {
+	names := map[string]string{
+		SourceCLI:    "命令行",
+		SourceSmart:  "智能创建",
+		SourceBatch:  "批量创建",
+		SourceAPI:    "REST API",
+		SourceSync:   "iCloud 同步",
+		SourceSignup: "新服务注册",
+		SourceDaemon: "守护进程任务",
+	}
+	name, ok := names[origin.Source]
+	if !ok {
+		name = origin.Source
+	}
+	if origin.Actor != "" {
+		name += " (" + origin.Actor + ")"
+	}
+	return name
+}
+
+// Origin 返回记录的创建来源
+func (r InventoryRecord) Origin() CreationOrigin {
+	return CreationOrigin{Source: r.Source, Actor: r.Actor}
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestInventoryPersists 修改后的记录重新打开数据库仍在，只写入的记录不影响其他记录
+func TestInventoryPersists(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "inventory.db")
+	inv, err := OpenInventory(path)
+	if err != nil {
+		t.Fatalf("打开清单失败: %v", err)
+	}
+	if err := inv.RecordCreation("first@icloud.com", "id-first", "first", CreationOrigin{Source: SourceCLI}, 80); err != nil {
+		t.Fatalf("记录创建失败: %v", err)
+	}
+	if err := inv.RecordCreation("second@icloud.com", "", "second", CreationOrigin{Source: SourceBatch}, 0); err != nil {
+		t.Fatalf("记录创建失败: %v", err)
+	}
+	if _, err := inv.Annotate("first@icloud.com", func(record *InventoryRecord) { record.Site = "example.com" }); err != nil {
+		t.Fatalf("修改台账失败: %v", err)
+	}
+
+	reopened, err := OpenInventory(path)
+	if err != nil {
+		t.Fatalf("重新打开清单失败: %v", err)
+	}
+	first, ok := reopened.Get("first@icloud.com")
+	if !ok || first.Site != "example.com" || first.Score != 80 || first.AnonymousID != "id-first" {
+		t.Fatalf("first = %+v，期望网站 example.com、评分 80、anonymousId id-first", first)
+	}
+	if second, ok := reopened.Get("second@icloud.com"); !ok || second.Source != SourceBatch {
+		t.Fatalf("second = %+v，期望来源 %s", second, SourceBatch)
+	}
+}
+
+// TestInventoryImportsLegacyJSON 旧版 JSON 清单在首次打开时导入同名的 .db，原文件保留为 .bak
+func TestInventoryImportsLegacyJSON(t *testing.T) {
+	dir := t.TempDir()
+	legacy := filepath.Join(dir, "hme_inventory.json")
+	records := []*InventoryRecord{{HME: "old@icloud.com", Label: "old", Source: SourceCLI, CreatedAt: 1}}
+	if err := inventoryFormat.write(legacy, inventoryTempPattern, records); err != nil {
+		t.Fatalf("写入旧版清单失败: %v", err)
+	}
+
+	inv, err := OpenInventory(legacy)
+	if err != nil {
+		t.Fatalf("打开清单失败: %v", err)
+	}
+	if _, ok := inv.Get("old@icloud.com"); !ok {
+		t.Fatal("旧版清单中的记录未导入")
+	}
+	if _, err := os.Stat(legacy + backupSuffix); err != nil {
+		t.Fatalf("旧版清单未保留为 .bak: %v", err)
+	}
+
+	reopened, err := OpenInventory(legacy)
+	if err != nil {
+		t.Fatalf("重新打开清单失败: %v", err)
+	}
+	if _, ok := reopened.Get("old@icloud.com"); !ok {
+		t.Fatal("导入的记录未写入数据库")
+	}
+	if _, err := os.Stat(filepath.Join(dir, "hme_inventory.db")); err != nil {
+		t.Fatalf("没有生成 .db: %v", err)
+	}
+}
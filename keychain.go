@@ -0,0 +1,140 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// 系统钥匙串中的服务名，条目按 account 区分
+const keychainService = "icloud-hme"
+
+// 钥匙串命令的超时：系统弹出授权对话框时需要留出确认的时间
+const keychainTimeout = 60 * time.Second
+
+// errKeychainNotFound 钥匙串中没有对应条目
+var errKeychainNotFound = errors.New("钥匙串中没有对应的条目")
+
+// keychainName 当前系统钥匙串的名称，用于提示
+func keychainName() string {
+	switch runtime.GOOS {
+	case "darwin":
+		return "macOS 钥匙串"
+	case "windows":
+		return "Windows 凭据管理器"
+	default:
+		return "Secret Service（GNOME 钥匙串 / KWallet）"
+	}
+}
+
+// psQuote PowerShell 单引号字符串
+func psQuote(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", "''") + "'"
+}
+
+// Windows 凭据管理器通过 PowerShell 的 PasswordVault 访问
+const psPasswordVault = "[void][Windows.Security.Credentials.PasswordVault,Windows.Security.Credentials,ContentType=WindowsRuntime];" +
+	"$v=New-Object Windows.Security.Credentials.PasswordVault;"
+
+// keychainCommand 按系统构建钥匙串命令：get 读取、set 写入（密钥从标准输入传入）、delete 删除
+func keychainCommand(ctx context.Context, action, account string) (*exec.Cmd, error) {
+	switch runtime.GOOS {
+	case "darwin":
+		switch action {
+		case "get":
+			return exec.CommandContext(ctx, "security", "find-generic-password", "-s", keychainService, "-a", account, "-w"), nil
+		case "set":
+			// -w 作为最后一个选项且不带值时，security 提示输入密码并从标准输入读取，密钥不会出现在任何进程的参数中
+			return exec.CommandContext(ctx, "security", "add-generic-password", "-U", "-s", keychainService, "-a", account, "-w"), nil
+		case "delete":
+			return exec.CommandContext(ctx, "security", "delete-generic-password", "-s", keychainService, "-a", account), nil
+		}
+	case "windows":
+		target := psQuote(keychainService) + "," + psQuote(account)
+		var script string
+		switch action {
+		case "get":
+			script = psPasswordVault + "$c=$v.Retrieve(" + target + ");$c.RetrievePassword();[Console]::Out.Write($c.Password)"
+		case "set":
+			script = psPasswordVault + "$v.Add((New-Object Windows.Security.Credentials.PasswordCredential(" + target + ",[Console]::In.ReadLine())))"
+		case "delete":
+			script = psPasswordVault + "$v.Remove($v.Retrieve(" + target + "))"
+		}
+		return exec.CommandContext(ctx, "powershell", "-NoProfile", "-NonInteractive", "-Command", script), nil
+	default:
+		switch action {
+		case "get":
+			return exec.CommandContext(ctx, "secret-tool", "lookup", "service", keychainService, "account", account), nil
+		case "set":
+			return exec.CommandContext(ctx, "secret-tool", "store", "--label=iCloud 隐藏邮箱配置密钥", "service", keychainService, "account", account), nil
+		case "delete":
+			return exec.CommandContext(ctx, "secret-tool", "clear", "service", keychainService, "account", account), nil
+		}
+	}
+	return nil, fmt.Errorf("未知的钥匙串操作: %s", action)
+}
+
+// runKeychain 执行钥匙串命令，input 写入标准输入，返回标准输出
+func runKeychain(action, account, input string) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), keychainTimeout)
+	defer cancel()
+	cmd, err := keychainCommand(ctx, action, account)
+	if err != nil {
+		return "", err
+	}
+	var stdout, stderr bytes.Buffer
+	cmd.Stdin = strings.NewReader(input)
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	if err := cmd.Run(); err != nil {
+		var exitErr *exec.ExitError
+		if !errors.As(err, &exitErr) {
+			return "", fmt.Errorf("无法访问%s: %v", keychainName(), err)
+		}
+		detail := strings.TrimSpace(stderr.String())
+		if detail == "" {
+			detail = err.Error()
+		}
+		return "", fmt.Errorf("%s返回错误: %s", keychainName(), detail)
+	}
+	return strings.TrimSpace(stdout.String()), nil
+}
+
+// keychainGet 读取钥匙串中的密钥
+func keychainGet(account string) (string, error) {
+	secret, err := runKeychain("get", account, "")
+	if err != nil {
+		return "", err
+	}
+	// secret-tool 找不到条目时以空输出正常退出
+	if secret == "" {
+		return "", errKeychainNotFound
+	}
+	return secret, nil
+}
+
+// keychainSet 写入（或覆盖）钥匙串中的密钥
+func keychainSet(account, secret string) error {
+	// secret-tool 把标准输入的全部内容作为密钥，不能带换行；PowerShell 按行读取；security 会要求再输入一次确认
+	var input string
+	switch runtime.GOOS {
+	case "darwin":
+		input = secret + "\n" + secret + "\n"
+	case "windows":
+		input = secret + "\n"
+	default:
+		input = secret
+	}
+	_, err := runKeychain("set", account, input)
+	return err
+}
+
+// keychainDelete 删除钥匙串中的密钥
+func keychainDelete(account string) error {
+	_, err := runKeychain("delete", account, "")
+	return err
+}
@@ -0,0 +1,309 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math/rand"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// LabelFunc 根据序号（从 1 开始）生成批量任务中每个邮箱的标签
+type LabelFunc func(index int) string
+
+var labelAdjectives = []string{
+	"amber", "brave", "calm", "clever", "crisp", "dusty", "eager", "fancy",
+	"gentle", "golden", "happy", "hidden", "icy", "jolly", "kind", "lively",
+	"lucky", "merry", "misty", "noble", "olive", "polite", "proud", "quick",
+	"quiet", "rapid", "rosy", "rustic", "shiny", "silent", "silver", "sunny",
+	"swift", "tidy", "urban", "vivid", "warm", "wild", "witty", "young",
+}
+
+var labelNouns = []string{
+	"anchor", "badger", "beacon", "birch", "canyon", "cedar", "comet", "coral",
+	"delta", "ember", "falcon", "fern", "forest", "harbor", "heron", "island",
+	"lantern", "maple", "meadow", "otter", "panda", "pebble", "pine", "planet",
+	"raven", "reef", "river", "robin", "sparrow", "spruce", "summit", "thunder",
+	"tiger", "valley", "walrus", "willow", "wolf", "yacht", "zebra", "zephyr",
+}
+
+// sequentialLabels 生成 "前缀+序号" 形式的标签
+func sequentialLabels(prefix string) LabelFunc {
+	return func(index int) string {
+		return fmt.Sprintf("%s%d", prefix, index)
+	}
+}
+
+// readableLabels 生成 "形容词-名词-三位数字" 形式的随机可读标签，同一批次内保证唯一
+func readableLabels(prefix string, existing []string) LabelFunc {
+	var mutex sync.Mutex
+	used := make(map[string]bool, len(existing))
+	for _, label := range existing {
+		used[label] = true
+	}
+
+	return func(index int) string {
+		mutex.Lock()
+		defer mutex.Unlock()
+
+		for {
+			label := fmt.Sprintf("%s%s-%s-%03d", prefix,
+				labelAdjectives[rand.Intn(len(labelAdjectives))],
+				labelNouns[rand.Intn(len(labelNouns))],
+				rand.Intn(1000))
+			if !used[label] {
+				used[label] = true
+				return label
+			}
+		}
+	}
+}
+
+// listLabels 依次使用给定的标签列表
+func listLabels(labels []string) LabelFunc {
+	return func(index int) string {
+		return labels[index-1]
+	}
+}
+
+// readLabelsFile 从文件读取标签，每行一个，忽略空行、# 注释与重复项；path 为 "-" 时读取标准输入
+func readLabelsFile(path string) ([]string, error) {
+	var reader io.Reader = os.Stdin
+	if path != "-" {
+		file, err := os.Open(path)
+		if err != nil {
+			return nil, fmt.Errorf("打开标签文件失败: %v", err)
+		}
+		defer file.Close()
+		reader = file
+	}
+
+	var labels []string
+	seen := make(map[string]bool)
+	scanner := bufio.NewScanner(reader)
+	for scanner.Scan() {
+		label := strings.TrimSpace(scanner.Text())
+		if label == "" || strings.HasPrefix(label, "#") || seen[label] {
+			continue
+		}
+		seen[label] = true
+		labels = append(labels, label)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("读取标签文件失败: %v", err)
+	}
+	if len(labels) == 0 {
+		return nil, fmt.Errorf("标签文件中没有有效的标签")
+	}
+	return labels, nil
+}
+
+// PrefixWeight 批量创建时轮换使用的标签前缀及其权重
+type PrefixWeight struct {
+	Prefix string `json:"prefix"`
+	Weight int    `json:"weight"` // 小于等于 0 时按 1 计算
+}
+
+// parsePrefixWeights 解析 "shop-:3,news-:1,forum-" 形式的前缀权重，省略权重时为 1
+func parsePrefixWeights(spec string) ([]PrefixWeight, error) {
+	var weights []PrefixWeight
+	for _, item := range strings.Split(spec, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		weight := PrefixWeight{Prefix: item, Weight: 1}
+		if i := strings.LastIndex(item, ":"); i >= 0 {
+			n, err := strconv.Atoi(strings.TrimSpace(item[i+1:]))
+			if err != nil || n <= 0 {
+				return nil, fmt.Errorf("无效的前缀权重: %s", item)
+			}
+			weight = PrefixWeight{Prefix: strings.TrimSpace(item[:i]), Weight: n}
+		}
+		if weight.Prefix == "" {
+			return nil, fmt.Errorf("前缀不能为空: %s", item)
+		}
+		weights = append(weights, weight)
+	}
+	if len(weights) == 0 {
+		return nil, fmt.Errorf("未指定任何前缀")
+	}
+	return weights, nil
+}
+
+// describePrefixWeights 生成如 "shop-* ×3 / news-* ×1" 的说明
+func describePrefixWeights(weights []PrefixWeight) string {
+	parts := make([]string, len(weights))
+	for i, w := range weights {
+		parts[i] = fmt.Sprintf("%s* ×%d", w.Prefix, max(w.Weight, 1))
+	}
+	return strings.Join(parts, " / ")
+}
+
+// weightedPrefixLabels 按权重随机选择前缀，各前缀独立编号并接在已有同前缀标签的最大序号之后。
+// 同一序号重复调用（预览、失败重试）返回相同标签
+func weightedPrefixLabels(weights []PrefixWeight, existing []HMEEmail) LabelFunc {
+	var mutex sync.Mutex
+	assigned := make(map[int]string)
+	next := make(map[string]int, len(weights))
+	total := 0
+	for _, w := range weights {
+		_, next[w.Prefix] = countPoolAliases(existing, w.Prefix)
+		total += max(w.Weight, 1)
+	}
+
+	return func(index int) string {
+		mutex.Lock()
+		defer mutex.Unlock()
+
+		if label, ok := assigned[index]; ok {
+			return label
+		}
+		pick := rand.Intn(total)
+		prefix := weights[len(weights)-1].Prefix
+		for _, w := range weights {
+			if pick < max(w.Weight, 1) {
+				prefix = w.Prefix
+				break
+			}
+			pick -= max(w.Weight, 1)
+		}
+		next[prefix]++
+		label := fmt.Sprintf("%s%d", prefix, next[prefix])
+		assigned[index] = label
+		return label
+	}
+}
+
+// 标签模板中占位符的起止标记，如 shop-{{date:2006-01}}-{{n}}
+const (
+	labelTemplateOpen  = "{{"
+	labelTemplateClose = "}}"
+)
+
+// labelTemplatePart 标签模板的一段：Name 为空时是原样输出的文本
+type labelTemplatePart struct {
+	Text string
+	Name string
+	Arg  string
+}
+
+// parseLabelTemplate 解析标签模板，支持的占位符：
+//
+//	{{n}} 序号，{{n:3}} 补零到 3 位
+//	{{date}} 创建日期（2006-01-02），{{date:2006-01}} 按 Go 时间格式
+//	{{word}} 随机名词，{{adj}} 随机形容词
+//	{{rand}} 4 位随机十六进制，{{rand:6}} 指定位数（1-32）
+func parseLabelTemplate(template string) ([]labelTemplatePart, error) {
+	var parts []labelTemplatePart
+	variable := false
+	rest := template
+	for rest != "" {
+		start := strings.Index(rest, labelTemplateOpen)
+		if start < 0 {
+			parts = append(parts, labelTemplatePart{Text: rest})
+			break
+		}
+		if start > 0 {
+			parts = append(parts, labelTemplatePart{Text: rest[:start]})
+		}
+		end := strings.Index(rest[start:], labelTemplateClose)
+		if end < 0 {
+			return nil, fmt.Errorf("标签模板中的 %s 没有闭合", labelTemplateOpen)
+		}
+		spec := strings.TrimSpace(rest[start+len(labelTemplateOpen) : start+end])
+		rest = rest[start+end+len(labelTemplateClose):]
+
+		part := labelTemplatePart{Name: spec}
+		if i := strings.Index(spec, ":"); i >= 0 {
+			part.Name, part.Arg = strings.TrimSpace(spec[:i]), spec[i+1:]
+		}
+		switch part.Name {
+		case "n", "rand":
+			if part.Arg != "" {
+				n, err := strconv.Atoi(strings.TrimSpace(part.Arg))
+				if err != nil || n < 1 || n > 32 {
+					return nil, fmt.Errorf("{{%s}} 的位数需在 1-32 之间", spec)
+				}
+			}
+		case "date":
+			if part.Arg == "" {
+				part.Arg = "2006-01-02"
+			}
+		case "word", "adj":
+			if part.Arg != "" {
+				return nil, fmt.Errorf("{{%s}} 不接受参数", part.Name)
+			}
+		default:
+			return nil, fmt.Errorf("未知的标签占位符 {{%s}}，可用 n、date、word、adj、rand", spec)
+		}
+		if part.Name != "date" {
+			variable = true
+		}
+		parts = append(parts, part)
+	}
+	if !variable {
+		return nil, fmt.Errorf("标签模板需包含 {{n}}、{{word}}、{{adj}} 或 {{rand}}，否则所有邮箱的标签都相同")
+	}
+	return parts, nil
+}
+
+// renderLabelTemplate 按序号生成一个标签
+func renderLabelTemplate(parts []labelTemplatePart, index int, now time.Time) string {
+	var b strings.Builder
+	for _, part := range parts {
+		switch part.Name {
+		case "":
+			b.WriteString(part.Text)
+		case "n":
+			width, _ := strconv.Atoi(strings.TrimSpace(part.Arg))
+			fmt.Fprintf(&b, "%0*d", width, index)
+		case "date":
+			b.WriteString(now.Format(part.Arg))
+		case "word":
+			b.WriteString(labelNouns[rand.Intn(len(labelNouns))])
+		case "adj":
+			b.WriteString(labelAdjectives[rand.Intn(len(labelAdjectives))])
+		case "rand":
+			digits := 4
+			if part.Arg != "" {
+				digits, _ = strconv.Atoi(strings.TrimSpace(part.Arg))
+			}
+			for i := 0; i < digits; i++ {
+				b.WriteByte("0123456789abcdef"[rand.Intn(16)])
+			}
+		}
+	}
+	return strings.TrimSpace(b.String())
+}
+
+// templateLabels 按标签模板生成标签。同一序号重复调用（预览、失败重试）返回相同标签，
+// 含随机部分时同一批次内避免重复
+func templateLabels(template string) (LabelFunc, error) {
+	parts, err := parseLabelTemplate(template)
+	if err != nil {
+		return nil, err
+	}
+	var mutex sync.Mutex
+	assigned := make(map[int]string)
+	used := make(map[string]bool)
+	return func(index int) string {
+		mutex.Lock()
+		defer mutex.Unlock()
+
+		if label, ok := assigned[index]; ok {
+			return label
+		}
+		label := renderLabelTemplate(parts, index, time.Now())
+		for attempt := 0; used[label] && attempt < 20; attempt++ {
+			label = renderLabelTemplate(parts, index, time.Now())
+		}
+		used[label] = true
+		assigned[index] = label
+		return label
+	}, nil
+}
@@ -0,0 +1,260 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// 标签命名风格
+const (
+	LabelStyleSequential = "sequential" // 前缀+序号，如 auto-17
+	LabelStyleKebab      = "kebab"      // 小写短横线，如 online-shopping
+	LabelStyleLower      = "lower"      // 纯小写单词，如 amazon
+	LabelStyleTitle      = "title"      // 首字母大写，如 Shopping
+	LabelStyleMixed      = "mixed"      // 其他混合写法
+	LabelStyleEmpty      = "empty"      // 无标签
+)
+
+var (
+	sequentialLabelPattern = regexp.MustCompile(`^(.*?)[-_ ]?(\d+)$`)
+	kebabLabelPattern      = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)+$`)
+	lowerLabelPattern      = regexp.MustCompile(`^[a-z0-9.]+$`)
+	titleLabelPattern      = regexp.MustCompile(`^([A-Z][a-z0-9]*)( [A-Z][a-z0-9]*)*$`)
+)
+
+// LabelRename 标签重命名建议
+type LabelRename struct {
+	Email    HMEEmail
+	From     string
+	To       string
+	Reason   string
+	Sequence bool // 是否为序号占位标签
+}
+
+// LabelTaxonomyReport 标签体系分析结果
+type LabelTaxonomyReport struct {
+	StyleCounts map[string]int
+	Variants    map[string][]string // 规范化后的标签 -> 不同写法
+	Renames     []LabelRename
+}
+
+// classifyLabelStyle 判断标签的命名风格
+func classifyLabelStyle(label string) string {
+	label = strings.TrimSpace(label)
+	switch {
+	case label == "":
+		return LabelStyleEmpty
+	case isSequentialLabel(label):
+		return LabelStyleSequential
+	case kebabLabelPattern.MatchString(label):
+		return LabelStyleKebab
+	case lowerLabelPattern.MatchString(label):
+		return LabelStyleLower
+	case titleLabelPattern.MatchString(label):
+		return LabelStyleTitle
+	default:
+		return LabelStyleMixed
+	}
+}
+
+// isSequentialLabel 判断是否为批量创建产生的 "前缀+序号" 标签
+func isSequentialLabel(label string) bool {
+	match := sequentialLabelPattern.FindStringSubmatch(label)
+	if match == nil {
+		return false
+	}
+	prefix := strings.ToLower(strings.Trim(match[1], "-_ "))
+	return prefix == "" || prefix == "auto" || prefix == "batch" || prefix == "hme" || prefix == "email"
+}
+
+// normalizeLabel 将标签转换为统一的小写短横线风格
+func normalizeLabel(label string) string {
+	var b strings.Builder
+	lastDash := true
+	for _, r := range strings.TrimSpace(label) {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			b.WriteRune(unicode.ToLower(r))
+			lastDash = false
+		case r == '.' && !lastDash:
+			// 保留域名中的点号，如 amazon.com
+			b.WriteRune(r)
+		default:
+			if !lastDash {
+				b.WriteRune('-')
+				lastDash = true
+			}
+		}
+	}
+	return strings.Trim(b.String(), "-.")
+}
+
+// analyzeLabelTaxonomy 分析现有标签并生成统一命名建议
+func analyzeLabelTaxonomy(emails []HMEEmail) *LabelTaxonomyReport {
+	report := &LabelTaxonomyReport{
+		StyleCounts: make(map[string]int),
+		Variants:    make(map[string][]string),
+	}
+
+	seenVariant := make(map[string]bool)
+	for _, email := range emails {
+		style := classifyLabelStyle(email.Label)
+		report.StyleCounts[style]++
+		if style == LabelStyleEmpty || style == LabelStyleSequential {
+			continue
+		}
+
+		normalized := normalizeLabel(email.Label)
+		if !seenVariant[email.Label] {
+			seenVariant[email.Label] = true
+			report.Variants[normalized] = append(report.Variants[normalized], email.Label)
+		}
+	}
+
+	for _, email := range emails {
+		style := classifyLabelStyle(email.Label)
+		switch style {
+		case LabelStyleEmpty:
+			continue
+		case LabelStyleSequential:
+			// 序号占位标签统一为 unsorted-序号，提示用户后续补充真实用途
+			match := sequentialLabelPattern.FindStringSubmatch(email.Label)
+			target := "unsorted-" + match[2]
+			if target != email.Label {
+				report.Renames = append(report.Renames, LabelRename{
+					Email: email, From: email.Label, To: target, Reason: "序号占位标签", Sequence: true,
+				})
+			}
+		default:
+			target := normalizeLabel(email.Label)
+			if target == "" || target == email.Label {
+				continue
+			}
+			reason := "统一为小写短横线风格"
+			if len(report.Variants[target]) > 1 {
+				reason = fmt.Sprintf("合并 %d 种写法", len(report.Variants[target]))
+			}
+			report.Renames = append(report.Renames, LabelRename{
+				Email: email, From: email.Label, To: target, Reason: reason,
+			})
+		}
+	}
+
+	sort.SliceStable(report.Renames, func(i, j int) bool {
+		return report.Renames[i].To < report.Renames[j].To
+	})
+	return report
+}
+
+// printLabelTaxonomyReport 输出标签分析结果
+func printLabelTaxonomyReport(report *LabelTaxonomyReport, total int) {
+	printSubHeader("命名风格分布")
+	styleNames := []struct{ key, name string }{
+		{LabelStyleSequential, "序号占位 (auto-1)"},
+		{LabelStyleKebab, "小写短横线 (online-shopping)"},
+		{LabelStyleLower, "纯小写 (amazon)"},
+		{LabelStyleTitle, "首字母大写 (Shopping)"},
+		{LabelStyleMixed, "混合写法"},
+		{LabelStyleEmpty, "无标签"},
+	}
+	for _, style := range styleNames {
+		if count := report.StyleCounts[style.key]; count > 0 {
+			fmt.Printf("  "+ColorCyan+"%-28s"+ColorReset+" %3d "+ColorDim+"(%d%%)"+ColorReset+"\n",
+				style.name, count, count*100/total)
+		}
+	}
+
+	var duplicates []string
+	for normalized, variants := range report.Variants {
+		if len(variants) > 1 {
+			duplicates = append(duplicates, normalized)
+		}
+	}
+	if len(duplicates) > 0 {
+		sort.Strings(duplicates)
+		printSubHeader("同一用途的不同写法")
+		for _, normalized := range duplicates {
+			fmt.Printf("  "+ColorYellow+"%s"+ColorReset+" ← %s\n", normalized, strings.Join(report.Variants[normalized], ", "))
+		}
+	}
+
+	printSubHeader("建议的重命名")
+	if len(report.Renames) == 0 {
+		printSuccess("标签命名已保持一致，无需调整")
+		return
+	}
+	for _, rename := range report.Renames {
+		fmt.Printf("  %s "+ColorDim+"→"+ColorReset+" "+ColorBrightGreen+"%s"+ColorReset+" "+ColorDim+"(%s)"+ColorReset+"\n",
+			rename.From, rename.To, rename.Reason)
+	}
+}
+
+// runLabels 标签体系分析命令
+func runLabels(config *Config, args []string) error {
+	fs := flag.NewFlagSet("labels", flag.ContinueOnError)
+	apply := fs.Bool("apply", false, "确认后批量应用建议的标签")
+	skipSequence := fs.Bool("keep-sequence", false, "保留 auto-N 等序号占位标签")
+	if err := fs.Parse(args); err != nil {
+		return usageError(err)
+	}
+
+	var emails []HMEEmail
+	if err := withSpinner("获取邮箱列表", func() error {
+		var err error
+		emails, err = listHME(config)
+		return err
+	}); err != nil {
+		return fmt.Errorf("获取列表失败: %w", err)
+	}
+	if len(emails) == 0 {
+		printInfo("暂无邮箱")
+		return nil
+	}
+
+	printHeader("标签体系建议")
+	report := analyzeLabelTaxonomy(emails)
+	if *skipSequence {
+		filtered := report.Renames[:0]
+		for _, rename := range report.Renames {
+			if !rename.Sequence {
+				filtered = append(filtered, rename)
+			}
+		}
+		report.Renames = filtered
+	}
+	printLabelTaxonomyReport(report, len(emails))
+
+	if !*apply || len(report.Renames) == 0 {
+		if len(report.Renames) > 0 {
+			fmt.Println()
+			printInfo("使用 labels -apply 批量应用以上建议")
+		}
+		return nil
+	}
+
+	if !confirmAction(fmt.Sprintf("确认修改 %d 个邮箱的标签", len(report.Renames))) {
+		printInfo("已取消")
+		return nil
+	}
+
+	printSubHeader("应用标签")
+	edits := make([]MetaDataEdit, len(report.Renames))
+	for i, rename := range report.Renames {
+		edits[i] = MetaDataEdit{Email: rename.Email, Label: rename.To, Note: rename.Email.Note}
+	}
+	succeeded, failed, _ := applyMetaDataEdits(config, "rename", edits)
+
+	printSeparator()
+	printSuccess(fmt.Sprintf("已更新 %d 个标签", succeeded))
+	switch {
+	case failed > 0:
+		return withExitCode(ExitPartial, fmt.Errorf("失败 %d 个", failed))
+	case succeeded < len(edits):
+		return withExitCode(ExitPartial, fmt.Errorf("已取消，完成 %d/%d", succeeded, len(edits)))
+	}
+	return nil
+}
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"regexp"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// lang_code 设为 auto（或留空）时按系统语言自动选择
+const langCodeAuto = "auto"
+
+// 未能识别系统语言时使用的默认值
+const defaultLangCode = "en-us"
+
+// Apple 接受的 langCode 形如 en-us、zh-cn、ja-jp
+var langCodePattern = regexp.MustCompile(`^[a-z]{2,3}(-[a-z0-9]{2,4})?$`)
+
+// normalizeLangCode 把 zh_CN.UTF-8、en-US、ja_JP@calendar 等系统区域写法转为 Apple 使用的 zh-cn 形式，
+// 无法识别（如 C、POSIX）时返回空字符串
+func normalizeLangCode(locale string) string {
+	locale = strings.TrimSpace(locale)
+	if i := strings.IndexAny(locale, ".@"); i >= 0 {
+		locale = locale[:i]
+	}
+	locale = strings.ToLower(strings.ReplaceAll(locale, "_", "-"))
+	// macOS 的 AppleLocale 可能带脚本，如 zh-hans-cn
+	if parts := strings.Split(locale, "-"); len(parts) == 3 {
+		locale = parts[0] + "-" + parts[2]
+	}
+	if !langCodePattern.MatchString(locale) {
+		return ""
+	}
+	return locale
+}
+
+// 系统语言的检测结果：每次请求都会用到，macOS 上检测需要启动 defaults 进程，因此只检测一次，配置热重载时重新检测
+var (
+	detectedLangMutex sync.Mutex
+	detectedLangCode  string
+)
+
+// detectLangCode 系统语言对应的 langCode，结果缓存到下次 resetDetectedLangCode
+func detectLangCode() string {
+	detectedLangMutex.Lock()
+	defer detectedLangMutex.Unlock()
+	if detectedLangCode == "" {
+		detectedLangCode = detectSystemLangCode()
+	}
+	return detectedLangCode
+}
+
+// resetDetectedLangCode 清除缓存的系统语言，下次使用时重新检测
+func resetDetectedLangCode() {
+	detectedLangMutex.Lock()
+	detectedLangCode = ""
+	detectedLangMutex.Unlock()
+}
+
+// detectSystemLangCode 按 LC_ALL、LC_MESSAGES、LANG 的顺序读取系统语言，macOS 上再读取系统偏好
+func detectSystemLangCode() string {
+	for _, key := range []string{"LC_ALL", "LC_MESSAGES", "LANG"} {
+		if code := normalizeLangCode(os.Getenv(key)); code != "" {
+			return code
+		}
+	}
+	if runtime.GOOS == "darwin" {
+		if out, err := exec.Command("defaults", "read", "-g", "AppleLocale").Output(); err == nil {
+			if code := normalizeLangCode(string(out)); code != "" {
+				return code
+			}
+		}
+	}
+	return defaultLangCode
+}
+
+// parseLangCode 校验单次创建指定的语言，空字符串表示使用配置
+func parseLangCode(value string) (string, error) {
+	if strings.TrimSpace(value) == "" {
+		return "", nil
+	}
+	if strings.EqualFold(strings.TrimSpace(value), langCodeAuto) {
+		return detectLangCode(), nil
+	}
+	code := normalizeLangCode(value)
+	if code == "" {
+		return "", fmt.Errorf("无效的语言代码 %q，应形如 en-us、zh-cn、ja-jp", value)
+	}
+	return code, nil
+}
+
+// resolveLangCode 返回请求使用的 langCode：单次指定优先，其次配置，auto 或未配置时按系统语言
+func (c *Config) resolveLangCode(override string) string {
+	if override != "" {
+		return override
+	}
+	code := strings.TrimSpace(c.LangCode)
+	if code == "" || strings.EqualFold(code, langCodeAuto) {
+		return detectLangCode()
+	}
+	return code
+}
+
+// candidateLangCodes 智能创建轮流使用的语言（已规范化并去重），无效项提示后跳过
+func candidateLangCodes(config *Config) []string {
+	var langs []string
+	seen := make(map[string]bool)
+	for _, value := range config.EmailQuality.CandidateLangCodes {
+		code, err := parseLangCode(value)
+		if err != nil {
+			printWarning(fmt.Sprintf("candidate_lang_codes: %v，已跳过", err))
+			continue
+		}
+		if code != "" && !seen[code] {
+			seen[code] = true
+			langs = append(langs, code)
+		}
+	}
+	return langs
+}
+
+// formatCandidateLang 候选邮箱后附加的语言标记
+func formatCandidateLang(lang string) string {
+	if lang == "" {
+		return ""
+	}
+	return " " + ColorDim + "[" + lang + "]" + ColorReset
+}
+
+// printLangComparison 多语言生成时按语言汇总平均分与最高分，便于比较哪种语言的前缀更易读
+func printLangComparison(candidates []EmailCandidate) {
+	type langStats struct {
+		count, total, best int
+	}
+	stats := make(map[string]*langStats)
+	var order []string
+	for _, candidate := range candidates {
+		if candidate.Lang == "" {
+			continue
+		}
+		s, ok := stats[candidate.Lang]
+		if !ok {
+			s = &langStats{}
+			stats[candidate.Lang] = s
+			order = append(order, candidate.Lang)
+		}
+		s.count++
+		s.total += candidate.Score
+		if candidate.Score > s.best {
+			s.best = candidate.Score
+		}
+	}
+	if len(order) < 2 {
+		return
+	}
+
+	sort.Slice(order, func(i, j int) bool {
+		a, b := stats[order[i]], stats[order[j]]
+		return a.best > b.best || (a.best == b.best && a.total*b.count > b.total*a.count)
+	})
+	fmt.Println()
+	fmt.Print("  " + ColorBold + "语言对比" + ColorReset + "\n")
+	for _, lang := range order {
+		s := stats[lang]
+		fmt.Printf("    %-6s "+ColorDim+"最高 %d | 平均 %d | %d 个"+ColorReset+"\n", lang, s.best, s.total/s.count, s.count)
+	}
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+	"time"
+)
+
+// 滚动窗口默认保留的样本数
+const defaultLatencyWindow = 20
+
+// 样本少于该数量时不判断是否超过阈值，避免单次慢请求触发警告
+const minLatencySamples = 5
+
+// 需要监控耗时的接口：reserve 变慢往往是即将被限流的信号
+const latencySLOEndpoint = "reserve"
+
+// LatencySLOConfig 接口耗时监控（latency_slo）
+type LatencySLOConfig struct {
+	ReserveWarnMS   int `json:"reserve_warn_ms"`  // reserve 滚动中位耗时超过该值（毫秒）时警告，0 表示不监控
+	Window          int `json:"window"`           // 滚动窗口的样本数
+	SlowdownSeconds int `json:"slowdown_seconds"` // 超过阈值期间批量创建每项之前额外等待的秒数，0 表示只警告
+}
+
+// latencyWindow 一个接口最近若干次请求的耗时
+type latencyWindow struct {
+	samples []time.Duration
+}
+
+// add 记录一次耗时，超过窗口大小时丢弃最早的样本
+func (w *latencyWindow) add(elapsed time.Duration, size int) {
+	w.samples = append(w.samples, elapsed)
+	if len(w.samples) > size {
+		w.samples = append(w.samples[:0], w.samples[len(w.samples)-size:]...)
+	}
+}
+
+// percentile 窗口内耗时的第 p 百分位（0~100）
+func (w *latencyWindow) percentile(p int) time.Duration {
+	if len(w.samples) == 0 {
+		return 0
+	}
+	sorted := append([]time.Duration(nil), w.samples...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	return sorted[(len(sorted)-1)*p/100]
+}
+
+// LatencyTracker 按接口统计滚动耗时，并在 reserve 变慢时警告
+type LatencyTracker struct {
+	mutex    sync.Mutex
+	windows  map[string]*latencyWindow
+	degraded bool
+}
+
+var latencyTracker = &LatencyTracker{}
+
+// Record 记录一次请求耗时，reserve 中位耗时越过阈值（或恢复）时输出提示
+func (t *LatencyTracker) Record(config *Config, endpoint string, elapsed time.Duration) {
+	size := config.LatencySLO.Window
+	if size <= 0 {
+		size = defaultLatencyWindow
+	}
+
+	t.mutex.Lock()
+	if t.windows == nil {
+		t.windows = make(map[string]*latencyWindow)
+	}
+	w, ok := t.windows[endpoint]
+	if !ok {
+		w = &latencyWindow{}
+		t.windows[endpoint] = w
+	}
+	w.add(elapsed, size)
+
+	threshold := time.Duration(config.LatencySLO.ReserveWarnMS) * time.Millisecond
+	if endpoint != latencySLOEndpoint || threshold <= 0 || len(w.samples) < minLatencySamples {
+		t.mutex.Unlock()
+		return
+	}
+	median, samples := w.percentile(50), len(w.samples)
+	changed := (median > threshold) != t.degraded
+	t.degraded = median > threshold
+	t.mutex.Unlock()
+
+	if !changed {
+		return
+	}
+	if t.degraded {
+		message := fmt.Sprintf("%s 最近 %d 次的中位耗时 %s，超过 %s，可能即将被限流", endpoint, samples, median.Round(time.Millisecond), threshold)
+		if config.LatencySLO.SlowdownSeconds > 0 {
+			message += fmt.Sprintf("；批量创建每项额外等待 %ds", config.LatencySLO.SlowdownSeconds)
+		}
+		logger().Warn("接口耗时超过阈值", "endpoint", endpoint, "median_ms", median.Milliseconds(), "threshold_ms", threshold.Milliseconds())
+		fmt.Fprintf(os.Stderr, "\n"+ColorYellow+"[!] %s"+ColorReset+"\n", message)
+	} else {
+		logger().Info("接口耗时已恢复", "endpoint", endpoint, "median_ms", median.Milliseconds())
+		fmt.Fprintf(os.Stderr, "\n"+ColorGreen+"[+] %s 中位耗时已恢复到 %s"+ColorReset+"\n", endpoint, median.Round(time.Millisecond))
+	}
+}
+
+// Slowdown 超过阈值期间批量创建每项之前需要额外等待的时间
+func (t *LatencyTracker) Slowdown(config *Config) time.Duration {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	if !t.degraded || config.LatencySLO.ReserveWarnMS <= 0 {
+		return 0
+	}
+	return time.Duration(config.LatencySLO.SlowdownSeconds) * time.Second
+}
+
+// Print 输出各接口的滚动耗时
+func (t *LatencyTracker) Print() {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	if len(t.windows) == 0 {
+		return
+	}
+	endpoints := make([]string, 0, len(t.windows))
+	for endpoint := range t.windows {
+		endpoints = append(endpoints, endpoint)
+	}
+	sort.Strings(endpoints)
+	for _, endpoint := range endpoints {
+		w := t.windows[endpoint]
+		fmt.Printf("  "+ColorCyan+"%-16s"+ColorReset+" 最近 %d 次 "+ColorDim+"|"+ColorReset+" p50 %s "+ColorDim+"|"+ColorReset+" p95 %s\n",
+			endpoint, len(w.samples), w.percentile(50).Round(time.Millisecond), w.percentile(95).Round(time.Millisecond))
+	}
+	if t.degraded {
+		printWarning(latencySLOEndpoint + " 耗时超过 latency_slo.reserve_warn_ms")
+	}
+}
+
+// Reset 清空滚动耗时
+func (t *LatencyTracker) Reset() {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.windows = nil
+	t.degraded = false
+}
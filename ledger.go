@@ -0,0 +1,450 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// LedgerFilter 台账的筛选条件
+type LedgerFilter struct {
+	Keyword     string
+	Tag         string
+	Site        string
+	DeletedOnly bool
+}
+
+// filterLedger 按关键字、标记和网站筛选本地清单（按创建时间从新到旧）
+func filterLedger(filter LedgerFilter) []InventoryRecord {
+	var results []InventoryRecord
+	for _, record := range inventory.Search(filter.Keyword) {
+		if filter.Tag != "" && !record.HasTag(filter.Tag) {
+			continue
+		}
+		if filter.Site != "" && !strings.Contains(strings.ToLower(record.Site), strings.ToLower(filter.Site)) {
+			continue
+		}
+		if filter.DeletedOnly && !record.IsTombstone() {
+			continue
+		}
+		results = append(results, record)
+	}
+	return results
+}
+
+// parseTags 解析逗号或空格分隔的标记，去掉空白与重复项
+func parseTags(values ...string) []string {
+	var tags []string
+	for _, value := range values {
+		for _, tag := range strings.FieldsFunc(value, func(r rune) bool { return r == ',' || r == ' ' }) {
+			if !containsFold(tags, tag) {
+				tags = append(tags, tag)
+			}
+		}
+	}
+	return tags
+}
+
+// containsFold 切片中是否有忽略大小写相同的值
+func containsFold(values []string, value string) bool {
+	for _, v := range values {
+		if strings.EqualFold(v, value) {
+			return true
+		}
+	}
+	return false
+}
+
+// applyTags 为记录添加或移除标记
+func applyTags(record *InventoryRecord, add, remove []string) {
+	for _, tag := range add {
+		if !record.HasTag(tag) {
+			record.Tags = append(record.Tags, tag)
+		}
+	}
+	if len(remove) == 0 {
+		return
+	}
+	kept := record.Tags[:0]
+	for _, tag := range record.Tags {
+		if !containsFold(remove, tag) {
+			kept = append(kept, tag)
+		}
+	}
+	record.Tags = kept
+}
+
+// writeLedgerCSV 导出台账为 CSV
+func writeLedgerCSV(w io.Writer, records []InventoryRecord) error {
+	writer := csv.NewWriter(w)
+	writer.Write([]string{"hme", "anonymous_id", "label", "note", "site", "tags", "score", "source", "created_at", "deleted_at"})
+	for _, record := range records {
+		deleted := ""
+		if record.IsTombstone() {
+			deleted = time.UnixMilli(record.DeletedAt).Format(time.RFC3339)
+		}
+		score := ""
+		if record.Score > 0 {
+			score = strconv.Itoa(record.Score)
+		}
+		writer.Write([]string{
+			record.HME, record.AnonymousID, record.Label, record.Note, record.Site, strings.Join(record.Tags, ";"),
+			score, record.Source, time.UnixMilli(record.CreatedAt).Format(time.RFC3339), deleted,
+		})
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// writeLedger 按格式导出台账
+func writeLedger(w io.Writer, records []InventoryRecord, format string) error {
+	if format == "json" {
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(records)
+	}
+	return writeLedgerCSV(w, records)
+}
+
+// exportLedgerFile 导出台账到文件
+func exportLedgerFile(path string, records []InventoryRecord, format string) error {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return fmt.Errorf("创建导出文件失败: %v", err)
+	}
+	defer file.Close()
+	if err := writeLedger(file, records, format); err != nil {
+		return fmt.Errorf("写入导出文件失败: %v", err)
+	}
+	return nil
+}
+
+// runLedger 本地邮箱台账：ledger [关键字] 搜索，ledger tag / site / export 标记与导出
+func runLedger(args []string) error {
+	if inventory == nil {
+		return fmt.Errorf("本地清单不可用")
+	}
+	if len(args) > 0 {
+		switch args[0] {
+		case "tag":
+			return runLedgerTag(args[1:])
+		case "site":
+			return runLedgerSite(args[1:])
+		case "export":
+			return runLedgerExport(args[1:])
+		}
+	}
+
+	fs := flag.NewFlagSet("ledger", flag.ContinueOnError)
+	var filter LedgerFilter
+	fs.StringVar(&filter.Tag, "tag", "", "只显示带有该标记的邮箱")
+	fs.StringVar(&filter.Site, "site", "", "只显示使用网站包含该关键字的邮箱")
+	fs.BoolVar(&filter.DeletedOnly, "deleted", false, "只显示已彻底删除的邮箱")
+	if err := fs.Parse(args); err != nil {
+		return usageError(err)
+	}
+	filter.Keyword = strings.Join(fs.Args(), " ")
+	records := filterLedger(filter)
+
+	if outputJSON {
+		return writeLedger(dataOutput, records, "json")
+	}
+	printHeader("邮箱台账")
+	if len(records) == 0 {
+		printInfo("没有匹配的记录")
+		return nil
+	}
+	for _, record := range records {
+		printInventoryRecord("", record)
+	}
+	fmt.Println()
+	printInfo(fmt.Sprintf("共 %d 条记录", len(records)))
+	return nil
+}
+
+// runLedgerTag 添加或移除标记：ledger tag [-remove] 邮箱或anonymous_id 标记...
+func runLedgerTag(args []string) error {
+	fs := flag.NewFlagSet("ledger tag", flag.ContinueOnError)
+	remove := fs.Bool("remove", false, "移除标记")
+	if err := fs.Parse(args); err != nil {
+		return usageError(err)
+	}
+	if fs.NArg() < 2 {
+		return usageError(fmt.Errorf("用法: ledger tag [-remove] 邮箱或anonymous_id 标记..."))
+	}
+	tags := parseTags(fs.Args()[1:]...)
+	record, err := inventory.Annotate(fs.Arg(0), func(record *InventoryRecord) {
+		if *remove {
+			applyTags(record, nil, tags)
+		} else {
+			applyTags(record, tags, nil)
+		}
+	})
+	if err != nil {
+		return err
+	}
+	printSuccess(fmt.Sprintf("%s 的标记: %s", record.HME, orDash(strings.Join(record.Tags, ", "))))
+	return nil
+}
+
+// runLedgerSite 记录邮箱用于哪个网站：ledger site 邮箱或anonymous_id 网站（传入空字符串清除）
+func runLedgerSite(args []string) error {
+	if len(args) != 2 {
+		return usageError(fmt.Errorf("用法: ledger site 邮箱或anonymous_id 网站"))
+	}
+	site := strings.TrimSpace(args[1])
+	record, err := inventory.Annotate(args[0], func(record *InventoryRecord) {
+		record.Site = site
+	})
+	if err != nil {
+		return err
+	}
+	printSuccess(fmt.Sprintf("%s 的使用网站: %s", record.HME, orDash(record.Site)))
+	return nil
+}
+
+// runLedgerExport 导出台账：ledger export [-format csv|json] [-o 文件] [-tag 标记] [-site 网站] [关键字]
+func runLedgerExport(args []string) error {
+	fs := flag.NewFlagSet("ledger export", flag.ContinueOnError)
+	format := fs.String("format", "csv", "导出格式：csv 或 json")
+	output := fs.String("o", "-", "导出文件（- 表示输出到标准输出）")
+	var filter LedgerFilter
+	fs.StringVar(&filter.Tag, "tag", "", "只导出带有该标记的邮箱")
+	fs.StringVar(&filter.Site, "site", "", "只导出使用网站包含该关键字的邮箱")
+	if err := fs.Parse(args); err != nil {
+		return usageError(err)
+	}
+	if *format != "csv" && *format != "json" {
+		return usageError(fmt.Errorf("-format 只支持 csv 或 json"))
+	}
+	filter.Keyword = strings.Join(fs.Args(), " ")
+	records := filterLedger(filter)
+
+	if *output == "-" {
+		return writeLedger(dataOutput, records, *format)
+	}
+	if err := exportLedgerFile(*output, records, *format); err != nil {
+		return err
+	}
+	printSuccess(fmt.Sprintf("已导出 %d 条记录到 %s", len(records), *output))
+	return nil
+}
+
+// SyncReport sync 命令的 JSON 输出
+type SyncReport struct {
+	InventoryDiff
+	Pulled bool `json:"pulled"`
+}
+
+// fetchInventoryDiff 获取 iCloud 列表并与本地台账比较
+func fetchInventoryDiff(config *Config) ([]HMEEmail, InventoryDiff, error) {
+	var emails []HMEEmail
+	if err := withSpinner("获取邮箱列表", func() error {
+		var err error
+		emails, err = listHME(config)
+		return err
+	}); err != nil {
+		return nil, InventoryDiff{}, fmt.Errorf("获取列表失败: %w", err)
+	}
+	return emails, inventory.Diff(emails), nil
+}
+
+// printInventoryDiff 按类别输出本地台账与 iCloud 的差异
+func printInventoryDiff(diff InventoryDiff) {
+	if diff.Empty() {
+		printSuccess("本地台账与 iCloud 一致")
+		return
+	}
+	if len(diff.Unknown) > 0 {
+		printSubHeader(fmt.Sprintf("在本工具之外创建 (%d)", len(diff.Unknown)))
+		for _, email := range diff.Unknown {
+			fmt.Printf("  "+ColorGreen+"+"+ColorReset+" %s "+ColorCyan+"%s"+ColorReset+" "+ColorDim+"创建 %s"+ColorReset+"\n", email.HME, email.Label, formatMillis(email.CreateTimestamp))
+		}
+	}
+	if len(diff.Deleted) > 0 {
+		printSubHeader(fmt.Sprintf("已在其他地方删除 (%d)", len(diff.Deleted)))
+		for _, record := range diff.Deleted {
+			fmt.Printf("  "+ColorRed+"-"+ColorReset+" %s "+ColorCyan+"%s"+ColorReset+" "+ColorDim+"创建 %s"+ColorReset+"\n", record.HME, record.Label, formatMillis(record.CreatedAt))
+		}
+	}
+	if len(diff.Restored) > 0 {
+		printSubHeader(fmt.Sprintf("本地标记为已删除、iCloud 中仍存在 (%d)", len(diff.Restored)))
+		for _, record := range diff.Restored {
+			fmt.Printf("  "+ColorYellow+"!"+ColorReset+" %s "+ColorCyan+"%s"+ColorReset+"\n", record.HME, record.Label)
+		}
+	}
+	if len(diff.LabelMismatches) > 0 {
+		printSubHeader(fmt.Sprintf("标签不一致 (%d)", len(diff.LabelMismatches)))
+		for _, mismatch := range diff.LabelMismatches {
+			fmt.Printf("  "+ColorYellow+"~"+ColorReset+" %s  本地 %s "+ColorDim+"→"+ColorReset+" iCloud "+ColorBrightGreen+"%s"+ColorReset+"\n", mismatch.HME, displayOrEmpty(mismatch.Local), displayOrEmpty(mismatch.Remote))
+		}
+	}
+}
+
+// runSync 比较本地台账与 iCloud 列表：sync [-pull]，-pull 时用 iCloud 的标签更新本地，并登记新邮箱、标记已删除的邮箱
+func runSync(config *Config, args []string) error {
+	fs := flag.NewFlagSet("sync", flag.ContinueOnError)
+	pull := fs.Bool("pull", false, "用 iCloud 的标签与备注更新本地台账，登记在本工具之外创建的邮箱，并把已删除的邮箱标记为墓碑")
+	if err := fs.Parse(args); err != nil {
+		return usageError(err)
+	}
+	if inventory == nil {
+		return fmt.Errorf("本地清单不可用")
+	}
+
+	emails, diff, err := fetchInventoryDiff(config)
+	if err != nil {
+		return err
+	}
+	if *pull && !diff.Empty() {
+		if err := inventory.Sync(emails); err != nil {
+			return err
+		}
+	}
+	if outputJSON {
+		return writeJSON(SyncReport{InventoryDiff: diff, Pulled: *pull && !diff.Empty()})
+	}
+
+	printHeader("本地台账与 iCloud 对比")
+	printInventoryDiff(diff)
+	if diff.Empty() {
+		return nil
+	}
+	fmt.Println()
+	if *pull {
+		printSuccess("已用 iCloud 的列表更新本地台账")
+	} else {
+		printInfo("以上仅为对比结果，未修改本地台账；加上 -pull 用 iCloud 的标签更新本地")
+	}
+	return nil
+}
+
+// handleLedgerSync 菜单中对比本地台账与 iCloud，确认后拉取远端标签
+func handleLedgerSync(config *Config) {
+	emails, diff, err := fetchInventoryDiff(config)
+	if err != nil {
+		printError(err.Error())
+		return
+	}
+	printInventoryDiff(diff)
+	if diff.Empty() {
+		return
+	}
+	fmt.Println()
+	if !confirmAction("用 iCloud 的标签更新本地台账（同时登记新邮箱、标记已删除的邮箱）") {
+		printInfo("未修改本地台账")
+		return
+	}
+	if err := inventory.Sync(emails); err != nil {
+		printError(err.Error())
+		return
+	}
+	printSuccess("已更新本地台账")
+}
+
+// handleLedger 菜单中搜索台账，为邮箱记录使用网站与标记，或导出搜索结果
+func handleLedger(config *Config) {
+	if inventory == nil {
+		printError("本地清单不可用")
+		return
+	}
+	keyword := ""
+	for {
+		printHeader("邮箱台账")
+		records := filterLedger(LedgerFilter{Keyword: keyword})
+		if keyword != "" {
+			fmt.Printf("  "+ColorCyan+"关键字:"+ColorReset+" %s\n\n", keyword)
+		}
+		if len(records) == 0 {
+			printInfo("没有匹配的记录")
+		}
+		for i, record := range records {
+			printInventoryRecord(fmt.Sprintf(ColorDim+"%2d."+ColorReset+" ", i+1), record)
+		}
+		fmt.Println()
+		printInfo("输入序号编辑网站与标记，/关键字 搜索（可搜索地址、标签、备注、网站和标记），e 导出当前结果，s 与 iCloud 对比")
+		input := strings.TrimSpace(readInput("操作 " + ColorGray + "(回车返回)" + ColorReset + ": "))
+		switch {
+		case input == "":
+			return
+		case strings.HasPrefix(input, "/"):
+			keyword = strings.TrimSpace(input[1:])
+		case strings.EqualFold(input, "e"):
+			exportLedgerInteractive(records)
+		case strings.EqualFold(input, "s"):
+			handleLedgerSync(config)
+		default:
+			index, err := strconv.Atoi(input)
+			if err != nil || index < 1 || index > len(records) {
+				printError("无效的序号")
+				continue
+			}
+			editLedgerRecord(records[index-1])
+		}
+	}
+}
+
+// editLedgerRecord 修改一条记录的使用网站与标记
+func editLedgerRecord(record InventoryRecord) {
+	fmt.Printf("  "+ColorCyan+"当前网站:"+ColorReset+" %s\n", displayOrEmpty(record.Site))
+	fmt.Printf("  "+ColorCyan+"当前标记:"+ColorReset+" %s\n", displayOrEmpty(strings.Join(record.Tags, ", ")))
+	siteInput := readInput("使用网站 " + ColorGray + "(回车保持不变，输入 - 清空)" + ColorReset + ": ")
+	tagInput := readInput("标记 " + ColorGray + "(逗号分隔，前加 - 表示移除，如 shop,-old)" + ColorReset + ": ")
+
+	var add, remove []string
+	for _, tag := range parseTags(tagInput) {
+		if strings.HasPrefix(tag, "-") {
+			remove = append(remove, strings.TrimPrefix(tag, "-"))
+		} else {
+			add = append(add, tag)
+		}
+	}
+	site := strings.TrimSpace(siteInput)
+	if site == "" && len(add) == 0 && len(remove) == 0 {
+		printInfo("没有需要修改的内容")
+		return
+	}
+	updated, err := inventory.Annotate(record.HME, func(r *InventoryRecord) {
+		switch site {
+		case "":
+		case clearNoteInput:
+			r.Site = ""
+		default:
+			r.Site = site
+		}
+		applyTags(r, add, remove)
+	})
+	if err != nil {
+		printError(err.Error())
+		return
+	}
+	printSuccess(fmt.Sprintf("已更新 %s", updated.HME))
+}
+
+// exportLedgerInteractive 菜单中导出当前搜索结果
+func exportLedgerInteractive(records []InventoryRecord) {
+	if len(records) == 0 {
+		printInfo("没有可导出的记录")
+		return
+	}
+	fallback := "hme-ledger-" + time.Now().Format("20060102") + ".csv"
+	path := strings.TrimSpace(readInput("导出文件 " + ColorGray + "(回车使用 " + fallback + "，扩展名为 .json 时导出 JSON)" + ColorReset + ": "))
+	if path == "" {
+		path = fallback
+	}
+	format := "csv"
+	if strings.HasSuffix(strings.ToLower(path), ".json") {
+		format = "json"
+	}
+	if err := exportLedgerFile(path, records, format); err != nil {
+		printError(err.Error())
+		return
+	}
+	printSuccess(fmt.Sprintf("已导出 %d 条记录到 %s", len(records), path))
+}
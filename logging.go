@@ -0,0 +1,282 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// logFileStderr logging.file 设为该值时日志输出到标准错误
+const logFileStderr = "stderr"
+
+// 日志文件默认的轮转大小与保留数量
+const (
+	defaultLogMaxSizeMB  = 10
+	defaultLogMaxBackups = 5
+)
+
+// LoggingConfig 结构化日志（logging）：请求、重试、限流与失败等诊断信息写入日志文件，不与界面输出混在一起，
+// 便于事后分析夜间的批量任务。Cookie、dsid 等账号信息写入前自动隐去
+type LoggingConfig struct {
+	File       string `json:"file"`        // 日志文件路径，留空表示不记录，stderr 表示输出到标准错误
+	Level      string `json:"level"`       // debug、info（默认）、warn、error；debug 记录每个接口请求
+	Format     string `json:"format"`      // text（默认，key=value）或 json（每行一个 JSON 对象）
+	MaxSizeMB  int    `json:"max_size_mb"` // 日志文件超过该大小时轮转
+	MaxBackups int    `json:"max_backups"` // 保留的旧日志数量，<文件名>.1 为最近的一份
+}
+
+var (
+	discardLogger = slog.New(slog.DiscardHandler)
+	activeLogger  atomic.Pointer[slog.Logger]
+
+	logOutputMutex sync.Mutex
+	logOutput      io.Closer // 当前打开的日志文件，重新配置或退出时关闭
+)
+
+// logger 当前的日志记录器，未配置 logging.file 时丢弃所有记录
+func logger() *slog.Logger {
+	if l := activeLogger.Load(); l != nil {
+		return l
+	}
+	return discardLogger
+}
+
+// setupLogging 按 logging 配置（重新）创建日志记录器，启动时与热重载时调用
+func setupLogging(config *Config) error {
+	settings := config.Logging
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(settings.Level)); err != nil {
+		return fmt.Errorf("logging.level 无效: %s（可用 debug、info、warn、error）", settings.Level)
+	}
+
+	var writer io.Writer
+	var closer io.Closer
+	switch path := strings.TrimSpace(settings.File); {
+	case path == "":
+	case strings.EqualFold(path, logFileStderr):
+		writer = os.Stderr
+	default:
+		file, err := openRotatingFile(path, int64(settings.MaxSizeMB)<<20, settings.MaxBackups)
+		if err != nil {
+			return fmt.Errorf("打开日志文件失败: %v", err)
+		}
+		writer, closer = file, file
+	}
+
+	var next *slog.Logger
+	if writer != nil {
+		options := &slog.HandlerOptions{Level: level, ReplaceAttr: redactLogAttr}
+		if settings.Format == OutputFormatJSON {
+			next = slog.New(slog.NewJSONHandler(writer, options))
+		} else {
+			next = slog.New(slog.NewTextHandler(writer, options))
+		}
+	}
+
+	logOutputMutex.Lock()
+	defer logOutputMutex.Unlock()
+	activeLogger.Store(next)
+	if logOutput != nil {
+		logOutput.Close()
+	}
+	logOutput = closer
+	return nil
+}
+
+// closeLogging 退出前关闭日志文件
+func closeLogging() {
+	logOutputMutex.Lock()
+	defer logOutputMutex.Unlock()
+	activeLogger.Store(nil)
+	if logOutput != nil {
+		logOutput.Close()
+		logOutput = nil
+	}
+}
+
+// logFailure 记录失败，附带失败归类与 Apple 的 errorCode、状态码，便于按原因筛选
+func logFailure(level slog.Level, message string, err error, args ...any) {
+	if !logger().Enabled(context.Background(), level) {
+		return
+	}
+	info := newCLIError(err)
+	args = append(args, "error", err, "class", info.Class)
+	if info.ErrorCode != "" {
+		args = append(args, "error_code", info.ErrorCode)
+	}
+	if info.HTTPStatus != 0 {
+		args = append(args, "http_status", info.HTTPStatus)
+	}
+	logger().Log(context.Background(), level, message, args...)
+}
+
+// 日志中整个值都要隐去的字段名（不区分大小写，包含即算）
+var sensitiveLogKeys = []string{"cookie", "dsid", "authorization", "password", "passphrase", "secret", "token"}
+
+// 文本中常见的账号信息：URL 查询参数中的 dsid 与请求头形式的 Cookie
+var (
+	dsidPattern   = regexp.MustCompile(`(?i)(dsid=)[^&\s"']+`)
+	cookiePattern = regexp.MustCompile(`(?i)(cookie["']?\s*[:=]\s*["']?)[^"'\n]+`)
+)
+
+// redactedLogValue 替换被隐去内容的占位符
+const redactedLogValue = "***"
+
+// redactLogAttr 写入日志前隐去账号信息：敏感字段名的值整个替换，其余文本中出现的 dsid、Cookie 值逐一替换
+func redactLogAttr(groups []string, attr slog.Attr) slog.Attr {
+	if attr.Key != slog.MessageKey && attr.Key != slog.TimeKey && attr.Key != slog.LevelKey {
+		lower := strings.ToLower(attr.Key)
+		for _, key := range sensitiveLogKeys {
+			if strings.Contains(lower, key) {
+				return slog.String(attr.Key, redactedLogValue)
+			}
+		}
+	}
+	switch attr.Value.Kind() {
+	case slog.KindString:
+		attr.Value = slog.StringValue(redactLogText(attr.Value.String()))
+	case slog.KindAny:
+		if err, ok := attr.Value.Any().(error); ok {
+			attr.Value = slog.StringValue(redactLogText(err.Error()))
+		} else if s, ok := attr.Value.Any().(fmt.Stringer); ok {
+			attr.Value = slog.StringValue(redactLogText(s.String()))
+		}
+	}
+	return attr
+}
+
+// redactLogText 隐去文本中的 dsid 与 Cookie
+func redactLogText(text string) string {
+	text = dsidPattern.ReplaceAllString(text, "${1}"+redactedLogValue)
+	text = cookiePattern.ReplaceAllString(text, "${1}"+redactedLogValue)
+	for _, secret := range logRedactor.secrets(getCurrentConfig()) {
+		text = strings.ReplaceAll(text, secret, redactedLogValue)
+	}
+	return text
+}
+
+// logSecrets 当前账号需要从日志中隐去的值（dsid 与各个 Cookie 的值），配置变化时重新提取
+type logSecrets struct {
+	mutex  sync.Mutex
+	config *Config
+	values []string
+}
+
+var logRedactor = &logSecrets{}
+
+// 短于该长度的 Cookie 值（如 true、en-US）不是凭据，替换反而会让日志难以阅读
+const minLogSecretLength = 8
+
+func (s *logSecrets) secrets(config *Config) []string {
+	if config == nil {
+		return nil
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.config == config {
+		return s.values
+	}
+	s.config, s.values = config, nil
+	if config.DSID != "" {
+		s.values = append(s.values, config.DSID)
+	}
+	for _, part := range strings.Split(config.Headers["Cookie"], ";") {
+		if _, value, ok := strings.Cut(part, "="); ok && len(strings.Trim(value, `" `)) >= minLogSecretLength {
+			s.values = append(s.values, strings.Trim(value, `" `))
+		}
+	}
+	return s.values
+}
+
+// rotatingFile 按大小轮转的日志文件：写入后超过 maxSize 时，<文件名>.1 … .N 依次后移，当前文件改名为 .1
+type rotatingFile struct {
+	mutex      sync.Mutex
+	path       string
+	maxSize    int64
+	maxBackups int
+	file       *os.File
+	size       int64
+}
+
+// openRotatingFile 以追加方式打开日志文件（权限 0600，日志中有邮箱地址与标签）
+func openRotatingFile(path string, maxSize int64, maxBackups int) (*rotatingFile, error) {
+	f := &rotatingFile{path: path, maxSize: maxSize, maxBackups: maxBackups}
+	if dir := filepath.Dir(path); dir != "." {
+		if err := os.MkdirAll(dir, 0700); err != nil {
+			return nil, err
+		}
+	}
+	if err := f.open(); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+func (f *rotatingFile) open() error {
+	file, err := os.OpenFile(f.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+	f.file, f.size = file, info.Size()
+	return nil
+}
+
+func (f *rotatingFile) Write(p []byte) (int, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	if f.file == nil {
+		return 0, os.ErrClosed
+	}
+	if f.maxSize > 0 && f.size > 0 && f.size+int64(len(p)) > f.maxSize {
+		if err := f.rotate(); err != nil {
+			return 0, err
+		}
+	}
+	n, err := f.file.Write(p)
+	f.size += int64(n)
+	return n, err
+}
+
+// rotate 轮转日志文件，超出 maxBackups 的最旧一份被删除
+func (f *rotatingFile) rotate() error {
+	f.file.Close()
+	f.file = nil
+	if f.maxBackups <= 0 {
+		os.Remove(f.path)
+	} else {
+		os.Remove(fmt.Sprintf("%s.%d", f.path, f.maxBackups))
+		for i := f.maxBackups - 1; i >= 1; i-- {
+			if err := os.Rename(fmt.Sprintf("%s.%d", f.path, i), fmt.Sprintf("%s.%d", f.path, i+1)); err != nil && !errors.Is(err, os.ErrNotExist) {
+				return err
+			}
+		}
+		if err := os.Rename(f.path, f.path+".1"); err != nil {
+			return err
+		}
+	}
+	return f.open()
+}
+
+func (f *rotatingFile) Close() error {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	if f.file == nil {
+		return nil
+	}
+	err := f.file.Close()
+	f.file = nil
+	return err
+}
@@ -0,0 +1,216 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"html"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// MailMessage 转发邮件的解析结果
+type MailMessage struct {
+	UID     uint32
+	From    string
+	To      string
+	Subject string
+	Date    time.Time
+	Text    string // 纯文本正文（HTML 会被去除标签）
+	Links   []string
+}
+
+var (
+	mailLinkPattern    = regexp.MustCompile(`https?://[^\s"'<>()]+`)
+	mailHTMLTagPattern = regexp.MustCompile(`(?s)<(script|style)[^>]*>.*?</(script|style)>|<[^>]+>`)
+	mailSpacePattern   = regexp.MustCompile(`[ \t]+`)
+	mailBlankPattern   = regexp.MustCompile(`\n{3,}`)
+
+	// 验证链接中常见的关键字
+	verificationLinkKeywords = []string{"verify", "verification", "confirm", "activate", "validate", "token", "magic", "signup", "register", "auth"}
+
+	// 验证码：关键字附近的 4-8 位数字或大写字母数字组合
+	verificationCodePattern = regexp.MustCompile(`(?:\b(?i:code|otp|pin|passcode|one-time)\b|验证码|校验码|动态码)[^\n0-9]{0,30}?\b([0-9]{4,8}|[0-9A-Z]{6,8})\b`)
+	mailDigitPattern        = regexp.MustCompile(`[0-9]`)
+)
+
+// parseMailMessage 解析邮件原文
+func parseMailMessage(uid uint32, raw []byte) (*MailMessage, error) {
+	msg, err := mail.ReadMessage(bytes.NewReader(raw))
+	if err != nil {
+		return nil, fmt.Errorf("解析邮件失败: %v", err)
+	}
+
+	decoder := new(mime.WordDecoder)
+	decodeHeader := func(name string) string {
+		value := msg.Header.Get(name)
+		if decoded, err := decoder.DecodeHeader(value); err == nil {
+			return decoded
+		}
+		return value
+	}
+
+	message := &MailMessage{
+		UID:     uid,
+		From:    decodeHeader("From"),
+		To:      decodeHeader("To"),
+		Subject: decodeHeader("Subject"),
+	}
+	if date, err := msg.Header.Date(); err == nil {
+		message.Date = date
+	}
+
+	plain, htmlText := extractMailBodies(msg.Header.Get("Content-Type"), msg.Header.Get("Content-Transfer-Encoding"), msg.Body)
+	switch {
+	case plain != "":
+		message.Text = plain
+	case htmlText != "":
+		message.Text = htmlToText(htmlText)
+	}
+	message.Links = uniqueStrings(mailLinkPattern.FindAllString(plain+"\n"+html.UnescapeString(htmlText), -1))
+	return message, nil
+}
+
+// extractMailBodies 递归提取 text/plain 与 text/html 正文
+func extractMailBodies(contentType, encoding string, body io.Reader) (plain, htmlText string) {
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		mediaType = "text/plain"
+	}
+
+	if strings.HasPrefix(mediaType, "multipart/") {
+		reader := multipart.NewReader(body, params["boundary"])
+		for {
+			part, err := reader.NextRawPart()
+			if err != nil {
+				break
+			}
+			p, h := extractMailBodies(part.Header.Get("Content-Type"), part.Header.Get("Content-Transfer-Encoding"), part)
+			if plain == "" {
+				plain = p
+			}
+			if htmlText == "" {
+				htmlText = h
+			}
+		}
+		return plain, htmlText
+	}
+
+	switch strings.ToLower(strings.TrimSpace(encoding)) {
+	case "base64":
+		body = base64.NewDecoder(base64.StdEncoding, body)
+	case "quoted-printable":
+		body = quotedprintable.NewReader(body)
+	}
+	data, err := io.ReadAll(io.LimitReader(body, 1<<20))
+	if err != nil && len(data) == 0 {
+		return "", ""
+	}
+
+	switch mediaType {
+	case "text/plain":
+		return string(data), ""
+	case "text/html":
+		return "", string(data)
+	}
+	return "", ""
+}
+
+// htmlToText 粗略地将 HTML 转为纯文本
+func htmlToText(source string) string {
+	source = strings.NewReplacer("<br>", "\n", "<br/>", "\n", "<br />", "\n", "</p>", "\n", "</div>", "\n", "</tr>", "\n").Replace(source)
+	text := html.UnescapeString(mailHTMLTagPattern.ReplaceAllString(source, " "))
+	text = mailSpacePattern.ReplaceAllString(text, " ")
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimSpace(line)
+	}
+	return strings.TrimSpace(mailBlankPattern.ReplaceAllString(strings.Join(lines, "\n"), "\n\n"))
+}
+
+// VerificationLinks 返回疑似验证/确认链接
+func (m *MailMessage) VerificationLinks() []string {
+	var links []string
+	for _, link := range m.Links {
+		lower := strings.ToLower(link)
+		for _, keyword := range verificationLinkKeywords {
+			if strings.Contains(lower, keyword) {
+				links = append(links, link)
+				break
+			}
+		}
+	}
+	return links
+}
+
+// VerificationCodes 返回疑似验证码
+func (m *MailMessage) VerificationCodes() []string {
+	var codes []string
+	for _, source := range []string{m.Subject, m.Text} {
+		for _, match := range verificationCodePattern.FindAllStringSubmatch(source, -1) {
+			// 纯字母的组合多半是普通单词
+			if mailDigitPattern.MatchString(match[1]) {
+				codes = append(codes, match[1])
+			}
+		}
+	}
+	return uniqueStrings(codes)
+}
+
+// fetchMessagesTo 读取发往指定地址、且不早于 since 的邮件（按时间倒序）
+func fetchMessagesTo(settings IMAPConfig, address string, since time.Time, timeout time.Duration) ([]*MailMessage, error) {
+	client, err := dialIMAP(settings, timeout)
+	if err != nil {
+		return nil, err
+	}
+	defer client.Close()
+
+	uids, err := client.SearchTo(address, since, timeout)
+	if err != nil {
+		return nil, fmt.Errorf("搜索邮件失败: %v", err)
+	}
+
+	var messages []*MailMessage
+	for _, uid := range uids {
+		raw, err := client.FetchRaw(uid, timeout)
+		if err != nil {
+			return nil, fmt.Errorf("读取邮件失败: %v", err)
+		}
+		message, err := parseMailMessage(uid, raw)
+		if err != nil {
+			continue
+		}
+		// SEARCH SINCE 只精确到天，这里再按时间过滤
+		if !message.Date.IsZero() && message.Date.Before(since) {
+			continue
+		}
+		messages = append(messages, message)
+	}
+
+	sort.SliceStable(messages, func(i, j int) bool {
+		return messages[i].UID > messages[j].UID
+	})
+	return messages, nil
+}
+
+// uniqueStrings 去重并保持顺序
+func uniqueStrings(values []string) []string {
+	seen := make(map[string]bool, len(values))
+	var result []string
+	for _, value := range values {
+		value = strings.TrimRight(value, ".,;")
+		if value == "" || seen[value] {
+			continue
+		}
+		seen[value] = true
+		result = append(result, value)
+	}
+	return result
+}
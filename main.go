@@ -26,30 +26,35 @@ package main
 
 import (
 	"bufio"
-	"bytes"
-	"compress/gzip"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"io/fs"
+	"log/slog"
 	"net"
 	"net/http"
-	"net/url"
 	"os"
 	"os/signal"
-	"path"
+	"path/filepath"
+	"runtime/debug"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"syscall"
 	"time"
-	"unsafe"
 
 	"github.com/fsnotify/fsnotify"
 )
 
 // Config 配置结构体
 type Config struct {
+	// 配置格式版本，旧版本的配置在加载时自动升级（见 configmigrate.go）
+	ConfigVersion int `json:"config_version"`
+
 	// API基础配置
 	BaseURL               string `json:"base_url"`
 	ClientBuildNumber     string `json:"client_build_number"`
@@ -61,24 +66,66 @@ type Config struct {
 	Headers map[string]string `json:"headers"`
 
 	// 请求体配置
-	LangCode string `json:"lang_code"`
+	LangCode string `json:"lang_code"` // 生成邮箱使用的语言，auto 或留空时按系统语言
 
 	// 批量生成配置
 	Count        int `json:"count"`
 	DelaySeconds int `json:"delay_seconds"`
 
+	// 创建请求的全局限速（generate 与 reserve 各算一次），所有并发任务、智能创建与服务模式共享，0 表示不限制
+	RequestsPerMinute    int `json:"requests_per_minute"`
+	RequestJitterPercent int `json:"request_jitter_percent"` // 每次等待额外附加 0~N% 请求间隔的随机抖动
+
+	// 接口请求的重试策略：网络错误与临时性状态码的重试次数、退避与抖动，以及批量创建被限流后的重试
+	RetryPolicy RetryPolicyConfig `json:"retry_policy"`
+
+	// 会话令牌临近过期时调用 validate 接口刷新，并把轮换后的 Cookie 写回配置
+	SessionRefresh SessionRefreshConfig `json:"session_refresh"`
+
+	// 被限流后在状态目录记录冷却截止时间，重启后仍拒绝创建直到冷却结束；Apple 未给出 retryAfter 时使用该分钟数
+	RateLimitCooldownMinutes int `json:"rate_limit_cooldown_minutes"`
+
+	// reserve 滚动耗时监控：变慢往往是即将被限流的信号，超过阈值时警告并可让批量创建主动放慢
+	LatencySLO LatencySLOConfig `json:"latency_slo"`
+
+	// 批量结束、限流暂停开始/结束与出错时的终端提示音
+	SoundCues SoundCuesConfig `json:"sound_cues"`
+
+	// 通知渠道（桌面、Webhook、Telegram、邮件、MQTT）及按事件的路由规则
+	Notifications NotificationsConfig `json:"notifications"`
+
+	// 结构化日志：级别、格式、日志文件与轮转，账号信息自动隐去
+	Logging LoggingConfig `json:"logging"`
+
+	// 纯文本界面：无颜色、无动画、固定 80 列、不清屏，启动时读取（见 setupPlainUI）
+	PlainUI bool `json:"plain_ui"`
+
+	// 分段创建：每成功创建 batch_chunk_size 个后暂停 batch_chunk_pause_minutes 分钟，0 表示不分段
+	BatchChunkSize         int `json:"batch_chunk_size"`
+	BatchChunkPauseMinutes int `json:"batch_chunk_pause_minutes"`
+
 	// 并发配置
 	MaxConcurrency int `json:"max_concurrency"` // 最大并发数，0表示串行
 
 	// 邮箱标签配置
 	LabelPrefix string `json:"label_prefix"` // 标签前缀，会自动加上序号
 
+	// 标签模板，如 shop-{{date:2006-01}}-{{n}}，菜单批量创建的模板模式默认使用
+	LabelTemplate string `json:"label_template"`
+
+	// 批量创建时按权重轮换的标签前缀，如 shop- ×3、news- ×1
+	LabelPrefixWeights []PrefixWeight `json:"label_prefix_weights"`
+
 	// 输出配置
-	OutputFile string `json:"output_file"`
+	OutputFile   string `json:"output_file"`
+	OutputFormat string `json:"output_format"` // 子命令输出格式：text（默认）或 json
 
 	// 网络配置
-	TimeoutSeconds int    `json:"timeout_seconds"`
-	UserAgent      string `json:"user_agent"`
+	TimeoutSeconds   int    `json:"timeout_seconds"`
+	ProxyURL         string `json:"proxy_url"` // 访问 iCloud 的代理（http、https、socks5，可带用户名与口令），留空时遵循 HTTPS_PROXY 等环境变量，direct 表示直连
+	UserAgent        string `json:"user_agent"`
+	UserAgentPreset  string `json:"user_agent_preset"`  // 内置浏览器标识，如 chrome-mac、safari-mac，设置后覆盖 headers 中的 User-Agent
+	SkipSessionCheck bool   `json:"skip_session_check"` // 启动菜单时不检查会话是否有效（离线使用或想少发一次请求时开启）
 
 	// 邮箱质量评估配置
 	EmailQuality EmailQualityConfig `json:"email_quality"`
@@ -86,9 +133,41 @@ type Config struct {
 	// 邮箱保存配置
 	SaveGeneratedEmails bool   `json:"save_generated_emails"` // 是否保存生成的邮箱列表
 	EmailListFile       string `json:"email_list_file"`       // 邮箱列表保存文件
+	InventoryFile       string `json:"inventory_file"`        // 本地邮箱清单（bbolt 数据库，记录创建来源等元数据）
+	BatchJobFile        string `json:"batch_job_file"`        // 批量任务断点文件，用于中断后继续
+	RetryQueueFile      string `json:"retry_queue_file"`      // 因限流失败、等待重试的标签，下次启动时自动重试
+	StateDir            string `json:"state_dir"`             // 状态目录（存放进程锁），默认 ~/.local/state/icloud-hme
 
 	// 开发者模式
-	DeveloperMode bool `json:"developer_mode"` // 开发者模式，显示调试功能
+	Developer DeveloperConfig `json:"developer"` // 开发者模式与开发者工具：模拟服务器地址、功能开关与会话录制
+
+	// 守护进程模式：持久化的创建任务队列、安静时段与客户端连接地址
+	Daemon DaemonConfig `json:"daemon"`
+
+	// 服务模式配置
+	Serve ServeConfig `json:"serve"`
+
+	// 启动口令配置
+	AppLock AppLockConfig `json:"app_lock"`
+
+	// dsid 与 Cookie 的加密存储，使用 secrets encrypt 启用
+	EncryptedSecrets *SecretsEnvelope `json:"encrypted_secrets,omitempty"`
+
+	// 转发目标邮箱的 IMAP 配置（读取验证邮件）
+	IMAP IMAPConfig `json:"imap"`
+
+	// 发送测试邮件的 SMTP 配置
+	SMTP SMTPConfig `json:"smtp"`
+
+	// 危险操作确认配置
+	Safety SafetyConfig `json:"safety"`
+
+	// 多账号：按名称保存的账号配置，active_profile 为默认使用的账号（也可用 --profile 或菜单切换）
+	Profiles      map[string]*AccountProfile `json:"profiles,omitempty"`
+	ActiveProfile string                     `json:"active_profile,omitempty"`
+
+	profile     string          // 已应用的账号名，空表示顶层配置
+	profileBase *AccountProfile // 应用账号前的顶层账号字段，保存时还原
 
 	client     *http.Client
 	clientOnce sync.Once
@@ -101,6 +180,9 @@ type ConfigManager struct {
 	mutex      sync.RWMutex
 	callbacks  []func(*Config)
 	lastMod    time.Time
+	overrides  []appliedOverride // 环境变量与 --set 的覆盖，保存时还原
+	warnings   []string          // 配置文件中未知的配置项等警告
+	upgraded   string            // 本次加载时配置文件的版本升级说明
 }
 
 // ProcessSafetyManager 进程安全管理器
@@ -111,33 +193,34 @@ type ProcessSafetyManager struct {
 	ctx        context.Context
 	cancel     context.CancelFunc
 	operations sync.WaitGroup
-}
+	active     atomic.Int32 // 进行中的操作数，收到退出信号时据此决定先停止操作还是直接退出
 
-// NetworkManager 网络管理器
-type NetworkManager struct {
-	client     *http.Client
-	retryCount int
-	timeout    time.Duration
-	mutex      sync.Mutex
+	// 通过 Go 启动的后台任务（加载动画、配置监控等）：在上下文取消时退出，Shutdown 时等待
+	background sync.WaitGroup
+	tasksMutex sync.Mutex
+	tasks      map[string]int // 运行中的后台任务：名称 → 数量
 }
 
 // 全局管理器实例
 var (
-	configManager  *ConfigManager
-	safetyManager  *ProcessSafetyManager
-	networkManager *NetworkManager
-	globalConfig   *Config
-	configMutex    sync.RWMutex
+	configManager *ConfigManager
+	safetyManager *ProcessSafetyManager
+	globalConfig  *Config
+	configMutex   sync.RWMutex
 )
 
 // 程序常量
 const (
-	VERSION     = "v2.3.0"
-	AUTHOR      = "yuzeguitarist"
-	LOCK_FILE   = ".icloud_smart.lock"
-	CONFIG_FILE = "config.json"
+	VERSION   = "v2.3.0"
+	AUTHOR    = "yuzeguitarist"
+	LOCK_FILE = "icloud_smart.lock" // 位于状态目录的 locks/ 下
+
+	mainMenuPrompt = "选择操作 (0-9): "
 )
 
+// CONFIG_FILE 配置文件：依次查找 config.json、config.yaml、config.yml、config.toml，都不存在时为 config.json
+var CONFIG_FILE = findConfigFile()
+
 // EmailQualityConfig 邮箱质量评估配置
 type EmailQualityConfig struct {
 	// 自动选择配置
@@ -145,13 +228,46 @@ type EmailQualityConfig struct {
 	MinScore           int  `json:"min_score"`            // 最低接受分数 (0-100)
 	MaxRegenerateCount int  `json:"max_regenerate_count"` // 最大重新生成次数
 
+	// 候选池模式：一次生成 pool_size 个候选（默认 20，最多 100），按分数列出前 pool_top 个（默认 5）供选择
+	PoolSize int `json:"pool_size"`
+	PoolTop  int `json:"pool_top"`
+
+	// 地址前缀（@ 之前）的格式要求：必须匹配 pattern、不能匹配 reject_pattern（如 [0-9] 表示不含数字）。
+	// 候选都不符合时继续生成，最多 pattern_max_tries 个（默认 20）或 pattern_timeout_seconds 秒（默认 60）
+	Pattern               string `json:"pattern"`
+	RejectPattern         string `json:"reject_pattern"`
+	PatternMaxTries       int    `json:"pattern_max_tries"`
+	PatternTimeoutSeconds int    `json:"pattern_timeout_seconds"`
+
+	// 屏蔽词：前缀包含其中任一子串（不区分大小写）或匹配 /.../ 形式的正则时评分为 0，候选直接丢弃并重新生成
+	Blocklist []string `json:"blocklist"`
+
+	// 与本地清单中已有邮箱前缀相同或编辑距离小于 similar_distance（默认 2，-1 表示不检查）的候选，
+	// similar_action 为 reject（默认）时丢弃并重新生成，为 penalize 时扣 40 分
+	SimilarDistance int    `json:"similar_distance"`
+	SimilarAction   string `json:"similar_action"`
+
+	// 智能创建时轮流使用的语言，如 ["en-us", "ja-jp"]，从所有语言的候选中选出最高分；为空时只用 lang_code
+	CandidateLangCodes []string `json:"candidate_lang_codes"`
+
 	// 手动选择配置
-	ShowScores    bool `json:"show_scores"`     // 是否显示邮箱分数
-	AllowManual   bool `json:"allow_manual"`    // 是否允许手动选择
-	ShowAllEmails bool `json:"show_all_emails"` // 是否显示所有生成的邮箱
+	Selection SelectionConfig `json:"selection"`
 
 	// 评分权重配置
 	Weights ScoreWeights `json:"weights"`
+
+	// 可读性评分追加的词表文件（每行一个单词），与内置英文常用词表合并
+	WordlistFile string `json:"wordlist_file"`
+
+	// 自定义评分器（regex、words），与内置的四项一起按各自的 weight 加权平均
+	Scorers []ScorerConfig `json:"scorers"`
+}
+
+// SelectionConfig 候选邮箱的展示与手动选择配置
+type SelectionConfig struct {
+	ShowScores    bool `json:"show_scores"`     // 是否显示邮箱分数
+	AllowManual   bool `json:"allow_manual"`    // 是否允许手动选择
+	ShowAllEmails bool `json:"show_all_emails"` // 是否显示所有生成的邮箱
 }
 
 // ScoreWeights 评分权重配置
@@ -160,13 +276,15 @@ type ScoreWeights struct {
 	Length          int `json:"length"`           // 长度权重 (0-100)
 	Readability     int `json:"readability"`      // 可读性权重 (0-100)
 	Security        int `json:"security"`         // 安全性权重 (0-100)
+	Randomness      int `json:"randomness"`       // 自然度（不像随机字符串）权重 (0-100)
 }
 
 // EmailCandidate 邮箱候选项
 type EmailCandidate struct {
 	Email string `json:"email"`
 	Score int    `json:"score"`
-	ID    int    `json:"id"` // 生成顺序ID (1, 2, 3)
+	ID    int    `json:"id"`             // 生成顺序ID (1, 2, 3)
+	Lang  string `json:"lang,omitempty"` // 生成时使用的语言（配置了 candidate_lang_codes 时）
 }
 
 // ConfigManager 方法实现
@@ -184,19 +302,62 @@ func (cm *ConfigManager) LoadConfig() (*Config, error) {
 	cm.mutex.Lock()
 	defer cm.mutex.Unlock()
 
-	data, err := os.ReadFile(cm.configPath)
+	overrides, err := collectConfigOverrides()
 	if err != nil {
+		return nil, err
+	}
+	// 旧版本的配置文件先就地升级并备份原文件；无法写入时仍按升级后的内容使用
+	upgraded, upgradeErr := upgradeConfigFile(cm.configPath)
+
+	// 按扩展名解析 JSON、YAML 或 TOML，并按 Config 的结构校验，错误带行号
+	data, warnings, err := readConfigJSON(cm.configPath)
+	if errors.Is(err, fs.ErrNotExist) && len(overrides) > 0 {
+		// 没有配置文件时完全由环境变量与 --set 提供配置
+		data, err = []byte("{}"), nil
+	}
+	if errors.Is(err, fs.ErrNotExist) {
 		return nil, fmt.Errorf("读取配置文件失败: %v", err)
 	}
+	if err != nil {
+		return nil, fmt.Errorf("配置文件有误: %v", err)
+	}
+	if upgradeErr != nil {
+		warnings = append([]string{fmt.Sprintf("配置文件未能自动升级（%v），本次按升级后的内容使用", upgradeErr)}, warnings...)
+	}
+	cm.warnings, cm.upgraded = warnings, upgraded
 
 	var config Config
 	if err := json.Unmarshal(data, &config); err != nil {
 		return nil, fmt.Errorf("解析配置文件失败: %v", err)
 	}
 
+	if err := openConfigSecrets(&config); err != nil {
+		return nil, err
+	}
+
+	// 环境变量与 --set 覆盖文件中的值（在解密之后，保存时还原为解密后的文件值再加密）；
+	// 账号字段的覆盖在选中账号之后应用，优先于账号配置中的值
+	general, account := splitAccountOverrides(overrides)
+	applied, err := applyConfigOverrides(&config, general)
+	if err != nil {
+		return nil, err
+	}
+
 	// 设置默认值
 	cm.setDefaults(&config)
 
+	if err := applyProfile(&config); err != nil {
+		return nil, err
+	}
+	accountApplied, err := applyConfigOverrides(&config, account)
+	if err != nil {
+		return nil, err
+	}
+	cm.overrides = append(applied, accountApplied...)
+	if _, err := parseProxyURL(config.ProxyURL); err != nil {
+		return nil, configError("proxy_url 无效: %v", err)
+	}
+
 	cm.config = &config
 
 	// 获取文件修改时间
@@ -212,10 +373,22 @@ func (cm *ConfigManager) SaveConfig(config *Config) error {
 	cm.mutex.Lock()
 	defer cm.mutex.Unlock()
 
-	data, err := json.MarshalIndent(config, "", "  ")
+	if !configWritable(cm.configPath) {
+		return fmt.Errorf("%s 不是 JSON 格式，程序不会改写它（会丢失其中的注释），本次修改只在运行期间生效，请手动写入", cm.configPath)
+	}
+
+	out := config
+	if len(cm.overrides) > 0 {
+		out = config.clone()
+		restoreConfigOverrides(out, cm.overrides)
+	}
+	data, err := marshalConfigForSave(out)
 	if err != nil {
 		return fmt.Errorf("序列化配置失败: %v", err)
 	}
+	if data, err = sealConfigSecrets(data); err != nil {
+		return fmt.Errorf("保存配置失败: %v", err)
+	}
 
 	if err := os.WriteFile(cm.configPath, data, 0644); err != nil {
 		return fmt.Errorf("保存配置文件失败: %v", err)
@@ -256,6 +429,27 @@ func (cm *ConfigManager) GetConfig() *Config {
 	return cm.config
 }
 
+// AppliedOverrides 最近一次加载时应用的配置覆盖
+func (cm *ConfigManager) AppliedOverrides() []appliedOverride {
+	cm.mutex.RLock()
+	defer cm.mutex.RUnlock()
+	return cm.overrides
+}
+
+// UpgradeNotice 最近一次加载时配置文件的版本升级说明，没有升级时为空
+func (cm *ConfigManager) UpgradeNotice() string {
+	cm.mutex.RLock()
+	defer cm.mutex.RUnlock()
+	return cm.upgraded
+}
+
+// Warnings 最近一次加载配置文件时的警告
+func (cm *ConfigManager) Warnings() []string {
+	cm.mutex.RLock()
+	defer cm.mutex.RUnlock()
+	return cm.warnings
+}
+
 // AddCallback 添加配置更新回调
 func (cm *ConfigManager) AddCallback(callback func(*Config)) {
 	cm.mutex.Lock()
@@ -265,15 +459,59 @@ func (cm *ConfigManager) AddCallback(callback func(*Config)) {
 
 // setDefaults 设置默认值
 func (cm *ConfigManager) setDefaults(config *Config) {
+	// 旧版本的配置已在加载时升级；更新的程序写入的配置以当前版本保存
+	config.ConfigVersion = currentConfigVersion
 	if config.TimeoutSeconds == 0 {
 		config.TimeoutSeconds = 30
 	}
+	if config.BatchChunkPauseMinutes == 0 {
+		config.BatchChunkPauseMinutes = 30
+	}
 	if config.DelaySeconds == 0 {
 		config.DelaySeconds = 1
 	}
 	if config.Count == 0 {
 		config.Count = 1
 	}
+	if config.SessionRefresh.IntervalMinutes == 0 {
+		config.SessionRefresh.IntervalMinutes = defaultSessionRefreshMinutes
+	}
+	if config.RetryPolicy.MaxRetries == 0 {
+		config.RetryPolicy.MaxRetries = 2
+	}
+	if config.RetryPolicy.BaseDelayMS == 0 {
+		config.RetryPolicy.BaseDelayMS = 500
+	}
+	if config.RetryPolicy.MaxDelaySeconds == 0 {
+		config.RetryPolicy.MaxDelaySeconds = 10
+	}
+	if config.RetryPolicy.JitterPercent == 0 {
+		config.RetryPolicy.JitterPercent = 20
+	}
+	if config.RetryPolicy.RetryStatusCodes == nil {
+		config.RetryPolicy.RetryStatusCodes = []int{500, 502, 503, 504}
+	}
+	if config.RetryPolicy.RateLimitRetries == 0 {
+		config.RetryPolicy.RateLimitRetries = 3
+	}
+	if config.RetryPolicy.RateLimitBackoffSeconds == 0 {
+		config.RetryPolicy.RateLimitBackoffSeconds = 60
+	}
+	if config.LatencySLO.Window == 0 {
+		config.LatencySLO.Window = defaultLatencyWindow
+	}
+	if config.Logging.Level == "" {
+		config.Logging.Level = "info"
+	}
+	if config.Logging.Format == "" {
+		config.Logging.Format = "text"
+	}
+	if config.Logging.MaxSizeMB == 0 {
+		config.Logging.MaxSizeMB = defaultLogMaxSizeMB
+	}
+	if config.Logging.MaxBackups == 0 {
+		config.Logging.MaxBackups = defaultLogMaxBackups
+	}
 	if config.EmailQuality.MinScore == 0 {
 		config.EmailQuality.MinScore = 70
 	}
@@ -292,10 +530,49 @@ func (cm *ConfigManager) setDefaults(config *Config) {
 	if config.EmailQuality.Weights.Security == 0 {
 		config.EmailQuality.Weights.Security = 15
 	}
+	if config.EmailQuality.Weights.Randomness == 0 {
+		config.EmailQuality.Weights.Randomness = 20
+	}
 	if config.EmailListFile == "" {
 		config.EmailListFile = "generated_emails.txt"
 	}
-	// DeveloperMode 默认为 false，不需要设置
+	if config.InventoryFile == "" {
+		config.InventoryFile = "hme_inventory.db"
+	}
+	if config.BatchJobFile == "" {
+		config.BatchJobFile = "hme_batch_job.json"
+	}
+	if config.RetryQueueFile == "" {
+		config.RetryQueueFile = "hme_retry_queue.json"
+	}
+	if config.Safety.PermanentDelete.Phrase == "" {
+		config.Safety.PermanentDelete.Phrase = "DELETE"
+	}
+	if config.Safety.Deactivate.Phrase == "" {
+		config.Safety.Deactivate.Phrase = confirmPhraseDisabled
+	}
+	// Developer.Enabled 默认为 false，不需要设置
+	if config.Serve.ListenAddr == "" {
+		config.Serve.ListenAddr = "127.0.0.1:8787"
+	}
+	if config.Serve.RateLimitPerMinute == 0 {
+		config.Serve.RateLimitPerMinute = 30
+	}
+	if config.Serve.RateLimitBurst == 0 {
+		config.Serve.RateLimitBurst = 5
+	}
+	if config.Serve.MaxConcurrent == 0 {
+		config.Serve.MaxConcurrent = 2
+	}
+	if config.Serve.GlobalMaxConcurrent == 0 {
+		config.Serve.GlobalMaxConcurrent = 4
+	}
+	if config.Serve.CacheTTLSeconds == 0 {
+		config.Serve.CacheTTLSeconds = 60
+	}
+	if config.Daemon.PollSeconds == 0 {
+		config.Daemon.PollSeconds = 30
+	}
 }
 
 // ProcessSafetyManager 方法实现
@@ -307,6 +584,7 @@ func NewProcessSafetyManager() *ProcessSafetyManager {
 		lockFile: LOCK_FILE,
 		ctx:      ctx,
 		cancel:   cancel,
+		tasks:    make(map[string]int),
 	}
 }
 
@@ -318,21 +596,44 @@ func (psm *ProcessSafetyManager) Lock() error {
 	if psm.isLocked {
 		return nil
 	}
+	return psm.acquireLocked()
+}
 
-	// 检查锁文件是否存在
-	if _, err := os.Stat(psm.lockFile); err == nil {
-		// 读取PID
-		data, err := os.ReadFile(psm.lockFile)
-		if err == nil {
-			pid := strings.TrimSpace(string(data))
-			return fmt.Errorf("程序已在运行 (PID: %s)", pid)
-		}
+// acquireLocked 创建锁文件（调用方需持有 psm.mutex）
+func (psm *ProcessSafetyManager) acquireLocked() error {
+	if err := os.MkdirAll(filepath.Dir(psm.lockFile), 0700); err != nil {
+		return fmt.Errorf("创建锁目录失败: %v", err)
 	}
 
-	// 创建锁文件
-	pid := fmt.Sprintf("%d", os.Getpid())
-	if err := os.WriteFile(psm.lockFile, []byte(pid), 0644); err != nil {
-		return fmt.Errorf("创建锁文件失败: %v", err)
+	data, err := json.Marshal(currentLockInfo())
+	if err != nil {
+		return fmt.Errorf("生成锁文件内容失败: %v", err)
+	}
+
+	// 以独占方式创建锁文件，避免两个进程同时通过检查；残留锁清理后重试一次
+	for attempt := 0; ; attempt++ {
+		file, err := os.OpenFile(psm.lockFile, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+		if os.IsExist(err) {
+			info, readErr := readLockInfo(psm.lockFile)
+			if attempt == 0 && lockIsStale(psm.lockFile, info, readErr) {
+				if err := os.Remove(psm.lockFile); err != nil && !os.IsNotExist(err) {
+					return fmt.Errorf("清理残留锁文件失败: %v", err)
+				}
+				printWarning(fmt.Sprintf("已清理残留锁文件（PID %d 已不存在）", info.PID))
+				continue
+			}
+			return &LockHeldError{Path: psm.lockFile, Info: info}
+		}
+		if err != nil {
+			return fmt.Errorf("创建锁文件失败: %v", err)
+		}
+		_, err = file.Write(data)
+		file.Close()
+		if err != nil {
+			os.Remove(psm.lockFile)
+			return fmt.Errorf("写入锁文件失败: %v", err)
+		}
+		break
 	}
 
 	psm.isLocked = true
@@ -364,84 +665,120 @@ func (psm *ProcessSafetyManager) Unlock() error {
 // AddOperation 添加操作计数
 func (psm *ProcessSafetyManager) AddOperation() {
 	psm.operations.Add(1)
+	psm.active.Add(1)
 }
 
 // DoneOperation 完成操作计数
 func (psm *ProcessSafetyManager) DoneOperation() {
+	psm.active.Add(-1)
 	psm.operations.Done()
 }
 
+// Busy 是否有需要收尾的操作（子命令、批量创建）正在进行
+func (psm *ProcessSafetyManager) Busy() bool {
+	return psm.active.Load() > 0
+}
+
+// Cancel 取消上下文：进行中的请求立即返回，批量任务停止并输出已完成部分的汇总
+func (psm *ProcessSafetyManager) Cancel() {
+	psm.cancel()
+}
+
+// Canceled 是否已收到退出信号
+func (psm *ProcessSafetyManager) Canceled() bool {
+	return psm.ctx.Err() != nil
+}
+
 // Context 获取上下文
 func (psm *ProcessSafetyManager) Context() context.Context {
 	return psm.ctx
 }
 
-// NetworkManager 方法实现
-
-// NewNetworkManager 创建网络管理器
-func NewNetworkManager(timeout time.Duration, retryCount int) *NetworkManager {
-	return &NetworkManager{
-		timeout:    timeout,
-		retryCount: retryCount,
-		client: &http.Client{
-			Timeout: timeout,
-		},
+// Go 启动归安全管理器所有的后台任务：fn 应在 ctx 取消时尽快返回；panic 会被恢复并报告，不会让整个进程退出。
+// 管理器尚未初始化时直接启动，使用不会取消的上下文
+func (psm *ProcessSafetyManager) Go(name string, fn func(ctx context.Context)) {
+	if psm == nil {
+		go fn(context.Background())
+		return
 	}
-}
+	psm.background.Add(1)
+	psm.tasksMutex.Lock()
+	psm.tasks[name]++
+	psm.tasksMutex.Unlock()
 
-// GetClient 获取HTTP客户端
-func (nm *NetworkManager) GetClient() *http.Client {
-	nm.mutex.Lock()
-	defer nm.mutex.Unlock()
-	return nm.client
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				fmt.Fprintf(os.Stderr, ColorRed+"[!] 后台任务 %s %v"+ColorReset+"\n", name, recoveredPanic(r))
+			}
+			psm.tasksMutex.Lock()
+			if psm.tasks[name]--; psm.tasks[name] <= 0 {
+				delete(psm.tasks, name)
+			}
+			psm.tasksMutex.Unlock()
+			psm.background.Done()
+		}()
+		fn(psm.ctx)
+	}()
 }
 
-// DoWithRetry 带重试的HTTP请求（使用指数退避策略）
-func (nm *NetworkManager) DoWithRetry(req *http.Request) (*http.Response, error) {
-	var lastErr error
-	baseDelay := 500 * time.Millisecond // 基础延迟 500ms
+// BackgroundTasks 运行中的后台任务快照（名称 → 数量）
+func (psm *ProcessSafetyManager) BackgroundTasks() map[string]int {
+	psm.tasksMutex.Lock()
+	defer psm.tasksMutex.Unlock()
+	tasks := make(map[string]int, len(psm.tasks))
+	for name, n := range psm.tasks {
+		tasks[name] = n
+	}
+	return tasks
+}
 
-	for i := 0; i <= nm.retryCount; i++ {
-		if i > 0 {
-			// 指数退避: 500ms, 1s, 2s, 4s, 8s...
-			delay := baseDelay * time.Duration(1<<uint(i-1))
-			// 最大延迟不超过 10 秒
-			if delay > 10*time.Second {
-				delay = 10 * time.Second
-			}
-			time.Sleep(delay)
-		}
+// Shutdown 释放进程锁、取消上下文并等待后台任务退出，最多等待 timeout；返回仍未退出的任务名
+func (psm *ProcessSafetyManager) Shutdown(timeout time.Duration) []string {
+	psm.Unlock()
+	psm.cancel()
 
-		resp, err := nm.client.Do(req)
-		if err == nil {
-			return resp, nil
-		}
+	done := make(chan struct{})
+	go func() {
+		psm.background.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-time.After(timeout):
+	}
+	var lingering []string
+	for name := range psm.BackgroundTasks() {
+		lingering = append(lingering, name)
+	}
+	sort.Strings(lingering)
+	return lingering
+}
 
-		lastErr = err
+// shutdownTimeout 退出时等待后台任务的最长时间
+const shutdownTimeout = 2 * time.Second
 
-		// 检查是否是网络错误
-		if isNetworkError(err) {
-			continue
+// shutdown 退出前收尾；开发者模式下报告未按时退出的后台任务，便于发现泄漏
+func shutdown() {
+	lingering := safetyManager.Shutdown(shutdownTimeout)
+	if len(lingering) > 0 {
+		logger().Warn("后台任务未按时退出", "timeout", shutdownTimeout, "tasks", strings.Join(lingering, ", "))
+		if getCurrentConfig() != nil && getCurrentConfig().Developer.Enabled {
+			fmt.Fprintf(os.Stderr, ColorYellow+"[!] 后台任务未在 %s 内退出: %s"+ColorReset+"\n", shutdownTimeout, strings.Join(lingering, ", "))
 		}
-
-		// 非网络错误直接返回
-		break
 	}
-
-	return nil, fmt.Errorf("请求失败 (重试%d次): %v", nm.retryCount, lastErr)
+	logger().Info("程序退出")
+	closeLogging()
 }
 
-// isNetworkError 判断是否是网络错误
-func isNetworkError(err error) bool {
-	if err == nil {
-		return false
+// recoveredPanic 把恢复的 panic 转为错误；开发者模式下在标准错误输出调用栈
+func recoveredPanic(r interface{}) error {
+	logger().Error("执行过程中出现未知错误", "panic", fmt.Sprint(r), "stack", string(debug.Stack()))
+	if config := getCurrentConfig(); config != nil && config.Developer.Enabled {
+		fmt.Fprintf(os.Stderr, "%v\n%s", r, debug.Stack())
 	}
-
-	errStr := err.Error()
-	return strings.Contains(errStr, "timeout") ||
-		strings.Contains(errStr, "connection refused") ||
-		strings.Contains(errStr, "no such host") ||
-		strings.Contains(errStr, "network is unreachable")
+	return fmt.Errorf("执行过程中出现未知错误: %v", r)
 }
 
 // EmailQualityResult 邮箱质量评估结果
@@ -462,6 +799,9 @@ func (c *Config) httpClient() *http.Client {
 
 		// 优化的 HTTP 传输配置
 		transport := &http.Transport{
+			// 代理（proxy_url 或环境变量）
+			Proxy: c.proxy(),
+
 			// 连接池优化
 			MaxIdleConns:        100,              // 全局最大空闲连接数
 			MaxIdleConnsPerHost: 10,               // 每个主机最大空闲连接数
@@ -490,202 +830,34 @@ func (c *Config) httpClient() *http.Client {
 
 		c.client = &http.Client{
 			Timeout:   time.Duration(timeout) * time.Second,
-			Transport: transport,
+			Transport: &instrumentedTransport{base: transport},
 		}
 	})
 
 	return c.client
 }
 
-func (c *Config) applyRequestHeaders(req *http.Request) {
-	for key, value := range c.Headers {
-		req.Header.Set(key, value)
-	}
-
-	if c.UserAgent != "" && req.Header.Get("User-Agent") == "" {
-		req.Header.Set("User-Agent", c.UserAgent)
-	}
-}
-
-func replaceEndpoint(baseURL, target, replacement string) (string, error) {
-	if baseURL == "" {
-		return "", fmt.Errorf("基础URL为空，无法构建API端点")
-	}
-
-	parsedURL, err := url.Parse(baseURL)
+// clone 复制配置（共享 HTTP 连接池）。已发布到 globalConfig 的配置可能被热重载、后台任务等其他 goroutine 读取，
+// 菜单只修改自己的副本，保存时再发布新的副本，从不原地修改已发布的配置
+func (c *Config) clone() *Config {
+	data, err := json.Marshal(c)
 	if err != nil {
-		return "", fmt.Errorf("无法解析基础URL %q: %w", baseURL, err)
-	}
-
-	normalizePath := func(p string) string {
-		if p == "" {
-			return ""
-		}
-		if !strings.HasPrefix(p, "/") {
-			p = "/" + p
-		}
-		cleaned := path.Clean(p)
-		if cleaned == "." {
-			return ""
-		}
-		return cleaned
+		panic(fmt.Sprintf("复制配置失败: %v", err))
 	}
-
-	currentPath := parsedURL.Path
-	if currentPath == "" {
-		currentPath = "/"
+	var out Config
+	if err := json.Unmarshal(data, &out); err != nil {
+		panic(fmt.Sprintf("复制配置失败: %v", err))
 	}
-	currentPath = path.Clean(currentPath)
-	if !strings.HasPrefix(currentPath, "/") {
-		currentPath = "/" + currentPath
-	}
-
-	targetPath := normalizePath(target)
-	if targetPath == "" {
-		return "", fmt.Errorf("目标路径为空，无法构建API端点")
-	}
-	replacementPath := normalizePath(replacement)
-	if replacementPath == "" {
-		return "", fmt.Errorf("替换路径为空，无法构建API端点")
-	}
-
-	updatedPath := strings.Replace(currentPath, targetPath, replacementPath, 1)
-	if updatedPath == currentPath {
-		return "", fmt.Errorf("基础URL %q 未包含期望的路径片段 %q", baseURL, targetPath)
-	}
-
-	parsedURL.Path = updatedPath
-	return parsedURL.String(), nil
+	out.profile = c.profile
+	out.profileBase = c.profileBase
+	out.shareClient(c)
+	return &out
 }
 
-func readResponseBody(resp *http.Response) ([]byte, error) {
-	defer resp.Body.Close()
-
-	var reader io.Reader = resp.Body
-	if strings.Contains(resp.Header.Get("Content-Encoding"), "gzip") {
-		gzipReader, err := gzip.NewReader(resp.Body)
-		if err != nil {
-			return nil, fmt.Errorf("无法创建 gzip reader: %w", err)
-		}
-		defer gzipReader.Close()
-		reader = gzipReader
-	}
-
-	body, err := io.ReadAll(reader)
-	if err != nil {
-		return nil, fmt.Errorf("无法读取响应: %w", err)
-	}
-
-	return body, nil
-}
-
-// GenerateRequest 生成邮箱地址请求体
-type GenerateRequest struct {
-	LangCode string `json:"langCode"`
-}
-
-// GenerateResponse 生成邮箱地址响应体
-type GenerateResponse struct {
-	Success   bool  `json:"success"`
-	Timestamp int64 `json:"timestamp"`
-	Result    struct {
-		HME string `json:"hme"` // 生成的邮箱地址
-	} `json:"result"`
-}
-
-// ReserveRequest 确认创建邮箱请求体
-type ReserveRequest struct {
-	HME   string `json:"hme"`   // 必填：第一步生成的邮箱地址
-	Label string `json:"label"` // 必填：邮箱标签/描述
-	Note  string `json:"note"`  // 可选：备注
-}
-
-// ReserveResponse 创建邮箱响应体
-type ReserveResponse struct {
-	Success   bool  `json:"success"`
-	Timestamp int64 `json:"timestamp"`
-	Result    struct {
-		HME HMEEmail `json:"hme"`
-	} `json:"result"`
-	Error *APIError `json:"error,omitempty"`
-}
-
-// HMEEmail 邮箱详细信息
-type HMEEmail struct {
-	Origin          string `json:"origin"`
-	AnonymousID     string `json:"anonymousId"`
-	Domain          string `json:"domain"`
-	HME             string `json:"hme"`
-	Label           string `json:"label"`
-	Note            string `json:"note"`
-	CreateTimestamp int64  `json:"createTimestamp"`
-	IsActive        bool   `json:"isActive"`
-	RecipientMailID string `json:"recipientMailId"`
-	ForwardToEmail  string `json:"forwardToEmail,omitempty"`
-}
-
-// ListResponse 邮箱列表响应
-type ListResponse struct {
-	Success   bool  `json:"success"`
-	Timestamp int64 `json:"timestamp"`
-	Result    struct {
-		ForwardToEmails   []string   `json:"forwardToEmails"`
-		HMEEmails         []HMEEmail `json:"hmeEmails"`
-		SelectedForwardTo string     `json:"selectedForwardTo"`
-	} `json:"result"`
-	Error *APIError `json:"error,omitempty"`
-}
-
-// DeactivateRequest 删除邮箱请求
-type DeactivateRequest struct {
-	AnonymousID string `json:"anonymousId"`
-}
-
-// DeactivateResponse 删除邮箱响应
-type DeactivateResponse struct {
-	Success   bool  `json:"success"`
-	Timestamp int64 `json:"timestamp"`
-	Result    struct {
-		Message string `json:"message"`
-	} `json:"result"`
-	Error *APIError `json:"error,omitempty"`
-}
-
-// PermanentDeleteRequest 彻底删除邮箱请求
-type PermanentDeleteRequest struct {
-	AnonymousID string `json:"anonymousId"`
-}
-
-// PermanentDeleteResponse 彻底删除邮箱响应
-type PermanentDeleteResponse struct {
-	Success   bool  `json:"success"`
-	Timestamp int64 `json:"timestamp"`
-	Result    struct {
-		Message string `json:"message"`
-	} `json:"result"`
-	Error *APIError `json:"error,omitempty"`
-}
-
-// ReactivateRequest 重新激活邮箱请求
-type ReactivateRequest struct {
-	AnonymousID string `json:"anonymousId"`
-}
-
-// ReactivateResponse 重新激活邮箱响应
-type ReactivateResponse struct {
-	Success   bool  `json:"success"`
-	Timestamp int64 `json:"timestamp"`
-	Result    struct {
-		Message string `json:"message"`
-	} `json:"result"`
-	Error *APIError `json:"error,omitempty"`
-}
-
-// APIError API错误信息
-type APIError struct {
-	ErrorCode    string `json:"errorCode"`
-	ErrorMessage string `json:"errorMessage"`
-	RetryAfter   int    `json:"retryAfter"`
+// shareClient 沿用另一份配置的 HTTP 客户端（连接池），不再单独创建
+func (c *Config) shareClient(from *Config) {
+	c.client = from.httpClient()
+	c.clientOnce.Do(func() {})
 }
 
 // 加载配置文件
@@ -705,134 +877,17 @@ func loadConfig(filename string) (*Config, error) {
 	return &config, nil
 }
 
-// 第1步：生成邮箱地址
-func generateHME(config *Config) (string, error) {
-	// 构建 /generate 接口的 URL
-	generateURL, err := replaceEndpoint(config.BaseURL, "/reserve", "/generate")
-	if err != nil {
-		return "", fmt.Errorf("无法构建 generate 接口: %w", err)
-	}
-	url := fmt.Sprintf("%s?clientBuildNumber=%s&clientMasteringNumber=%s&clientId=%s&dsid=%s",
-		generateURL,
-		config.ClientBuildNumber,
-		config.ClientMasteringNumber,
-		config.ClientID,
-		config.DSID,
-	)
-
-	// 构建请求体
-	reqBody := GenerateRequest{
-		LangCode: config.LangCode,
-	}
-	jsonData, err := json.Marshal(reqBody)
-	if err != nil {
-		return "", fmt.Errorf("无法序列化请求体: %v", err)
-	}
-
-	// 创建HTTP请求
-	req, err := http.NewRequest("POST", url, bytes.NewReader(jsonData))
-	if err != nil {
-		return "", fmt.Errorf("无法创建请求: %v", err)
-	}
-
-	config.applyRequestHeaders(req)
-	if req.Header.Get("Content-Type") == "" {
-		req.Header.Set("Content-Type", "application/json")
-	}
-
-	// 发送请求
-	resp, err := config.httpClient().Do(req)
-	if err != nil {
-		return "", fmt.Errorf("请求失败: %v", err)
-	}
-
-	body, err := readResponseBody(resp)
-	if err != nil {
-		return "", err
-	}
-
-	// 检查HTTP状态码
-	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("API返回错误状态码: %d, 响应: %s", resp.StatusCode, strings.TrimSpace(string(body)))
-	}
-
-	// 解析响应
-	var response GenerateResponse
-	if err := json.Unmarshal(body, &response); err != nil {
-		return "", fmt.Errorf("无法解析响应: %v, 原始响应: %s", err, strings.TrimSpace(string(body)))
-	}
-
-	// 检查是否成功
-	if !response.Success {
-		return "", fmt.Errorf("API返回失败: %s", strings.TrimSpace(string(body)))
-	}
-
-	return response.Result.HME, nil
-}
-
-// 邮箱质量评估算法
-func evaluateEmailQuality(email string, weights ScoreWeights) int {
+// 邮箱质量评估算法：按 email_quality 中的权重组合内置与自定义评分器（见 scoring.go）
+func evaluateEmailQuality(email string, quality EmailQualityConfig) int {
 	if email == "" {
 		return 0
 	}
-
-	// 分离前缀和域名
-	parts := strings.Split(email, "@")
-	if len(parts) != 2 {
-		return 0
-	}
-	prefix := parts[0]
-	domain := parts[1]
-
-	var totalScore float64
-	var totalWeight int
-
-	// 1. 前缀结构评分 (0-100)
-	if weights.PrefixStructure > 0 {
-		structureScore := evaluatePrefixStructure(prefix)
-		totalScore += float64(structureScore * weights.PrefixStructure)
-		totalWeight += weights.PrefixStructure
-	}
-
-	// 2. 长度评分 (0-100)
-	if weights.Length > 0 {
-		lengthScore := evaluateLength(prefix)
-		totalScore += float64(lengthScore * weights.Length)
-		totalWeight += weights.Length
-	}
-
-	// 3. 可读性评分 (0-100)
-	if weights.Readability > 0 {
-		readabilityScore := evaluateReadability(prefix)
-		totalScore += float64(readabilityScore * weights.Readability)
-		totalWeight += weights.Readability
-	}
-
-	// 4. 安全性评分 (0-100)
-	if weights.Security > 0 {
-		securityScore := evaluateSecurity(prefix, domain)
-		totalScore += float64(securityScore * weights.Security)
-		totalWeight += weights.Security
-	}
-
-	if totalWeight == 0 {
-		return 0
-	}
-
-	// 计算加权平均分
-	finalScore := int(totalScore / float64(totalWeight))
-	if finalScore > 100 {
-		finalScore = 100
-	}
-	if finalScore < 0 {
-		finalScore = 0
-	}
-
-	return finalScore
+	score, _ := scoringEngineFor(quality).Score(email)
+	return score
 }
 
-// 评估前缀结构 (0-100分)
-func evaluatePrefixStructure(prefix string) int {
+// 评估前缀结构 (0-100分)，why 记录判定的结构类型
+func evaluatePrefixStructure(prefix string, why *scoreReasons) int {
 	if prefix == "" {
 		return 0
 	}
@@ -840,8 +895,10 @@ func evaluatePrefixStructure(prefix string) int {
 	// 纯字母 - 最安全 (90-100分)
 	if isOnlyLetters(prefix) {
 		if len(prefix) >= 4 && len(prefix) <= 12 {
+			why.add("纯字母")
 			return 95
 		}
+		why.add("纯字母，但长度不在 4-12 之间")
 		return 85
 	}
 
@@ -849,11 +906,14 @@ func evaluatePrefixStructure(prefix string) int {
 	if isLettersWithDots(prefix) {
 		dotCount := strings.Count(prefix, ".")
 		if dotCount == 1 && len(prefix) >= 5 && len(prefix) <= 15 {
+			why.add("字母加 1 个点号")
 			return 80
 		}
 		if dotCount <= 2 {
+			why.add("字母加 %d 个点号", dotCount)
 			return 70
 		}
+		why.add("点号过多 (%d 个)", dotCount)
 		return 50 // 太多点号
 	}
 
@@ -861,8 +921,10 @@ func evaluatePrefixStructure(prefix string) int {
 	if isLettersWithNumbers(prefix) {
 		digitCount := countDigits(prefix)
 		if digitCount <= 4 && len(prefix) >= 4 && len(prefix) <= 15 {
+			why.add("字母加 %d 位数字", digitCount)
 			return 65
 		}
+		why.add("字母加 %d 位数字，数字过多或长度不合适", digitCount)
 		return 55
 	}
 
@@ -871,18 +933,26 @@ func evaluatePrefixStructure(prefix string) int {
 		underscoreCount := strings.Count(prefix, "_")
 		hyphenCount := strings.Count(prefix, "-")
 		if underscoreCount+hyphenCount == 1 {
+			why.add("包含 1 个下划线或连字符")
 			return 45
 		}
+		why.add("包含 %d 个下划线或连字符", underscoreCount+hyphenCount)
 		return 25 // 多个特殊字符
 	}
 
 	// 其他复杂格式 - 很差 (0-30分)
+	why.add("混合了点号、数字等多种字符")
 	return 20
 }
 
 // 评估长度 (0-100分)
-func evaluateLength(prefix string) int {
+func evaluateLength(prefix string, why *scoreReasons) int {
 	length := len(prefix)
+	if length < 6 || length > 10 {
+		why.add("%d 个字符，理想长度为 6-10", length)
+	} else {
+		why.add("%d 个字符，长度理想", length)
+	}
 
 	// 理想长度 6-10 字符 (90-100分)
 	if length >= 6 && length <= 10 {
@@ -910,73 +980,39 @@ func evaluateLength(prefix string) int {
 	return 40
 }
 
-// 评估可读性 (0-100分)
-func evaluateReadability(prefix string) int {
-	if prefix == "" {
-		return 0
-	}
-
-	score := 50 // 基础分
-
-	// 检查是否像真实单词
-	if looksLikeRealWords(prefix) {
-		score += 30
-	}
-
-	// 检查字符重复
-	if hasExcessiveRepeating(prefix) {
-		score -= 25
-	}
-
-	// 检查随机性
-	if looksRandom(prefix) {
-		score -= 30
-	}
-
-	// 检查元音辅音比例
-	if hasGoodVowelConsonantRatio(prefix) {
-		score += 15
-	}
-
-	if score > 100 {
-		score = 100
-	}
-	if score < 0 {
-		score = 0
-	}
-
-	return score
-}
-
 // 评估安全性 (0-100分)
-func evaluateSecurity(prefix, domain string) int {
+func evaluateSecurity(prefix, domain string, why *scoreReasons) int {
 	score := 50 // 基础分
 
 	// 域名评分
+	bonus := 10 // 其他域名
 	switch domain {
 	case "icloud.com":
-		score += 25 // iCloud 域名很好
+		bonus = 25 // iCloud 域名很好
 	case "gmail.com":
-		score += 30 // Gmail 域名最好
+		bonus = 30 // Gmail 域名最好
 	case "outlook.com", "hotmail.com":
-		score += 20
-	default:
-		score += 10 // 其他域名
+		bonus = 20
 	}
+	score += bonus
+	why.add("基础 50，%s 域名 +%d", domain, bonus)
 
 	// 检查是否看起来像临时邮箱
-	if looksLikeTemporaryEmail(prefix) {
+	if hint := temporaryEmailHint(prefix); hint != "" {
+		why.add("%s，像临时邮箱 (-30)", hint)
 		score -= 30
 	}
 
 	// 检查是否包含明显的无限邮箱特征
-	if hasInfiniteEmailPattern(prefix) {
+	if keyword := infiniteEmailKeyword(prefix); keyword != "" {
+		why.add("包含无限邮箱特征 %s (-25)", keyword)
 		score -= 25
 	}
 
 	// 检查特殊字符过多
 	specialCharCount := countSpecialChars(prefix)
 	if specialCharCount > 2 {
+		why.add("%d 个特殊字符 (-20)", specialCharCount)
 		score -= 20
 	}
 
@@ -1037,52 +1073,10 @@ func countDigits(s string) int {
 	return count
 }
 
-// 辅助函数：检查是否看起来像真实单词
-func looksLikeRealWords(s string) bool {
-	// 简单的启发式检查
-	s = strings.ToLower(s)
-
-	// 常见的英文单词模式
-	commonPatterns := []string{
-		"john", "smith", "mike", "david", "alex", "chris", "sarah", "mary",
-		"test", "demo", "user", "admin", "mail", "email", "work", "home",
-		"info", "contact", "support", "hello", "world", "apple", "google",
-	}
-
-	for _, pattern := range commonPatterns {
-		if strings.Contains(s, pattern) {
-			return true
-		}
-	}
-
-	// 检查元音辅音模式
-	vowels := "aeiou"
-	consonants := "bcdfghjklmnpqrstvwxyz"
-
-	vowelCount := 0
-	consonantCount := 0
-
-	for _, r := range s {
-		if strings.ContainsRune(vowels, r) {
-			vowelCount++
-		} else if strings.ContainsRune(consonants, r) {
-			consonantCount++
-		}
-	}
-
-	// 合理的元音辅音比例
-	if vowelCount > 0 && consonantCount > 0 {
-		ratio := float64(vowelCount) / float64(consonantCount)
-		return ratio >= 0.2 && ratio <= 2.0
-	}
-
-	return false
-}
-
-// 辅助函数：检查是否有过多重复字符
-func hasExcessiveRepeating(s string) bool {
+// 辅助函数：最长的连续相同字符数，3 个或以上视为重复过多
+func longestRepeat(s string) int {
 	if len(s) < 2 {
-		return false
+		return len(s)
 	}
 
 	maxRepeat := 0
@@ -1103,69 +1097,11 @@ func hasExcessiveRepeating(s string) bool {
 		maxRepeat = currentRepeat
 	}
 
-	return maxRepeat >= 3 // 连续3个或以上相同字符
+	return maxRepeat
 }
 
-// 辅助函数：检查是否看起来随机
-func looksRandom(s string) bool {
-	if len(s) < 4 {
-		return false
-	}
-
-	// 检查字符变化频率
-	changes := 0
-	for i := 1; i < len(s); i++ {
-		if s[i] != s[i-1] {
-			changes++
-		}
-	}
-
-	changeRatio := float64(changes) / float64(len(s)-1)
-
-	// 如果变化太频繁，可能是随机字符串
-	if changeRatio > 0.8 {
-		return true
-	}
-
-	// 检查是否包含常见的随机字符串模式
-	randomPatterns := []string{
-		"xyz", "abc", "123", "qwe", "asd", "zxc",
-	}
-
-	s = strings.ToLower(s)
-	for _, pattern := range randomPatterns {
-		if strings.Contains(s, pattern) {
-			return true
-		}
-	}
-
-	return false
-}
-
-// 辅助函数：检查元音辅音比例是否合理
-func hasGoodVowelConsonantRatio(s string) bool {
-	vowels := "aeiouAEIOU"
-	vowelCount := 0
-	consonantCount := 0
-
-	for _, r := range s {
-		if strings.ContainsRune(vowels, r) {
-			vowelCount++
-		} else if (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') {
-			consonantCount++
-		}
-	}
-
-	if vowelCount == 0 || consonantCount == 0 {
-		return false
-	}
-
-	ratio := float64(vowelCount) / float64(consonantCount)
-	return ratio >= 0.25 && ratio <= 1.5
-}
-
-// 辅助函数：检查是否看起来像临时邮箱
-func looksLikeTemporaryEmail(prefix string) bool {
+// 辅助函数：检查是否看起来像临时邮箱，返回判断依据（如 “包含临时邮箱关键词 temp”），不像时为空
+func temporaryEmailHint(prefix string) string {
 	prefix = strings.ToLower(prefix)
 
 	// 临时邮箱常见模式
@@ -1177,7 +1113,7 @@ func looksLikeTemporaryEmail(prefix string) bool {
 
 	for _, pattern := range tempPatterns {
 		if strings.Contains(prefix, pattern) {
-			return true
+			return "包含临时邮箱关键词 " + pattern
 		}
 	}
 
@@ -1185,18 +1121,18 @@ func looksLikeTemporaryEmail(prefix string) bool {
 	if len(prefix) >= 6 {
 		digitCount := countDigits(prefix)
 		if float64(digitCount)/float64(len(prefix)) > 0.6 {
-			return true
+			return fmt.Sprintf("数字占 %d/%d", digitCount, len(prefix))
 		}
 	}
 
-	return false
+	return ""
 }
 
-// 辅助函数：检查是否有无限邮箱模式
-func hasInfiniteEmailPattern(prefix string) bool {
+// 辅助函数：检查是否有无限邮箱模式，返回命中的标识，没有时为空
+func infiniteEmailKeyword(prefix string) string {
 	// 检查是否包含 + 号（虽然iCloud不支持，但作为检查）
 	if strings.Contains(prefix, "+") {
-		return true
+		return "+"
 	}
 
 	// 检查是否有明显的无限邮箱标识
@@ -1208,11 +1144,11 @@ func hasInfiniteEmailPattern(prefix string) bool {
 	prefix = strings.ToLower(prefix)
 	for _, pattern := range infinitePatterns {
 		if strings.Contains(prefix, pattern) {
-			return true
+			return pattern
 		}
 	}
 
-	return false
+	return ""
 }
 
 // 辅助函数：计算特殊字符数量
@@ -1233,9 +1169,32 @@ func generateSmartEmail(config *Config, label string) (*EmailQualityResult, erro
 	if maxTries <= 0 {
 		maxTries = 3 // 默认最多3次
 	}
+	// 多语言时每种语言至少生成一个候选
+	langs := candidateLangCodes(config)
+	if len(langs) > maxTries {
+		maxTries = len(langs)
+	}
+
+	// 自定义评分器配置有误时直接报错，而不是悄悄退回内置评分器
+	if _, err := newScoringEngine(qualityConfig); err != nil {
+		return nil, err
+	}
+	// 格式要求：不符合的候选直接丢弃
+	filter, err := newCandidateFilter(qualityConfig)
+	if err != nil {
+		return nil, err
+	}
+	started := time.Now()
 
 	printSubHeader("智能邮箱生成")
-	fmt.Printf("  "+ColorCyan+"目标分数:"+ColorReset+" %d+ "+ColorDim+"|"+ColorReset+" "+ColorCyan+"最大尝试:"+ColorReset+" %d 次\n\n", qualityConfig.MinScore, maxTries)
+	fmt.Printf("  "+ColorCyan+"目标分数:"+ColorReset+" %d+ "+ColorDim+"|"+ColorReset+" "+ColorCyan+"最大尝试:"+ColorReset+" %d 次\n", qualityConfig.MinScore, maxTries)
+	if len(langs) > 0 {
+		fmt.Printf("  "+ColorCyan+"候选语言:"+ColorReset+" %s\n", strings.Join(langs, "、"))
+	}
+	if filter != nil {
+		fmt.Printf("  "+ColorCyan+"格式要求:"+ColorReset+" %s\n", filter.Describe())
+	}
+	fmt.Println()
 
 	// 并发生成所有候选邮箱
 	type candidateResult struct {
@@ -1251,20 +1210,25 @@ func generateSmartEmail(config *Config, label string) (*EmailQualityResult, erro
 		go func(id int) {
 			defer wg.Done()
 
-			// 生成邮箱
-			email, err := generateHME(config)
+			// 生成邮箱，多语言时按序号轮流使用
+			lang := ""
+			if len(langs) > 0 {
+				lang = langs[(id-1)%len(langs)]
+			}
+			email, err := generateHMEWithLang(config, lang)
 			if err != nil {
 				resultChan <- candidateResult{err: err}
 				return
 			}
 
 			// 评估质量
-			score := evaluateEmailQuality(email, qualityConfig.Weights)
+			score := filter.Score(email, qualityConfig)
 			resultChan <- candidateResult{
 				candidate: EmailCandidate{
 					Email: email,
 					Score: score,
 					ID:    id,
+					Lang:  lang,
 				},
 			}
 		}(i)
@@ -1288,6 +1252,10 @@ func generateSmartEmail(config *Config, label string) (*EmailQualityResult, erro
 		}
 
 		candidate := result.candidate
+		if reason := filter.Check(candidate.Email); reason != "" {
+			fmt.Printf("  "+ColorDim+"[-] 邮箱 #%d: %s（%s）"+ColorReset+"\n", candidate.ID, candidate.Email, reason)
+			continue
+		}
 		candidates = append(candidates, candidate)
 
 		// 显示结果
@@ -1300,7 +1268,7 @@ func generateSmartEmail(config *Config, label string) (*EmailQualityResult, erro
 			scoreColor = ColorRed
 		}
 
-		fmt.Printf("  "+ColorGreen+"[+]"+ColorReset+" 邮箱 #%d: %s\n", candidate.ID, candidate.Email)
+		fmt.Printf("  "+ColorGreen+"[+]"+ColorReset+" 邮箱 #%d: %s%s\n", candidate.ID, candidate.Email, formatCandidateLang(candidate.Lang))
 		fmt.Printf("      "+ColorMagenta+"分数:"+ColorReset+" "+scoreColor+"%d"+ColorReset+"/100\n", candidate.Score)
 
 		// 更新最佳邮箱
@@ -1310,16 +1278,34 @@ func generateSmartEmail(config *Config, label string) (*EmailQualityResult, erro
 		}
 	}
 
+	// 设置了格式要求且这一轮都不符合时，继续生成直到找到符合的候选或用完预算
+	if filter != nil && len(candidates) == 0 {
+		for _, candidate := range extendFilteredCandidates(config, filter, maxTries, langs, started) {
+			fmt.Printf("  "+ColorGreen+"[+]"+ColorReset+" 邮箱 #%d: %s%s\n", candidate.ID, candidate.Email, formatCandidateLang(candidate.Lang))
+			fmt.Printf("      "+ColorMagenta+"分数:"+ColorReset+" %d/100\n", candidate.Score)
+			candidates = append(candidates, candidate)
+			bestEmail, bestScore = candidate.Email, candidate.Score
+		}
+	}
+	if summary := filter.Summary(); summary != "" {
+		fmt.Println()
+		printInfo("格式要求" + summary)
+	}
+
+	printLangComparison(candidates)
 	fmt.Println()
 
 	// 如果没有成功生成任何邮箱
 	if len(candidates) == 0 {
+		if filter.Rejected() > 0 {
+			return nil, fmt.Errorf("生成的 %d 个候选均不符合格式要求，可放宽 pattern / reject_pattern / blocklist / similar_distance 或提高 pattern_max_tries", filter.Rejected())
+		}
 		return nil, fmt.Errorf("所有生成尝试均失败")
 	}
 
 	// 如果启用自动选择且有满足条件的邮箱
 	if qualityConfig.AutoSelect && bestScore >= qualityConfig.MinScore {
-		fmt.Printf("  " + ColorBrightGreen + "[+] 自动选择最佳邮箱 (分数: %d)" + ColorReset + "\n\n", bestScore)
+		fmt.Printf("  "+ColorBrightGreen+"[+] 自动选择最佳邮箱 (分数: %d)"+ColorReset+"\n\n", bestScore)
 
 		// 确认创建邮箱
 		finalEmail, err := reserveHME(config, bestEmail, label)
@@ -1331,7 +1317,7 @@ func generateSmartEmail(config *Config, label string) (*EmailQualityResult, erro
 			Candidates:   candidates,
 			BestEmail:    finalEmail,
 			BestScore:    bestScore,
-			TotalTries:   len(candidates),
+			TotalTries:   len(candidates) + filter.Rejected(),
 			AutoSelected: true,
 		}, nil
 	}
@@ -1341,7 +1327,7 @@ func generateSmartEmail(config *Config, label string) (*EmailQualityResult, erro
 		Candidates:   candidates,
 		BestEmail:    bestEmail,
 		BestScore:    bestScore,
-		TotalTries:   len(candidates),
+		TotalTries:   len(candidates) + filter.Rejected(),
 		AutoSelected: false,
 	}, nil
 }
@@ -1369,18 +1355,18 @@ func selectEmailManually(result *EmailQualityResult, config *Config, label strin
 			statusIcon = ColorRed + "[!]" + ColorReset
 		}
 
-		fmt.Printf("  "+ColorBrightCyan+"ID%d."+ColorReset+" %s "+ColorBrightWhite+"%s"+ColorReset+"\n",
-			candidate.ID, statusIcon, candidate.Email)
+		fmt.Printf("  "+ColorBrightCyan+"ID%d."+ColorReset+" %s "+ColorBrightWhite+"%s"+ColorReset+"%s\n",
+			candidate.ID, statusIcon, candidate.Email, formatCandidateLang(candidate.Lang))
 		fmt.Printf("      "+ColorMagenta+"分数:"+ColorReset+" "+scoreColor+"%d"+ColorReset+"/100", candidate.Score)
 
 		if candidate.Email == result.BestEmail {
-			fmt.Printf(" " + ColorBold + ColorBrightGreen + "(最佳)" + ColorReset)
+			fmt.Print(" " + ColorBold + ColorBrightGreen + "(最佳)" + ColorReset)
 		}
 		fmt.Println()
 
 		// 显示详细评分
-		if config.EmailQuality.ShowScores {
-			showDetailedScore(candidate.Email, config.EmailQuality.Weights)
+		if config.EmailQuality.Selection.ShowScores {
+			showDetailedScore(candidate.Email, config.EmailQuality)
 		}
 		fmt.Println()
 	}
@@ -1396,7 +1382,7 @@ func selectEmailManually(result *EmailQualityResult, config *Config, label strin
 		fmt.Printf("\n  "+ColorBrightGreen+"[+] 自动选择最佳邮箱"+ColorReset+" (分数: %d)\n", result.BestScore)
 	} else {
 		id, err := strconv.Atoi(input)
-		if err != nil || id < 1 || id > len(result.Candidates) {
+		if err != nil || id < 1 {
 			return "", fmt.Errorf("无效的选择: %s", input)
 		}
 
@@ -1415,13 +1401,13 @@ func selectEmailManually(result *EmailQualityResult, config *Config, label strin
 	}
 
 	// 确认创建邮箱
-	fmt.Printf("\n  " + ColorDim + "..." + ColorReset + " 确认创建邮箱 ... ")
+	fmt.Print("\n  " + ColorDim + "..." + ColorReset + " 确认创建邮箱 ... ")
 	finalEmail, err := reserveHME(config, selectedEmail, label)
 	if err != nil {
-		fmt.Printf(ColorRed + "[!]" + ColorReset + "\n")
-		return "", fmt.Errorf("确认创建邮箱失败: %v", err)
+		fmt.Print(ColorRed + "[!]" + ColorReset + "\n")
+		return "", fmt.Errorf("确认创建邮箱失败: %w", err)
 	}
-	fmt.Printf(ColorGreen + "[+]" + ColorReset + "\n")
+	fmt.Print(ColorGreen + "[+]" + ColorReset + "\n")
 
 	return finalEmail, nil
 }
@@ -1444,360 +1430,35 @@ func getBestCandidateID(candidates []EmailCandidate) int {
 }
 
 // 显示详细评分
-func showDetailedScore(email string, weights ScoreWeights) {
+func showDetailedScore(email string, quality EmailQualityConfig) {
 	if email == "" {
 		return
 	}
-
-	parts := strings.Split(email, "@")
-	if len(parts) != 2 {
+	_, breakdown := scoringEngineFor(quality).Score(email)
+	if len(breakdown) == 0 {
 		return
 	}
-	prefix := parts[0]
-	domain := parts[1]
-
-	fmt.Printf("      " + ColorDim + "详细评分:" + ColorReset)
-
-	if weights.PrefixStructure > 0 {
-		score := evaluatePrefixStructure(prefix)
-		fmt.Printf(" "+ColorCyan+"结构"+ColorReset+":%d", score)
-	}
-
-	if weights.Length > 0 {
-		score := evaluateLength(prefix)
-		fmt.Printf(" "+ColorBlue+"长度"+ColorReset+":%d", score)
-	}
-
-	if weights.Readability > 0 {
-		score := evaluateReadability(prefix)
-		fmt.Printf(" "+ColorYellow+"可读"+ColorReset+":%d", score)
-	}
-
-	if weights.Security > 0 {
-		score := evaluateSecurity(prefix, domain)
-		fmt.Printf(" "+ColorMagenta+"安全"+ColorReset+":%d", score)
-	}
-}
-
-// 第2步：确认创建邮箱（设置 label）
-func reserveHME(config *Config, hme string, label string) (string, error) {
-	// 构建 /reserve 接口的 URL
-	url := fmt.Sprintf("%s?clientBuildNumber=%s&clientMasteringNumber=%s&clientId=%s&dsid=%s",
-		config.BaseURL,
-		config.ClientBuildNumber,
-		config.ClientMasteringNumber,
-		config.ClientID,
-		config.DSID,
-	)
-
-	// 构建请求体 - 必须包含 hme 和 label
-	reqBody := ReserveRequest{
-		HME:   hme,   // 第一步生成的邮箱地址
-		Label: label, // 邮箱标签
-		Note:  "",    // 备注（可选）
-	}
-	jsonData, err := json.Marshal(reqBody)
-	if err != nil {
-		return "", fmt.Errorf("无法序列化请求体: %v", err)
-	}
-
-	// 创建HTTP请求
-	req, err := http.NewRequest("POST", url, bytes.NewReader(jsonData))
-	if err != nil {
-		return "", fmt.Errorf("无法创建请求: %v", err)
-	}
-
-	config.applyRequestHeaders(req)
-	if req.Header.Get("Content-Type") == "" {
-		req.Header.Set("Content-Type", "application/json")
-	}
-
-	// 发送请求
-	resp, err := config.httpClient().Do(req)
-	if err != nil {
-		return "", fmt.Errorf("请求失败: %v", err)
-	}
-
-	body, err := readResponseBody(resp)
-	if err != nil {
-		return "", err
-	}
-
-	// 检查HTTP状态码
-	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("API返回错误状态码: %d, 响应: %s", resp.StatusCode, strings.TrimSpace(string(body)))
-	}
-
-	// 解析响应
-	var response ReserveResponse
-	if err := json.Unmarshal(body, &response); err != nil {
-		return "", fmt.Errorf("无法解析响应: %v, 原始响应: %s", err, strings.TrimSpace(string(body)))
-	}
-
-	// 检查是否成功
-	if !response.Success {
-		return "", fmt.Errorf("API返回失败: %s", strings.TrimSpace(string(body)))
-	}
-
-	// 返回实际的邮箱地址 - 注意是 result.hme.hme
-	return response.Result.HME.HME, nil
-}
-
-// 创建隐藏邮件地址（完整流程：生成 + 确认）
-func createHME(config *Config, label string) (string, error) {
-	// 第1步：生成邮箱地址
-	hme, err := generateHME(config)
-	if err != nil {
-		return "", fmt.Errorf("生成邮箱地址失败: %v", err)
-	}
-
-	// 第2步：确认创建并设置 label
-	finalHME, err := reserveHME(config, hme, label)
-	if err != nil {
-		return "", fmt.Errorf("确认创建邮箱失败: %v", err)
-	}
-
-	return finalHME, nil
-}
-
-// 获取邮箱列表
-func listHME(config *Config) ([]HMEEmail, error) {
-	// 构建 /list 接口的 URL
-	listURL, err := replaceEndpoint(config.BaseURL, "/v1/hme/reserve", "/v2/hme/list")
-	if err != nil {
-		return nil, fmt.Errorf("无法构建 list 接口: %w", err)
-	}
-	url := fmt.Sprintf("%s?clientBuildNumber=%s&clientMasteringNumber=%s&clientId=%s&dsid=%s",
-		listURL,
-		config.ClientBuildNumber,
-		config.ClientMasteringNumber,
-		config.ClientID,
-		config.DSID,
-	)
-
-	// 创建HTTP请求
-	req, err := http.NewRequest("GET", url, nil)
-	if err != nil {
-		return nil, fmt.Errorf("无法创建请求: %v", err)
-	}
-
-	config.applyRequestHeaders(req)
-
-	// 发送请求
-	resp, err := config.httpClient().Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("网络请求失败: %v", err)
-	}
-
-	body, err := readResponseBody(resp)
-	if err != nil {
-		return nil, err
-	}
-
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("服务器返回错误 (状态码: %d, 响应: %s)", resp.StatusCode, strings.TrimSpace(string(body)))
-	}
-
-	var response ListResponse
-	if err := json.Unmarshal(body, &response); err != nil {
-		return nil, fmt.Errorf("解析响应失败: %v, 原始响应: %s", err, strings.TrimSpace(string(body)))
-	}
-
-	if !response.Success {
-		if response.Error != nil {
-			return nil, fmt.Errorf("API错误: %s", response.Error.ErrorMessage)
-		}
-		return nil, fmt.Errorf("获取列表失败")
-	}
 
-	return response.Result.HMEEmails, nil
-}
-
-// 删除邮箱（停用）
-func deactivateHME(config *Config, anonymousID string) error {
-	// 构建 /deactivate 接口的 URL
-	deactivateURL, err := replaceEndpoint(config.BaseURL, "/reserve", "/deactivate")
-	if err != nil {
-		return fmt.Errorf("无法构建 deactivate 接口: %w", err)
-	}
-	url := fmt.Sprintf("%s?clientBuildNumber=%s&clientMasteringNumber=%s&clientId=%s&dsid=%s",
-		deactivateURL,
-		config.ClientBuildNumber,
-		config.ClientMasteringNumber,
-		config.ClientID,
-		config.DSID,
-	)
-
-	// 构建请求体
-	reqBody := DeactivateRequest{AnonymousID: anonymousID}
-	jsonData, err := json.Marshal(reqBody)
-	if err != nil {
-		return fmt.Errorf("序列化请求失败: %v", err)
-	}
-
-	req, err := http.NewRequest("POST", url, bytes.NewReader(jsonData))
-	if err != nil {
-		return fmt.Errorf("创建请求失败: %v", err)
-	}
-
-	config.applyRequestHeaders(req)
-	if req.Header.Get("Content-Type") == "" {
-		req.Header.Set("Content-Type", "application/json")
-	}
-
-	resp, err := config.httpClient().Do(req)
-	if err != nil {
-		return fmt.Errorf("网络请求失败: %v", err)
-	}
-
-	body, err := readResponseBody(resp)
-	if err != nil {
-		return err
-	}
-
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("服务器返回错误 (状态码: %d, 响应: %s)", resp.StatusCode, strings.TrimSpace(string(body)))
-	}
-
-	var response DeactivateResponse
-	if err := json.Unmarshal(body, &response); err != nil {
-		return fmt.Errorf("解析响应失败: %v, 原始响应: %s", err, strings.TrimSpace(string(body)))
+	fmt.Print("      " + ColorDim + "详细评分:" + ColorReset)
+	for _, item := range breakdown {
+		fmt.Printf(" "+scoreColor(item.Name)+"%s"+ColorReset+":%d", item.Title, item.Score)
 	}
+	fmt.Println()
 
-	if !response.Success {
-		if response.Error != nil {
-			return fmt.Errorf("API错误: %s", response.Error.ErrorMessage)
+	// 低于 70 分（一般、较差）的项说明原因，完整说明见 score 命令
+	var notes []string
+	for _, item := range breakdown {
+		if item.Score < 70 && len(item.Reasons) > 0 {
+			notes = append(notes, item.Title+": "+strings.Join(item.Reasons, "，"))
 		}
-		return fmt.Errorf("停用失败")
-	}
-
-	return nil
-}
-
-// 彻底删除邮箱（不可恢复）
-func permanentDeleteHME(config *Config, anonymousID string) error {
-	// 构建 /delete 接口的 URL
-	deleteURL, err := replaceEndpoint(config.BaseURL, "/v1/hme/reserve", "/v1/hme/delete")
-	if err != nil {
-		return fmt.Errorf("无法构建 delete 接口: %w", err)
-	}
-	url := fmt.Sprintf("%s?clientBuildNumber=%s&clientMasteringNumber=%s&clientId=%s&dsid=%s",
-		deleteURL,
-		config.ClientBuildNumber,
-		config.ClientMasteringNumber,
-		config.ClientID,
-		config.DSID,
-	)
-
-	// 构建请求体
-	reqBody := PermanentDeleteRequest{AnonymousID: anonymousID}
-	jsonData, err := json.Marshal(reqBody)
-	if err != nil {
-		return fmt.Errorf("序列化请求失败: %v", err)
 	}
-
-	req, err := http.NewRequest("POST", url, bytes.NewReader(jsonData))
-	if err != nil {
-		return fmt.Errorf("创建请求失败: %v", err)
-	}
-
-	config.applyRequestHeaders(req)
-	if req.Header.Get("Content-Type") == "" {
-		req.Header.Set("Content-Type", "application/json")
+	if len(notes) > 0 {
+		fmt.Println("      " + ColorDim + "低分原因: " + strings.Join(notes, "；") + ColorReset)
 	}
-
-	resp, err := config.httpClient().Do(req)
-	if err != nil {
-		return fmt.Errorf("网络请求失败: %v", err)
-	}
-
-	body, err := readResponseBody(resp)
-	if err != nil {
-		return err
-	}
-
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("服务器返回错误 (状态码: %d, 响应: %s)", resp.StatusCode, strings.TrimSpace(string(body)))
-	}
-
-	var response PermanentDeleteResponse
-	if err := json.Unmarshal(body, &response); err != nil {
-		return fmt.Errorf("解析响应失败: %v, 原始响应: %s", err, strings.TrimSpace(string(body)))
-	}
-
-	if !response.Success {
-		if response.Error != nil {
-			return fmt.Errorf("API错误: %s", response.Error.ErrorMessage)
-		}
-		return fmt.Errorf("彻底删除失败")
-	}
-
-	return nil
-}
-
-// 重新激活邮箱
-func reactivateHME(config *Config, anonymousID string) error {
-	// 构建 /reactivate 接口的 URL
-	reactivateURL, err := replaceEndpoint(config.BaseURL, "/v1/hme/reserve", "/v1/hme/reactivate")
-	if err != nil {
-		return fmt.Errorf("无法构建 reactivate 接口: %w", err)
-	}
-	url := fmt.Sprintf("%s?clientBuildNumber=%s&clientMasteringNumber=%s&clientId=%s&dsid=%s",
-		reactivateURL,
-		config.ClientBuildNumber,
-		config.ClientMasteringNumber,
-		config.ClientID,
-		config.DSID,
-	)
-
-	// 构建请求体
-	reqBody := ReactivateRequest{AnonymousID: anonymousID}
-	jsonData, err := json.Marshal(reqBody)
-	if err != nil {
-		return fmt.Errorf("序列化请求失败: %v", err)
-	}
-
-	req, err := http.NewRequest("POST", url, bytes.NewReader(jsonData))
-	if err != nil {
-		return fmt.Errorf("创建请求失败: %v", err)
-	}
-
-	config.applyRequestHeaders(req)
-	if req.Header.Get("Content-Type") == "" {
-		req.Header.Set("Content-Type", "application/json")
-	}
-
-	resp, err := config.httpClient().Do(req)
-	if err != nil {
-		return fmt.Errorf("网络请求失败: %v", err)
-	}
-
-	body, err := readResponseBody(resp)
-	if err != nil {
-		return err
-	}
-
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("服务器返回错误 (状态码: %d, 响应: %s)", resp.StatusCode, strings.TrimSpace(string(body)))
-	}
-
-	var response ReactivateResponse
-	if err := json.Unmarshal(body, &response); err != nil {
-		return fmt.Errorf("解析响应失败: %v, 原始响应: %s", err, strings.TrimSpace(string(body)))
-	}
-
-	if !response.Success {
-		if response.Error != nil {
-			return fmt.Errorf("API错误: %s", response.Error.ErrorMessage)
-		}
-		return fmt.Errorf("重新激活失败")
-	}
-
-	return nil
 }
 
 // 批量创建邮箱地址
-func batchGenerate(config *Config, count int, labelPrefix string) ([]string, []error) {
+func batchGenerate(config *Config, count int, labelDesc string, labelFor LabelFunc) ([]string, []error) {
 	if count <= 0 {
 		return nil, []error{fmt.Errorf("批量创建数量必须大于 0")}
 	}
@@ -1812,37 +1473,55 @@ func batchGenerate(config *Config, count int, labelPrefix string) ([]string, []e
 		concurrency = count
 	}
 
-	fmt.Printf("  "+ColorCyan+"数量:"+ColorReset+" %d "+ColorDim+"|"+ColorReset+" "+ColorCyan+"标签:"+ColorReset+" %s* "+ColorDim+"|"+ColorReset+" "+ColorCyan+"并发:"+ColorReset+" %d\n\n", count, labelPrefix, concurrency)
+	fmt.Printf("  "+ColorCyan+"数量:"+ColorReset+" %d "+ColorDim+"|"+ColorReset+" "+ColorCyan+"标签:"+ColorReset+" %s "+ColorDim+"|"+ColorReset+" "+ColorCyan+"并发:"+ColorReset+" %d\n\n", count, labelDesc, concurrency)
+
+	// 超过单段数量时分段执行
+	if config.BatchChunkSize > 0 && count > config.BatchChunkSize {
+		return batchGenerateChunked(config, count, labelFor)
+	}
 
 	// 使用并发模式
 	if concurrency > 1 {
-		return batchGenerateConcurrent(config, count, labelPrefix, concurrency)
+		return batchGenerateConcurrent(config, count, labelFor, concurrency)
 	}
 
 	// 串行模式（原有逻辑）
 	emails := make([]string, 0, count)
 	errs := make([]error, 0, count)
+	gate := &rateLimitGate{}
 
-	for i := 0; i < count; i++ {
-		label := fmt.Sprintf("%s%d", labelPrefix, i+1)
+	for i := 0; i < count && !operationCanceled(); i++ {
+		label := labelFor(i + 1)
 
 		// 显示进度条
 		printProgressBar(i, count, "创建进度")
 
 		fmt.Printf("  "+ColorGray+"..."+ColorReset+" 创建邮箱 "+ColorDim+"(%s)"+ColorReset+" ... ", label)
 
-		email, err := createHME(config, label)
+		email, err := createHMEWithBackoff(config, label, gate, func(wait time.Duration) bool {
+			fmt.Printf(ColorYellow+"[~]"+ColorReset+" 被限流，%s 后重试 ... ", wait.Round(time.Second))
+			emitProgressPause("create", count, wait)
+			return true
+		})
+		// 请求被取消时不计入失败，该标签保留在断点中
+		if errors.Is(err, context.Canceled) {
+			fmt.Print(ColorYellow + "[-]" + ColorReset + " 已取消\n")
+			break
+		}
+		emitProgressItem("create", i+1, count, label, email, err)
+		currentBatchJob.Record(label, email, err)
+		updateRetryQueue(config, label, email, err)
 		if err != nil {
-			fmt.Printf(ColorRed + "[!]" + ColorReset + "\n")
+			fmt.Print(ColorRed + "[!]" + ColorReset + "\n")
 			fmt.Printf("    错误: %v\n", err)
 			errs = append(errs, err)
 		} else {
-			fmt.Printf(ColorGreen + "[+]" + ColorReset + "\n")
+			fmt.Print(ColorGreen + "[+]" + ColorReset + "\n")
 			fmt.Printf("    "+ColorCyan+"邮箱:"+ColorReset+" %s\n", email)
 			emails = append(emails, email)
 
 			// 保存邮箱到文件
-			if err := saveEmailToFile(config, email, label); err != nil {
+			if err := saveEmailToFile(config, email, label, CreationOrigin{Source: SourceBatch}); err != nil {
 				fmt.Printf("    "+ColorYellow+"警告:"+ColorReset+" 保存到文件失败: %v\n", err)
 			}
 		}
@@ -1850,33 +1529,44 @@ func batchGenerate(config *Config, count int, labelPrefix string) ([]string, []e
 		// 延迟
 		if i < count-1 && config.DelaySeconds > 0 {
 			fmt.Printf("    "+ColorDim+"等待 %ds\n"+ColorReset, config.DelaySeconds)
-			time.Sleep(time.Duration(config.DelaySeconds) * time.Second)
+			emitProgressPause("create", count, time.Duration(config.DelaySeconds)*time.Second)
+			if !sleepUnlessCanceled(time.Duration(config.DelaySeconds) * time.Second) {
+				break
+			}
 		}
 	}
 
 	// 完成进度条
-	printProgressBar(count, count, "创建进度")
+	printProgressBar(len(emails)+len(errs), count, "创建进度")
+	reportBatchCanceled(len(emails)+len(errs), count)
 	fmt.Println()
+	emitProgressDone("create", count, len(emails), len(errs))
 
 	return emails, errs
 }
 
-// 并发批量生成邮箱
-func batchGenerateConcurrent(config *Config, count int, labelPrefix string, concurrency int) ([]string, []error) {
-	// 结果通道
+// batchItemID 批量任务中单项的稳定编号（从 1 开始，按总数补零），流式输出与结果表共用，如 #03
+func batchItemID(index, total int) string {
+	return fmt.Sprintf("#%0*d", len(strconv.Itoa(total)), index)
+}
+
+// 并发批量生成邮箱：每项完成时立即按完成顺序输出一行（带稳定编号），全部结束后再按编号输出结果表
+func batchGenerateConcurrent(config *Config, count int, labelFor LabelFunc, concurrency int) ([]string, []error) {
+	// 结果
 	type result struct {
-		index int
-		email string
-		label string
-		err   error
+		email    string
+		label    string
+		err      error
+		canceled bool // 收到退出信号，未创建
 	}
 
-	resultChan := make(chan result, count)
+	results := make([]result, count)              // 按编号存放，各 goroutine 只写自己的下标
 	semaphore := make(chan struct{}, concurrency) // 并发控制
 
 	var wg sync.WaitGroup
-	var progressMutex sync.Mutex
+	var outputMutex sync.Mutex
 	completed := 0
+	gate := &rateLimitGate{} // 任一任务被限流时所有任务一起暂停
 
 	// 启动并发任务
 	for i := 0; i < count; i++ {
@@ -1888,74 +1578,80 @@ func batchGenerateConcurrent(config *Config, count int, labelPrefix string, conc
 			semaphore <- struct{}{}
 			defer func() { <-semaphore }()
 
-			label := fmt.Sprintf("%s%d", labelPrefix, index+1)
-			email, err := createHME(config, label)
-
-			// 发送结果
-			resultChan <- result{
-				index: index,
-				email: email,
-				label: label,
-				err:   err,
+			id := batchItemID(index+1, count)
+			label := labelFor(index + 1)
+			if operationCanceled() {
+				results[index] = result{label: label, canceled: true}
+				return
+			}
+			email, err := createHMEWithBackoff(config, label, gate, func(wait time.Duration) bool {
+				outputMutex.Lock()
+				fmt.Printf("  "+ColorYellow+"[~]"+ColorReset+" "+ColorDim+"%s"+ColorReset+" %s 被限流，%s 后重试\n", id, label, wait.Round(time.Second))
+				outputMutex.Unlock()
+				emitProgressPause("create", count, wait)
+				return true
+			})
+			if errors.Is(err, context.Canceled) {
+				results[index] = result{label: label, canceled: true}
+				return
 			}
+			emitProgressItem("create", index+1, count, label, email, err)
+			currentBatchJob.Record(label, email, err)
+			updateRetryQueue(config, label, email, err)
+			results[index] = result{email: email, label: label, err: err}
 
-			// 更新进度
-			progressMutex.Lock()
+			// 按完成顺序输出
+			outputMutex.Lock()
 			completed++
-			printProgressBar(completed, count, "创建进度")
-			progressMutex.Unlock()
+			if err != nil {
+				fmt.Printf("  "+ColorRed+"[!]"+ColorReset+" "+ColorDim+"%s (%d/%d)"+ColorReset+" %s: %v\n", id, completed, count, label, err)
+			} else {
+				fmt.Printf("  "+ColorGreen+"[+]"+ColorReset+" "+ColorDim+"%s (%d/%d)"+ColorReset+" %s: %s\n", id, completed, count, label, email)
+			}
+			outputMutex.Unlock()
 
 			// 延迟（避免请求过快）
 			if config.DelaySeconds > 0 {
-				time.Sleep(time.Duration(config.DelaySeconds) * time.Second)
+				emitProgressPause("create", count, time.Duration(config.DelaySeconds)*time.Second)
+				sleepUnlessCanceled(time.Duration(config.DelaySeconds) * time.Second)
 			}
 		}(i)
 	}
 
 	// 等待所有任务完成
-	go func() {
-		wg.Wait()
-		close(resultChan)
-	}()
-
-	// 收集结果
-	results := make([]result, 0, count)
-	for r := range resultChan {
-		results = append(results, r)
-	}
-
-	// 按索引排序结果
-	sortedResults := make([]result, count)
-	for _, r := range results {
-		sortedResults[r.index] = r
-	}
+	wg.Wait()
 
-	// 提取邮箱和错误
+	// 提取邮箱和错误，按编号输出结果表并保存
 	emails := make([]string, 0, count)
 	errs := make([]error, 0)
 
-	fmt.Println() // 换行
-	for _, r := range sortedResults {
-		if r.err != nil {
-			fmt.Printf("  "+ColorRed+"[!]"+ColorReset+" %s: %v\n", r.label, r.err)
+	reportBatchCanceled(completed, count)
+	printSubHeader("结果（按编号）")
+	for i, r := range results {
+		id := batchItemID(i+1, count)
+		if r.canceled {
+			fmt.Printf("  "+ColorDim+"%s - %-24s 已取消"+ColorReset+"\n", id, r.label)
+		} else if r.err != nil {
+			fmt.Printf("  "+ColorDim+"%s"+ColorReset+" "+ColorRed+"✗"+ColorReset+" %-24s "+ColorRed+"%s"+ColorReset+"\n", id, r.label, classifyFailure(r.err).Name)
 			errs = append(errs, r.err)
 		} else {
-			fmt.Printf("  "+ColorGreen+"[+]"+ColorReset+" %s: %s\n", r.label, r.email)
+			fmt.Printf("  "+ColorDim+"%s"+ColorReset+" "+ColorGreen+"✓"+ColorReset+" %-24s %s\n", id, r.label, r.email)
 			emails = append(emails, r.email)
 
 			// 保存邮箱到文件
-			if err := saveEmailToFile(config, r.email, r.label); err != nil {
+			if err := saveEmailToFile(config, r.email, r.label, CreationOrigin{Source: SourceBatch}); err != nil {
 				fmt.Printf("    "+ColorYellow+"警告:"+ColorReset+" 保存到文件失败: %v\n", err)
 			}
 		}
 	}
 
 	fmt.Println()
+	emitProgressDone("create", count, len(emails), len(errs))
 	return emails, errs
 }
 
-// ANSI 颜色代码 - 丰富多彩配色方案
-const (
+// ANSI 颜色代码 - 丰富多彩配色方案；纯文本模式下全部清空
+var (
 	ColorReset = "\033[0m"
 	ColorBold  = "\033[1m"
 	ColorDim   = "\033[2m"
@@ -1993,15 +1689,27 @@ const (
 
 // UI 辅助函数 - 多彩风格
 func printSeparator() {
+	if plainUI {
+		fmt.Println(strings.Repeat("-", 70))
+		return
+	}
 	fmt.Println(ColorCyan + strings.Repeat("─", 70) + ColorReset)
 }
 
 func printThickSeparator() {
+	if plainUI {
+		fmt.Println(strings.Repeat("=", 70))
+		return
+	}
 	fmt.Println(ColorBrightCyan + strings.Repeat("━", 70) + ColorReset)
 }
 
-// clearScreen 清屏函数
+// clearScreen 清屏函数，纯文本模式下只空一行
 func clearScreen() {
+	if plainUI {
+		fmt.Println()
+		return
+	}
 	fmt.Print("\033[2J\033[H")
 }
 
@@ -2015,6 +1723,11 @@ func printHeader(title string) {
 
 func printSubHeader(title string) {
 	fmt.Println()
+	if plainUI {
+		fmt.Printf("[ %s ]\n", title)
+		printSeparator()
+		return
+	}
 	fmt.Printf(ColorBold+ColorBrightBlue+"┌─ %s"+ColorReset+"\n", title)
 	printSeparator()
 }
@@ -2039,24 +1752,15 @@ func printStep(message string) {
 	fmt.Printf("  "+ColorDim+"..."+ColorReset+" %s\n", message)
 }
 
-// 获取终端宽度
+// 获取终端宽度，纯文本模式下固定为 80 列
 func getTerminalWidth() int {
-	type winsize struct {
-		Row    uint16
-		Col    uint16
-		Xpixel uint16
-		Ypixel uint16
+	if plainUI {
+		return plainUIWidth
 	}
-	ws := &winsize{}
-	retCode, _, _ := syscall.Syscall(syscall.SYS_IOCTL,
-		uintptr(syscall.Stdin),
-		uintptr(syscall.TIOCGWINSZ),
-		uintptr(unsafe.Pointer(ws)))
-
-	if int(retCode) == -1 {
-		return 80 // 默认宽度
+	if width, ok := terminalWidth(); ok && width > 0 {
+		return width
 	}
-	return int(ws.Col)
+	return 80 // 默认宽度
 }
 
 // 格式化邮箱地址以适应指定宽度
@@ -2071,11 +1775,34 @@ func formatEmailAddress(email string, maxWidth int) string {
 	return email[:maxWidth-3] + "..."
 }
 
+// maskEmailAddress 隐去邮箱前缀中间部分（ab****xy@icloud.com），用于截图与分享报告
+func maskEmailAddress(email string) string {
+	at := strings.LastIndex(email, "@")
+	if at < 0 {
+		at = len(email)
+	}
+	local, domain := []rune(email[:at]), email[at:]
+	if len(local) <= 4 {
+		if len(local) == 0 {
+			return email
+		}
+		return string(local[:1]) + "***" + domain
+	}
+	return string(local[:2]) + "****" + string(local[len(local)-2:]) + domain
+}
+
 func printProgressBar(current, total int, prefix string) {
 	barWidth := 40
 	if total <= 0 {
 		total = 1
 	}
+	// 纯文本模式下不原地刷新，每前进一步输出一行
+	if plainUI {
+		if current > 0 {
+			fmt.Printf("  %s %d/%d (%d%%)\n", prefix, min(current, total), total, min(current, total)*100/total)
+		}
+		return
+	}
 	if current < 0 {
 		current = 0
 	}
@@ -2125,6 +1852,9 @@ func printProgressBar(current, total int, prefix string) {
 }
 
 func withSpinner(message string, action func() error) (err error) {
+	if plainUI {
+		return runPlain(message, action)
+	}
 	// 彩色加载动画
 	frames := []string{"⠋", "⠙", "⠹", "⠸", "⠼", "⠴", "⠦", "⠧", "⠇", "⠏"}
 	colors := []string{ColorBrightCyan, ColorBrightBlue, ColorBrightMagenta, ColorBrightRed, ColorBrightYellow, ColorBrightGreen}
@@ -2137,7 +1867,7 @@ func withSpinner(message string, action func() error) (err error) {
 	var wg sync.WaitGroup
 	wg.Add(1)
 
-	go func() {
+	safetyManager.Go("spinner", func(ctx context.Context) {
 		defer wg.Done()
 		ticker := time.NewTicker(80 * time.Millisecond)
 		defer ticker.Stop()
@@ -2148,6 +1878,8 @@ func withSpinner(message string, action func() error) (err error) {
 			select {
 			case <-done:
 				return
+			case <-ctx.Done():
+				return
 			case <-ticker.C:
 				frame := frames[idx%frameCount]
 				color := ColorBrightWhite
@@ -2158,11 +1890,11 @@ func withSpinner(message string, action func() error) (err error) {
 				idx++
 			}
 		}
-	}()
+	})
 
 	defer func() {
 		if r := recover(); r != nil {
-			err = fmt.Errorf("执行过程中出现未知错误: %v", r)
+			err = recoveredPanic(r)
 		}
 
 		close(done)
@@ -2209,6 +1941,10 @@ func readInt(prompt string) (int, error) {
 }
 
 func confirmAction(message string) bool {
+	if assumeYes {
+		fmt.Printf("\n  "+ColorYellow+"?"+ColorReset+" %s "+ColorDim+"(--yes 自动确认)"+ColorReset+"\n", message)
+		return true
+	}
 	fmt.Printf("\n  "+ColorYellow+"?"+ColorReset+" %s "+ColorDim+"(y/n)"+ColorReset+": ", message)
 	reader := bufio.NewReader(os.Stdin)
 	input, _ := reader.ReadString('\n')
@@ -2241,6 +1977,15 @@ func saveEmailsToFile(emails []string, filename string) {
 func showMainMenu() {
 	printHeader("iCloud 隐藏邮箱管理工具")
 
+	config := getCurrentConfig()
+	if config != nil {
+		fmt.Println("  " + rateLimitStatus(config))
+		if line := sessionStatusLine(); line != "" {
+			fmt.Println("  " + line)
+		}
+		fmt.Println()
+	}
+
 	fmt.Println("  " + ColorGreen + "[1]" + ColorReset + " 查看邮箱列表")
 	fmt.Println("  " + ColorBlue + "[2]" + ColorReset + " 创建新邮箱 " + ColorDim + "(普通模式)" + ColorReset)
 	fmt.Println("  " + ColorBrightBlue + "[3]" + ColorReset + " 智能创建邮箱 " + ColorBrightGreen + "(推荐)" + ColorReset)
@@ -2249,11 +1994,32 @@ func showMainMenu() {
 	fmt.Println("  " + ColorRed + "[6]" + ColorReset + " 彻底删除停用的邮箱 " + ColorDim + "(不可恢复)" + ColorReset)
 	fmt.Println("  " + ColorCyan + "[7]" + ColorReset + " 重新激活停用的邮箱")
 	fmt.Println("  " + ColorBrightMagenta + "[8]" + ColorReset + " 程序设置")
+	fmt.Println("  " + ColorBrightBlue + "[m]" + ColorReset + " 编辑标签/备注 " + ColorDim + "(支持多选批量改名)" + ColorReset)
+	fmt.Println("  " + ColorBrightGreen + "[f]" + ColorReset + " 转发地址 " + ColorDim + "(查看与切换接收邮件的邮箱)" + ColorReset)
+	fmt.Println("  " + ColorCyan + "[l]" + ColorReset + " 邮箱台账 " + ColorDim + "(搜索、记录使用网站与标记、导出、与 iCloud 对比)" + ColorReset)
+	fmt.Println("  " + ColorBrightYellow + "[s]" + ColorReset + " 创建统计 " + ColorDim + "(每日成功/失败次数与失败率趋势)" + ColorReset)
+	fmt.Println("  " + ColorBrightGreen + "[e]" + ColorReset + " 导出邮箱列表 " + ColorDim + "(CSV、JSON、Bitwarden、1Password)" + ColorReset)
+	fmt.Println("  " + ColorBrightBlue + "[n]" + ColorReset + " 新服务注册 " + ColorDim + "(准备邮箱、复制、打开注册页、等待验证邮件)" + ColorReset)
+	fmt.Println("  " + ColorBrightYellow + "[v]" + ColorReset + " 重新验证会话 " + ColorDim + "(Cookie 过期时粘贴新的 curl 或导入 HAR)" + ColorReset)
+
+	if config != nil && len(config.Profiles) > 0 {
+		fmt.Println("  " + ColorBrightCyan + "[a]" + ColorReset + " 切换账号 " + ColorDim + "(当前: " + config.profileLabel() + ")" + ColorReset)
+	}
+	if config != nil {
+		if job := unfinishedBatchJob(config); job != nil {
+			fmt.Printf("  "+ColorBrightYellow+"[r]"+ColorReset+" 继续上次的批量任务 "+ColorDim+"(已完成 %d/%d)"+ColorReset+"\n", job.Done(), job.Total)
+		}
+	}
+
+	if config != nil {
+		if items := retryQueueItems(config); len(items) > 0 {
+			fmt.Println("  " + ColorBrightYellow + "[q]" + ColorReset + " 重试队列 " + ColorDim + "(" + formatRetryQueueStatus(items) + ")" + ColorReset)
+		}
+	}
 
 	// 开发者模式下显示测试选项
-	config := getCurrentConfig()
-	if config != nil && config.DeveloperMode {
-		fmt.Println("  " + ColorGray + "[9]" + ColorReset + " 测试评分算法 " + ColorDim + "(开发调试)" + ColorReset)
+	if config != nil && config.Developer.Enabled {
+		fmt.Println("  " + ColorGray + "[9]" + ColorReset + " 开发者工具 " + ColorDim + "(评分测试、模拟服务器、传输统计、功能开关、会话重放)" + ColorReset)
 	}
 	fmt.Println("  " + ColorDim + "[0]" + ColorReset + " 退出")
 
@@ -2264,16 +2030,16 @@ func showMainMenu() {
 // 查看邮箱列表
 func handleListEmails(config *Config) {
 	printHeader("邮箱列表")
-	var emails []HMEEmail
-	if err := withSpinner("获取邮箱列表", func() error {
-		var err error
-		emails, err = listHME(config)
-		return err
-	}); err != nil {
-		printError(fmt.Sprintf("获取列表失败: %v", err))
+	emails, ok := listHMEInteractive(config, "获取邮箱列表")
+	if !ok {
 		return
 	}
 
+	// 同步本地清单（记录已在 iCloud 中消失的邮箱）
+	if err := inventory.Sync(emails); err != nil {
+		printWarning(fmt.Sprintf("同步本地清单失败: %v", err))
+	}
+
 	if len(emails) == 0 {
 		printInfo("暂无邮箱")
 		return
@@ -2348,11 +2114,66 @@ func handleListEmails(config *Config) {
 		fmt.Printf("  "+ColorBrightCyan+"%2d."+ColorReset+" %s "+emailColor+"%s"+ColorReset+" %s\n",
 			i+1, statusSymbol, formattedEmail, labelDisplay)
 	}
+
+	// 查看详情
+	fmt.Println()
+	for {
+		input := readInput("输入序号查看详情 " + ColorGray + "(回车返回)" + ColorReset + ": ")
+		if input == "" {
+			return
+		}
+		idx, err := strconv.Atoi(input)
+		if err != nil || idx < 1 || idx > len(emails) {
+			printError(fmt.Sprintf("无效的序号: %s", input))
+			continue
+		}
+		showEmailDetail(emails[idx-1])
+		if strings.ToLower(readInput("输入 t 查看活动时间线 "+ColorGray+"(回车继续)"+ColorReset+": ")) == "t" {
+			showAliasTimeline(config, emails[idx-1])
+		}
+	}
+}
+
+// 显示邮箱详情
+func showEmailDetail(email HMEEmail) {
+	printSubHeader("邮箱详情")
+
+	status := ColorGreen + "激活" + ColorReset
+	if !email.IsActive {
+		status = ColorYellow + "停用" + ColorReset
+	}
+	label := email.Label
+	if label == "" {
+		label = ColorDim + "(无标签)" + ColorReset
+	}
+
+	fmt.Printf("  "+ColorCyan+"邮箱:"+ColorReset+" "+ColorBold+"%s"+ColorReset+"\n", email.HME)
+	fmt.Printf("  "+ColorCyan+"标签:"+ColorReset+" %s\n", label)
+	if email.Note != "" {
+		fmt.Printf("  "+ColorCyan+"备注:"+ColorReset+" %s\n", email.Note)
+	}
+	fmt.Printf("  "+ColorCyan+"状态:"+ColorReset+" %s\n", status)
+	if email.CreateTimestamp > 0 {
+		fmt.Printf("  "+ColorCyan+"创建:"+ColorReset+" %s\n", time.UnixMilli(email.CreateTimestamp).Format("2006-01-02 15:04"))
+	}
+	if email.ForwardToEmail != "" {
+		fmt.Printf("  "+ColorCyan+"转发:"+ColorReset+" %s\n", email.ForwardToEmail)
+	}
+
+	if record, ok := inventory.Get(email.HME); ok {
+		fmt.Printf("  "+ColorCyan+"来源:"+ColorReset+" %s\n", formatOrigin(record.Origin()))
+	} else {
+		fmt.Print("  " + ColorCyan + "来源:" + ColorReset + " " + ColorDim + "未知 (非本工具创建或早于本地清单)" + ColorReset + "\n")
+	}
+	fmt.Printf("  "+ColorDim+"ID: %s"+ColorReset+"\n\n", email.AnonymousID)
 }
 
 // 创建单个邮箱
 func handleCreateEmail(config *Config) {
 	printHeader("创建新邮箱")
+	if !waitForCooldown(config) {
+		return
+	}
 
 	label := readInput("邮箱标签: ")
 	if label == "" {
@@ -2361,17 +2182,23 @@ func handleCreateEmail(config *Config) {
 	}
 
 	var email string
-	if err := withSpinner("创建邮箱", func() error {
-		var err error
-		email, err = createHME(config, label)
-		return err
-	}); err != nil {
-		printError(fmt.Sprintf("创建失败: %v", err))
-		return
+	createdAt := time.Now()
+	for {
+		err := withSpinner("创建邮箱", func() error {
+			var err error
+			email, err = createHME(config, label)
+			return err
+		})
+		if err == nil {
+			break
+		}
+		if !reportAPIError(config, "创建", err) {
+			return
+		}
 	}
 
 	// 保存邮箱到文件
-	if err := saveEmailToFile(config, email, label); err != nil {
+	if err := saveEmailToFile(config, email, label, CreationOrigin{Source: SourceCLI}); err != nil {
 		printWarning(fmt.Sprintf("保存邮箱到文件失败: %v", err))
 	}
 
@@ -2380,11 +2207,16 @@ func handleCreateEmail(config *Config) {
 	fmt.Printf("\n  "+ColorBrightMagenta+"@ 邮箱: "+ColorReset+ColorBold+ColorBrightWhite+"%s"+ColorReset+"\n", email)
 	fmt.Printf("  "+ColorBrightBlue+"# 标签: "+ColorReset+ColorCyan+"%s"+ColorReset+"\n", label)
 	fmt.Printf("  "+ColorBrightGreen+"& 时间: "+ColorReset+ColorGreen+"%s"+ColorReset+"\n", time.Now().Format("2006-01-02 15:04"))
+
+	offerVerificationWait(config, email, createdAt)
 }
 
 // 智能创建邮箱
 func handleSmartCreateEmail(config *Config) {
 	printHeader("智能创建邮箱")
+	if !waitForCooldown(config) {
+		return
+	}
 
 	label := readInput("邮箱标签: ")
 	if label == "" {
@@ -2392,11 +2224,22 @@ func handleSmartCreateEmail(config *Config) {
 		return
 	}
 
+	size, top := candidatePoolSize(config)
+	fmt.Printf("  "+ColorCyan+"[1]"+ColorReset+" 标准 "+ColorDim+"(最多 %d 个候选)"+ColorReset+"\n", config.EmailQuality.MaxRegenerateCount)
+	fmt.Printf("  "+ColorCyan+"[2]"+ColorReset+" 候选池 "+ColorDim+"(生成 %d 个，从前 %d 名中选一个或多个)"+ColorReset+"\n", size, top)
+	if strings.TrimSpace(readInput("生成方式 "+ColorGray+"(回车为 1)"+ColorReset+": ")) == "2" {
+		handleCandidatePool(config, label)
+		return
+	}
+
 	// 生成智能邮箱
+	createdAt := time.Now()
 	result, err := generateSmartEmail(config, label)
-	if err != nil {
-		printError(fmt.Sprintf("智能生成失败: %v", err))
-		return
+	for err != nil {
+		if !reportAPIError(config, "智能生成", err) {
+			return
+		}
+		result, err = generateSmartEmail(config, label)
 	}
 
 	var finalEmail string
@@ -2406,26 +2249,30 @@ func handleSmartCreateEmail(config *Config) {
 		printSuccess("邮箱创建成功 (自动选择)")
 	} else {
 		// 需要手动选择
-		if config.EmailQuality.AllowManual {
+		if config.EmailQuality.Selection.AllowManual {
 			finalEmail, err = selectEmailManually(result, config, label)
-			if err != nil {
-				printError(fmt.Sprintf("手动选择失败: %v", err))
-				return
+			for err != nil {
+				if !reportAPIError(config, "手动选择", err) {
+					return
+				}
+				finalEmail, err = selectEmailManually(result, config, label)
 			}
 			printSuccess("邮箱创建成功 (手动选择)")
 		} else {
 			// 自动选择最佳
 			finalEmail, err = reserveHME(config, result.BestEmail, label)
-			if err != nil {
-				printError(fmt.Sprintf("确认创建失败: %v", err))
-				return
+			for err != nil {
+				if !reportAPIError(config, "确认创建", err) {
+					return
+				}
+				finalEmail, err = reserveHME(config, result.BestEmail, label)
 			}
 			printSuccess("邮箱创建成功 (自动选择最佳)")
 		}
 	}
 
 	// 保存邮箱到文件
-	if err := saveEmailToFile(config, finalEmail, label); err != nil {
+	if err := saveEmailToFile(config, finalEmail, label, CreationOrigin{Source: SourceSmart}); err != nil {
 		printWarning(fmt.Sprintf("保存邮箱到文件失败: %v", err))
 	}
 
@@ -2433,6 +2280,8 @@ func handleSmartCreateEmail(config *Config) {
 	fmt.Println()
 	fmt.Printf("  "+ColorBrightMagenta+"邮箱: "+ColorReset+ColorBold+"%s"+ColorReset+" "+ColorDim+"(分数: %d, 尝试: %d次)"+ColorReset+"\n",
 		finalEmail, result.BestScore, result.TotalTries)
+
+	offerVerificationWait(config, finalEmail, createdAt)
 }
 
 // 程序设置
@@ -2440,16 +2289,18 @@ func handleProgramSettings(config *Config) {
 	for {
 		printHeader("程序设置")
 
-		fmt.Printf("  " + ColorBold + "当前配置" + ColorReset + "\n\n")
-		fmt.Printf("  " + ColorGreen + "[1]" + ColorReset + " 邮箱质量设置\n")
-		fmt.Printf("  " + ColorBlue + "[2]" + ColorReset + " 邮箱保存设置\n")
-		fmt.Printf("  "+ColorYellow+"[3]"+ColorReset+" 开发者模式: %s\n", formatBoolSetting(config.DeveloperMode))
-		fmt.Printf("  " + ColorDim + "[0]" + ColorReset + " 返回主菜单\n")
+		fmt.Print("  " + ColorBold + "当前配置" + ColorReset + "\n\n")
+		fmt.Print("  " + ColorGreen + "[1]" + ColorReset + " 邮箱质量设置\n")
+		fmt.Print("  " + ColorBlue + "[2]" + ColorReset + " 邮箱保存设置\n")
+		fmt.Printf("  "+ColorYellow+"[3]"+ColorReset+" 开发者模式: %s\n", formatBoolSetting(config.Developer.Enabled))
+		fmt.Print("  " + ColorMagenta + "[4]" + ColorReset + " 从 curl 更新账号 " + ColorDim + "(Cookie 过期后重新抓包)" + ColorReset + "\n")
+		fmt.Print("  " + ColorCyan + "[5]" + ColorReset + " 从 HAR 文件更新账号 " + ColorDim + "(浏览器导出的网络记录)" + ColorReset + "\n")
+		fmt.Print("  " + ColorDim + "[0]" + ColorReset + " 返回主菜单\n")
 
 		printSeparator()
 		fmt.Println()
 
-		choice := readInput("选择设置项 (0-3): ")
+		choice := readInput("选择设置项 (0-5): ")
 		choice = strings.TrimSpace(choice)
 
 		switch choice {
@@ -2458,12 +2309,16 @@ func handleProgramSettings(config *Config) {
 		case "2":
 			handleEmailSaveSettings(config)
 		case "3":
-			config.DeveloperMode = !config.DeveloperMode
-			saveConfigWithMessage(config, fmt.Sprintf("开发者模式已设置为: %v", config.DeveloperMode))
+			config.Developer.Enabled = !config.Developer.Enabled
+			saveConfigWithMessage(config, fmt.Sprintf("开发者模式已设置为: %v", config.Developer.Enabled))
+		case "4":
+			handleImportCurl(config)
+		case "5":
+			handleImportHAR(config)
 		case "0":
 			return
 		default:
-			printError("无效选择，请输入 0-3")
+			printError("无效选择，请输入 0-5")
 		}
 	}
 }
@@ -2473,21 +2328,23 @@ func handleEmailQualitySettings(config *Config) {
 	for {
 		printHeader("邮箱质量设置")
 
-		fmt.Printf("  " + ColorBold + "当前配置" + ColorReset + "\n\n")
+		fmt.Print("  " + ColorBold + "当前配置" + ColorReset + "\n\n")
 		fmt.Printf("  "+ColorGreen+"[1]"+ColorReset+" 自动选择: %s\n", formatBoolSetting(config.EmailQuality.AutoSelect))
 		fmt.Printf("  "+ColorBlue+"[2]"+ColorReset+" 最低分数: "+ColorCyan+"%d"+ColorReset+"/100\n", config.EmailQuality.MinScore)
 		fmt.Printf("  "+ColorYellow+"[3]"+ColorReset+" 最大尝试: "+ColorCyan+"%d"+ColorReset+" 次\n", config.EmailQuality.MaxRegenerateCount)
-		fmt.Printf("  "+ColorMagenta+"[4]"+ColorReset+" 显示详分: %s\n", formatBoolSetting(config.EmailQuality.ShowScores))
-		fmt.Printf("  "+ColorCyan+"[5]"+ColorReset+" 允许手动: %s\n", formatBoolSetting(config.EmailQuality.AllowManual))
-		fmt.Printf("  " + ColorBrightBlue + "[6]" + ColorReset + " 评分权重设置\n")
-		fmt.Printf("  " + ColorBrightGreen + "[7]" + ColorReset + " 重置为默认值\n")
-		fmt.Printf("  " + ColorBrightYellow + "[8]" + ColorReset + " 邮箱保存设置\n")
-		fmt.Printf("  " + ColorDim + "[0]" + ColorReset + " 返回主菜单\n")
+		fmt.Printf("  "+ColorMagenta+"[4]"+ColorReset+" 显示详分: %s\n", formatBoolSetting(config.EmailQuality.Selection.ShowScores))
+		fmt.Printf("  "+ColorCyan+"[5]"+ColorReset+" 允许手动: %s\n", formatBoolSetting(config.EmailQuality.Selection.AllowManual))
+		fmt.Print("  " + ColorBrightBlue + "[6]" + ColorReset + " 评分权重设置\n")
+		fmt.Print("  " + ColorBrightGreen + "[7]" + ColorReset + " 重置为默认值\n")
+		fmt.Print("  " + ColorBrightYellow + "[8]" + ColorReset + " 邮箱保存设置\n")
+		poolSize, poolTop := candidatePoolSize(config)
+		fmt.Printf("  "+ColorBrightMagenta+"[9]"+ColorReset+" 候选池: "+ColorCyan+"%d"+ColorReset+" 个，显示前 "+ColorCyan+"%d"+ColorReset+" 个\n", poolSize, poolTop)
+		fmt.Print("  " + ColorDim + "[0]" + ColorReset + " 返回主菜单\n")
 
 		printSeparator()
 		fmt.Println()
 
-		choice := readInput("选择设置项 (0-8): ")
+		choice := readInput("选择设置项 (0-9): ")
 		choice = strings.TrimSpace(choice)
 
 		switch choice {
@@ -2511,11 +2368,11 @@ func handleEmailQualitySettings(config *Config) {
 				saveConfigWithMessage(config, fmt.Sprintf("最大尝试次数已设置为: %d", tries))
 			}
 		case "4":
-			config.EmailQuality.ShowScores = !config.EmailQuality.ShowScores
-			saveConfigWithMessage(config, fmt.Sprintf("显示详细评分已设置为: %v", config.EmailQuality.ShowScores))
+			config.EmailQuality.Selection.ShowScores = !config.EmailQuality.Selection.ShowScores
+			saveConfigWithMessage(config, fmt.Sprintf("显示详细评分已设置为: %v", config.EmailQuality.Selection.ShowScores))
 		case "5":
-			config.EmailQuality.AllowManual = !config.EmailQuality.AllowManual
-			saveConfigWithMessage(config, fmt.Sprintf("允许手动选择已设置为: %v", config.EmailQuality.AllowManual))
+			config.EmailQuality.Selection.AllowManual = !config.EmailQuality.Selection.AllowManual
+			saveConfigWithMessage(config, fmt.Sprintf("允许手动选择已设置为: %v", config.EmailQuality.Selection.AllowManual))
 		case "6":
 			handleWeightSettings(config)
 		case "7":
@@ -2523,10 +2380,23 @@ func handleEmailQualitySettings(config *Config) {
 			saveConfigWithMessage(config, "已重置为默认设置")
 		case "8":
 			handleEmailSaveSettings(config)
+		case "9":
+			size, err := readInt(fmt.Sprintf("候选池大小 (1-%d): ", maxCandidatePoolSize))
+			if err != nil || size < 1 || size > maxCandidatePoolSize {
+				printError(fmt.Sprintf("请输入 1-%d 之间的数字", maxCandidatePoolSize))
+				continue
+			}
+			top, err := readInt(fmt.Sprintf("显示前几个 (1-%d): ", size))
+			if err != nil || top < 1 || top > size {
+				printError(fmt.Sprintf("请输入 1-%d 之间的数字", size))
+				continue
+			}
+			config.EmailQuality.PoolSize, config.EmailQuality.PoolTop = size, top
+			saveConfigWithMessage(config, fmt.Sprintf("候选池已设置为: %d 个，显示前 %d 个", size, top))
 		case "0":
 			return
 		default:
-			printError("无效选择，请输入 0-8")
+			printError("无效选择，请输入 0-9")
 		}
 	}
 }
@@ -2539,17 +2409,37 @@ func formatBoolSetting(value bool) string {
 	return ColorRed + "禁用" + ColorReset
 }
 
-// 保存邮箱到文件
-func saveEmailToFile(config *Config, email, label string) error {
+// emailListMutex 服务模式下多个请求可能同时追加邮箱列表文件
+var emailListMutex sync.Mutex
+
+// 保存邮箱到文件（同时记录到本地清单）；先写邮箱列表，清单写入失败单独提示，不影响列表中的记录
+func saveEmailToFile(config *Config, email, label string, origin CreationOrigin) error {
+	err := appendEmailList(config, email, label, origin)
+
+	// 本地清单始终记录创建来源与 anonymousId
+	if inventory != nil {
+		anonymousID, _ := createdAnonymousIDs.LoadAndDelete(email)
+		id, _ := anonymousID.(string)
+		if invErr := inventory.RecordCreation(email, id, label, origin, evaluateEmailQuality(email, config.EmailQuality)); invErr != nil {
+			printWarning(fmt.Sprintf("记录本地清单失败: %v", invErr))
+		}
+	}
+	return err
+}
+
+// appendEmailList 把创建记录追加到邮箱列表文件，未启用 save_generated_emails 时不写入
+func appendEmailList(config *Config, email, label string, origin CreationOrigin) error {
 	if !config.SaveGeneratedEmails {
 		return nil // 如果未启用保存功能，直接返回
 	}
 
 	// 创建邮箱记录
 	timestamp := time.Now().Format("2006-01-02 15:04:05")
-	record := fmt.Sprintf("[%s] @ 邮箱: %s | # 标签: %s\n", timestamp, email, label)
+	record := fmt.Sprintf("[%s] @ 邮箱: %s | # 标签: %s | 来源: %s\n", timestamp, email, label, formatOrigin(origin))
 
 	// 追加到文件
+	emailListMutex.Lock()
+	defer emailListMutex.Unlock()
 	file, err := os.OpenFile(config.EmailListFile, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
 	if err != nil {
 		return fmt.Errorf("无法打开邮箱保存文件: %v", err)
@@ -2568,10 +2458,10 @@ func handleEmailSaveSettings(config *Config) {
 	for {
 		printHeader("邮箱保存设置")
 
-		fmt.Printf("  " + ColorBold + "当前配置" + ColorReset + "\n\n")
+		fmt.Print("  " + ColorBold + "当前配置" + ColorReset + "\n\n")
 		fmt.Printf("  "+ColorGreen+"[1]"+ColorReset+" 保存生成的邮箱: %s\n", formatBoolSetting(config.SaveGeneratedEmails))
 		fmt.Printf("  "+ColorBlue+"[2]"+ColorReset+" 保存文件路径: "+ColorCyan+"%s"+ColorReset+"\n", config.EmailListFile)
-		fmt.Printf("  " + ColorDim + "[0]" + ColorReset + " 返回上级菜单\n")
+		fmt.Print("  " + ColorDim + "[0]" + ColorReset + " 返回上级菜单\n")
 
 		printSeparator()
 		fmt.Println()
@@ -2606,20 +2496,21 @@ func handleWeightSettings(config *Config) {
 		printHeader("评分权重设置")
 
 		weights := &config.EmailQuality.Weights
-		total := weights.PrefixStructure + weights.Length + weights.Readability + weights.Security
+		total := weights.PrefixStructure + weights.Length + weights.Readability + weights.Security + weights.Randomness
 
 		fmt.Printf("  "+ColorBold+"当前权重配置"+ColorReset+" "+ColorDim+"(总计: %d)"+ColorReset+"\n\n", total)
 		fmt.Printf("  "+ColorGreen+"[1]"+ColorReset+" 前缀结构: "+ColorCyan+"%d"+ColorReset+"\n", weights.PrefixStructure)
 		fmt.Printf("  "+ColorBlue+"[2]"+ColorReset+" 长度评分: "+ColorCyan+"%d"+ColorReset+"\n", weights.Length)
 		fmt.Printf("  "+ColorYellow+"[3]"+ColorReset+" 可读性评分: "+ColorCyan+"%d"+ColorReset+"\n", weights.Readability)
 		fmt.Printf("  "+ColorMagenta+"[4]"+ColorReset+" 安全性评分: "+ColorCyan+"%d"+ColorReset+"\n", weights.Security)
-		fmt.Printf("  " + ColorBrightGreen + "[5]" + ColorReset + " 重置为推荐值\n")
-		fmt.Printf("  " + ColorDim + "[0]" + ColorReset + " 返回上级菜单\n")
+		fmt.Printf("  "+ColorBrightBlue+"[5]"+ColorReset+" 自然度评分: "+ColorCyan+"%d"+ColorReset+" "+ColorDim+"(不像随机字符串)"+ColorReset+"\n", weights.Randomness)
+		fmt.Print("  " + ColorBrightGreen + "[6]" + ColorReset + " 重置为推荐值\n")
+		fmt.Print("  " + ColorDim + "[0]" + ColorReset + " 返回上级菜单\n")
 
 		printSeparator()
 		fmt.Println()
 
-		choice := readInput("选择权重项 (0-5): ")
+		choice := readInput("选择权重项 (0-6): ")
 		choice = strings.TrimSpace(choice)
 
 		switch choice {
@@ -2656,16 +2547,25 @@ func handleWeightSettings(config *Config) {
 				saveConfigWithMessage(config, fmt.Sprintf("安全性权重已设置为: %d", weight))
 			}
 		case "5":
+			weight, err := readInt("输入自然度权重 (0-100): ")
+			if err != nil || weight < 0 || weight > 100 {
+				printError("请输入 0-100 之间的数字")
+			} else {
+				weights.Randomness = weight
+				saveConfigWithMessage(config, fmt.Sprintf("自然度权重已设置为: %d", weight))
+			}
+		case "6":
 			// 推荐权重配置
 			weights.PrefixStructure = 40
 			weights.Length = 20
 			weights.Readability = 25
 			weights.Security = 15
+			weights.Randomness = 20
 			saveConfigWithMessage(config, "已重置为推荐权重配置")
 		case "0":
 			return
 		default:
-			printError("无效选择，请输入 0-5")
+			printError("无效选择，请输入 0-6")
 		}
 	}
 }
@@ -2676,14 +2576,15 @@ func resetToDefaults(config *Config) {
 		AutoSelect:         false,
 		MinScore:           70,
 		MaxRegenerateCount: 3,
-		ShowScores:         true,
-		AllowManual:        true,
-		ShowAllEmails:      true,
+		PoolSize:           defaultPoolSize,
+		PoolTop:            defaultPoolTop,
+		Selection:          SelectionConfig{ShowScores: true, AllowManual: true, ShowAllEmails: true},
 		Weights: ScoreWeights{
 			PrefixStructure: 40,
 			Length:          20,
 			Readability:     25,
 			Security:        15,
+			Randomness:      20,
 		},
 	}
 }
@@ -2691,13 +2592,8 @@ func resetToDefaults(config *Config) {
 // 停用邮箱
 func handleDeleteEmails(config *Config) {
 	printHeader("停用邮箱")
-	var emails []HMEEmail
-	if err := withSpinner("正在获取邮箱列表", func() error {
-		var err error
-		emails, err = listHME(config)
-		return err
-	}); err != nil {
-		printError(fmt.Sprintf("获取邮箱列表失败: %v", err))
+	emails, ok := listHMEInteractive(config, "正在获取邮箱列表")
+	if !ok {
 		return
 	}
 
@@ -2755,7 +2651,7 @@ func handleDeleteEmails(config *Config) {
 	}
 
 	printInfo("停用后可重新激活")
-	if !confirmAction("确认停用这些邮箱") {
+	if !confirmOperation(config.Safety.Deactivate, "确认停用这些邮箱", len(toDeactivate), false) {
 		printInfo("已取消")
 		return
 	}
@@ -2771,12 +2667,16 @@ func handleDeleteEmails(config *Config) {
 
 		err := deactivateHME(config, email.AnonymousID)
 		if err != nil {
-			fmt.Printf(ColorRed + "[!]" + ColorReset + "\n")
+			fmt.Print(ColorRed + "[!]" + ColorReset + "\n")
 			fmt.Printf("    错误: %v\n", err)
 			failCount++
 		} else {
-			fmt.Printf(ColorGreen + "[+]" + ColorReset + "\n")
+			fmt.Print(ColorGreen + "[+]" + ColorReset + "\n")
 			successCount++
+
+			if err := inventory.RecordEvent(email, InventoryEventDeactivated, CreationOrigin{Source: SourceCLI}); err != nil {
+				fmt.Printf("    "+ColorYellow+"警告:"+ColorReset+" 记录本地清单失败: %v\n", err)
+			}
 		}
 
 		if i < len(toDeactivate)-1 {
@@ -2800,6 +2700,9 @@ func handleDeleteEmails(config *Config) {
 // 批量创建邮箱
 func handleBatchCreate(config *Config) {
 	printHeader("批量创建邮箱")
+	if !waitForCooldown(config) {
+		return
+	}
 
 	count, err := readInt("创建数量: ")
 	if err != nil || count <= 0 {
@@ -2815,14 +2718,89 @@ func handleBatchCreate(config *Config) {
 		}
 	}
 
-	labelPrefix := readInput("标签前缀 " + ColorGray + "(默认: auto-)" + ColorReset + ": ")
-	if labelPrefix == "" {
-		labelPrefix = "auto-"
+	printInfo("标签模式: [1] 前缀+序号 (auto-1, auto-2...)  [2] 随机可读标签 (brave-otter-042)  [3] 从文件读取 (每行一个)  [4] 按权重轮换前缀 (shop-:3,news-:1)  [5] 标签模板 (shop-{{date:2006-01}}-{{n}})")
+	labelMode := readInput("标签模式 " + ColorGray + "(默认: 1)" + ColorReset + ": ")
+
+	var labelFor LabelFunc
+	var labelDesc string
+	switch labelMode {
+	case "", "1":
+		labelPrefix := readInput("标签前缀 " + ColorGray + "(默认: auto-)" + ColorReset + ": ")
+		if labelPrefix == "" {
+			labelPrefix = "auto-"
+		}
+		labelFor = sequentialLabels(labelPrefix)
+		labelDesc = labelPrefix + "*"
+	case "2":
+		labelPrefix := readInput("标签前缀 " + ColorGray + "(可留空)" + ColorReset + ": ")
+		// 读取现有标签以避免与历史标签重复，失败时仅保证批次内唯一
+		var existing []string
+		if emails, err := listHME(config); err == nil {
+			for _, email := range emails {
+				existing = append(existing, email.Label)
+			}
+		}
+		labelFor = readableLabels(labelPrefix, existing)
+		labelDesc = labelPrefix + "<形容词>-<名词>-<数字>"
+	case "3":
+		path := readInput("标签文件路径: ")
+		labels, err := readLabelsFile(path)
+		if err != nil {
+			printError(err.Error())
+			return
+		}
+		if len(labels) != count {
+			printInfo(fmt.Sprintf("按标签文件创建 %d 个邮箱", len(labels)))
+			count = len(labels)
+		}
+		labelFor = listLabels(labels)
+		labelDesc = "来自 " + path
+	case "4":
+		weights := config.LabelPrefixWeights
+		prompt := "前缀权重 " + ColorGray + "(如 shop-:3,news-:1,forum-:1)" + ColorReset + ": "
+		if len(weights) > 0 {
+			prompt = "前缀权重 " + ColorGray + "(默认: " + describePrefixWeights(weights) + ")" + ColorReset + ": "
+		}
+		if spec := readInput(prompt); spec != "" || len(weights) == 0 {
+			parsed, err := parsePrefixWeights(spec)
+			if err != nil {
+				printError(err.Error())
+				return
+			}
+			weights = parsed
+		}
+		// 读取现有标签，各前缀序号接在已有最大序号之后
+		existing, _ := listHME(config)
+		labelFor = weightedPrefixLabels(weights, existing)
+		labelDesc = describePrefixWeights(weights)
+	case "5":
+		template := config.LabelTemplate
+		printInfo("占位符: {{n}} 序号 ({{n:3}} 补零)、{{date}} 日期 ({{date:2006-01}} 自定格式)、{{word}} 随机名词、{{adj}} 随机形容词、{{rand:4}} 随机十六进制")
+		prompt := "标签模板 " + ColorGray + "(如 shop-{{date:2006-01}}-{{n}})" + ColorReset + ": "
+		if template != "" {
+			prompt = "标签模板 " + ColorGray + "(默认: " + template + ")" + ColorReset + ": "
+		}
+		if input := readInput(prompt); input != "" {
+			template = input
+		}
+		generated, err := templateLabels(template)
+		if err != nil {
+			printError(err.Error())
+			return
+		}
+		labelFor = generated
+		labelDesc = template
+		for i := 1; i <= count && i <= 3; i++ {
+			fmt.Printf("    "+ColorDim+"%d. %s"+ColorReset+"\n", i, labelFor(i))
+		}
+	default:
+		printError("无效的标签模式")
+		return
 	}
 
-	fmt.Printf("\n  " + ColorBold + "创建计划" + ColorReset + "\n\n")
+	fmt.Print("\n  " + ColorBold + "创建计划" + ColorReset + "\n\n")
 	fmt.Printf("  "+ColorCyan+"数量:"+ColorReset+" "+ColorBold+"%d"+ColorReset+" 个\n", count)
-	fmt.Printf("  "+ColorCyan+"标签:"+ColorReset+" %s1, %s2, %s3, ...\n", labelPrefix, labelPrefix, labelPrefix)
+	fmt.Printf("  "+ColorCyan+"标签:"+ColorReset+" %s\n", labelDesc)
 	fmt.Printf("  "+ColorCyan+"延迟:"+ColorReset+" %d 秒\n", config.DelaySeconds)
 
 	estimatedTime := count * config.DelaySeconds
@@ -2832,16 +2810,42 @@ func handleBatchCreate(config *Config) {
 		printInfo("已取消")
 		return
 	}
+	if !confirmQuota(config) {
+		return
+	}
+
+	safetyManager.AddOperation()
+	defer safetyManager.DoneOperation()
+	startBatchReport(labelDesc, count)
+	emails, errors := runTrackedBatch(config, count, labelDesc, labelFor)
+	showBatchResult(config, emails, errors)
+}
 
-	emails, errors := batchGenerate(config, count, labelPrefix)
+// handleResumeBatch 继续上次未完成的批量任务
+func handleResumeBatch(config *Config) {
+	printHeader("继续批量任务")
+	if !waitForCooldown(config) || !confirmQuota(config) {
+		return
+	}
+	safetyManager.AddOperation()
+	defer safetyManager.DoneOperation()
+	emails, errors, err := resumeBatchJob(config)
+	if err != nil {
+		printError(err.Error())
+		return
+	}
+	showBatchResult(config, emails, errors)
+}
 
+// showBatchResult 显示菜单批量创建的结果
+func showBatchResult(config *Config, emails []string, errors []error) {
 	printSeparator()
 	if len(emails) > 0 {
 		printSuccess(fmt.Sprintf("批量创建完成 (成功 %d 个)", len(emails)))
 	}
-	if len(errors) > 0 {
-		printError(fmt.Sprintf("失败 %d 个", len(errors)))
-	}
+	printFailureSummary(errors)
+	finishBatchReport(config, len(emails), errors)
+	announceBatch(config, len(emails), len(errors))
 
 	if len(emails) > 0 {
 		fmt.Println("\n  " + ColorBold + "创建结果" + ColorReset)
@@ -2863,13 +2867,8 @@ func handlePermanentDelete(config *Config) {
 	printHeader("彻底删除停用的邮箱（不可恢复！）")
 	printWarning("此操作将永久删除邮箱，无法恢复！")
 
-	var emails []HMEEmail
-	if err := withSpinner("正在获取邮箱列表", func() error {
-		var err error
-		emails, err = listHME(config)
-		return err
-	}); err != nil {
-		printError(fmt.Sprintf("获取邮箱列表失败: %v", err))
+	emails, ok := listHMEInteractive(config, "正在获取邮箱列表")
+	if !ok {
 		return
 	}
 
@@ -2927,7 +2926,7 @@ func handlePermanentDelete(config *Config) {
 	}
 
 	printWarning("此操作不可恢复")
-	if !confirmAction("确认彻底删除这些邮箱") {
+	if !confirmOperation(config.Safety.PermanentDelete, "确认彻底删除这些邮箱", len(toDelete), true) {
 		printInfo("已取消")
 		return
 	}
@@ -2943,12 +2942,17 @@ func handlePermanentDelete(config *Config) {
 
 		err := permanentDeleteHME(config, email.AnonymousID)
 		if err != nil {
-			fmt.Printf(ColorRed + "[!]" + ColorReset + "\n")
+			fmt.Print(ColorRed + "[!]" + ColorReset + "\n")
 			fmt.Printf("    错误: %v\n", err)
 			failCount++
 		} else {
-			fmt.Printf(ColorGreen + "[+]" + ColorReset + "\n")
+			fmt.Print(ColorGreen + "[+]" + ColorReset + "\n")
 			successCount++
+
+			// 保留墓碑记录
+			if err := inventory.RecordDeletion(email); err != nil {
+				fmt.Printf("    "+ColorYellow+"警告:"+ColorReset+" 记录本地墓碑失败: %v\n", err)
+			}
 		}
 
 		if i < len(toDelete)-1 {
@@ -2972,13 +2976,8 @@ func handlePermanentDelete(config *Config) {
 // 重新激活停用的邮箱
 func handleReactivate(config *Config) {
 	printHeader("重新激活停用的邮箱")
-	var emails []HMEEmail
-	if err := withSpinner("正在获取邮箱列表", func() error {
-		var err error
-		emails, err = listHME(config)
-		return err
-	}); err != nil {
-		printError(fmt.Sprintf("获取邮箱列表失败: %v", err))
+	emails, ok := listHMEInteractive(config, "正在获取邮箱列表")
+	if !ok {
 		return
 	}
 
@@ -3051,12 +3050,16 @@ func handleReactivate(config *Config) {
 
 		err := reactivateHME(config, email.AnonymousID)
 		if err != nil {
-			fmt.Printf(ColorRed + "[!]" + ColorReset + "\n")
+			fmt.Print(ColorRed + "[!]" + ColorReset + "\n")
 			fmt.Printf("    错误: %v\n", err)
 			failCount++
 		} else {
-			fmt.Printf(ColorGreen + "[+]" + ColorReset + "\n")
+			fmt.Print(ColorGreen + "[+]" + ColorReset + "\n")
 			successCount++
+
+			if err := inventory.RecordEvent(email, InventoryEventReactivated, CreationOrigin{Source: SourceCLI}); err != nil {
+				fmt.Printf("    "+ColorYellow+"警告:"+ColorReset+" 记录本地清单失败: %v\n", err)
+			}
 		}
 
 		if i < len(toReactivate)-1 {
@@ -3087,6 +3090,7 @@ func testEmailScoring() {
 		Length:          20,
 		Readability:     25,
 		Security:        15,
+		Randomness:      20,
 	}
 
 	// 测试邮箱列表
@@ -3095,6 +3099,7 @@ func testEmailScoring() {
 		"johnsmith@icloud.com",                        // 纯字母
 		"john123@icloud.com",                          // 字母+数字
 		"a3x9kf@icloud.com",                           // 随机字符
+		"xqzvbkwt@icloud.com",                         // 随机字母
 		"test_temp@icloud.com",                        // 临时邮箱特征
 		"kettles.doltish_8p@icloud.com",               // 实际生成的例子
 		"user@gmail.com",                              // Gmail域名
@@ -3103,43 +3108,15 @@ func testEmailScoring() {
 		"mike.work.2024@icloud.com",                   // 复杂结构
 	}
 
-	fmt.Printf("  "+ColorBold+"权重配置"+ColorReset+": 结构(%d) 长度(%d) 可读(%d) 安全(%d)\n\n",
-		weights.PrefixStructure, weights.Length, weights.Readability, weights.Security)
+	fmt.Printf("  "+ColorBold+"权重配置"+ColorReset+": 结构(%d) 长度(%d) 可读(%d) 安全(%d) 自然(%d)\n\n",
+		weights.PrefixStructure, weights.Length, weights.Readability, weights.Security, weights.Randomness)
 
+	engine := scoringEngineFor(EmailQualityConfig{Weights: weights})
 	for i, email := range testEmails {
-		score := evaluateEmailQuality(email, weights)
-
-		// 分离前缀和域名用于详细分析
-		parts := strings.Split(email, "@")
-		prefix := parts[0]
-		domain := parts[1]
-
-		// 计算各项分数
-		structureScore := evaluatePrefixStructure(prefix)
-		lengthScore := evaluateLength(prefix)
-		readabilityScore := evaluateReadability(prefix)
-		securityScore := evaluateSecurity(prefix, domain)
-
-		// 评级和颜色
-		var grade, gradeColor string
-		if score >= 85 {
-			grade = "优秀"
-			gradeColor = ColorBrightGreen
-		} else if score >= 70 {
-			grade = "良好"
-			gradeColor = ColorGreen
-		} else if score >= 60 {
-			grade = "一般"
-			gradeColor = ColorYellow
-		} else {
-			grade = "较差"
-			gradeColor = ColorRed
-		}
-
+		score, breakdown := engine.Score(email)
 		fmt.Printf("  "+ColorBrightCyan+"%2d."+ColorReset+" %s\n", i+1, email)
-		fmt.Printf("      "+ColorMagenta+"总分:"+ColorReset+" "+gradeColor+"%d"+ColorReset+"/100 "+ColorDim+"("+gradeColor+"%s"+ColorReset+ColorDim+")"+ColorReset+"\n", score, grade)
-		fmt.Printf("      "+ColorDim+"详细:"+ColorReset+" 结构(%d) 长度(%d) 可读(%d) 安全(%d)\n\n",
-			structureScore, lengthScore, readabilityScore, securityScore)
+		printScoreReport(score, breakdown)
+		fmt.Println()
 	}
 
 	printSubHeader("评分标准说明")
@@ -3156,9 +3133,6 @@ func initializeManagers() {
 
 	// 初始化进程安全管理器
 	safetyManager = NewProcessSafetyManager()
-
-	// 初始化网络管理器 (默认30秒超时，3次重试)
-	networkManager = NewNetworkManager(30*time.Second, 3)
 }
 
 // 设置信号处理
@@ -3168,16 +3142,19 @@ func setupSignalHandlers() {
 
 	go func() {
 		<-c
+		// 有操作进行中时先取消请求，由操作输出已完成部分的汇总后正常退出；再次按 Ctrl-C 立即退出
+		if safetyManager != nil && safetyManager.Busy() {
+			fmt.Println("\n\n" + ColorYellow + "[!] 接收到退出信号，正在停止当前任务（再次按 Ctrl-C 立即退出）..." + ColorReset)
+			safetyManager.Cancel()
+			<-c
+		}
 		fmt.Println("\n\n" + ColorYellow + "[!] 接收到退出信号，正在安全退出..." + ColorReset)
 
-		// 释放进程锁
+		// 释放进程锁并等待后台任务退出
 		if safetyManager != nil {
-			safetyManager.Unlock()
+			shutdown()
 		}
 
-		// 清理锁文件
-		os.Remove(LOCK_FILE)
-
 		fmt.Println(ColorGreen + "[+] 程序已安全退出" + ColorReset)
 		os.Exit(0)
 	}()
@@ -3185,7 +3162,7 @@ func setupSignalHandlers() {
 
 // 启动配置热重载监控（使用 fsnotify 优化）
 func startConfigWatcher() {
-	go func() {
+	safetyManager.Go("config-watcher", func(ctx context.Context) {
 		// 创建文件监控器
 		watcher, err := fsnotify.NewWatcher()
 		if err != nil {
@@ -3201,6 +3178,8 @@ func startConfigWatcher() {
 			return
 		}
 
+		// 防抖回调在各自的 goroutine 中执行，串行化以免两次重载交错（同时保护 reloadAttempts）
+		var reloadMutex sync.Mutex
 		var reloadAttempts int
 		const maxReloadAttempts = 3
 
@@ -3215,7 +3194,7 @@ func startConfigWatcher() {
 					return
 				}
 
-				// 只处理 config.json 的写入、创建和重命名事件
+				// 只处理配置文件的写入、创建和重命名事件
 				if event.Name != CONFIG_FILE && event.Name != "./"+CONFIG_FILE {
 					continue
 				}
@@ -3231,34 +3210,38 @@ func startConfigWatcher() {
 					}
 
 					debounceTimer = time.AfterFunc(debounceDelay, func() {
+						reloadMutex.Lock()
+						defer reloadMutex.Unlock()
+						if ctx.Err() != nil {
+							return
+						}
+
 						// 检查文件是否存在（处理重命名情况）
 						if _, err := os.Stat(CONFIG_FILE); os.IsNotExist(err) {
 							return
 						}
 
-						fmt.Printf("\n" + ColorYellow + "[!] 检测到配置文件更新，正在重新加载..." + ColorReset + "\n")
-
 						newConfig, err := configManager.LoadConfig()
 						if err != nil {
 							reloadAttempts++
+							logger().Warn("重新加载配置失败", "error", err, "attempt", reloadAttempts)
 							fmt.Printf(ColorRed+"[!] 重新加载配置失败: %v"+ColorReset+"\n", err)
 
 							if reloadAttempts >= maxReloadAttempts {
 								fmt.Printf(ColorRed+"[!] 配置重载失败次数过多 (%d/%d)"+ColorReset+"\n", reloadAttempts, maxReloadAttempts)
-								fmt.Printf(ColorYellow + "[!] 修复建议:" + ColorReset + "\n")
-								fmt.Printf("  1. 检查 config.json 文件格式是否正确\n")
-								fmt.Printf("  2. 确保 JSON 语法无误\n")
+								fmt.Print(ColorYellow + "[!] 修复建议:" + ColorReset + "\n")
+								fmt.Printf("  1. 检查 %s 文件格式是否正确\n", CONFIG_FILE)
+								fmt.Printf("  2. 按错误信息中的行号修正语法或取值\n")
 								fmt.Printf("  3. 恢复备份的配置文件\n")
 								fmt.Printf("  4. 重启程序\n")
-								fmt.Printf(ColorRed + "[!] 程序将安全退出..." + ColorReset + "\n")
+								fmt.Print(ColorRed + "[!] 程序将安全退出..." + ColorReset + "\n")
 
 								// 安全退出
 								if safetyManager != nil {
 									safetyManager.Unlock()
 								}
-								os.Remove(LOCK_FILE)
 								fmt.Println(ColorGreen + "[+] 程序已安全退出" + ColorReset)
-								os.Exit(1)
+								os.Exit(ExitConfig)
 								return
 							}
 							return
@@ -3267,15 +3250,33 @@ func startConfigWatcher() {
 						// 重置重试计数
 						reloadAttempts = 0
 
-						// 更新全局配置
-						configMutex.Lock()
-						globalConfig = newConfig
-						configMutex.Unlock()
+						// 账号锁按 DSID 与状态目录获取，运行中不能切换
+						if accountLockFile(newConfig) != accountLockFile(getCurrentConfig()) {
+							fmt.Print(ColorYellow + "[!] 配置中的 dsid 或 state_dir 已变更，请重启程序后生效，本次修改未应用" + ColorReset + "\n")
+							return
+						}
+
+						// 只重建受影响的子系统；文件内容与当前配置相同时（如菜单刚保存过）不做任何提示
+						changed := configChanges(getCurrentConfig(), newConfig)
+						if len(changed) == 0 {
+							return
+						}
+						// 批量创建等操作进行中时不输出任何内容，操作结束回到主菜单后再应用
+						if deferConfigReload(newConfig) {
+							return
+						}
+						rebuilt, restart := applyConfigReload(getCurrentConfig(), newConfig, changed)
 
-						// 清屏并重新显示主菜单
+						// 停在主菜单时清屏并重绘，操作进行中只输出变更摘要，新配置从下一次操作开始使用
+						if !atMainMenu.Load() {
+							fmt.Println()
+							printConfigReload(changed, rebuilt, restart)
+							return
+						}
 						clearScreen()
-						fmt.Printf(ColorGreen + "[+] 配置已成功重新加载" + ColorReset + "\n")
+						printConfigReload(changed, rebuilt, restart)
 						showMainMenu()
+						fmt.Print(ColorCyan + "  › " + ColorReset + mainMenuPrompt)
 					})
 				}
 
@@ -3285,14 +3286,14 @@ func startConfigWatcher() {
 				}
 				fmt.Printf(ColorYellow+"[!] 配置文件监控错误: %v"+ColorReset+"\n", err)
 
-			case <-safetyManager.Context().Done():
+			case <-ctx.Done():
 				if debounceTimer != nil {
 					debounceTimer.Stop()
 				}
 				return
 			}
 		}
-	}()
+	})
 }
 
 // 获取当前配置 (线程安全)
@@ -3304,19 +3305,110 @@ func getCurrentConfig() *Config {
 
 // 保存配置并显示消息
 func saveConfigWithMessage(config *Config, message string) {
-	// 更新全局配置
+	// 发布副本，调用方之后继续修改 config 不影响其他 goroutine
+	published := config.clone()
 	configMutex.Lock()
-	globalConfig = config
+	globalConfig = published
 	configMutex.Unlock()
 
 	// 保存到文件
-	if err := configManager.SaveConfig(config); err != nil {
+	if err := configManager.SaveConfig(published); err != nil {
 		printError(fmt.Sprintf("保存配置失败: %v", err))
 	} else {
 		printSuccess(message + " (已保存)")
 	}
 }
 
+// 执行子命令
+func runCommand(config *Config, command string, args []string) error {
+	switch command {
+	case "serve":
+		return runServe(config)
+	case "daemon":
+		return runDaemon(config, args)
+	case "jobs":
+		return runJobs(config, args)
+	case "watch":
+		return runWatch(config, args)
+	case "labels":
+		return runLabels(config, args)
+	case "history":
+		return runHistory(args)
+	case "purge-local-data":
+		return runPurgeLocalData(config, args)
+	case "app-lock":
+		return runAppLock(config, args)
+	case "secrets":
+		return runSecrets(config, args)
+	case "config-keys":
+		return runConfigKeys(args)
+	case "verify-watch":
+		return runVerifyWatch(config, args)
+	case "otp":
+		return runOTP(config, args)
+	case "auto-label":
+		return runAutoLabel(config, args)
+	case "forward-check":
+		return runForwardCheck(config, args)
+	case "test-send":
+		return runTestSend(config, args)
+	case "deactivated":
+		return runDeactivatedReport(config, args)
+	case "batch":
+		return runBatchCommand(config, args)
+	case "create":
+		return runCreateCommand(config, args)
+	case "list":
+		return runListCommand(config, args)
+	case "deactivate":
+		return runDeactivateCommand(config, args)
+	case "reactivate":
+		return runReactivateCommand(config, args)
+	case "delete":
+		return runDeleteCommand(config, args)
+	case "edit":
+		return runEditCommand(config, args)
+	case "forward-to":
+		return runForwardToCommand(config, args)
+	case "stats":
+		return runStats(config, args)
+	case "ledger":
+		return runLedger(args)
+	case "migrate":
+		return runMigrate(config, args)
+	case "sync":
+		return runSync(config, args)
+	case "export":
+		return runExport(config, args)
+	case "import-labels":
+		return runImportLabels(config, args)
+	case "checklist":
+		return runChecklist(args)
+	case "retry-queue":
+		return runRetryQueueCommand(config, args)
+	case "notify-test":
+		return runNotifyTest(config, args)
+	case "signup":
+		return runSignup(config, args)
+	case "mock-server":
+		return runMockServer(config, args)
+	case "score":
+		return runScoreCommand(config, args)
+	case "soak":
+		return runSoak(config, args)
+	case "import-curl":
+		return runImportCurl(config, args)
+	case "import-har":
+		return runImportHAR(config, args)
+	case "data-format":
+		return runDataFormat(config, args)
+	case "doctor":
+		return runDoctor(config, args)
+	default:
+		return usageError(fmt.Errorf("未知命令: %s", command))
+	}
+}
+
 func main() {
 	// 初始化管理器
 	initializeManagers()
@@ -3324,19 +3416,35 @@ func main() {
 	// 设置信号处理
 	setupSignalHandlers()
 
-	// 获取进程锁
-	if err := safetyManager.Lock(); err != nil {
-		printError(fmt.Sprintf("启动失败: %v", err))
-		os.Exit(1)
-	}
-	defer safetyManager.Unlock()
+	// 全局选项（--yes 等）可出现在任意位置；JSON 输出时标准输出只保留数据
+	args := parseGlobalFlags(os.Args[1:])
+	setupDataOutput(args)
+	setupPlainUI()
 
 	// 显示启动信息
 	printHeader("iCloud 隐藏邮箱管理工具")
-	fmt.Printf("  " + ColorCyan + "版本:" + ColorReset + " " + ColorBold + VERSION + ColorReset + "\n")
-	fmt.Printf("  " + ColorCyan + "作者:" + ColorReset + " " + AUTHOR + "\n")
+	fmt.Print("  " + ColorCyan + "版本:" + ColorReset + " " + ColorBold + VERSION + ColorReset + "\n")
+	fmt.Print("  " + ColorCyan + "作者:" + ColorReset + " " + AUTHOR + "\n")
 	fmt.Println()
 
+	// config.json 中的 dsid 与 Cookie 已加密时先解锁
+	if err := unlockSecrets(CONFIG_FILE); err != nil {
+		printError(err.Error())
+		os.Exit(ExitAuth)
+	}
+
+	// 首次运行（没有 config.json）时进入配置向导，init 命令重新运行向导
+	if wizardRequested(args) {
+		if err := runSetupWizard(); err != nil {
+			printError(err.Error())
+			os.Exit(exitCodeFor(err))
+		}
+		if len(args) > 0 {
+			os.Exit(ExitOK)
+		}
+		fmt.Println()
+	}
+
 	// 加载配置
 	var config *Config
 	if err := withSpinner("加载配置文件", func() error {
@@ -3354,26 +3462,139 @@ func main() {
 		return nil
 	}); err != nil {
 		printError(fmt.Sprintf("加载失败: %v", err))
-		printInfo("请确保 config.json 文件存在且格式正确")
-		os.Exit(1)
+		if code := exitCodeFor(err); code == ExitUsage || code == ExitConfig && configOverridesPresent() {
+			printInfo("检查环境变量与 --set 的配置覆盖，运行 config-keys 查看可用的配置项")
+		} else if _, statErr := os.Stat(CONFIG_FILE); os.IsNotExist(statErr) {
+			printInfo("运行 ./icloud-hme init 按向导创建配置，或复制 config.json.example 为 config.json 后填写；也可只用 HME_DSID、HME_COOKIE 等环境变量提供配置")
+		} else {
+			printInfo(fmt.Sprintf("请按提示的行号修改 %s", CONFIG_FILE))
+		}
+		os.Exit(ExitConfig)
+	}
+	if notice := configManager.UpgradeNotice(); notice != "" {
+		printInfo(notice)
+	}
+	for _, warning := range configManager.Warnings() {
+		printWarning(warning)
+	}
+	if config.profile != "" {
+		printInfo(fmt.Sprintf("使用账号: %s", config.profile))
+	}
+	if applied := configManager.AppliedOverrides(); len(applied) > 0 {
+		printInfo("已应用配置覆盖: " + describeOverrides(applied))
+	}
+	checkUserAgent(config)
+	if err := setupLogging(config); err != nil {
+		printWarning(err.Error())
+	}
+	command := "menu"
+	if len(args) > 0 {
+		command = args[0]
+	}
+	logger().Info("程序启动", "command", command, "config", CONFIG_FILE, "profile", config.profile, "pid", os.Getpid())
+
+	// 获取账号锁：同一账号同时只允许一个进程修改，不同账号可并行；只读子命令无需加锁
+	if commandNeedsLock(args) {
+		safetyManager.UseAccount(config)
+		if err := safetyManager.Lock(); err != nil {
+			// 守护进程持有账号锁时，菜单作为它的客户端提交与查看任务
+			if len(args) == 0 && daemonRunning(config) {
+				printInfo("该账号的守护进程正在运行，创建任务将交给守护进程执行")
+				runDaemonClientMenu(config)
+				shutdown()
+				os.Exit(ExitOK)
+			}
+			printError(fmt.Sprintf("启动失败: %v", err))
+			printLockGuidance(err)
+			os.Exit(ExitLocked)
+		}
+	}
+	defer shutdown()
+
+	// 启动口令校验
+	if config.AppLock.Enabled() {
+		if err := unlockApp(config); err != nil {
+			printError(err.Error())
+			safetyManager.Unlock()
+			os.Exit(ExitAuth)
+		}
+	}
+
+	// 打开本地邮箱清单
+	if inv, err := OpenInventory(config.InventoryFile); err != nil {
+		printWarning(fmt.Sprintf("本地清单不可用: %v", err))
+	} else {
+		inventory = inv
+	}
+
+	// 子命令模式（如 serve）
+	if len(args) > 0 {
+		safetyManager.AddOperation()
+		err := runCommand(config, args[0], args[1:])
+		safetyManager.DoneOperation()
+		if err != nil {
+			code := exitCodeFor(err)
+			if code != ExitOK {
+				logFailure(slog.LevelError, "命令失败", err, "command", args[0], "exit_code", code)
+				printError(err.Error())
+				if class := classifyFailure(err); class != failureOther && !outputJSON {
+					printInfo("建议: " + class.Advice)
+				}
+				writeFailure(err)
+			}
+			// 部分失败时批量结束已提示过
+			if code != ExitOK && code != ExitUsage && code != ExitPartial {
+				announce(config, SoundError, fmt.Sprintf("%s 失败: %v", args[0], err))
+			}
+			waitAnnouncements(5 * time.Second)
+			shutdown()
+			os.Exit(code)
+		}
+		waitAnnouncements(5 * time.Second)
+		return
+	}
+
+	// 先确认会话有效，过期时给出处理方法，也不再自动重试（必然失败）；
+	// 上次因限流失败的标签已到重试时间时自动重试
+	if startupSessionCheck(config) {
+		retryQueueOnStart(config)
 	}
 
 	// 启动配置热重载监控
 	startConfigWatcher()
 
+	// 空闲超时后需要重新输入口令
+	appLock := newIdleLock(config.AppLock)
+
 	// 主循环
 	firstIteration := true
 	for {
 		// 在显示菜单前清屏（第一次除外，以便用户看到启动信息）
 		if !firstIteration {
-			fmt.Print("\033[2J\033[H")
+			clearScreen()
 		}
 		firstIteration = false
 
+		applyPendingReload()
 		showMainMenu()
-		choice := readInput("选择操作 (0-9): ")
+		atMainMenu.Store(true)
+		choice := readInput(mainMenuPrompt)
+		atMainMenu.Store(false)
 		choice = strings.ToLower(strings.TrimSpace(choice))
 
+		if appLock.Expired() {
+			printWarning("空闲时间过长，已锁定")
+			if err := unlockApp(getCurrentConfig()); err != nil {
+				printError(err.Error())
+				return
+			}
+			appLock.Unlock()
+		}
+		appLock.Touch()
+
+		// 配置可能已热重载或切换了账号；设置菜单只修改副本，保存时再发布
+		config = getCurrentConfig().clone()
+
 		switch choice {
 		case "1":
 			handleListEmails(config)
@@ -3391,9 +3612,33 @@ func main() {
 			handleReactivate(config)
 		case "8":
 			handleProgramSettings(config)
+		case "m", "edit":
+			handleEditEmails(config)
+		case "f", "forward":
+			handleForwardTo(config)
+		case "r", "resume":
+			handleResumeBatch(config)
+		case "q", "retry":
+			handleRetryQueue(config)
+		case "s", "stats":
+			handleErrorStats(config)
+		case "l", "ledger":
+			handleLedger(config)
+		case "e", "export":
+			handleExport(config)
+		case "n", "signup":
+			handleSignup(config)
+		case "v", "session":
+			handleReverifySession(config)
+		case "a", "account":
+			if len(config.Profiles) > 0 {
+				handleSwitchProfile(config)
+			} else {
+				printError("未配置 profiles，无法切换账号")
+			}
 		case "9":
-			if config.DeveloperMode {
-				testEmailScoring()
+			if config.Developer.Enabled {
+				handleDeveloperHarness(config)
 			} else {
 				printError("无效选择，请输入 0-8")
 			}
@@ -3406,5 +3651,11 @@ func main() {
 		default:
 			printError("无效选择，请输入 0-8")
 		}
+
+		// 批量任务因退出信号停止后，输出汇总即退出（锁文件由 defer 释放）
+		if safetyManager.Canceled() {
+			printInfo("已收到退出信号，安全退出")
+			return
+		}
 	}
 }
@@ -0,0 +1,359 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// 迁移清单的格式版本
+const manifestVersion = 1
+
+// ManifestAlias 迁移清单中的一个邮箱：只保存在新账号上重建所需的信息
+type ManifestAlias struct {
+	Label  string   `json:"label"`
+	Note   string   `json:"note,omitempty"`
+	Site   string   `json:"site,omitempty"`
+	Tags   []string `json:"tags,omitempty"`
+	Source string   `json:"source_hme,omitempty"` // 原账号上的地址，便于到各网站逐个替换
+	Active bool     `json:"active"`
+}
+
+// MigrationManifest 迁移到另一个 Apple ID 时导出的标签、备注与用途清单，可用 migrate import 或 batch -manifest 在新账号上重建
+type MigrationManifest struct {
+	Version    int             `json:"version"`
+	ExportedAt time.Time       `json:"exported_at"`
+	Account    string          `json:"account"`
+	Aliases    []ManifestAlias `json:"aliases"`
+}
+
+// buildManifest 由当前账号的邮箱列表与本地台账生成迁移清单（按创建时间排列）
+func buildManifest(config *Config, emails []HMEEmail, includeInactive bool) *MigrationManifest {
+	sorted := append([]HMEEmail(nil), emails...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].CreateTimestamp < sorted[j].CreateTimestamp })
+
+	manifest := &MigrationManifest{Version: manifestVersion, ExportedAt: time.Now(), Account: config.profileLabel()}
+	for _, email := range sorted {
+		if !email.IsActive && !includeInactive {
+			continue
+		}
+		alias := ManifestAlias{Label: email.Label, Note: email.Note, Source: email.HME, Active: email.IsActive}
+		if record, ok := inventory.Get(email.HME); ok {
+			alias.Site, alias.Tags = record.Site, record.Tags
+		}
+		manifest.Aliases = append(manifest.Aliases, alias)
+	}
+	return manifest
+}
+
+// loadManifest 读取迁移清单
+func loadManifest(path string) (*MigrationManifest, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("读取迁移清单失败: %w", err)
+	}
+	var manifest MigrationManifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("解析迁移清单失败: %w", err)
+	}
+	if manifest.Version > manifestVersion {
+		return nil, fmt.Errorf("迁移清单版本 %d 高于当前支持的版本 %d，请升级程序", manifest.Version, manifestVersion)
+	}
+	for i, alias := range manifest.Aliases {
+		if strings.TrimSpace(alias.Label) == "" {
+			return nil, fmt.Errorf("迁移清单第 %d 项缺少标签", i+1)
+		}
+	}
+	return &manifest, nil
+}
+
+// aliasesByLabel 按标签（忽略大小写）分组邮箱，组内按创建时间排列。
+// 已停用的邮箱也计入，清单中的停用项重建后会被停用，重复执行时不应再次创建
+func aliasesByLabel(emails []HMEEmail) map[string][]HMEEmail {
+	sorted := append([]HMEEmail(nil), emails...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].CreateTimestamp < sorted[j].CreateTimestamp })
+	groups := make(map[string][]HMEEmail)
+	for _, email := range sorted {
+		key := strings.ToLower(email.Label)
+		groups[key] = append(groups[key], email)
+	}
+	return groups
+}
+
+// pendingManifestLabels 清单中在当前账号上还没有对应邮箱的标签。同一标签出现多次时按数量比较，
+// 因此中断后重新执行 batch -manifest 只会创建剩余部分
+func pendingManifestLabels(manifest *MigrationManifest, current []HMEEmail) []string {
+	existing := make(map[string]int)
+	for label, group := range aliasesByLabel(current) {
+		existing[label] = len(group)
+	}
+	var labels []string
+	for _, alias := range manifest.Aliases {
+		key := strings.ToLower(alias.Label)
+		if existing[key] > 0 {
+			existing[key]--
+			continue
+		}
+		labels = append(labels, alias.Label)
+	}
+	return labels
+}
+
+// ManifestPair 清单中的一项与当前账号上对应的邮箱，Email 为 nil 表示尚未重建
+type ManifestPair struct {
+	Alias ManifestAlias
+	Email *HMEEmail
+}
+
+// pairManifest 同一标签的邮箱按创建顺序与清单项一一对应
+func pairManifest(manifest *MigrationManifest, current []HMEEmail) []ManifestPair {
+	groups := aliasesByLabel(current)
+	pairs := make([]ManifestPair, 0, len(manifest.Aliases))
+	for _, alias := range manifest.Aliases {
+		pair := ManifestPair{Alias: alias}
+		key := strings.ToLower(alias.Label)
+		if len(groups[key]) > 0 {
+			email := groups[key][0]
+			groups[key] = groups[key][1:]
+			pair.Email = &email
+		}
+		pairs = append(pairs, pair)
+	}
+	return pairs
+}
+
+// applyManifestMetadata 为已重建的邮箱补上清单中的备注，并把网站与标记写入本地台账；
+// 清单中标记为停用的邮箱重建后随即停用。已一致的备注与状态不会重复修改
+func applyManifestMetadata(config *Config, manifest *MigrationManifest) {
+	var current []HMEEmail
+	if err := withSpinner("获取邮箱列表", func() error {
+		var err error
+		current, err = listHME(config)
+		return err
+	}); err != nil {
+		printWarning(fmt.Sprintf("获取列表失败，未能补上备注: %v", err))
+		return
+	}
+	if err := inventory.Sync(current); err != nil {
+		printWarning(fmt.Sprintf("同步本地清单失败: %v", err))
+	}
+
+	notes, annotated, deactivated, failed := 0, 0, 0, 0
+	for _, pair := range pairManifest(manifest, current) {
+		if pair.Email == nil {
+			continue
+		}
+		alias, email := pair.Alias, *pair.Email
+
+		if alias.Note != "" && email.Note != alias.Note {
+			if err := updateMetaDataHME(config, email.AnonymousID, email.Label, alias.Note); err != nil {
+				printError(fmt.Sprintf("为 %s 补上备注失败: %v", email.HME, err))
+				failed++
+			} else {
+				email.Note = alias.Note
+				inventory.RecordMetaData(email)
+				notes++
+			}
+		}
+		if !alias.Active && email.IsActive {
+			if err := deactivateHME(config, email.AnonymousID); err != nil {
+				printError(fmt.Sprintf("停用 %s 失败: %v", email.HME, err))
+				failed++
+			} else {
+				deactivated++
+				if err := inventory.RecordEvent(email, InventoryEventDeactivated, CreationOrigin{Source: SourceCLI}); err != nil {
+					printWarning(fmt.Sprintf("记录本地清单失败: %v", err))
+				}
+			}
+		}
+		if alias.Site != "" || len(alias.Tags) > 0 {
+			changed := false
+			inventory.Annotate(email.HME, func(record *InventoryRecord) {
+				tags := len(record.Tags)
+				if alias.Site != "" && record.Site != alias.Site {
+					record.Site = alias.Site
+					changed = true
+				}
+				applyTags(record, alias.Tags, nil)
+				changed = changed || len(record.Tags) != tags
+			})
+			if changed {
+				annotated++
+			}
+		}
+	}
+	if notes > 0 || annotated > 0 {
+		printSuccess(fmt.Sprintf("已补上 %d 个备注，%d 个邮箱的网站与标记已写入本地台账", notes, annotated))
+	}
+	if deactivated > 0 {
+		printSuccess(fmt.Sprintf("已按清单停用 %d 个邮箱", deactivated))
+	}
+	if failed > 0 {
+		printWarning(fmt.Sprintf("%d 项备注或停用未能完成，可重新执行 batch -manifest 补上", failed))
+	}
+}
+
+// MigrationMapping 迁移报告中的一行：原地址与新账号上对应的地址
+type MigrationMapping struct {
+	Label  string `json:"label"`
+	Site   string `json:"site,omitempty"`
+	Note   string `json:"note,omitempty"`
+	OldHME string `json:"old_hme,omitempty"`
+	NewHME string `json:"new_hme,omitempty"`
+	Status string `json:"status"` // created 本次创建、existing 已存在、missing 尚未重建
+}
+
+// buildMigrationMappings 按清单顺序列出原地址与新地址的对应关系，since 之后创建的邮箱视为本次创建
+func buildMigrationMappings(manifest *MigrationManifest, current []HMEEmail, since time.Time) []MigrationMapping {
+	var mappings []MigrationMapping
+	for _, pair := range pairManifest(manifest, current) {
+		mapping := MigrationMapping{Label: pair.Alias.Label, Site: pair.Alias.Site, Note: pair.Alias.Note, OldHME: pair.Alias.Source, Status: "missing"}
+		if pair.Email != nil {
+			mapping.NewHME = pair.Email.HME
+			mapping.Status = "existing"
+			if pair.Email.CreateTimestamp >= since.UnixMilli() {
+				mapping.Status = "created"
+			}
+		}
+		mappings = append(mappings, mapping)
+	}
+	return mappings
+}
+
+// writeMigrationReport 把对应关系写成 CSV，便于逐个网站更新登录邮箱
+func writeMigrationReport(path string, mappings []MigrationMapping) error {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return fmt.Errorf("创建迁移报告失败: %v", err)
+	}
+	defer file.Close()
+	writer := csv.NewWriter(file)
+	writer.Write([]string{"old_hme", "new_hme", "label", "site", "note", "status"})
+	for _, m := range mappings {
+		writer.Write([]string{m.OldHME, m.NewHME, m.Label, m.Site, m.Note, m.Status})
+	}
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return fmt.Errorf("写入迁移报告失败: %v", err)
+	}
+	return nil
+}
+
+// printMigrationMappings 输出原地址 → 新地址
+func printMigrationMappings(mappings []MigrationMapping) {
+	printSubHeader("地址对应")
+	for _, m := range mappings {
+		old := orDash(m.OldHME)
+		switch m.Status {
+		case "missing":
+			fmt.Printf("  "+ColorRed+"✗"+ColorReset+" %s → "+ColorDim+"尚未重建"+ColorReset+" "+ColorCyan+"%s"+ColorReset+"\n", old, m.Label)
+		case "created":
+			fmt.Printf("  "+ColorGreen+"+"+ColorReset+" %s → %s "+ColorCyan+"%s"+ColorReset+" "+ColorDim+"%s"+ColorReset+"\n", old, m.NewHME, m.Label, m.Site)
+		default:
+			fmt.Printf("  "+ColorDim+"="+ColorReset+" %s → %s "+ColorCyan+"%s"+ColorReset+" "+ColorDim+"%s"+ColorReset+"\n", old, m.NewHME, m.Label, m.Site)
+		}
+	}
+}
+
+// runMigrateImport 在当前账号上按迁移清单重建邮箱（与 batch -manifest 相同，遵循批量创建的间隔与限流），
+// 并把原地址与新地址的对应关系写入报告
+func runMigrateImport(config *Config, args []string) error {
+	fs := flag.NewFlagSet("migrate import", flag.ContinueOnError)
+	report := fs.String("report", "hme-migration-"+time.Now().Format("20060102-150405")+".csv", "原地址与新地址对应关系的 CSV 报告")
+	if err := fs.Parse(args); err != nil {
+		return usageError(err)
+	}
+	if fs.NArg() != 1 {
+		return usageError(fmt.Errorf("用法: migrate import [-report 报告文件] 清单文件"))
+	}
+	path := fs.Arg(0)
+	manifest, err := loadManifest(path)
+	if err != nil {
+		return err
+	}
+
+	since := time.Now()
+	batchErr := runBatchCommand(config, []string{"-manifest", path})
+	if exitCodeFor(batchErr) == ExitUsage {
+		return batchErr
+	}
+
+	// 中断或部分失败时也生成报告，已重建的部分可以先去更新
+	var current []HMEEmail
+	if err := withSpinner("获取邮箱列表", func() error {
+		var err error
+		current, err = listHME(config)
+		return err
+	}); err != nil {
+		if batchErr != nil {
+			return batchErr
+		}
+		return fmt.Errorf("获取列表失败，未能生成迁移报告: %w", err)
+	}
+	mappings := buildMigrationMappings(manifest, current, since)
+	if outputJSON {
+		writeJSON(struct {
+			Op       string             `json:"op"`
+			Report   string             `json:"report"`
+			Mappings []MigrationMapping `json:"mappings"`
+		}{"migrate-import", *report, mappings})
+	} else {
+		printMigrationMappings(mappings)
+	}
+	if err := writeMigrationReport(*report, mappings); err != nil {
+		printError(err.Error())
+	} else {
+		printSuccess(fmt.Sprintf("迁移报告已写入 %s，可据此到各网站把登录邮箱换成新地址", *report))
+		printInfo(fmt.Sprintf("执行 checklist -report %s 生成按服务排列、可勾选的更新清单", *report))
+	}
+	return batchErr
+}
+
+// runMigrate 迁移工具：migrate export 导出当前账号的迁移清单，migrate import 在新账号上重建并生成地址对应报告
+func runMigrate(config *Config, args []string) error {
+	if len(args) > 0 && args[0] == "import" {
+		return runMigrateImport(config, args[1:])
+	}
+	if len(args) == 0 || args[0] != "export" {
+		return usageError(fmt.Errorf("用法: migrate export [-o 文件] [-inactive] 或 migrate import [-report 报告文件] 清单文件"))
+	}
+	fs := flag.NewFlagSet("migrate export", flag.ContinueOnError)
+	output := fs.String("o", "hme-manifest.json", "迁移清单文件（- 表示输出到标准输出）")
+	includeInactive := fs.Bool("inactive", false, "同时导出已停用的邮箱")
+	if err := fs.Parse(args[1:]); err != nil {
+		return usageError(err)
+	}
+
+	var emails []HMEEmail
+	if err := withSpinner("获取邮箱列表", func() error {
+		var err error
+		emails, err = listHME(config)
+		return err
+	}); err != nil {
+		return fmt.Errorf("获取列表失败: %w", err)
+	}
+	if err := inventory.Sync(emails); err != nil {
+		printWarning(fmt.Sprintf("同步本地清单失败: %v", err))
+	}
+
+	manifest := buildManifest(config, emails, *includeInactive)
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return fmt.Errorf("序列化迁移清单失败: %v", err)
+	}
+	if *output == "-" {
+		_, err := dataOutput.Write(append(data, '\n'))
+		return err
+	}
+	if err := os.WriteFile(*output, append(data, '\n'), 0600); err != nil {
+		return fmt.Errorf("写入迁移清单失败: %v", err)
+	}
+	printSuccess(fmt.Sprintf("已导出 %d 个邮箱的标签、备注与用途到 %s", len(manifest.Aliases), *output))
+	printInfo(fmt.Sprintf("切换到新账号后执行 migrate import %s 重建并生成地址对应报告（已存在的同名邮箱会跳过，可重复执行）", *output))
+	return nil
+}
@@ -0,0 +1,450 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"math/rand"
+	"net"
+	"net/http"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"icloud-hme-generator/pkg/hme"
+)
+
+// 模拟服务器默认监听地址，与开发者工具默认请求的 mock_base_url 一致
+const defaultMockListenAddr = "127.0.0.1:8765"
+
+// 故障注入的默认参数
+const (
+	defaultMockRetryAfter = 2    // 429 响应的 Retry-After（秒）
+	defaultMockSlowMillis = 3000 // 慢响应的延迟（毫秒）
+)
+
+// 模拟服务器注入的故障类型
+const (
+	MockFaultRateLimit   = "rate_limit"
+	MockFaultServerError = "server_error"
+	MockFaultSlow        = "slow"
+	MockFaultMalformed   = "malformed"
+	MockFaultDisconnect  = "disconnect"
+)
+
+// MockServerConfig 内置模拟服务器（mock-server 命令）的监听地址与故障注入
+type MockServerConfig struct {
+	ListenAddr string          `json:"listen_addr,omitempty"`
+	Faults     MockFaultConfig `json:"faults"`
+}
+
+// MockFaultConfig 故障注入：各概率取 0-1，每个请求独立抽取；
+// 限流、服务器错误、格式错误与断开连接互斥（概率之和不能超过 1），慢响应可与它们叠加
+type MockFaultConfig struct {
+	RateLimit         float64  `json:"rate_limit"`          // 返回 429 与 Retry-After
+	RetryAfterSeconds int      `json:"retry_after_seconds"` // 429 的 Retry-After，默认 2
+	ServerError       float64  `json:"server_error"`        // 返回 503
+	Slow              float64  `json:"slow"`                // 延迟 slow_ms 后再响应
+	SlowMillis        int      `json:"slow_ms"`             // 默认 3000
+	Malformed         float64  `json:"malformed"`           // 返回 200 但响应体不是完整的 JSON
+	Disconnect        float64  `json:"disconnect"`          // 照常处理请求，但只写出一半响应体就断开连接
+	Endpoints         []string `json:"endpoints,omitempty"` // 只对这些接口注入，如 ["generate", "reserve"]，为空时全部
+	Seed              int64    `json:"seed,omitempty"`      // 随机种子，非 0 时每次运行注入的故障序列相同
+}
+
+// validate 检查概率范围
+func (f MockFaultConfig) validate() error {
+	for name, p := range map[string]float64{"rate_limit": f.RateLimit, "server_error": f.ServerError, "slow": f.Slow, "malformed": f.Malformed, "disconnect": f.Disconnect} {
+		if p < 0 || p > 1 {
+			return fmt.Errorf("%s 的概率必须在 0 到 1 之间", name)
+		}
+	}
+	if f.RateLimit+f.ServerError+f.Malformed+f.Disconnect > 1 {
+		return fmt.Errorf("rate_limit、server_error、malformed、disconnect 的概率之和不能超过 1")
+	}
+	return nil
+}
+
+// Describe 启用的故障注入说明，如 “429 10%、慢响应 20% (3000ms)”
+func (f MockFaultConfig) Describe() string {
+	var parts []string
+	add := func(p float64, text string) {
+		if p > 0 {
+			parts = append(parts, fmt.Sprintf("%s %g%%", text, p*100))
+		}
+	}
+	add(f.RateLimit, fmt.Sprintf("429 (Retry-After %ds)", f.RetryAfterSeconds))
+	add(f.ServerError, "503")
+	add(f.Slow, fmt.Sprintf("慢响应 (%dms)", f.SlowMillis))
+	add(f.Malformed, "格式错误的 JSON")
+	add(f.Disconnect, "响应中途断开")
+	if len(parts) == 0 {
+		return "无"
+	}
+	description := strings.Join(parts, "、")
+	if len(f.Endpoints) > 0 {
+		description += "，仅限 " + strings.Join(f.Endpoints, "、")
+	}
+	return description
+}
+
+// mockServer 模拟 iCloud 隐藏邮箱接口的内存服务器
+type mockServer struct {
+	faults MockFaultConfig
+	words  []string
+
+	mutex     sync.Mutex
+	random    *rand.Rand
+	generated map[string]bool
+	emails    []*hme.Email
+	forwardTo string
+	requests  map[string]int // 接口 → 请求数
+	injected  map[string]int // 故障类型 → 注入次数
+	quiet     bool           // 不逐条输出请求记录（soak 长时间运行时使用）
+}
+
+// newMockServer 创建模拟服务器，生成地址使用内置英文词表中的单词
+func newMockServer(faults MockFaultConfig) *mockServer {
+	seed := faults.Seed
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	s := &mockServer{
+		faults:    faults,
+		random:    rand.New(rand.NewSource(seed)),
+		generated: make(map[string]bool),
+		forwardTo: "dev@example.com",
+		requests:  make(map[string]int),
+		injected:  make(map[string]int),
+	}
+	for _, line := range strings.Split(embeddedWordlist, "\n") {
+		if word := strings.TrimSpace(line); len(word) >= 4 && len(word) <= 7 && !strings.HasPrefix(word, "#") {
+			s.words = append(s.words, word)
+		}
+	}
+	return s
+}
+
+// handler 模拟服务器的路由，接口路径与 iCloud 一致，另有 GET /mock/stats 查看统计
+func (s *mockServer) handler() http.Handler {
+	mux := http.NewServeMux()
+	for _, endpoint := range []string{"/v1/hme/reserve", "/v1/hme/generate", "/v2/hme/list", "/v1/hme/deactivate", "/v1/hme/reactivate", "/v1/hme/delete", "/v1/hme/updateMetaData", "/v1/hme/updateForwardTo"} {
+		mux.HandleFunc(endpoint, s.serveAPI)
+	}
+	mux.HandleFunc("GET /mock/stats", func(w http.ResponseWriter, r *http.Request) {
+		s.mutex.Lock()
+		defer s.mutex.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{"requests": s.requests, "injected": s.injected, "emails": len(s.emails)})
+	})
+	return mux
+}
+
+// pickFault 按概率抽取本次请求注入的故障，返回是否延迟与互斥故障（无故障时为空）
+func (s *mockServer) pickFault(endpoint string) (slow bool, fault string) {
+	if len(s.faults.Endpoints) > 0 && !containsFold(s.faults.Endpoints, endpoint) {
+		return false, ""
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	slow = s.random.Float64() < s.faults.Slow
+	roll := s.random.Float64()
+	for _, candidate := range []struct {
+		name string
+		p    float64
+	}{
+		{MockFaultRateLimit, s.faults.RateLimit},
+		{MockFaultServerError, s.faults.ServerError},
+		{MockFaultMalformed, s.faults.Malformed},
+		{MockFaultDisconnect, s.faults.Disconnect},
+	} {
+		if roll < candidate.p {
+			fault = candidate.name
+			break
+		}
+		roll -= candidate.p
+	}
+	if slow {
+		s.injected[MockFaultSlow]++
+	}
+	if fault != "" {
+		s.injected[fault]++
+	}
+	return slow, fault
+}
+
+// serveAPI 处理一个接口请求，按抽取结果注入故障
+func (s *mockServer) serveAPI(w http.ResponseWriter, r *http.Request) {
+	endpoint := path.Base(r.URL.Path)
+	s.mutex.Lock()
+	s.requests[endpoint]++
+	s.mutex.Unlock()
+
+	slow, fault := s.pickFault(endpoint)
+	if slow {
+		select {
+		case <-time.After(time.Duration(s.faults.SlowMillis) * time.Millisecond):
+		case <-r.Context().Done():
+			return
+		}
+	}
+
+	status := http.StatusOK
+	var body []byte
+	switch fault {
+	case MockFaultRateLimit:
+		status = http.StatusTooManyRequests
+		w.Header().Set("Retry-After", strconv.Itoa(s.faults.RetryAfterSeconds))
+		body = []byte(`{"success":false,"error":{"errorCode":"-41015","errorMessage":"rate limited"}}`)
+	case MockFaultServerError:
+		status = http.StatusServiceUnavailable
+		body = []byte("Service Unavailable")
+	case MockFaultMalformed:
+		body = []byte(`{"success":true,"timestamp":` + strconv.FormatInt(time.Now().Unix(), 10) + `,"result":{"hme":`)
+	default:
+		// 断开连接时请求已经生效（如 reserve 已创建），用于验证客户端不会重复创建
+		body = s.handleAPI(endpoint, r)
+	}
+	if !s.quiet {
+		logMockRequest(r, endpoint, status, fault, slow)
+	}
+
+	if fault == MockFaultDisconnect {
+		if hijacker, ok := w.(http.Hijacker); ok {
+			if conn, buf, err := hijacker.Hijack(); err == nil {
+				fmt.Fprintf(buf, "HTTP/1.1 200 OK\r\nContent-Type: application/json\r\nContent-Length: %d\r\n\r\n", len(body))
+				buf.Write(body[:len(body)/2])
+				buf.Flush()
+				conn.Close()
+				return
+			}
+		}
+	}
+	if status == http.StatusOK || fault == MockFaultRateLimit {
+		w.Header().Set("Content-Type", "application/json")
+	}
+	w.WriteHeader(status)
+	w.Write(body)
+}
+
+// handleAPI 按接口修改内存中的邮箱列表，返回 iCloud 格式的响应体
+func (s *mockServer) handleAPI(endpoint string, r *http.Request) []byte {
+	var request struct {
+		HME            string `json:"hme"`
+		Label          string `json:"label"`
+		Note           string `json:"note"`
+		AnonymousID    string `json:"anonymousId"`
+		ForwardToEmail string `json:"forwardToEmail"`
+	}
+	if r.Method == http.MethodPost {
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			return mockFailure("-41001", "invalid request body")
+		}
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	now := time.Now()
+
+	var result interface{}
+	switch endpoint {
+	case "generate":
+		address := fmt.Sprintf("%s.%s_%d@icloud.com", s.words[s.random.Intn(len(s.words))], s.words[s.random.Intn(len(s.words))], s.random.Intn(100))
+		s.generated[address] = true
+		result = map[string]string{"hme": address}
+	case "reserve":
+		if !s.generated[request.HME] {
+			return mockFailure("-41011", "hme not generated")
+		}
+		if request.Label == "" {
+			return mockFailure("-41012", "label is required")
+		}
+		delete(s.generated, request.HME)
+		email := &hme.Email{
+			Origin:          "ON_DEMAND",
+			AnonymousID:     s.randomID(),
+			HME:             request.HME,
+			Label:           request.Label,
+			Note:            request.Note,
+			CreateTimestamp: now.UnixMilli(),
+			IsActive:        true,
+			ForwardToEmail:  s.forwardTo,
+		}
+		s.emails = append(s.emails, email)
+		result = map[string]interface{}{"hme": email}
+	case "list":
+		emails := make([]hme.Email, len(s.emails))
+		for i, email := range s.emails {
+			emails[i] = *email
+		}
+		result = hme.List{ForwardToEmails: []string{s.forwardTo, "other@example.com"}, Emails: emails, SelectedForwardTo: s.forwardTo}
+	case "updateForwardTo":
+		s.forwardTo = request.ForwardToEmail
+		for _, email := range s.emails {
+			email.ForwardToEmail = request.ForwardToEmail
+		}
+	default:
+		index := -1
+		for i, email := range s.emails {
+			if email.AnonymousID == request.AnonymousID {
+				index = i
+			}
+		}
+		if index < 0 {
+			return mockFailure("-41020", "anonymousId not found")
+		}
+		email := s.emails[index]
+		switch endpoint {
+		case "deactivate":
+			email.IsActive = false
+		case "reactivate":
+			email.IsActive = true
+		case "delete":
+			if email.IsActive {
+				return mockFailure("-41021", "deactivate before deleting")
+			}
+			s.emails = append(s.emails[:index], s.emails[index+1:]...)
+		case "updateMetaData":
+			email.Label, email.Note = request.Label, request.Note
+		}
+		result = map[string]string{"message": "success"}
+	}
+
+	data, _ := json.Marshal(map[string]interface{}{"success": true, "timestamp": now.Unix(), "result": result})
+	return data
+}
+
+// randomID 10 位小写字母的 anonymousId（调用方需持有锁）
+func (s *mockServer) randomID() string {
+	id := make([]byte, 10)
+	for i := range id {
+		id[i] = byte('a' + s.random.Intn(26))
+	}
+	return string(id)
+}
+
+// mockFailure iCloud 格式的失败响应
+func mockFailure(code, message string) []byte {
+	data, _ := json.Marshal(map[string]interface{}{"success": false, "error": hme.APIError{ErrorCode: code, ErrorMessage: message}})
+	return data
+}
+
+// logMockRequest 输出一行请求记录，注入了故障时标出故障类型
+func logMockRequest(r *http.Request, endpoint string, status int, fault string, slow bool) {
+	color := ColorGreen
+	if status != http.StatusOK || fault != "" {
+		color = ColorYellow
+	}
+	line := fmt.Sprintf("  "+ColorDim+"%s"+ColorReset+" %-4s %-16s "+color+"%d"+ColorReset, time.Now().Format("15:04:05"), r.Method, endpoint, status)
+	var notes []string
+	if slow {
+		notes = append(notes, "慢响应")
+	}
+	if fault != "" && fault != MockFaultRateLimit && fault != MockFaultServerError {
+		notes = append(notes, fault)
+	}
+	if len(notes) > 0 {
+		line += " " + ColorMagenta + "[" + strings.Join(notes, ", ") + "]" + ColorReset
+	}
+	fmt.Println(line)
+}
+
+// printSummary 退出时按接口与故障类型汇总
+func (s *mockServer) printSummary() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	printSubHeader("请求统计")
+	total := 0
+	endpoints := make([]string, 0, len(s.requests))
+	for endpoint, n := range s.requests {
+		endpoints = append(endpoints, endpoint)
+		total += n
+	}
+	sort.Strings(endpoints)
+	for _, endpoint := range endpoints {
+		fmt.Printf("  %-16s %d\n", endpoint, s.requests[endpoint])
+	}
+	fmt.Printf("  "+ColorCyan+"共 %d 个请求，注入故障:"+ColorReset, total)
+	if len(s.injected) == 0 {
+		fmt.Print(" 无")
+	}
+	for _, fault := range []string{MockFaultRateLimit, MockFaultServerError, MockFaultSlow, MockFaultMalformed, MockFaultDisconnect} {
+		if n := s.injected[fault]; n > 0 {
+			fmt.Printf(" %s %d", fault, n)
+		}
+	}
+	fmt.Printf("\n  "+ColorCyan+"邮箱:"+ColorReset+" %d 个\n", len(s.emails))
+}
+
+// mockFaultFlags 注册故障注入参数（默认值取自 faults），返回的函数在解析参数后调用，补全默认值并校验
+func mockFaultFlags(fs *flag.FlagSet, faults *MockFaultConfig) func() error {
+	fs.Float64Var(&faults.RateLimit, "rate-limit", faults.RateLimit, "返回 429 的概率 (0-1)")
+	fs.IntVar(&faults.RetryAfterSeconds, "retry-after", faults.RetryAfterSeconds, "429 响应的 Retry-After 秒数")
+	fs.Float64Var(&faults.ServerError, "server-error", faults.ServerError, "返回 503 的概率 (0-1)")
+	fs.Float64Var(&faults.Slow, "slow", faults.Slow, "慢响应的概率 (0-1)")
+	fs.IntVar(&faults.SlowMillis, "slow-ms", faults.SlowMillis, "慢响应的延迟（毫秒）")
+	fs.Float64Var(&faults.Malformed, "malformed", faults.Malformed, "返回格式错误 JSON 的概率 (0-1)")
+	fs.Float64Var(&faults.Disconnect, "disconnect", faults.Disconnect, "响应中途断开连接的概率 (0-1)")
+	endpoints := fs.String("endpoints", strings.Join(faults.Endpoints, ","), "只对这些接口注入故障，逗号分隔，如 generate,reserve")
+	fs.Int64Var(&faults.Seed, "seed", faults.Seed, "随机种子，非 0 时故障序列可复现")
+	return func() error {
+		faults.Endpoints = parseTags(*endpoints)
+		if faults.RetryAfterSeconds <= 0 {
+			faults.RetryAfterSeconds = defaultMockRetryAfter
+		}
+		if faults.SlowMillis <= 0 {
+			faults.SlowMillis = defaultMockSlowMillis
+		}
+		return faults.validate()
+	}
+}
+
+// runMockServer 启动内置模拟服务器：mock-server [-addr 地址] [-rate-limit 概率] [-slow 概率] ...，
+// 未指定的参数取 developer.mock_server 中的配置
+func runMockServer(config *Config, args []string) error {
+	settings := config.Developer.MockServer
+	faults := settings.Faults
+	fs := flag.NewFlagSet("mock-server", flag.ContinueOnError)
+	addr := fs.String("addr", settings.ListenAddr, "监听地址（默认 "+defaultMockListenAddr+"）")
+	parseFaults := mockFaultFlags(fs, &faults)
+	if err := fs.Parse(args); err != nil {
+		return usageError(err)
+	}
+	if err := parseFaults(); err != nil {
+		return usageError(err)
+	}
+	if *addr == "" {
+		*addr = defaultMockListenAddr
+	}
+
+	listener, err := net.Listen("tcp", *addr)
+	if err != nil {
+		return fmt.Errorf("监听 %s 失败: %v", *addr, err)
+	}
+	mock := newMockServer(faults)
+	server := &http.Server{Handler: mock.handler(), ReadHeaderTimeout: 10 * time.Second}
+
+	printHeader("模拟服务器")
+	printInfo(fmt.Sprintf("接口地址: http://%s/v1/hme/reserve (填入 base_url 或 developer.mock_base_url)", listener.Addr()))
+	printInfo("故障注入: " + faults.Describe())
+	if faults.Seed != 0 {
+		printInfo(fmt.Sprintf("随机种子: %d", faults.Seed))
+	}
+	printInfo(fmt.Sprintf("统计: GET http://%s/mock/stats，Ctrl+C 退出", listener.Addr()))
+	fmt.Println()
+
+	safetyManager.Go("mock-shutdown", func(ctx context.Context) {
+		<-ctx.Done()
+		server.Close()
+	})
+	err = server.Serve(listener)
+	mock.printSummary()
+	if err == http.ErrServerClosed {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,485 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 通知事件之外的测试事件，notify-test 使用
+const NotifyTest = "test"
+
+// 同一事件在该间隔内只通知一次，避免并发创建时多个请求同时被限流连续推送
+const notifyDebounce = 30 * time.Second
+
+// 单个渠道发送一条通知的超时
+const notifyTimeout = 15 * time.Second
+
+// notifyEventTitles 各事件的通知标题
+var notifyEventTitles = map[string]string{
+	SoundBatchDone:      "批量创建结束",
+	SoundRateLimitStart: "被限流",
+	SoundRateLimitEnd:   "限流结束",
+	SoundError:          "出错",
+	NotifyTest:          "测试通知",
+}
+
+// Notification 一条通知
+type Notification struct {
+	Event   string    `json:"event"` // batch_done、rate_limit_start、rate_limit_end、error，与 sound_cues 的事件相同
+	Title   string    `json:"title"`
+	Message string    `json:"message"`
+	Account string    `json:"account,omitempty"`
+	Time    time.Time `json:"time"`
+}
+
+// Notifier 通知渠道
+type Notifier interface {
+	Notify(ctx context.Context, n Notification) error
+}
+
+// NotifyChannelConfig 一个通知渠道（notifications.channels 中的一项），按 type 使用其中的字段
+type NotifyChannelConfig struct {
+	Name string `json:"name"`
+	Type string `json:"type"` // desktop、webhook、telegram、email、mqtt
+
+	URL     string            `json:"url,omitempty"`     // webhook 地址；telegram 时可改为自建的 Bot API 地址
+	Headers map[string]string `json:"headers,omitempty"` // webhook 附加的请求头
+
+	BotToken string `json:"bot_token,omitempty"` // telegram
+	ChatID   string `json:"chat_id,omitempty"`
+
+	To string `json:"to,omitempty"` // email：收件地址，通过 smtp 配置发送
+
+	Broker   string `json:"broker,omitempty"` // mqtt：host:port，只支持不加密的 MQTT 3.1.1，QoS 0
+	Topic    string `json:"topic,omitempty"`
+	Username string `json:"username,omitempty"`
+	Password string `json:"password,omitempty"`
+}
+
+// NotificationsConfig 通知（notifications）：渠道列表与按事件的路由规则
+type NotificationsConfig struct {
+	Channels []NotifyChannelConfig `json:"channels"`
+	// 事件 → 渠道名，如 {"rate_limit_start": ["tg"], "batch_done": ["desktop"]}，"*" 匹配其他所有事件；
+	// 为空时每个事件发往所有渠道
+	Routes map[string][]string `json:"routes"`
+}
+
+// notifierFactories 通知渠道的类型注册表：type → 按配置构建渠道。新增渠道只需在这里注册
+var notifierFactories = map[string]func(c NotifyChannelConfig, config *Config) (Notifier, error){
+	"desktop": func(c NotifyChannelConfig, _ *Config) (Notifier, error) {
+		if runtime.GOOS != "linux" && runtime.GOOS != "darwin" {
+			return nil, fmt.Errorf("桌面通知暂只支持 Linux 与 macOS")
+		}
+		return desktopNotifier{}, nil
+	},
+	"webhook": func(c NotifyChannelConfig, config *Config) (Notifier, error) {
+		if c.URL == "" {
+			return nil, fmt.Errorf("未设置 url")
+		}
+		return webhookNotifier{client: notifyHTTPClient(config), url: c.URL, headers: c.Headers}, nil
+	},
+	"telegram": func(c NotifyChannelConfig, config *Config) (Notifier, error) {
+		if c.BotToken == "" || c.ChatID == "" {
+			return nil, fmt.Errorf("需要设置 bot_token 与 chat_id")
+		}
+		api := strings.TrimRight(c.URL, "/")
+		if api == "" {
+			api = "https://api.telegram.org"
+		}
+		return telegramNotifier{client: notifyHTTPClient(config), api: api, token: c.BotToken, chatID: c.ChatID}, nil
+	},
+	"email": func(c NotifyChannelConfig, config *Config) (Notifier, error) {
+		if c.To == "" {
+			return nil, fmt.Errorf("未设置 to")
+		}
+		if !config.SMTP.Enabled() {
+			return nil, fmt.Errorf("需要先在 smtp 中配置发信服务器")
+		}
+		return emailNotifier{settings: config.SMTP, to: c.To}, nil
+	},
+	"mqtt": func(c NotifyChannelConfig, _ *Config) (Notifier, error) {
+		if c.Broker == "" || c.Topic == "" {
+			return nil, fmt.Errorf("需要设置 broker 与 topic")
+		}
+		return mqttNotifier{broker: c.Broker, topic: c.Topic, username: c.Username, password: c.Password}, nil
+	},
+}
+
+// notifyChannel 已构建的渠道
+type notifyChannel struct {
+	name     string
+	notifier Notifier
+}
+
+// buildNotifiers 按配置构建所有渠道，并检查路由中引用的渠道名
+func buildNotifiers(config *Config) ([]notifyChannel, error) {
+	var channels []notifyChannel
+	names := make(map[string]bool)
+	for i, c := range config.Notifications.Channels {
+		name := strings.TrimSpace(c.Name)
+		if name == "" {
+			name = c.Type
+		}
+		if names[name] {
+			return nil, configError("notifications.channels 中的渠道名称 %q 重复，同类型的多个渠道请设置不同的 name", name)
+		}
+		names[name] = true
+		factory, ok := notifierFactories[strings.ToLower(c.Type)]
+		if !ok {
+			return nil, configError("notifications.channels[%d] 的类型 %q 不支持（可选: %s）", i, c.Type, strings.Join(notifierTypes(), "、"))
+		}
+		notifier, err := factory(c, config)
+		if err != nil {
+			return nil, configError("通知渠道 %s: %v", name, err)
+		}
+		channels = append(channels, notifyChannel{name: name, notifier: notifier})
+	}
+	for event, targets := range config.Notifications.Routes {
+		for _, target := range targets {
+			if !names[target] {
+				return nil, configError("notifications.routes 中事件 %s 的渠道 %q 不存在", event, target)
+			}
+		}
+	}
+	return channels, nil
+}
+
+// notifierTypes 已注册的渠道类型
+func notifierTypes() []string {
+	types := make([]string, 0, len(notifierFactories))
+	for name := range notifierFactories {
+		types = append(types, name)
+	}
+	sort.Strings(types)
+	return types
+}
+
+// routeChannels 事件应发往的渠道：先看事件自己的路由，再看 "*"，都没有配置时发往所有渠道
+func routeChannels(routes map[string][]string, channels []notifyChannel, event string) []notifyChannel {
+	if len(routes) == 0 {
+		return channels
+	}
+	targets, ok := routes[event]
+	if !ok {
+		targets = routes["*"]
+	}
+	var routed []notifyChannel
+	for _, channel := range channels {
+		if containsFold(targets, channel.name) {
+			routed = append(routed, channel)
+		}
+	}
+	return routed
+}
+
+var (
+	notifyMutex    sync.Mutex
+	lastNotify     = make(map[string]time.Time)
+	notifyWarned   bool
+	notifyInFlight sync.WaitGroup // 子命令退出前等待通知发送完
+)
+
+// notify 按路由规则把事件发往各渠道，不阻塞调用方；发送失败只输出到标准错误
+func notify(config *Config, event, message string) {
+	if config == nil || len(config.Notifications.Channels) == 0 {
+		return
+	}
+	channels, err := buildNotifiers(config)
+	notifyMutex.Lock()
+	if err != nil {
+		if !notifyWarned {
+			logger().Warn("通知配置无效", "error", err)
+			fmt.Fprintf(os.Stderr, ColorYellow+"[!] %v，通知未发送"+ColorReset+"\n", err)
+			notifyWarned = true
+		}
+		notifyMutex.Unlock()
+		return
+	}
+	if event != NotifyTest && time.Since(lastNotify[event]) < notifyDebounce {
+		notifyMutex.Unlock()
+		return
+	}
+	lastNotify[event] = time.Now()
+	notifyMutex.Unlock()
+
+	n := Notification{Event: event, Title: "iCloud 隐藏邮箱: " + notifyEventTitles[event], Message: message, Account: config.profile, Time: time.Now()}
+	for _, channel := range routeChannels(config.Notifications.Routes, channels, event) {
+		notifyInFlight.Add(1)
+		go func(channel notifyChannel) {
+			defer notifyInFlight.Done()
+			if err := sendNotification(channel, n); err != nil {
+				logger().Warn("发送通知失败", "event", event, "error", err)
+				fmt.Fprintf(os.Stderr, ColorYellow+"[!] %v"+ColorReset+"\n", err)
+			}
+		}(channel)
+	}
+}
+
+// sendNotification 向一个渠道发送通知，超过 notifyTimeout 视为失败
+func sendNotification(channel notifyChannel, n Notification) error {
+	ctx, cancel := context.WithTimeout(context.Background(), notifyTimeout)
+	defer cancel()
+	if err := channel.notifier.Notify(ctx, n); err != nil {
+		return fmt.Errorf("通知渠道 %s 发送失败: %v", channel.name, err)
+	}
+	return nil
+}
+
+// announce 发生事件时按 sound_cues 响铃，并按 notifications 发送通知
+func announce(config *Config, event, message string) {
+	soundCue(config, event)
+	notify(config, event, message)
+}
+
+// announceBatch 批量创建结束时提示：全部失败时按出错提示，否则按完成提示；取消时不提示
+func announceBatch(config *Config, succeeded, failed int) {
+	switch {
+	case operationCanceled():
+	case succeeded == 0 && failed > 0:
+		announce(config, SoundError, fmt.Sprintf("批量创建全部失败（%d 个）", failed))
+	default:
+		announce(config, SoundBatchDone, fmt.Sprintf("批量创建结束：成功 %d 个，失败 %d 个", succeeded, failed))
+	}
+}
+
+// waitAnnouncements 等待正在播放的提示音与正在发送的通知，最多等待 timeout
+func waitAnnouncements(timeout time.Duration) {
+	done := make(chan struct{})
+	go func() {
+		soundCueWait.Wait()
+		notifyInFlight.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(timeout):
+	}
+}
+
+// desktopNotifier 系统桌面通知：Linux 使用 notify-send，macOS 使用 osascript
+type desktopNotifier struct{}
+
+func (desktopNotifier) Notify(ctx context.Context, n Notification) error {
+	var cmd *exec.Cmd
+	if runtime.GOOS == "darwin" {
+		script := fmt.Sprintf("display notification %s with title %s", appleScriptString(n.Message), appleScriptString(n.Title))
+		cmd = exec.CommandContext(ctx, "osascript", "-e", script)
+	} else {
+		cmd = exec.CommandContext(ctx, "notify-send", "--app-name=icloud-hme", n.Title, n.Message)
+	}
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("%v %s", err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+// appleScriptString 转义为 AppleScript 字符串字面量
+func appleScriptString(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
+
+// notifyHTTPClient 通知渠道使用的 HTTP 客户端，与 iCloud 请求一样遵循 proxy_url（留空时遵循代理环境变量）
+func notifyHTTPClient(config *Config) *http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = config.proxy()
+	return &http.Client{Transport: transport}
+}
+
+// webhookNotifier 以 JSON POST 通知内容
+type webhookNotifier struct {
+	client  *http.Client
+	url     string
+	headers map[string]string
+}
+
+func (w webhookNotifier) Notify(ctx context.Context, n Notification) error {
+	payload, err := json.Marshal(n)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.url, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for key, value := range w.headers {
+		req.Header.Set(key, value)
+	}
+	return doNotifyRequest(w.client, req)
+}
+
+// telegramNotifier 通过 Telegram Bot API 的 sendMessage 发送
+type telegramNotifier struct {
+	client             *http.Client
+	api, token, chatID string
+}
+
+func (t telegramNotifier) Notify(ctx context.Context, n Notification) error {
+	form := url.Values{"chat_id": {t.chatID}, "text": {n.Title + "\n" + n.Message}}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, t.api+"/bot"+t.token+"/sendMessage", strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	err = doNotifyRequest(t.client, req)
+	// 请求地址中含有 Bot Token，*url.Error 会把它带进错误信息与日志
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		urlErr.URL = t.api + "/bot" + redactedLogValue + "/sendMessage"
+	}
+	return err
+}
+
+// doNotifyRequest 发送请求，非 2xx 状态码视为失败
+func doNotifyRequest(client *http.Client, req *http.Request) error {
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("HTTP %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	return nil
+}
+
+// emailNotifier 通过 smtp 配置发送邮件
+type emailNotifier struct {
+	settings SMTPConfig
+	to       string
+}
+
+func (e emailNotifier) Notify(ctx context.Context, n Notification) error {
+	return sendMail(ctx, e.settings, e.to, n.Title, n.Message+"\n\n"+n.Time.Format("2006-01-02 15:04:05"))
+}
+
+// mqttNotifier 以 MQTT 3.1.1 QoS 0 向 topic 发布 JSON 通知，每条通知单独建立连接
+type mqttNotifier struct {
+	broker, topic      string
+	username, password string
+}
+
+func (m mqttNotifier) Notify(ctx context.Context, n Notification) error {
+	payload, err := json.Marshal(n)
+	if err != nil {
+		return err
+	}
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", m.broker)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	// CONNECT：协议名 MQTT、级别 4（3.1.1）、清除会话、保活 30 秒
+	flags := byte(0x02)
+	body := append(mqttString("MQTT"), 4, 0, 0, 30)
+	body = append(body, mqttString(fmt.Sprintf("icloud-hme-%d", os.Getpid()))...)
+	if m.username != "" {
+		flags |= 0x80
+		body = append(body, mqttString(m.username)...)
+		if m.password != "" {
+			flags |= 0x40
+			body = append(body, mqttString(m.password)...)
+		}
+	}
+	body[7] = flags
+	if _, err := conn.Write(mqttPacket(0x10, body)); err != nil {
+		return err
+	}
+	ack := make([]byte, 4)
+	if _, err := io.ReadFull(conn, ack); err != nil {
+		return fmt.Errorf("读取 CONNACK 失败: %v", err)
+	}
+	if ack[0] != 0x20 || ack[3] != 0 {
+		return fmt.Errorf("MQTT 服务器拒绝连接（返回码 %d）", ack[3])
+	}
+
+	publish := append(mqttString(m.topic), payload...)
+	if _, err := conn.Write(mqttPacket(0x30, publish)); err != nil {
+		return err
+	}
+	_, err = conn.Write([]byte{0xE0, 0}) // DISCONNECT
+	return err
+}
+
+// mqttString MQTT 的长度前缀字符串
+func mqttString(s string) []byte {
+	b := make([]byte, 2, 2+len(s))
+	binary.BigEndian.PutUint16(b, uint16(len(s)))
+	return append(b, s...)
+}
+
+// mqttPacket 按 MQTT 的变长编码写入剩余长度
+func mqttPacket(header byte, body []byte) []byte {
+	packet := []byte{header}
+	length := len(body)
+	for {
+		digit := byte(length % 128)
+		length /= 128
+		if length > 0 {
+			digit |= 0x80
+		}
+		packet = append(packet, digit)
+		if length == 0 {
+			break
+		}
+	}
+	return append(packet, body...)
+}
+
+// runNotifyTest 向各渠道发送一条测试通知并逐个报告结果：notify-test [渠道名...]
+func runNotifyTest(config *Config, args []string) error {
+	fs := flag.NewFlagSet("notify-test", flag.ContinueOnError)
+	if err := fs.Parse(args); err != nil {
+		return usageError(err)
+	}
+	channels, err := buildNotifiers(config)
+	if err != nil {
+		return err
+	}
+	if len(channels) == 0 {
+		return configError("未配置通知渠道，请在 config.json 的 notifications.channels 中添加")
+	}
+	n := Notification{Event: NotifyTest, Title: "iCloud 隐藏邮箱: " + notifyEventTitles[NotifyTest], Message: "通知渠道配置正确", Account: config.profile, Time: time.Now()}
+	sent, failed := 0, 0
+	for _, channel := range channels {
+		if fs.NArg() > 0 && !containsFold(fs.Args(), channel.name) {
+			continue
+		}
+		sent++
+		if err := sendNotification(channel, n); err != nil {
+			printError(err.Error())
+			failed++
+			continue
+		}
+		printSuccess(fmt.Sprintf("已发送到 %s", channel.name))
+	}
+	if sent == 0 {
+		return usageError(fmt.Errorf("没有名为 %s 的通知渠道", strings.Join(fs.Args(), "、")))
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d 个渠道发送失败", failed)
+	}
+	return nil
+}
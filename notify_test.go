@@ -0,0 +1,25 @@
+package main
+
+import (
+	"context"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// TestTelegramErrorHidesToken 请求失败时错误信息中不出现 Bot Token
+func TestTelegramErrorHidesToken(t *testing.T) {
+	server := httptest.NewServer(nil)
+	server.Close()
+	notifier, err := notifierFactories["telegram"](NotifyChannelConfig{URL: server.URL, BotToken: "123:secret-token", ChatID: "1"}, &Config{ProxyURL: proxyDirect})
+	if err != nil {
+		t.Fatalf("创建渠道失败: %v", err)
+	}
+	err = notifier.Notify(context.Background(), Notification{Title: "t", Message: "m"})
+	if err == nil {
+		t.Fatal("连接已关闭的服务器应当失败")
+	}
+	if strings.Contains(err.Error(), "secret-token") {
+		t.Fatalf("错误信息中含有 Bot Token: %v", err)
+	}
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// OTPResult 从最新邮件中提取的一次性验证码
+type OTPResult struct {
+	Code       string `json:"code"`
+	Address    string `json:"address"`
+	From       string `json:"from"`
+	Subject    string `json:"subject"`
+	ReceivedAt int64  `json:"receivedAt,omitempty"` // Unix 毫秒
+	Pattern    string `json:"pattern"`              // 命中的规则，builtin 表示内置规则
+}
+
+// compileOTPPatterns 编译用户配置的验证码规则，每条规则需包含一个捕获组
+func compileOTPPatterns(patterns []string) ([]*regexp.Regexp, error) {
+	compiled := make([]*regexp.Regexp, 0, len(patterns))
+	for _, pattern := range patterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("验证码规则 %q 无效: %v", pattern, err)
+		}
+		if re.NumSubexp() < 1 {
+			return nil, fmt.Errorf("验证码规则 %q 缺少捕获组", pattern)
+		}
+		compiled = append(compiled, re)
+	}
+	return compiled, nil
+}
+
+// ExtractOTP 按自定义规则优先、内置规则兜底的顺序提取验证码
+func (m *MailMessage) ExtractOTP(custom []*regexp.Regexp) (code, pattern string) {
+	for _, re := range custom {
+		for _, source := range []string{m.Subject, m.Text} {
+			if match := re.FindStringSubmatch(source); match != nil && match[1] != "" {
+				return match[1], re.String()
+			}
+		}
+	}
+	if codes := m.VerificationCodes(); len(codes) > 0 {
+		return codes[0], "builtin"
+	}
+	return "", ""
+}
+
+// latestOTP 读取发往 address 的最新邮件并提取验证码
+func latestOTP(settings IMAPConfig, address string, within time.Duration) (*OTPResult, error) {
+	custom, err := compileOTPPatterns(settings.OTPPatterns)
+	if err != nil {
+		return nil, err
+	}
+
+	messages, err := fetchMessagesTo(settings, address, time.Now().Add(-within), 30*time.Second)
+	if err != nil {
+		return nil, err
+	}
+	if len(messages) == 0 {
+		return nil, nil
+	}
+
+	// 只看最新一封，避免返回已过期的旧验证码
+	message := messages[0]
+	code, pattern := message.ExtractOTP(custom)
+	if code == "" {
+		return nil, nil
+	}
+	result := &OTPResult{
+		Code:    code,
+		Address: address,
+		From:    message.From,
+		Subject: message.Subject,
+		Pattern: pattern,
+	}
+	if !message.Date.IsZero() {
+		result.ReceivedAt = message.Date.UnixMilli()
+	}
+	return result, nil
+}
+
+// runOTP 命令行提取最新验证码
+func runOTP(config *Config, args []string) error {
+	fs := flag.NewFlagSet("otp", flag.ContinueOnError)
+	within := fs.Int("within", 15, "只查找最近多少分钟内的邮件")
+	raw := fs.Bool("raw", false, "只输出验证码，便于脚本使用")
+	if err := fs.Parse(args); err != nil {
+		return usageError(err)
+	}
+	if fs.NArg() != 1 || !strings.Contains(fs.Arg(0), "@") {
+		return fmt.Errorf("用法: otp [-within 分钟] [-raw] 邮箱地址")
+	}
+
+	result, err := latestOTP(config.IMAP, fs.Arg(0), time.Duration(*within)*time.Minute)
+	if err != nil {
+		return err
+	}
+	if result == nil {
+		return fmt.Errorf("最近 %d 分钟内没有发往 %s 的验证码邮件", *within, fs.Arg(0))
+	}
+
+	if *raw {
+		fmt.Println(result.Code)
+		return nil
+	}
+	printHeader("一次性验证码")
+	fmt.Printf("  "+ColorBrightGreen+"验证码:"+ColorReset+" "+ColorBold+"%s"+ColorReset+"\n", result.Code)
+	fmt.Printf("  "+ColorCyan+"发件人:"+ColorReset+" %s\n", result.From)
+	fmt.Printf("  "+ColorCyan+"主题:"+ColorReset+" %s\n", result.Subject)
+	if result.ReceivedAt > 0 {
+		fmt.Printf("  "+ColorCyan+"时间:"+ColorReset+" %s\n", formatMillis(result.ReceivedAt))
+	}
+	return nil
+}
+
+// handleOTP 查询发往指定隐藏邮箱的最新验证码
+func (s *APIServer) handleOTP(w http.ResponseWriter, r *http.Request, client *apiClient) {
+	address := strings.TrimSpace(r.URL.Query().Get("address"))
+	if !strings.Contains(address, "@") {
+		writeServeError(w, http.StatusBadRequest, "invalid_address", "address 参数必须是邮箱地址")
+		return
+	}
+	within := 15
+	if value := r.URL.Query().Get("within"); value != "" {
+		minutes, err := strconv.Atoi(value)
+		if err != nil || minutes <= 0 || minutes > 1440 {
+			writeServeError(w, http.StatusBadRequest, "invalid_within", "within 必须在 1-1440 分钟之间")
+			return
+		}
+		within = minutes
+	}
+
+	config := getCurrentConfig()
+	if !config.IMAP.Enabled() {
+		writeServeError(w, http.StatusNotImplemented, "imap_not_configured", "服务未配置 IMAP")
+		return
+	}
+	result, err := latestOTP(config.IMAP, address, time.Duration(within)*time.Minute)
+	if err != nil {
+		writeServeError(w, http.StatusBadGateway, "imap_error", err.Error())
+		return
+	}
+	if result == nil {
+		writeServeError(w, http.StatusNotFound, "not_found", "没有找到验证码邮件")
+		return
+	}
+	writeServeResult(w, result)
+}
@@ -0,0 +1,392 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// 配置覆盖的环境变量前缀：HME_ 加上大写的配置路径，层级之间用下划线连接，如 HME_RETRY_POLICY_MAX_RETRIES
+const configEnvPrefix = "HME_"
+
+// configFlagOverrides 命令行 --set 路径=值 指定的覆盖，按出现顺序生效
+var configFlagOverrides []string
+
+// configSetting 可以覆盖的一项配置
+type configSetting struct {
+	Path  string // JSON 路径，如 retry_policy.max_retries
+	Env   string // 对应的环境变量
+	index []int
+	typ   reflect.Type
+}
+
+// 不允许覆盖的配置项：加密内容只能由 secrets 命令维护
+var configOverrideExcluded = map[string]bool{
+	"encrypted_secrets": true,
+}
+
+// 常用的简写：HME_COOKIE / --set cookie=... 等同于 headers.Cookie
+var configOverrideAliases = map[string]string{
+	"cookie": "headers.Cookie",
+}
+
+var (
+	configSettingsOnce sync.Once
+	configSettingsList []configSetting
+)
+
+// configSettings Config 中所有可覆盖的配置项：嵌套的结构体逐项展开，列表、映射等整体以 JSON 覆盖
+func configSettings() []configSetting {
+	configSettingsOnce.Do(func() {
+		var walk func(t reflect.Type, prefix string, index []int)
+		walk = func(t reflect.Type, prefix string, index []int) {
+			for i := 0; i < t.NumField(); i++ {
+				field := t.Field(i)
+				name := strings.Split(field.Tag.Get("json"), ",")[0]
+				if !field.IsExported() || name == "" || name == "-" {
+					continue
+				}
+				path := prefix + name
+				if configOverrideExcluded[path] {
+					continue
+				}
+				fieldIndex := append(append([]int{}, index...), i)
+				if field.Type.Kind() == reflect.Struct {
+					walk(field.Type, path+".", fieldIndex)
+					continue
+				}
+				configSettingsList = append(configSettingsList, configSetting{
+					Path:  path,
+					Env:   configEnvPrefix + strings.ToUpper(strings.ReplaceAll(path, ".", "_")),
+					index: fieldIndex,
+					typ:   field.Type,
+				})
+			}
+		}
+		walk(reflect.TypeOf(Config{}), "", nil)
+	})
+	return configSettingsList
+}
+
+// configOverride 一项覆盖
+type configOverride struct {
+	Setting configSetting
+	Key     string // 映射中的单个键（如 headers.Cookie 的 Cookie），为空表示覆盖整个字段
+	Source  string // 来源：环境变量名或 --set
+	Value   string
+}
+
+// Name 显示用的名称
+func (o configOverride) Name() string {
+	if o.Key != "" {
+		return o.Setting.Path + "." + o.Key
+	}
+	return o.Setting.Path
+}
+
+// findConfigSetting 按路径查找配置项；路径指向映射中的某个键时返回该键
+func findConfigSetting(path string) (configSetting, string, bool) {
+	path = strings.TrimSpace(path)
+	if alias, ok := configOverrideAliases[strings.ToLower(path)]; ok {
+		path = alias
+	}
+	for _, setting := range configSettings() {
+		if strings.EqualFold(setting.Path, path) {
+			return setting, "", true
+		}
+		if setting.typ.Kind() == reflect.Map && setting.typ.Key().Kind() == reflect.String &&
+			len(path) > len(setting.Path)+1 && strings.EqualFold(path[:len(setting.Path)+1], setting.Path+".") {
+			return setting, path[len(setting.Path)+1:], true
+		}
+	}
+	return configSetting{}, "", false
+}
+
+// collectConfigOverrides 收集环境变量与 --set 中的覆盖，优先级：--set > 环境变量 > 配置文件（后生效的覆盖先生效的）
+func collectConfigOverrides() ([]configOverride, error) {
+	var overrides []configOverride
+	for _, setting := range configSettings() {
+		if value, ok := os.LookupEnv(setting.Env); ok {
+			overrides = append(overrides, configOverride{Setting: setting, Source: setting.Env, Value: value})
+		}
+	}
+	// 简写比整体覆盖（如 HME_HEADERS）更具体，后生效
+	for alias, path := range configOverrideAliases {
+		env := configEnvPrefix + strings.ToUpper(alias)
+		if value, ok := os.LookupEnv(env); ok {
+			setting, key, _ := findConfigSetting(path)
+			overrides = append(overrides, configOverride{Setting: setting, Key: key, Source: env, Value: value})
+		}
+	}
+	for _, arg := range configFlagOverrides {
+		path, value, ok := strings.Cut(arg, "=")
+		if !ok {
+			return nil, usageError(fmt.Errorf("--set 的格式为 路径=值，如 --set max_concurrency=3: %s", arg))
+		}
+		setting, key, found := findConfigSetting(path)
+		if !found {
+			return nil, usageError(fmt.Errorf("--set: 未知的配置项 %s（运行 config-keys 查看全部）", path))
+		}
+		overrides = append(overrides, configOverride{Setting: setting, Key: key, Source: "--set", Value: value})
+	}
+	return overrides, nil
+}
+
+// configOverridesPresent 是否指定了任何覆盖（没有 config.json 时也可以只靠覆盖运行）
+func configOverridesPresent() bool {
+	overrides, err := collectConfigOverrides()
+	return err == nil && len(overrides) > 0
+}
+
+// parseSettingValue 把字符串解析为配置项的类型：字符串原样使用，数字与布尔按字面解析，其余按 JSON 解析（映射在 apply 中与原值合并）
+func parseSettingValue(typ reflect.Type, raw string) (reflect.Value, error) {
+	value := reflect.New(typ).Elem()
+	switch typ.Kind() {
+	case reflect.String:
+		value.SetString(raw)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(strings.TrimSpace(raw))
+		if err != nil {
+			return value, fmt.Errorf("需要 true 或 false")
+		}
+		value.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(strings.TrimSpace(raw), 10, typ.Bits())
+		if err != nil {
+			return value, fmt.Errorf("需要整数")
+		}
+		value.SetInt(n)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(strings.TrimSpace(raw), typ.Bits())
+		if err != nil {
+			return value, fmt.Errorf("需要数字")
+		}
+		value.SetFloat(f)
+	default:
+		if err := json.Unmarshal([]byte(raw), value.Addr().Interface()); err != nil {
+			return value, fmt.Errorf("需要 JSON: %v", err)
+		}
+	}
+	return value, nil
+}
+
+// appliedOverride 已应用的覆盖及被覆盖前的文件值，保存配置时据此还原，覆盖的值不会写入 config.json
+type appliedOverride struct {
+	configOverride
+	fileValue    reflect.Value
+	fileHasKey   bool // 映射中的键在文件中是否存在
+	appliedValue reflect.Value
+}
+
+// field 覆盖作用的字段
+func (o configOverride) field(config *Config) reflect.Value {
+	return reflect.ValueOf(config).Elem().FieldByIndex(o.Setting.index)
+}
+
+// apply 应用一项覆盖，返回用于保存时还原的记录
+func (o configOverride) apply(config *Config) (appliedOverride, error) {
+	field := o.field(config)
+	applied := appliedOverride{configOverride: o}
+	if o.Key != "" {
+		elemType := o.Setting.typ.Elem()
+		value, err := parseSettingValue(elemType, o.Value)
+		if err != nil {
+			return applied, configError("%s（%s）: %v", o.Name(), o.Source, err)
+		}
+		if field.IsNil() {
+			field.Set(reflect.MakeMap(o.Setting.typ))
+		}
+		key := reflect.ValueOf(o.Key)
+		if existing := field.MapIndex(key); existing.IsValid() {
+			applied.fileValue, applied.fileHasKey = existing, true
+		}
+		field.SetMapIndex(key, value)
+		applied.appliedValue = value
+		return applied, nil
+	}
+
+	var value reflect.Value
+	var err error
+	if o.Setting.typ.Kind() == reflect.Map {
+		// 映射与文件中的合并，只替换给出的键
+		value = reflect.MakeMap(o.Setting.typ)
+		for iter := field.MapRange(); iter.Next(); {
+			value.SetMapIndex(iter.Key(), iter.Value())
+		}
+		target := reflect.New(o.Setting.typ)
+		target.Elem().Set(value)
+		if jsonErr := json.Unmarshal([]byte(o.Value), target.Interface()); jsonErr != nil {
+			err = fmt.Errorf("需要 JSON: %v", jsonErr)
+		}
+	} else {
+		value, err = parseSettingValue(o.Setting.typ, o.Value)
+	}
+	if err != nil {
+		return applied, configError("%s（%s）: %v", o.Name(), o.Source, err)
+	}
+	applied.fileValue = reflect.New(o.Setting.typ).Elem()
+	applied.fileValue.Set(field)
+	field.Set(value)
+	applied.appliedValue = value
+	return applied, nil
+}
+
+// accountSettingPaths 账号配置（profiles）中也有的配置项，选中账号时这些覆盖在账号字段之后应用
+var accountSettingPaths = func() map[string]bool {
+	paths := make(map[string]bool)
+	t := reflect.TypeOf(AccountProfile{})
+	for i := 0; i < t.NumField(); i++ {
+		paths[strings.Split(t.Field(i).Tag.Get("json"), ",")[0]] = true
+	}
+	return paths
+}()
+
+// splitAccountOverrides 把覆盖分为普通配置项与账号字段两组，各自保持原有顺序
+func splitAccountOverrides(overrides []configOverride) (general, account []configOverride) {
+	for _, override := range overrides {
+		if accountSettingPaths[override.Setting.Path] {
+			account = append(account, override)
+		} else {
+			general = append(general, override)
+		}
+	}
+	return general, account
+}
+
+// applyConfigOverrides 依次应用覆盖
+func applyConfigOverrides(config *Config, overrides []configOverride) ([]appliedOverride, error) {
+	applied := make([]appliedOverride, 0, len(overrides))
+	for _, override := range overrides {
+		record, err := override.apply(config)
+		if err != nil {
+			return nil, err
+		}
+		applied = append(applied, record)
+	}
+	return applied, nil
+}
+
+// sameValue 两个值序列化后是否相同
+func sameValue(a, b reflect.Value) bool {
+	left, err1 := json.Marshal(a.Interface())
+	right, err2 := json.Marshal(b.Interface())
+	return err1 == nil && err2 == nil && bytes.Equal(left, right)
+}
+
+// restoreConfigOverrides 保存配置前把仍为覆盖值的字段还原为文件中的原值；程序运行中改过的（如重新导入 Cookie）照常保存。
+// out 为待写入配置的副本，账号字段还原为账号配置中的值后再由 marshalConfigForSave 写回
+func restoreConfigOverrides(out *Config, applied []appliedOverride) {
+	// 后应用的先还原，同一字段多次覆盖时最终回到文件值
+	for i := len(applied) - 1; i >= 0; i-- {
+		record := applied[i]
+		field := record.field(out)
+		if record.Key == "" {
+			if sameValue(field, record.appliedValue) {
+				field.Set(record.fileValue)
+			}
+			continue
+		}
+		if field.IsNil() {
+			continue
+		}
+		key := reflect.ValueOf(record.Key)
+		current := field.MapIndex(key)
+		if !current.IsValid() || !sameValue(current, record.appliedValue) {
+			continue
+		}
+		if record.fileHasKey {
+			field.SetMapIndex(key, record.fileValue)
+		} else {
+			field.SetMapIndex(key, reflect.Value{})
+		}
+	}
+}
+
+// peekConfig 主配置加载前读取配置文件并应用覆盖，用于提前决定界面与输出格式；出错时尽量返回可用部分
+func peekConfig(path string) *Config {
+	var config Config
+	if data, _, err := readConfigJSON(path); err == nil {
+		json.Unmarshal(data, &config)
+	}
+	if overrides, err := collectConfigOverrides(); err == nil {
+		for _, override := range overrides {
+			override.apply(&config)
+		}
+	}
+	return &config
+}
+
+// describeOverrides 已应用覆盖的说明（不含值，避免把 Cookie 打印出来）
+func describeOverrides(applied []appliedOverride) string {
+	names := make([]string, 0, len(applied))
+	for _, record := range applied {
+		if record.Source == "--set" {
+			names = append(names, "--set "+record.Name())
+		} else {
+			names = append(names, record.Source)
+		}
+	}
+	return strings.Join(names, ", ")
+}
+
+// runConfigKeys 列出所有可覆盖的配置项及对应的环境变量：config-keys [关键字]
+func runConfigKeys(args []string) error {
+	if len(args) > 1 {
+		return usageError(fmt.Errorf("用法: config-keys [关键字]"))
+	}
+	filter := ""
+	if len(args) == 1 {
+		filter = strings.ToLower(args[0])
+	}
+
+	type keyInfo struct {
+		Path string `json:"path"`
+		Env  string `json:"env"`
+		Type string `json:"type"`
+		Set  string `json:"set,omitempty"` // 当前生效的覆盖来源
+	}
+	active := map[string]string{}
+	if overrides, err := collectConfigOverrides(); err == nil {
+		for _, override := range overrides {
+			active[override.Name()] = override.Source
+		}
+	}
+	var keys []keyInfo
+	for _, setting := range configSettings() {
+		if filter != "" && !strings.Contains(strings.ToLower(setting.Path), filter) && !strings.Contains(strings.ToLower(setting.Env), filter) {
+			continue
+		}
+		typ := setting.typ.Kind().String()
+		if kind := setting.typ.Kind(); kind == reflect.Slice || kind == reflect.Map || kind == reflect.Pointer {
+			typ = "json"
+		}
+		keys = append(keys, keyInfo{Path: setting.Path, Env: setting.Env, Type: typ, Set: active[setting.Path]})
+	}
+	for alias, path := range configOverrideAliases {
+		if filter == "" || strings.Contains(alias, filter) {
+			keys = append(keys, keyInfo{Path: path, Env: configEnvPrefix + strings.ToUpper(alias), Type: "string", Set: active[path]})
+		}
+	}
+	sort.SliceStable(keys, func(i, j int) bool { return keys[i].Path < keys[j].Path })
+
+	if outputJSON {
+		return writeJSON(keys)
+	}
+	printHeader("可覆盖的配置项")
+	fmt.Println("  优先级：--set 路径=值 > 环境变量 > 配置文件；列表与映射的值为 JSON，映射也可用 --set headers.名称=值 只改一项")
+	fmt.Println()
+	for _, key := range keys {
+		line := fmt.Sprintf("  %-44s %s%-50s%s %s%s%s", key.Path, ColorCyan, key.Env, ColorReset, ColorDim, key.Type, ColorReset)
+		if key.Set != "" {
+			line += " " + ColorYellow + "← " + key.Set + ColorReset
+		}
+		fmt.Println(line)
+	}
+	return nil
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestAccountOverrideWithProfile 选中账号配置时，环境变量覆盖的账号字段优先于账号配置，保存时也不会写入文件
+func TestAccountOverrideWithProfile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	data := fmt.Sprintf(`{
+  "config_version": %d,
+  "dsid": "top-dsid",
+  "headers": {"Cookie": "top-cookie"},
+  "active_profile": "work",
+  "profiles": {"work": {"dsid": "work-dsid", "headers": {"Cookie": "work-cookie"}}}
+}`, currentConfigVersion)
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("HME_DSID", "env-dsid")
+	configFlagOverrides = []string{"cookie=flag-cookie"}
+	defer func() { configFlagOverrides = nil }()
+
+	manager := NewConfigManager(path)
+	config, err := manager.LoadConfig()
+	if err != nil {
+		t.Fatalf("加载配置失败: %v", err)
+	}
+	if config.profile != "work" {
+		t.Fatalf("当前账号 %q，期望 work", config.profile)
+	}
+	if config.DSID != "env-dsid" {
+		t.Errorf("dsid 为 %q，期望环境变量的 env-dsid", config.DSID)
+	}
+	if cookie := config.Headers["Cookie"]; cookie != "flag-cookie" {
+		t.Errorf("Cookie 为 %q，期望 --set 的 flag-cookie", cookie)
+	}
+
+	if err := manager.SaveConfig(config); err != nil {
+		t.Fatalf("保存配置失败: %v", err)
+	}
+	saved, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out Config
+	if err := json.Unmarshal(saved, &out); err != nil {
+		t.Fatal(err)
+	}
+	if out.DSID != "top-dsid" || out.Headers["Cookie"] != "top-cookie" {
+		t.Errorf("顶层账号字段被改写: dsid=%q Cookie=%q", out.DSID, out.Headers["Cookie"])
+	}
+	work := out.Profiles["work"]
+	if work == nil || work.DSID != "work-dsid" || work.Headers["Cookie"] != "work-cookie" {
+		t.Errorf("覆盖的值写入了账号配置: %+v", work)
+	}
+}
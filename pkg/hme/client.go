@@ -0,0 +1,371 @@
+// Package hme 是 iCloud 隐藏邮件地址（Hide My Email）网页接口的客户端。
+//
+// 使用前需要从已登录的 icloud.com 会话中取得 reserve 接口地址、Cookie 与 dsid 等参数：
+//
+//	client := &hme.Client{
+//		BaseURL:  "https://p68-maildomainws.icloud.com/v1/hme/reserve",
+//		ClientID: "...",
+//		DSID:     "...",
+//		Headers:  map[string]string{"Cookie": "..."},
+//	}
+//	email, err := client.Create(ctx, "newsletter", "")
+package hme
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+)
+
+// DefaultLangCode 未指定 LangCode 时生成邮箱使用的语言
+const DefaultLangCode = "en-us"
+
+// Client iCloud 隐藏邮件地址客户端，零值字段使用默认值；并发使用时不要修改字段
+type Client struct {
+	// BaseURL reserve 接口的完整地址，其余接口由它推导，如 https://pXX-maildomainws.icloud.com/v1/hme/reserve
+	BaseURL               string
+	ClientBuildNumber     string
+	ClientMasteringNumber string
+	ClientID              string
+	DSID                  string
+
+	// Headers 每个请求都会带上的请求头，至少需要 Cookie
+	Headers map[string]string
+
+	// LangCode Generate 未指定语言时使用，为空时使用 DefaultLangCode
+	LangCode string
+
+	// HTTPClient 为空时使用 http.DefaultClient
+	HTTPClient *http.Client
+
+	// PrepareRequest 在设置完请求头后、发送前调用，可用于调整 User-Agent 等
+	PrepareRequest func(req *http.Request)
+
+	// Limiter 不为空时，Generate 与 Reserve 发送前先调用 Wait（Apple 只对创建相关接口限流）
+	Limiter Limiter
+
+	// Retry 网络错误与临时性状态码的重试策略，为空时不重试
+	Retry *RetryPolicy
+
+	// RefreshSession 请求因会话过期（SessionExpired）失败时调用一次，返回刷新后的 Cookie 请求头，
+	// 成功后用新 Cookie 重发该请求；为空时直接返回错误
+	RefreshSession func(ctx context.Context) (string, error)
+}
+
+// Limiter 限制创建请求的速率，可由多个 Client 与 goroutine 共享
+type Limiter interface {
+	// Wait 阻塞到允许发送下一个请求，ctx 取消时返回其错误
+	Wait(ctx context.Context) error
+}
+
+// messageResult 停用、重新激活、彻底删除接口的结果
+type messageResult struct {
+	Message string `json:"message"`
+}
+
+// Generate 第1步：生成一个候选邮箱地址，langCode 为空时使用 c.LangCode（影响 Apple 生成前缀所用的单词）
+func (c *Client) Generate(ctx context.Context, langCode string) (string, error) {
+	if langCode == "" {
+		langCode = c.LangCode
+	}
+	if langCode == "" {
+		langCode = DefaultLangCode
+	}
+	if err := c.wait(ctx); err != nil {
+		return "", err
+	}
+	var result generateResult
+	if err := c.call(ctx, http.MethodPost, "/reserve", "/generate", generateRequest{LangCode: langCode}, &result); err != nil {
+		return "", err
+	}
+	return result.HME, nil
+}
+
+// Reserve 第2步：确认创建 Generate 得到的邮箱地址并设置标签
+func (c *Client) Reserve(ctx context.Context, address, label, note string) (*Email, error) {
+	if err := c.wait(ctx); err != nil {
+		return nil, err
+	}
+	var result reserveResult
+	if err := c.call(ctx, http.MethodPost, "", "", reserveRequest{HME: address, Label: label, Note: note}, &result); err != nil {
+		return nil, err
+	}
+	return &result.HME, nil
+}
+
+// Create 生成并确认创建一个邮箱（Generate + Reserve）
+func (c *Client) Create(ctx context.Context, label, langCode string) (*Email, error) {
+	address, err := c.Generate(ctx, langCode)
+	if err != nil {
+		return nil, fmt.Errorf("生成邮箱地址失败: %w", err)
+	}
+	email, err := c.Reserve(ctx, address, label, "")
+	if err != nil {
+		return nil, fmt.Errorf("确认创建邮箱失败: %w", err)
+	}
+	return email, nil
+}
+
+// List 获取全部邮箱（包括已停用的）
+func (c *Client) List(ctx context.Context) (*List, error) {
+	var result List
+	if err := c.call(ctx, http.MethodGet, "/v1/hme/reserve", "/v2/hme/list", nil, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// Deactivate 停用邮箱，停用后不再转发邮件，可重新激活
+func (c *Client) Deactivate(ctx context.Context, anonymousID string) error {
+	return c.call(ctx, http.MethodPost, "/reserve", "/deactivate", anonymousIDRequest{AnonymousID: anonymousID}, &messageResult{})
+}
+
+// Reactivate 重新激活已停用的邮箱
+func (c *Client) Reactivate(ctx context.Context, anonymousID string) error {
+	return c.call(ctx, http.MethodPost, "/v1/hme/reserve", "/v1/hme/reactivate", anonymousIDRequest{AnonymousID: anonymousID}, &messageResult{})
+}
+
+// Delete 彻底删除已停用的邮箱（不可恢复）
+func (c *Client) Delete(ctx context.Context, anonymousID string) error {
+	return c.call(ctx, http.MethodPost, "/v1/hme/reserve", "/v1/hme/delete", anonymousIDRequest{AnonymousID: anonymousID}, &messageResult{})
+}
+
+// UpdateMetaData 更新邮箱的标签和备注
+func (c *Client) UpdateMetaData(ctx context.Context, anonymousID, label, note string) error {
+	return c.call(ctx, http.MethodPost, "/v1/hme/reserve", "/v1/hme/updateMetaData", updateMetaDataRequest{AnonymousID: anonymousID, Label: label, Note: note}, nil)
+}
+
+// UpdateForwardTo 修改所有隐藏邮箱的转发目标，address 须为 List 返回的 ForwardToEmails 之一
+func (c *Client) UpdateForwardTo(ctx context.Context, address string) error {
+	return c.call(ctx, http.MethodPost, "/v1/hme/reserve", "/v1/hme/updateForwardTo", updateForwardToRequest{ForwardToEmail: address}, nil)
+}
+
+// wait 按 Limiter 等待发送创建请求
+func (c *Client) wait(ctx context.Context) error {
+	if c.Limiter == nil {
+		return nil
+	}
+	return c.Limiter.Wait(ctx)
+}
+
+// endpoint 在 BaseURL 的基础上替换路径并附加账号参数，target 为空时直接使用 BaseURL
+func (c *Client) endpoint(target, replacement string) (string, error) {
+	base := c.BaseURL
+	if target != "" {
+		var err error
+		base, err = ReplaceEndpoint(c.BaseURL, target, replacement)
+		if err != nil {
+			return "", fmt.Errorf("无法构建 %s 接口: %w", path.Base(replacement), err)
+		}
+	}
+	return fmt.Sprintf("%s?clientBuildNumber=%s&clientMasteringNumber=%s&clientId=%s&dsid=%s",
+		base,
+		c.ClientBuildNumber,
+		c.ClientMasteringNumber,
+		c.ClientID,
+		c.DSID,
+	), nil
+}
+
+// errTransport 标记请求未收到响应（连接失败、超时等），用于判断能否安全重试
+var errTransport = errors.New("请求失败")
+
+// call 发送请求并把 result 字段解析到 out（可为 nil），按 Retry 策略重试。
+// 非 200 状态码返回 *StatusError，success 为 false 时返回 *APIError；被限流时二者包装在 *RateLimitError 中，
+// 可用 errors.Is 按类别（ErrRateLimited、ErrSessionExpired 等）判断
+func (c *Client) call(ctx context.Context, method, target, replacement string, body, out any) error {
+	endpoint, err := c.endpoint(target, replacement)
+	if err != nil {
+		return err
+	}
+
+	var jsonData []byte
+	if body != nil {
+		jsonData, err = json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("无法序列化请求体: %w", err)
+		}
+	}
+
+	// 只有 reserve（target 为空，直接请求 BaseURL）重复发送可能重复创建邮箱
+	idempotent := target != ""
+	refreshed := false
+	for attempt := 0; ; attempt++ {
+		err = c.do(ctx, method, endpoint, jsonData, body != nil, out)
+		// 会话过期的请求不会被处理，刷新 Cookie 后重发是安全的（包括 reserve），且不计入重试次数
+		var status *StatusError
+		if !refreshed && c.RefreshSession != nil && errors.As(err, &status) && status.SessionExpired() {
+			refreshed = true
+			cookie, refreshErr := c.RefreshSession(ctx)
+			if refreshErr != nil {
+				return err
+			}
+			c = c.withCookie(cookie)
+			attempt--
+			continue
+		}
+		if err == nil || c.Retry == nil || attempt >= c.Retry.MaxRetries || !c.Retry.retryable(err, idempotent) {
+			return err
+		}
+		wait := c.Retry.delay(attempt+1, err)
+		if c.Retry.OnRetry != nil {
+			c.Retry.OnRetry(attempt+1, wait, err)
+		}
+		if sleepErr := sleepContext(ctx, wait); sleepErr != nil {
+			return err
+		}
+	}
+}
+
+// withCookie 复制客户端并替换 Cookie 请求头，不修改调用方共享的 Headers
+func (c *Client) withCookie(cookie string) *Client {
+	copied := *c
+	copied.Headers = make(map[string]string, len(c.Headers)+1)
+	for key, value := range c.Headers {
+		if !strings.EqualFold(key, "Cookie") {
+			copied.Headers[key] = value
+		}
+	}
+	copied.Headers["Cookie"] = cookie
+	return &copied
+}
+
+// do 发送一次请求
+func (c *Client) do(ctx context.Context, method, endpoint string, jsonData []byte, hasBody bool, out any) error {
+	var reader io.Reader
+	if hasBody {
+		reader = bytes.NewReader(jsonData)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, endpoint, reader)
+	if err != nil {
+		return fmt.Errorf("无法创建请求: %w", err)
+	}
+	for key, value := range c.Headers {
+		req.Header.Set(key, value)
+	}
+	if hasBody && req.Header.Get("Content-Type") == "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.PrepareRequest != nil {
+		c.PrepareRequest(req)
+	}
+
+	httpClient := c.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("%w: %w", errTransport, err)
+	}
+
+	data, err := ReadResponseBody(resp)
+	if err != nil {
+		return err
+	}
+	raw := strings.TrimSpace(string(data))
+
+	if resp.StatusCode != http.StatusOK {
+		return typedError(&StatusError{StatusCode: resp.StatusCode, Body: raw, RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After"))})
+	}
+
+	var envelope response
+	if err := json.Unmarshal(data, &envelope); err != nil {
+		return fmt.Errorf("无法解析响应: %v, 原始响应: %s", err, raw)
+	}
+	if !envelope.Success {
+		if envelope.Error != nil {
+			return typedError(envelope.Error)
+		}
+		return fmt.Errorf("API返回失败: %s", raw)
+	}
+	if out != nil && len(envelope.Result) > 0 {
+		if err := json.Unmarshal(envelope.Result, out); err != nil {
+			return fmt.Errorf("无法解析响应: %v, 原始响应: %s", err, raw)
+		}
+	}
+	return nil
+}
+
+// ReplaceEndpoint 把 baseURL 路径中的 target 片段替换为 replacement，用于由 reserve 接口推导其他接口
+func ReplaceEndpoint(baseURL, target, replacement string) (string, error) {
+	if baseURL == "" {
+		return "", fmt.Errorf("基础URL为空，无法构建API端点")
+	}
+
+	parsedURL, err := url.Parse(baseURL)
+	if err != nil {
+		return "", fmt.Errorf("无法解析基础URL %q: %w", baseURL, err)
+	}
+
+	normalizePath := func(p string) string {
+		if p == "" {
+			return ""
+		}
+		if !strings.HasPrefix(p, "/") {
+			p = "/" + p
+		}
+		cleaned := path.Clean(p)
+		if cleaned == "." {
+			return ""
+		}
+		return cleaned
+	}
+
+	currentPath := parsedURL.Path
+	if currentPath == "" {
+		currentPath = "/"
+	}
+	currentPath = path.Clean(currentPath)
+	if !strings.HasPrefix(currentPath, "/") {
+		currentPath = "/" + currentPath
+	}
+
+	targetPath := normalizePath(target)
+	if targetPath == "" {
+		return "", fmt.Errorf("目标路径为空，无法构建API端点")
+	}
+	replacementPath := normalizePath(replacement)
+	if replacementPath == "" {
+		return "", fmt.Errorf("替换路径为空，无法构建API端点")
+	}
+
+	updatedPath := strings.Replace(currentPath, targetPath, replacementPath, 1)
+	if updatedPath == currentPath {
+		return "", fmt.Errorf("基础URL %q 未包含期望的路径片段 %q", baseURL, targetPath)
+	}
+
+	parsedURL.Path = updatedPath
+	return parsedURL.String(), nil
+}
+
+// ReadResponseBody 读取并关闭响应体，自动解压 gzip
+func ReadResponseBody(resp *http.Response) ([]byte, error) {
+	defer resp.Body.Close()
+
+	var reader io.Reader = resp.Body
+	if strings.Contains(resp.Header.Get("Content-Encoding"), "gzip") {
+		gzipReader, err := gzip.NewReader(resp.Body)
+		if err != nil {
+			return nil, fmt.Errorf("无法创建 gzip reader: %w", err)
+		}
+		defer gzipReader.Close()
+		reader = gzipReader
+	}
+
+	body, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, fmt.Errorf("无法读取响应: %w", err)
+	}
+
+	return body, nil
+}
@@ -0,0 +1,219 @@
+package hme
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// countingLimiter 记录 Wait 的调用次数，err 不为空时拒绝发送
+type countingLimiter struct {
+	calls atomic.Int32
+	err   error
+}
+
+func (l *countingLimiter) Wait(ctx context.Context) error {
+	l.calls.Add(1)
+	return l.err
+}
+
+// newTestClient 指向测试服务器的客户端
+func newTestClient(server *httptest.Server) *Client {
+	return &Client{
+		BaseURL:  server.URL + "/v1/hme/reserve",
+		ClientID: "client",
+		DSID:     "12345",
+		Headers:  map[string]string{"Cookie": "X-APPLE-WEBAUTH-TOKEN=old"},
+	}
+}
+
+// writeResult 写入成功的响应
+func writeResult(w http.ResponseWriter, result any) {
+	data, _ := json.Marshal(result)
+	json.NewEncoder(w).Encode(response{Success: true, Result: data})
+}
+
+// TestCreate Generate 与 Reserve 依次请求对应接口，带上账号参数、Cookie 与请求体，并各经过一次 Limiter
+func TestCreate(t *testing.T) {
+	var paths []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.Path)
+		if got := r.URL.Query().Get("dsid"); got != "12345" {
+			t.Errorf("dsid = %q", got)
+		}
+		if got := r.Header.Get("Cookie"); got != "X-APPLE-WEBAUTH-TOKEN=old" {
+			t.Errorf("Cookie = %q", got)
+		}
+		switch r.URL.Path {
+		case "/v1/hme/generate":
+			var body generateRequest
+			json.NewDecoder(r.Body).Decode(&body)
+			if body.LangCode != "zh-cn" {
+				t.Errorf("langCode = %q，期望 zh-cn", body.LangCode)
+			}
+			writeResult(w, generateResult{HME: "abc@icloud.com"})
+		case "/v1/hme/reserve":
+			var body reserveRequest
+			json.NewDecoder(r.Body).Decode(&body)
+			if body.HME != "abc@icloud.com" || body.Label != "shop" {
+				t.Errorf("reserve 请求体 = %+v", body)
+			}
+			writeResult(w, reserveResult{HME: Email{HME: body.HME, Label: body.Label, AnonymousID: "anon-1", IsActive: true}})
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	client := newTestClient(server)
+	client.LangCode = "zh-cn"
+	limiter := &countingLimiter{}
+	client.Limiter = limiter
+	email, err := client.Create(context.Background(), "shop", "")
+	if err != nil {
+		t.Fatalf("创建失败: %v", err)
+	}
+	if email.HME != "abc@icloud.com" || email.AnonymousID != "anon-1" || email.Label != "shop" {
+		t.Errorf("邮箱 = %+v", email)
+	}
+	if len(paths) != 2 || paths[0] != "/v1/hme/generate" || paths[1] != "/v1/hme/reserve" {
+		t.Errorf("请求顺序 = %v", paths)
+	}
+	if calls := limiter.calls.Load(); calls != 2 {
+		t.Errorf("Limiter.Wait 调用了 %d 次，期望 2", calls)
+	}
+
+	// 列表不经过 Limiter；Limiter 拒绝时不发送请求
+	if _, err := client.List(context.Background()); err == nil {
+		t.Error("测试服务器没有列表接口，期望返回错误")
+	}
+	if calls := limiter.calls.Load(); calls != 2 {
+		t.Errorf("List 调用了 Limiter.Wait")
+	}
+	limiter.err = context.Canceled
+	sent := len(paths)
+	if _, err := client.Generate(context.Background(), ""); !errors.Is(err, context.Canceled) {
+		t.Errorf("Limiter 拒绝时的错误 = %v", err)
+	}
+	if len(paths) != sent {
+		t.Error("Limiter 拒绝后仍发送了请求")
+	}
+}
+
+// TestRateLimited 429 与 errorCode -41015 都返回 *RateLimitError，等待时间取自 Retry-After 头或响应中的 retryAfter
+func TestRateLimited(t *testing.T) {
+	tests := []struct {
+		name    string
+		handler http.HandlerFunc
+		wait    time.Duration
+	}{
+		{"429 与 Retry-After", func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Retry-After", "7")
+			w.WriteHeader(http.StatusTooManyRequests)
+		}, 7 * time.Second},
+		{"errorCode -41015", func(w http.ResponseWriter, r *http.Request) {
+			json.NewEncoder(w).Encode(response{Error: &APIError{ErrorCode: "-41015", ErrorMessage: "rate limited", RetryAfter: 30}})
+		}, 30 * time.Second},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(tt.handler)
+			defer server.Close()
+
+			_, err := newTestClient(server).Generate(context.Background(), "")
+			if !errors.Is(err, ErrRateLimited) || ErrorKind(err) != ErrRateLimited {
+				t.Fatalf("错误 = %v，期望 ErrRateLimited", err)
+			}
+			var limited *RateLimitError
+			if !errors.As(err, &limited) || limited.RetryAfter != tt.wait {
+				t.Fatalf("RateLimitError = %+v，期望等待 %s", limited, tt.wait)
+			}
+		})
+	}
+}
+
+// TestRetryAfterDelay 重试等待不少于响应给出的 Retry-After
+func TestRetryAfterDelay(t *testing.T) {
+	policy := &RetryPolicy{BaseDelay: time.Millisecond}
+	if wait := policy.delay(1, &StatusError{StatusCode: http.StatusServiceUnavailable, RetryAfter: 5 * time.Second}); wait != 5*time.Second {
+		t.Errorf("等待 %s，期望 5s", wait)
+	}
+	if wait := policy.delay(3, &StatusError{StatusCode: http.StatusServiceUnavailable}); wait != 4*time.Millisecond {
+		t.Errorf("第 3 次重试等待 %s，期望 4ms", wait)
+	}
+}
+
+// TestSessionExpired 会话过期的状态码归为 ErrSessionExpired；设置了 RefreshSession 时刷新一次 Cookie 后重发
+func TestSessionExpired(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Cookie") != "X-APPLE-WEBAUTH-TOKEN=new" {
+			w.WriteHeader(http.StatusMisdirectedRequest)
+			return
+		}
+		writeResult(w, List{Emails: []Email{{HME: "a@icloud.com"}}})
+	}))
+	defer server.Close()
+
+	client := newTestClient(server)
+	_, err := client.List(context.Background())
+	if !errors.Is(err, ErrSessionExpired) || ErrorKind(err) != ErrSessionExpired {
+		t.Fatalf("错误 = %v，期望 ErrSessionExpired", err)
+	}
+	var status *StatusError
+	if !errors.As(err, &status) || status.StatusCode != http.StatusMisdirectedRequest {
+		t.Fatalf("StatusError = %+v", status)
+	}
+
+	refreshes := 0
+	client.RefreshSession = func(ctx context.Context) (string, error) {
+		refreshes++
+		return "X-APPLE-WEBAUTH-TOKEN=new", nil
+	}
+	list, err := client.List(context.Background())
+	if err != nil || len(list.Emails) != 1 {
+		t.Fatalf("刷新后列表 = %+v，错误 %v", list, err)
+	}
+	if refreshes != 1 {
+		t.Errorf("RefreshSession 调用了 %d 次，期望 1", refreshes)
+	}
+	if client.Headers["Cookie"] != "X-APPLE-WEBAUTH-TOKEN=old" {
+		t.Error("刷新会话修改了调用方的 Headers")
+	}
+}
+
+// TestReserveNotRetried 请求已发出但没有收到响应时，Reserve 不重发（可能已经创建），可重复的 List 按策略重试
+func TestReserveNotRetried(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		// 读完请求后直接断开连接，不返回响应
+		conn, _, err := w.(http.Hijacker).Hijack()
+		if err == nil {
+			conn.Close()
+		}
+	}))
+	defer server.Close()
+
+	client := newTestClient(server)
+	client.HTTPClient = &http.Client{Transport: &http.Transport{DisableKeepAlives: true}}
+	client.Retry = &RetryPolicy{MaxRetries: 2, BaseDelay: time.Millisecond}
+
+	if _, err := client.Reserve(context.Background(), "abc@icloud.com", "shop", ""); err == nil {
+		t.Fatal("期望返回错误")
+	}
+	if got := requests.Swap(0); got != 1 {
+		t.Errorf("Reserve 发送了 %d 次，期望 1", got)
+	}
+
+	if _, err := client.List(context.Background()); err == nil {
+		t.Fatal("期望返回错误")
+	}
+	if got := requests.Load(); got != 3 {
+		t.Errorf("List 发送了 %d 次，期望 3（重试 2 次）", got)
+	}
+}
@@ -0,0 +1,182 @@
+package hme
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 按 Apple errorCode 与 HTTP 状态码归类的错误，调用方用 errors.Is 判断，如 errors.Is(err, hme.ErrSessionExpired)；
+// *APIError、*StatusError 与 *RateLimitError 都支持这种判断，原始的 errorCode 与状态码仍可用 errors.As 取得
+var (
+	ErrRateLimited    = errors.New("创建过于频繁，已被 iCloud 限流")
+	ErrSessionExpired = errors.New("iCloud 会话已过期")
+	ErrHMEUnavailable = errors.New("该账号无法使用隐藏邮件地址")
+	ErrAliasNotFound  = errors.New("找不到该隐藏邮件地址")
+)
+
+// errorCodeKinds errorCode 对应的错误类别。只收录实际观察到、含义确定的 errorCode，
+// 其他 errorCode 按 *APIError 原样返回，由调用方根据 ErrorMessage 处理
+var errorCodeKinds = map[string]error{
+	"-41015": ErrRateLimited,    // 创建过于频繁，通常带有 retryAfter
+	"-41003": ErrHMEUnavailable, // 账号未订阅 iCloud+ 或未启用隐藏邮件地址
+	"-41020": ErrAliasNotFound,  // anonymousId 不存在（已在其他设备上删除）
+}
+
+// ErrorKind 错误所属的类别（上面的 Err* 之一），无法归类时返回 nil
+func ErrorKind(err error) error {
+	for _, kind := range []error{ErrRateLimited, ErrSessionExpired, ErrHMEUnavailable, ErrAliasNotFound} {
+		if errors.Is(err, kind) {
+			return kind
+		}
+	}
+	return nil
+}
+
+// APIError iCloud 接口在响应体中返回的错误（success 为 false）
+type APIError struct {
+	ErrorCode    string `json:"errorCode"`
+	ErrorMessage string `json:"errorMessage"`
+	RetryAfter   int    `json:"retryAfter"` // 被限流（-41015）时需要等待的秒数
+}
+
+func (e *APIError) Error() string {
+	if e.ErrorCode == "" {
+		return fmt.Sprintf("API错误: %s", e.ErrorMessage)
+	}
+	if kind := errorCodeKinds[e.ErrorCode]; kind != nil {
+		return fmt.Sprintf("%v (%s: %s)", kind, e.ErrorCode, e.ErrorMessage)
+	}
+	return fmt.Sprintf("API错误 (%s): %s", e.ErrorCode, e.ErrorMessage)
+}
+
+// Is 按 errorCode 匹配错误类别
+func (e *APIError) Is(target error) bool {
+	kind, ok := errorCodeKinds[e.ErrorCode]
+	return ok && kind == target
+}
+
+// StatusError iCloud 接口返回了非 200 状态码
+type StatusError struct {
+	StatusCode int
+	Body       string
+	RetryAfter time.Duration // 来自 Retry-After 响应头，未提供时为 0
+}
+
+func (e *StatusError) Error() string {
+	switch {
+	case e.SessionExpired():
+		return fmt.Sprintf("%v (状态码: %d)", ErrSessionExpired, e.StatusCode)
+	case e.StatusCode == http.StatusTooManyRequests:
+		return fmt.Sprintf("%v (状态码: %d)", ErrRateLimited, e.StatusCode)
+	}
+	if apiErr := e.APIError(); apiErr != nil {
+		return fmt.Sprintf("%v (状态码: %d)", apiErr, e.StatusCode)
+	}
+	return fmt.Sprintf("服务器返回错误 (状态码: %d, 响应: %s)", e.StatusCode, bodyExcerpt(e.Body))
+}
+
+// Is 会话过期的状态码匹配 ErrSessionExpired，429 匹配 ErrRateLimited；响应体中带有 errorCode 时按 errorCode 匹配
+func (e *StatusError) Is(target error) bool {
+	switch {
+	case e.SessionExpired():
+		return target == ErrSessionExpired
+	case e.StatusCode == http.StatusTooManyRequests && target == ErrRateLimited:
+		return true
+	}
+	if apiErr := e.APIError(); apiErr != nil {
+		return apiErr.Is(target)
+	}
+	return false
+}
+
+// APIError 响应体中的 iCloud 错误（部分非 200 响应也带有 errorCode），没有时返回 nil
+func (e *StatusError) APIError() *APIError {
+	var envelope response
+	if json.Unmarshal([]byte(e.Body), &envelope) != nil || envelope.Error == nil || envelope.Error.ErrorCode == "" {
+		return nil
+	}
+	return envelope.Error
+}
+
+// bodyExcerpt 错误信息中的响应体：过长时截断，HTML 页面只说明类型，不把整页内容打印出来
+func bodyExcerpt(body string) string {
+	const limit = 200
+	if strings.HasPrefix(strings.ToLower(strings.TrimSpace(body)), "<") {
+		return "HTML 页面"
+	}
+	if runes := []rune(body); len(runes) > limit {
+		return string(runes[:limit]) + "…"
+	}
+	return body
+}
+
+// RateLimitError 被限流（errorCode -41015 或 HTTP 429），RetryAfter 为 Apple 给出的等待时间，未给出时为 0。
+// 它包装原始的 *APIError 或 *StatusError，errors.Is(err, ErrRateLimited) 为 true
+type RateLimitError struct {
+	RetryAfter time.Duration
+	Err        error
+}
+
+func (e *RateLimitError) Error() string {
+	if e.RetryAfter > 0 {
+		return fmt.Sprintf("%v，需等待 %s", e.Err, e.RetryAfter.Round(time.Second))
+	}
+	return e.Err.Error()
+}
+
+func (e *RateLimitError) Unwrap() error {
+	return e.Err
+}
+
+// Is 匹配 ErrRateLimited
+func (e *RateLimitError) Is(target error) bool {
+	return target == ErrRateLimited
+}
+
+// typedError 把被限流的响应包装为 *RateLimitError，其他错误原样返回
+func typedError(err error) error {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) && apiErr.Is(ErrRateLimited) {
+		return &RateLimitError{RetryAfter: time.Duration(apiErr.RetryAfter) * time.Second, Err: err}
+	}
+	var status *StatusError
+	if errors.As(err, &status) && status.Is(ErrRateLimited) {
+		wait := status.RetryAfter
+		if apiErr := status.APIError(); apiErr != nil && wait == 0 {
+			wait = time.Duration(apiErr.RetryAfter) * time.Second
+		}
+		return &RateLimitError{RetryAfter: wait, Err: err}
+	}
+	return err
+}
+
+// SessionExpiredStatus 状态码是否表示 Cookie 或会话已失效：401 未认证、421 会话与服务器不匹配（会话过期时最常见）、450 需要重新登录
+func SessionExpiredStatus(code int) bool {
+	return code == http.StatusUnauthorized || code == http.StatusMisdirectedRequest || code == 450
+}
+
+// SessionExpired 是否因 Cookie 或会话失效而失败
+func (e *StatusError) SessionExpired() bool {
+	return SessionExpiredStatus(e.StatusCode)
+}
+
+// parseRetryAfter 解析 HTTP Retry-After 头（秒数或 HTTP 日期）
+func parseRetryAfter(value string) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if at, err := http.ParseTime(value); err == nil {
+		if wait := time.Until(at); wait > 0 {
+			return wait
+		}
+	}
+	return 0
+}
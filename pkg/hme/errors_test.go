@@ -0,0 +1,54 @@
+package hme
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// TestErrorKind errorCode 与状态码归入对应的错误类别，未收录的 errorCode 不归类
+func TestErrorKind(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want error
+	}{
+		{"限流", &APIError{ErrorCode: "-41015"}, ErrRateLimited},
+		{"无法使用隐藏邮件地址", &APIError{ErrorCode: "-41003"}, ErrHMEUnavailable},
+		{"邮箱不存在", &APIError{ErrorCode: "-41020"}, ErrAliasNotFound},
+		{"未收录的 errorCode", &APIError{ErrorCode: "-40000"}, nil},
+		{"状态码 401", &StatusError{StatusCode: http.StatusUnauthorized}, ErrSessionExpired},
+		{"状态码 429", &StatusError{StatusCode: http.StatusTooManyRequests}, ErrRateLimited},
+		{"状态码 400 带 errorCode", &StatusError{StatusCode: http.StatusBadRequest, Body: `{"success":false,"error":{"errorCode":"-41020"}}`}, ErrAliasNotFound},
+		{"状态码 500", &StatusError{StatusCode: http.StatusInternalServerError}, nil},
+		{"被包装的错误", fmt.Errorf("停用失败: %w", &APIError{ErrorCode: "-41003"}), ErrHMEUnavailable},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ErrorKind(tt.err); got != tt.want {
+				t.Fatalf("ErrorKind = %v，期望 %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestAliasNotFoundFromAPI 接口返回 -41020 时可以用 errors.Is 判断，并用 errors.As 取得原始的 errorCode
+func TestAliasNotFoundFromAPI(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(response{Error: &APIError{ErrorCode: "-41020", ErrorMessage: "alias not found"}})
+	}))
+	defer server.Close()
+
+	err := newTestClient(server).Deactivate(context.Background(), "missing")
+	if !errors.Is(err, ErrAliasNotFound) || errors.Is(err, ErrHMEUnavailable) {
+		t.Fatalf("错误 = %v，期望 ErrAliasNotFound", err)
+	}
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.ErrorCode != "-41020" || apiErr.ErrorMessage != "alias not found" {
+		t.Fatalf("APIError = %+v", apiErr)
+	}
+}
@@ -0,0 +1,81 @@
+package hme
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"net"
+	"slices"
+	"time"
+)
+
+// RetryPolicy 网络错误与临时性状态码（如 502、503）的重试策略，nil 或 MaxRetries 为 0 时不重试。
+// 创建邮箱的 Reserve 请求发出后可能已被 Apple 处理，因此只在连接未建立（拨号、DNS 失败）
+// 或收到可重试的状态码时重发，读取响应超时等情况不重发
+type RetryPolicy struct {
+	MaxRetries  int           // 最多重试的次数
+	BaseDelay   time.Duration // 第一次重试前的等待，之后每次翻倍
+	MaxDelay    time.Duration // 单次等待的上限，0 表示不限
+	Jitter      float64       // 每次等待随机增减的比例（0~1）
+	StatusCodes []int         // 可重试的 HTTP 状态码
+
+	// OnRetry 每次重试等待前调用，attempt 从 1 开始，可用于记录日志或统计
+	OnRetry func(attempt int, wait time.Duration, err error)
+}
+
+// retryable 判断本次失败是否应当重试；idempotent 为 false 时网络错误不重试
+func (p *RetryPolicy) retryable(err error, idempotent bool) bool {
+	var status *StatusError
+	if errors.As(err, &status) {
+		return slices.Contains(p.StatusCodes, status.StatusCode)
+	}
+	var apiErr *APIError
+	if errors.As(err, &apiErr) || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	if !errors.Is(err, errTransport) {
+		return false
+	}
+	return idempotent || notSent(err)
+}
+
+// notSent 请求是否确定没有发出：拨号失败或域名解析失败
+func notSent(err error) bool {
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		return true
+	}
+	var opErr *net.OpError
+	return errors.As(err, &opErr) && opErr.Op == "dial"
+}
+
+// delay 第 attempt 次重试（从 1 开始）前的等待；响应给出 Retry-After 时不少于该值
+func (p *RetryPolicy) delay(attempt int, err error) time.Duration {
+	wait := p.BaseDelay << (attempt - 1)
+	if p.MaxDelay > 0 && (wait > p.MaxDelay || wait <= 0) {
+		wait = p.MaxDelay
+	}
+	if p.Jitter > 0 && wait > 0 {
+		wait += time.Duration((rand.Float64()*2 - 1) * p.Jitter * float64(wait))
+	}
+	var status *StatusError
+	if errors.As(err, &status) && status.RetryAfter > wait {
+		wait = status.RetryAfter
+	}
+	return wait
+}
+
+// sleepContext 等待 d，ctx 取消时提前返回其错误
+func sleepContext(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,131 @@
+package hme
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+// DefaultValidateURL icloud.com 网页版定期调用的会话校验接口，响应中的 Set-Cookie 带有轮换后的会话令牌
+const DefaultValidateURL = "https://setup.icloud.com/setup/ws/1/validate"
+
+// Validate 调用会话校验接口刷新会话，返回响应中设置的 Cookie；validateURL 为空时使用 DefaultValidateURL。
+// 会话已经失效时返回 SessionExpired 的 *StatusError，此时只能重新登录
+func (c *Client) Validate(ctx context.Context, validateURL string) ([]*http.Cookie, error) {
+	if validateURL == "" {
+		validateURL = DefaultValidateURL
+	}
+	query := url.Values{}
+	query.Set("clientBuildNumber", c.ClientBuildNumber)
+	query.Set("clientMasteringNumber", c.ClientMasteringNumber)
+	query.Set("clientId", c.ClientID)
+	query.Set("dsid", c.DSID)
+	sep := "?"
+	if strings.Contains(validateURL, "?") {
+		sep = "&"
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, validateURL+sep+query.Encode(), strings.NewReader("null"))
+	if err != nil {
+		return nil, fmt.Errorf("无法创建请求: %w", err)
+	}
+	for key, value := range c.Headers {
+		req.Header.Set(key, value)
+	}
+	req.Header.Set("Content-Type", "text/plain")
+	if c.PrepareRequest != nil {
+		c.PrepareRequest(req)
+	}
+
+	httpClient := c.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", errTransport, err)
+	}
+	data, err := ReadResponseBody(resp)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, &StatusError{StatusCode: resp.StatusCode, Body: strings.TrimSpace(string(data)), RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After"))}
+	}
+	return resp.Cookies(), nil
+}
+
+// MergeCookies 把 Set-Cookie 合并进 Cookie 请求头：同名的替换，新的追加，已过期或被清空的删除。
+// 返回合并后的请求头与发生变化的 Cookie 名称（按名称排列）
+func MergeCookies(header string, updates []*http.Cookie) (string, []string) {
+	type pair struct{ name, value string }
+	var pairs []pair
+	index := make(map[string]int)
+	for _, part := range strings.Split(header, ";") {
+		name, value, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok || name == "" {
+			continue
+		}
+		index[name] = len(pairs)
+		pairs = append(pairs, pair{name, value})
+	}
+
+	now := time.Now()
+	removed := make(map[string]bool)
+	changedSet := make(map[string]bool)
+	for _, cookie := range updates {
+		expired := cookie.MaxAge < 0 || (!cookie.Expires.IsZero() && cookie.Expires.Before(now)) || cookie.Value == ""
+		i, exists := index[cookie.Name]
+		switch {
+		case expired && exists && !removed[cookie.Name]:
+			removed[cookie.Name] = true
+			changedSet[cookie.Name] = true
+		case expired:
+		case exists:
+			if pairs[i].value != cookie.Value || removed[cookie.Name] {
+				pairs[i].value = cookie.Value
+				delete(removed, cookie.Name)
+				changedSet[cookie.Name] = true
+			}
+		default:
+			index[cookie.Name] = len(pairs)
+			pairs = append(pairs, pair{cookie.Name, cookie.Value})
+			changedSet[cookie.Name] = true
+		}
+	}
+
+	var parts []string
+	for _, p := range pairs {
+		if !removed[p.name] {
+			parts = append(parts, p.name+"="+p.value)
+		}
+	}
+	changed := make([]string, 0, len(changedSet))
+	for name := range changedSet {
+		changed = append(changed, name)
+	}
+	sort.Strings(changed)
+	return strings.Join(parts, "; "), changed
+}
+
+// EarliestExpiry 会话令牌（X-APPLE-WEBAUTH-* 与 X-APPLE-DS-WEB-SESSION-TOKEN）中最早的过期时间，未给出时返回零值
+func EarliestExpiry(cookies []*http.Cookie) time.Time {
+	var earliest time.Time
+	for _, cookie := range cookies {
+		if !strings.HasPrefix(cookie.Name, "X-APPLE-WEBAUTH-") && cookie.Name != "X-APPLE-DS-WEB-SESSION-TOKEN" {
+			continue
+		}
+		expires := cookie.Expires
+		if cookie.MaxAge > 0 {
+			expires = time.Now().Add(time.Duration(cookie.MaxAge) * time.Second)
+		}
+		if !expires.IsZero() && (earliest.IsZero() || expires.Before(earliest)) {
+			earliest = expires
+		}
+	}
+	return earliest
+}
@@ -0,0 +1,71 @@
+package hme
+
+import "encoding/json"
+
+// Email 邮箱详细信息
+type Email struct {
+	Origin          string `json:"origin"`
+	AnonymousID     string `json:"anonymousId"`
+	Domain          string `json:"domain"`
+	HME             string `json:"hme"`
+	Label           string `json:"label"`
+	Note            string `json:"note"`
+	CreateTimestamp int64  `json:"createTimestamp"`
+	IsActive        bool   `json:"isActive"`
+	RecipientMailID string `json:"recipientMailId"`
+	ForwardToEmail  string `json:"forwardToEmail,omitempty"`
+}
+
+// List 邮箱列表及可选的转发目标
+type List struct {
+	ForwardToEmails   []string `json:"forwardToEmails"`
+	Emails            []Email  `json:"hmeEmails"`
+	SelectedForwardTo string   `json:"selectedForwardTo"`
+}
+
+// response 所有接口共用的响应外层
+type response struct {
+	Success   bool            `json:"success"`
+	Timestamp int64           `json:"timestamp"`
+	Result    json.RawMessage `json:"result"`
+	Error     *APIError       `json:"error,omitempty"`
+}
+
+// generateRequest 生成邮箱地址请求体
+type generateRequest struct {
+	LangCode string `json:"langCode"`
+}
+
+// generateResult 生成邮箱地址的结果
+type generateResult struct {
+	HME string `json:"hme"`
+}
+
+// reserveRequest 确认创建邮箱请求体
+type reserveRequest struct {
+	HME   string `json:"hme"`   // 必填：第一步生成的邮箱地址
+	Label string `json:"label"` // 必填：邮箱标签/描述
+	Note  string `json:"note"`  // 可选：备注
+}
+
+// reserveResult 确认创建的结果
+type reserveResult struct {
+	HME Email `json:"hme"`
+}
+
+// anonymousIDRequest 停用、重新激活、彻底删除的请求体
+type anonymousIDRequest struct {
+	AnonymousID string `json:"anonymousId"`
+}
+
+// updateMetaDataRequest 更新邮箱标签/备注请求
+type updateMetaDataRequest struct {
+	AnonymousID string `json:"anonymousId"`
+	Label       string `json:"label"`
+	Note        string `json:"note"`
+}
+
+// updateForwardToRequest 修改转发目标请求体
+type updateForwardToRequest struct {
+	ForwardToEmail string `json:"forwardToEmail"`
+}
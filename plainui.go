@@ -0,0 +1,57 @@
+package main
+
+import (
+	"fmt"
+	"os"
+)
+
+// plainUI 纯文本界面：无颜色、无动画（加载动画、进度条与倒计时不再原地刷新）、固定 80 列宽度、
+// 不清屏，适合通过 SSH 在低性能 NAS/路由器上运行或记录到日志
+var plainUI bool
+
+// 纯文本模式下使用的固定宽度
+const plainUIWidth = 80
+
+// plainUIConfigured 在加载完整配置之前读取 plain_ui，使启动信息也以纯文本输出
+func plainUIConfigured(path string) bool {
+	return peekConfig(path).PlainUI
+}
+
+// setupPlainUI 按 --plain、配置中的 plain_ui 或 TERM=dumb 启用纯文本界面；设置 NO_COLOR 时只关闭颜色
+func setupPlainUI() {
+	if plainUI || os.Getenv("TERM") == "dumb" || plainUIConfigured(CONFIG_FILE) {
+		plainUI = true
+	}
+	if plainUI || os.Getenv("NO_COLOR") != "" {
+		disableColors()
+	}
+}
+
+// disableColors 清空所有颜色代码
+func disableColors() {
+	for _, color := range []*string{
+		&ColorReset, &ColorBold, &ColorDim,
+		&ColorRed, &ColorGreen, &ColorYellow, &ColorBlue, &ColorMagenta, &ColorCyan, &ColorWhite,
+		&ColorBrightRed, &ColorBrightGreen, &ColorBrightYellow, &ColorBrightBlue, &ColorBrightMagenta, &ColorBrightCyan, &ColorBrightWhite,
+		&ColorGray, &ColorLightGray,
+		&BgRed, &BgGreen, &BgYellow, &BgBlue, &BgMagenta, &BgCyan,
+	} {
+		*color = ""
+	}
+}
+
+// runPlain 纯文本模式下代替加载动画：先输出提示，完成后在同一行补上结果
+func runPlain(message string, action func() error) (err error) {
+	fmt.Printf("  %s ... ", message)
+	defer func() {
+		if r := recover(); r != nil {
+			err = recoveredPanic(r)
+		}
+		if err != nil {
+			fmt.Println("失败")
+		} else {
+			fmt.Println("完成")
+		}
+	}()
+	return action()
+}
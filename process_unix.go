@@ -0,0 +1,45 @@
+//go:build unix
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// processAlive 检查本机进程是否存在
+func processAlive(pid int) bool {
+	if pid <= 0 {
+		return false
+	}
+	if err := syscall.Kill(pid, 0); err != nil && !errors.Is(err, syscall.EPERM) {
+		return false
+	}
+	// 容器中没有 init 回收时，已退出的进程会以僵尸状态残留
+	if data, err := os.ReadFile(fmt.Sprintf("/proc/%d/stat", pid)); err == nil {
+		if end := strings.LastIndexByte(string(data), ')'); end >= 0 && end+2 < len(data) && data[end+2] == 'Z' {
+			return false
+		}
+	}
+	return true
+}
+
+// terminalWidth 标准输入所在终端的列数
+func terminalWidth() (int, bool) {
+	ws, err := unix.IoctlGetWinsize(int(os.Stdin.Fd()), unix.TIOCGWINSZ)
+	if err != nil {
+		return 0, false
+	}
+	return int(ws.Col), true
+}
+
+// isTerminal 文件是否为终端（/dev/null 等字符设备不算）
+func isTerminal(f *os.File) bool {
+	_, err := unix.IoctlGetWinsize(int(f.Fd()), unix.TIOCGWINSZ)
+	return err == nil
+}
@@ -0,0 +1,267 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// 不使用任何账号配置，直接使用顶层字段
+const defaultProfileName = "default"
+
+// profileOverride 由 --profile 或菜单切换指定的账号，优先于配置中的 active_profile
+var profileOverride string
+
+// AccountProfile 一个 Apple ID 的账号配置，未填写的字段沿用顶层配置
+type AccountProfile struct {
+	BaseURL               string `json:"base_url,omitempty"`
+	ClientBuildNumber     string `json:"client_build_number,omitempty"`
+	ClientMasteringNumber string `json:"client_mastering_number,omitempty"`
+	ClientID              string `json:"client_id,omitempty"`
+	DSID                  string `json:"dsid,omitempty"`
+
+	// 与顶层 headers 合并，通常只需填写 Cookie
+	Headers map[string]string `json:"headers,omitempty"`
+
+	// 该账号使用的代理，留空时沿用顶层 proxy_url（direct 表示该账号直连），可让各 Apple ID 经不同出口访问
+	ProxyURL string `json:"proxy_url,omitempty"`
+
+	// 本地清单、批量断点与重试队列按账号分开，未填写时在顶层文件名后加上账号名，如 hme_inventory.work.db
+	InventoryFile  string `json:"inventory_file,omitempty"`
+	BatchJobFile   string `json:"batch_job_file,omitempty"`
+	RetryQueueFile string `json:"retry_queue_file,omitempty"`
+}
+
+// accountFields 取出配置中与账号相关的字段
+func accountFields(config *Config) AccountProfile {
+	headers := make(map[string]string, len(config.Headers))
+	for key, value := range config.Headers {
+		headers[key] = value
+	}
+	return AccountProfile{
+		BaseURL:               config.BaseURL,
+		ClientBuildNumber:     config.ClientBuildNumber,
+		ClientMasteringNumber: config.ClientMasteringNumber,
+		ClientID:              config.ClientID,
+		DSID:                  config.DSID,
+		Headers:               headers,
+		ProxyURL:              config.ProxyURL,
+		InventoryFile:         config.InventoryFile,
+		BatchJobFile:          config.BatchJobFile,
+		RetryQueueFile:        config.RetryQueueFile,
+	}
+}
+
+// setAccountFields 把账号字段写回配置
+func setAccountFields(config *Config, account AccountProfile) {
+	config.BaseURL = account.BaseURL
+	config.ClientBuildNumber = account.ClientBuildNumber
+	config.ClientMasteringNumber = account.ClientMasteringNumber
+	config.ClientID = account.ClientID
+	config.DSID = account.DSID
+	config.Headers = account.Headers
+	config.ProxyURL = account.ProxyURL
+	config.InventoryFile = account.InventoryFile
+	config.BatchJobFile = account.BatchJobFile
+	config.RetryQueueFile = account.RetryQueueFile
+}
+
+// profileFileName 在文件名的扩展名前插入账号名
+func profileFileName(path, profile string) string {
+	if path == "" {
+		return ""
+	}
+	ext := filepath.Ext(path)
+	return strings.TrimSuffix(path, ext) + "." + profile + ext
+}
+
+// profileNames 按名称排序的账号列表
+func (c *Config) profileNames() []string {
+	names := make([]string, 0, len(c.Profiles))
+	for name := range c.Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// selectedProfile 当前应使用的账号名，空字符串表示顶层配置
+func (c *Config) selectedProfile() string {
+	name := profileOverride
+	if name == "" {
+		name = c.ActiveProfile
+	}
+	if name == defaultProfileName {
+		return ""
+	}
+	return strings.TrimSpace(name)
+}
+
+// applyProfile 用选中账号的字段覆盖顶层配置，并记住原值以便保存时还原
+func applyProfile(config *Config) error {
+	name := config.selectedProfile()
+	if name == "" {
+		return nil
+	}
+	profile, ok := config.Profiles[name]
+	if !ok || profile == nil {
+		return fmt.Errorf("未找到账号配置 %q（可选: %s）", name, strings.Join(append([]string{defaultProfileName}, config.profileNames()...), "、"))
+	}
+
+	base := accountFields(config)
+	account := accountFields(config)
+	overlay := func(target *string, value string) {
+		if value != "" {
+			*target = value
+		}
+	}
+	overlay(&account.BaseURL, profile.BaseURL)
+	overlay(&account.ClientBuildNumber, profile.ClientBuildNumber)
+	overlay(&account.ClientMasteringNumber, profile.ClientMasteringNumber)
+	overlay(&account.ClientID, profile.ClientID)
+	overlay(&account.DSID, profile.DSID)
+	for key, value := range profile.Headers {
+		account.Headers[key] = value
+	}
+	overlay(&account.ProxyURL, profile.ProxyURL)
+	account.InventoryFile = profileFileName(base.InventoryFile, name)
+	overlay(&account.InventoryFile, profile.InventoryFile)
+	account.BatchJobFile = profileFileName(base.BatchJobFile, name)
+	overlay(&account.BatchJobFile, profile.BatchJobFile)
+	account.RetryQueueFile = profileFileName(base.RetryQueueFile, name)
+	overlay(&account.RetryQueueFile, profile.RetryQueueFile)
+
+	setAccountFields(config, account)
+	config.profile = name
+	config.profileBase = &base
+	return nil
+}
+
+// marshalConfigForSave 序列化要保存的配置。使用账号配置时，顶层账号字段还原为原值，
+// 运行中修改的账号字段（如在设置中更新 Cookie）写入当前账号
+func marshalConfigForSave(config *Config) ([]byte, error) {
+	if config.profile == "" || config.profileBase == nil {
+		return json.MarshalIndent(config, "", "  ")
+	}
+
+	data, err := json.Marshal(config)
+	if err != nil {
+		return nil, err
+	}
+	var out Config
+	if err := json.Unmarshal(data, &out); err != nil {
+		return nil, err
+	}
+
+	base := *config.profileBase
+	current := accountFields(config)
+	profile := &AccountProfile{}
+	if existing := out.Profiles[config.profile]; existing != nil {
+		profile = existing
+	}
+	update := func(target *string, currentValue, baseValue string) {
+		if currentValue != baseValue || *target != "" {
+			*target = currentValue
+		}
+	}
+	update(&profile.BaseURL, current.BaseURL, base.BaseURL)
+	update(&profile.ClientBuildNumber, current.ClientBuildNumber, base.ClientBuildNumber)
+	update(&profile.ClientMasteringNumber, current.ClientMasteringNumber, base.ClientMasteringNumber)
+	update(&profile.ClientID, current.ClientID, base.ClientID)
+	update(&profile.DSID, current.DSID, base.DSID)
+	update(&profile.ProxyURL, current.ProxyURL, base.ProxyURL)
+	for key, value := range current.Headers {
+		if _, ok := profile.Headers[key]; ok || base.Headers[key] != value {
+			if profile.Headers == nil {
+				profile.Headers = make(map[string]string)
+			}
+			profile.Headers[key] = value
+		}
+	}
+	// 自动派生的文件名不写入
+	if current.InventoryFile != profileFileName(base.InventoryFile, config.profile) {
+		profile.InventoryFile = current.InventoryFile
+	}
+	if current.BatchJobFile != profileFileName(base.BatchJobFile, config.profile) {
+		profile.BatchJobFile = current.BatchJobFile
+	}
+
+	if out.Profiles == nil {
+		out.Profiles = make(map[string]*AccountProfile)
+	}
+	out.Profiles[config.profile] = profile
+	setAccountFields(&out, base)
+	return json.MarshalIndent(&out, "", "  ")
+}
+
+// profileLabel 用于显示的当前账号名
+func (c *Config) profileLabel() string {
+	if c.profile == "" {
+		return defaultProfileName
+	}
+	return c.profile
+}
+
+// handleSwitchProfile 菜单中切换账号：重新加载配置、切换账号锁与本地清单
+func handleSwitchProfile(config *Config) {
+	printHeader("切换账号")
+	names := append([]string{defaultProfileName}, config.profileNames()...)
+	for i, name := range names {
+		marker := " "
+		if name == config.profileLabel() {
+			marker = ColorGreen + "●" + ColorReset
+		}
+		dsid := config.DSID
+		if name == defaultProfileName && config.profileBase != nil {
+			dsid = config.profileBase.DSID
+		} else if profile := config.Profiles[name]; profile != nil && profile.DSID != "" {
+			dsid = profile.DSID
+		}
+		fmt.Printf("  %s "+ColorCyan+"[%d]"+ColorReset+" %s "+ColorDim+"(dsid %s)"+ColorReset+"\n", marker, i+1, name, dsid)
+	}
+
+	input := readInput("\n选择账号序号 (回车取消): ")
+	if input == "" {
+		return
+	}
+	var index int
+	if _, err := fmt.Sscanf(input, "%d", &index); err != nil || index < 1 || index > len(names) {
+		printError("无效的序号")
+		return
+	}
+	if err := switchProfile(names[index-1]); err != nil {
+		printError(fmt.Sprintf("切换失败: %v", err))
+		printLockGuidance(err)
+		return
+	}
+	printSuccess(fmt.Sprintf("已切换到账号 %s", names[index-1]))
+}
+
+// switchProfile 切换到指定账号；失败时保持当前账号不变
+func switchProfile(name string) error {
+	previous := profileOverride
+	profileOverride = name
+	newConfig, err := configManager.LoadConfig()
+	if err != nil {
+		profileOverride = previous
+		return err
+	}
+	if err := safetyManager.SwitchAccount(newConfig); err != nil {
+		profileOverride = previous
+		configManager.LoadConfig()
+		return err
+	}
+
+	inv, err := OpenInventory(newConfig.InventoryFile)
+	if err != nil {
+		printWarning(fmt.Sprintf("本地清单不可用: %v", err))
+	}
+	inventory = inv
+
+	configMutex.Lock()
+	globalConfig = newConfig
+	configMutex.Unlock()
+	return nil
+}
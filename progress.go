@@ -0,0 +1,94 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// 机器可读进度输出格式，由全局选项 --progress 设置
+const ProgressNDJSON = "ndjson"
+
+var (
+	progressFormat string
+	progressOutput sync.Mutex
+)
+
+// ProgressEvent 批量操作中的一条进度事件
+type ProgressEvent struct {
+	Event   string  `json:"event"` // item：单项完成；pause：限速等待；done：全部结束
+	Op      string  `json:"op"`    // create、rename
+	Time    string  `json:"time"`
+	Index   int     `json:"index,omitempty"` // 从 1 开始
+	Total   int     `json:"total"`
+	Label   string  `json:"label,omitempty"`
+	Email   string  `json:"email,omitempty"`
+	OK      *bool   `json:"ok,omitempty"`
+	Error   string  `json:"error,omitempty"`
+	Seconds float64 `json:"seconds,omitempty"`
+
+	Succeeded *int `json:"succeeded,omitempty"` // 仅 done 事件
+	Failed    *int `json:"failed,omitempty"`
+}
+
+// setProgressFormat 校验并设置 --progress 的值
+func setProgressFormat(value string) {
+	if value != ProgressNDJSON {
+		printWarning(fmt.Sprintf("不支持的进度格式 %q，目前仅支持 ndjson，已忽略", value))
+		return
+	}
+	progressFormat = value
+}
+
+// emitProgress 启用 --progress ndjson 时向标准错误输出一行 JSON，同时写入批量运行摘要
+func emitProgress(event ProgressEvent) {
+	recordBatchReport(event)
+	logProgress(event)
+	if progressFormat != ProgressNDJSON {
+		return
+	}
+	event.Time = time.Now().Format(time.RFC3339Nano)
+	data, err := json.Marshal(event)
+	if err != nil {
+		return
+	}
+
+	progressOutput.Lock()
+	defer progressOutput.Unlock()
+	os.Stderr.Write(append(data, '\n'))
+}
+
+// logProgress 把批量进度写入日志：每项结果为 debug（创建结果已单独记录），暂停与结束为 info
+func logProgress(event ProgressEvent) {
+	switch event.Event {
+	case "item":
+		logger().Debug("批量操作项", "op", event.Op, "index", event.Index, "total", event.Total, "label", event.Label, "email", event.Email, "ok", *event.OK, "error", event.Error)
+	case "pause":
+		logger().Info("批量操作暂停", "op", event.Op, "seconds", event.Seconds)
+	case "done":
+		logger().Info("批量操作结束", "op", event.Op, "total", event.Total, "succeeded", *event.Succeeded, "failed", *event.Failed)
+	}
+}
+
+// emitProgressItem 输出单项完成事件
+func emitProgressItem(op string, index, total int, label, email string, err error) {
+	writeResult(CLIResult{Op: op, Index: index, Label: label, Email: email}, err)
+	ok := err == nil
+	event := ProgressEvent{Event: "item", Op: op, Index: index, Total: total, Label: label, Email: email, OK: &ok}
+	if err != nil {
+		event.Error = err.Error()
+	}
+	emitProgress(event)
+}
+
+// emitProgressPause 输出限速等待事件
+func emitProgressPause(op string, total int, wait time.Duration) {
+	emitProgress(ProgressEvent{Event: "pause", Op: op, Total: total, Seconds: wait.Seconds()})
+}
+
+// emitProgressDone 输出批量操作结束事件
+func emitProgressDone(op string, total, succeeded, failed int) {
+	emitProgress(ProgressEvent{Event: "done", Op: op, Total: total, Succeeded: &succeeded, Failed: &failed})
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"crypto/rand"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// localDataFiles 列出本工具在本机产生的数据文件（去重，仅包含实际存在的文件）：
+// 本地清单数据库、stateFiles 中的各个状态文件及其备份与残留的临时文件、邮箱列表与导出文件、日志文件及轮转的旧日志、开发者会话录制
+func localDataFiles(config *Config, includeConfig bool) []string {
+	candidates := []string{config.EmailListFile, config.OutputFile}
+	if config.InventoryFile != "" {
+		// 本地清单数据库，以及导入后保留的旧版 JSON 清单
+		legacy := legacyInventoryPath(config.InventoryFile)
+		candidates = append(candidates, inventoryDBPath(config.InventoryFile), legacy, legacy+backupSuffix)
+	}
+	glob := func(pattern string) {
+		if matches, err := filepath.Glob(pattern); err == nil {
+			candidates = append(candidates, matches...)
+		}
+	}
+	for _, state := range stateFiles(config) {
+		candidates = append(candidates, state.Path, state.Path+backupSuffix)
+		// 异常退出时可能残留的临时文件
+		glob(filepath.Join(filepath.Dir(state.Path), state.tmpPattern))
+	}
+	reportDir := "."
+	if config.OutputFile != "" {
+		reportDir = filepath.Dir(config.OutputFile)
+	}
+	glob(filepath.Join(reportDir, "batch-report-*.md"))
+	if path := strings.TrimSpace(config.Logging.File); path != "" && !strings.EqualFold(path, logFileStderr) {
+		candidates = append(candidates, path)
+		glob(path + ".[0-9]*")
+	}
+	for _, pattern := range []string{"session-*.ndjson", "http-dump-*.log"} {
+		glob(filepath.Join(config.developerSessionDir(), pattern))
+	}
+	if includeConfig {
+		// 配置版本升级时留下的旧配置备份同样含有 Cookie 与 dsid
+		candidates = append(candidates, CONFIG_FILE)
+		glob(CONFIG_FILE + ".v*.bak")
+	}
+
+	seen := make(map[string]bool)
+	var files []string
+	for _, path := range candidates {
+		if path == "" {
+			continue
+		}
+		abs, err := filepath.Abs(path)
+		if err != nil || seen[abs] {
+			continue
+		}
+		seen[abs] = true
+		if info, err := os.Stat(path); err == nil && info.Mode().IsRegular() {
+			files = append(files, path)
+		}
+	}
+	return files
+}
+
+// secureRemove 用随机数据覆盖文件内容后删除
+func secureRemove(path string) error {
+	file, err := os.OpenFile(path, os.O_WRONLY, 0)
+	if err != nil {
+		return fmt.Errorf("打开文件失败: %v", err)
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("读取文件信息失败: %v", err)
+	}
+	if _, err := io.CopyN(file, rand.Reader, info.Size()); err != nil {
+		file.Close()
+		return fmt.Errorf("覆盖文件失败: %v", err)
+	}
+	if err := file.Sync(); err != nil {
+		file.Close()
+		return fmt.Errorf("覆盖文件失败: %v", err)
+	}
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("覆盖文件失败: %v", err)
+	}
+	return os.Remove(path)
+}
+
+// runPurgeLocalData 清除本机上的所有本地数据（清单、邮箱列表、导出文件等）
+func runPurgeLocalData(config *Config, args []string) error {
+	fs := flag.NewFlagSet("purge-local-data", flag.ContinueOnError)
+	includeConfig := fs.Bool("include-config", false, "同时删除 config.json（包含 iCloud 凭证），需要同时加 -force")
+	force := fs.Bool("force", false, "确认删除配置文件；全局的 --yes 不足以删除凭证")
+	if err := fs.Parse(args); err != nil {
+		return usageError(err)
+	}
+	if *includeConfig && !*force {
+		return usageError(fmt.Errorf("-include-config 会删除 %s 中的 iCloud 凭证，请同时加 -force 确认", CONFIG_FILE))
+	}
+
+	printHeader("清除本地数据")
+	files := localDataFiles(config, *includeConfig)
+	if len(files) == 0 {
+		printInfo("没有找到需要清除的本地数据")
+		return nil
+	}
+
+	for _, path := range files {
+		size := int64(0)
+		if info, err := os.Stat(path); err == nil {
+			size = info.Size()
+		}
+		fmt.Printf("  "+ColorRed+"✕"+ColorReset+" %s "+ColorDim+"(%d 字节)"+ColorReset+"\n", path, size)
+	}
+	fmt.Println()
+	printWarning("文件将被随机数据覆盖后删除，操作不可恢复")
+	printInfo("在 SSD 或写时复制文件系统上，覆盖不能保证物理擦除，建议同时启用磁盘加密")
+	if !*includeConfig {
+		printInfo("config.json 中的 iCloud 凭证未包含在内，如需一并删除请加 -include-config -force")
+	}
+
+	if !confirmAction(fmt.Sprintf("确认清除以上 %d 个文件", len(files))) {
+		printInfo("已取消")
+		return nil
+	}
+
+	// 防止后续操作重新写入清单
+	inventory = nil
+
+	failCount := 0
+	for _, path := range files {
+		if err := secureRemove(path); err != nil {
+			printError(fmt.Sprintf("%s: %v", path, err))
+			failCount++
+			continue
+		}
+		printSuccess(fmt.Sprintf("已清除 %s", path))
+	}
+
+	printSeparator()
+	if failCount > 0 {
+		return fmt.Errorf("%d 个文件清除失败", failCount)
+	}
+	printSuccess("本地数据已全部清除")
+	return nil
+}
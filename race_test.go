@@ -0,0 +1,173 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+// newTestConfig 指向 baseURL 的最小配置，状态文件写入临时目录，不做创建间隔
+func newTestConfig(t *testing.T, baseURL string) *Config {
+	t.Helper()
+	dir := t.TempDir()
+	config := &Config{
+		BaseURL:               baseURL,
+		ClientBuildNumber:     "test",
+		ClientMasteringNumber: "test",
+		ClientID:              "test-client",
+		DSID:                  "test-dsid",
+		Headers:               map[string]string{"Cookie": "X-APPLE-WEBAUTH-TOKEN=test"},
+		StateDir:              dir,
+		EmailListFile:         filepath.Join(dir, "emails.txt"),
+	}
+	NewConfigManager(filepath.Join(dir, "config.json")).setDefaults(config)
+	config.DelaySeconds = 0
+	return config
+}
+
+// TestConfigCloneWhileSaving 菜单保存配置（发布新副本）的同时，其他 goroutine 复制并读取已发布的配置
+func TestConfigCloneWhileSaving(t *testing.T) {
+	config := newTestConfig(t, "http://127.0.0.1:1/v1/hme/reserve")
+	configManager = NewConfigManager(filepath.Join(config.StateDir, "config.json"))
+	configMutex.Lock()
+	globalConfig = config
+	configMutex.Unlock()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(2)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 20; j++ {
+				edited := getCurrentConfig().clone()
+				edited.Count = i*100 + j
+				edited.Headers["X-Test"] = fmt.Sprint(j)
+				saveConfigWithMessage(edited, "已保存")
+			}
+		}(i)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				copied := getCurrentConfig().clone()
+				_ = copied.requestHeaders()["Cookie"]
+				_ = copied.httpClient()
+			}
+		}()
+	}
+	wg.Wait()
+
+	saved, err := loadConfig(configManager.configPath)
+	if err != nil {
+		t.Fatalf("读取保存的配置失败: %v", err)
+	}
+	if saved.DSID != config.DSID {
+		t.Fatalf("保存的 dsid = %q，期望 %q", saved.DSID, config.DSID)
+	}
+}
+
+// TestTransportStatsConcurrent 并发请求同时记录统计与重置
+func TestTransportStatsConcurrent(t *testing.T) {
+	stats := &TransportStats{Since: time.Now()}
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				stats.record(fmt.Sprintf("/v1/hme/%d", i%3), 200, nil, time.Duration(j)*time.Millisecond, j%2 == 0)
+				stats.recordRetry()
+			}
+		}(i)
+	}
+	wg.Wait()
+	if stats.Requests != 800 || stats.Retries != 800 || stats.StatusCounts[200] != 800 {
+		t.Fatalf("统计 = %d 个请求、%d 次重试、%d 个 200，期望各 800", stats.Requests, stats.Retries, stats.StatusCounts[200])
+	}
+
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for j := 0; j < 50; j++ {
+			stats.record("/v2/hme/list", 503, nil, time.Millisecond, false)
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for j := 0; j < 10; j++ {
+			stats.Reset()
+		}
+	}()
+	wg.Wait()
+}
+
+// TestSaveEmailToFileConcurrent 服务模式下多个请求同时追加邮箱列表文件，每条记录各占完整的一行
+func TestSaveEmailToFileConcurrent(t *testing.T) {
+	config := newTestConfig(t, "http://127.0.0.1:1/v1/hme/reserve")
+	config.SaveGeneratedEmails = true
+	saved := inventory
+	inventory = nil
+	defer func() { inventory = saved }()
+
+	const count = 50
+	var wg sync.WaitGroup
+	for i := 0; i < count; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			email := fmt.Sprintf("test%02d@icloud.com", i)
+			if err := saveEmailToFile(config, email, fmt.Sprintf("label-%d", i), CreationOrigin{Source: SourceAPI}); err != nil {
+				t.Errorf("保存 %s 失败: %v", email, err)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	file, err := os.Open(config.EmailListFile)
+	if err != nil {
+		t.Fatalf("打开邮箱列表失败: %v", err)
+	}
+	defer file.Close()
+	lines := 0
+	for scanner := bufio.NewScanner(file); scanner.Scan(); {
+		lines++
+	}
+	if lines != count {
+		t.Fatalf("邮箱列表有 %d 行，期望 %d", lines, count)
+	}
+}
+
+// TestBatchGenerateConcurrent 并发批量创建对着模拟服务器跑完，每项都创建成功
+func TestBatchGenerateConcurrent(t *testing.T) {
+	mock := newMockServer(MockFaultConfig{Seed: 1})
+	mock.quiet = true
+	server := httptest.NewServer(mock.handler())
+	defer server.Close()
+
+	config := newTestConfig(t, server.URL+"/v1/hme/reserve")
+	saved := inventory
+	inventory = nil
+	defer func() { inventory = saved }()
+
+	const count = 8
+	emails, errs := batchGenerateConcurrent(config, count, func(index int) string {
+		return fmt.Sprintf("race-%d", index)
+	}, 4)
+	if len(errs) > 0 {
+		t.Fatalf("批量创建出错: %v", errs)
+	}
+	if len(emails) != count {
+		t.Fatalf("创建了 %d 个邮箱，期望 %d", len(emails), count)
+	}
+	seen := make(map[string]bool)
+	for _, email := range emails {
+		if seen[email] {
+			t.Fatalf("重复的邮箱 %s", email)
+		}
+		seen[email] = true
+	}
+}
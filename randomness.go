@@ -0,0 +1,175 @@
+package main
+
+import (
+	"math"
+	"strings"
+	"sync"
+)
+
+// ngramModel 字母三元组语言模型，用内置英文词表训练，评估字母串有多像英文
+type ngramModel struct {
+	unigrams map[byte]float64
+	bigrams  map[string]float64
+	trigrams map[string]float64
+	total    float64
+}
+
+// 词首与词尾的占位符，让模型学到单词通常怎样开头和结尾
+const (
+	ngramStart = '^'
+	ngramEnd   = '$'
+)
+
+// 三元组、二元组、一元组概率的插值系数
+const (
+	trigramLambda = 0.6
+	bigramLambda  = 0.3
+	unigramLambda = 0.1
+)
+
+// 平均每个字母的信息量（比特）：英文单词约 2.5~3.5，随机字母接近 log2(26)≈4.7 以上
+const (
+	englishBitsPerChar = 3.0
+	randomBitsPerChar  = 5.5
+)
+
+var (
+	englishModelOnce sync.Once
+	englishModel     *ngramModel
+)
+
+// sharedNgramModel 第一次使用时用内置词表训练模型
+func sharedNgramModel() *ngramModel {
+	englishModelOnce.Do(func() {
+		englishModel = trainNgramModel(embeddedWordlist)
+	})
+	return englishModel
+}
+
+// trainNgramModel 统计词表中每个单词（加上首尾占位符）的一元、二元与三元组出现次数
+func trainNgramModel(text string) *ngramModel {
+	model := &ngramModel{unigrams: make(map[byte]float64), bigrams: make(map[string]float64), trigrams: make(map[string]float64)}
+	for _, line := range strings.Split(text, "\n") {
+		word := strings.ToLower(strings.TrimSpace(line))
+		if word == "" || strings.HasPrefix(word, "#") {
+			continue
+		}
+		padded := string([]byte{ngramStart, ngramStart}) + word + string(ngramEnd)
+		for i := 2; i < len(padded); i++ {
+			model.unigrams[padded[i]]++
+			model.bigrams[padded[i-1:i+1]]++
+			model.trigrams[padded[i-2:i+1]]++
+			model.total++
+		}
+	}
+	return model
+}
+
+// bitsPerChar 字母串在模型下平均每个字母的信息量，越低越像英文
+func (m *ngramModel) bitsPerChar(token string) float64 {
+	padded := string([]byte{ngramStart, ngramStart}) + token + string(ngramEnd)
+	bits := 0.0
+	for i := 2; i < len(padded); i++ {
+		c := padded[i]
+		// 一元组加一平滑（26 个字母与词尾），保证未见过的组合概率不为 0
+		p := unigramLambda * (m.unigrams[c] + 1) / (m.total + 27)
+		if ctx := m.unigramCount(padded[i-1]); ctx > 0 {
+			p += bigramLambda * m.bigrams[padded[i-1:i+1]] / ctx
+		}
+		if ctx := m.bigramCount(padded[i-2 : i]); ctx > 0 {
+			p += trigramLambda * m.trigrams[padded[i-2:i+1]] / ctx
+		}
+		bits -= math.Log2(p)
+	}
+	return bits / float64(len(padded)-2)
+}
+
+// unigramCount 作为上文的字母出现次数（词首占位符按单词数计）
+func (m *ngramModel) unigramCount(c byte) float64 {
+	if c == ngramStart {
+		return m.unigrams[ngramEnd]
+	}
+	return m.unigrams[c]
+}
+
+// bigramCount 作为上文的二元组出现次数
+func (m *ngramModel) bigramCount(ctx string) float64 {
+	if ctx == string([]byte{ngramStart, ngramStart}) {
+		return m.unigrams[ngramEnd]
+	}
+	return m.bigrams[ctx]
+}
+
+// charEntropy 字符分布的香农熵（比特）
+func charEntropy(s string) float64 {
+	counts := make(map[rune]int)
+	for _, r := range s {
+		counts[r]++
+	}
+	entropy := 0.0
+	for _, n := range counts {
+		p := float64(n) / float64(len(s))
+		entropy -= p * math.Log2(p)
+	}
+	return entropy
+}
+
+// evaluateNaturalness 评估前缀不像随机字符串的程度 (0-100分)：
+// 字母片段按三元组模型计算与英文的接近程度，再结合整体字符熵与字母、数字交替的次数
+func evaluateNaturalness(prefix string, why *scoreReasons) int {
+	prefix = strings.ToLower(prefix)
+	var chars strings.Builder
+	for _, r := range prefix {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+			chars.WriteRune(r)
+		}
+	}
+	s := chars.String()
+	if s == "" {
+		return 0
+	}
+
+	// 英文程度：按片段长度加权平均每个字母的信息量
+	model := sharedNgramModel()
+	letters, bits := 0, 0.0
+	for _, token := range strings.FieldsFunc(prefix, func(r rune) bool { return r < 'a' || r > 'z' }) {
+		letters += len(token)
+		bits += model.bitsPerChar(token) * float64(len(token))
+	}
+	ngramScore := 0.0
+	if letters > 0 {
+		ngramScore = (randomBitsPerChar - bits/float64(letters)) / (randomBitsPerChar - englishBitsPerChar) * 100
+		ngramScore = math.Max(0, math.Min(100, ngramScore))
+		// 字母占比低时（大部分是数字）英文程度按比例打折
+		ngramScore *= float64(letters) / float64(len(s))
+		why.add("英文相似度 %.0f（平均每个字母 %.1f 比特）", ngramScore, bits/float64(letters))
+	}
+
+	// 字符熵：较长的前缀中字符几乎各不相同时更像随机生成
+	entropyScore := 100.0
+	if len(s) >= 6 {
+		ratio := charEntropy(s) / math.Log2(math.Min(float64(len(s)), 36))
+		if penalty := math.Max(0, ratio-0.85) * 400; penalty > 0 {
+			why.add("字符几乎各不相同，熵偏高 (字符熵项 -%.0f)", penalty)
+			entropyScore -= penalty
+		}
+	}
+	// 字母与数字来回交替（如 a3x9kf）是随机字符串的典型特征，结尾一段数字不算
+	switches := 0
+	for i := 1; i < len(s); i++ {
+		if isDigit(s[i]) != isDigit(s[i-1]) {
+			switches++
+		}
+	}
+	if switches > 1 {
+		why.add("字母与数字交替 %d 次 (字符熵项 -%d)", switches, (switches-1)*20)
+		entropyScore -= float64(switches-1) * 20
+	}
+
+	return clampScore(int(ngramScore*0.7 + math.Max(0, entropyScore)*0.3))
+}
+
+// isDigit 是否为数字
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
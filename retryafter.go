@@ -0,0 +1,203 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"os"
+	"sync"
+	"time"
+
+	"icloud-hme-generator/pkg/hme"
+)
+
+// 限流响应未给出等待时间时，用于归类与 JSON 输出的默认值；实际等待由 retry_policy.rate_limit_backoff_seconds 决定
+const defaultRateLimitBackoff = 60 * time.Second
+
+// RetryPolicyConfig 接口请求的重试策略（retry_policy）
+type RetryPolicyConfig struct {
+	MaxRetries              int   `json:"max_retries"`                // 网络错误与可重试状态码的最大重试次数，-1 表示不重试
+	BaseDelayMS             int   `json:"base_delay_ms"`              // 第一次重试前的等待（毫秒），之后每次翻倍
+	MaxDelaySeconds         int   `json:"max_delay_seconds"`          // 单次等待的上限
+	JitterPercent           int   `json:"jitter_percent"`             // 每次等待随机增减的百分比
+	RetryStatusCodes        []int `json:"retry_status_codes"`         // 可重试的 HTTP 状态码
+	RateLimitRetries        int   `json:"rate_limit_retries"`         // 批量创建被限流时同一标签最多重试的次数，-1 表示不重试
+	RateLimitBackoffSeconds int   `json:"rate_limit_backoff_seconds"` // 限流响应未给出 retryAfter 时的等待
+}
+
+// hmePolicy 转换为接口客户端使用的重试策略，不重试时返回 nil
+func (p RetryPolicyConfig) hmePolicy() *hme.RetryPolicy {
+	if p.MaxRetries <= 0 {
+		return nil
+	}
+	return &hme.RetryPolicy{
+		MaxRetries:  p.MaxRetries,
+		BaseDelay:   time.Duration(p.BaseDelayMS) * time.Millisecond,
+		MaxDelay:    time.Duration(p.MaxDelaySeconds) * time.Second,
+		Jitter:      float64(p.JitterPercent) / 100,
+		StatusCodes: p.RetryStatusCodes,
+		OnRetry:     logRetry,
+	}
+}
+
+// logRetry 记录一次重试：计入传输统计，开启 http_trace 时输出到标准错误
+func logRetry(attempt int, wait time.Duration, err error) {
+	transportStats.recordRetry()
+	logger().Info("重试请求", "attempt", attempt, "wait", wait.Round(time.Millisecond).String(), "error", err)
+	if getCurrentConfig().featureEnabled(FeatureHTTPTrace) {
+		fmt.Fprintf(os.Stderr, ColorDim+"[http] 第 %d 次重试，等待 %s: %v"+ColorReset+"\n", attempt, wait.Round(time.Millisecond), err)
+	}
+}
+
+// rateLimitWait 被限流后重试前的等待：Apple 给出 retryAfter 时以它为准，否则使用 rate_limit_backoff_seconds
+func (p RetryPolicyConfig) rateLimitWait(err error) time.Duration {
+	if wait, ok := explicitRetryAfter(err); ok {
+		return wait
+	}
+	if p.RateLimitBackoffSeconds > 0 {
+		return time.Duration(p.RateLimitBackoffSeconds) * time.Second
+	}
+	return defaultRateLimitBackoff
+}
+
+// explicitRetryAfter 错误中由 Apple 明确给出的等待时间（响应体的 retryAfter 或 Retry-After 头）
+func explicitRetryAfter(err error) (time.Duration, bool) {
+	var limited *hme.RateLimitError
+	if errors.As(err, &limited) && limited.RetryAfter > 0 {
+		return limited.RetryAfter, true
+	}
+	var apiErr *APIError
+	if errors.As(err, &apiErr) && apiErr.RetryAfter > 0 {
+		return time.Duration(apiErr.RetryAfter) * time.Second, true
+	}
+	var status *APIStatusError
+	if errors.As(err, &status) && status.RetryAfter > 0 {
+		return status.RetryAfter, true
+	}
+	return 0, false
+}
+
+// retryAfterFor 判断错误是否为限流（hme.ErrRateLimited 或本地冷却），并返回建议的等待时间
+func retryAfterFor(err error) (time.Duration, bool) {
+	var cooldown *CooldownError
+	if errors.As(err, &cooldown) {
+		return time.Until(cooldown.Until), true
+	}
+	if !errors.Is(err, hme.ErrRateLimited) {
+		return 0, false
+	}
+	if wait, ok := explicitRetryAfter(err); ok {
+		return wait, true
+	}
+	return defaultRateLimitBackoff, true
+}
+
+// rateLimitGate 批量创建时各任务共享的限流暂停：任一任务被限流后，所有任务等待到同一时间再继续
+type rateLimitGate struct {
+	mutex sync.Mutex
+	until time.Time
+}
+
+// Wait 等待暂停结束，收到退出信号时提前返回 false
+func (g *rateLimitGate) Wait() bool {
+	g.mutex.Lock()
+	wait := time.Until(g.until)
+	g.mutex.Unlock()
+	return wait <= 0 || sleepUnlessCanceled(wait)
+}
+
+// Pause 暂停到 wait 之后（已有更晚的暂停时保持不变）
+func (g *rateLimitGate) Pause(wait time.Duration) {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	if until := time.Now().Add(wait); until.After(g.until) {
+		g.until = until
+	}
+}
+
+// createHMEWithBackoff 创建邮箱，遇到限流时按 retryAfter 暂停后用同一标签重试。
+// onPause 在每次暂停前调用，返回 false 时放弃重试并返回限流错误
+func createHMEWithBackoff(config *Config, label string, gate *rateLimitGate, onPause func(wait time.Duration) bool) (string, error) {
+	// 长时间运行的批量任务在会话令牌过期前刷新
+	maybeRefreshSession(config)
+	// reserve 耗时超过 latency_slo 阈值期间主动放慢
+	if wait := latencyTracker.Slowdown(config); wait > 0 && !sleepUnlessCanceled(wait) {
+		return "", apiContext().Err()
+	}
+	for attempt := 0; ; attempt++ {
+		if !gate.Wait() {
+			return "", apiContext().Err()
+		}
+		if attempt > 0 {
+			announce(config, SoundRateLimitEnd, "限流暂停结束，继续创建")
+		}
+		email, err := createHME(config, label)
+		if _, limited := retryAfterFor(err); !limited || attempt >= config.RetryPolicy.RateLimitRetries {
+			return email, err
+		}
+		wait := config.RetryPolicy.rateLimitWait(err)
+		if onPause != nil && !onPause(wait) {
+			return email, err
+		}
+		logger().Warn("创建时被限流，暂停后重试", "label", label, "wait", wait.Round(time.Second).String(), "attempt", attempt+1)
+		announce(config, SoundRateLimitStart, fmt.Sprintf("创建 %s 时被限流，暂停 %s 后继续", label, wait.Round(time.Second)))
+		gate.Pause(wait)
+	}
+}
+
+// requestLimiter 创建请求的全局令牌桶：按 requests_per_minute 补充、不允许突发，
+// 等待时附加随机抖动，避免多个任务在同一时刻醒来一起发出请求
+type requestLimiter struct {
+	perMinute     int
+	jitterPercent int
+	bucket        *tokenBucket
+}
+
+var (
+	requestLimiterMutex  sync.Mutex
+	sharedRequestLimiter *requestLimiter
+)
+
+// sharedLimiter 返回进程内共享的限速器，配置变化（如热重载）时重新创建；未配置限速时返回 nil
+func (c *Config) sharedLimiter() hme.Limiter {
+	if c.RequestsPerMinute <= 0 {
+		return nil
+	}
+	requestLimiterMutex.Lock()
+	defer requestLimiterMutex.Unlock()
+	if l := sharedRequestLimiter; l == nil || l.perMinute != c.RequestsPerMinute || l.jitterPercent != c.RequestJitterPercent {
+		sharedRequestLimiter = &requestLimiter{
+			perMinute:     c.RequestsPerMinute,
+			jitterPercent: c.RequestJitterPercent,
+			bucket:        newTokenBucket(c.RequestsPerMinute, 1),
+		}
+	}
+	return sharedRequestLimiter
+}
+
+// NextIn 距离下一次可以发出请求还需的时间，0 表示可以立即发出（不占用令牌）
+func (l *requestLimiter) NextIn() time.Duration {
+	return l.bucket.Peek()
+}
+
+// Wait 等待取得令牌
+func (l *requestLimiter) Wait(ctx context.Context) error {
+	interval := time.Minute / time.Duration(l.perMinute)
+	for {
+		ok, wait := l.bucket.Allow()
+		if ok {
+			return nil
+		}
+		if l.jitterPercent > 0 {
+			wait += time.Duration(rand.Int63n(int64(interval)*int64(l.jitterPercent)/100 + 1))
+		}
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
@@ -0,0 +1,308 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RetryQueueItem 批量创建中因限流失败、等待重试的一个标签
+type RetryQueueItem struct {
+	Label     string `json:"label"`
+	Attempts  int    `json:"attempts"`
+	LastError string `json:"last_error"`
+	FailedAt  int64  `json:"failed_at"` // Unix 毫秒
+	RetryAt   int64  `json:"retry_at"`  // 不早于该时间重试
+}
+
+// 重试队列写入时使用的临时文件名
+const retryQueueTempPattern = ".retry-queue-*.tmp"
+
+// 同一进程内的批量任务（包括并发任务）共用一个队列文件
+var retryQueueMutex sync.Mutex
+
+// loadRetryQueue 读取重试队列，文件不存在时返回空队列（调用方需持有锁）
+func loadRetryQueue(path string) ([]RetryQueueItem, error) {
+	data, recoveredFrom, err := readFileRecovering(path, retryQueueTempPattern, retryQueueFormat.validator(func() interface{} {
+		return &[]RetryQueueItem{}
+	}))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("读取重试队列失败: %v", err)
+	}
+	if recoveredFrom != "" {
+		printWarning(fmt.Sprintf("重试队列 %s 已损坏或未写完，已从 %s 恢复", path, recoveredFrom))
+	}
+	var items []RetryQueueItem
+	if err := retryQueueFormat.decode(data, &items); err != nil {
+		return nil, fmt.Errorf("解析重试队列失败: %v", err)
+	}
+	return items, nil
+}
+
+// saveRetryQueue 原子写入重试队列（调用方需持有锁）。队列为空时写入空列表而不是删除文件，
+// 否则下次读取时会从 .bak 恢复出旧的队列
+func saveRetryQueue(path string, items []RetryQueueItem) error {
+	if items == nil {
+		items = []RetryQueueItem{}
+	}
+	if err := retryQueueFormat.write(path, retryQueueTempPattern, items); err != nil {
+		return fmt.Errorf("写入重试队列失败: %v", err)
+	}
+	return nil
+}
+
+// retryQueueItems 当前账号的重试队列，按重试时间排列
+func retryQueueItems(config *Config) []RetryQueueItem {
+	if config.RetryQueueFile == "" {
+		return nil
+	}
+	retryQueueMutex.Lock()
+	defer retryQueueMutex.Unlock()
+	items, err := loadRetryQueue(config.RetryQueueFile)
+	if err != nil {
+		printWarning(err.Error())
+		return nil
+	}
+	sort.SliceStable(items, func(i, j int) bool { return items[i].RetryAt < items[j].RetryAt })
+	return items
+}
+
+// dueRetryLabels 已到重试时间的标签
+func dueRetryLabels(config *Config) []string {
+	var labels []string
+	now := time.Now().UnixMilli()
+	for _, item := range retryQueueItems(config) {
+		if item.RetryAt <= now {
+			labels = append(labels, item.Label)
+		}
+	}
+	return labels
+}
+
+// updateRetryQueue 记录批量创建中一项的结果：被限流时加入队列（已在队列中则累计次数），
+// 创建成功时从队列移除；其他错误不加入，交给失败汇总处理
+func updateRetryQueue(config *Config, label, email string, err error) {
+	if config.RetryQueueFile == "" {
+		return
+	}
+	_, limited := retryAfterFor(err)
+	if err != nil && !limited {
+		return
+	}
+
+	retryQueueMutex.Lock()
+	defer retryQueueMutex.Unlock()
+	items, loadErr := loadRetryQueue(config.RetryQueueFile)
+	if loadErr != nil {
+		printWarning(loadErr.Error())
+		return
+	}
+	index := -1
+	for i, item := range items {
+		if item.Label == label {
+			index = i
+			break
+		}
+	}
+
+	if err == nil {
+		if index < 0 {
+			return
+		}
+		items = append(items[:index], items[index+1:]...)
+		// 不在批量任务中执行（如启动时自动重试）时，同时在断点中记为完成，避免继续任务时重复创建
+		if currentBatchJob == nil {
+			if job := unfinishedBatchJob(config); job != nil && containsFold(job.Remaining(), label) {
+				job.Record(label, email, nil)
+			}
+		}
+	} else {
+		now := time.Now()
+		item := RetryQueueItem{Label: label, FailedAt: now.UnixMilli()}
+		if index >= 0 {
+			item = items[index]
+			item.FailedAt = now.UnixMilli()
+		}
+		item.Attempts++
+		item.LastError = err.Error()
+		item.RetryAt = now.Add(cooldownDuration(config, err)).UnixMilli()
+		if index >= 0 {
+			items[index] = item
+		} else {
+			items = append(items, item)
+		}
+	}
+	if saveErr := saveRetryQueue(config.RetryQueueFile, items); saveErr != nil {
+		printWarning(saveErr.Error())
+	}
+}
+
+// removeRetryQueue 从队列中移除标签，labels 为空时清空
+func removeRetryQueue(config *Config, labels []string) (int, error) {
+	retryQueueMutex.Lock()
+	defer retryQueueMutex.Unlock()
+	items, err := loadRetryQueue(config.RetryQueueFile)
+	if err != nil {
+		return 0, err
+	}
+	kept := items[:0]
+	for _, item := range items {
+		if len(labels) > 0 && !containsFold(labels, item.Label) {
+			kept = append(kept, item)
+		}
+	}
+	removed := len(items) - len(kept)
+	return removed, saveRetryQueue(config.RetryQueueFile, kept)
+}
+
+// formatRetryQueueStatus 菜单中显示的队列状态，如 “3 个等待重试，15:04 起”
+func formatRetryQueueStatus(items []RetryQueueItem) string {
+	due := 0
+	now := time.Now().UnixMilli()
+	for _, item := range items {
+		if item.RetryAt <= now {
+			due++
+		}
+	}
+	if due > 0 {
+		return fmt.Sprintf("%d 个等待重试，%d 个已到时间", len(items), due)
+	}
+	return fmt.Sprintf("%d 个等待重试，%s 起", len(items), time.UnixMilli(items[0].RetryAt).Format("15:04"))
+}
+
+// runRetryQueue 重试已到时间的标签；再次被限流的标签留在队列中并推迟重试时间
+func runRetryQueue(config *Config, labels []string) ([]string, []error) {
+	labelDesc := fmt.Sprintf("重试队列（%d 个）", len(labels))
+	startBatchReport(labelDesc, len(labels))
+	return batchGenerate(config, len(labels), labelDesc, listLabels(labels))
+}
+
+// retryQueueOnStart 进入菜单前自动重试已到时间的标签，冷却未结束时跳过
+func retryQueueOnStart(config *Config) {
+	labels := dueRetryLabels(config)
+	if len(labels) == 0 || !activeCooldown(config).IsZero() {
+		return
+	}
+	printHeader("重试队列")
+	printInfo(fmt.Sprintf("上次批量创建中有 %d 个因限流失败，自动重试: %s", len(labels), strings.Join(labels, "、")))
+	safetyManager.AddOperation()
+	defer safetyManager.DoneOperation()
+	emails, errs := runRetryQueue(config, labels)
+	showBatchResult(config, emails, errs)
+	if remaining := len(retryQueueItems(config)); remaining > 0 {
+		printInfo(fmt.Sprintf("仍有 %d 个在重试队列中，下次启动或在菜单中输入 q 时继续", remaining))
+	}
+}
+
+// handleRetryQueue 菜单中查看并立即重试队列
+func handleRetryQueue(config *Config) {
+	printHeader("重试队列")
+	items := retryQueueItems(config)
+	if len(items) == 0 {
+		printInfo("重试队列为空")
+		return
+	}
+	printRetryQueue(items)
+	if !waitForCooldown(config) {
+		return
+	}
+	labels := make([]string, len(items))
+	for i, item := range items {
+		labels[i] = item.Label
+	}
+	if !confirmAction(fmt.Sprintf("立即重试这 %d 个标签", len(labels))) {
+		printInfo("已取消")
+		return
+	}
+	safetyManager.AddOperation()
+	defer safetyManager.DoneOperation()
+	emails, errs := runRetryQueue(config, labels)
+	showBatchResult(config, emails, errs)
+}
+
+// printRetryQueue 列出队列中的标签
+func printRetryQueue(items []RetryQueueItem) {
+	now := time.Now().UnixMilli()
+	for _, item := range items {
+		when := ColorGreen + "可重试" + ColorReset
+		if item.RetryAt > now {
+			when = ColorDim + time.UnixMilli(item.RetryAt).Format("01-02 15:04") + " 后" + ColorReset
+		}
+		fmt.Printf("  %-28s %s "+ColorDim+"(失败 %d 次)"+ColorReset+"\n", item.Label, when, item.Attempts)
+	}
+	fmt.Println()
+}
+
+// runRetryQueueCommand 查看或执行重试队列：retry-queue [-run] [-all] [-clear] [标签...]
+func runRetryQueueCommand(config *Config, args []string) error {
+	fs := flag.NewFlagSet("retry-queue", flag.ContinueOnError)
+	run := fs.Bool("run", false, "重试已到时间的标签")
+	all := fs.Bool("all", false, "与 -run 一起使用时不等重试时间，重试全部标签")
+	remove := fs.Bool("clear", false, "从队列中移除指定的标签（不指定时清空）")
+	if err := fs.Parse(args); err != nil {
+		return usageError(err)
+	}
+	if config.RetryQueueFile == "" {
+		return configError("未配置 retry_queue_file")
+	}
+	if *run && *remove {
+		return usageError(fmt.Errorf("-run 与 -clear 不能同时使用"))
+	}
+
+	if *remove {
+		removed, err := removeRetryQueue(config, fs.Args())
+		if err != nil {
+			return err
+		}
+		printSuccess(fmt.Sprintf("已从重试队列移除 %d 个标签", removed))
+		return nil
+	}
+
+	items := retryQueueItems(config)
+	if !*run {
+		if outputJSON {
+			if items == nil {
+				items = []RetryQueueItem{}
+			}
+			return writeJSON(items)
+		}
+		printHeader("重试队列")
+		if len(items) == 0 {
+			printInfo("重试队列为空")
+			return nil
+		}
+		printRetryQueue(items)
+		printInfo("用 retry-queue -run 重试已到时间的标签")
+		return nil
+	}
+
+	if err := checkCooldown(config); err != nil {
+		return err
+	}
+	var labels []string
+	if *all {
+		for _, item := range items {
+			labels = append(labels, item.Label)
+		}
+	} else {
+		labels = dueRetryLabels(config)
+	}
+	if len(labels) == 0 {
+		printInfo("没有到时间的标签")
+		if outputJSON {
+			writeJSON(CLISummary{Op: "batch", Summary: true})
+		}
+		return nil
+	}
+	printHeader("重试队列")
+	emails, errs := runRetryQueue(config, labels)
+	return finishBatchCommand(config, emails, errs)
+}
@@ -0,0 +1,315 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Scorer 评分器：对邮箱地址打 0-100 分，Breakdown 为参与计算的各项得分
+type Scorer interface {
+	Score(email string) (int, Breakdown)
+}
+
+// ScoreItem 一个评分器的得分
+type ScoreItem struct {
+	Name    string   `json:"name"`
+	Title   string   `json:"title"` // 显示名称，如 结构
+	Score   int      `json:"score"`
+	Weight  int      `json:"weight"`
+	Reasons []string `json:"reasons,omitempty"` // 得分原因，如 “3 个连续重复字符 (-25)”
+}
+
+// scoreReasons 评分时记录得分原因；为 nil 时不记录
+type scoreReasons []string
+
+// add 记录一条原因
+func (r *scoreReasons) add(format string, args ...interface{}) {
+	if r != nil {
+		*r = append(*r, fmt.Sprintf(format, args...))
+	}
+}
+
+// Breakdown 各评分器的得分，按参与计算的顺序排列
+type Breakdown []ScoreItem
+
+// ScorerConfig 配置中的自定义评分器（email_quality.scorers），与内置的四项一起按权重加权平均
+type ScorerConfig struct {
+	Name       string   `json:"name"`
+	Type       string   `json:"type"` // regex：前缀匹配 pattern；words：前缀包含 words 中任一词
+	Weight     int      `json:"weight"`
+	Pattern    string   `json:"pattern,omitempty"`
+	Words      []string `json:"words,omitempty"`
+	MatchScore int      `json:"match_score"` // 命中时的分数；与 miss_score 都为 0 时命中 100 分、未命中 0 分
+	MissScore  int      `json:"miss_score"`
+}
+
+// prefixScorer 只看前缀和域名的单项评分器
+type prefixScorer struct {
+	name, title string
+	evaluate    func(prefix, domain string, why *scoreReasons) int
+}
+
+func (s prefixScorer) Score(email string) (int, Breakdown) {
+	prefix, domain := splitEmail(email)
+	var why scoreReasons
+	score := clampScore(s.evaluate(prefix, domain, &why))
+	return score, Breakdown{{Name: s.name, Title: s.title, Score: score, Reasons: why}}
+}
+
+// builtinScorers 内置评分器，权重来自 email_quality.weights
+var builtinScorers = []struct {
+	name, title string
+	evaluate    func(quality EmailQualityConfig) func(prefix, domain string, why *scoreReasons) int
+	weight      func(ScoreWeights) int
+	color       *string // 纯文本模式会清空颜色，取值时再读取
+}{
+	{"structure", "结构", prefixOnly(evaluatePrefixStructure), func(w ScoreWeights) int { return w.PrefixStructure }, &ColorCyan},
+	{"length", "长度", prefixOnly(evaluateLength), func(w ScoreWeights) int { return w.Length }, &ColorBlue},
+	{"readability", "可读", func(quality EmailQualityConfig) func(prefix, domain string, why *scoreReasons) int {
+		dict := dictionaryFor(quality.WordlistFile)
+		return func(prefix, _ string, why *scoreReasons) int { return evaluateReadability(prefix, dict, why) }
+	}, func(w ScoreWeights) int { return w.Readability }, &ColorYellow},
+	{"security", "安全", func(EmailQualityConfig) func(prefix, domain string, why *scoreReasons) int { return evaluateSecurity }, func(w ScoreWeights) int { return w.Security }, &ColorMagenta},
+	{"randomness", "自然", prefixOnly(evaluateNaturalness), func(w ScoreWeights) int { return w.Randomness }, &ColorBrightBlue},
+}
+
+// prefixOnly 只看前缀、与配置无关的内置评分
+func prefixOnly(evaluate func(prefix string, why *scoreReasons) int) func(EmailQualityConfig) func(prefix, domain string, why *scoreReasons) int {
+	return func(EmailQualityConfig) func(prefix, domain string, why *scoreReasons) int {
+		return func(prefix, _ string, why *scoreReasons) int { return evaluate(prefix, why) }
+	}
+}
+
+// scorerFactories 自定义评分器的类型注册表：type → 按配置构建评分器
+var scorerFactories = map[string]func(ScorerConfig) (Scorer, error){
+	"regex": func(c ScorerConfig) (Scorer, error) {
+		pattern, err := regexp.Compile(c.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("pattern 不是有效的正则表达式: %v", err)
+		}
+		return matchScorer(c, func(prefix string) string {
+			if pattern.MatchString(prefix) {
+				return "匹配 " + c.Pattern
+			}
+			return ""
+		}), nil
+	},
+	"words": func(c ScorerConfig) (Scorer, error) {
+		if len(c.Words) == 0 {
+			return nil, fmt.Errorf("words 不能为空")
+		}
+		return matchScorer(c, func(prefix string) string {
+			prefix = strings.ToLower(prefix)
+			for _, word := range c.Words {
+				if word = strings.ToLower(strings.TrimSpace(word)); word != "" && strings.Contains(prefix, word) {
+					return "包含 " + word
+				}
+			}
+			return ""
+		}), nil
+	},
+}
+
+// matchScorer 命中与未命中各给一个固定分数的评分器，match 返回命中的说明，未命中时为空
+func matchScorer(c ScorerConfig, match func(prefix string) string) Scorer {
+	hit, miss := c.MatchScore, c.MissScore
+	if hit == 0 && miss == 0 {
+		hit = 100
+	}
+	return prefixScorer{name: c.Name, title: c.Name, evaluate: func(prefix, _ string, why *scoreReasons) int {
+		if reason := match(prefix); reason != "" {
+			why.add("%s (%d 分)", reason, hit)
+			return hit
+		}
+		why.add("未命中 (%d 分)", miss)
+		return miss
+	}}
+}
+
+// weightedScorer 参与加权平均的评分器
+type weightedScorer struct {
+	scorer Scorer
+	weight int
+}
+
+// ScoringEngine 按权重组合多个评分器，本身也是一个 Scorer
+type ScoringEngine struct {
+	scorers []weightedScorer
+	blocked []blockRule // 命中屏蔽词时总分为 0
+}
+
+// newScoringEngine 按 email_quality 组装评分器：权重大于 0 的内置评分器在前，自定义评分器在后
+func newScoringEngine(quality EmailQualityConfig) (*ScoringEngine, error) {
+	blocked, err := parseBlocklist(quality.Blocklist)
+	if err != nil {
+		return nil, err
+	}
+	engine := &ScoringEngine{blocked: blocked}
+	names := make(map[string]bool)
+	for _, builtin := range builtinScorers {
+		names[builtin.name] = true
+		if weight := builtin.weight(quality.Weights); weight > 0 {
+			scorer := prefixScorer{name: builtin.name, title: builtin.title, evaluate: builtin.evaluate(quality)}
+			engine.scorers = append(engine.scorers, weightedScorer{scorer, weight})
+		}
+	}
+	for i, c := range quality.Scorers {
+		c.Name = strings.TrimSpace(c.Name)
+		if c.Name == "" {
+			c.Name = fmt.Sprintf("%s-%d", c.Type, i+1)
+		}
+		if names[c.Name] {
+			return nil, configError("email_quality.scorers 中的评分器名称 %q 重复", c.Name)
+		}
+		names[c.Name] = true
+		factory, ok := scorerFactories[strings.ToLower(c.Type)]
+		if !ok {
+			return nil, configError("email_quality.scorers[%d] 的类型 %q 不支持（可选: %s）", i, c.Type, strings.Join(scorerTypes(), "、"))
+		}
+		scorer, err := factory(c)
+		if err != nil {
+			return nil, configError("email_quality.scorers 中的评分器 %s: %v", c.Name, err)
+		}
+		if c.Weight > 0 {
+			engine.scorers = append(engine.scorers, weightedScorer{scorer, c.Weight})
+		}
+	}
+	return engine, nil
+}
+
+// scorerTypes 已注册的自定义评分器类型
+func scorerTypes() []string {
+	types := make([]string, 0, len(scorerFactories))
+	for name := range scorerFactories {
+		types = append(types, name)
+	}
+	sort.Strings(types)
+	return types
+}
+
+// Score 各评分器得分的加权平均，命中屏蔽词时为 0
+func (e *ScoringEngine) Score(email string) (int, Breakdown) {
+	prefix, domain := splitEmail(email)
+	if domain == "" {
+		return 0, nil
+	}
+	if rule := blockedBy(e.blocked, prefix); rule != "" {
+		return 0, Breakdown{{Name: "blocklist", Title: "屏蔽词 " + rule, Reasons: []string{"包含屏蔽词 " + rule + "，总分记为 0"}}}
+	}
+	var breakdown Breakdown
+	total, totalWeight := 0, 0
+	for _, ws := range e.scorers {
+		score, items := ws.scorer.Score(email)
+		for _, item := range items {
+			item.Weight = ws.weight
+			breakdown = append(breakdown, item)
+		}
+		total += score * ws.weight
+		totalWeight += ws.weight
+	}
+	if totalWeight == 0 {
+		return 0, breakdown
+	}
+	return clampScore(total / totalWeight), breakdown
+}
+
+var (
+	scoringEngineMutex sync.Mutex
+	scoringEngineCache = make(map[string]*ScoringEngine) // 按配置缓存，避免每次评分都重新编译正则
+	scoringWarned      bool
+)
+
+// scoringEngineFor 取得配置对应的评分引擎；自定义评分器配置有误时只提示一次并退回内置评分器
+func scoringEngineFor(quality EmailQualityConfig) *ScoringEngine {
+	key := fmt.Sprintf("%+v|%+v|%s|%q", quality.Weights, quality.Scorers, quality.WordlistFile, quality.Blocklist)
+	scoringEngineMutex.Lock()
+	defer scoringEngineMutex.Unlock()
+	if engine, ok := scoringEngineCache[key]; ok {
+		return engine
+	}
+	engine, err := newScoringEngine(quality)
+	if err != nil {
+		if !scoringWarned {
+			printWarning(fmt.Sprintf("%v，暂时只使用内置评分器", err))
+			scoringWarned = true
+		}
+		engine, _ = newScoringEngine(EmailQualityConfig{Weights: quality.Weights, WordlistFile: quality.WordlistFile})
+	}
+	scoringEngineCache[key] = engine
+	return engine
+}
+
+// resetScoringEngines 清空评分引擎与词典缓存（配置热重载后重新编译、重新读取词表），配置有误时会再次提示
+func resetScoringEngines() {
+	scoringEngineMutex.Lock()
+	defer scoringEngineMutex.Unlock()
+	scoringEngineCache = make(map[string]*ScoringEngine)
+	scoringWarned = false
+	resetDictionaries()
+}
+
+// scoreColor 详细评分中各项的颜色，自定义评分器统一为绿色，命中屏蔽词为红色
+func scoreColor(name string) string {
+	if name == "blocklist" {
+		return ColorRed
+	}
+	for _, builtin := range builtinScorers {
+		if builtin.name == name {
+			return *builtin.color
+		}
+	}
+	return ColorGreen
+}
+
+// scoreGrade 分数对应的评级及颜色
+func scoreGrade(score int) (string, string) {
+	switch {
+	case score >= 85:
+		return "优秀", ColorBrightGreen
+	case score >= 70:
+		return "良好", ColorGreen
+	case score >= 60:
+		return "一般", ColorYellow
+	}
+	return "较差", ColorRed
+}
+
+// printScoreReport 显示总分与各项的得分、评级、权重及原因
+func printScoreReport(score int, breakdown Breakdown) {
+	grade, gradeColor := scoreGrade(score)
+	fmt.Printf("      "+ColorMagenta+"总分:"+ColorReset+" "+gradeColor+"%d"+ColorReset+"/100 "+ColorDim+"("+gradeColor+"%s"+ColorReset+ColorDim+")"+ColorReset+"\n", score, grade)
+	for _, item := range breakdown {
+		grade, gradeColor := scoreGrade(item.Score)
+		fmt.Printf("      "+scoreColor(item.Name)+"%s"+ColorReset+" %3d "+gradeColor+"%s"+ColorReset, item.Title, item.Score, grade)
+		if item.Weight > 0 {
+			fmt.Printf(ColorDim+" 权重 %d"+ColorReset, item.Weight)
+		}
+		if len(item.Reasons) > 0 {
+			fmt.Print("  " + strings.Join(item.Reasons, "，"))
+		}
+		fmt.Println()
+	}
+}
+
+// splitEmail 拆分为前缀与域名，没有 @ 时域名为空
+func splitEmail(email string) (string, string) {
+	parts := strings.Split(email, "@")
+	if len(parts) != 2 {
+		return email, ""
+	}
+	return parts[0], parts[1]
+}
+
+// clampScore 限制在 0-100
+func clampScore(score int) int {
+	if score > 100 {
+		return 100
+	}
+	if score < 0 {
+		return 0
+	}
+	return score
+}
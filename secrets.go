@@ -0,0 +1,426 @@
+package main
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/scrypt"
+)
+
+// 加密方式
+const (
+	SecretsPassphrase = "passphrase" // 口令经 scrypt 派生密钥
+	SecretsKeychain   = "keychain"   // 随机密钥保存在系统钥匙串
+)
+
+// scrypt 参数（N=2^15 约占用 32 MiB 内存）与 AES-GCM 的附加数据
+const (
+	secretsKDF           = "scrypt"
+	secretsScryptN       = 1 << 15
+	secretsScryptR       = 8
+	secretsScryptP       = 1
+	secretsKeyLength     = 32
+	secretsAssociated    = "icloud-hme-secrets-v1"
+	secretsPassphraseEnv = "ICLOUD_HME_SECRETS_PASSPHRASE" // 定时任务等无法输入口令时由环境变量提供
+)
+
+// SecretsEnvelope 加密后的账号敏感字段（dsid 与 Cookie，含各账号配置），保存在 config.json 的 encrypted_secrets 中；
+// 启用后这些字段在文件中留空，加载时解密，保存时重新加密
+type SecretsEnvelope struct {
+	Method          string `json:"method"`
+	KDF             string `json:"kdf,omitempty"`
+	N               int    `json:"n,omitempty"`
+	R               int    `json:"r,omitempty"`
+	P               int    `json:"p,omitempty"`
+	Salt            string `json:"salt,omitempty"`
+	KeychainAccount string `json:"keychain_account,omitempty"` // 钥匙串中保存密钥的条目名
+	Nonce           string `json:"nonce"`
+	Ciphertext      string `json:"ciphertext"`
+}
+
+// sealedSecrets 加密的内容
+type sealedSecrets struct {
+	DSID     string                   `json:"dsid,omitempty"`
+	Cookie   string                   `json:"cookie,omitempty"`
+	Profiles map[string]sealedSecrets `json:"profiles,omitempty"`
+}
+
+// keyID 区分不同密钥：口令方式为盐，钥匙串方式为条目名
+func (e *SecretsEnvelope) keyID() string {
+	return e.Method + ":" + e.Salt + e.KeychainAccount
+}
+
+// 已解锁的密钥，按 keyID 缓存，配置重新加载与保存时不再重复询问口令
+var (
+	secretsMutex sync.Mutex
+	secretsKeys  = map[string][]byte{}
+)
+
+// cachedSecretsKey 已解锁的密钥，钥匙串方式未缓存时直接读取
+func cachedSecretsKey(envelope *SecretsEnvelope) ([]byte, error) {
+	secretsMutex.Lock()
+	key, ok := secretsKeys[envelope.keyID()]
+	secretsMutex.Unlock()
+	if ok {
+		return key, nil
+	}
+	switch envelope.Method {
+	case SecretsKeychain:
+		return keychainSecretsKey(envelope)
+	case SecretsPassphrase:
+		return nil, fmt.Errorf("%s 中的 dsid 与 Cookie 已用口令加密，尚未解锁", CONFIG_FILE)
+	default:
+		return nil, fmt.Errorf("未知的加密方式: %s", envelope.Method)
+	}
+}
+
+// rememberSecretsKey 缓存已确认正确的密钥
+func rememberSecretsKey(envelope *SecretsEnvelope, key []byte) {
+	secretsMutex.Lock()
+	secretsKeys[envelope.keyID()] = key
+	secretsMutex.Unlock()
+}
+
+// keychainSecretsKey 从系统钥匙串读取密钥
+func keychainSecretsKey(envelope *SecretsEnvelope) ([]byte, error) {
+	encoded, err := keychainGet(envelope.KeychainAccount)
+	if err != nil {
+		return nil, fmt.Errorf("无法从%s读取配置密钥（条目 %s/%s）: %w", keychainName(), keychainService, envelope.KeychainAccount, err)
+	}
+	key, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil || len(key) != secretsKeyLength {
+		return nil, fmt.Errorf("%s中的配置密钥格式不正确", keychainName())
+	}
+	rememberSecretsKey(envelope, key)
+	return key, nil
+}
+
+// passphraseSecretsKey 由口令派生密钥
+func passphraseSecretsKey(envelope *SecretsEnvelope, passphrase string) ([]byte, error) {
+	if envelope.KDF != secretsKDF {
+		return nil, fmt.Errorf("不支持的密钥派生算法: %s", envelope.KDF)
+	}
+	salt, err := base64.StdEncoding.DecodeString(envelope.Salt)
+	if err != nil {
+		return nil, fmt.Errorf("盐格式不正确: %v", err)
+	}
+	return scrypt.Key([]byte(passphrase), salt, envelope.N, envelope.R, envelope.P, secretsKeyLength)
+}
+
+// open 解密
+func (e *SecretsEnvelope) open(key []byte) (sealedSecrets, error) {
+	var secrets sealedSecrets
+	gcm, err := secretsCipher(key)
+	if err != nil {
+		return secrets, err
+	}
+	nonce, err := base64.StdEncoding.DecodeString(e.Nonce)
+	if err != nil || len(nonce) != gcm.NonceSize() {
+		return secrets, fmt.Errorf("encrypted_secrets 格式不正确")
+	}
+	ciphertext, err := base64.StdEncoding.DecodeString(e.Ciphertext)
+	if err != nil {
+		return secrets, fmt.Errorf("encrypted_secrets 格式不正确")
+	}
+	plaintext, err := gcm.Open(nil, nonce, ciphertext, []byte(secretsAssociated))
+	if err != nil {
+		return secrets, errSecretsWrongKey
+	}
+	if err := json.Unmarshal(plaintext, &secrets); err != nil {
+		return secrets, fmt.Errorf("解密后的内容无法解析: %v", err)
+	}
+	return secrets, nil
+}
+
+// errSecretsWrongKey 口令或密钥不正确（或密文被改动）
+var errSecretsWrongKey = errors.New("口令或密钥不正确，无法解密 dsid 与 Cookie")
+
+// seal 用新的随机 nonce 加密
+func (e *SecretsEnvelope) seal(key []byte, secrets sealedSecrets) error {
+	gcm, err := secretsCipher(key)
+	if err != nil {
+		return err
+	}
+	plaintext, err := json.Marshal(secrets)
+	if err != nil {
+		return err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return fmt.Errorf("生成随机数失败: %v", err)
+	}
+	e.Nonce = base64.StdEncoding.EncodeToString(nonce)
+	e.Ciphertext = base64.StdEncoding.EncodeToString(gcm.Seal(nil, nonce, plaintext, []byte(secretsAssociated)))
+	return nil
+}
+
+// secretsCipher AES-256-GCM
+func secretsCipher(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// openConfigSecrets 加载配置时解密 dsid 与 Cookie。文件中手动填写的明文（如重新粘贴的 Cookie）优先，下次保存时加密
+func openConfigSecrets(config *Config) error {
+	envelope := config.EncryptedSecrets
+	if envelope == nil {
+		return nil
+	}
+	key, err := cachedSecretsKey(envelope)
+	if err != nil {
+		return err
+	}
+	secrets, err := envelope.open(key)
+	if err != nil {
+		return err
+	}
+	fill := func(dsid *string, headers *map[string]string, sealed sealedSecrets) {
+		if *dsid == "" {
+			*dsid = sealed.DSID
+		}
+		if sealed.Cookie == "" {
+			return
+		}
+		if *headers == nil {
+			*headers = make(map[string]string)
+		}
+		if (*headers)["Cookie"] == "" {
+			(*headers)["Cookie"] = sealed.Cookie
+		}
+	}
+	fill(&config.DSID, &config.Headers, secrets)
+	for name, sealed := range secrets.Profiles {
+		if profile := config.Profiles[name]; profile != nil {
+			fill(&profile.DSID, &profile.Headers, sealed)
+		}
+	}
+	return nil
+}
+
+// sealConfigSecrets 保存配置前把 dsid 与 Cookie 移入 encrypted_secrets，data 为待写入的配置
+func sealConfigSecrets(data []byte) ([]byte, error) {
+	var out Config
+	if err := json.Unmarshal(data, &out); err != nil {
+		return nil, err
+	}
+	if out.EncryptedSecrets == nil {
+		return data, nil
+	}
+	key, err := cachedSecretsKey(out.EncryptedSecrets)
+	if err != nil {
+		return nil, err
+	}
+
+	take := func(dsid *string, headers map[string]string) sealedSecrets {
+		sealed := sealedSecrets{DSID: *dsid, Cookie: headers["Cookie"]}
+		*dsid = ""
+		delete(headers, "Cookie")
+		return sealed
+	}
+	secrets := take(&out.DSID, out.Headers)
+	for name, profile := range out.Profiles {
+		if profile == nil {
+			continue
+		}
+		if sealed := take(&profile.DSID, profile.Headers); sealed.DSID != "" || sealed.Cookie != "" {
+			if secrets.Profiles == nil {
+				secrets.Profiles = make(map[string]sealedSecrets)
+			}
+			secrets.Profiles[name] = sealed
+		}
+		if len(profile.Headers) == 0 {
+			profile.Headers = nil
+		}
+	}
+	if err := out.EncryptedSecrets.seal(key, secrets); err != nil {
+		return nil, fmt.Errorf("加密 dsid 与 Cookie 失败: %v", err)
+	}
+	return json.MarshalIndent(&out, "", "  ")
+}
+
+// readSecretsEnvelope 读取配置文件中的 encrypted_secrets，文件不存在或未加密时返回 nil
+func readSecretsEnvelope(path string) *SecretsEnvelope {
+	data, _, err := readConfigJSON(path)
+	if err != nil {
+		return nil
+	}
+	var raw struct {
+		EncryptedSecrets *SecretsEnvelope `json:"encrypted_secrets"`
+	}
+	if json.Unmarshal(data, &raw) != nil {
+		return nil
+	}
+	return raw.EncryptedSecrets
+}
+
+// unlockSecrets 启动时解锁加密的 dsid 与 Cookie：钥匙串方式直接读取密钥；口令方式先看环境变量 ICLOUD_HME_SECRETS_PASSPHRASE，
+// 否则在终端询问口令，连续错误超过上限时返回错误
+func unlockSecrets(path string) error {
+	envelope := readSecretsEnvelope(path)
+	if envelope == nil {
+		return nil
+	}
+	if envelope.Method != SecretsPassphrase {
+		key, err := cachedSecretsKey(envelope)
+		if err != nil {
+			return err
+		}
+		_, err = envelope.open(key)
+		return err
+	}
+
+	try := func(passphrase string) error {
+		key, err := passphraseSecretsKey(envelope, passphrase)
+		if err != nil {
+			return err
+		}
+		if _, err := envelope.open(key); err != nil {
+			return err
+		}
+		rememberSecretsKey(envelope, key)
+		return nil
+	}
+	if passphrase, ok := os.LookupEnv(secretsPassphraseEnv); ok {
+		if err := try(passphrase); err != nil {
+			return fmt.Errorf("%s: %w", secretsPassphraseEnv, err)
+		}
+		return nil
+	}
+	if !stdinIsTerminal() {
+		return fmt.Errorf("%s 中的 dsid 与 Cookie 已加密，非交互运行时请通过环境变量 %s 提供口令", CONFIG_FILE, secretsPassphraseEnv)
+	}
+	for attempt := 1; attempt <= passphraseMaxAttempts; attempt++ {
+		err := try(readPassphrase("请输入配置解密口令: "))
+		if err == nil {
+			return nil
+		}
+		if !errors.Is(err, errSecretsWrongKey) {
+			return err
+		}
+		printError(fmt.Sprintf("口令错误 (%d/%d)", attempt, passphraseMaxAttempts))
+		time.Sleep(time.Second)
+	}
+	return fmt.Errorf("口令错误次数过多")
+}
+
+// newSecretsEnvelope 生成新的密钥并返回对应的加密配置：口令方式派生密钥，钥匙串方式把随机密钥写入钥匙串
+func newSecretsEnvelope(method, passphrase string) (*SecretsEnvelope, []byte, error) {
+	envelope := &SecretsEnvelope{Method: method}
+	random := make([]byte, secretsKeyLength)
+	if _, err := rand.Read(random); err != nil {
+		return nil, nil, fmt.Errorf("生成随机数失败: %v", err)
+	}
+	if method == SecretsKeychain {
+		envelope.KeychainAccount = "config-" + hex.EncodeToString(random[:6])
+		key := make([]byte, secretsKeyLength)
+		if _, err := rand.Read(key); err != nil {
+			return nil, nil, fmt.Errorf("生成随机数失败: %v", err)
+		}
+		if err := keychainSet(envelope.KeychainAccount, base64.StdEncoding.EncodeToString(key)); err != nil {
+			return nil, nil, err
+		}
+		return envelope, key, nil
+	}
+
+	envelope.KDF, envelope.N, envelope.R, envelope.P = secretsKDF, secretsScryptN, secretsScryptR, secretsScryptP
+	envelope.Salt = base64.StdEncoding.EncodeToString(random[:16])
+	key, err := passphraseSecretsKey(envelope, passphrase)
+	if err != nil {
+		return nil, nil, err
+	}
+	return envelope, key, nil
+}
+
+// runSecrets 管理 dsid 与 Cookie 的加密：secrets [status|encrypt [-keychain]|decrypt]
+func runSecrets(config *Config, args []string) error {
+	action := "status"
+	if len(args) > 0 && args[0] != "" && args[0][0] != '-' {
+		action, args = args[0], args[1:]
+	}
+
+	fs := flag.NewFlagSet("secrets", flag.ContinueOnError)
+	useKeychain := fs.Bool("keychain", false, "密钥保存在系统钥匙串中，启动时无需输入口令")
+	if err := fs.Parse(args); err != nil {
+		return usageError(err)
+	}
+
+	switch action {
+	case "status":
+		printHeader("配置加密")
+		switch envelope := config.EncryptedSecrets; {
+		case envelope == nil:
+			printInfo(fmt.Sprintf("未启用，%s 中的 dsid 与 Cookie 为明文；使用 secrets encrypt 加密", CONFIG_FILE))
+		case envelope.Method == SecretsKeychain:
+			printSuccess(fmt.Sprintf("已启用，密钥保存在%s（条目 %s/%s）", keychainName(), keychainService, envelope.KeychainAccount))
+		default:
+			printSuccess(fmt.Sprintf("已启用，口令加密（scrypt N=%d r=%d p=%d + AES-256-GCM）", envelope.N, envelope.R, envelope.P))
+			printInfo(fmt.Sprintf("启动时输入口令，定时任务可通过环境变量 %s 提供", secretsPassphraseEnv))
+		}
+		return nil
+
+	case "encrypt":
+		if config.EncryptedSecrets != nil {
+			return fmt.Errorf("已启用加密；更换口令或方式请先运行 secrets decrypt")
+		}
+		method, passphrase := SecretsPassphrase, ""
+		if *useKeychain {
+			method = SecretsKeychain
+		} else if env, ok := os.LookupEnv(secretsPassphraseEnv); ok {
+			passphrase = env
+		} else {
+			passphrase = readPassphrase("设置配置解密口令: ")
+			if readPassphrase("再次输入口令: ") != passphrase {
+				return fmt.Errorf("两次输入的口令不一致")
+			}
+		}
+		if method == SecretsPassphrase && len([]rune(passphrase)) < passphraseMinLength {
+			return fmt.Errorf("口令长度不能少于 %d 个字符", passphraseMinLength)
+		}
+		envelope, key, err := newSecretsEnvelope(method, passphrase)
+		if err != nil {
+			return err
+		}
+		rememberSecretsKey(envelope, key)
+		config.EncryptedSecrets = envelope
+		saveConfigWithMessage(config, "dsid 与 Cookie 已加密")
+		if method == SecretsPassphrase {
+			printWarning("忘记口令将无法恢复，只能重新登录抓取 Cookie")
+		}
+		return nil
+
+	case "decrypt":
+		envelope := config.EncryptedSecrets
+		if envelope == nil {
+			printInfo("未启用加密")
+			return nil
+		}
+		if !confirmAction("确认把 dsid 与 Cookie 以明文写回 " + CONFIG_FILE) {
+			printInfo("已取消")
+			return nil
+		}
+		config.EncryptedSecrets = nil
+		saveConfigWithMessage(config, "已关闭加密")
+		if envelope.Method == SecretsKeychain {
+			if err := keychainDelete(envelope.KeychainAccount); err != nil {
+				printWarning(fmt.Sprintf("未能删除钥匙串中的密钥: %v", err))
+			}
+		}
+		return nil
+
+	default:
+		return usageError(fmt.Errorf("未知操作: %s (可用: status, encrypt [-keychain], decrypt)", action))
+	}
+}
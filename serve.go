@@ -0,0 +1,762 @@
+package main
+
+import (
+	"context"
+	"crypto/subtle"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ServeConfig 服务模式配置
+type ServeConfig struct {
+	ListenAddr string `json:"listen_addr"` // 监听地址，如 127.0.0.1:8787 或 unix:/tmp/icloud-hme.sock
+
+	// 默认限流配置（API Key 未单独配置时使用）
+	RateLimitPerMinute int `json:"rate_limit_per_minute"` // 每分钟允许的请求数
+	RateLimitBurst     int `json:"rate_limit_burst"`      // 突发请求数
+	MaxConcurrent      int `json:"max_concurrent"`        // 单个 Key 的最大并发请求数
+
+	// 全局并发上限，保护 Apple 端配额
+	GlobalMaxConcurrent int `json:"global_max_concurrent"`
+
+	// 邮箱列表缓存有效期，过期后先返回旧数据再在后台刷新
+	CacheTTLSeconds int `json:"cache_ttl_seconds"`
+
+	APIKeys []ServeAPIKey `json:"api_keys"`
+
+	// 是否关闭内嵌的网页管理界面
+	DisableDashboard bool `json:"disable_dashboard"`
+
+	// 创建邮箱时若已存在相同标签的激活邮箱则直接返回，可被请求体中的 reuse_existing 覆盖
+	IdempotentCreate bool `json:"idempotent_create"`
+
+	// TLS / 双向 TLS 配置
+	TLS ServeTLSConfig `json:"tls"`
+
+	// 审计 Webhook，记录所有变更操作
+	AuditWebhook AuditWebhookConfig `json:"audit_webhook"`
+}
+
+// ServeTLSConfig 服务模式 TLS 配置
+type ServeTLSConfig struct {
+	CertFile     string `json:"cert_file"`      // 服务端证书，为空表示不启用 TLS
+	KeyFile      string `json:"key_file"`       // 服务端私钥
+	ClientCAFile string `json:"client_ca_file"` // 客户端证书 CA，配置后启用双向 TLS
+	MinVersion   string `json:"min_version"`    // 最低 TLS 版本：1.2 或 1.3
+}
+
+// Enabled 是否启用 TLS
+func (tc ServeTLSConfig) Enabled() bool {
+	return tc.CertFile != "" || tc.KeyFile != ""
+}
+
+// buildTLSConfig 根据配置构建 tls.Config
+func (tc ServeTLSConfig) buildTLSConfig() (*tls.Config, error) {
+	if tc.CertFile == "" || tc.KeyFile == "" {
+		return nil, fmt.Errorf("cert_file 和 key_file 必须同时配置")
+	}
+
+	cert, err := tls.LoadX509KeyPair(tc.CertFile, tc.KeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("加载服务端证书失败: %v", err)
+	}
+
+	tlsConfig := &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   tls.VersionTLS12,
+	}
+
+	switch tc.MinVersion {
+	case "", "1.2":
+	case "1.3":
+		tlsConfig.MinVersion = tls.VersionTLS13
+	default:
+		return nil, fmt.Errorf("不支持的 TLS 版本: %s", tc.MinVersion)
+	}
+
+	if tc.ClientCAFile != "" {
+		caData, err := os.ReadFile(tc.ClientCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("读取客户端 CA 失败: %v", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(caData) {
+			return nil, fmt.Errorf("客户端 CA 文件中没有有效的 PEM 证书")
+		}
+		tlsConfig.ClientCAs = pool
+		tlsConfig.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+
+	return tlsConfig, nil
+}
+
+// ServeAPIKey 服务模式 API Key 配置
+type ServeAPIKey struct {
+	Name               string `json:"name"`
+	Key                string `json:"key"`
+	RateLimitPerMinute int    `json:"rate_limit_per_minute"` // 0 表示使用默认值
+	RateLimitBurst     int    `json:"rate_limit_burst"`      // 0 表示使用默认值
+	MaxConcurrent      int    `json:"max_concurrent"`        // 0 表示使用默认值
+}
+
+// ServeResponse 服务模式统一响应体
+type ServeResponse struct {
+	Success bool        `json:"success"`
+	Result  interface{} `json:"result,omitempty"`
+	Error   *APIError   `json:"error,omitempty"`
+}
+
+// tokenBucket 令牌桶限流器
+type tokenBucket struct {
+	rate   float64 // 每秒补充的令牌数
+	burst  float64
+	tokens float64
+	last   time.Time
+	mutex  sync.Mutex
+}
+
+// newTokenBucket 创建令牌桶
+func newTokenBucket(perMinute, burst int) *tokenBucket {
+	if burst <= 0 {
+		burst = 1
+	}
+	return &tokenBucket{
+		rate:   float64(perMinute) / 60,
+		burst:  float64(burst),
+		tokens: float64(burst),
+		last:   time.Now(),
+	}
+}
+
+// Allow 尝试取出一个令牌，失败时返回需要等待的时间
+func (tb *tokenBucket) Allow() (bool, time.Duration) {
+	tb.mutex.Lock()
+	defer tb.mutex.Unlock()
+
+	now := time.Now()
+	tb.tokens = math.Min(tb.burst, tb.tokens+now.Sub(tb.last).Seconds()*tb.rate)
+	tb.last = now
+
+	if tb.tokens >= 1 {
+		tb.tokens--
+		return true, 0
+	}
+
+	if tb.rate <= 0 {
+		return false, time.Minute
+	}
+	wait := time.Duration((1 - tb.tokens) / tb.rate * float64(time.Second))
+	return false, wait
+}
+
+// Peek 不取出令牌，返回距离下一个令牌可用还需的时间，0 表示可以立即取出
+func (tb *tokenBucket) Peek() time.Duration {
+	tb.mutex.Lock()
+	defer tb.mutex.Unlock()
+
+	tokens := math.Min(tb.burst, tb.tokens+time.Since(tb.last).Seconds()*tb.rate)
+	if tokens >= 1 {
+		return 0
+	}
+	if tb.rate <= 0 {
+		return time.Minute
+	}
+	return time.Duration((1 - tokens) / tb.rate * float64(time.Second))
+}
+
+// apiClient 已认证的 API 调用方及其限流状态
+type apiClient struct {
+	name      string
+	limiter   *tokenBucket
+	semaphore chan struct{}
+}
+
+// APIServer 本地 REST API 服务
+type APIServer struct {
+	settings  ServeConfig
+	clients   map[string]*apiClient
+	global    chan struct{}
+	server    *http.Server
+	audit     *AuditWebhook
+	emails    *EmailListCache
+	events    *EventHub
+	batches   *batchRegistry
+	lock      *idleLock  // 空闲超时锁，nil 表示不启用
+	creating  sync.Mutex // 幂等创建时串行化“查找-创建”，避免并发重试产生重复邮箱
+	startedAt time.Time
+}
+
+// NewAPIServer 创建 API 服务
+func NewAPIServer(settings ServeConfig) (*APIServer, error) {
+	if len(settings.APIKeys) == 0 {
+		return nil, configError("未配置 serve.api_keys，拒绝以无认证方式启动")
+	}
+	// 环境变量与 --set 覆盖的值不经过配置文件校验，这里再检查一次，负数会让创建信号量时 panic
+	if settings.MaxConcurrent <= 0 || settings.GlobalMaxConcurrent <= 0 {
+		return nil, configError("serve.max_concurrent 与 serve.global_max_concurrent 必须大于 0")
+	}
+
+	s := &APIServer{
+		settings: settings,
+		clients:  make(map[string]*apiClient),
+		global:   make(chan struct{}, settings.GlobalMaxConcurrent),
+		audit:    NewAuditWebhook(settings.AuditWebhook),
+		events:   NewEventHub(),
+		batches:  newBatchRegistry(),
+		emails: NewEmailListCache(time.Duration(settings.CacheTTLSeconds)*time.Second, func() ([]HMEEmail, error) {
+			emails, err := listHME(getCurrentConfig())
+			if err == nil {
+				if err := inventory.Sync(emails); err != nil {
+					printWarning(fmt.Sprintf("同步本地清单失败: %v", err))
+				}
+			}
+			return emails, err
+		}),
+	}
+
+	for _, key := range settings.APIKeys {
+		if strings.TrimSpace(key.Key) == "" {
+			return nil, fmt.Errorf("API Key %q 为空", key.Name)
+		}
+		if _, exists := s.clients[key.Key]; exists {
+			return nil, fmt.Errorf("API Key %q 重复", key.Name)
+		}
+
+		perMinute := key.RateLimitPerMinute
+		if perMinute <= 0 {
+			perMinute = settings.RateLimitPerMinute
+		}
+		burst := key.RateLimitBurst
+		if burst <= 0 {
+			burst = settings.RateLimitBurst
+		}
+		concurrent := key.MaxConcurrent
+		if concurrent <= 0 {
+			concurrent = settings.MaxConcurrent
+		}
+
+		s.clients[key.Key] = &apiClient{
+			name:      key.Name,
+			limiter:   newTokenBucket(perMinute, burst),
+			semaphore: make(chan struct{}, concurrent),
+		}
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /health", s.handleHealth)
+	if !settings.DisableDashboard {
+		mux.HandleFunc("GET /{$}", s.handleDashboard)
+	}
+	mux.Handle("GET /emails", s.protect(s.handleList))
+	mux.Handle("POST /emails", s.protect(s.handleCreate))
+	mux.Handle("POST /emails/{id}/deactivate", s.protect(s.handleDeactivate))
+	mux.Handle("POST /emails/{id}/reactivate", s.protect(s.handleReactivate))
+	mux.Handle("DELETE /emails/{id}", s.protect(s.handleDelete))
+	mux.Handle("POST /batches", s.protect(s.handleCreateBatch))
+	mux.Handle("GET /batches/{id}", s.protect(s.handleGetBatch))
+	mux.Handle("GET /otp", s.protect(s.handleOTP))
+	mux.Handle("GET /events", s.authorize(s.handleEvents))
+
+	s.server = &http.Server{
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	if settings.TLS.Enabled() {
+		tlsConfig, err := settings.TLS.buildTLSConfig()
+		if err != nil {
+			return nil, err
+		}
+		s.server.TLSConfig = tlsConfig
+	}
+
+	return s, nil
+}
+
+// ListenAndServe 启动监听，支持 TCP 地址和 unix: 前缀的 Unix Socket
+func (s *APIServer) ListenAndServe() error {
+	listener, err := listenAddr(s.settings.ListenAddr)
+	if err != nil {
+		return err
+	}
+	return s.Serve(listener)
+}
+
+// listenAddr 监听 TCP 地址或 unix: 前缀的 Unix Socket（权限 0600，只有当前用户可以连接）
+func listenAddr(addr string) (net.Listener, error) {
+	network, address := "tcp", addr
+	if strings.HasPrefix(address, "unix:") {
+		network, address = "unix", strings.TrimPrefix(address, "unix:")
+		// 只清理上次残留的 socket 文件，路径写错时不会删除普通文件
+		if info, err := os.Lstat(address); err == nil {
+			if info.Mode()&os.ModeSocket == 0 {
+				return nil, fmt.Errorf("监听 %s 失败: %s 已存在且不是 socket 文件", addr, address)
+			}
+			os.Remove(address)
+		}
+	}
+
+	listener, err := net.Listen(network, address)
+	if err != nil {
+		return nil, fmt.Errorf("监听 %s 失败: %v", addr, err)
+	}
+	if network == "unix" {
+		os.Chmod(address, 0600)
+	}
+	return listener, nil
+}
+
+// Serve 在已建立的监听上提供服务（启用 TLS 时在其上包装 TLS），直到 Shutdown
+func (s *APIServer) Serve(listener net.Listener) error {
+	if s.server.TLSConfig != nil {
+		listener = tls.NewListener(listener, s.server.TLSConfig)
+	}
+
+	s.startedAt = time.Now()
+	err := s.server.Serve(listener)
+	if err == http.ErrServerClosed {
+		return nil
+	}
+	return err
+}
+
+// Shutdown 关闭服务
+func (s *APIServer) Shutdown() error {
+	return s.server.Close()
+}
+
+// authenticate 从请求头中解析 API Key
+func (s *APIServer) authenticate(r *http.Request) *apiClient {
+	key := r.Header.Get("X-API-Key")
+	if key == "" {
+		key, _ = bearerToken(r)
+	}
+	// 浏览器的 EventSource 无法设置请求头，事件流允许通过查询参数传递
+	if key == "" && r.URL.Path == "/events" {
+		key = r.URL.Query().Get("api_key")
+	}
+	if key == "" {
+		return nil
+	}
+	// 逐个按常量时间比较，响应时间不随 Key 前缀是否匹配而变化
+	var matched *apiClient
+	for candidate, client := range s.clients {
+		if tokenEqual(key, candidate) {
+			matched = client
+		}
+	}
+	return matched
+}
+
+// bearerToken 取出 Authorization: Bearer 后的令牌，没有 Bearer 前缀时返回空字符串与 false
+func bearerToken(r *http.Request) (string, bool) {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok {
+		return "", false
+	}
+	return token, true
+}
+
+// tokenEqual 按常量时间比较令牌
+func tokenEqual(got, want string) bool {
+	return subtle.ConstantTimeCompare([]byte(got), []byte(want)) == 1
+}
+
+// authorize 为处理函数加上认证和限流（适用于长连接，不占用并发名额）
+func (s *APIServer) authorize(next func(http.ResponseWriter, *http.Request, *apiClient)) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		client := s.authenticate(r)
+		if client == nil {
+			writeServeError(w, http.StatusUnauthorized, "unauthorized", "API Key 无效或缺失")
+			return
+		}
+
+		if !s.lock.Touch() {
+			writeServeError(w, http.StatusLocked, "locked", "服务空闲超时已锁定，请在服务终端输入启动口令解锁")
+			return
+		}
+
+		if ok, wait := client.limiter.Allow(); !ok {
+			retryAfter := int(math.Ceil(wait.Seconds()))
+			w.Header().Set("Retry-After", strconv.Itoa(retryAfter))
+			writeServeError(w, http.StatusTooManyRequests, "rate_limited",
+				fmt.Sprintf("请求过于频繁，请在 %d 秒后重试", retryAfter))
+			return
+		}
+
+		next(w, r, client)
+	})
+}
+
+// protect 为处理函数加上认证、限流和并发控制
+func (s *APIServer) protect(next func(http.ResponseWriter, *http.Request, *apiClient)) http.Handler {
+	return s.authorize(func(w http.ResponseWriter, r *http.Request, client *apiClient) {
+		select {
+		case client.semaphore <- struct{}{}:
+			defer func() { <-client.semaphore }()
+		default:
+			writeServeError(w, http.StatusTooManyRequests, "too_many_concurrent", "并发请求数超过上限")
+			return
+		}
+
+		select {
+		case s.global <- struct{}{}:
+			defer func() { <-s.global }()
+		default:
+			writeServeError(w, http.StatusServiceUnavailable, "server_busy", "服务繁忙，请稍后重试")
+			return
+		}
+
+		next(w, r, client)
+	})
+}
+
+func (s *APIServer) handleHealth(w http.ResponseWriter, r *http.Request) {
+	writeServeResult(w, map[string]interface{}{
+		"version": VERSION,
+		"uptime":  time.Since(s.startedAt).Round(time.Second).String(),
+	})
+}
+
+// handleList 返回邮箱列表，支持 max_age 参数和 If-Modified-Since 条件请求
+func (s *APIServer) handleList(w http.ResponseWriter, r *http.Request, client *apiClient) {
+	maxAge := time.Duration(-1)
+	if value := r.URL.Query().Get("max_age"); value != "" {
+		seconds, err := strconv.Atoi(value)
+		if err != nil || seconds < 0 {
+			writeServeError(w, http.StatusBadRequest, "invalid_max_age", "max_age 必须是非负整数（秒）")
+			return
+		}
+		maxAge = time.Duration(seconds) * time.Second
+	}
+
+	snapshot, err := s.emails.Get(maxAge)
+	if err != nil {
+		writeServeError(w, http.StatusBadGateway, "upstream_error", err.Error())
+		return
+	}
+
+	modifiedAt := snapshot.ModifiedAt.UTC().Truncate(time.Second)
+	w.Header().Set("Last-Modified", modifiedAt.Format(http.TimeFormat))
+	w.Header().Set("Age", strconv.Itoa(int(time.Since(snapshot.FetchedAt).Seconds())))
+	w.Header().Set("X-Cache", snapshot.Status)
+	w.Header().Set("Cache-Control", "private, no-cache")
+
+	if since, err := http.ParseTime(r.Header.Get("If-Modified-Since")); err == nil && !modifiedAt.After(since) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	writeServeResult(w, snapshot.Emails)
+}
+
+func (s *APIServer) handleCreate(w http.ResponseWriter, r *http.Request, client *apiClient) {
+	var body struct {
+		Label         string `json:"label"`
+		ReuseExisting *bool  `json:"reuse_existing"` // 为空时使用 serve.idempotent_create
+		Lang          string `json:"lang"`           // 本次生成使用的语言，为空时使用 lang_code
+	}
+	if !decodeServeBody(w, r, &body) {
+		return
+	}
+	if strings.TrimSpace(body.Label) == "" {
+		writeServeError(w, http.StatusBadRequest, "invalid_label", "标签不能为空")
+		return
+	}
+	lang, err := parseLangCode(body.Lang)
+	if err != nil {
+		writeServeError(w, http.StatusBadRequest, "invalid_lang", err.Error())
+		return
+	}
+
+	reuse := s.settings.IdempotentCreate
+	if body.ReuseExisting != nil {
+		reuse = *body.ReuseExisting
+	}
+	if reuse {
+		s.creating.Lock()
+		defer s.creating.Unlock()
+
+		snapshot, err := s.emails.Get(time.Duration(s.settings.CacheTTLSeconds) * time.Second)
+		if err != nil {
+			writeServeError(w, http.StatusBadGateway, "upstream_error", err.Error())
+			return
+		}
+		if existing, ok := findActiveByLabel(snapshot.Emails, body.Label); ok {
+			writeServeResult(w, map[string]interface{}{"hme": existing.HME, "label": existing.Label, "existing": true})
+			return
+		}
+	}
+
+	config := getCurrentConfig()
+	email, err := createHMEWithLang(config, body.Label, lang)
+	s.recordMutation(r, client, "create", AuditEvent{HME: email, Label: body.Label}, err)
+	if err != nil {
+		writeServeError(w, http.StatusBadGateway, "upstream_error", err.Error())
+		return
+	}
+	s.emails.Invalidate()
+
+	if err := saveEmailToFile(config, email, body.Label, CreationOrigin{Source: SourceAPI, Actor: client.name}); err != nil {
+		printWarning(fmt.Sprintf("保存邮箱到文件失败: %v", err))
+	}
+
+	writeServeResult(w, map[string]interface{}{"hme": email, "label": body.Label, "existing": false})
+}
+
+// findActiveByLabel 查找标签相同（忽略大小写与首尾空格）的激活邮箱
+func findActiveByLabel(emails []HMEEmail, label string) (HMEEmail, bool) {
+	label = strings.TrimSpace(label)
+	for _, email := range emails {
+		if email.IsActive && strings.EqualFold(strings.TrimSpace(email.Label), label) {
+			return email, true
+		}
+	}
+	return HMEEmail{}, false
+}
+
+func (s *APIServer) handleDeactivate(w http.ResponseWriter, r *http.Request, client *apiClient) {
+	s.handleMutation(w, r, client, "deactivate", deactivateHME)
+}
+
+func (s *APIServer) handleReactivate(w http.ResponseWriter, r *http.Request, client *apiClient) {
+	s.handleMutation(w, r, client, "reactivate", reactivateHME)
+}
+
+func (s *APIServer) handleDelete(w http.ResponseWriter, r *http.Request, client *apiClient) {
+	s.handleMutation(w, r, client, "delete", permanentDeleteHME)
+}
+
+// handleMutation 执行针对单个邮箱的变更操作
+func (s *APIServer) handleMutation(w http.ResponseWriter, r *http.Request, client *apiClient, name string, action func(*Config, string) error) {
+	anonymousID := r.PathValue("id")
+	err := action(getCurrentConfig(), anonymousID)
+	s.recordMutation(r, client, name, AuditEvent{AnonymousID: anonymousID}, err)
+	if err != nil {
+		writeServeError(w, http.StatusBadGateway, "upstream_error", err.Error())
+		return
+	}
+	switch name {
+	case "delete":
+		if err := inventory.RecordDeletion(HMEEmail{AnonymousID: anonymousID}); err != nil {
+			printWarning(fmt.Sprintf("记录本地墓碑失败: %v", err))
+		}
+	case "deactivate", "reactivate":
+		eventType := InventoryEventDeactivated
+		if name == "reactivate" {
+			eventType = InventoryEventReactivated
+		}
+		origin := CreationOrigin{Source: SourceAPI, Actor: client.name}
+		if err := inventory.RecordEvent(HMEEmail{AnonymousID: anonymousID}, eventType, origin); err != nil {
+			printWarning(fmt.Sprintf("记录本地清单失败: %v", err))
+		}
+	}
+	s.emails.Invalidate()
+	writeServeResult(w, map[string]string{"anonymousId": anonymousID})
+}
+
+// recordMutation 补全审计事件并提交到 Webhook
+func (s *APIServer) recordMutation(r *http.Request, client *apiClient, action string, event AuditEvent, err error) {
+	s.recordMutationFrom(client, r.RemoteAddr, action, event, err)
+}
+
+// recordMutationFrom 同 recordMutation，供脱离请求上下文的后台任务使用
+func (s *APIServer) recordMutationFrom(client *apiClient, remoteAddr, action string, event AuditEvent, err error) {
+	event.ID = newEventID()
+	event.Timestamp = time.Now().UnixMilli()
+	event.Action = action
+	event.Actor = client.name
+	event.RemoteAddr = remoteAddr
+	event.Success = err == nil
+	if err != nil {
+		event.Error = err.Error()
+	}
+	s.audit.Emit(event)
+
+	if err == nil {
+		if eventType, ok := mutationEventTypes[action]; ok {
+			s.events.Publish(eventType, event)
+		}
+	}
+}
+
+// 变更操作对应的推送事件类型
+var mutationEventTypes = map[string]string{
+	"create":     EventEmailCreated,
+	"deactivate": EventEmailDeactivated,
+	"reactivate": EventEmailReactivated,
+	"delete":     EventEmailDeleted,
+}
+
+// handleEvents 以 Server-Sent Events 推送实时事件
+func (s *APIServer) handleEvents(w http.ResponseWriter, r *http.Request, client *apiClient) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeServeError(w, http.StatusInternalServerError, "streaming_unsupported", "当前连接不支持流式响应")
+		return
+	}
+
+	events, cancel := s.events.Subscribe()
+	defer cancel()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprintf(w, ": connected\n\n")
+	flusher.Flush()
+
+	heartbeat := time.NewTicker(15 * time.Second)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-heartbeat.C:
+			fmt.Fprintf(w, ": ping\n\n")
+			flusher.Flush()
+		case event := <-events:
+			data, err := json.Marshal(event)
+			if err != nil {
+				continue
+			}
+			fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", event.ID, event.Type, data)
+			flusher.Flush()
+		}
+	}
+}
+
+func writeServeResult(w http.ResponseWriter, result interface{}) {
+	writeServeJSON(w, http.StatusOK, ServeResponse{Success: true, Result: result})
+}
+
+// maxServeBodyBytes JSON 请求体的大小上限
+const maxServeBodyBytes = 64 << 10
+
+// decodeServeBody 解析 JSON 请求体，超过 maxServeBodyBytes 时返回 413；失败时写入错误响应并返回 false
+func decodeServeBody(w http.ResponseWriter, r *http.Request, out any) bool {
+	r.Body = http.MaxBytesReader(w, r.Body, maxServeBodyBytes)
+	if err := json.NewDecoder(r.Body).Decode(out); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			writeServeError(w, http.StatusRequestEntityTooLarge, "body_too_large", fmt.Sprintf("请求体不能超过 %d 字节", tooLarge.Limit))
+			return false
+		}
+		writeServeError(w, http.StatusBadRequest, "invalid_body", fmt.Sprintf("无法解析请求体: %v", err))
+		return false
+	}
+	return true
+}
+
+func writeServeError(w http.ResponseWriter, status int, code, message string) {
+	writeServeJSON(w, status, ServeResponse{
+		Success: false,
+		Error:   &APIError{ErrorCode: code, ErrorMessage: message},
+	})
+}
+
+func writeServeJSON(w http.ResponseWriter, status int, response ServeResponse) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(response)
+}
+
+// isLoopbackListenAddr 判断监听地址是否仅限本机访问
+func isLoopbackListenAddr(addr string) bool {
+	if strings.HasPrefix(addr, "unix:") {
+		return true
+	}
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return false
+	}
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// runServe 以服务模式运行
+func runServe(config *Config) error {
+	server, err := NewAPIServer(config.Serve)
+	if err != nil {
+		return err
+	}
+
+	printHeader("iCloud 隐藏邮箱 API 服务")
+	printInfo(fmt.Sprintf("监听地址: %s", config.Serve.ListenAddr))
+	printInfo(fmt.Sprintf("已加载 %d 个 API Key", len(config.Serve.APIKeys)))
+	if !config.Serve.DisableDashboard {
+		printInfo("网页管理界面: 访问监听地址根路径 /")
+	}
+	switch {
+	case config.Serve.TLS.ClientCAFile != "":
+		printInfo("传输加密: 双向 TLS (需要客户端证书)")
+	case config.Serve.TLS.Enabled():
+		printInfo("传输加密: TLS")
+	case !isLoopbackListenAddr(config.Serve.ListenAddr):
+		printWarning("监听地址不是本机回环地址且未启用 TLS，API Key 将以明文传输")
+	}
+	if config.Serve.AuditWebhook.URL != "" {
+		printInfo(fmt.Sprintf("审计 Webhook: %s", config.Serve.AuditWebhook.URL))
+	}
+	// 空闲锁定后只能在终端输入口令解锁，非交互运行时不启用
+	if server.lock = newIdleLock(config.AppLock); server.lock != nil && !stdinIsTerminal() {
+		printWarning("标准输入不是终端，无法输入口令解锁，空闲锁定不启用")
+		server.lock = nil
+	}
+	if server.lock != nil {
+		printInfo(fmt.Sprintf("空闲锁定: %d 分钟无请求后锁定", config.AppLock.IdleTimeoutMinutes))
+		safetyManager.Go("idle-lock", func(ctx context.Context) {
+			server.watchIdleLock(ctx, config)
+		})
+	}
+
+	safetyManager.Go("serve-shutdown", func(ctx context.Context) {
+		<-ctx.Done()
+		server.Shutdown()
+	})
+
+	return server.ListenAndServe()
+}
+
+// watchIdleLock 定期检查空闲超时，锁定后在终端等待输入口令解锁
+func (s *APIServer) watchIdleLock(ctx context.Context, config *Config) {
+	ticker := time.NewTicker(15 * time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+		if !s.lock.Expired() {
+			continue
+		}
+
+		printWarning("服务空闲超时，已锁定，API 请求将返回 423")
+		for !verifyPassphrase(config.AppLock.PassphraseHash, readPassphrase("输入启动口令解锁服务: ")) {
+			if ctx.Err() != nil {
+				return
+			}
+			printError("口令错误")
+			time.Sleep(time.Second)
+		}
+		s.lock.Unlock()
+		printSuccess("服务已解锁")
+	}
+}
@@ -0,0 +1,195 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 服务模式下单个批量任务的最大数量
+const maxServeBatchCount = 50
+
+// ServeBatch 服务模式中的批量创建任务
+type ServeBatch struct {
+	ID          string   `json:"id"`
+	Actor       string   `json:"actor"`
+	LabelPrefix string   `json:"labelPrefix"`
+	Total       int      `json:"total"`
+	Completed   int      `json:"completed"`
+	Failed      int      `json:"failed"`
+	Emails      []string `json:"emails"`
+	Errors      []string `json:"errors"`
+	Done        bool     `json:"done"`
+	StartedAt   int64    `json:"startedAt"`
+	FinishedAt  int64    `json:"finishedAt,omitempty"`
+}
+
+// batchRegistry 记录服务运行期间的批量任务
+type batchRegistry struct {
+	mutex   sync.Mutex
+	batches map[string]*ServeBatch
+	running int
+}
+
+func newBatchRegistry() *batchRegistry {
+	return &batchRegistry{batches: make(map[string]*ServeBatch)}
+}
+
+// snapshot 复制任务状态，避免并发读写
+func (br *batchRegistry) snapshot(id string) (ServeBatch, bool) {
+	br.mutex.Lock()
+	defer br.mutex.Unlock()
+
+	batch, ok := br.batches[id]
+	if !ok {
+		return ServeBatch{}, false
+	}
+	copied := *batch
+	copied.Emails = append([]string(nil), batch.Emails...)
+	copied.Errors = append([]string(nil), batch.Errors...)
+	return copied, true
+}
+
+// handleCreateBatch 启动后台批量创建任务
+func (s *APIServer) handleCreateBatch(w http.ResponseWriter, r *http.Request, client *apiClient) {
+	var body struct {
+		Count         int    `json:"count"`
+		LabelPrefix   string `json:"label_prefix"`
+		LabelMode     string `json:"label_mode"`     // sequence（默认）、readable 或 template
+		LabelTemplate string `json:"label_template"` // template 模式的标签模板，默认使用配置中的 label_template
+	}
+	if !decodeServeBody(w, r, &body) {
+		return
+	}
+	if body.Count <= 0 || body.Count > maxServeBatchCount {
+		writeServeError(w, http.StatusBadRequest, "invalid_count", fmt.Sprintf("count 必须在 1-%d 之间", maxServeBatchCount))
+		return
+	}
+	var labelFor LabelFunc
+	switch body.LabelMode {
+	case "", "sequence":
+		if strings.TrimSpace(body.LabelPrefix) == "" {
+			body.LabelPrefix = "auto-"
+		}
+		labelFor = sequentialLabels(body.LabelPrefix)
+	case "readable":
+		labelFor = readableLabels(body.LabelPrefix, nil)
+	case "template":
+		if body.LabelTemplate == "" {
+			body.LabelTemplate = getCurrentConfig().LabelTemplate
+		}
+		generated, err := templateLabels(body.LabelTemplate)
+		if err != nil {
+			writeServeError(w, http.StatusBadRequest, "invalid_label_template", err.Error())
+			return
+		}
+		labelFor = generated
+		body.LabelPrefix = body.LabelTemplate
+	default:
+		writeServeError(w, http.StatusBadRequest, "invalid_label_mode", "label_mode 只能是 sequence、readable 或 template")
+		return
+	}
+
+	s.batches.mutex.Lock()
+	if s.batches.running > 0 {
+		s.batches.mutex.Unlock()
+		writeServeError(w, http.StatusConflict, "batch_running", "已有批量任务正在执行")
+		return
+	}
+	batch := &ServeBatch{
+		ID:          newEventID(),
+		Actor:       client.name,
+		LabelPrefix: body.LabelPrefix,
+		Total:       body.Count,
+		StartedAt:   time.Now().UnixMilli(),
+	}
+	s.batches.batches[batch.ID] = batch
+	s.batches.running++
+	s.batches.mutex.Unlock()
+
+	// 纳入 safetyManager，退出时等待当前一个创建完成并写入结果后再结束
+	remoteAddr := r.RemoteAddr
+	safetyManager.Go("api-batch", func(ctx context.Context) {
+		s.runBatch(ctx, batch, labelFor, client, remoteAddr)
+	})
+
+	snapshot, _ := s.batches.snapshot(batch.ID)
+	writeServeJSON(w, http.StatusAccepted, ServeResponse{Success: true, Result: snapshot})
+}
+
+// handleGetBatch 查询批量任务状态
+func (s *APIServer) handleGetBatch(w http.ResponseWriter, r *http.Request, client *apiClient) {
+	batch, ok := s.batches.snapshot(r.PathValue("id"))
+	if !ok {
+		writeServeError(w, http.StatusNotFound, "not_found", "批量任务不存在")
+		return
+	}
+	writeServeResult(w, batch)
+}
+
+// runBatch 串行执行批量创建并推送进度事件，ctx 取消（程序退出）时在两次创建之间停止
+func (s *APIServer) runBatch(ctx context.Context, batch *ServeBatch, labelFor LabelFunc, client *apiClient, remoteAddr string) {
+	defer func() {
+		s.batches.mutex.Lock()
+		batch.Done = true
+		batch.FinishedAt = time.Now().UnixMilli()
+		s.batches.running--
+		s.batches.mutex.Unlock()
+
+		snapshot, _ := s.batches.snapshot(batch.ID)
+		s.events.Publish(EventBatchCompleted, snapshot)
+	}()
+
+	gate := &rateLimitGate{}
+	for i := 0; i < batch.Total; i++ {
+		select {
+		case <-ctx.Done():
+			return
+		default:
+		}
+
+		config := getCurrentConfig()
+		label := labelFor(i + 1)
+		email, err := createHMEWithBackoff(config, label, gate, func(wait time.Duration) bool {
+			printWarning(fmt.Sprintf("批量任务 %s 被限流，%s 后重试 %s", batch.ID, wait.Round(time.Second), label))
+			return true
+		})
+		s.recordMutationFrom(client, remoteAddr, "create", AuditEvent{HME: email, Label: label}, err)
+
+		s.batches.mutex.Lock()
+		if err != nil {
+			batch.Failed++
+			batch.Errors = append(batch.Errors, fmt.Sprintf("%s: %v", label, err))
+		} else {
+			batch.Completed++
+			batch.Emails = append(batch.Emails, email)
+		}
+		completed, failed := batch.Completed, batch.Failed
+		s.batches.mutex.Unlock()
+
+		if err == nil {
+			s.emails.Invalidate()
+			if err := saveEmailToFile(config, email, label, CreationOrigin{Source: SourceAPI, Actor: client.name}); err != nil {
+				printWarning(fmt.Sprintf("保存邮箱到文件失败: %v", err))
+			}
+		}
+
+		s.events.Publish(EventBatchProgress, map[string]interface{}{
+			"id":        batch.ID,
+			"index":     i + 1,
+			"total":     batch.Total,
+			"label":     label,
+			"email":     email,
+			"success":   err == nil,
+			"completed": completed,
+			"failed":    failed,
+		})
+
+		if i < batch.Total-1 && config.DelaySeconds > 0 && !sleepUnlessCanceled(time.Duration(config.DelaySeconds)*time.Second) {
+			return
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestAuthenticate API Key 可以放在 X-API-Key 或 Authorization: Bearer 中，缺少 Bearer 前缀的 Authorization 不被接受
+func TestAuthenticate(t *testing.T) {
+	server, err := NewAPIServer(ServeConfig{
+		APIKeys:             []ServeAPIKey{{Name: "test", Key: "secret-key"}},
+		MaxConcurrent:       1,
+		GlobalMaxConcurrent: 1,
+		RateLimitPerMinute:  60,
+		RateLimitBurst:      1,
+	})
+	if err != nil {
+		t.Fatalf("创建服务失败: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		header string
+		value  string
+		ok     bool
+	}{
+		{"X-API-Key", "X-API-Key", "secret-key", true},
+		{"Bearer", "Authorization", "Bearer secret-key", true},
+		{"缺少 Bearer 前缀", "Authorization", "secret-key", false},
+		{"其他认证方式", "Authorization", "Basic secret-key", false},
+		{"错误的 Key", "Authorization", "Bearer secret-kex", false},
+		{"Key 的前缀", "X-API-Key", "secret", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/emails", nil)
+			req.Header.Set(tt.header, tt.value)
+			if got := server.authenticate(req) != nil; got != tt.ok {
+				t.Fatalf("认证结果 = %v，期望 %v", got, tt.ok)
+			}
+		})
+	}
+}
+
+// TestNewAPIServerRejectsNegativeConcurrency 负数的并发上限直接报配置错误，而不是在创建信号量时 panic
+func TestNewAPIServerRejectsNegativeConcurrency(t *testing.T) {
+	_, err := NewAPIServer(ServeConfig{
+		APIKeys:             []ServeAPIKey{{Name: "test", Key: "secret-key"}},
+		MaxConcurrent:       2,
+		GlobalMaxConcurrent: -1,
+	})
+	if exitCodeFor(err) != ExitConfig {
+		t.Fatalf("错误 = %v，期望配置错误", err)
+	}
+}
+
+// TestListenAddrKeepsRegularFile unix: 地址指向已存在的普通文件时报错，不会删除该文件
+func TestListenAddrKeepsRegularFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "notes.txt")
+	if err := os.WriteFile(path, []byte("keep"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if listener, err := listenAddr("unix:" + path); err == nil {
+		listener.Close()
+		t.Fatal("期望返回错误")
+	}
+	if data, err := os.ReadFile(path); err != nil || string(data) != "keep" {
+		t.Fatalf("文件被改动: %q %v", data, err)
+	}
+}
+
+// TestDecodeServeBodyLimit 超过大小上限的请求体返回 413
+func TestDecodeServeBodyLimit(t *testing.T) {
+	body := `{"label":"` + strings.Repeat("x", maxServeBodyBytes) + `"}`
+	req := httptest.NewRequest("POST", "/emails", strings.NewReader(body))
+	rec := httptest.NewRecorder()
+	var out struct {
+		Label string `json:"label"`
+	}
+	if decodeServeBody(rec, req, &out) {
+		t.Fatal("期望解析失败")
+	}
+	if rec.Code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("状态码 = %d，期望 %d", rec.Code, http.StatusRequestEntityTooLarge)
+	}
+}
@@ -0,0 +1,207 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"icloud-hme-generator/pkg/hme"
+)
+
+// 会话状态
+const (
+	SessionUnknown     = "unknown"     // 尚未确认
+	SessionValid       = "valid"       // Cookie 有效
+	SessionExpired     = "expired"     // Cookie 或会话已失效，需要重新登录抓包
+	SessionUnreachable = "unreachable" // 网络或服务器原因无法确认
+)
+
+// SessionStatus 最近一次确认的会话状态，doctor --json 时输出
+type SessionStatus struct {
+	State      string    `json:"state"`
+	HTTPStatus int       `json:"http_status,omitempty"` // 判定过期时的状态码
+	Emails     int       `json:"emails,omitempty"`      // 检查时账号中的邮箱数量
+	Error      string    `json:"error,omitempty"`
+	Advice     string    `json:"advice,omitempty"` // 无法确认时的处理建议
+	CheckedAt  time.Time `json:"checked_at"`
+}
+
+// SessionTracker 跟踪会话状态：启动检查、重新验证以及每个隐藏邮箱接口的响应都会更新它
+type SessionTracker struct {
+	mutex  sync.Mutex
+	status SessionStatus
+}
+
+// 全局会话状态
+var sessionTracker = &SessionTracker{status: SessionStatus{State: SessionUnknown}}
+
+// Observe 根据隐藏邮箱接口的响应状态码更新会话状态，其他状态码（如限流）不说明会话是否有效
+func (t *SessionTracker) Observe(code int) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	switch {
+	case hme.SessionExpiredStatus(code):
+		if t.status.State != SessionExpired {
+			logger().Error("iCloud 会话已过期", "http_status", code)
+		}
+		t.status = SessionStatus{State: SessionExpired, HTTPStatus: code, CheckedAt: time.Now()}
+	case code == 200:
+		if t.status.State != SessionValid {
+			t.status = SessionStatus{State: SessionValid}
+		}
+		t.status.CheckedAt = time.Now()
+	}
+}
+
+// Set 记录一次主动检查的结果
+func (t *SessionTracker) Set(status SessionStatus) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.status = status
+}
+
+// Status 当前会话状态
+func (t *SessionTracker) Status() SessionStatus {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	return t.status
+}
+
+// checkSession 用一次列表请求确认 Cookie 是否仍然有效
+func checkSession(config *Config) SessionStatus {
+	var emails []HMEEmail
+	err := withSpinner("检查 iCloud 会话", func() error {
+		var err error
+		emails, err = listHME(config)
+		return err
+	})
+
+	status := SessionStatus{State: SessionValid, Emails: len(emails), CheckedAt: time.Now()}
+	if err != nil {
+		status = SessionStatus{State: SessionUnreachable, Error: err.Error(), Advice: classifyFailure(err).Advice, CheckedAt: time.Now()}
+		var statusErr *APIStatusError
+		if errors.As(err, &statusErr) && statusErr.SessionExpired() {
+			status.State, status.HTTPStatus = SessionExpired, statusErr.StatusCode
+		} else if classifyFailure(err) == failureAuth {
+			status.State = SessionExpired
+		}
+		if status.State == SessionExpired {
+			status.Advice = ""
+		}
+	}
+	sessionTracker.Set(status)
+	return status
+}
+
+// printSessionProblem 会话过期或无法确认时给出具体的处理方法
+func printSessionProblem(status SessionStatus) {
+	switch status.State {
+	case SessionExpired:
+		reason := "Cookie 已失效"
+		if status.HTTPStatus != 0 {
+			reason = fmt.Sprintf("HTTP %d，Cookie 已失效", status.HTTPStatus)
+		}
+		printError(fmt.Sprintf("iCloud 会话已过期（%s），列表、创建等操作都会失败", reason))
+		fmt.Println("  在浏览器中重新登录 icloud.com 并打开“隐藏邮件地址”，然后任选一种方式更新 Cookie：")
+		fmt.Println("  " + ColorCyan + "·" + ColorReset + " 主菜单 " + ColorCyan + "[v]" + ColorReset + " 重新验证会话，粘贴 curl 命令或导入 HAR 文件")
+		fmt.Println("  " + ColorCyan + "·" + ColorReset + " 运行 " + ColorBold + "./icloud-hme import-curl" + ColorReset + " 或 " + ColorBold + "./icloud-hme import-har 文件.har" + ColorReset)
+		fmt.Println()
+	case SessionUnreachable:
+		printWarning(fmt.Sprintf("无法确认会话状态: %s", status.Error))
+		printInfo(status.Advice)
+	}
+}
+
+// sessionStatusLine 主菜单中的会话状态，尚未确认时返回空字符串
+func sessionStatusLine() string {
+	status := sessionTracker.Status()
+	var text string
+	switch status.State {
+	case SessionValid:
+		text = ColorGreen + "● 有效" + ColorReset + ColorDim + fmt.Sprintf(" (%s 确认)", status.CheckedAt.Format("15:04")) + ColorReset
+	case SessionExpired:
+		text = ColorRed + "● 已过期" + ColorReset + " 按 " + ColorCyan + "[v]" + ColorReset + " 更新 Cookie"
+	case SessionUnreachable:
+		text = ColorYellow + "● 无法确认" + ColorReset + ColorDim + " (网络或服务器错误)" + ColorReset
+	default:
+		return ""
+	}
+	return ColorCyan + "会话状态:" + ColorReset + " " + text
+}
+
+// startupSessionCheck 启动菜单时检查会话（skip_session_check 可关闭）；会话已过期时返回 false，
+// 调用方据此跳过启动时的自动重试等请求
+func startupSessionCheck(config *Config) bool {
+	if config.SkipSessionCheck {
+		return true
+	}
+	status := checkSession(config)
+	printSessionProblem(status)
+	return status.State != SessionExpired
+}
+
+// handleReverifySession 菜单中的“重新验证会话”：检查会话，过期时引导粘贴新的 curl 命令或导入 HAR 文件
+func handleReverifySession(config *Config) {
+	printHeader("重新验证会话")
+	status := checkSession(config)
+	if status.State == SessionValid {
+		printSuccess(fmt.Sprintf("会话有效，账号中共有 %d 个隐藏邮箱", status.Emails))
+		readInput(ColorGray + "(回车返回)" + ColorReset + " ")
+		return
+	}
+	printSessionProblem(status)
+
+	fmt.Println("  " + ColorCyan + "[1]" + ColorReset + " 粘贴 curl 命令更新账号")
+	fmt.Println("  " + ColorCyan + "[2]" + ColorReset + " 导入 HAR 文件更新账号")
+	fmt.Println("  " + ColorDim + "[0]" + ColorReset + " 返回")
+	switch strings.TrimSpace(readInput("选择 (0-2): ")) {
+	case "1":
+		handleImportCurl(config)
+	case "2":
+		handleImportHAR(config)
+	}
+}
+
+// runDoctor 命令行检查会话：doctor [-refresh]。-refresh 先调用 validate 接口刷新会话并写回 Cookie；
+// 会话过期时以认证失败的退出码（4）结束，可在定时任务前先行检查
+func runDoctor(config *Config, args []string) error {
+	fs := flag.NewFlagSet("doctor", flag.ContinueOnError)
+	refresh := fs.Bool("refresh", false, "先刷新会话令牌")
+	if err := fs.Parse(args); err != nil {
+		return usageError(err)
+	}
+	if fs.NArg() > 0 {
+		return usageError(fmt.Errorf("用法: doctor [-refresh]"))
+	}
+	printHeader("会话检查")
+	if *refresh {
+		if _, err := refreshSession(config); err != nil {
+			printWarning(err.Error())
+		} else {
+			printSuccess("会话已刷新")
+		}
+	}
+	status := checkSession(config)
+	if !outputJSON {
+		printInfo("自动刷新: " + formatSessionRefreshState(config))
+		printInfo("代理: " + describeProxy(config))
+	}
+	if outputJSON {
+		if err := writeJSON(status); err != nil {
+			return err
+		}
+	}
+	switch status.State {
+	case SessionValid:
+		printSuccess(fmt.Sprintf("会话有效，账号中共有 %d 个隐藏邮箱", status.Emails))
+		return nil
+	case SessionExpired:
+		printSessionProblem(status)
+		return withExitCode(ExitAuth, fmt.Errorf("iCloud 会话已过期"))
+	default:
+		return fmt.Errorf("无法确认会话状态: %s", status.Error)
+	}
+}
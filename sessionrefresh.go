@@ -0,0 +1,243 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"icloud-hme-generator/pkg/hme"
+)
+
+// SessionRefreshConfig 自动刷新会话：Cookie 临近过期时调用 iCloud 的 validate 接口换取轮换后的会话令牌，
+// 并把新的 Cookie 写回配置（使用 --profile 时写回该账号），长时间运行的批量任务不会中途因认证过期而失败
+type SessionRefreshConfig struct {
+	Enabled         bool   `json:"enabled"`
+	IntervalMinutes int    `json:"interval_minutes"` // 距上次刷新超过该时长，或距令牌过期不足该时长时刷新
+	ValidateURL     string `json:"validate_url"`     // 默认 https://setup.icloud.com/setup/ws/1/validate
+}
+
+// 默认的刷新间隔
+const defaultSessionRefreshMinutes = 30
+
+// 刷新失败后至少间隔这么久才再次尝试，避免每个请求都去调用 validate
+const sessionRefreshRetryDelay = time.Minute
+
+// 刷新记录写入时使用的临时文件名
+const sessionRefreshTempPattern = ".session-*.tmp"
+
+// SessionRefreshState 持久化的刷新记录，与冷却记录一样位于状态目录并按 dsid 区分，定时任务的每次运行共享
+type SessionRefreshState struct {
+	RefreshedAt time.Time `json:"refreshed_at"`
+	ExpiresAt   time.Time `json:"expires_at,omitempty"` // validate 响应中会话令牌最早的过期时间，未给出时为零值
+	LastError   string    `json:"last_error,omitempty"`
+	FailedAt    time.Time `json:"failed_at,omitempty"`
+}
+
+// cookieChain 同一 dsid 的 Cookie 轮换链：cookie 是最新的值，previous 是此前被它取代的各个值。
+// 正在运行的任务持有的是配置副本，副本中的 Cookie 只要在链上就改用最新值，经过多次轮换也不会用回旧令牌；
+// 不在链上的 Cookie 说明配置被重新导入过，照常使用
+type cookieChain struct {
+	previous map[string]bool
+	cookie   string
+	at       time.Time
+}
+
+// resolve 链上的 Cookie 对应的最新值
+func (ch *cookieChain) resolve(cookie string) (string, bool) {
+	if ch == nil || (cookie != ch.cookie && !ch.previous[cookie]) {
+		return "", false
+	}
+	return ch.cookie, true
+}
+
+var (
+	sessionRefreshMutex sync.Mutex                  // 同一时间只有一个刷新请求，并发的任务等待并共用结果
+	refreshedCookies    = map[string]*cookieChain{} // dsid → Cookie 轮换链
+)
+
+// sessionRefreshFile 刷新记录文件
+func sessionRefreshFile(config *Config) string {
+	name := "session.json"
+	if dsid := accountLockUnsafeChars.ReplaceAllString(config.DSID, "_"); dsid != "" {
+		name = "session-" + dsid + ".json"
+	}
+	return filepath.Join(stateDir(config), name)
+}
+
+// loadSessionRefreshState 读取刷新记录，不存在或无法读取时返回零值
+func loadSessionRefreshState(config *Config) SessionRefreshState {
+	var state SessionRefreshState
+	if data, err := os.ReadFile(sessionRefreshFile(config)); err == nil {
+		sessionRefreshFormat.decode(data, &state)
+	}
+	return state
+}
+
+// saveSessionRefreshState 写入刷新记录，失败只影响下次判断是否需要刷新
+func saveSessionRefreshState(config *Config, state SessionRefreshState) {
+	path := sessionRefreshFile(config)
+	if os.MkdirAll(filepath.Dir(path), 0700) == nil {
+		sessionRefreshFormat.write(path, sessionRefreshTempPattern, state)
+	}
+}
+
+// requestHeaders 请求使用的请求头：会话刷新过时用轮换链上最新的 Cookie 代替配置中的旧值
+func (c *Config) requestHeaders() map[string]string {
+	sessionRefreshMutex.Lock()
+	latest, ok := refreshedCookies[c.DSID].resolve(c.Headers["Cookie"])
+	sessionRefreshMutex.Unlock()
+	if !ok || latest == c.Headers["Cookie"] {
+		return c.Headers
+	}
+	headers := make(map[string]string, len(c.Headers))
+	for key, value := range c.Headers {
+		headers[key] = value
+	}
+	headers["Cookie"] = latest
+	return headers
+}
+
+// sessionRefresher 接口客户端遇到会话过期时调用的刷新函数，未启用自动刷新时返回 nil
+func (c *Config) sessionRefresher() func(ctx context.Context) (string, error) {
+	if !c.SessionRefresh.Enabled {
+		return nil
+	}
+	return func(ctx context.Context) (string, error) {
+		return refreshSession(c)
+	}
+}
+
+// refreshSession 调用 validate 接口刷新会话，合并轮换后的 Cookie、记录刷新时间并写回配置，返回新的 Cookie 请求头。
+// 其他任务刚刷新过时直接返回其结果
+func refreshSession(config *Config) (string, error) {
+	if cookie, ok := recentRefresh(config); ok {
+		return cookie, nil
+	}
+
+	// 刷新请求本身不持有 sessionRefreshMutex（requestHeaders 需要它），用单独的锁串行化
+	sessionRefreshSerial.Lock()
+	defer sessionRefreshSerial.Unlock()
+	if cookie, ok := recentRefresh(config); ok {
+		return cookie, nil
+	}
+
+	client := config.hmeClient()
+	client.RefreshSession = nil
+	current := client.Headers["Cookie"]
+	cookies, err := client.Validate(apiContext(), config.SessionRefresh.ValidateURL)
+	state := loadSessionRefreshState(config)
+	if err != nil {
+		state.LastError, state.FailedAt = err.Error(), time.Now()
+		saveSessionRefreshState(config, state)
+		var status *APIStatusError
+		if errors.As(err, &status) && status.SessionExpired() {
+			return "", fmt.Errorf("会话已失效，无法自动刷新，请重新登录后更新 Cookie: %w", err)
+		}
+		return "", fmt.Errorf("刷新会话失败: %w", err)
+	}
+
+	merged, changed := hme.MergeCookies(current, cookies)
+	sessionRefreshMutex.Lock()
+	chain := refreshedCookies[config.DSID]
+	if _, ok := chain.resolve(config.Headers["Cookie"]); !ok {
+		// 配置被重新导入后从新的 Cookie 开始一条新链
+		chain = &cookieChain{previous: map[string]bool{config.Headers["Cookie"]: true}}
+		refreshedCookies[config.DSID] = chain
+	}
+	chain.previous[current] = true
+	delete(chain.previous, merged)
+	chain.cookie, chain.at = merged, time.Now()
+	sessionRefreshMutex.Unlock()
+	saveSessionRefreshState(config, SessionRefreshState{RefreshedAt: time.Now(), ExpiresAt: hme.EarliestExpiry(cookies)})
+
+	persistRefreshedCookie(config)
+	if len(changed) > 0 {
+		printInfo(fmt.Sprintf("已刷新 iCloud 会话，更新了 %d 个 Cookie: %s", len(changed), strings.Join(changed, ", ")))
+	}
+	return merged, nil
+}
+
+// 串行化 validate 请求
+var sessionRefreshSerial sync.Mutex
+
+// recentRefresh 其他任务在 sessionRefreshRetryDelay 内刚刷新过同一条轮换链时直接用其结果
+func recentRefresh(config *Config) (string, bool) {
+	sessionRefreshMutex.Lock()
+	defer sessionRefreshMutex.Unlock()
+	chain := refreshedCookies[config.DSID]
+	cookie, ok := chain.resolve(config.Headers["Cookie"])
+	if !ok || time.Since(chain.at) >= sessionRefreshRetryDelay {
+		return "", false
+	}
+	return cookie, true
+}
+
+// persistRefreshedCookie 把轮换链上最新的 Cookie 写回配置文件：配置中的 Cookie 是链上任一旧值时都会更新；
+// 期间账号已切换或 Cookie 已被重新导入时不覆盖
+func persistRefreshedCookie(config *Config) {
+	current := getCurrentConfig()
+	if current == nil || current.DSID != config.DSID || current.profile != config.profile {
+		return
+	}
+	sessionRefreshMutex.Lock()
+	cookie, ok := refreshedCookies[config.DSID].resolve(current.Headers["Cookie"])
+	sessionRefreshMutex.Unlock()
+	if !ok || cookie == current.Headers["Cookie"] {
+		return
+	}
+	updated := current.clone()
+	updated.Headers["Cookie"] = cookie
+	configMutex.Lock()
+	globalConfig = updated
+	configMutex.Unlock()
+	if err := configManager.SaveConfig(updated); err != nil {
+		printWarning(fmt.Sprintf("刷新后的 Cookie 写回配置失败: %v", err))
+	}
+}
+
+// sessionRefreshDue 是否需要主动刷新：从未刷新过、距上次刷新超过 interval_minutes，或令牌即将过期；
+// 上次失败后 sessionRefreshRetryDelay 内不再尝试
+func sessionRefreshDue(config *Config, state SessionRefreshState) bool {
+	if time.Since(state.FailedAt) < sessionRefreshRetryDelay {
+		return false
+	}
+	interval := time.Duration(config.SessionRefresh.IntervalMinutes) * time.Minute
+	if state.RefreshedAt.IsZero() || time.Since(state.RefreshedAt) >= interval {
+		return true
+	}
+	return !state.ExpiresAt.IsZero() && time.Until(state.ExpiresAt) < interval
+}
+
+// maybeRefreshSession 启用自动刷新且临近过期时刷新会话；失败只提示，请求照常发送（过期时还会再被动刷新一次）
+func maybeRefreshSession(config *Config) {
+	if !config.SessionRefresh.Enabled || !sessionRefreshDue(config, loadSessionRefreshState(config)) {
+		return
+	}
+	if _, err := refreshSession(config); err != nil {
+		printWarning(err.Error())
+	}
+}
+
+// formatSessionRefreshState 刷新记录的说明，用于 doctor
+func formatSessionRefreshState(config *Config) string {
+	if !config.SessionRefresh.Enabled {
+		return "未启用（session_refresh.enabled）"
+	}
+	state := loadSessionRefreshState(config)
+	text := "尚未刷新"
+	if !state.RefreshedAt.IsZero() {
+		text = "上次刷新 " + state.RefreshedAt.Format("2006-01-02 15:04")
+	}
+	if !state.ExpiresAt.IsZero() {
+		text += "，令牌 " + state.ExpiresAt.Format("2006-01-02 15:04") + " 过期"
+	}
+	if state.LastError != "" && state.FailedAt.After(state.RefreshedAt) {
+		text += "，最近一次失败: " + state.LastError
+	}
+	return text
+}
@@ -0,0 +1,308 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"net/url"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// 预留给新服务注册的邮箱标记：提前创建并加上此标记（如 ledger tag 邮箱 pool），注册时优先取用
+const signupPoolTag = "pool"
+
+// 注册流程等待验证邮件的默认时长与检查间隔
+const (
+	defaultSignupWait     = 5 * time.Minute
+	signupWaitInterval    = 5 * time.Second
+	signupLookbackForPool = time.Minute // 取自预留池时，同时检查流程开始前不久到达的邮件
+)
+
+// signupOptions 新服务注册流程的选项
+type signupOptions struct {
+	Service string
+	URL     string // 注册页地址，可为空
+	UsePool bool   // 预留池中有邮箱时优先取用
+	Open    bool   // 在浏览器中打开注册页
+	Wait    bool   // 通过 IMAP 等待验证邮件
+	Timeout time.Duration
+}
+
+// signupResult 注册流程的结果，命令行 --json 时输出
+type signupResult struct {
+	Email    string `json:"email"`
+	Service  string `json:"service"`
+	Site     string `json:"site"`
+	FromPool bool   `json:"from_pool"`
+	Copied   bool   `json:"copied"`
+	Opened   bool   `json:"opened"`
+	Verified bool   `json:"verified"`
+}
+
+// normalizeSignupURL 补全注册页地址的协议，返回地址与用于台账的网站名（主机名，不含 www.）
+func normalizeSignupURL(raw string) (string, string, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return "", "", nil
+	}
+	if !strings.Contains(raw, "://") {
+		raw = "https://" + raw
+	}
+	parsed, err := url.Parse(raw)
+	if err != nil || parsed.Host == "" || (parsed.Scheme != "http" && parsed.Scheme != "https") {
+		return "", "", fmt.Errorf("无效的网址: %s", raw)
+	}
+	return parsed.String(), strings.TrimPrefix(strings.ToLower(parsed.Hostname()), "www."), nil
+}
+
+// copyToClipboard 复制文本到系统剪贴板（macOS pbcopy、Windows clip，Linux 依次尝试 wl-copy、xclip、xsel）
+func copyToClipboard(text string) error {
+	var candidates [][]string
+	switch runtime.GOOS {
+	case "darwin":
+		candidates = [][]string{{"pbcopy"}}
+	case "windows":
+		candidates = [][]string{{"clip"}}
+	default:
+		if os.Getenv("WAYLAND_DISPLAY") != "" {
+			candidates = append(candidates, []string{"wl-copy"})
+		}
+		candidates = append(candidates, []string{"xclip", "-selection", "clipboard"}, []string{"xsel", "--clipboard", "--input"})
+	}
+	for _, args := range candidates {
+		if _, err := exec.LookPath(args[0]); err != nil {
+			continue
+		}
+		cmd := exec.Command(args[0], args[1:]...)
+		cmd.Stdin = strings.NewReader(text)
+		if err := cmd.Run(); err != nil {
+			return fmt.Errorf("%s 执行失败: %v", args[0], err)
+		}
+		return nil
+	}
+	return fmt.Errorf("未找到剪贴板工具")
+}
+
+// openURL 用系统默认浏览器打开网址
+func openURL(target string) error {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		cmd = exec.Command("open", target)
+	case "windows":
+		cmd = exec.Command("rundll32", "url.dll,FileProtocolHandler", target)
+	default:
+		cmd = exec.Command("xdg-open", target)
+	}
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("无法打开浏览器: %v", err)
+	}
+	go cmd.Wait() // 回收子进程，不等待浏览器退出
+	return nil
+}
+
+// provisionSignupAlias 为服务准备邮箱：优先取用预留池中的邮箱，否则以服务名为标签创建新邮箱
+func provisionSignupAlias(config *Config, opts signupOptions, site string) (email string, fromPool bool, err error) {
+	origin := CreationOrigin{Source: SourceSignup}
+	if opts.UsePool {
+		record, ok, err := inventory.ClaimPooled(signupPoolTag, func(r *InventoryRecord) {
+			r.Site = site
+			applyTags(r, nil, []string{signupPoolTag})
+			r.Events = append(r.Events, InventoryEvent{Type: InventoryEventSignup, At: time.Now().UnixMilli(), Source: origin.Source})
+		})
+		if err != nil {
+			return "", false, err
+		}
+		if ok {
+			printInfo(fmt.Sprintf("使用预留邮箱 %s (原标签: %s，剩余 %d 个)", record.HME, orDash(record.Label), inventory.PoolSize(signupPoolTag)))
+			return record.HME, true, nil
+		}
+	}
+
+	if err := checkCooldown(config); err != nil {
+		return "", false, err
+	}
+	if err := withSpinner("创建邮箱", func() error {
+		var err error
+		email, err = createHME(config, opts.Service)
+		return err
+	}); err != nil {
+		return "", false, fmt.Errorf("创建失败: %w", err)
+	}
+	if err := saveEmailToFile(config, email, opts.Service, origin); err != nil {
+		printWarning(fmt.Sprintf("保存邮箱到文件失败: %v", err))
+	}
+	if _, err := inventory.Annotate(email, func(r *InventoryRecord) {
+		r.Site = site
+		r.Events = append(r.Events, InventoryEvent{Type: InventoryEventSignup, At: time.Now().UnixMilli(), Source: origin.Source})
+	}); err != nil {
+		printWarning(fmt.Sprintf("记录使用网站失败: %v", err))
+	}
+	printSuccess(fmt.Sprintf("已创建 %s (%s)", email, opts.Service))
+	return email, false, nil
+}
+
+// runSignupFlow 新服务注册：准备邮箱、复制到剪贴板、打开注册页、等待验证邮件，并把每一步记录到本地台账
+func runSignupFlow(config *Config, opts signupOptions) (signupResult, error) {
+	target, site, err := normalizeSignupURL(opts.URL)
+	if err != nil {
+		return signupResult{}, err
+	}
+	if site == "" {
+		site = opts.Service
+	}
+	started := time.Now()
+
+	email, fromPool, err := provisionSignupAlias(config, opts, site)
+	if err != nil {
+		return signupResult{}, err
+	}
+	result := signupResult{Email: email, Service: opts.Service, Site: site, FromPool: fromPool}
+
+	fmt.Printf("\n  "+ColorBrightMagenta+"@ 邮箱: "+ColorReset+ColorBold+ColorBrightWhite+"%s"+ColorReset+"\n", email)
+	fmt.Printf("  "+ColorBrightBlue+"# 网站: "+ColorReset+ColorCyan+"%s"+ColorReset+"\n\n", site)
+
+	if err := copyToClipboard(email); err != nil {
+		printWarning(fmt.Sprintf("复制到剪贴板失败: %v，请手动复制上面的邮箱", err))
+	} else {
+		result.Copied = true
+		printSuccess("邮箱已复制到剪贴板")
+	}
+
+	if target != "" && opts.Open {
+		if err := openURL(target); err != nil {
+			printWarning(fmt.Sprintf("%v，请手动访问 %s", err, target))
+		} else {
+			result.Opened = true
+			printSuccess("已在浏览器中打开 " + target)
+		}
+	}
+
+	if !opts.Wait {
+		return result, nil
+	}
+	if !config.IMAP.Enabled() {
+		printInfo("未配置 imap，跳过等待验证邮件")
+		return result, nil
+	}
+	since := started
+	if fromPool {
+		since = started.Add(-signupLookbackForPool)
+	}
+	fmt.Println()
+	if err := waitForVerificationMail(config.IMAP, email, since, opts.Timeout, signupWaitInterval); err != nil {
+		return result, err
+	}
+	if operationCanceled() {
+		return result, nil
+	}
+	result.Verified = true
+	if err := inventory.RecordEvent(HMEEmail{HME: email}, InventoryEventVerified, CreationOrigin{Source: SourceSignup}); err != nil {
+		printWarning(fmt.Sprintf("记录验证事件失败: %v", err))
+	}
+	return result, nil
+}
+
+// handleSignup 菜单中的新服务注册向导
+func handleSignup(config *Config) {
+	printHeader("新服务注册")
+
+	service := strings.TrimSpace(readInput("服务名称 " + ColorGray + "(作为新邮箱的标签，如 Netflix)" + ColorReset + ": "))
+	if service == "" {
+		printError("服务名称不能为空")
+		return
+	}
+	opts := signupOptions{Service: service, Timeout: defaultSignupWait}
+	opts.URL = readInput("注册页网址 " + ColorGray + "(可选，回车跳过)" + ColorReset + ": ")
+	if _, _, err := normalizeSignupURL(opts.URL); err != nil {
+		printError(err.Error())
+		return
+	}
+
+	if n := inventory.PoolSize(signupPoolTag); n > 0 {
+		opts.UsePool = confirmAction(fmt.Sprintf("预留池中有 %d 个邮箱，使用其中最早的一个（否则新建）", n))
+	}
+	if !opts.UsePool && !waitForCooldown(config) {
+		return
+	}
+	if strings.TrimSpace(opts.URL) != "" {
+		opts.Open = confirmAction("在浏览器中打开注册页")
+	}
+	opts.Wait = config.IMAP.Enabled()
+
+	result, err := runSignupFlow(config, opts)
+	if err != nil {
+		printError(err.Error())
+		if result.Email == "" {
+			return
+		}
+	}
+	printSignupSummary(result)
+	readInput(ColorGray + "(回车返回)" + ColorReset + " ")
+}
+
+// printSignupSummary 显示注册流程各步骤的结果
+func printSignupSummary(result signupResult) {
+	step := func(done bool, text string) {
+		mark := ColorBrightGreen + "✓" + ColorReset
+		if !done {
+			mark = ColorDim + "-" + ColorReset
+		}
+		fmt.Printf("  %s %s\n", mark, text)
+	}
+	printSubHeader("注册记录")
+	if result.FromPool {
+		step(true, "取用预留邮箱 "+result.Email)
+	} else {
+		step(true, "新建邮箱 "+result.Email)
+	}
+	step(true, "台账已记录使用网站 "+result.Site)
+	step(result.Copied, "复制到剪贴板")
+	step(result.Opened, "打开注册页")
+	step(result.Verified, "收到验证邮件")
+	fmt.Println()
+}
+
+// runSignup 命令行新服务注册：signup [-url 网址] [-new] [-open] [-no-wait] [-timeout 秒] 服务名
+func runSignup(config *Config, args []string) error {
+	fs := flag.NewFlagSet("signup", flag.ContinueOnError)
+	target := fs.String("url", "", "注册页网址，记录其主机名为使用网站")
+	fresh := fs.Bool("new", false, "总是新建邮箱，不使用预留池")
+	open := fs.Bool("open", false, "在浏览器中打开注册页")
+	noWait := fs.Bool("no-wait", false, "不等待验证邮件")
+	timeout := fs.Int("timeout", int(defaultSignupWait/time.Second), "等待验证邮件的最长时间（秒）")
+	if err := fs.Parse(args); err != nil {
+		return usageError(err)
+	}
+	service := strings.TrimSpace(strings.Join(fs.Args(), " "))
+	if service == "" {
+		return usageError(fmt.Errorf("用法: signup [-url 网址] [-new] [-open] [-no-wait] [-timeout 秒] 服务名"))
+	}
+	if *timeout <= 0 {
+		return usageError(fmt.Errorf("-timeout 必须大于 0"))
+	}
+
+	printHeader("新服务注册")
+	result, err := runSignupFlow(config, signupOptions{
+		Service: service,
+		URL:     *target,
+		UsePool: !*fresh,
+		Open:    *open,
+		Wait:    !*noWait,
+		Timeout: time.Duration(*timeout) * time.Second,
+	})
+	if result.Email == "" {
+		return err
+	}
+	if outputJSON {
+		if jsonErr := writeJSON(result); jsonErr != nil {
+			return jsonErr
+		}
+	} else {
+		printSignupSummary(result)
+	}
+	return err
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"strings"
+)
+
+// 与已有邮箱相似的候选的处理方式（email_quality.similar_action）
+const (
+	SimilarActionReject   = "reject"   // 直接丢弃并重新生成
+	SimilarActionPenalize = "penalize" // 保留但大幅扣分
+)
+
+// 默认的最小编辑距离：前缀相同或只差一个字符的候选视为与已有邮箱相似
+const defaultSimilarDistance = 2
+
+// penalize 模式下相似候选扣除的分数
+const similarAliasPenalty = 40
+
+// aliasPrefixes 本地清单中仍存在（激活或停用）的邮箱前缀，清单在每次获取列表时与 iCloud 同步
+func aliasPrefixes() []string {
+	var prefixes []string
+	for _, record := range inventory.Search("") {
+		if record.IsTombstone() {
+			continue
+		}
+		prefixes = append(prefixes, strings.ToLower(emailPrefix(record.HME)))
+	}
+	return prefixes
+}
+
+// emailPrefix 地址中 @ 之前的部分
+func emailPrefix(email string) string {
+	if i := strings.LastIndex(email, "@"); i >= 0 {
+		return email[:i]
+	}
+	return email
+}
+
+// closestAlias 与 prefix 编辑距离小于 limit 的最相近的已有前缀及其距离，没有时返回空字符串
+func closestAlias(prefixes []string, prefix string, limit int) (string, int) {
+	prefix = strings.ToLower(prefix)
+	best, bestDistance := "", limit
+	for _, existing := range prefixes {
+		if d := levenshtein(prefix, existing, bestDistance); d < bestDistance {
+			best, bestDistance = existing, d
+			if d == 0 {
+				break
+			}
+		}
+	}
+	return best, bestDistance
+}
+
+// levenshtein 两个字符串的编辑距离；超过 limit 时提前结束并返回 limit
+func levenshtein(a, b string, limit int) int {
+	if diff := len(a) - len(b); diff >= limit || -diff >= limit {
+		return limit
+	}
+	previous := make([]int, len(b)+1)
+	current := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		current[0] = i
+		rowMin := current[0]
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+			rowMin = min(rowMin, current[j])
+		}
+		if rowMin >= limit {
+			return limit
+		}
+		previous, current = current, previous
+	}
+	return min(previous[len(b)], limit)
+}
@@ -0,0 +1,452 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// soak 的默认节奏：每 10 秒创建一个，每分钟清理并重连事件流，每 30 秒采样一次
+const (
+	defaultSoakDuration     = time.Hour
+	defaultSoakCreateEvery  = 10 * time.Second
+	defaultSoakCleanupEvery = time.Minute
+	defaultSoakSampleEvery  = 30 * time.Second
+	defaultSoakKeep         = 20
+	soakLabelPrefix         = "soak-"
+)
+
+// 判定资源泄漏的默认阈值，与第一次清理后的基线比较
+const (
+	defaultSoakGoroutineSlack = 20
+	defaultSoakFDSlack        = 10
+	defaultSoakHeapGrowth     = 100     // 百分比
+	soakHeapFloor             = 4 << 20 // 堆增长不足 4 MiB 时不判定
+)
+
+// SoakSample 一次资源采样（采样前先执行 GC，堆大小为存活对象）
+type SoakSample struct {
+	ElapsedSeconds int64  `json:"elapsed_seconds"`
+	Goroutines     int    `json:"goroutines"`
+	HeapBytes      uint64 `json:"heap_bytes"`
+	SysBytes       uint64 `json:"sys_bytes"`
+	OpenFiles      int    `json:"open_files"` // -1 表示当前系统无法统计
+	Created        int64  `json:"created"`
+	Failed         int64  `json:"failed"`
+	Deleted        int64  `json:"deleted"`
+	Events         int64  `json:"events"`
+}
+
+// SoakReport soak 结束时的报告，--json 时输出
+type SoakReport struct {
+	MockURL  string       `json:"mock_url"`
+	Duration string       `json:"duration"`
+	Faults   string       `json:"faults"`
+	Baseline SoakSample   `json:"baseline"`
+	Final    SoakSample   `json:"final"`
+	Peak     SoakSample   `json:"peak"` // 各项分别取最大值
+	Samples  []SoakSample `json:"samples"`
+	Leaks    []string     `json:"leaks"`
+}
+
+// soakCounters 驱动过程中的累计计数
+type soakCounters struct {
+	created, failed, deleted, events atomic.Int64
+}
+
+// openFileCount 当前进程打开的文件描述符数（Linux 读 /proc/self/fd，macOS 读 /dev/fd），无法统计时返回 -1
+func openFileCount() int {
+	for _, dir := range []string{"/proc/self/fd", "/dev/fd"} {
+		if entries, err := os.ReadDir(dir); err == nil {
+			return len(entries)
+		}
+	}
+	return -1
+}
+
+// takeSoakSample 采集协程数、堆与文件描述符
+func takeSoakSample(started time.Time, counters *soakCounters) SoakSample {
+	runtime.GC()
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+	return SoakSample{
+		ElapsedSeconds: int64(time.Since(started).Seconds()),
+		Goroutines:     runtime.NumGoroutine(),
+		HeapBytes:      mem.HeapAlloc,
+		SysBytes:       mem.Sys,
+		OpenFiles:      openFileCount(),
+		Created:        counters.created.Load(),
+		Failed:         counters.failed.Load(),
+		Deleted:        counters.deleted.Load(),
+		Events:         counters.events.Load(),
+	}
+}
+
+// formatMiB 以 MiB 显示字节数
+func formatMiB(n uint64) string {
+	return fmt.Sprintf("%.1f MiB", float64(n)/(1<<20))
+}
+
+// printSoakSample 输出一行采样
+func printSoakSample(sample SoakSample) {
+	files := "-"
+	if sample.OpenFiles >= 0 {
+		files = fmt.Sprintf("%d", sample.OpenFiles)
+	}
+	fmt.Printf("  "+ColorDim+"[%8s]"+ColorReset+" 协程 %-4d 堆 %-9s 文件 %-4s 创建 %d (失败 %d)  删除 %d  事件 %d\n",
+		(time.Duration(sample.ElapsedSeconds) * time.Second).String(), sample.Goroutines, formatMiB(sample.HeapBytes),
+		files, sample.Created, sample.Failed, sample.Deleted, sample.Events)
+}
+
+// soakClient 通过本地 REST API 驱动服务模式，与真实的调用方走同一条路径
+type soakClient struct {
+	base string
+	key  string
+	http *http.Client
+}
+
+// do 发送请求并解析服务模式的统一响应体，result 可为 nil
+func (c *soakClient) do(ctx context.Context, method, path string, body, result interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.base+path, reader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("X-API-Key", c.key)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	var response struct {
+		Success bool            `json:"success"`
+		Result  json.RawMessage `json:"result"`
+		Error   *APIError       `json:"error"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		return fmt.Errorf("%s %s: 无法解析响应 (HTTP %d): %v", method, path, resp.StatusCode, err)
+	}
+	if !response.Success {
+		if response.Error != nil {
+			return fmt.Errorf("%s %s: %s", method, path, response.Error.ErrorMessage)
+		}
+		return fmt.Errorf("%s %s: HTTP %d", method, path, resp.StatusCode)
+	}
+	if result != nil {
+		return json.Unmarshal(response.Result, result)
+	}
+	return nil
+}
+
+// streamEvents 订阅事件流直到 ctx 取消，统计收到的事件
+func (c *soakClient) streamEvents(ctx context.Context, counters *soakCounters) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.base+"/events", nil)
+	if err != nil {
+		return
+	}
+	req.Header.Set("X-API-Key", c.key)
+	// 事件流是长连接，不受普通请求的超时限制
+	resp, err := (&http.Client{Transport: c.http.Transport}).Do(req)
+	if err != nil {
+		return
+	}
+	defer resp.Body.Close()
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		if strings.HasPrefix(scanner.Text(), "event:") {
+			counters.events.Add(1)
+		}
+	}
+}
+
+// soakCleanup 保留最新的 keep 个 soak 邮箱，其余先停用再删除
+func soakCleanup(ctx context.Context, client *soakClient, keep int, counters *soakCounters) {
+	var emails []HMEEmail
+	if err := client.do(ctx, http.MethodGet, "/emails?max_age=0", nil, &emails); err != nil {
+		printWarning(fmt.Sprintf("清理时获取列表失败: %v", err))
+		return
+	}
+	var ours []HMEEmail
+	for _, email := range emails {
+		if strings.HasPrefix(email.Label, soakLabelPrefix) {
+			ours = append(ours, email)
+		}
+	}
+	sort.Slice(ours, func(i, j int) bool { return ours[i].CreateTimestamp > ours[j].CreateTimestamp })
+	for i, email := range ours {
+		if i < keep && email.IsActive {
+			continue
+		}
+		if email.IsActive {
+			if err := client.do(ctx, http.MethodPost, "/emails/"+email.AnonymousID+"/deactivate", nil, nil); err != nil {
+				counters.failed.Add(1)
+				continue
+			}
+		}
+		if err := client.do(ctx, http.MethodDelete, "/emails/"+email.AnonymousID, nil, nil); err != nil {
+			counters.failed.Add(1)
+			continue
+		}
+		counters.deleted.Add(1)
+	}
+}
+
+// soakLeaks 比较基线与最终采样，超过阈值的项视为疑似泄漏
+func soakLeaks(baseline, final SoakSample, goroutineSlack, fdSlack, heapGrowth int) []string {
+	var leaks []string
+	if final.Goroutines-baseline.Goroutines > goroutineSlack {
+		leaks = append(leaks, fmt.Sprintf("协程 %d → %d (+%d)", baseline.Goroutines, final.Goroutines, final.Goroutines-baseline.Goroutines))
+	}
+	if baseline.OpenFiles >= 0 && final.OpenFiles-baseline.OpenFiles > fdSlack {
+		leaks = append(leaks, fmt.Sprintf("文件描述符 %d → %d (+%d)", baseline.OpenFiles, final.OpenFiles, final.OpenFiles-baseline.OpenFiles))
+	}
+	if final.HeapBytes > baseline.HeapBytes+soakHeapFloor && final.HeapBytes > baseline.HeapBytes*uint64(100+heapGrowth)/100 {
+		leaks = append(leaks, fmt.Sprintf("堆 %s → %s", formatMiB(baseline.HeapBytes), formatMiB(final.HeapBytes)))
+	}
+	return leaks
+}
+
+// runSoak 长时间稳定性测试：在进程内启动模拟服务器与服务模式，按计划创建、清理邮箱并重连事件流，
+// 定期采样协程数、内存与文件描述符，结束时与基线比较以发现后台路径中的泄漏。
+// 全程只请求模拟服务器，清单、重试队列与冷却记录写入临时目录，不触碰真实账号
+func runSoak(config *Config, args []string) error {
+	settings := config.Developer.MockServer
+	faults := settings.Faults
+	fs := flag.NewFlagSet("soak", flag.ContinueOnError)
+	duration := fs.Duration("duration", defaultSoakDuration, "运行时长，如 30m、4h")
+	createEvery := fs.Duration("create-every", defaultSoakCreateEvery, "创建邮箱的间隔")
+	cleanupEvery := fs.Duration("cleanup-every", defaultSoakCleanupEvery, "清理旧邮箱并重连事件流的间隔")
+	sampleEvery := fs.Duration("sample-every", defaultSoakSampleEvery, "资源采样的间隔")
+	keep := fs.Int("keep", defaultSoakKeep, "清理时保留的最新邮箱数")
+	goroutineSlack := fs.Int("goroutine-slack", defaultSoakGoroutineSlack, "协程数比基线多出该值时判定为泄漏")
+	fdSlack := fs.Int("fd-slack", defaultSoakFDSlack, "文件描述符比基线多出该值时判定为泄漏")
+	heapGrowth := fs.Int("heap-growth", defaultSoakHeapGrowth, "堆比基线增长超过该百分比时判定为泄漏")
+	parseFaults := mockFaultFlags(fs, &faults)
+	if err := fs.Parse(args); err != nil {
+		return usageError(err)
+	}
+	if err := parseFaults(); err != nil {
+		return usageError(err)
+	}
+	if *createEvery <= 0 || *cleanupEvery <= 0 || *sampleEvery <= 0 {
+		return usageError(fmt.Errorf("间隔必须大于 0"))
+	}
+	if *keep < 0 || *goroutineSlack < 0 || *fdSlack < 0 || *heapGrowth < 0 {
+		return usageError(fmt.Errorf("-keep 与各项阈值不能为负数"))
+	}
+	if *duration <= *cleanupEvery {
+		return usageError(fmt.Errorf("-duration 必须大于 -cleanup-every，基线在第一次清理后采集"))
+	}
+
+	dir, err := os.MkdirTemp("", "icloud-hme-soak-*")
+	if err != nil {
+		return fmt.Errorf("创建临时目录失败: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	// 模拟服务器
+	mockListener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return fmt.Errorf("启动模拟服务器失败: %v", err)
+	}
+	mock := newMockServer(faults)
+	mock.quiet = true
+	mockServer := &http.Server{Handler: mock.handler(), ReadHeaderTimeout: 10 * time.Second}
+	go mockServer.Serve(mockListener)
+	defer mockServer.Close()
+
+	// 指向模拟服务器的配置：本进程内所有路径（服务模式、清单、重试、冷却）都使用它
+	soakConfig := config.clone()
+	soakConfig.BaseURL = "http://" + mockListener.Addr().String() + "/v1/hme/reserve"
+	soakConfig.DSID = "soak"
+	soakConfig.StateDir = dir
+	soakConfig.InventoryFile = filepath.Join(dir, "inventory.db")
+	soakConfig.RetryQueueFile = filepath.Join(dir, "retry_queue.json")
+	soakConfig.BatchJobFile = filepath.Join(dir, "batch_job.json")
+	soakConfig.EmailListFile = filepath.Join(dir, "generated_emails.txt")
+	soakConfig.SaveGeneratedEmails = true
+	soakConfig.Notifications = NotificationsConfig{}
+	soakConfig.SoundCues = SoundCuesConfig{}
+	apiKey := newEventID()
+	soakConfig.Serve = ServeConfig{
+		RateLimitPerMinute:  6000, // 服务模式自身的限流不是测试对象，放宽到不会触发
+		RateLimitBurst:      100,
+		MaxConcurrent:       4,
+		GlobalMaxConcurrent: 4,
+		CacheTTLSeconds:     5,
+		APIKeys:             []ServeAPIKey{{Name: "soak", Key: apiKey}},
+		DisableDashboard:    true,
+		IdempotentCreate:    true,
+	}
+	configMutex.Lock()
+	globalConfig = soakConfig
+	configMutex.Unlock()
+	inv, err := OpenInventory(soakConfig.InventoryFile)
+	if err != nil {
+		return fmt.Errorf("打开临时清单失败: %v", err)
+	}
+	inventory = inv
+
+	// 服务模式
+	server, err := NewAPIServer(soakConfig.Serve)
+	if err != nil {
+		return err
+	}
+	apiListener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return fmt.Errorf("启动服务模式失败: %v", err)
+	}
+	go server.Serve(apiListener)
+	defer server.Shutdown()
+
+	transport := &http.Transport{MaxIdleConnsPerHost: 4}
+	defer transport.CloseIdleConnections()
+	client := &soakClient{
+		base: "http://" + apiListener.Addr().String(),
+		key:  apiKey,
+		http: &http.Client{Transport: transport, Timeout: 2 * time.Minute},
+	}
+
+	printHeader("长时间稳定性测试")
+	printInfo(fmt.Sprintf("模拟服务器: %s", soakConfig.BaseURL))
+	printInfo(fmt.Sprintf("服务模式: %s", client.base))
+	printInfo("故障注入: " + faults.Describe())
+	printInfo(fmt.Sprintf("时长 %s，每 %s 创建一个，每 %s 清理（保留 %d 个）并重连事件流，每 %s 采样",
+		*duration, *createEvery, *cleanupEvery, *keep, *sampleEvery))
+	printInfo("基线在第一次清理后采集，Ctrl+C 提前结束并输出报告")
+	fmt.Println()
+
+	ctx := apiContext()
+	var counters soakCounters
+	var streams sync.WaitGroup
+	stopStream := func() {}
+	startStream := func() {
+		stopStream()
+		streamCtx, cancel := context.WithCancel(ctx)
+		streams.Add(1)
+		go func() {
+			defer streams.Done()
+			client.streamEvents(streamCtx, &counters)
+		}()
+		stopStream = cancel
+	}
+
+	started := time.Now()
+	report := SoakReport{MockURL: soakConfig.BaseURL, Faults: faults.Describe()}
+	haveBaseline := false
+	record := func(sample SoakSample) {
+		report.Samples = append(report.Samples, sample)
+		printSoakSample(sample)
+		if !haveBaseline && time.Duration(sample.ElapsedSeconds)*time.Second >= *cleanupEvery {
+			report.Baseline = sample
+			haveBaseline = true
+		}
+	}
+
+	startStream()
+	record(takeSoakSample(started, &counters))
+	createTicker := time.NewTicker(*createEvery)
+	cleanupTicker := time.NewTicker(*cleanupEvery)
+	sampleTicker := time.NewTicker(*sampleEvery)
+	defer createTicker.Stop()
+	defer cleanupTicker.Stop()
+	defer sampleTicker.Stop()
+	deadline := time.NewTimer(*duration)
+	defer deadline.Stop()
+
+	sequence := 0
+loop:
+	for {
+		select {
+		case <-ctx.Done():
+			break loop
+		case <-deadline.C:
+			break loop
+		case <-createTicker.C:
+			sequence++
+			body := map[string]string{"label": fmt.Sprintf("%s%d", soakLabelPrefix, sequence)}
+			if err := client.do(ctx, http.MethodPost, "/emails", body, nil); err != nil {
+				counters.failed.Add(1)
+			} else {
+				counters.created.Add(1)
+			}
+			client.do(ctx, http.MethodGet, "/emails", nil, nil)
+		case <-cleanupTicker.C:
+			soakCleanup(ctx, client, *keep, &counters)
+			startStream()
+		case <-sampleTicker.C:
+			record(takeSoakSample(started, &counters))
+		}
+	}
+
+	report.Final = takeSoakSample(started, &counters)
+	stopStream()
+	streams.Wait()
+	report.Duration = time.Since(started).Round(time.Second).String()
+	if !haveBaseline {
+		report.Baseline = report.Final
+	}
+	report.Peak = report.Final
+	for _, sample := range report.Samples {
+		report.Peak.Goroutines = max(report.Peak.Goroutines, sample.Goroutines)
+		report.Peak.HeapBytes = max(report.Peak.HeapBytes, sample.HeapBytes)
+		report.Peak.SysBytes = max(report.Peak.SysBytes, sample.SysBytes)
+		report.Peak.OpenFiles = max(report.Peak.OpenFiles, sample.OpenFiles)
+	}
+	report.Leaks = soakLeaks(report.Baseline, report.Final, *goroutineSlack, *fdSlack, *heapGrowth)
+
+	printSubHeader("稳定性报告")
+	fmt.Printf("  运行 %s，创建 %d 个（失败 %d），删除 %d 个，收到事件 %d 条\n",
+		report.Duration, report.Final.Created, report.Final.Failed, report.Final.Deleted, report.Final.Events)
+	for _, row := range []struct {
+		name   string
+		sample SoakSample
+	}{{"基线", report.Baseline}, {"峰值", report.Peak}, {"结束", report.Final}} {
+		fmt.Printf("  %s  协程 %-4d 堆 %-9s 文件 %d\n", row.name, row.sample.Goroutines, formatMiB(row.sample.HeapBytes), row.sample.OpenFiles)
+	}
+	mock.printSummary()
+	fmt.Println()
+
+	if outputJSON {
+		if err := writeJSON(report); err != nil {
+			return err
+		}
+	}
+	if len(report.Leaks) > 0 {
+		for _, leak := range report.Leaks {
+			printWarning(leak)
+		}
+		return fmt.Errorf("疑似资源泄漏: %s", strings.Join(report.Leaks, "；"))
+	}
+	if !haveBaseline {
+		printWarning("运行时间不足一个清理周期，未能采集基线")
+		return nil
+	}
+	printSuccess("未发现资源泄漏")
+	return nil
+}
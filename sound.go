@@ -0,0 +1,116 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"sync"
+	"time"
+)
+
+// 提示音事件
+const (
+	SoundBatchDone      = "batch_done"       // 批量创建结束
+	SoundRateLimitStart = "rate_limit_start" // 被限流，开始暂停
+	SoundRateLimitEnd   = "rate_limit_end"   // 限流暂停结束，继续创建
+	SoundError          = "error"            // 命令失败或批量创建全部失败
+)
+
+// 同一事件在该间隔内只提示一次，避免并发创建时多个请求同时被限流连续响铃
+const soundCueDebounce = 3 * time.Second
+
+// SoundCuesConfig 终端提示音（sound_cues），便于在后台终端运行批量任务时留意进度。
+// 各事件的值为响铃次数，0 表示不提示
+type SoundCuesConfig struct {
+	BatchDone      int    `json:"batch_done"`
+	RateLimitStart int    `json:"rate_limit_start"`
+	RateLimitEnd   int    `json:"rate_limit_end"`
+	Error          int    `json:"error"`
+	Command        string `json:"command"` // 设置后以该命令代替终端响铃，如 afplay /System/Library/Sounds/Glass.aiff；事件名通过 HME_SOUND_EVENT 传入
+}
+
+// count 事件对应的响铃次数
+func (c SoundCuesConfig) count(event string) int {
+	switch event {
+	case SoundBatchDone:
+		return c.BatchDone
+	case SoundRateLimitStart:
+		return c.RateLimitStart
+	case SoundRateLimitEnd:
+		return c.RateLimitEnd
+	case SoundError:
+		return c.Error
+	}
+	return 0
+}
+
+var (
+	soundCueMutex sync.Mutex
+	lastSoundCue  = make(map[string]time.Time)
+	soundCueWait  sync.WaitGroup // 子命令退出前等待提示音播放完
+)
+
+// soundCue 按配置为事件发出提示音，不阻塞调用方
+func soundCue(config *Config, event string) {
+	if config == nil {
+		return
+	}
+	times := config.SoundCues.count(event)
+	if times <= 0 {
+		return
+	}
+	soundCueMutex.Lock()
+	if time.Since(lastSoundCue[event]) < soundCueDebounce {
+		soundCueMutex.Unlock()
+		return
+	}
+	lastSoundCue[event] = time.Now()
+	soundCueMutex.Unlock()
+
+	command := config.SoundCues.Command
+	soundCueWait.Add(1)
+	go func() {
+		defer soundCueWait.Done()
+		for i := 0; i < times; i++ {
+			if i > 0 {
+				time.Sleep(300 * time.Millisecond)
+			}
+			if command != "" {
+				if err := runSoundCommand(command, event); err != nil {
+					logger().Warn("提示音命令执行失败", "error", err)
+					fmt.Fprintf(os.Stderr, ColorYellow+"[!] 提示音命令执行失败: %v"+ColorReset+"\n", err)
+					return
+				}
+				continue
+			}
+			ringBell()
+		}
+	}()
+}
+
+// 提示音命令的最长执行时间，超时后结束进程，避免卡住的播放器让提示音协程一直挂起
+const soundCommandTimeout = 10 * time.Second
+
+// runSoundCommand 通过 shell 执行提示音命令
+func runSoundCommand(command, event string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), soundCommandTimeout)
+	defer cancel()
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.CommandContext(ctx, "cmd", "/C", command)
+	} else {
+		cmd = exec.CommandContext(ctx, "sh", "-c", command)
+	}
+	cmd.Env = append(os.Environ(), "HME_SOUND_EVENT="+event)
+	return cmd.Run()
+}
+
+// ringBell 向终端输出响铃符；标准错误被重定向到文件时不输出，以免写入日志
+func ringBell() {
+	if info, err := os.Stderr.Stat(); err != nil || info.Mode()&os.ModeCharDevice == 0 {
+		return
+	}
+	fmt.Fprint(os.Stderr, "\a")
+}
@@ -0,0 +1,354 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+)
+
+// stateFormat 本地状态文件（清单、批量任务、重试队列、统计、冷却）的格式版本。
+// 文件写成 {"format", "version", "min_reader", "written_by", "data"}，data 为原来的内容；
+// 没有这层包装的旧文件视为版本 0
+type stateFormat struct {
+	Name    string // 写入文件的格式名
+	Title   string // 提示中使用的名称
+	Version int    // 当前程序写入的版本
+	// MinReader 当前写入的文件至少需要支持到哪个版本的程序才能读取：只新增可忽略的字段时保持不变，
+	// 旧程序仍可读取；删除字段或改变已有字段含义时提高到 Version
+	MinReader int
+	// Migrations 按版本逐级升级：Migrations[n] 把版本 n 的 data 转换为版本 n+1
+	Migrations map[int]func(data json.RawMessage) (json.RawMessage, error)
+}
+
+// stateEnvelope 版本化状态文件的外层结构
+type stateEnvelope struct {
+	Format    string          `json:"format"`
+	Version   int             `json:"version"`
+	MinReader int             `json:"min_reader"`
+	WrittenBy string          `json:"written_by,omitempty"` // 写入文件的程序版本
+	Data      json.RawMessage `json:"data"`
+}
+
+// unwrapLegacy 旧文件（版本 0）就是 data 本身，升级到版本 1 只需加上外层结构
+func unwrapLegacy(data json.RawMessage) (json.RawMessage, error) {
+	return data, nil
+}
+
+// 各状态文件的格式
+var (
+	inventoryFormat = &stateFormat{Name: "hme-inventory", Title: "本地清单", Version: 1, MinReader: 1,
+		Migrations: map[int]func(json.RawMessage) (json.RawMessage, error){0: unwrapLegacy}}
+	batchJobFormat = &stateFormat{Name: "hme-batch-job", Title: "批量任务", Version: 1, MinReader: 1,
+		Migrations: map[int]func(json.RawMessage) (json.RawMessage, error){0: unwrapLegacy}}
+	retryQueueFormat = &stateFormat{Name: "hme-retry-queue", Title: "重试队列", Version: 1, MinReader: 1,
+		Migrations: map[int]func(json.RawMessage) (json.RawMessage, error){0: unwrapLegacy}}
+	errorStatsFormat = &stateFormat{Name: "hme-error-stats", Title: "创建统计", Version: 1, MinReader: 1,
+		Migrations: map[int]func(json.RawMessage) (json.RawMessage, error){0: unwrapLegacy}}
+	cooldownFormat = &stateFormat{Name: "hme-cooldown", Title: "限流冷却", Version: 1, MinReader: 1,
+		Migrations: map[int]func(json.RawMessage) (json.RawMessage, error){0: unwrapLegacy}}
+	sessionRefreshFormat = &stateFormat{Name: "hme-session-refresh", Title: "会话刷新", Version: 1, MinReader: 1}
+	daemonJobsFormat     = &stateFormat{Name: "hme-daemon-jobs", Title: "守护进程任务", Version: 1, MinReader: 1}
+)
+
+// FormatTooNewError 文件由更新的程序写入，且声明当前程序无法正确读取
+type FormatTooNewError struct {
+	Title     string
+	Version   int
+	MinReader int
+	Supported int
+}
+
+func (e *FormatTooNewError) Error() string {
+	return fmt.Sprintf("%s由更新版本的程序写入（格式版本 %d，需要支持到版本 %d），当前程序只支持到版本 %d，请升级程序",
+		e.Title, e.Version, e.MinReader, e.Supported)
+}
+
+// 已提示过“由更新版本写入”的格式，每次运行只提示一次
+var newerFormatWarned sync.Map
+
+// envelope 读取外层结构；没有外层结构的旧文件返回版本 0，data 为整个文件
+func (f *stateFormat) envelope(data []byte) (stateEnvelope, error) {
+	var env stateEnvelope
+	// 旧的清单与重试队列是 JSON 数组，无法解析为对象
+	if err := json.Unmarshal(data, &env); err != nil || env.Format == "" {
+		if !json.Valid(data) {
+			return env, fmt.Errorf("不是有效的 JSON")
+		}
+		return stateEnvelope{Data: data}, nil
+	}
+	if env.Format != f.Name {
+		return env, fmt.Errorf("不是%s文件（格式为 %s）", f.Title, env.Format)
+	}
+	return env, nil
+}
+
+// decode 解析状态文件到 v：旧版本逐级升级；更新版本写入但声明兼容时照常读取（当前程序不认识的字段会在下次保存时丢失）
+func (f *stateFormat) decode(data []byte, v interface{}) error {
+	env, err := f.envelope(data)
+	if err != nil {
+		return err
+	}
+	if env.MinReader > f.Version {
+		return &FormatTooNewError{Title: f.Title, Version: env.Version, MinReader: env.MinReader, Supported: f.Version}
+	}
+	if env.Version > f.Version {
+		if _, warned := newerFormatWarned.LoadOrStore(f.Name, true); !warned {
+			printWarning(fmt.Sprintf("%s由更新版本的程序写入（格式版本 %d），将以版本 %d 读取和保存", f.Title, env.Version, f.Version))
+		}
+	}
+	payload := env.Data
+	for version := env.Version; version < f.Version; version++ {
+		migrate := f.Migrations[version]
+		if migrate == nil {
+			return fmt.Errorf("不支持从%s格式版本 %d 升级", f.Title, version)
+		}
+		if payload, err = migrate(payload); err != nil {
+			return fmt.Errorf("%s从格式版本 %d 升级失败: %v", f.Title, version, err)
+		}
+	}
+	return json.Unmarshal(payload, v)
+}
+
+// encode 以当前版本写出状态文件
+func (f *stateFormat) encode(v interface{}) ([]byte, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return json.MarshalIndent(stateEnvelope{
+		Format:    f.Name,
+		Version:   f.Version,
+		MinReader: f.MinReader,
+		WrittenBy: VERSION,
+		Data:      data,
+	}, "", "  ")
+}
+
+// checkWritable 写入前确认现有文件不是更新的程序写入的不兼容格式，避免把它覆盖成当前程序的旧格式
+func (f *stateFormat) checkWritable(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+	env, err := f.envelope(data)
+	if err == nil && env.MinReader > f.Version {
+		return &FormatTooNewError{Title: f.Title, Version: env.Version, MinReader: env.MinReader, Supported: f.Version}
+	}
+	return nil
+}
+
+// write 检查兼容性后以当前版本原子写入状态文件
+func (f *stateFormat) write(path, tmpPattern string, v interface{}) error {
+	if err := f.checkWritable(path); err != nil {
+		return err
+	}
+	data, err := f.encode(v)
+	if err != nil {
+		return fmt.Errorf("序列化%s失败: %v", f.Title, err)
+	}
+	return writeFileAtomic(path, tmpPattern, data)
+}
+
+// 状态文件的格式检查结果
+const (
+	StateFileCurrent  = "current"  // 当前版本
+	StateFileOutdated = "outdated" // 旧版本，可迁移
+	StateFileNewer    = "newer"    // 更新版本写入，兼容读取
+	StateFileTooNew   = "too-new"  // 更新版本写入，当前程序无法读取
+	StateFileInvalid  = "invalid"  // 无法解析
+	StateFileMigrated = "migrated" // 已迁移到当前版本
+)
+
+// StateFileStatus 一个状态文件的格式检查结果
+type StateFileStatus struct {
+	Path      string `json:"path"`
+	Title     string `json:"title"`
+	Format    string `json:"format"`
+	Version   int    `json:"version"`
+	Supported int    `json:"supported"`
+	Status    string `json:"status"`
+	Error     string `json:"error,omitempty"`
+
+	format     *stateFormat
+	tmpPattern string
+}
+
+// stateFiles 当前账号的全部状态文件（仅包含实际存在的文件）
+func stateFiles(config *Config) []StateFileStatus {
+	candidates := []StateFileStatus{
+		{Path: config.BatchJobFile, format: batchJobFormat, tmpPattern: batchJobTempPattern},
+		{Path: config.RetryQueueFile, format: retryQueueFormat, tmpPattern: retryQueueTempPattern},
+		{Path: errorStatsFile(config), format: errorStatsFormat, tmpPattern: errorStatsTempPattern},
+	}
+	// 任务队列按 dsid 区分，各账号的都检查；另行指定了 daemon.jobs_file 时也包含在内
+	jobs, _ := filepath.Glob(filepath.Join(stateDir(config), "daemon-jobs*.json"))
+	sort.Strings(jobs)
+	if path := daemonJobsFile(config); !containsString(jobs, path) {
+		jobs = append(jobs, path)
+	}
+	for _, path := range jobs {
+		candidates = append(candidates, StateFileStatus{Path: path, format: daemonJobsFormat, tmpPattern: daemonJobsTempPattern})
+	}
+	// 冷却记录按 dsid 区分，各账号的都检查
+	cooldowns, _ := filepath.Glob(filepath.Join(stateDir(config), "cooldown*.json"))
+	sort.Strings(cooldowns)
+	for _, path := range cooldowns {
+		candidates = append(candidates, StateFileStatus{Path: path, format: cooldownFormat, tmpPattern: cooldownTempPattern})
+	}
+	sessions, _ := filepath.Glob(filepath.Join(stateDir(config), "session*.json"))
+	sort.Strings(sessions)
+	for _, path := range sessions {
+		candidates = append(candidates, StateFileStatus{Path: path, format: sessionRefreshFormat, tmpPattern: sessionRefreshTempPattern})
+	}
+
+	var files []StateFileStatus
+	for _, file := range candidates {
+		if file.Path == "" {
+			continue
+		}
+		if info, err := os.Stat(file.Path); err != nil || !info.Mode().IsRegular() {
+			continue
+		}
+		file.Title, file.Format, file.Supported = file.format.Title, file.format.Name, file.format.Version
+		files = append(files, file)
+	}
+	return files
+}
+
+// inspect 检查文件的格式版本
+func (s *StateFileStatus) inspect() {
+	data, err := os.ReadFile(s.Path)
+	if err != nil {
+		s.Status, s.Error = StateFileInvalid, err.Error()
+		return
+	}
+	env, err := s.format.envelope(data)
+	s.Version = env.Version
+	switch {
+	case err != nil:
+		s.Status, s.Error = StateFileInvalid, err.Error()
+	case env.MinReader > s.Supported:
+		s.Status = StateFileTooNew
+	case env.Version > s.Supported:
+		s.Status = StateFileNewer
+	case env.Version < s.Supported:
+		s.Status = StateFileOutdated
+	default:
+		s.Status = StateFileCurrent
+	}
+}
+
+// migrate 把旧版本的文件升级为当前版本；原文件保留为 .bak
+func (s *StateFileStatus) migrate() error {
+	data, err := os.ReadFile(s.Path)
+	if err != nil {
+		return err
+	}
+	var payload json.RawMessage
+	if err := s.format.decode(data, &payload); err != nil {
+		return err
+	}
+	return s.format.write(s.Path, s.tmpPattern, payload)
+}
+
+// formatStateStatus 格式检查结果的显示文字
+func formatStateStatus(s StateFileStatus) string {
+	switch s.Status {
+	case StateFileCurrent:
+		return ColorGreen + "最新" + ColorReset
+	case StateFileOutdated:
+		if s.Version == 0 {
+			return ColorYellow + "旧格式（无版本号），可迁移" + ColorReset
+		}
+		return ColorYellow + "旧版本，可迁移" + ColorReset
+	case StateFileMigrated:
+		return ColorGreen + "已迁移" + ColorReset
+	case StateFileNewer:
+		return ColorCyan + "由更新版本写入，兼容读取" + ColorReset
+	case StateFileTooNew:
+		return ColorRed + "由更新版本写入，请升级程序" + ColorReset
+	default:
+		return ColorRed + "无法解析: " + s.Error + ColorReset
+	}
+}
+
+// runDataFormat 检查并迁移本地状态文件的格式版本：data-format [-check]。
+// 旧文件在读取时会自动升级、下次保存时写成新格式；此命令用于一次性迁移全部文件，
+// -check 只检查，有需要迁移或无法读取的文件时以退出码 6 结束，便于升级前在脚本中确认
+func runDataFormat(config *Config, args []string) error {
+	fs := flag.NewFlagSet("data-format", flag.ContinueOnError)
+	check := fs.Bool("check", false, "只检查，不迁移")
+	if err := fs.Parse(args); err != nil {
+		return usageError(err)
+	}
+	if fs.NArg() > 0 {
+		return usageError(fmt.Errorf("用法: data-format [-check]"))
+	}
+
+	files := stateFiles(config)
+	var pending, failed int
+	for i := range files {
+		file := &files[i]
+		file.inspect()
+		if file.Status == StateFileOutdated && !*check {
+			if err := file.migrate(); err != nil {
+				file.Status, file.Error = StateFileInvalid, err.Error()
+			} else {
+				file.Status, file.Version = StateFileMigrated, file.Supported
+			}
+		}
+		switch file.Status {
+		case StateFileOutdated:
+			pending++
+		case StateFileInvalid, StateFileTooNew:
+			failed++
+		}
+	}
+
+	if outputJSON {
+		if files == nil {
+			files = []StateFileStatus{}
+		}
+		if err := writeJSON(files); err != nil {
+			return err
+		}
+	} else {
+		printHeader("本地数据格式")
+		if len(files) == 0 {
+			printInfo("没有本地状态文件")
+		}
+		for _, file := range files {
+			fmt.Printf("  %s  v%d/%d  %s\n", file.Title, file.Version, file.Supported, formatStateStatus(file))
+			fmt.Printf("  "+ColorGray+"%s"+ColorReset+"\n", file.Path)
+		}
+		fmt.Println()
+	}
+
+	switch {
+	case failed > 0:
+		return withExitCode(ExitPartial, fmt.Errorf("%d 个文件无法读取或需要更新的程序", failed))
+	case pending > 0:
+		return withExitCode(ExitPartial, fmt.Errorf("%d 个文件需要迁移，运行 data-format 迁移", pending))
+	}
+	if !outputJSON {
+		printSuccess("全部本地状态文件均可由当前版本读取")
+	}
+	return nil
+}
+
+// validator 供 readFileRecovering 校验文件完整性：由更新的程序写入的文件是完整的，
+// 不能因为当前程序读不了就用旧的备份覆盖它
+func (f *stateFormat) validator(v func() interface{}) func([]byte) error {
+	return func(data []byte) error {
+		var tooNew *FormatTooNewError
+		if err := f.decode(data, v()); err != nil && !errors.As(err, &tooNew) {
+			return err
+		}
+		return nil
+	}
+}
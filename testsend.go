@@ -0,0 +1,184 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"flag"
+	"fmt"
+	"net"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SMTPConfig 发送测试邮件使用的 SMTP 配置
+type SMTPConfig struct {
+	Host     string `json:"host"`     // 如 smtp.gmail.com，为空表示不启用
+	Port     int    `json:"port"`     // 默认 587
+	Username string `json:"username"` // 登录用户名，为空表示不认证
+	Password string `json:"password"` // 密码或应用专用密码
+	From     string `json:"from"`     // 发件地址，默认与用户名相同
+	Security string `json:"security"` // starttls（默认）、tls（465 端口）或 none
+}
+
+// Enabled 是否配置了 SMTP
+func (c SMTPConfig) Enabled() bool {
+	return c.Host != ""
+}
+
+// sendMail 通过 SMTP 向 to 发送一封纯文本邮件，ctx 的截止时间同时限制整个会话
+func sendMail(ctx context.Context, settings SMTPConfig, to, subject, body string) error {
+	if !settings.Enabled() {
+		return configError("未配置 SMTP，请在 config.json 的 smtp 中填写服务器与账号")
+	}
+	port := settings.Port
+	if port == 0 {
+		port = 587
+	}
+	from := settings.From
+	if from == "" {
+		from = settings.Username
+	}
+	if !strings.Contains(from, "@") {
+		return fmt.Errorf("发件地址无效，请设置 smtp.from")
+	}
+
+	address := net.JoinHostPort(settings.Host, strconv.Itoa(port))
+	tlsConfig := &tls.Config{ServerName: settings.Host}
+
+	var conn net.Conn
+	var err error
+	dialer := &net.Dialer{Timeout: 30 * time.Second}
+	if settings.Security == "tls" {
+		conn, err = (&tls.Dialer{NetDialer: dialer, Config: tlsConfig}).DialContext(ctx, "tcp", address)
+	} else {
+		conn, err = dialer.DialContext(ctx, "tcp", address)
+	}
+	if err != nil {
+		return fmt.Errorf("连接 SMTP 服务器失败: %v", err)
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	client, err := smtp.NewClient(conn, settings.Host)
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("SMTP 握手失败: %v", err)
+	}
+	defer client.Close()
+
+	if settings.Security == "" || settings.Security == "starttls" {
+		if ok, _ := client.Extension("STARTTLS"); !ok {
+			return fmt.Errorf("SMTP 服务器不支持 STARTTLS")
+		}
+		if err := client.StartTLS(tlsConfig); err != nil {
+			return fmt.Errorf("STARTTLS 失败: %v", err)
+		}
+	}
+	if settings.Username != "" {
+		if err := client.Auth(smtp.PlainAuth("", settings.Username, settings.Password, settings.Host)); err != nil {
+			return fmt.Errorf("SMTP 认证失败: %v", err)
+		}
+	}
+
+	if err := client.Mail(from); err != nil {
+		return fmt.Errorf("SMTP 发件人被拒绝: %v", err)
+	}
+	if err := client.Rcpt(to); err != nil {
+		return fmt.Errorf("SMTP 收件人被拒绝: %v", err)
+	}
+	writer, err := client.Data()
+	if err != nil {
+		return fmt.Errorf("SMTP 发送失败: %v", err)
+	}
+	message := fmt.Sprintf("From: %s\r\nTo: %s\r\nSubject: %s\r\nDate: %s\r\nMIME-Version: 1.0\r\nContent-Type: text/plain; charset=utf-8\r\n\r\n%s\r\n",
+		from, to, subject, time.Now().Format(time.RFC1123Z), strings.ReplaceAll(body, "\n", "\r\n"))
+	if _, err := writer.Write([]byte(message)); err != nil {
+		writer.Close()
+		return fmt.Errorf("SMTP 发送失败: %v", err)
+	}
+	if err := writer.Close(); err != nil {
+		return fmt.Errorf("SMTP 发送失败: %v", err)
+	}
+	return client.Quit()
+}
+
+// waitForSubject 轮询 IMAP 直到出现主题包含 token 的邮件，返回其到达时间
+func waitForSubject(settings IMAPConfig, token string, since time.Time, timeout, interval time.Duration) (time.Time, error) {
+	deadline := time.Now().Add(timeout)
+	for {
+		client, err := dialIMAP(settings, 30*time.Second)
+		if err != nil {
+			return time.Time{}, err
+		}
+		uids, err := client.SearchSubject(token, since, 30*time.Second)
+		if err == nil && len(uids) > 0 {
+			dates, dateErr := client.FetchDates(uids[:1], 30*time.Second)
+			client.Close()
+			if dateErr == nil {
+				if arrived, ok := dates[uids[0]]; ok {
+					return arrived, nil
+				}
+			}
+			return time.Now(), nil
+		}
+		client.Close()
+		if err != nil {
+			return time.Time{}, fmt.Errorf("搜索邮件失败: %v", err)
+		}
+
+		if time.Now().After(deadline) {
+			return time.Time{}, fmt.Errorf("%s 内未收到测试邮件，转发可能已中断或被归入垃圾邮件", timeout)
+		}
+		select {
+		case <-time.After(interval):
+		case <-safetyManager.Context().Done():
+			return time.Time{}, fmt.Errorf("已取消")
+		}
+	}
+}
+
+// runTestSend 向隐藏邮箱发送测试邮件并确认转发到达
+func runTestSend(config *Config, args []string) error {
+	fs := flag.NewFlagSet("test-send", flag.ContinueOnError)
+	timeout := fs.Int("timeout", 180, "最长等待时间（秒）")
+	interval := fs.Int("interval", 5, "检查间隔（秒）")
+	if err := fs.Parse(args); err != nil {
+		return usageError(err)
+	}
+	if fs.NArg() != 1 || !strings.Contains(fs.Arg(0), "@") {
+		return fmt.Errorf("用法: test-send [-timeout 秒] [-interval 秒] 邮箱地址")
+	}
+	if !config.IMAP.Enabled() {
+		return configError("未配置 IMAP，无法确认邮件到达")
+	}
+	alias := fs.Arg(0)
+
+	printHeader("转发测试")
+	token := "hme-test-" + newEventID()[:12]
+	sentAt := time.Now()
+	if err := withSpinner("发送测试邮件", func() error {
+		return sendMail(safetyManager.Context(), config.SMTP, alias, "[icloud-hme] 转发测试 "+token,
+			fmt.Sprintf("这是一封由 icloud-hme 发送的转发测试邮件。\n\n标识: %s\n发送时间: %s", token, sentAt.Format(time.RFC3339)))
+	}); err != nil {
+		return err
+	}
+
+	printInfo(fmt.Sprintf("等待转发到达 (最长 %d 秒)", *timeout))
+	arrivedAt, err := waitForSubject(config.IMAP, token, sentAt,
+		time.Duration(*timeout)*time.Second, time.Duration(*interval)*time.Second)
+	if err != nil {
+		return err
+	}
+	observedAt := time.Now()
+
+	printSuccess(fmt.Sprintf("%s 转发正常", alias))
+	// INTERNALDATE 精确到秒且依赖服务器时钟，可能略早于发送时间
+	if rtt := arrivedAt.Sub(sentAt); rtt > 0 {
+		fmt.Printf("  "+ColorCyan+"投递耗时:"+ColorReset+" %s "+ColorDim+"(按收件服务器记录的到达时间)"+ColorReset+"\n", rtt.Round(time.Second))
+	}
+	fmt.Printf("  "+ColorCyan+"往返耗时:"+ColorReset+" %s "+ColorDim+"(发送到本工具检测到，含轮询间隔)"+ColorReset+"\n", observedAt.Sub(sentAt).Round(time.Second))
+	return nil
+}
@@ -0,0 +1,182 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"net/mail"
+	"sort"
+	"time"
+)
+
+// TimelineEntry 邮箱活动时间线中的一个节点
+type TimelineEntry struct {
+	At     time.Time
+	Title  string
+	Detail string
+	Color  string
+}
+
+// buildAliasTimeline 汇总 iCloud 元数据、本地清单和 IMAP 来信统计，生成邮箱的活动时间线
+func buildAliasTimeline(config *Config, email HMEEmail) ([]TimelineEntry, error) {
+	var entries []TimelineEntry
+
+	record, hasRecord := inventory.Get(email.HME)
+	if email.CreateTimestamp > 0 || hasRecord {
+		createdAt := email.CreateTimestamp
+		if createdAt == 0 {
+			createdAt = record.CreatedAt
+		}
+		detail := ""
+		if hasRecord {
+			detail = "来源: " + formatOrigin(record.Origin())
+		}
+		entries = append(entries, TimelineEntry{At: time.UnixMilli(createdAt), Title: "创建", Detail: detail, Color: ColorBrightGreen})
+	}
+
+	if hasRecord {
+		for _, event := range record.Events {
+			entry := TimelineEntry{At: time.UnixMilli(event.At)}
+			switch event.Type {
+			case InventoryEventDeactivated:
+				entry.Title, entry.Color = "停用", ColorYellow
+			case InventoryEventReactivated:
+				entry.Title, entry.Color = "重新激活", ColorGreen
+			case InventoryEventSignup:
+				entry.Title, entry.Color = "用于注册", ColorBrightBlue
+			case InventoryEventVerified:
+				entry.Title, entry.Color = "收到验证邮件", ColorBrightGreen
+			default:
+				entry.Title = event.Type
+			}
+			if event.Detected {
+				entry.Detail = "在列表同步时发现（可能在 Apple 设置中操作）"
+			} else if event.Source != "" {
+				entry.Detail = "操作: " + formatOrigin(CreationOrigin{Source: event.Source, Actor: event.Actor})
+			}
+			entries = append(entries, entry)
+		}
+		if record.IsTombstone() {
+			entries = append(entries, TimelineEntry{At: time.UnixMilli(record.DeletedAt), Title: "彻底删除", Color: ColorRed})
+		}
+	}
+
+	var imapErr error
+	if config.IMAP.Enabled() {
+		mailEntries, err := mailTimelineEntries(config.IMAP, email.HME)
+		if err != nil {
+			imapErr = err
+		}
+		entries = append(entries, mailEntries...)
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].At.Before(entries[j].At)
+	})
+	return entries, imapErr
+}
+
+// mailTimelineEntries 统计转发邮箱中的首封/最近来信，以及垃圾邮件文件夹中的首封来信
+func mailTimelineEntries(settings IMAPConfig, address string) ([]TimelineEntry, error) {
+	timeout := 30 * time.Second
+	client, err := dialIMAP(settings, timeout)
+	if err != nil {
+		return nil, err
+	}
+	defer client.Close()
+
+	var entries []TimelineEntry
+	first, last, count, sender, err := mailboxStats(client, address, timeout)
+	if err != nil {
+		return nil, err
+	}
+	if count > 0 {
+		entries = append(entries, TimelineEntry{At: first, Title: "首封来信", Detail: sender, Color: ColorCyan})
+		if count > 1 {
+			entries = append(entries, TimelineEntry{At: last, Title: "最近来信", Detail: fmt.Sprintf("收件箱共 %d 封", count), Color: ColorCyan})
+		}
+	}
+
+	if settings.JunkMailbox != "" {
+		if err := client.Examine(settings.JunkMailbox, timeout); err != nil {
+			return entries, err
+		}
+		first, _, count, sender, err := mailboxStats(client, address, timeout)
+		if err != nil {
+			return entries, err
+		}
+		if count > 0 {
+			entries = append(entries, TimelineEntry{
+				At: first, Title: "首次被判为垃圾邮件",
+				Detail: fmt.Sprintf("%s（垃圾邮件共 %d 封）", sender, count), Color: ColorMagenta,
+			})
+		}
+	}
+	return entries, nil
+}
+
+// mailboxStats 统计当前文件夹中发往 address 的邮件
+func mailboxStats(client *imapClient, address string, timeout time.Duration) (first, last time.Time, count int, sender string, err error) {
+	uids, err := client.SearchTo(address, time.Time{}, timeout)
+	if err != nil || len(uids) == 0 {
+		return
+	}
+	dates, err := client.FetchDates(uids, timeout)
+	if err != nil {
+		return
+	}
+
+	var firstUID uint32
+	for uid, date := range dates {
+		if first.IsZero() || date.Before(first) {
+			first, firstUID = date, uid
+		}
+		if date.After(last) {
+			last = date
+		}
+	}
+	count = len(dates)
+
+	if header, err := client.FetchHeader(firstUID, timeout); err == nil {
+		if msg, err := mail.ReadMessage(bytes.NewReader(append(header, '\r', '\n'))); err == nil {
+			sender = msg.Header.Get("From")
+		}
+	}
+	return
+}
+
+// showAliasTimeline 显示邮箱活动时间线
+func showAliasTimeline(config *Config, email HMEEmail) {
+	var entries []TimelineEntry
+	var imapErr error
+	withSpinner("汇总活动记录", func() error {
+		entries, imapErr = buildAliasTimeline(config, email)
+		return nil
+	})
+
+	printSubHeader("活动时间线 · " + email.HME)
+	if len(entries) == 0 {
+		printInfo("暂无活动记录")
+	}
+	for i, entry := range entries {
+		connector := "├─"
+		if i == len(entries)-1 {
+			connector = "└─"
+		}
+		fmt.Printf("  "+ColorDim+"%s"+ColorReset+" "+ColorDim+"%s"+ColorReset+"  "+entry.Color+"%s"+ColorReset+"\n",
+			connector, entry.At.Local().Format("2006-01-02 15:04"), entry.Title)
+		if entry.Detail != "" {
+			pipe := "│"
+			if i == len(entries)-1 {
+				pipe = " "
+			}
+			fmt.Printf("  "+ColorDim+"%s"+ColorReset+"                     "+ColorDim+"%s"+ColorReset+"\n", pipe, entry.Detail)
+		}
+	}
+
+	if !config.IMAP.Enabled() {
+		printInfo("配置 imap 后可在时间线中显示来信记录")
+	} else if imapErr != nil {
+		printWarning(fmt.Sprintf("读取来信记录失败: %v", imapErr))
+	}
+	fmt.Println()
+}
@@ -0,0 +1,284 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptrace"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// TransportStats 进程启动以来接口请求的统计，供开发者工具查看
+type TransportStats struct {
+	mutex        sync.Mutex
+	Requests     int
+	Failures     int // 网络错误（未收到响应）
+	ReusedConns  int
+	Retries      int // 按 retry_policy 重试的次数
+	StatusCounts map[int]int
+	Endpoints    map[string]int
+	TotalLatency time.Duration
+	MaxLatency   time.Duration
+	Since        time.Time
+}
+
+var transportStats = &TransportStats{Since: time.Now()}
+
+// record 记录一次请求
+func (s *TransportStats) record(endpoint string, status int, err error, elapsed time.Duration, reused bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.StatusCounts == nil {
+		s.StatusCounts = make(map[int]int)
+		s.Endpoints = make(map[string]int)
+	}
+	s.Requests++
+	s.Endpoints[endpoint]++
+	if err != nil {
+		s.Failures++
+	} else {
+		s.StatusCounts[status]++
+	}
+	if reused {
+		s.ReusedConns++
+	}
+	s.TotalLatency += elapsed
+	if elapsed > s.MaxLatency {
+		s.MaxLatency = elapsed
+	}
+}
+
+// recordRetry 记录一次重试
+func (s *TransportStats) recordRetry() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.Retries++
+}
+
+// Reset 清空统计
+func (s *TransportStats) Reset() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.Requests, s.Failures, s.ReusedConns, s.Retries = 0, 0, 0, 0
+	s.StatusCounts, s.Endpoints = nil, nil
+	s.TotalLatency, s.MaxLatency = 0, 0
+	s.Since = time.Now()
+}
+
+// Print 输出统计
+func (s *TransportStats) Print() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	fmt.Printf("  "+ColorCyan+"统计区间:"+ColorReset+" %s 起（%s）\n", s.Since.Format("15:04:05"), time.Since(s.Since).Round(time.Second))
+	fmt.Printf("  "+ColorCyan+"请求数:"+ColorReset+" %d "+ColorDim+"|"+ColorReset+" 网络错误 %d "+ColorDim+"|"+ColorReset+" 重试 %d "+ColorDim+"|"+ColorReset+" 复用连接 %d\n", s.Requests, s.Failures, s.Retries, s.ReusedConns)
+	if s.Requests == 0 {
+		return
+	}
+	fmt.Printf("  "+ColorCyan+"耗时:"+ColorReset+" 平均 %s "+ColorDim+"|"+ColorReset+" 最长 %s\n",
+		(s.TotalLatency / time.Duration(s.Requests)).Round(time.Millisecond), s.MaxLatency.Round(time.Millisecond))
+
+	statuses := make([]int, 0, len(s.StatusCounts))
+	for status := range s.StatusCounts {
+		statuses = append(statuses, status)
+	}
+	sort.Ints(statuses)
+	var parts []string
+	for _, status := range statuses {
+		parts = append(parts, fmt.Sprintf("%d×%d", status, s.StatusCounts[status]))
+	}
+	fmt.Printf("  "+ColorCyan+"状态码:"+ColorReset+" %s\n", strings.Join(parts, "  "))
+
+	endpoints := make([]string, 0, len(s.Endpoints))
+	for endpoint := range s.Endpoints {
+		endpoints = append(endpoints, endpoint)
+	}
+	sort.Strings(endpoints)
+	parts = parts[:0]
+	for _, endpoint := range endpoints {
+		parts = append(parts, fmt.Sprintf("%s×%d", endpoint, s.Endpoints[endpoint]))
+	}
+	fmt.Printf("  "+ColorCyan+"接口:"+ColorReset+" %s\n", strings.Join(parts, "  "))
+}
+
+// instrumentedTransport 统计每个请求，并按开发者功能开关输出请求日志、写入 HTTP 调试日志或录制会话。
+// 连接池在配置副本之间共享，开关以当前发布的配置为准
+type instrumentedTransport struct {
+	base http.RoundTripper
+}
+
+func (t *instrumentedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	config := getCurrentConfig()
+	recording := config.featureEnabled(FeatureRecordSession)
+	dumping := config.featureEnabled(FeatureHTTPDump)
+	var reqBody []byte
+	if (recording || dumping) && req.GetBody != nil {
+		if body, err := req.GetBody(); err == nil {
+			reqBody, _ = io.ReadAll(body)
+			body.Close()
+		}
+	}
+
+	var reused bool
+	trace := &httptrace.ClientTrace{GotConn: func(info httptrace.GotConnInfo) { reused = info.Reused }}
+	timing := &httpTiming{}
+	if dumping {
+		timing.attach(trace)
+	}
+	req = req.WithContext(httptrace.WithClientTrace(req.Context(), trace))
+
+	start := time.Now()
+	resp, err := t.base.RoundTrip(req)
+	elapsed := time.Since(start)
+
+	status := 0
+	if resp != nil {
+		status = resp.StatusCode
+	}
+	endpoint := path.Base(req.URL.Path)
+	transportStats.record(endpoint, status, err, elapsed, reused)
+	if status != 0 && strings.Contains(req.URL.Path, "/hme/") {
+		sessionTracker.Observe(status)
+	}
+	if err == nil {
+		latencyTracker.Record(config, endpoint, elapsed)
+	}
+
+	if err != nil {
+		logger().Warn("接口请求失败", "method", req.Method, "endpoint", endpoint, "error", err, "elapsed_ms", elapsed.Milliseconds())
+	} else {
+		logger().Debug("接口请求", "method", req.Method, "endpoint", endpoint, "status", status, "elapsed_ms", elapsed.Milliseconds(), "reused", reused)
+	}
+
+	if config.featureEnabled(FeatureHTTPTrace) {
+		result := fmt.Sprintf("%d", status)
+		if err != nil {
+			result = err.Error()
+		}
+		fmt.Fprintf(os.Stderr, ColorDim+"[http] %s %s → %s (%s)"+ColorReset+"\n", req.Method, req.URL.Path, result, elapsed.Round(time.Millisecond))
+	}
+
+	var respBody []byte
+	if (recording || dumping) && err == nil {
+		// 读出响应体后放回，调用方照常读取
+		raw, readErr := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		resp.Body = io.NopCloser(bytes.NewReader(raw))
+		if readErr == nil {
+			respBody = raw
+		}
+	}
+
+	if dumping {
+		exchange := HTTPExchange{At: start, Request: req, RequestBody: reqBody, Response: resp, ResponseBody: respBody, Elapsed: elapsed, Timing: timing, Err: err}
+		if dumpErr := httpDumper.Dump(config, exchange); dumpErr != nil {
+			logger().Warn("写入 HTTP 调试日志失败", "error", dumpErr)
+			fmt.Fprintf(os.Stderr, ColorYellow+"[!] 写入 HTTP 调试日志失败: %v"+ColorReset+"\n", dumpErr)
+		}
+	}
+
+	if recording {
+		entry := SessionEntry{At: start, Method: req.Method, Path: req.URL.Path, RequestBody: rawJSON(reqBody), Status: status, ElapsedMS: elapsed.Milliseconds()}
+		if err != nil {
+			entry.Error = err.Error()
+		} else if respBody != nil {
+			entry.ResponseBody = rawJSON(decodeRecordedBody(respBody, resp.Header.Get("Content-Encoding")))
+		}
+		if recordErr := sessionRecorder.Record(config, entry); recordErr != nil {
+			logger().Warn("录制会话失败", "error", recordErr)
+			fmt.Fprintf(os.Stderr, ColorYellow+"[!] 录制会话失败: %v"+ColorReset+"\n", recordErr)
+		}
+	}
+	return resp, err
+}
+
+// decodeRecordedBody 录制时解开 gzip，便于阅读和比较
+func decodeRecordedBody(raw []byte, encoding string) []byte {
+	if !strings.Contains(encoding, "gzip") {
+		return raw
+	}
+	reader, err := gzip.NewReader(bytes.NewReader(raw))
+	if err != nil {
+		return raw
+	}
+	defer reader.Close()
+	decoded, err := io.ReadAll(reader)
+	if err != nil {
+		return raw
+	}
+	return decoded
+}
+
+// rawJSON 合法的 JSON 原样保存，否则保存为字符串
+func rawJSON(data []byte) json.RawMessage {
+	data = bytes.TrimSpace(data)
+	if len(data) == 0 {
+		return nil
+	}
+	if json.Valid(data) {
+		return json.RawMessage(data)
+	}
+	quoted, _ := json.Marshal(string(data))
+	return quoted
+}
+
+// SessionEntry 录制的一次接口请求。只保存路径与请求体，不保存查询参数（含 dsid）和请求头（含 Cookie）
+type SessionEntry struct {
+	At           time.Time       `json:"at"`
+	Method       string          `json:"method"`
+	Path         string          `json:"path"`
+	RequestBody  json.RawMessage `json:"request_body,omitempty"`
+	Status       int             `json:"status"`
+	ResponseBody json.RawMessage `json:"response_body,omitempty"`
+	ElapsedMS    int64           `json:"elapsed_ms"`
+	Error        string          `json:"error,omitempty"`
+}
+
+// SessionRecorder 把接口请求按 NDJSON 写入会话文件，每次启动程序新建一个文件
+type SessionRecorder struct {
+	mutex sync.Mutex
+	file  *os.File
+	path  string
+}
+
+var sessionRecorder = &SessionRecorder{}
+
+// Record 追加一条记录，首次调用时创建会话文件
+func (r *SessionRecorder) Record(config *Config, entry SessionEntry) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if r.file == nil {
+		dir := config.developerSessionDir()
+		if err := os.MkdirAll(dir, 0700); err != nil {
+			return err
+		}
+		r.path = filepath.Join(dir, "session-"+time.Now().Format("20060102-150405")+".ndjson")
+		file, err := os.OpenFile(r.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+		if err != nil {
+			return err
+		}
+		r.file = file
+	}
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	_, err = r.file.Write(append(data, '\n'))
+	return err
+}
+
+// Path 当前会话文件，尚未录制时为空
+func (r *SessionRecorder) Path() string {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return r.path
+}
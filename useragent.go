@@ -0,0 +1,173 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 浏览器发布节奏的参照点，用于推算当前版本：Chrome/Edge 与 Firefox 每 4 周一个大版本，
+// Safari 每年 9 月一个大版本（2025 年起版本号与系统年份对齐为 26）
+var (
+	chromeAnchorVersion  = 140
+	chromeAnchorDate     = time.Date(2025, 9, 2, 0, 0, 0, 0, time.UTC)
+	firefoxAnchorVersion = 143
+	firefoxAnchorDate    = time.Date(2025, 9, 16, 0, 0, 0, 0, time.UTC)
+	safariAnchorVersion  = 26
+	safariAnchorDate     = time.Date(2025, 9, 15, 0, 0, 0, 0, time.UTC)
+)
+
+const (
+	browserReleaseCycle  = 28 * 24 * time.Hour
+	outdatedChromeLag    = 6 // 落后推算版本超过该数量（约半年）视为明显过时
+	outdatedFirefoxLag   = 6
+	outdatedSafariLag    = 1
+	userAgentPlaceholder = "XXX"
+)
+
+var (
+	userAgentChromePattern  = regexp.MustCompile(`(?:Chrome|Edg)/(\d+)`)
+	userAgentFirefoxPattern = regexp.MustCompile(`Firefox/(\d+)`)
+	userAgentSafariPattern  = regexp.MustCompile(`Version/(\d+)[.\d]* .*Safari/`)
+)
+
+// UserAgentPreset 内置的浏览器标识，版本号按发布节奏推算，保持接近当前稳定版
+type UserAgentPreset struct {
+	Name        string
+	Description string
+	build       func(now time.Time) (userAgent, secCHUA, platform string)
+}
+
+var userAgentPresets = map[string]UserAgentPreset{
+	"chrome-mac": {"chrome-mac", "macOS 上的 Chrome", func(now time.Time) (string, string, string) {
+		v := estimatedChromeVersion(now)
+		return fmt.Sprintf("Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/%d.0.0.0 Safari/537.36", v),
+			chromeClientHints("Google Chrome", v), `"macOS"`
+	}},
+	"chrome-windows": {"chrome-windows", "Windows 上的 Chrome", func(now time.Time) (string, string, string) {
+		v := estimatedChromeVersion(now)
+		return fmt.Sprintf("Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/%d.0.0.0 Safari/537.36", v),
+			chromeClientHints("Google Chrome", v), `"Windows"`
+	}},
+	"edge-windows": {"edge-windows", "Windows 上的 Edge", func(now time.Time) (string, string, string) {
+		v := estimatedChromeVersion(now)
+		return fmt.Sprintf("Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/%d.0.0.0 Safari/537.36 Edg/%d.0.0.0", v, v),
+			chromeClientHints("Microsoft Edge", v), `"Windows"`
+	}},
+	"safari-mac": {"safari-mac", "macOS 上的 Safari", func(now time.Time) (string, string, string) {
+		return fmt.Sprintf("Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/%d.0 Safari/605.1.15", estimatedSafariVersion(now)), "", ""
+	}},
+	"firefox-mac": {"firefox-mac", "macOS 上的 Firefox", func(now time.Time) (string, string, string) {
+		v := estimatedFirefoxVersion(now)
+		return fmt.Sprintf("Mozilla/5.0 (Macintosh; Intel Mac OS X 10.15; rv:%d.0) Gecko/20100101 Firefox/%d.0", v, v), "", ""
+	}},
+}
+
+// estimatedChromeVersion 推算当前 Chrome 稳定版；保守起见取上一个大版本（更新通常分批推送）
+func estimatedChromeVersion(now time.Time) int {
+	return chromeAnchorVersion + int(now.Sub(chromeAnchorDate)/browserReleaseCycle) - 1
+}
+
+func estimatedFirefoxVersion(now time.Time) int {
+	return firefoxAnchorVersion + int(now.Sub(firefoxAnchorDate)/browserReleaseCycle) - 1
+}
+
+func estimatedSafariVersion(now time.Time) int {
+	return safariAnchorVersion + int(now.Sub(safariAnchorDate).Hours()/24/365)
+}
+
+// chromeClientHints Chromium 系浏览器随请求发送的 sec-ch-ua
+func chromeClientHints(brand string, version int) string {
+	return fmt.Sprintf(`"%s";v="%d", "Chromium";v="%d", "Not/A)Brand";v="24"`, brand, version, version)
+}
+
+// userAgentPresetNames 按名称排序的预设列表
+func userAgentPresetNames() []string {
+	names := make([]string, 0, len(userAgentPresets))
+	for name := range userAgentPresets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// applyUserAgentPreset 用预设覆盖 User-Agent 及配套的 Client Hints；Safari/Firefox 不发送 sec-ch-ua
+func applyUserAgentPreset(header http.Header, name string) bool {
+	preset, ok := userAgentPresets[strings.ToLower(strings.TrimSpace(name))]
+	if !ok {
+		return false
+	}
+	userAgent, secCHUA, platform := preset.build(time.Now())
+	header.Set("User-Agent", userAgent)
+	for _, key := range []string{"sec-ch-ua", "sec-ch-ua-mobile", "sec-ch-ua-platform"} {
+		header.Del(key)
+	}
+	if secCHUA != "" {
+		header.Set("sec-ch-ua", secCHUA)
+		header.Set("sec-ch-ua-mobile", "?0")
+		header.Set("sec-ch-ua-platform", platform)
+	}
+	return true
+}
+
+// configuredUserAgent 返回请求实际使用的 User-Agent（不含预设）
+func (c *Config) configuredUserAgent() string {
+	for key, value := range c.Headers {
+		if strings.EqualFold(key, "User-Agent") {
+			return value
+		}
+	}
+	return c.UserAgent
+}
+
+// userAgentOutdated 判断 User-Agent 是否明显过时，返回原因；无法识别的浏览器不做判断
+func userAgentOutdated(userAgent string, now time.Time) string {
+	if strings.Contains(userAgent, userAgentPlaceholder) {
+		return "仍是示例中的占位符 XXX"
+	}
+	check := func(pattern *regexp.Regexp, browser string, current, lag int) string {
+		match := pattern.FindStringSubmatch(userAgent)
+		if match == nil {
+			return ""
+		}
+		version, err := strconv.Atoi(match[1])
+		if err != nil || version >= current-lag {
+			return ""
+		}
+		return fmt.Sprintf("%s %d 落后于当前版本（约 %d）", browser, version, current)
+	}
+	switch {
+	case userAgentChromePattern.MatchString(userAgent):
+		return check(userAgentChromePattern, "Chrome", estimatedChromeVersion(now)+1, outdatedChromeLag)
+	case userAgentFirefoxPattern.MatchString(userAgent):
+		return check(userAgentFirefoxPattern, "Firefox", estimatedFirefoxVersion(now)+1, outdatedFirefoxLag)
+	case userAgentSafariPattern.MatchString(userAgent):
+		return check(userAgentSafariPattern, "Safari", estimatedSafariVersion(now), outdatedSafariLag)
+	}
+	return ""
+}
+
+// checkUserAgent 启动时检查浏览器标识：预设名无效或自定义 User-Agent 明显过时时给出提示。
+// 过时的 User-Agent 容易被 Apple 风控，表现为创建失败率升高
+func checkUserAgent(config *Config) {
+	if name := strings.TrimSpace(config.UserAgentPreset); name != "" {
+		if _, ok := userAgentPresets[strings.ToLower(name)]; !ok {
+			printWarning(fmt.Sprintf("未知的 user_agent_preset %q，已忽略（可选: %s）", name, strings.Join(userAgentPresetNames(), "、")))
+		} else {
+			return
+		}
+	}
+
+	userAgent := config.configuredUserAgent()
+	if userAgent == "" {
+		return
+	}
+	if reason := userAgentOutdated(userAgent, time.Now()); reason != "" {
+		printWarning(fmt.Sprintf("配置的 User-Agent 可能已过时: %s", reason))
+		printInfo(fmt.Sprintf("过时的浏览器标识可能导致创建失败率升高，建议设置 user_agent_preset（%s）或更新 headers 中的 User-Agent", strings.Join(userAgentPresetNames(), "、")))
+	}
+}
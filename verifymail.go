@@ -0,0 +1,102 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// waitForVerificationMail 轮询转发邮箱，等待发往 address 的邮件并显示其中的验证链接与验证码
+func waitForVerificationMail(settings IMAPConfig, address string, since time.Time, timeout, interval time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	seen := make(map[uint32]bool)
+
+	printInfo(fmt.Sprintf("等待发往 %s 的邮件 (最长 %s，Ctrl+C 退出)", address, timeout))
+	for {
+		messages, err := fetchMessagesTo(settings, address, since, 30*time.Second)
+		if err != nil {
+			return err
+		}
+
+		// 按到达顺序显示新邮件
+		for i := len(messages) - 1; i >= 0; i-- {
+			message := messages[i]
+			if seen[message.UID] {
+				continue
+			}
+			seen[message.UID] = true
+			if printVerificationMail(message) {
+				return nil
+			}
+		}
+
+		if time.Now().After(deadline) {
+			return fmt.Errorf("等待超时，未收到验证邮件")
+		}
+		select {
+		case <-time.After(interval):
+		case <-safetyManager.Context().Done():
+			return nil
+		}
+	}
+}
+
+// printVerificationMail 显示邮件摘要及验证信息，找到验证信息时返回 true
+func printVerificationMail(message *MailMessage) bool {
+	printSubHeader("收到新邮件")
+	fmt.Printf("  "+ColorCyan+"发件人:"+ColorReset+" %s\n", message.From)
+	fmt.Printf("  "+ColorCyan+"主题:"+ColorReset+" %s\n", message.Subject)
+	if !message.Date.IsZero() {
+		fmt.Printf("  "+ColorCyan+"时间:"+ColorReset+" %s\n", message.Date.Local().Format("2006-01-02 15:04:05"))
+	}
+
+	codes := message.VerificationCodes()
+	links := message.VerificationLinks()
+	for _, code := range codes {
+		fmt.Printf("  "+ColorBrightGreen+"验证码:"+ColorReset+" "+ColorBold+"%s"+ColorReset+"\n", code)
+	}
+	for _, link := range links {
+		fmt.Printf("  "+ColorBrightGreen+"验证链接:"+ColorReset+" %s\n", link)
+	}
+	if len(codes) == 0 && len(links) == 0 {
+		printInfo("未识别到验证链接或验证码，继续等待")
+		return false
+	}
+	return true
+}
+
+// offerVerificationWait 创建邮箱后询问是否等待验证邮件（仅在配置了 IMAP 时）
+func offerVerificationWait(config *Config, address string, createdAt time.Time) {
+	if !config.IMAP.Enabled() {
+		return
+	}
+	if !confirmAction("等待该邮箱的验证邮件") {
+		return
+	}
+	if err := waitForVerificationMail(config.IMAP, address, createdAt, 5*time.Minute, 5*time.Second); err != nil {
+		printError(err.Error())
+	}
+}
+
+// runVerifyWatch 命令行等待验证邮件
+func runVerifyWatch(config *Config, args []string) error {
+	fs := flag.NewFlagSet("verify-watch", flag.ContinueOnError)
+	timeout := fs.Int("timeout", 300, "最长等待时间（秒）")
+	interval := fs.Int("interval", 5, "检查间隔（秒）")
+	lookback := fs.Int("since", 10, "同时检查最近多少分钟内已到达的邮件")
+	if err := fs.Parse(args); err != nil {
+		return usageError(err)
+	}
+	if fs.NArg() != 1 || !strings.Contains(fs.Arg(0), "@") {
+		return fmt.Errorf("用法: verify-watch [-timeout 秒] [-interval 秒] [-since 分钟] 邮箱地址")
+	}
+	if *interval < 2 {
+		return fmt.Errorf("检查间隔不能小于 2 秒")
+	}
+
+	printHeader("等待验证邮件")
+	since := time.Now().Add(-time.Duration(*lookback) * time.Minute)
+	return waitForVerificationMail(config.IMAP, fs.Arg(0), since,
+		time.Duration(*timeout)*time.Second, time.Duration(*interval)*time.Second)
+}
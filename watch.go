@@ -0,0 +1,171 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// 邮箱在两次刷新之间的变化类型
+const (
+	watchUnchanged = iota
+	watchAdded
+	watchRemoved
+	watchStatusChanged
+	watchLabelChanged
+)
+
+// watchEntry 监控视图中的一行
+type watchEntry struct {
+	email  HMEEmail
+	change int
+}
+
+// diffEmailLists 对比两次列表，返回带变化标记的合并结果（按创建时间倒序）
+func diffEmailLists(previous, current []HMEEmail) []watchEntry {
+	prevByID := make(map[string]HMEEmail, len(previous))
+	for _, email := range previous {
+		prevByID[email.AnonymousID] = email
+	}
+
+	entries := make([]watchEntry, 0, len(current))
+	seen := make(map[string]bool, len(current))
+	for _, email := range current {
+		seen[email.AnonymousID] = true
+		change := watchUnchanged
+
+		if old, ok := prevByID[email.AnonymousID]; !ok {
+			if previous != nil {
+				change = watchAdded
+			}
+		} else if old.IsActive != email.IsActive {
+			change = watchStatusChanged
+		} else if old.Label != email.Label || old.Note != email.Note {
+			change = watchLabelChanged
+		}
+		entries = append(entries, watchEntry{email: email, change: change})
+	}
+
+	for _, email := range previous {
+		if !seen[email.AnonymousID] {
+			entries = append(entries, watchEntry{email: email, change: watchRemoved})
+		}
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].email.CreateTimestamp > entries[j].email.CreateTimestamp
+	})
+	return entries
+}
+
+// runWatch 定时刷新邮箱列表并高亮变化
+func runWatch(config *Config, args []string) error {
+	fs := flag.NewFlagSet("watch", flag.ContinueOnError)
+	interval := fs.Int("interval", 10, "刷新间隔（秒）")
+	autoLabel := fs.Bool("auto-label", false, "邮箱收到首封邮件时按发件域名自动更新占位标签（需配置 IMAP）")
+	if err := fs.Parse(args); err != nil {
+		return usageError(err)
+	}
+	if *interval < 2 {
+		return fmt.Errorf("刷新间隔不能小于 2 秒")
+	}
+	if *autoLabel && !config.IMAP.Enabled() {
+		return fmt.Errorf("自动标注需要先配置 IMAP")
+	}
+
+	var previous []HMEEmail
+	ticker := time.NewTicker(time.Duration(*interval) * time.Second)
+	defer ticker.Stop()
+
+	for {
+		emails, err := listHME(getCurrentConfig())
+
+		clearScreen()
+		printHeader("邮箱列表监控")
+		fmt.Printf("  "+ColorDim+"每 %d 秒刷新 | 上次刷新 %s | Ctrl+C 退出"+ColorReset+"\n\n",
+			*interval, time.Now().Format("15:04:05"))
+
+		if err != nil {
+			printError(fmt.Sprintf("获取列表失败: %v", err))
+		} else {
+			printWatchEntries(diffEmailLists(previous, emails))
+			previous = emails
+			if err := inventory.Sync(emails); err != nil {
+				printWarning(fmt.Sprintf("同步本地清单失败: %v", err))
+			}
+			if *autoLabel {
+				updated, err := autoLabelPass(getCurrentConfig(), emails)
+				if err != nil {
+					printWarning(fmt.Sprintf("自动标注失败: %v", err))
+				}
+				if updated > 0 {
+					printInfo(fmt.Sprintf("已根据来信自动标注 %d 个邮箱，下次刷新时显示", updated))
+				}
+			}
+		}
+
+		select {
+		case <-ticker.C:
+		case <-safetyManager.Context().Done():
+			return nil
+		}
+	}
+}
+
+// printWatchEntries 输出监控视图
+func printWatchEntries(entries []watchEntry) {
+	var active, added, removed, changed int
+	for _, entry := range entries {
+		if entry.email.IsActive && entry.change != watchRemoved {
+			active++
+		}
+		switch entry.change {
+		case watchAdded:
+			added++
+		case watchRemoved:
+			removed++
+		case watchStatusChanged, watchLabelChanged:
+			changed++
+		}
+	}
+
+	fmt.Printf("  "+ColorGreen+"激活"+ColorReset+" %d "+ColorDim+"|"+ColorReset+" "+ColorBrightGreen+"新增"+ColorReset+" %d "+ColorDim+"|"+ColorReset+" "+ColorRed+"移除"+ColorReset+" %d "+ColorDim+"|"+ColorReset+" "+ColorYellow+"变更"+ColorReset+" %d\n\n",
+		active, added, removed, changed)
+
+	emailWidth := 40
+	if width := getTerminalWidth() - 30; width < emailWidth && width > 20 {
+		emailWidth = width
+	}
+
+	for _, entry := range entries {
+		email := entry.email
+		status := ColorBrightGreen + "●" + ColorReset
+		if !email.IsActive {
+			status = ColorYellow + "○" + ColorReset
+		}
+
+		marker, color := " ", ColorBrightWhite
+		switch entry.change {
+		case watchAdded:
+			marker, color = ColorBrightGreen+"+"+ColorReset, ColorBrightGreen
+		case watchRemoved:
+			marker, color = ColorRed+"-"+ColorReset, ColorRed
+		case watchStatusChanged:
+			marker, color = ColorYellow+"~"+ColorReset, ColorYellow
+		case watchLabelChanged:
+			marker, color = ColorCyan+"*"+ColorReset, ColorCyan
+		default:
+			if !email.IsActive {
+				color = ColorGray
+			}
+		}
+
+		label := email.Label
+		if label == "" {
+			label = "(无标签)"
+		}
+		fmt.Printf("  %s %s "+color+"%s"+ColorReset+" "+ColorDim+"%s"+ColorReset+"\n",
+			marker, status, formatEmailAddress(email.HME, emailWidth), label)
+	}
+}
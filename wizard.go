@@ -0,0 +1,247 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// wizardHeaders 逐项填写时使用的请求头（与 config.json.example 一致），User-Agent 由 user_agent_preset 提供
+var wizardHeaders = map[string]string{
+	"Accept":          "*/*",
+	"Accept-Language": "en-US,en;q=0.9",
+	"Content-Type":    "text/plain",
+	"Origin":          "https://www.icloud.com",
+	"Referer":         "https://www.icloud.com/",
+	"Sec-Fetch-Dest":  "empty",
+	"Sec-Fetch-Mode":  "cors",
+	"Sec-Fetch-Site":  "same-site",
+}
+
+// 逐项填写时默认使用的浏览器标识预设
+const wizardUserAgentPreset = "chrome-mac"
+
+// wizardRequested 是否需要运行配置向导：init 命令，或交互运行主菜单时 config.json 不存在（且没有用环境变量或 --set 提供配置）
+func wizardRequested(args []string) bool {
+	if len(args) > 0 {
+		return args[0] == "init"
+	}
+	if _, err := os.Stat(CONFIG_FILE); !os.IsNotExist(err) || configOverridesPresent() {
+		return false
+	}
+	return stdinIsTerminal()
+}
+
+// stdinIsTerminal 标准输入是否为终端（而非管道、文件或 /dev/null）
+func stdinIsTerminal() bool {
+	return isTerminal(os.Stdin)
+}
+
+// askField 读取一项配置，回车沿用当前值；required 时不允许为空
+func askField(label, current string, required bool) (string, error) {
+	prompt := label
+	if current != "" {
+		prompt += " " + ColorGray + "(回车沿用 " + truncateMiddle(current, 40) + ")" + ColorReset
+	}
+	value := readInput(prompt + ": ")
+	if value == "" {
+		value = current
+	}
+	if required && value == "" {
+		return "", configError("%s 不能为空", label)
+	}
+	return value, nil
+}
+
+// askPositiveInt 读取正整数配置，回车沿用当前值
+func askPositiveInt(label string, current int) (int, error) {
+	value := readInput(fmt.Sprintf("%s "+ColorGray+"(回车使用 %d)"+ColorReset+": ", label, current))
+	if value == "" {
+		return current, nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n <= 0 {
+		return 0, configError("%s 必须是正整数", label)
+	}
+	return n, nil
+}
+
+// truncateMiddle 过长的值只显示首尾，避免把整段 Cookie 打在屏幕上
+func truncateMiddle(value string, max int) string {
+	if len(value) <= max {
+		return value
+	}
+	return value[:max/2] + "…" + value[len(value)-max/2:]
+}
+
+// wizardAccount 填写账号字段：粘贴 curl 命令、导入 HAR 文件或逐项输入
+func wizardAccount(config *Config) error {
+	printSubHeader("iCloud 账号")
+	fmt.Println("  在浏览器中登录 icloud.com 并打开“隐藏邮件地址”，在开发者工具的网络面板中")
+	fmt.Println("  找到发往 pXX-maildomainws.icloud.com 的请求（如 list），右键“复制为 cURL (bash)”。")
+	fmt.Println()
+	fmt.Println("  " + ColorCyan + "[1]" + ColorReset + " 粘贴 curl 命令 " + ColorDim + "(推荐，自动取出地址、参数与请求头)" + ColorReset)
+	fmt.Println("  " + ColorCyan + "[2]" + ColorReset + " 逐项填写 dsid、client_id 与 Cookie")
+	fmt.Println("  " + ColorCyan + "[3]" + ColorReset + " 导入浏览器导出的 HAR 文件 " + ColorDim + "(不熟悉命令行时更简单)" + ColorReset)
+	fmt.Println()
+
+	switch choice := readInput("选择方式 (1-3，回车为 1): "); choice {
+	case "", "1", "3":
+		var request *curlRequest
+		var err error
+		if choice == "3" {
+			fmt.Println()
+			printHARGuide()
+			request, err = readHARFile(readInput("HAR 文件路径: "))
+		} else {
+			request, err = parseCurlCommand(readCurlCommand())
+		}
+		if err != nil {
+			return configError("%v", err)
+		}
+		request.applyTo(config)
+		printSuccess(fmt.Sprintf("已读取 %s 与 %d 个请求头", request.BaseURL, len(request.Headers)))
+		// 少数请求不带账号参数，缺少的逐项补齐
+		for _, field := range []struct {
+			label  string
+			target *string
+		}{
+			{"dsid", &config.DSID},
+			{"client_id", &config.ClientID},
+			{"client_build_number", &config.ClientBuildNumber},
+			{"client_mastering_number", &config.ClientMasteringNumber},
+		} {
+			if *field.target != "" {
+				continue
+			}
+			value, err := askField(field.label, "", true)
+			if err != nil {
+				return err
+			}
+			*field.target = value
+		}
+	case "2":
+		host, err := askField("接口地址 "+ColorGray+"(如 p68-maildomainws.icloud.com)"+ColorReset, config.BaseURL, true)
+		if err != nil {
+			return err
+		}
+		if !strings.Contains(host, "://") {
+			host = "https://" + host
+		}
+		parsed, err := url.Parse(host)
+		if err != nil || parsed.Host == "" {
+			return configError("无效的接口地址: %s", host)
+		}
+		config.BaseURL = parsed.Scheme + "://" + parsed.Host + "/v1/hme/reserve"
+		for _, field := range []struct {
+			label  string
+			target *string
+		}{
+			{"dsid", &config.DSID},
+			{"client_id", &config.ClientID},
+			{"client_build_number", &config.ClientBuildNumber},
+			{"client_mastering_number", &config.ClientMasteringNumber},
+		} {
+			value, err := askField(field.label, *field.target, true)
+			if err != nil {
+				return err
+			}
+			*field.target = value
+		}
+		cookie, err := askField("Cookie "+ColorGray+"(完整的 Cookie 请求头)"+ColorReset, config.Headers["Cookie"], true)
+		if err != nil {
+			return err
+		}
+		if config.Headers == nil {
+			config.Headers = make(map[string]string)
+			for key, value := range wizardHeaders {
+				config.Headers[key] = value
+			}
+			config.UserAgentPreset = wizardUserAgentPreset
+		}
+		config.Headers["Cookie"] = strings.TrimPrefix(cookie, "Cookie: ")
+	default:
+		return configError("无效的选择")
+	}
+	return nil
+}
+
+// wizardDefaults 填写语言与创建数量、间隔的默认值
+func wizardDefaults(config *Config) error {
+	printSubHeader("默认设置")
+	lang, err := askField(fmt.Sprintf("语言代码 "+ColorGray+"(auto 按系统语言，当前系统为 %s；也可填 en-us、zh-cn 等)"+ColorReset, detectLangCode()), config.LangCode, false)
+	if err != nil {
+		return err
+	}
+	if lang == "" || strings.EqualFold(lang, langCodeAuto) {
+		config.LangCode = langCodeAuto
+	} else if config.LangCode, err = parseLangCode(lang); err != nil {
+		return configError("%v", err)
+	}
+	if config.Count, err = askPositiveInt("默认批量创建数量", config.Count); err != nil {
+		return err
+	}
+	if config.DelaySeconds, err = askPositiveInt("每次创建的间隔（秒）", config.DelaySeconds); err != nil {
+		return err
+	}
+	return nil
+}
+
+// runSetupWizard 首次运行的配置向导：填写账号与默认设置，用一次列表请求验证后写入 config.json；
+// 配置已存在时（init 命令）以现有值为默认值，只更新这些字段
+func runSetupWizard() error {
+	printHeader("配置向导")
+
+	config := &Config{}
+	if _, err := os.Stat(CONFIG_FILE); err == nil {
+		loaded, err := configManager.LoadConfig()
+		if err != nil {
+			return configError("现有配置无法读取: %v", err)
+		}
+		config = loaded
+		printInfo(fmt.Sprintf("%s 已存在，回车沿用现有值，其余配置保持不变", CONFIG_FILE))
+	} else {
+		printInfo(fmt.Sprintf("未找到 %s，接下来几步将创建它（之后可随时运行 init 重新配置）", CONFIG_FILE))
+		config.Count = 5
+		config.DelaySeconds = 2
+		configManager.setDefaults(config)
+	}
+
+	for {
+		if err := wizardAccount(config); err != nil {
+			return err
+		}
+		if err := wizardDefaults(config); err != nil {
+			return err
+		}
+
+		// 请求统计等按当前配置记录，验证前先发布
+		configMutex.Lock()
+		globalConfig = config
+		configMutex.Unlock()
+
+		n, err := verifyAccount(config)
+		if err == nil {
+			printSuccess(fmt.Sprintf("验证通过，账号中共有 %d 个隐藏邮箱", n))
+			break
+		}
+		printError(err.Error())
+		fmt.Println()
+		fmt.Println("  " + ColorCyan + "[r]" + ColorReset + " 重新填写  " + ColorCyan + "[s]" + ColorReset + " 仍然保存  " + ColorCyan + "[q]" + ColorReset + " 放弃")
+		choice := strings.ToLower(readInput("选择: "))
+		if choice == "s" {
+			break
+		}
+		if choice != "r" {
+			return configError("已放弃配置，未写入 %s", CONFIG_FILE)
+		}
+	}
+
+	if err := configManager.SaveConfig(config); err != nil {
+		return configError("%v", err)
+	}
+	printSuccess(fmt.Sprintf("配置已写入 %s，其余选项可参考 config.json.example 或在菜单 [8] 程序设置中修改", CONFIG_FILE))
+	return nil
+}